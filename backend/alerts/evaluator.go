@@ -0,0 +1,99 @@
+// Package alerts evalúa las reglas de alerta de los usuarios contra los datos de los
+// stocks tras cada ejecución del enricher.
+package alerts
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/clock"
+	"github.com/jannin2/stock-app/backend/database"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// Evaluator evalúa todas las reglas de alerta de una vez en la base de datos
+// (database.UserDB.EvaluateAlerts), sin cargarlas en memoria.
+type Evaluator struct {
+	users    database.UserDB
+	clock    clock.Clock
+	notifier Notifier
+
+	running  sync.Mutex    // Evita dos evaluaciones simultáneas
+	stopOnce sync.Once     // Stop toma running una sola vez
+	stopped  chan struct{} // Se cierra cuando Stop tiene running
+}
+
+// Notifier recibe las alertas disparadas en cada evaluación (normalmente un
+// notify.Dispatcher).
+type Notifier interface {
+	Enqueue(ctx context.Context, events []models.AlertEvent) error
+}
+
+// NewEvaluator crea un Evaluator sobre la base de datos de usuarios que pasa las alertas
+// disparadas a notifier (si no es nil).
+func NewEvaluator(users database.UserDB, c clock.Clock, notifier Notifier) *Evaluator {
+	return &Evaluator{users: users, clock: c, notifier: notifier}
+}
+
+// RunOnce evalúa las reglas y devuelve las alertas disparadas.
+func (e *Evaluator) RunOnce(ctx context.Context) ([]models.AlertEvent, error) {
+	e.running.Lock()
+	defer e.running.Unlock()
+	return e.evaluate(ctx)
+}
+
+// evaluate evalúa las reglas; el llamador debe tener e.running.
+func (e *Evaluator) evaluate(ctx context.Context) ([]models.AlertEvent, error) {
+	start := e.clock.Now()
+	fired, rearmed, err := e.users.EvaluateAlerts(ctx, start.UTC())
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("🔔 Alertas evaluadas en %s: %d disparadas, %d rearmadas", e.clock.Now().Sub(start).Round(time.Millisecond), len(fired), rearmed)
+	if e.notifier != nil && len(fired) > 0 {
+		if err := e.notifier.Enqueue(ctx, fired); err != nil {
+			return fired, fmt.Errorf("alertas disparadas pero no notificadas: %w", err)
+		}
+	}
+	return fired, nil
+}
+
+// AfterEnrichment evalúa las reglas en segundo plano, para llamarlo al terminar cada
+// ejecución del enricher sin retrasarla. Si ya hay una evaluación en curso no hace nada:
+// la siguiente ejecución del enricher volverá a evaluar.
+func (e *Evaluator) AfterEnrichment() {
+	if !e.running.TryLock() {
+		log.Println("Advertencia: evaluación de alertas aún en curso, se omite esta vuelta")
+		return
+	}
+	go func() {
+		defer e.running.Unlock()
+		if _, err := e.evaluate(context.Background()); err != nil {
+			log.Printf("ERROR: no se pudieron evaluar las alertas: %v", err)
+		}
+	}()
+}
+
+// Stop espera a que termine la evaluación en curso, o a que venza ctx, para que el
+// apagado no cierre la base de datos a mitad de la transacción. Después de Stop no se
+// evalúa nada más: las llamadas a AfterEnrichment se omiten y RunOnce se bloquea. Se
+// puede llamar más de una vez.
+func (e *Evaluator) Stop(ctx context.Context) error {
+	e.stopOnce.Do(func() {
+		e.stopped = make(chan struct{})
+		go func() {
+			e.running.Lock() // No se libera: el Evaluator queda detenido
+			close(e.stopped)
+		}()
+	})
+
+	select {
+	case <-e.stopped:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("evaluación de alertas aún en curso: %w", ctx.Err())
+	}
+}
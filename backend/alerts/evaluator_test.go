@@ -0,0 +1,105 @@
+package alerts
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/clock"
+	"github.com/jannin2/stock-app/backend/database"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// evalUserDB cuenta las evaluaciones y puede bloquearlas hasta que se cierre release.
+type evalUserDB struct {
+	database.UserDB
+	mu      sync.Mutex
+	calls   []time.Time
+	release chan struct{}
+	err     error
+}
+
+func (db *evalUserDB) EvaluateAlerts(_ context.Context, at time.Time) ([]models.AlertEvent, int64, error) {
+	if db.release != nil {
+		<-db.release
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.calls = append(db.calls, at)
+	if db.err != nil {
+		return nil, 0, db.err
+	}
+	return []models.AlertEvent{{ID: uuid.New(), Ticker: "AAPL", TriggeredAt: at}}, 1, nil
+}
+
+// recordingNotifier guarda las alertas que recibe.
+type recordingNotifier struct {
+	events []models.AlertEvent
+}
+
+func (n *recordingNotifier) Enqueue(_ context.Context, events []models.AlertEvent) error {
+	n.events = append(n.events, events...)
+	return nil
+}
+
+func TestRunOnce(t *testing.T) {
+	c := clock.NewMock()
+	db := &evalUserDB{}
+	notifier := &recordingNotifier{}
+	e := NewEvaluator(db, c, notifier)
+
+	fired, err := e.RunOnce(context.Background())
+	if err != nil || len(fired) != 1 || !db.calls[0].Equal(c.Now()) {
+		t.Errorf("❌ RunOnce = %+v, %v; llamadas %v", fired, err, db.calls)
+	}
+	if len(notifier.events) != 1 || notifier.events[0].ID != fired[0].ID {
+		t.Errorf("❌ las alertas disparadas deberían notificarse: %+v", notifier.events)
+	}
+
+	db.err = errors.New("sin conexión")
+	if _, err := e.RunOnce(context.Background()); err == nil {
+		t.Errorf("❌ se esperaba el error de la base de datos")
+	}
+}
+
+func TestAfterEnrichment_SkipsWhileRunning(t *testing.T) {
+	db := &evalUserDB{release: make(chan struct{})}
+	e := NewEvaluator(db, clock.NewMock(), nil)
+
+	e.AfterEnrichment()
+	e.AfterEnrichment() // La primera sigue bloqueada: se omite
+	close(db.release)
+
+	e.RunOnce(context.Background()) // Espera a que termine la evaluación en segundo plano
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if len(db.calls) != 2 {
+		t.Errorf("❌ se esperaban 2 evaluaciones (una en segundo plano y RunOnce), hubo %d", len(db.calls))
+	}
+}
+
+func TestStop_WaitsForEvaluation(t *testing.T) {
+	db := &evalUserDB{release: make(chan struct{})}
+	e := NewEvaluator(db, clock.NewMock(), nil)
+
+	e.AfterEnrichment()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := e.Stop(ctx); err == nil {
+		t.Fatal("❌ Stop debería vencer con la evaluación bloqueada")
+	}
+
+	close(db.release)
+	if err := e.Stop(context.Background()); err != nil {
+		t.Fatalf("❌ error inesperado al detener: %v", err)
+	}
+	e.AfterEnrichment() // Detenido: se omite
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if len(db.calls) != 1 {
+		t.Errorf("❌ se esperaba 1 evaluación, hubo %d", len(db.calls))
+	}
+}
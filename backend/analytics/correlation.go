@@ -0,0 +1,105 @@
+// Package analytics contains pure computations over stock price history.
+package analytics
+
+import (
+	"math"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// MinCorrelationObservations is the minimum number of shared daily returns needed for a
+// correlation to be reported; below it the pair is left as null.
+const MinCorrelationObservations = 3
+
+// CorrelationMatrix holds the pairwise Pearson correlation of daily returns.
+// Matrix[i][j] corresponds to Tickers[i] and Tickers[j] and is invalid (null in JSON)
+// when the pair has fewer than MinCorrelationObservations returns in common.
+type CorrelationMatrix struct {
+	Tickers      []string               `json:"tickers"`
+	Matrix       [][]models.NullFloat64 `json:"matrix"`
+	Observations [][]int                `json:"observations"` // Shared returns behind each coefficient
+}
+
+// Correlation computes the correlation matrix for tickers from their price history.
+// Daily returns are computed between consecutive stored days of each ticker, and each
+// pair is correlated over the days on which both tickers have a return.
+func Correlation(tickers []string, history map[string][]models.PricePoint) CorrelationMatrix {
+	returns := make([]map[time.Time]float64, len(tickers))
+	for i, ticker := range tickers {
+		returns[i] = dailyReturns(history[ticker])
+	}
+
+	m := CorrelationMatrix{
+		Tickers:      tickers,
+		Matrix:       make([][]models.NullFloat64, len(tickers)),
+		Observations: make([][]int, len(tickers)),
+	}
+	for i := range tickers {
+		m.Matrix[i] = make([]models.NullFloat64, len(tickers))
+		m.Observations[i] = make([]int, len(tickers))
+	}
+
+	for i := range tickers {
+		for j := i; j < len(tickers); j++ {
+			corr, n := pearson(returns[i], returns[j])
+			m.Matrix[i][j], m.Matrix[j][i] = corr, corr
+			m.Observations[i][j], m.Observations[j][i] = n, n
+		}
+	}
+	return m
+}
+
+// dailyReturns maps each trading day to the simple return since the previous stored day.
+// points must be sorted by trading day.
+func dailyReturns(points []models.PricePoint) map[time.Time]float64 {
+	returns := make(map[time.Time]float64, len(points))
+	for k := 1; k < len(points); k++ {
+		prev := points[k-1].Close
+		if prev == 0 {
+			continue
+		}
+		returns[points[k].TradingDay] = (points[k].Close - prev) / prev
+	}
+	return returns
+}
+
+// pearson correlates the returns the two series share. It returns an invalid value when
+// there are too few observations or one of the series is constant.
+func pearson(a, b map[time.Time]float64) (models.NullFloat64, int) {
+	var xs, ys []float64
+	for day, x := range a {
+		if y, ok := b[day]; ok {
+			xs = append(xs, x)
+			ys = append(ys, y)
+		}
+	}
+
+	n := len(xs)
+	if n < MinCorrelationObservations {
+		return models.NullFloat64{}, n
+	}
+
+	var meanX, meanY float64
+	for k := range xs {
+		meanX += xs[k]
+		meanY += ys[k]
+	}
+	meanX /= float64(n)
+	meanY /= float64(n)
+
+	var cov, varX, varY float64
+	for k := range xs {
+		dx, dy := xs[k]-meanX, ys[k]-meanY
+		cov += dx * dy
+		varX += dx * dx
+		varY += dy * dy
+	}
+	if varX == 0 || varY == 0 {
+		return models.NullFloat64{}, n
+	}
+
+	corr := cov / math.Sqrt(varX*varY)
+	// Clamp floating point noise so identical series report exactly 1.
+	return models.NewNullFloat64(math.Max(-1, math.Min(1, corr))), n
+}
@@ -0,0 +1,45 @@
+package analytics
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+func series(ticker string, closes ...float64) []models.PricePoint {
+	start := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	points := make([]models.PricePoint, len(closes))
+	for i, c := range closes {
+		points[i] = models.PricePoint{Ticker: ticker, TradingDay: start.AddDate(0, 0, i), Close: c}
+	}
+	return points
+}
+
+func TestCorrelation(t *testing.T) {
+	history := map[string][]models.PricePoint{
+		"AAA": series("AAA", 100, 110, 99, 104, 120),
+		"BBB": series("BBB", 50, 55, 49.5, 52, 60),     // Same returns as AAA
+		"CCC": series("CCC", 100, 90, 99, 94.05, 78.4), // Roughly opposite returns
+		"DDD": series("DDD", 10, 11),                   // Too short
+	}
+
+	m := Correlation([]string{"AAA", "BBB", "CCC", "DDD"}, history)
+
+	if got := m.Matrix[0][1]; !got.Valid || math.Abs(got.Float64-1) > 1e-9 {
+		t.Errorf("Expected AAA/BBB correlation 1, got %+v", got)
+	}
+	if got := m.Matrix[0][2]; !got.Valid || got.Float64 > -0.9 {
+		t.Errorf("Expected strongly negative AAA/CCC correlation, got %+v", got)
+	}
+	if m.Matrix[0][2] != m.Matrix[2][0] {
+		t.Errorf("Expected a symmetric matrix")
+	}
+	if got := m.Matrix[0][3]; got.Valid {
+		t.Errorf("Expected null correlation with too few observations, got %+v", got)
+	}
+	if m.Observations[0][1] != 4 {
+		t.Errorf("Expected 4 shared returns, got %d", m.Observations[0][1])
+	}
+}
@@ -0,0 +1,212 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// ProjectionPercentiles are the percentiles reported for every simulated day.
+var ProjectionPercentiles = []float64{5, 25, 50, 75, 95}
+
+// ErrInsufficientHistory is returned when there are not enough shared daily returns to
+// estimate drift and volatility.
+var ErrInsufficientHistory = errors.New("not enough price history to estimate drift and volatility")
+
+// Holding is a portfolio position used by the projection. Weights are normalized, so they
+// only need to be relative to each other.
+type Holding struct {
+	Ticker string  `json:"ticker"`
+	Weight float64 `json:"weight"`
+}
+
+// ProjectionParams configures a Monte Carlo projection.
+type ProjectionParams struct {
+	Holdings    []Holding
+	HorizonDays int   // Trading days to simulate
+	Paths       int   // Number of simulated paths
+	Seed        int64 // Seed for reproducible runs; each path derives its own stream
+	StartValue  float64
+	Workers     int // Defaults to runtime.NumCPU()
+}
+
+// ProjectionBand holds the percentile values of the simulated paths on one day.
+type ProjectionBand struct {
+	Day         int       `json:"day"`
+	Percentiles []float64 `json:"percentiles"` // Same order as ProjectionPercentiles
+}
+
+// Projection is the result of a Monte Carlo simulation.
+type Projection struct {
+	StartValue     float64          `json:"start_value"`
+	Drift          float64          `json:"drift"`      // Mean daily log return
+	Volatility     float64          `json:"volatility"` // Standard deviation of daily log returns
+	Observations   int              `json:"observations"`
+	HorizonDays    int              `json:"horizon_days"`
+	Paths          int              `json:"paths"` // Paths actually simulated
+	RequestedPaths int              `json:"requested_paths"`
+	Truncated      bool             `json:"truncated"` // The deadline hit before every path finished
+	Percentiles    []float64        `json:"percentiles"`
+	Bands          []ProjectionBand `json:"bands"`
+}
+
+// EstimateDrift computes the mean and standard deviation of the daily log returns of the
+// weighted portfolio, using only the days on which every holding has a return.
+func EstimateDrift(holdings []Holding, history map[string][]models.PricePoint) (drift, vol float64, n int, err error) {
+	totalWeight := 0.0
+	for _, h := range holdings {
+		totalWeight += h.Weight
+	}
+	if totalWeight <= 0 {
+		return 0, 0, 0, errors.New("holding weights must add up to a positive number")
+	}
+
+	var portfolio map[time.Time]float64
+	for _, h := range holdings {
+		returns := dailyReturns(history[h.Ticker])
+		w := h.Weight / totalWeight
+		if portfolio == nil {
+			portfolio = make(map[time.Time]float64, len(returns))
+			for day, r := range returns {
+				portfolio[day] = w * r
+			}
+			continue
+		}
+		for day := range portfolio {
+			r, ok := returns[day]
+			if !ok {
+				delete(portfolio, day)
+				continue
+			}
+			portfolio[day] += w * r
+		}
+	}
+
+	logReturns := make([]float64, 0, len(portfolio))
+	for _, r := range portfolio {
+		if r > -1 {
+			logReturns = append(logReturns, math.Log1p(r))
+		}
+	}
+	n = len(logReturns)
+	if n < MinCorrelationObservations {
+		return 0, 0, n, ErrInsufficientHistory
+	}
+
+	for _, r := range logReturns {
+		drift += r
+	}
+	drift /= float64(n)
+	for _, r := range logReturns {
+		vol += (r - drift) * (r - drift)
+	}
+	vol = math.Sqrt(vol / float64(n-1))
+	return drift, vol, n, nil
+}
+
+// Project runs a Monte Carlo simulation of geometric Brownian motion with the given daily
+// drift and volatility. Paths are split across a pool of workers; when ctx expires the
+// simulation stops and the bands are computed from the paths that finished.
+func Project(ctx context.Context, p ProjectionParams, drift, vol float64) (Projection, error) {
+	workers := p.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	workers = min(workers, p.Paths)
+
+	// values[day][path] holds the simulated value at the end of each day.
+	values := make([][]float64, p.HorizonDays)
+	for d := range values {
+		values[d] = make([]float64, p.Paths)
+	}
+	done := make([]bool, p.Paths)
+
+	paths := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range paths {
+				// Seeding per path keeps results independent of how paths land on workers.
+				rng := rand.New(rand.NewSource(p.Seed*1_000_003 + int64(path)))
+				value := p.StartValue
+				for d := 0; d < p.HorizonDays; d++ {
+					value *= math.Exp(drift - vol*vol/2 + vol*rng.NormFloat64())
+					values[d][path] = value
+				}
+				done[path] = true
+			}
+		}()
+	}
+
+	truncated := false
+feed:
+	for path := 0; path < p.Paths; path++ {
+		select {
+		case paths <- path:
+		case <-ctx.Done():
+			truncated = true
+			break feed
+		}
+	}
+	close(paths)
+	wg.Wait()
+
+	completed := 0
+	for _, ok := range done {
+		if ok {
+			completed++
+		}
+	}
+	if completed == 0 {
+		return Projection{}, ctx.Err()
+	}
+
+	bands := make([]ProjectionBand, p.HorizonDays)
+	sample := make([]float64, 0, completed)
+	for d := range values {
+		sample = sample[:0]
+		for path, v := range values[d] {
+			if done[path] {
+				sample = append(sample, v)
+			}
+		}
+		sort.Float64s(sample)
+		band := ProjectionBand{Day: d + 1, Percentiles: make([]float64, len(ProjectionPercentiles))}
+		for i, pct := range ProjectionPercentiles {
+			band.Percentiles[i] = percentile(sample, pct)
+		}
+		bands[d] = band
+	}
+
+	return Projection{
+		StartValue:     p.StartValue,
+		Drift:          drift,
+		Volatility:     vol,
+		HorizonDays:    p.HorizonDays,
+		Paths:          completed,
+		RequestedPaths: p.Paths,
+		Truncated:      truncated,
+		Percentiles:    ProjectionPercentiles,
+		Bands:          bands,
+	}, nil
+}
+
+// percentile returns the pct-th percentile of sorted using linear interpolation.
+func percentile(sorted []float64, pct float64) float64 {
+	if len(sorted) == 1 {
+		return sorted[0]
+	}
+	pos := pct / 100 * float64(len(sorted)-1)
+	lo := int(math.Floor(pos))
+	hi := min(lo+1, len(sorted)-1)
+	return sorted[lo] + (sorted[hi]-sorted[lo])*(pos-float64(lo))
+}
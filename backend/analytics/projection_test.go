@@ -0,0 +1,76 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+func TestEstimateDrift(t *testing.T) {
+	history := map[string][]models.PricePoint{
+		"AAA": series("AAA", 100, 101, 102.01, 103.0301, 104.060401), // +1% daily
+		"BBB": series("BBB", 10, 10.1, 10.201, 10.30301, 10.4060401),
+	}
+
+	drift, vol, n, err := EstimateDrift([]Holding{{Ticker: "AAA", Weight: 3}, {Ticker: "BBB", Weight: 1}}, history)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if n != 4 || math.Abs(drift-math.Log(1.01)) > 1e-9 || vol > 1e-9 {
+		t.Errorf("Expected drift ln(1.01) with zero volatility over 4 returns, got drift=%v vol=%v n=%d", drift, vol, n)
+	}
+
+	_, _, _, err = EstimateDrift([]Holding{{Ticker: "AAA", Weight: 1}, {Ticker: "ZZZ", Weight: 1}}, history)
+	if !errors.Is(err, ErrInsufficientHistory) {
+		t.Errorf("Expected ErrInsufficientHistory without shared days, got %v", err)
+	}
+}
+
+func TestProject(t *testing.T) {
+	params := ProjectionParams{HorizonDays: 20, Paths: 2000, Seed: 42, StartValue: 100, Workers: 4}
+
+	first, err := Project(context.Background(), params, 0.0005, 0.02)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if first.Truncated || first.Paths != 2000 || len(first.Bands) != 20 {
+		t.Fatalf("Unexpected projection shape: paths=%d bands=%d truncated=%t", first.Paths, len(first.Bands), first.Truncated)
+	}
+	for _, band := range first.Bands {
+		for i := 1; i < len(band.Percentiles); i++ {
+			if band.Percentiles[i] < band.Percentiles[i-1] {
+				t.Fatalf("Expected increasing percentiles on day %d, got %v", band.Day, band.Percentiles)
+			}
+		}
+	}
+	last := first.Bands[len(first.Bands)-1].Percentiles
+	if last[2] < 95 || last[2] > 105 {
+		t.Errorf("Expected the median to stay near the start value, got %v", last[2])
+	}
+
+	// The same seed must produce the same bands regardless of worker scheduling.
+	params.Workers = 1
+	second, _ := Project(context.Background(), params, 0.0005, 0.02)
+	if !reflect.DeepEqual(first.Bands, second.Bands) {
+		t.Errorf("Expected identical bands for the same seed")
+	}
+}
+
+func TestProject_CancelledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// With the deadline already gone the simulation stops early: either no path finished
+	// (error) or the result is marked as truncated.
+	projection, err := Project(ctx, ProjectionParams{HorizonDays: 5, Paths: 100, StartValue: 1}, 0, 0.01)
+	if err != nil && !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if err == nil && !projection.Truncated {
+		t.Errorf("Expected a truncated projection, got %d paths", projection.Paths)
+	}
+}
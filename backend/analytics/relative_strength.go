@@ -0,0 +1,57 @@
+package analytics
+
+import (
+	"time"
+
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// maxCloseGap is how old a close can be to stand for a day without one, so weekends,
+// holidays and skipped enrichments do not leave a period without a return.
+const maxCloseGap = 7 * 24 * time.Hour
+
+// PeriodReturn computes the return of points, sorted by day, over the days calendar days
+// up to asOf: from the last close on or before the start of the period to the last close
+// on or before asOf. It reports false when either close is missing or older than
+// maxCloseGap, which happens until the history covers the whole period.
+func PeriodReturn(points []models.PricePoint, asOf time.Time, days int) (float64, bool) {
+	start := asOf.AddDate(0, 0, -days)
+	base, ok := closeOn(points, start)
+	if !ok || base == 0 {
+		return 0, false
+	}
+	last, ok := closeOn(points, asOf)
+	if !ok {
+		return 0, false
+	}
+	return last/base - 1, true
+}
+
+// closeOn returns the last close on or before day, if it is at most maxCloseGap older.
+func closeOn(points []models.PricePoint, day time.Time) (float64, bool) {
+	for i := len(points) - 1; i >= 0; i-- {
+		p := points[i]
+		if p.TradingDay.After(day) {
+			continue
+		}
+		if day.Sub(p.TradingDay) > maxCloseGap {
+			return 0, false
+		}
+		return p.Close, true
+	}
+	return 0, false
+}
+
+// RelativeStrength is the return of stock minus that of benchmark over days calendar days
+// up to asOf, in percentage points. It is null when either return is unknown.
+func RelativeStrength(stock, benchmark []models.PricePoint, asOf time.Time, days int) models.NullFloat64 {
+	stockReturn, ok := PeriodReturn(stock, asOf, days)
+	if !ok {
+		return models.NullFloat64{}
+	}
+	benchmarkReturn, ok := PeriodReturn(benchmark, asOf, days)
+	if !ok {
+		return models.NullFloat64{}
+	}
+	return models.NewNullFloat64((stockReturn - benchmarkReturn) * 100)
+}
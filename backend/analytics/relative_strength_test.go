@@ -0,0 +1,45 @@
+package analytics
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+func TestPeriodReturn(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2025, 1, d, 0, 0, 0, 0, time.UTC) }
+	points := []models.PricePoint{
+		{TradingDay: day(2), Close: 100},
+		{TradingDay: day(3), Close: 102}, // Friday: stands for the weekend
+		{TradingDay: day(31), Close: 110},
+	}
+
+	// The period starts on Sunday 5 January, so its base is Friday's close.
+	if r, ok := PeriodReturn(points, day(31), 26); !ok || math.Abs(r-(110.0/102-1)) > 1e-12 {
+		t.Errorf("Expected the return from the Friday close, got %v (%v)", r, ok)
+	}
+	// The history does not reach back 90 days.
+	if _, ok := PeriodReturn(points, day(31), 90); ok {
+		t.Error("Expected no return before the history covers the period")
+	}
+	// The closest close to the start of the period is more than a week old.
+	if _, ok := PeriodReturn(points, day(31), 15); ok {
+		t.Error("Expected no return across a gap in the history")
+	}
+}
+
+func TestRelativeStrength(t *testing.T) {
+	start, end := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC)
+	stock := []models.PricePoint{{TradingDay: start, Close: 50}, {TradingDay: end, Close: 60}}
+	etf := []models.PricePoint{{TradingDay: start, Close: 200}, {TradingDay: end, Close: 210}}
+
+	// The stock gained 20% and its sector 5%.
+	if rs := RelativeStrength(stock, etf, end, 30); !rs.Valid || math.Abs(rs.Float64-15) > 1e-9 {
+		t.Errorf("Expected a relative strength of 15 points, got %+v", rs)
+	}
+	if rs := RelativeStrength(stock, nil, end, 30); rs.Valid {
+		t.Errorf("Expected null without the sector history, got %+v", rs)
+	}
+}
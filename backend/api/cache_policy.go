@@ -0,0 +1,127 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jannin2/stock-app/backend/auth"
+)
+
+// Valores de Cache-Control usados por las políticas de caché.
+const (
+	cacheNoStore = "no-store"
+	cachePrivate = "private, no-store" // Respuestas con datos exclusivos de administrador o de un usuario
+)
+
+// cachePolicies declara el Cache-Control de cada ruta (patrón de chi). Un patrón terminado
+// en "/*" se aplica a todas las rutas bajo ese prefijo. Las rutas sin política no envían
+// Cache-Control. Las sondas de salud (/healthz, /readyz) fijan no-store por su cuenta.
+var cachePolicies = map[string]string{
+	"/api/v1/stocks":                "public, max-age=30",
+	"/api/v1/stocks/{id}":           "public, max-age=30",
+	"/api/v1/stocks/{id}/history":   "public, max-age=300", // Solo cambia el último punto, en cada ejecución del enricher
+	"/api/v1/stocks/{id}/ohlc":      "public, max-age=300",
+	"/api/v1/stocks/recommended":    "public, max-age=60",
+	"/api/v1/stocks/aggregates":     "public, max-age=60",
+	"/api/v1/stocks/export":         cacheNoStore,           // Se reanuda con Range/If-Range, no con cachés intermedias
+	"/api/v1/stocks/schema":         "public, max-age=3600", // Solo cambia con un despliegue
+	"/api/v1/quotes":                "public, max-age=15",   // Widgets que refrescan cada 15 segundos
+	"/api/v1/status":                "public, max-age=15",
+	"/api/v1/openapi.json":          "public, max-age=3600", // Solo cambia con un despliegue
+	"/api/v1/docs":                  "public, max-age=3600",
+	"/api/v1/config":                "public, max-age=60", // Cambia con una recarga y markets con la hora
+	"/api/v1/stream/poll":           cacheNoStore,         // Cada respuesta depende de since_seq y de lo publicado mientras esperaba
+	"/api/v1/market/heatmap":        "public, max-age=60",
+	"/api/v1/market/movers":         "public, max-age=60", // Varía con ?tz= o, sin él, con el usuario
+	"/api/v1/market/exchanges":      "public, max-age=60", // is_open cambia con la hora
+	"/api/v1/analytics/correlation": "public, max-age=300",
+	"/api/v1/analytics/brokerages":  "public, max-age=300",
+	"/api/v1/ipos":                  "public, max-age=300",
+	"/api/v1/macro/events":          "public, max-age=300",
+	"/api/v1/screens/presets":       "public, max-age=3600", // Solo cambian con un despliegue
+	"/api/v1/auth/*":                cacheNoStore,           // Respuestas con claves de API
+	"/api/v1/me/*":                  cachePrivate,           // Datos del usuario autenticado
+	"/api/v1/watchlists/*":          cachePrivate,
+	"/api/v1/alerts/*":              cachePrivate,
+	"/api/v1/admin/*":               cacheNoStore,
+}
+
+// cachePolicyFor devuelve el Cache-Control declarado para pattern, si existe.
+func cachePolicyFor(pattern string) (string, bool) {
+	if policy, ok := cachePolicies[pattern]; ok {
+		return policy, true
+	}
+	for prefix, policy := range cachePolicies {
+		if strings.HasSuffix(prefix, "/*") && strings.HasPrefix(pattern, strings.TrimSuffix(prefix, "*")) {
+			return policy, true
+		}
+	}
+	return "", false
+}
+
+// CacheHeaders aplica la política de cachePolicies de la ruta resuelta por chi.
+// El patrón solo se conoce después del enrutamiento, así que la cabecera se fija justo
+// antes de escribir la respuesta. Solo las respuestas 2xx a GET/HEAD pueden cachearse; las
+// demás, y las de peticiones con scope de administrador, se marcan como no cacheables.
+func CacheHeaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(&cacheHeaderWriter{ResponseWriter: w, r: r}, r)
+	})
+}
+
+type cacheHeaderWriter struct {
+	http.ResponseWriter
+	r           *http.Request
+	wroteHeader bool
+}
+
+func (cw *cacheHeaderWriter) WriteHeader(status int) {
+	if !cw.wroteHeader {
+		cw.wroteHeader = true
+		cw.applyPolicy(status)
+	}
+	cw.ResponseWriter.WriteHeader(status)
+}
+
+func (cw *cacheHeaderWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+// Unwrap permite a http.ResponseController acceder al ResponseWriter original.
+func (cw *cacheHeaderWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+func (cw *cacheHeaderWriter) applyPolicy(status int) {
+	header := cw.Header()
+	if header.Get("Cache-Control") != "" {
+		return // El handler decidió su propia política
+	}
+
+	rctx := chi.RouteContext(cw.r.Context())
+	if rctx == nil {
+		return
+	}
+	policy, ok := cachePolicyFor(rctx.RoutePattern())
+	if !ok {
+		return
+	}
+
+	cacheable := (cw.r.Method == http.MethodGet || cw.r.Method == http.MethodHead) && status >= 200 && status < 300
+	switch {
+	case !cacheable:
+		policy = cacheNoStore
+	case policy != cacheNoStore && auth.ScopeFromContext(cw.r.Context()) >= auth.ScopeAdmin:
+		policy = cachePrivate
+	}
+
+	header.Set("Cache-Control", policy)
+	if policy != cacheNoStore && policy != cachePrivate {
+		// Las respuestas dependen del scope, que se decide con estas cabeceras.
+		header.Add("Vary", auth.AdminKeyHeader)
+	}
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jannin2/stock-app/backend/auth"
+)
+
+func TestCacheHeaders(t *testing.T) {
+	t.Setenv("ADMIN_API_KEY", "secreto")
+
+	ok := func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("{}")) }
+	r := chi.NewRouter()
+	r.Route("/api/v1", func(r chi.Router) {
+		r.Use(auth.Middleware)
+		r.Use(CacheHeaders)
+		r.Route("/stocks", func(r chi.Router) {
+			r.Get("/", ok)
+			r.Get("/{id}", func(w http.ResponseWriter, r *http.Request) {
+				if chi.URLParam(r, "id") == "missing" {
+					http.Error(w, "no encontrado", http.StatusNotFound)
+					return
+				}
+				ok(w, r)
+			})
+			r.Get("/recommended", ok)
+		})
+		r.Get("/admin/config", ok)
+		r.Get("/other", ok)
+	})
+
+	tests := []struct {
+		name     string
+		path     string
+		adminKey string
+		want     string
+	}{
+		{"listado", "/api/v1/stocks/", "", "public, max-age=30"},
+		{"detalle", "/api/v1/stocks/abc", "", "public, max-age=30"},
+		{"recomendados", "/api/v1/stocks/recommended", "", "public, max-age=60"},
+		{"admin", "/api/v1/admin/config", "secreto", "no-store"},
+		{"scope admin en ruta pública", "/api/v1/stocks/recommended", "secreto", "private, no-store"},
+		{"error", "/api/v1/stocks/missing", "", "no-store"},
+		{"sin política", "/api/v1/other", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.adminKey != "" {
+				req.Header.Set(auth.AdminKeyHeader, tt.adminKey)
+			}
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			if got := rr.Header().Get("Cache-Control"); got != tt.want {
+				t.Errorf("❌ Cache-Control = %q, se esperaba %q", got, tt.want)
+			}
+		})
+	}
+}
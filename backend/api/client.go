@@ -2,31 +2,210 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
-	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/jannin2/stock-app/backend/apierror"
+	"github.com/jannin2/stock-app/backend/auth"
+	"github.com/jannin2/stock-app/backend/chaos"
+	"github.com/jannin2/stock-app/backend/clock"
 	"github.com/jannin2/stock-app/backend/handlers"
 	"github.com/jannin2/stock-app/backend/models"
 )
 
+var (
+	// ErrNoData indica que el proveedor respondió correctamente pero sin datos para el ticker.
+	ErrNoData = errors.New("el proveedor no tiene datos para el ticker")
+	// ErrRateLimited indica que el proveedor rechazó la petición por superar su límite de uso.
+	ErrRateLimited = errors.New("límite de peticiones del proveedor superado")
+	// ErrMissingAPIKey indica que falta la clave de API del proveedor.
+	ErrMissingAPIKey = errors.New("no está configurada")
+)
+
+// StatusError indica que el proveedor respondió con un estado HTTP distinto de 200.
+type StatusError struct {
+	StatusCode int
+	msg        string
+}
+
+func (e *StatusError) Error() string { return e.msg }
+
+// Is permite comprobar un 429 con errors.Is(err, ErrRateLimited) y un 401 o 403 con
+// ErrUnauthorized.
+func (e *StatusError) Is(target error) bool {
+	switch target {
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	}
+	return false
+}
+
+func newStatusError(statusCode int, format string, args ...interface{}) *StatusError {
+	return &StatusError{StatusCode: statusCode, msg: fmt.Sprintf(format, args...)}
+}
+
 const (
 	KARENAI_API_URL        = "https://api.karenai.click/swechallenge/list"
 	FINNHUB_BASE_URL       = "https://finnhub.io/api/v1"
 	ALPHA_VANTAGE_BASE_URL = "https://www.alphavantage.co/query"
 )
 
-func SetupRouter(r *chi.Mux, stockHandlers *handlers.StockHandlers) {
+func SetupRouter(r *chi.Mux, stockHandlers *handlers.StockHandlers, quoteHandlers *handlers.QuoteHandlers, streamHandlers *handlers.StreamHandlers, userHandlers *handlers.UserHandlers, statusHandlers *handlers.StatusHandlers, webhookHandlers *handlers.WebhookHandlers, watchlistHandlers *handlers.WatchlistHandlers, jobHandlers *handlers.JobHandlers, responseCache *ResponseCache) {
+	root := r
+	// Antes de r.Route: chi copia estos manejadores a los subrouters al montarlos
+	r.NotFound(apierror.NotFound)
+	r.MethodNotAllowed(methodNotAllowed(root))
 	r.Route("/api/v1", func(r chi.Router) {
+		r.Use(auth.Middleware) // Determina el scope (público/usuario/admin) de cada petición
+		r.Use(NewRateLimiter(clock.New()).Middleware)
+		r.Use(chaos.Middleware) // Antes de CacheHeaders para que un fallo inyectado nunca se cachee
+		r.Use(CacheHeaders)     // Cache-Control según cachePolicies
+		r.Use(Deprecations)     // Avisos de lo declarado en deprecations
+
 		r.Route("/stocks", func(r chi.Router) {
-			r.Get("/", stockHandlers.GetStocks)
+			r.With(responseCache.Fallback, responseCache.Middleware).Get("/", stockHandlers.GetStocks)
+			r.Get("/export", stockHandlers.ExportStocks)
+			r.Get("/schema", handlers.GetStockSchema) // Campos por los que se puede filtrar y ordenar
+			r.Get("/aggregates", stockHandlers.GetStockAggregates)
 			r.Get("/{id}", stockHandlers.GetStockByID)
-			r.Get("/recommended", stockHandlers.GetRecommendedStocks)
+			r.Get("/{id}/history", stockHandlers.GetStockHistory)
+			r.Get("/{id}/ohlc", stockHandlers.GetStockCandles)
+			r.Get("/{ticker}/options-summary", stockHandlers.GetOptionsSummary)
+			r.Get("/{ticker}/corporate-actions", stockHandlers.GetCorporateActions)
+			r.With(responseCache.Fallback, responseCache.Middleware).Get("/recommended", stockHandlers.GetRecommendedStocks)
+			r.With(auth.RequireScope(auth.ScopeAdmin)).Post("/bulk", stockHandlers.BulkUpsertStocks)
+			r.Group(func(r chi.Router) {
+				r.Use(auth.RequireScope(auth.ScopeAdmin), responseCache.PurgeAfter)
+				r.Post("/", stockHandlers.CreateStock)
+				r.Put("/{id}", stockHandlers.UpdateStock)
+				r.Delete("/{id}", stockHandlers.DeleteStock)
+			})
+
+		})
 
+		r.Get("/status", statusHandlers.GetStatus)
+		r.Get("/openapi.json", handlers.OpenAPI(root)) // Especificación de todas las rutas de /api/v1
+		r.Get("/docs", handlers.SwaggerUI)
+		r.Get("/config", handlers.GetClientConfig) // Configuración no secreta para el frontend
+		r.Get("/quotes", quoteHandlers.GetQuotes)
+		r.Get("/stream", streamHandlers.Stream)    // WebSocket con las actualizaciones de los tickers suscritos
+		r.Get("/stream/poll", streamHandlers.Poll) // Lo mismo por long-polling, para redes que bloquean WebSocket
+		r.With(responseCache.Middleware).Get("/market/heatmap", stockHandlers.GetMarketHeatmap)
+		r.Get("/market/exchanges", handlers.GetExchanges)
+		r.Get("/ipos", stockHandlers.GetIPOs)
+		r.Get("/macro/events", stockHandlers.GetMacroEvents)
+		r.Get("/screens/presets", stockHandlers.GetScreenPresets)
+		// Estas rutas dependen de qué día es "hoy" para el cliente (?tz= o sus preferencias)
+		r.With(userHandlers.Timezone).Get("/market/movers", stockHandlers.GetMarketMovers)
+		r.With(userHandlers.Timezone).Get("/analytics/correlation", stockHandlers.GetCorrelation)
+		r.With(responseCache.Middleware).Get("/analytics/brokerages", stockHandlers.GetBrokerageStats)
+		r.With(userHandlers.Timezone).Post("/analytics/projection", stockHandlers.RunProjection)
+		r.Post("/scoring/what-if", stockHandlers.ScoreWhatIf)
+
+		r.Route("/auth", func(r chi.Router) {
+			r.Post("/register", userHandlers.Register)
+			r.Post("/login", userHandlers.Login)
+			r.Post("/verify-email", userHandlers.VerifyEmail)
+			r.Post("/verify-email/resend", userHandlers.ResendVerification)
+			r.Post("/password/forgot", userHandlers.ForgotPassword)
+			r.Post("/password/reset", userHandlers.ResetPassword)
+		})
+
+		r.Route("/jobs", func(r chi.Router) {
+			r.Use(auth.RequireScope(auth.ScopeUser))
+			r.Get("/{id}", jobHandlers.GetJob)
+			r.Get("/{id}/result", jobHandlers.DownloadJobResult)
+		})
+
+		r.Route("/webhooks", func(r chi.Router) {
+			// Avisos de los proveedores: se autentican con la firma, no con el usuario
+			r.Post("/incoming/karenai", webhookHandlers.KarenaiPush)
+
+			r.Group(func(r chi.Router) {
+				r.Use(auth.RequireScope(auth.ScopeUser))
+				r.Post("/", webhookHandlers.CreateWebhook)
+				r.Get("/", webhookHandlers.ListWebhooks)
+				r.Delete("/{id}", webhookHandlers.DeleteWebhook)
+				r.Post("/{id}/test", webhookHandlers.TestWebhook)
+			})
+		})
+
+		r.Route("/alerts", func(r chi.Router) {
+			r.Use(auth.RequireScope(auth.ScopeUser))
+			r.Post("/", userHandlers.CreateAlert)
+			r.Get("/", userHandlers.ListAlerts)
+			r.Get("/{id}", userHandlers.GetAlert)
+			r.Put("/{id}", userHandlers.UpdateAlert)
+			r.Delete("/{id}", userHandlers.DeleteAlert)
+		})
+
+		r.Route("/watchlists", func(r chi.Router) {
+			r.Use(auth.RequireScope(auth.ScopeUser))
+			r.Post("/", userHandlers.CreateWatchlist) // El mismo que POST /me/watchlists, con la cuota
+			r.Get("/", watchlistHandlers.ListWatchlists)
+			r.Get("/{id}", watchlistHandlers.GetWatchlist)
+			r.Patch("/{id}", watchlistHandlers.UpdateWatchlist)
+			r.Delete("/{id}", watchlistHandlers.DeleteWatchlist)
+			r.Get("/{id}/members", watchlistHandlers.ListWatchlistMembers)
+			r.Put("/{id}/members", watchlistHandlers.SetWatchlistMember)
+			r.Delete("/{id}/members/{userID}", watchlistHandlers.RemoveWatchlistMember)
+		})
+
+		r.Route("/me", func(r chi.Router) {
+			r.Use(auth.RequireScope(auth.ScopeUser))
+
+			r.Get("/data", userHandlers.ExportMyData)
+			r.Get("/export", userHandlers.RequestExport)
+			r.Get("/export/{id}", userHandlers.GetExport)
+			r.Get("/export/{id}/download", userHandlers.DownloadExport)
+			r.Delete("/", userHandlers.DeleteMe)
+			r.Get("/quotas", userHandlers.GetQuotas)
+			r.Post("/watchlists", userHandlers.CreateWatchlist)
+			r.Get("/notifications", userHandlers.GetNotificationSettings)
+			r.Put("/notifications", userHandlers.PutNotificationSettings)
+			r.Post("/devices", userHandlers.RegisterDevice)
+			r.Get("/devices", userHandlers.ListDevices)
+			r.Delete("/devices/{id}", userHandlers.DeleteDevice)
+
+			r.Get("/2fa", userHandlers.GetTwoFactor)
+			r.Post("/2fa/enroll", userHandlers.EnrollTwoFactor)
+			r.Post("/2fa/confirm", userHandlers.ConfirmTwoFactor)
+			r.Post("/2fa/verify", userHandlers.VerifySecondFactor)
+			r.Delete("/2fa", userHandlers.DisableTwoFactor)
+
+			// Acciones sensibles: requieren haber verificado el 2FA hace poco
+			r.Post("/2fa/recovery-codes", userHandlers.RegenerateRecoveryCodes)
+			r.Post("/api-key", userHandlers.CreateAPIKey)
+			r.Delete("/portfolios/{id}", userHandlers.DeletePortfolio)
+		})
+
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(auth.RequireScope(auth.ScopeAdmin))
+
+			r.Get("/config", handlers.GetConfig)
+			r.Post("/config/reload", handlers.ReloadConfig)
+			r.Put("/stocks/{ticker}/enrichment-tier", stockHandlers.SetEnrichmentTier)
+			r.Get("/stocks/{ticker}/payloads", stockHandlers.GetProviderPayloads)
+			r.Get("/providers/stats", stockHandlers.GetProviderStats)
+			r.Get("/enrich/runs", stockHandlers.ListEnrichmentRuns)
+			r.Get("/enrich/runs/{id}", stockHandlers.GetEnrichmentRun)
+			r.Get("/enrich/runs/{id}/details", stockHandlers.GetEnrichmentRunDetails)
+			r.Get("/scoring/rules", stockHandlers.GetScoringRules)
+			r.Put("/scoring/rules", stockHandlers.SaveScoringRules)
+			r.Get("/import-mappings", stockHandlers.ListImportMappings)
+			r.Get("/import-mappings/{name}", stockHandlers.GetImportMapping)
+			r.Put("/import-mappings/{name}", stockHandlers.SaveImportMapping)
+			r.Delete("/import-mappings/{name}", stockHandlers.DeleteImportMapping)
+			r.Get("/audit", userHandlers.GetAuditLog)
 		})
 	})
 }
@@ -44,12 +223,122 @@ type FinnhubMetricResponse struct {
 		DividendYield    float64 `json:"dividendYieldAnnually"`
 		DividendYieldAlt float64 `json:"dividendYield"`
 		MarketCap        float64 `json:"marketCapitalization"`
+		AverageVolume10D float64 `json:"10DayAverageTradingVolume"` // En millones de acciones
 	} `json:"metric"`
 }
 
 type FinnhubQuoteResponse struct {
-	CurrentPrice float64 `json:"c"`
-	Timestamp    int64   `json:"t"`
+	CurrentPrice  float64 `json:"c"`
+	Open          float64 `json:"o"`
+	High          float64 `json:"h"`
+	Low           float64 `json:"l"`
+	PreviousClose float64 `json:"pc"`
+	Timestamp     int64   `json:"t"`
+}
+
+type FinnhubProfileResponse struct {
+	FinnhubIndustry string `json:"finnhubIndustry"`
+}
+
+// FinnhubSocialSentimentResponse son las menciones por hora de /stock/social-sentiment.
+type FinnhubSocialSentimentResponse struct {
+	Symbol  string                 `json:"symbol"`
+	Reddit  []FinnhubSocialMention `json:"reddit"`
+	Twitter []FinnhubSocialMention `json:"twitter"`
+}
+
+// FinnhubSocialMention son las menciones de una hora. AtTime tiene el formato
+// "2006-01-02 15:04:05" en UTC.
+type FinnhubSocialMention struct {
+	AtTime  string `json:"atTime"`
+	Mention int    `json:"mention"`
+}
+
+// FinnhubESGResponse son las puntuaciones ESG de /stock/esg, de 0 a 100.
+type FinnhubESGResponse struct {
+	Symbol           string  `json:"symbol"`
+	TotalESGScore    float64 `json:"totalESGScore"`
+	EnvironmentScore float64 `json:"environmentScore"`
+	SocialScore      float64 `json:"socialScore"`
+	GovernanceScore  float64 `json:"governanceScore"`
+}
+
+// FinnhubShortInterestResponse son los informes de posiciones cortas de
+// /stock/short-interest, uno por fecha de liquidación.
+type FinnhubShortInterestResponse struct {
+	Symbol string `json:"symbol"`
+	Data   []struct {
+		Date          string  `json:"date"` // "2006-01-02"
+		ShortInterest float64 `json:"shortInterest"`
+	} `json:"data"`
+}
+
+// FinnhubOptionChainResponse es la cadena de opciones de /stock/option-chain, resumida por
+// fecha de vencimiento. ImpliedVolatility viene en porcentaje.
+type FinnhubOptionChainResponse struct {
+	Code string `json:"code"`
+	Data []struct {
+		ExpirationDate    string  `json:"expirationDate"` // "2006-01-02"
+		ImpliedVolatility float64 `json:"impliedVolatility"`
+		PutVolume         float64 `json:"putVolume"`
+		CallVolume        float64 `json:"callVolume"`
+		PutOpenInterest   float64 `json:"putOpenInterest"`
+		CallOpenInterest  float64 `json:"callOpenInterest"`
+	} `json:"data"`
+}
+
+// FinnhubSplit es un split de /stock/split: FromFactor acciones pasan a ser ToFactor.
+type FinnhubSplit struct {
+	Symbol     string  `json:"symbol"`
+	Date       string  `json:"date"` // "2006-01-02"
+	FromFactor float64 `json:"fromFactor"`
+	ToFactor   float64 `json:"toFactor"`
+}
+
+// FinnhubSymbolChangeResponse son los cambios de ticker de /ca/symbol-change, de todo el
+// mercado.
+type FinnhubSymbolChangeResponse struct {
+	Data []struct {
+		AtDate    string `json:"atDate"` // "2006-01-02"
+		OldSymbol string `json:"oldSymbol"`
+		NewSymbol string `json:"newSymbol"`
+	} `json:"data"`
+}
+
+// FinnhubIPOCalendarResponse es el calendario de /calendar/ipo. Price es un rango
+// ("14.00-16.00") hasta que se fija el precio, y un único valor después.
+type FinnhubIPOCalendarResponse struct {
+	IPOCalendar []struct {
+		Date           string  `json:"date"` // "2006-01-02"
+		Exchange       string  `json:"exchange"`
+		Name           string  `json:"name"`
+		NumberOfShares float64 `json:"numberOfShares"`
+		Price          string  `json:"price"`
+		Status         string  `json:"status"`
+		Symbol         string  `json:"symbol"`
+	} `json:"ipoCalendar"`
+}
+
+// FinnhubEconomicCalendarResponse es el calendario de /calendar/economic. Time está en UTC
+// ("2006-01-02 15:04:05"); Actual es null hasta que se publica el dato.
+type FinnhubEconomicCalendarResponse struct {
+	EconomicCalendar []struct {
+		Actual   models.NullFloat64 `json:"actual"`
+		Country  string             `json:"country"`
+		Estimate models.NullFloat64 `json:"estimate"`
+		Event    string             `json:"event"`
+		Impact   string             `json:"impact"`
+		Prev     models.NullFloat64 `json:"prev"`
+		Time     string             `json:"time"`
+		Unit     string             `json:"unit"`
+	} `json:"economicCalendar"`
+}
+
+// FinnhubNewsItem es una noticia de /company-news. Datetime es un timestamp Unix.
+type FinnhubNewsItem struct {
+	Headline string `json:"headline"`
+	Source   string `json:"source"`
+	Datetime int64  `json:"datetime"`
 }
 
 // Consolidated struct for Finnhub data
@@ -57,7 +346,10 @@ type FinnhubData struct {
 	PE_Ratio             float64
 	DividendYield        float64
 	MarketCapitalization float64
+	AverageVolume        float64 // Volumen medio diario de los últimos 10 días, en millones de acciones
 	CurrentPrice         float64
+	Open, High, Low      float64 // Del día de LatestTradingDay; 0 si no se conocen
+	PreviousClose        float64
 	LatestTradingDay     time.Time
 	Error                error
 }
@@ -65,14 +357,26 @@ type FinnhubData struct {
 // Consolidated struct for Alpha Vantage data
 type AlphaVantageData struct {
 	Alpha            float64
+	Price            float64
+	Open, High, Low  float64 // Del día de LatestTradingDay; 0 si no se conocen
+	Volume           int64
+	PreviousClose    float64
 	LatestTradingDay time.Time
 	Error            error
 }
 
+// AlphaVantageOverview son los fundamentales de la función OVERVIEW de Alpha Vantage,
+// convertidos a las mismas unidades que usa Finnhub.
+type AlphaVantageOverview struct {
+	PERatio              float64 // PE ratio
+	DividendYield        float64 // En porcentaje (Alpha Vantage lo devuelve como fracción)
+	MarketCapitalization float64 // En millones de USD (Alpha Vantage lo devuelve en USD)
+}
+
 func GetRecommendationsFromKarenai() ([]models.Stock, error) {
-	karenaiAPIKey := os.Getenv("KARENAI_API_KEY")
-	if karenaiAPIKey == "" {
-		return nil, fmt.Errorf("KARENAI_API_KEY no está configurada en las variables de entorno. Necesaria para Karenai.click API.")
+	karenaiAPIKey, err := providerAPIKey("KARENAI_API_KEY")
+	if err != nil {
+		return nil, fmt.Errorf("%w en las variables de entorno. Necesaria para Karenai.click API.", err)
 	}
 
 	log.Println("DEBUG: Intentando obtener recomendaciones de Karenai.click desde:", KARENAI_API_URL)
@@ -85,8 +389,7 @@ func GetRecommendationsFromKarenai() ([]models.Stock, error) {
 	req.Header.Set("Authorization", "Bearer "+karenaiAPIKey)
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
+	resp, err := providerClient("karenai").Do(req)
 	if err != nil {
 		log.Printf("ERROR HTTP (Karenai.click): Falló la solicitud: %v", err)
 		return nil, fmt.Errorf("error al realizar la solicitud HTTP a Karenai.click: %w", err)
@@ -103,7 +406,7 @@ func GetRecommendationsFromKarenai() ([]models.Stock, error) {
 	log.Printf("DEBUG: Karenai.click API - Cuerpo de respuesta RAW: %s", string(body))
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("Karenai.click API devolvió un estado de error: %s - Cuerpo de respuesta: %s", resp.Status, string(body))
+		return nil, newStatusError(resp.StatusCode, "Karenai.click API devolvió un estado de error: %s - Cuerpo de respuesta: %s", resp.Status, string(body))
 	}
 
 	var karenaiResp karenaiResponse
@@ -117,114 +420,149 @@ func GetRecommendationsFromKarenai() ([]models.Stock, error) {
 	return karenaiResp.Items, nil
 }
 
-func GetFinnhubMetricsAndQuote(ticker string) (FinnhubData, error) {
-	finnhubAPIKey := os.Getenv("FINNHUB_API_KEY")
-	if finnhubAPIKey == "" {
-		return FinnhubData{Error: fmt.Errorf("FINNHUB_API_KEY no está configurada")}, fmt.Errorf("FINNHUB_API_KEY no está configurada")
+// GetFinnhubMetrics obtiene PE, rentabilidad por dividendo y capitalización (en millones de USD).
+func GetFinnhubMetrics(ticker string) (FinnhubData, error) {
+	finnhubAPIKey, err := providerAPIKey("FINNHUB_API_KEY")
+	if err != nil {
+		return FinnhubData{Error: err}, err
 	}
 
 	var finnhubData FinnhubData
-
-	// --- 1. Fetch Metrics (PE Ratio, Dividend Yield, Market Cap) ---
 	metricURL := fmt.Sprintf("%s/stock/metric?symbol=%s&metricType=all&token=%s", FINNHUB_BASE_URL, ticker, finnhubAPIKey)
-	log.Printf("DEBUG: Finnhub API (metrics) - Intentando obtener métricas para %s desde: %s", ticker, metricURL)
+	log.Printf("DEBUG: Finnhub API (metrics) - Intentando obtener métricas para %s desde: %s", ticker, redactURL(metricURL))
 
-	respMetrics, err := http.Get(metricURL)
-	if err != nil {
-		finnhubData.Error = fmt.Errorf("error al consultar métricas de Finnhub para %s: %w", ticker, err)
-		log.Printf("ERROR: Finnhub API (metrics) - Error al hacer la solicitud para %s: %v", ticker, err)
+	var metricData FinnhubMetricResponse
+	if err := getProviderJSON("Finnhub métricas", ticker, metricURL, &metricData); err != nil {
+		finnhubData.Error = err
+		return finnhubData, err
+	}
+
+	if metricData.Metric.PeExclExtraTTM != 0 {
+		finnhubData.PE_Ratio = metricData.Metric.PeExclExtraTTM
 	} else {
-		defer respMetrics.Body.Close()
-		bodyMetrics, err := io.ReadAll(respMetrics.Body)
-		if err != nil {
-			finnhubData.Error = fmt.Errorf("error al leer el cuerpo de la respuesta de Finnhub metrics: %w", err)
-			log.Printf("ERROR: Finnhub API (metrics) - Error al leer el cuerpo de la respuesta para %s: %v", ticker, err)
-		} else {
-			log.Printf("DEBUG: Finnhub API (metrics) - Estado HTTP para %s: %d %s", ticker, respMetrics.StatusCode, respMetrics.Status)
-			log.Printf("DEBUG: Finnhub API (metrics) - Cuerpo RAW para %s: %s", ticker, string(bodyMetrics))
+		finnhubData.PE_Ratio = metricData.Metric.PeRatio
+	}
+	if metricData.Metric.DividendYield != 0 {
+		finnhubData.DividendYield = metricData.Metric.DividendYield
+	} else {
+		finnhubData.DividendYield = metricData.Metric.DividendYieldAlt
+	}
+	finnhubData.MarketCapitalization = metricData.Metric.MarketCap
+	finnhubData.AverageVolume = metricData.Metric.AverageVolume10D
+	return finnhubData, nil
+}
 
-			if respMetrics.StatusCode != http.StatusOK {
-				finnhubData.Error = fmt.Errorf("Finnhub métricas API devolvió estado de error para %s: %s - Cuerpo: %s", ticker, respMetrics.Status, string(bodyMetrics))
-				log.Printf("ADVERTENCIA: %v", finnhubData.Error)
-			} else {
-				var metricData FinnhubMetricResponse
-				err = json.Unmarshal(bodyMetrics, &metricData)
-				if err != nil {
-					finnhubData.Error = fmt.Errorf("error al decodificar JSON de métricas de Finnhub para %s: %w", ticker, err)
-					log.Printf("ERROR: %v. Cuerpo: %s", finnhubData.Error, string(bodyMetrics))
-				} else {
-
-					if metricData.Metric.PeExclExtraTTM != 0 {
-						finnhubData.PE_Ratio = metricData.Metric.PeExclExtraTTM
-					} else {
-						finnhubData.PE_Ratio = metricData.Metric.PeRatio
-					}
-					if metricData.Metric.DividendYield != 0 {
-						finnhubData.DividendYield = metricData.Metric.DividendYield
-					} else {
-						finnhubData.DividendYield = metricData.Metric.DividendYieldAlt
-					}
-					finnhubData.MarketCapitalization = metricData.Metric.MarketCap
-				}
-			}
-		}
+// GetFinnhubQuote obtiene el precio actual, el cierre anterior y el día de la cotización.
+// Finnhub responde 200 con precio 0 para símbolos que no cotiza; en ese caso devuelve ErrNoData.
+func GetFinnhubQuote(ticker string) (FinnhubData, error) {
+	finnhubAPIKey, err := providerAPIKey("FINNHUB_API_KEY")
+	if err != nil {
+		return FinnhubData{Error: err}, err
 	}
 
+	var finnhubData FinnhubData
 	quoteURL := fmt.Sprintf("%s/quote?symbol=%s&token=%s", FINNHUB_BASE_URL, ticker, finnhubAPIKey)
-	log.Printf("DEBUG: Finnhub API (quote) - Intentando obtener cotización para %s desde: %s", ticker, quoteURL)
+	log.Printf("DEBUG: Finnhub API (quote) - Intentando obtener cotización para %s desde: %s", ticker, redactURL(quoteURL))
+
+	var quoteData FinnhubQuoteResponse
+	if err := getProviderJSON("Finnhub cotización", ticker, quoteURL, &quoteData); err != nil {
+		finnhubData.Error = err
+		return finnhubData, err
+	}
+	if quoteData.CurrentPrice == 0 {
+		finnhubData.Error = fmt.Errorf("Finnhub no devolvió cotización para %s: %w", ticker, ErrNoData)
+		return finnhubData, finnhubData.Error
+	}
+
+	finnhubData.CurrentPrice = quoteData.CurrentPrice
+	finnhubData.Open, finnhubData.High, finnhubData.Low = quoteData.Open, quoteData.High, quoteData.Low
+	finnhubData.PreviousClose = quoteData.PreviousClose
+	if quoteData.Timestamp != 0 {
+		finnhubData.LatestTradingDay = time.Unix(quoteData.Timestamp, 0)
+	}
+	return finnhubData, nil
+}
 
-	respQuote, err := http.Get(quoteURL)
+// getProviderJSON hace un GET a url y decodifica la respuesta JSON en v. name identifica
+// al proveedor y la operación en los mensajes de error.
+func getProviderJSON(name, ticker, url string, v interface{}) error {
+	resp, err := providerClient(providerForURL(url)).Get(url)
 	if err != nil {
-		finnhubData.Error = fmt.Errorf("error al consultar cotización de Finnhub para %s: %w. %v", ticker, err, finnhubData.Error) // Combine errors
-		log.Printf("ERROR: Finnhub API (quote) - Error al hacer la solicitud para %s: %v", ticker, err)
-	} else {
-		defer respQuote.Body.Close()
-		bodyQuote, err := io.ReadAll(respQuote.Body)
-		if err != nil {
-			finnhubData.Error = fmt.Errorf("error al leer el cuerpo de la respuesta de Finnhub quote: %w. %v", err, finnhubData.Error) // Combine errors
-			log.Printf("ERROR: Finnhub API (quote) - Error al leer el cuerpo de la respuesta de cotización para %s: %v", ticker, err)
-		} else {
-			log.Printf("DEBUG: Finnhub API (quote) - Estado HTTP para %s: %d %s", ticker, respQuote.StatusCode, respQuote.Status)
-			log.Printf("DEBUG: Finnhub API (quote) - Cuerpo RAW para %s: %s", ticker, string(bodyQuote))
+		err = redactRequestError(err)
+		log.Printf("ERROR: %s - Error al hacer la solicitud para %s: %v", name, ticker, err)
+		return fmt.Errorf("error al consultar %s para %s: %w", name, ticker, err)
+	}
+	defer resp.Body.Close()
 
-			if respQuote.StatusCode != http.StatusOK {
-				finnhubData.Error = fmt.Errorf("Finnhub cotización API devolvió estado de error para %s: %s - Cuerpo: %s. %v", ticker, respQuote.Status, string(bodyQuote), finnhubData.Error) // Combine errors
-				log.Printf("ADVERTENCIA: %v", finnhubData.Error)
-			} else {
-				var quoteData FinnhubQuoteResponse
-				err = json.Unmarshal(bodyQuote, &quoteData)
-				if err != nil {
-					finnhubData.Error = fmt.Errorf("error al decodificar JSON de cotización de Finnhub para %s: %w. %v", ticker, err, finnhubData.Error) // Combine errors
-					log.Printf("ERROR: %v. Cuerpo: %s", finnhubData.Error, string(bodyQuote))
-				} else {
-					finnhubData.CurrentPrice = quoteData.CurrentPrice
-
-					if quoteData.Timestamp != 0 {
-						finnhubData.LatestTradingDay = time.Unix(quoteData.Timestamp, 0)
-					}
-				}
-			}
-		}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		log.Printf("ERROR: %s - Error al leer el cuerpo de la respuesta para %s: %v", name, ticker, err)
+		return fmt.Errorf("error al leer el cuerpo de la respuesta de %s: %w", name, err)
 	}
+	log.Printf("DEBUG: %s - Estado HTTP para %s: %d %s", name, ticker, resp.StatusCode, resp.Status)
+	log.Printf("DEBUG: %s - Cuerpo RAW para %s: %s", name, ticker, string(body))
 
-	return finnhubData, finnhubData.Error
+	if resp.StatusCode != http.StatusOK {
+		err := newStatusError(resp.StatusCode, "%s API devolvió estado de error para %s: %s - Cuerpo: %s", name, ticker, resp.Status, string(body))
+		log.Printf("ADVERTENCIA: %v", err)
+		return err
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		err = fmt.Errorf("error al decodificar JSON de %s para %s: %w", name, ticker, err)
+		log.Printf("ERROR: %v. Cuerpo: %s", err, string(body))
+		return err
+	}
+	return nil
 }
 
-func GetAlphaAndLatestTradingDayFromAlphaVantage(ticker string) (AlphaVantageData, error) {
-	alphaVantageAPIKey := os.Getenv("ALPHA_VANTAGE_API_KEY")
-	if alphaVantageAPIKey == "" {
-		return AlphaVantageData{Error: fmt.Errorf("ALPHA_VANTAGE_API_KEY no está configurada")}, fmt.Errorf("ALPHA_VANTAGE_API_KEY no está configurada")
+// GetFinnhubSector obtiene el sector (finnhubIndustry) del perfil de la compañía en Finnhub.
+func GetFinnhubSector(ticker string) (string, error) {
+	finnhubAPIKey, err := providerAPIKey("FINNHUB_API_KEY")
+	if err != nil {
+		return "", err
+	}
+
+	profileURL := fmt.Sprintf("%s/stock/profile2?symbol=%s&token=%s", FINNHUB_BASE_URL, ticker, finnhubAPIKey)
+	log.Printf("DEBUG: Finnhub API (profile) - Intentando obtener perfil para %s", ticker)
+
+	resp, err := providerClient("finnhub").Get(profileURL)
+	if err != nil {
+		err = redactRequestError(err)
+		return "", fmt.Errorf("error al consultar el perfil de Finnhub para %s: %w", ticker, err)
 	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("error al leer el cuerpo de la respuesta de Finnhub profile: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", newStatusError(resp.StatusCode, "Finnhub perfil API devolvió estado de error para %s: %s - Cuerpo: %s", ticker, resp.Status, string(body))
+	}
+
+	var profile FinnhubProfileResponse
+	if err := json.Unmarshal(body, &profile); err != nil {
+		return "", fmt.Errorf("error al decodificar JSON de perfil de Finnhub para %s: %w", ticker, err)
+	}
+
+	return profile.FinnhubIndustry, nil
+}
 
-	time.Sleep(15 * time.Second)
+func GetAlphaAndLatestTradingDayFromAlphaVantage(ticker string) (AlphaVantageData, error) {
+	alphaVantageAPIKey, err := providerAPIKey("ALPHA_VANTAGE_API_KEY")
+	if err != nil {
+		return AlphaVantageData{Error: err}, err
+	}
 
 	url := fmt.Sprintf("%s?function=GLOBAL_QUOTE&symbol=%s&apikey=%s", ALPHA_VANTAGE_BASE_URL, ticker, alphaVantageAPIKey)
-	log.Printf("DEBUG: Alpha Vantage API - Intentando obtener datos para %s desde: %s", ticker, url)
+	log.Printf("DEBUG: Alpha Vantage API - Intentando obtener datos para %s desde: %s", ticker, redactURL(url))
 
 	var avData AlphaVantageData
 
-	resp, err := http.Get(url)
+	resp, err := providerClient("alphavantage").Get(url)
 	if err != nil {
+		err = redactRequestError(err)
 		avData.Error = fmt.Errorf("error al consultar Alpha Vantage para %s: %w", ticker, err)
 		log.Printf("ERROR: Alpha Vantage API - Error al hacer la solicitud para %s: %v", ticker, err)
 		return avData, avData.Error
@@ -242,7 +580,7 @@ func GetAlphaAndLatestTradingDayFromAlphaVantage(ticker string) (AlphaVantageDat
 	log.Printf("DEBUG: Alpha Vantage API - Cuerpo RAW para %s: %s", ticker, string(bodyBytes))
 
 	if resp.StatusCode != http.StatusOK {
-		avData.Error = fmt.Errorf("Alpha Vantage API devolvió estado de error para %s: %s. Cuerpo: %s", ticker, resp.Status, string(bodyBytes))
+		avData.Error = newStatusError(resp.StatusCode, "Alpha Vantage API devolvió estado de error para %s: %s. Cuerpo: %s", ticker, resp.Status, string(bodyBytes))
 		log.Printf("ADVERTENCIA: %v", avData.Error)
 		return avData, avData.Error
 	}
@@ -255,13 +593,14 @@ func GetAlphaAndLatestTradingDayFromAlphaVantage(ticker string) (AlphaVantageDat
 		return avData, avData.Error
 	}
 
+	// Alpha Vantage responde 200 con "Error Message" a los símbolos que no conoce.
 	if errorMessage, ok := avResponse["Error Message"].(string); ok {
-		avData.Error = fmt.Errorf("Alpha Vantage API error: %s", errorMessage)
+		avData.Error = fmt.Errorf("Alpha Vantage API error: %s (%w)", errorMessage, ErrNoData)
 		log.Printf("ADVERTENCIA: %v. Se usarán 0.0 para Alpha y fecha inválida.", avData.Error)
 		return avData, avData.Error
 	}
 	if note, ok := avResponse["Note"].(string); ok {
-		avData.Error = fmt.Errorf("Alpha Vantage API note/warning: %s", note)
+		avData.Error = fmt.Errorf("Alpha Vantage API note/warning: %s (%w)", note, ErrRateLimited)
 		log.Printf("ADVERTENCIA: %v. Se usarán 0.0 para Alpha y fecha inválida.", avData.Error)
 		return avData, avData.Error
 	}
@@ -281,10 +620,302 @@ func GetAlphaAndLatestTradingDayFromAlphaVantage(ticker string) (AlphaVantageDat
 			log.Printf("ADVERTENCIA: Alpha Vantage API - '07. latest trading day' no encontrado o vacío para %s.", ticker)
 		}
 
+		avData.Price = parseAlphaVantageNumber(globalQuote["05. price"])
+		avData.Open = parseAlphaVantageNumber(globalQuote["02. open"])
+		avData.High = parseAlphaVantageNumber(globalQuote["03. high"])
+		avData.Low = parseAlphaVantageNumber(globalQuote["04. low"])
+		avData.Volume = int64(parseAlphaVantageNumber(globalQuote["06. volume"]))
+		avData.PreviousClose = parseAlphaVantageNumber(globalQuote["08. previous close"])
+
 	} else {
-		avData.Error = fmt.Errorf("Alpha Vantage API - 'Global Quote' no encontrado en la respuesta para %s", ticker)
+		avData.Error = fmt.Errorf("Alpha Vantage API - 'Global Quote' no encontrado en la respuesta para %s: %w", ticker, ErrMalformedResponse)
 		log.Printf("ADVERTENCIA: %v", avData.Error)
 	}
 
 	return avData, avData.Error
 }
+
+// GetAlphaVantageOverview obtiene PE, rentabilidad por dividendo y capitalización de la
+// función OVERVIEW de Alpha Vantage. Devuelve ErrNoData si la respuesta no trae el símbolo.
+func GetAlphaVantageOverview(ticker string) (AlphaVantageOverview, error) {
+	alphaVantageAPIKey, err := providerAPIKey("ALPHA_VANTAGE_API_KEY")
+	if err != nil {
+		return AlphaVantageOverview{}, err
+	}
+
+	url := fmt.Sprintf("%s?function=OVERVIEW&symbol=%s&apikey=%s", ALPHA_VANTAGE_BASE_URL, ticker, alphaVantageAPIKey)
+	log.Printf("DEBUG: Alpha Vantage API (overview) - Intentando obtener fundamentales para %s desde: %s", ticker, redactURL(url))
+
+	var overview map[string]interface{}
+	if err := getProviderJSON("Alpha Vantage overview", ticker, url, &overview); err != nil {
+		return AlphaVantageOverview{}, err
+	}
+	if note, ok := overview["Note"].(string); ok {
+		return AlphaVantageOverview{}, fmt.Errorf("Alpha Vantage API note/warning: %s (%w)", note, ErrRateLimited)
+	}
+	if _, ok := overview["Symbol"]; !ok {
+		return AlphaVantageOverview{}, fmt.Errorf("Alpha Vantage no devolvió fundamentales para %s: %w", ticker, ErrNoData)
+	}
+
+	return AlphaVantageOverview{
+		PERatio:              parseAlphaVantageNumber(overview["PERatio"]),
+		DividendYield:        parseAlphaVantageNumber(overview["DividendYield"]) * 100,
+		MarketCapitalization: parseAlphaVantageNumber(overview["MarketCapitalization"]) / 1e6,
+	}, nil
+}
+
+// AlphaVantageBulkQuoteLimit es el máximo de símbolos por llamada a REALTIME_BULK_QUOTES.
+const AlphaVantageBulkQuoteLimit = 100
+
+// alphaVantageBulkResponse es la respuesta de la función REALTIME_BULK_QUOTES.
+type alphaVantageBulkResponse struct {
+	Message     string `json:"message"`
+	Information string `json:"Information"`
+	Note        string `json:"Note"`
+	Data        []struct {
+		Symbol        string `json:"symbol"`
+		Timestamp     string `json:"timestamp"` // "2006-01-02 15:04:05.000"
+		Open          string `json:"open"`
+		High          string `json:"high"`
+		Low           string `json:"low"`
+		Close         string `json:"close"`
+		Volume        string `json:"volume"`
+		PreviousClose string `json:"previous_close"`
+	} `json:"data"`
+}
+
+// GetAlphaVantageBulkQuotes obtiene en una sola llamada la cotización de hasta
+// AlphaVantageBulkQuoteLimit símbolos, por símbolo. Los símbolos que Alpha Vantage no
+// conoce no aparecen en el resultado. REALTIME_BULK_QUOTES es una función de pago: con
+// una clave gratuita devuelve un error que envuelve ErrUnauthorized.
+func GetAlphaVantageBulkQuotes(symbols []string) (map[string]AlphaVantageData, error) {
+	if len(symbols) == 0 || len(symbols) > AlphaVantageBulkQuoteLimit {
+		return nil, fmt.Errorf("se pueden pedir entre 1 y %d símbolos a la vez, se pidieron %d", AlphaVantageBulkQuoteLimit, len(symbols))
+	}
+	alphaVantageAPIKey, err := providerAPIKey("ALPHA_VANTAGE_API_KEY")
+	if err != nil {
+		return nil, err
+	}
+
+	joined := strings.Join(symbols, ",")
+	url := fmt.Sprintf("%s?function=REALTIME_BULK_QUOTES&symbol=%s&apikey=%s", ALPHA_VANTAGE_BASE_URL, joined, alphaVantageAPIKey)
+	log.Printf("DEBUG: Alpha Vantage API (bulk quotes) - Intentando obtener %d cotizaciones", len(symbols))
+
+	var bulk alphaVantageBulkResponse
+	if err := getProviderJSON("Alpha Vantage bulk quotes", joined, url, &bulk); err != nil {
+		return nil, err
+	}
+	if bulk.Note != "" {
+		return nil, fmt.Errorf("Alpha Vantage API note/warning: %s (%w)", bulk.Note, ErrRateLimited)
+	}
+	if bulk.Information != "" || (len(bulk.Data) == 0 && bulk.Message != "") {
+		return nil, fmt.Errorf("Alpha Vantage no permite cotizaciones en bloque con esta clave: %s%s (%w)", bulk.Information, bulk.Message, ErrUnauthorized)
+	}
+	if bulk.Data == nil {
+		return nil, fmt.Errorf("Alpha Vantage API - 'data' no encontrado en la respuesta de cotizaciones en bloque: %w", ErrMalformedResponse)
+	}
+
+	quotes := make(map[string]AlphaVantageData, len(bulk.Data))
+	for _, q := range bulk.Data {
+		price := parseAlphaVantageNumber(q.Close)
+		if q.Symbol == "" || price == 0 {
+			continue
+		}
+		data := AlphaVantageData{
+			Price:         price,
+			Open:          parseAlphaVantageNumber(q.Open),
+			High:          parseAlphaVantageNumber(q.High),
+			Low:           parseAlphaVantageNumber(q.Low),
+			Volume:        int64(parseAlphaVantageNumber(q.Volume)),
+			PreviousClose: parseAlphaVantageNumber(q.PreviousClose),
+		}
+		if len(q.Timestamp) >= len("2006-01-02") {
+			data.LatestTradingDay, _ = time.Parse("2006-01-02", q.Timestamp[:len("2006-01-02")])
+		}
+		quotes[strings.ToUpper(q.Symbol)] = data
+	}
+	return quotes, nil
+}
+
+// parseAlphaVantageNumber convierte los números que Alpha Vantage envía como texto
+// ("195.5000", "None", "-") en float64, devolviendo 0 si no son numéricos.
+func parseAlphaVantageNumber(v interface{}) float64 {
+	str, ok := v.(string)
+	if !ok {
+		return 0
+	}
+	f, err := strconv.ParseFloat(strings.TrimSuffix(str, "%"), 64)
+	if err != nil {
+		return 0
+	}
+	return f
+}
+
+// GetFinnhubCompanyNews obtiene las noticias de la compañía publicadas entre from y to
+// (fechas incluidas, según el calendario de Finnhub).
+func GetFinnhubCompanyNews(ticker string, from, to time.Time) ([]FinnhubNewsItem, error) {
+	finnhubAPIKey, err := providerAPIKey("FINNHUB_API_KEY")
+	if err != nil {
+		return nil, err
+	}
+
+	newsURL := fmt.Sprintf("%s/company-news?symbol=%s&from=%s&to=%s&token=%s",
+		FINNHUB_BASE_URL, ticker, from.Format("2006-01-02"), to.Format("2006-01-02"), finnhubAPIKey)
+	log.Printf("DEBUG: Finnhub API (news) - Intentando obtener noticias para %s", ticker)
+
+	var news []FinnhubNewsItem
+	if err := getProviderJSON("Finnhub noticias", ticker, newsURL, &news); err != nil {
+		return nil, err
+	}
+	return news, nil
+}
+
+// GetFinnhubSocialMentions obtiene las menciones por hora en Reddit y Twitter entre from y
+// to (fechas incluidas).
+func GetFinnhubSocialMentions(ticker string, from, to time.Time) (FinnhubSocialSentimentResponse, error) {
+	finnhubAPIKey, err := providerAPIKey("FINNHUB_API_KEY")
+	if err != nil {
+		return FinnhubSocialSentimentResponse{}, err
+	}
+
+	socialURL := fmt.Sprintf("%s/stock/social-sentiment?symbol=%s&from=%s&to=%s&token=%s",
+		FINNHUB_BASE_URL, ticker, from.Format("2006-01-02"), to.Format("2006-01-02"), finnhubAPIKey)
+	log.Printf("DEBUG: Finnhub API (social) - Intentando obtener menciones para %s", ticker)
+
+	var social FinnhubSocialSentimentResponse
+	if err := getProviderJSON("Finnhub menciones", ticker, socialURL, &social); err != nil {
+		return FinnhubSocialSentimentResponse{}, err
+	}
+	return social, nil
+}
+
+// GetFinnhubESG obtiene las puntuaciones ESG de la compañía. Finnhub responde 200 con
+// puntuación 0 para las compañías que no cubre; en ese caso devuelve ErrNoData.
+func GetFinnhubESG(ticker string) (FinnhubESGResponse, error) {
+	finnhubAPIKey, err := providerAPIKey("FINNHUB_API_KEY")
+	if err != nil {
+		return FinnhubESGResponse{}, err
+	}
+
+	esgURL := fmt.Sprintf("%s/stock/esg?symbol=%s&token=%s", FINNHUB_BASE_URL, ticker, finnhubAPIKey)
+	log.Printf("DEBUG: Finnhub API (esg) - Intentando obtener ESG para %s", ticker)
+
+	var esg FinnhubESGResponse
+	if err := getProviderJSON("Finnhub ESG", ticker, esgURL, &esg); err != nil {
+		return FinnhubESGResponse{}, err
+	}
+	if esg.TotalESGScore == 0 {
+		return FinnhubESGResponse{}, fmt.Errorf("Finnhub no devolvió ESG para %s: %w", ticker, ErrNoData)
+	}
+	return esg, nil
+}
+
+// GetFinnhubShortInterest obtiene los informes de posiciones cortas publicados entre from y
+// to (fechas incluidas).
+func GetFinnhubShortInterest(ticker string, from, to time.Time) (FinnhubShortInterestResponse, error) {
+	finnhubAPIKey, err := providerAPIKey("FINNHUB_API_KEY")
+	if err != nil {
+		return FinnhubShortInterestResponse{}, err
+	}
+
+	shortURL := fmt.Sprintf("%s/stock/short-interest?symbol=%s&from=%s&to=%s&token=%s",
+		FINNHUB_BASE_URL, ticker, from.Format("2006-01-02"), to.Format("2006-01-02"), finnhubAPIKey)
+	log.Printf("DEBUG: Finnhub API (short interest) - Intentando obtener posiciones cortas para %s", ticker)
+
+	var short FinnhubShortInterestResponse
+	if err := getProviderJSON("Finnhub posiciones cortas", ticker, shortURL, &short); err != nil {
+		return FinnhubShortInterestResponse{}, err
+	}
+	return short, nil
+}
+
+// GetFinnhubOptionChain obtiene la cadena de opciones del ticker.
+func GetFinnhubOptionChain(ticker string) (FinnhubOptionChainResponse, error) {
+	finnhubAPIKey, err := providerAPIKey("FINNHUB_API_KEY")
+	if err != nil {
+		return FinnhubOptionChainResponse{}, err
+	}
+
+	chainURL := fmt.Sprintf("%s/stock/option-chain?symbol=%s&token=%s", FINNHUB_BASE_URL, ticker, finnhubAPIKey)
+	log.Printf("DEBUG: Finnhub API (options) - Intentando obtener la cadena de opciones para %s", ticker)
+
+	var chain FinnhubOptionChainResponse
+	if err := getProviderJSON("Finnhub opciones", ticker, chainURL, &chain); err != nil {
+		return FinnhubOptionChainResponse{}, err
+	}
+	return chain, nil
+}
+
+// GetFinnhubSplits obtiene los splits del ticker entre from y to (fechas incluidas).
+func GetFinnhubSplits(ticker string, from, to time.Time) ([]FinnhubSplit, error) {
+	finnhubAPIKey, err := providerAPIKey("FINNHUB_API_KEY")
+	if err != nil {
+		return nil, err
+	}
+
+	splitURL := fmt.Sprintf("%s/stock/split?symbol=%s&from=%s&to=%s&token=%s",
+		FINNHUB_BASE_URL, ticker, from.Format("2006-01-02"), to.Format("2006-01-02"), finnhubAPIKey)
+	log.Printf("DEBUG: Finnhub API (splits) - Intentando obtener splits para %s", ticker)
+
+	var splits []FinnhubSplit
+	if err := getProviderJSON("Finnhub splits", ticker, splitURL, &splits); err != nil {
+		return nil, err
+	}
+	return splits, nil
+}
+
+// GetFinnhubSymbolChanges obtiene los cambios de ticker de todo el mercado entre from y to
+// (fechas incluidas).
+func GetFinnhubSymbolChanges(from, to time.Time) (FinnhubSymbolChangeResponse, error) {
+	finnhubAPIKey, err := providerAPIKey("FINNHUB_API_KEY")
+	if err != nil {
+		return FinnhubSymbolChangeResponse{}, err
+	}
+
+	changeURL := fmt.Sprintf("%s/ca/symbol-change?from=%s&to=%s&token=%s",
+		FINNHUB_BASE_URL, from.Format("2006-01-02"), to.Format("2006-01-02"), finnhubAPIKey)
+	log.Printf("DEBUG: Finnhub API (symbol change) - Intentando obtener cambios de ticker")
+
+	var changes FinnhubSymbolChangeResponse
+	if err := getProviderJSON("Finnhub cambios de ticker", "todo el mercado", changeURL, &changes); err != nil {
+		return FinnhubSymbolChangeResponse{}, err
+	}
+	return changes, nil
+}
+
+// GetFinnhubIPOCalendar obtiene las salidas a bolsa con fecha entre from y to (incluidas).
+func GetFinnhubIPOCalendar(from, to time.Time) (FinnhubIPOCalendarResponse, error) {
+	finnhubAPIKey, err := providerAPIKey("FINNHUB_API_KEY")
+	if err != nil {
+		return FinnhubIPOCalendarResponse{}, err
+	}
+
+	calendarURL := fmt.Sprintf("%s/calendar/ipo?from=%s&to=%s&token=%s",
+		FINNHUB_BASE_URL, from.Format("2006-01-02"), to.Format("2006-01-02"), finnhubAPIKey)
+	log.Printf("DEBUG: Finnhub API (IPO calendar) - Intentando obtener el calendario de IPOs")
+
+	var calendar FinnhubIPOCalendarResponse
+	if err := getProviderJSON("Finnhub calendario de IPOs", "todo el mercado", calendarURL, &calendar); err != nil {
+		return FinnhubIPOCalendarResponse{}, err
+	}
+	return calendar, nil
+}
+
+// GetFinnhubEconomicCalendar obtiene los eventos económicos con fecha entre from y to
+// (incluidas).
+func GetFinnhubEconomicCalendar(from, to time.Time) (FinnhubEconomicCalendarResponse, error) {
+	finnhubAPIKey, err := providerAPIKey("FINNHUB_API_KEY")
+	if err != nil {
+		return FinnhubEconomicCalendarResponse{}, err
+	}
+
+	calendarURL := fmt.Sprintf("%s/calendar/economic?from=%s&to=%s&token=%s",
+		FINNHUB_BASE_URL, from.Format("2006-01-02"), to.Format("2006-01-02"), finnhubAPIKey)
+	log.Printf("DEBUG: Finnhub API (economic calendar) - Intentando obtener el calendario económico")
+
+	var calendar FinnhubEconomicCalendarResponse
+	if err := getProviderJSON("Finnhub calendario económico", "todo el mercado", calendarURL, &calendar); err != nil {
+		return FinnhubEconomicCalendarResponse{}, err
+	}
+	return calendar, nil
+}
@@ -0,0 +1,159 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/jannin2/stock-app/backend/apierror"
+	"github.com/jannin2/stock-app/backend/auth"
+)
+
+// DegradedPaths son los listados que siguen respondiendo con la base de datos caída, desde
+// la última respuesta correcta (ver Fallback). RequireReady no los bloquea.
+var DegradedPaths = []string{"/api/v1/stocks", "/api/v1/stocks/recommended"}
+
+// DegradedHeader marca con "true" una respuesta servida desde la última copia correcta
+// porque la base de datos no está disponible. Va acompañada de staleWarning.
+const DegradedHeader = "X-Degraded"
+
+// staleWarning es la cabecera Warning de las respuestas degradadas (RFC 7234, 110).
+const staleWarning = `110 - "Response is Stale"`
+
+// SetReadiness indica a Fallback cómo saber si la base de datos está disponible. Sin
+// llamarla, Fallback solo sirve la copia cuando la consulta falla.
+func (c *ResponseCache) SetReadiness(ready func() bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ready = ready
+}
+
+// Fallback guarda la última respuesta correcta de cada consulta pública GET a next y, si la
+// base de datos no está disponible o next falla con un 5xx, sirve esa copia marcada con
+// DegradedHeader y Warning en lugar del error, para que el panel de solo lectura siga
+// funcionando durante caídas cortas. Sin copia, con la base de datos caída responde 503.
+// A diferencia de Middleware, las copias sobreviven a Purge y Warm: una copia antigua es
+// preferible a un error.
+func (c *ResponseCache) Fallback(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key := cacheKey(r)
+		c.mu.RLock()
+		ready := c.ready
+		c.mu.RUnlock()
+		if ready != nil && !ready() {
+			if !c.serveStale(w, key) {
+				w.Header().Set("Retry-After", "5")
+				apierror.HTTPError(w, "Servicio no disponible: la base de datos no está lista y no hay una copia de esta consulta", http.StatusServiceUnavailable)
+			}
+			return
+		}
+
+		// La respuesta se retiene hasta saber si es un error que hay que sustituir.
+		buf := &bufferedResponse{header: w.Header().Clone(), status: http.StatusOK}
+		next.ServeHTTP(buf, r)
+		if buf.status >= http.StatusInternalServerError && c.serveStale(w, key) {
+			return
+		}
+		if buf.status == http.StatusOK && auth.ScopeFromContext(r.Context()) == auth.ScopePublic {
+			c.storeLastGood(key, buf.cached())
+		}
+		buf.copyTo(w)
+	})
+}
+
+// serveStale escribe la última copia correcta de key, si la hay. Si es un objeto JSON (el
+// sobre de los listados paginados) lleva además "degraded": true.
+func (c *ResponseCache) serveStale(w http.ResponseWriter, key string) bool {
+	c.mu.RLock()
+	entry, ok := c.lastGood[key]
+	c.mu.RUnlock()
+	if !ok {
+		return false
+	}
+	for name, values := range entry.header {
+		w.Header()[name] = values
+	}
+	w.Header().Set(DegradedHeader, "true")
+	w.Header().Set("Warning", staleWarning)
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(entry.status)
+	w.Write(markDegraded(entry.body))
+	return true
+}
+
+// markDegraded añade "degraded": true a body si es un objeto JSON, conservando el orden de
+// sus campos. Los arrays se devuelven sin cambios: solo llevan DegradedHeader.
+func markDegraded(body []byte) []byte {
+	return appendJSONField(body, "degraded", []byte("true"))
+}
+
+// appendJSONField añade el campo name con el valor JSON value al final de body si es un
+// objeto JSON, conservando el orden de sus campos. Cualquier otro cuerpo se devuelve sin
+// cambios.
+func appendJSONField(body []byte, name string, value []byte) []byte {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) < 2 || trimmed[0] != '{' || trimmed[len(trimmed)-1] != '}' {
+		return body
+	}
+	marked := append([]byte(nil), trimmed[:len(trimmed)-1]...)
+	if len(bytes.TrimSpace(trimmed[1:len(trimmed)-1])) > 0 {
+		marked = append(marked, ',')
+	}
+	marked = append(marked, `"`+name+`":`...)
+	marked = append(marked, value...)
+	return append(marked, "}\n"...)
+}
+
+func (c *ResponseCache) storeLastGood(key string, entry cachedResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.lastGood[key]; ok || len(c.lastGood) < maxCachedResponses {
+		c.lastGood[key] = entry
+	}
+}
+
+// bufferedResponse retiene una respuesta completa en memoria.
+type bufferedResponse struct {
+	header      http.Header
+	status      int
+	body        bytes.Buffer
+	wroteHeader bool
+}
+
+func (b *bufferedResponse) Header() http.Header { return b.header }
+
+func (b *bufferedResponse) WriteHeader(status int) {
+	if !b.wroteHeader {
+		b.wroteHeader = true
+		b.status = status
+	}
+}
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	b.WriteHeader(http.StatusOK)
+	return b.body.Write(p)
+}
+
+// cached devuelve la respuesta con las cabeceras que se guardan (cachedHeaders).
+func (b *bufferedResponse) cached() cachedResponse {
+	header := http.Header{}
+	for _, name := range cachedHeaders {
+		if values := b.header.Values(name); len(values) > 0 {
+			header[http.CanonicalHeaderKey(name)] = append([]string(nil), values...)
+		}
+	}
+	return cachedResponse{status: b.status, header: header, body: append([]byte(nil), b.body.Bytes()...)}
+}
+
+// copyTo escribe la respuesta retenida en w.
+func (b *bufferedResponse) copyTo(w http.ResponseWriter) {
+	for name, values := range b.header {
+		w.Header()[name] = values
+	}
+	w.WriteHeader(b.status)
+	w.Write(b.body.Bytes())
+}
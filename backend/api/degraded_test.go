@@ -0,0 +1,86 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jannin2/stock-app/backend/auth"
+)
+
+func TestResponseCache_Fallback(t *testing.T) {
+	ready, failing := true, false
+	calls := 0
+	cache := NewResponseCache()
+	cache.SetReadiness(func() bool { return ready })
+	r := chi.NewRouter()
+	r.Route("/api/v1", func(r chi.Router) {
+		r.Use(auth.Middleware)
+		r.With(cache.Fallback, cache.Middleware).Get("/stocks", func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			if failing {
+				http.Error(w, "Error al obtener stocks", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("X-Total-Count", "2")
+			w.Write([]byte(`[{"ticker":"AAPL"},{"ticker":"MSFT"}]`))
+		})
+	})
+	get := func(path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		return rr
+	}
+
+	if rr := get("/api/v1/stocks"); rr.Code != http.StatusOK || rr.Header().Get(DegradedHeader) != "" {
+		t.Fatalf("❌ respuesta normal inesperada: %d %v", rr.Code, rr.Header())
+	}
+
+	// Purge (tras una ejecución del enricher) no descarta la copia; la consulta que falla se
+	// sustituye por ella.
+	cache.Purge()
+	failing = true
+	rr := get("/api/v1/stocks")
+	if rr.Code != http.StatusOK || rr.Header().Get(DegradedHeader) != "true" || rr.Header().Get("Warning") == "" {
+		t.Errorf("❌ se esperaba la copia degradada, se obtuvo %d %v", rr.Code, rr.Header())
+	}
+	if rr.Header().Get("X-Total-Count") != "2" || rr.Body.String() != `[{"ticker":"AAPL"},{"ticker":"MSFT"}]` {
+		t.Errorf("❌ copia degradada inesperada: %v %s", rr.Header(), rr.Body.String())
+	}
+	if got := rr.Header().Get("Cache-Control"); got != "no-store" {
+		t.Errorf("❌ Cache-Control = %q en una respuesta degradada", got)
+	}
+
+	// Con la base de datos caída no se llega a consultar.
+	ready = false
+	before := calls
+	if rr := get("/api/v1/stocks"); rr.Code != http.StatusOK || rr.Header().Get(DegradedHeader) != "true" || calls != before {
+		t.Errorf("❌ con la base de datos caída se esperaba la copia sin consultar: %d, %d llamadas", rr.Code, calls-before)
+	}
+
+	// Sin copia de esa consulta, 503.
+	if rr := get("/api/v1/stocks?limit=5"); rr.Code != http.StatusServiceUnavailable || rr.Header().Get("Retry-After") == "" {
+		t.Errorf("❌ sin copia se esperaba 503 con Retry-After, se obtuvo %d", rr.Code)
+	}
+
+	// Un error sin copia se devuelve tal cual.
+	ready = true
+	if rr := get("/api/v1/stocks?limit=10"); rr.Code != http.StatusInternalServerError {
+		t.Errorf("❌ sin copia se esperaba el error original, se obtuvo %d", rr.Code)
+	}
+}
+
+func TestMarkDegraded(t *testing.T) {
+	tests := []struct{ body, want string }{
+		{`{"data":[],"total":0}` + "\n", `{"data":[],"total":0,"degraded":true}` + "\n"},
+		{`{}`, `{"degraded":true}` + "\n"},
+		{`[{"ticker":"AAPL"}]`, `[{"ticker":"AAPL"}]`},
+	}
+	for _, tt := range tests {
+		if got := string(markDegraded([]byte(tt.body))); got != tt.want {
+			t.Errorf("❌ markDegraded(%s) = %s, se esperaba %s", tt.body, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jannin2/stock-app/backend/handlers"
+)
+
+// Deprecation es una parte de la API que dejará de funcionar: una ruta entera o, si Param o
+// Header no están vacíos, solo las peticiones que usan ese parámetro de la query o envían
+// esa cabecera.
+type Deprecation struct {
+	Param   string
+	Header  string
+	Since   time.Time // Desde cuándo está obsoleta
+	Sunset  time.Time // Cuándo se retirará; cero si aún no hay fecha
+	Link    string    // Documentación de la alternativa, opcional
+	Message string    // Qué usar en su lugar
+}
+
+// deprecations declara, por ruta (patrón de chi, como cachePolicies), lo que está obsoleto.
+// Para retirar algo se declara aquí con antelación, se espera a Sunset y solo entonces se
+// elimina del código.
+var deprecations = map[string][]Deprecation{
+	"/api/v1/stocks": {
+		{
+			Header:  handlers.LegacyPaginationHeader,
+			Since:   time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC),
+			Sunset:  time.Date(2027, 4, 16, 0, 0, 0, 0, time.UTC),
+			Message: "El listado como array con X-Total-Count está obsoleto; use el sobre de paginación (data, total y next_offset o next_cursor)",
+		},
+		{
+			Param:   "max_pe_ratio",
+			Since:   time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC),
+			Sunset:  time.Date(2027, 4, 16, 0, 0, 0, 0, time.UTC),
+			Message: "El parámetro max_pe_ratio está obsoleto; use max_pe",
+		},
+	},
+	"/api/v1/stocks/export": {
+		{
+			Param:   "max_pe_ratio",
+			Since:   time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC),
+			Sunset:  time.Date(2027, 4, 16, 0, 0, 0, 0, time.UTC),
+			Message: "El parámetro max_pe_ratio está obsoleto; use max_pe",
+		},
+	},
+}
+
+// applies indica si la petición r usa lo que d declara obsoleto.
+func (d Deprecation) applies(r *http.Request) bool {
+	switch {
+	case d.Param != "":
+		return r.URL.Query().Has(d.Param)
+	case d.Header != "":
+		return r.Header.Get(d.Header) != ""
+	}
+	return true
+}
+
+// deprecationWarning es un elemento del array warnings de las respuestas.
+type deprecationWarning struct {
+	Type    string `json:"type"` // Siempre "deprecation"
+	Message string `json:"message"`
+	Sunset  string `json:"sunset,omitempty"` // RFC 3339
+	Link    string `json:"link,omitempty"`
+}
+
+// Deprecations avisa a los clientes que usan partes obsoletas de la API (ver deprecations)
+// sin cambiar la respuesta: añade las cabeceras Deprecation (RFC 9745, la fecha más antigua),
+// Sunset (RFC 8594, la más próxima) y Link con rel="deprecation", y si la respuesta es un
+// objeto JSON, como los sobres de los listados, un array "warnings" con cada aviso. Como en
+// CacheHeaders, la ruta solo se conoce después del enrutamiento, así que se decide justo
+// antes de escribir la respuesta; solo entonces se retiene el cuerpo para añadir warnings.
+func Deprecations(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		dw := &deprecationWriter{ResponseWriter: w, r: r}
+		next.ServeHTTP(dw, r)
+		dw.finish()
+	})
+}
+
+type deprecationWriter struct {
+	http.ResponseWriter
+	r           *http.Request
+	wroteHeader bool
+	status      int
+	warnings    []deprecationWarning
+	body        *bytes.Buffer // No nil mientras se retiene el cuerpo para añadir warnings
+}
+
+func (dw *deprecationWriter) WriteHeader(status int) {
+	if dw.wroteHeader {
+		return
+	}
+	dw.wroteHeader = true
+	dw.status = status
+	dw.apply()
+	if dw.body == nil {
+		dw.ResponseWriter.WriteHeader(status)
+	}
+}
+
+func (dw *deprecationWriter) Write(b []byte) (int, error) {
+	if !dw.wroteHeader {
+		dw.WriteHeader(http.StatusOK)
+	}
+	if dw.body != nil {
+		return dw.body.Write(b)
+	}
+	return dw.ResponseWriter.Write(b)
+}
+
+// Unwrap permite a http.ResponseController acceder al ResponseWriter original.
+func (dw *deprecationWriter) Unwrap() http.ResponseWriter {
+	return dw.ResponseWriter
+}
+
+// apply fija las cabeceras de los avisos de la ruta y decide si retener el cuerpo.
+func (dw *deprecationWriter) apply() {
+	rctx := chi.RouteContext(dw.r.Context())
+	if rctx == nil {
+		return
+	}
+	var since, sunset time.Time
+	header := dw.Header()
+	for _, d := range deprecations[rctx.RoutePattern()] {
+		if !d.applies(dw.r) {
+			continue
+		}
+		if since.IsZero() || d.Since.Before(since) {
+			since = d.Since
+		}
+		warning := deprecationWarning{Type: "deprecation", Message: d.Message, Link: d.Link}
+		if !d.Sunset.IsZero() {
+			if sunset.IsZero() || d.Sunset.Before(sunset) {
+				sunset = d.Sunset
+			}
+			warning.Sunset = d.Sunset.UTC().Format(time.RFC3339)
+		}
+		if d.Link != "" {
+			header.Add("Link", fmt.Sprintf(`<%s>; rel="deprecation"`, d.Link))
+		}
+		dw.warnings = append(dw.warnings, warning)
+	}
+	if len(dw.warnings) == 0 {
+		return
+	}
+
+	header.Set("Deprecation", fmt.Sprintf("@%d", since.Unix()))
+	if !sunset.IsZero() {
+		header.Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+	}
+	if strings.HasPrefix(header.Get("Content-Type"), "application/json") {
+		header.Del("Content-Length") // El cuerpo cambia de longitud
+		dw.body = &bytes.Buffer{}
+	}
+}
+
+// finish escribe el cuerpo retenido con los warnings añadidos.
+func (dw *deprecationWriter) finish() {
+	if dw.body == nil {
+		return
+	}
+	dw.ResponseWriter.WriteHeader(dw.status)
+	warnings, _ := json.Marshal(dw.warnings) // Solo contiene cadenas
+	dw.ResponseWriter.Write(appendJSONField(dw.body.Bytes(), "warnings", warnings))
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jannin2/stock-app/backend/handlers"
+)
+
+func TestDeprecations(t *testing.T) {
+	r := chi.NewRouter()
+	r.Route("/api/v1", func(r chi.Router) {
+		r.Use(Deprecations)
+		r.Get("/stocks", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			if r.Header.Get(handlers.LegacyPaginationHeader) == "true" {
+				w.Write([]byte(`[{"ticker":"AAPL"}]` + "\n"))
+				return
+			}
+			w.Write([]byte(`{"data":[{"ticker":"AAPL"}],"total":1}` + "\n"))
+		})
+		r.Get("/quotes", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{}`))
+		})
+	})
+	get := func(path string, legacy bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if legacy {
+			req.Header.Set(handlers.LegacyPaginationHeader, "true")
+		}
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Sin nada obsoleto la respuesta no cambia
+	for _, path := range []string{"/api/v1/stocks?max_pe=12", "/api/v1/quotes?max_pe_ratio=12"} {
+		if rr := get(path, false); rr.Header().Get("Deprecation") != "" || strings.Contains(rr.Body.String(), "warnings") {
+			t.Errorf("❌ %s no usa nada obsoleto: %v %s", path, rr.Header(), rr.Body.String())
+		}
+	}
+
+	rr := get("/api/v1/stocks?max_pe_ratio=12", false)
+	if rr.Code != http.StatusOK || !strings.HasPrefix(rr.Header().Get("Deprecation"), "@") || rr.Header().Get("Sunset") == "" {
+		t.Fatalf("❌ cabeceras inesperadas: %d %v", rr.Code, rr.Header())
+	}
+	var page struct {
+		Total    int                  `json:"total"`
+		Warnings []deprecationWarning `json:"warnings"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &page); err != nil {
+		t.Fatalf("❌ JSON inválido: %v: %s", err, rr.Body.String())
+	}
+	if page.Total != 1 || len(page.Warnings) != 1 || !strings.Contains(page.Warnings[0].Message, "max_pe") || page.Warnings[0].Sunset == "" {
+		t.Errorf("❌ avisos inesperados: %+v", page)
+	}
+
+	// Un array no admite warnings: solo lleva las cabeceras
+	rr = get("/api/v1/stocks?max_pe_ratio=12", true)
+	if rr.Header().Get("Deprecation") == "" || rr.Body.String() != `[{"ticker":"AAPL"}]`+"\n" {
+		t.Errorf("❌ respuesta legacy inesperada: %v %s", rr.Header(), rr.Body.String())
+	}
+}
+
+func TestAppendJSONField(t *testing.T) {
+	tests := map[string]string{
+		`{"a":1}`:    `{"a":1,"w":[]}` + "\n",
+		" {}\n":      `{"w":[]}` + "\n",
+		`[1,2]`:      `[1,2]`,
+		`no es JSON`: `no es JSON`,
+	}
+	for body, want := range tests {
+		if got := string(appendJSONField([]byte(body), "w", []byte("[]"))); got != want {
+			t.Errorf("❌ appendJSONField(%q) = %q, se esperaba %q", body, got, want)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jannin2/stock-app/backend/apierror"
+)
+
+// allowMethods son los métodos que se comprueban para la cabecera Allow de un 405.
+var allowMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+	http.MethodPatch, http.MethodDelete, http.MethodOptions,
+}
+
+// methodNotAllowed responde 405 con el formato común de errores. El manejador por defecto
+// de chi indica en Allow los métodos de la ruta; como al reemplazarlo se pierde, se
+// calculan de nuevo preguntando a routes por cada método.
+func methodNotAllowed(routes chi.Routes) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var allowed []string
+		for _, method := range allowMethods {
+			if routes.Match(chi.NewRouteContext(), method, r.URL.Path) {
+				allowed = append(allowed, method)
+			}
+		}
+		if len(allowed) > 0 {
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+		}
+		apierror.HTTPError(w, "Método no permitido en esta ruta", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,42 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// TestRouterErrorsAreJSON comprueba que las rutas inexistentes y los métodos no admitidos
+// responden con el formato común de errores.
+func TestRouterErrorsAreJSON(t *testing.T) {
+	r := chi.NewRouter()
+	SetupRouter(r, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+
+	serve := func(method, path string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		var body map[string]interface{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("❌ %s %s no devolvió JSON: %v: %s", method, path, err, rec.Body)
+		}
+		return rec, body
+	}
+
+	for _, path := range []string{"/nada", "/api/v1/nada", "/api/v1/stocks/a/b/c"} {
+		rec, body := serve(http.MethodGet, path)
+		if rec.Code != http.StatusNotFound || body["code"] != "not_found" {
+			t.Errorf("❌ GET %s: estado %d, cuerpo %v; se esperaba 404 not_found", path, rec.Code, body)
+		}
+	}
+
+	rec, body := serve(http.MethodDelete, "/api/v1/openapi.json")
+	if rec.Code != http.StatusMethodNotAllowed || body["code"] != "method_not_allowed" {
+		t.Errorf("❌ DELETE /api/v1/openapi.json: estado %d, cuerpo %v; se esperaba 405 method_not_allowed", rec.Code, body)
+	}
+	if got := rec.Header().Get("Allow"); got != "GET" {
+		t.Errorf("❌ Allow = %q, se esperaba GET", got)
+	}
+}
@@ -0,0 +1,42 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jannin2/stock-app/backend/handlers"
+)
+
+// TestOpenAPICoversRoutes comprueba que cada ruta de /api/v1 está documentada y que no se
+// documentan rutas que ya no existen.
+func TestOpenAPICoversRoutes(t *testing.T) {
+	r := chi.NewRouter()
+	SetupRouter(r, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+
+	routes := handlers.OpenAPIRouteKeys(r)
+	if documented := handlers.DocumentedRouteKeys(); !reflect.DeepEqual(routes, documented) {
+		t.Errorf("❌ Las rutas documentadas no coinciden con las del router:\n rutas:        %v\n documentadas: %v", routes, documented)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/openapi.json", nil)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("❌ GET /api/v1/openapi.json devolvió %d: %s", rec.Code, rec.Body)
+	}
+	var spec struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &spec); err != nil {
+		t.Fatalf("❌ La especificación no es JSON válido: %v", err)
+	}
+	for _, path := range []string{"/stocks", "/stocks/{id}", "/openapi.json"} {
+		if _, ok := spec.Paths[path]["get"]; !ok {
+			t.Errorf("❌ Falta GET %s en la especificación", path)
+		}
+	}
+}
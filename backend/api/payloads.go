@@ -0,0 +1,118 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/config"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// maxPayloadSize es el máximo de bytes que se guardan de cada respuesta. Las de Finnhub y
+// Alpha Vantage por ticker son de pocos KB; el límite solo evita guardar respuestas
+// anómalas enteras.
+const maxPayloadSize = 256 << 10
+
+// payloadQueueSize es cuántas respuestas pueden esperar a guardarse. Si la cola se llena se
+// descartan: la captura es para depurar y no debe frenar el enriquecimiento.
+const payloadQueueSize = 256
+
+// PayloadStore guarda la última respuesta de cada endpoint de un proveedor por ticker.
+type PayloadStore interface {
+	SaveProviderPayload(ctx context.Context, p models.ProviderPayload) error
+}
+
+var (
+	payloadMu    sync.Mutex
+	payloadQueue chan models.ProviderPayload
+)
+
+// RecordPayloads empieza a guardar en store las respuestas de Finnhub y Alpha Vantage que
+// llevan un ticker. Se llama una vez al arrancar; sin llamarla no se captura nada.
+func RecordPayloads(store PayloadStore) {
+	payloadMu.Lock()
+	defer payloadMu.Unlock()
+	if payloadQueue != nil {
+		return
+	}
+	payloadQueue = make(chan models.ProviderPayload, payloadQueueSize)
+	go func(queue <-chan models.ProviderPayload) {
+		for p := range queue {
+			if err := store.SaveProviderPayload(context.Background(), p); err != nil {
+				log.Printf("ADVERTENCIA: %v", err)
+			}
+		}
+	}(payloadQueue)
+}
+
+// payloadTransport copia el cuerpo de las respuestas de los proveedores a la cola de
+// RecordPayloads sin alterar lo que recibe el llamador. No guarda la URL, que lleva la API key.
+type payloadTransport struct {
+	next http.RoundTripper
+}
+
+func (t *payloadTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	payloadMu.Lock()
+	queue := payloadQueue
+	payloadMu.Unlock()
+	if queue == nil || config.Current().ProviderPayloadRetention <= 0 {
+		return resp, nil
+	}
+	provider, endpoint, ticker := payloadKey(req)
+	if ticker == "" {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		// El llamador recibe lo que se pudo leer y el error al leer el resto, como sin captura.
+		resp.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), errReader{err}))
+		return resp, nil
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	payload := models.ProviderPayload{Ticker: ticker, Provider: provider, Endpoint: endpoint,
+		StatusCode: resp.StatusCode, FetchedAt: time.Now().UTC()}
+	if len(body) > maxPayloadSize {
+		payload.Body, payload.Truncated = append([]byte(nil), body[:maxPayloadSize]...), true
+	} else {
+		payload.Body = body
+	}
+	select {
+	case queue <- payload:
+	default:
+		log.Printf("ADVERTENCIA: cola de respuestas de proveedores llena, se descarta %s/%s de %s", provider, endpoint, ticker)
+	}
+	return resp, nil
+}
+
+// payloadKey identifica el proveedor, el endpoint y el ticker de una petición. Devuelve un
+// ticker vacío si la petición no es de un ticker concreto (p. ej. las de Karenai).
+func payloadKey(req *http.Request) (provider, endpoint, ticker string) {
+	provider = providerHosts[req.URL.Host]
+	query := req.URL.Query()
+	switch provider {
+	case "finnhub":
+		endpoint = strings.TrimPrefix(req.URL.Path, "/api/v1/")
+	case "alphavantage":
+		endpoint = query.Get("function")
+	default:
+		return "", "", ""
+	}
+	return provider, endpoint, strings.ToUpper(query.Get("symbol"))
+}
+
+type errReader struct{ err error }
+
+func (r errReader) Read([]byte) (int, error) { return 0, r.err }
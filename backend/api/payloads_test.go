@@ -0,0 +1,73 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// capturePayloads redirige la cola de RecordPayloads a un canal del test.
+func capturePayloads(t *testing.T) chan models.ProviderPayload {
+	t.Helper()
+	queue := make(chan models.ProviderPayload, 4)
+	payloadMu.Lock()
+	previous := payloadQueue
+	payloadQueue = queue
+	payloadMu.Unlock()
+	t.Cleanup(func() {
+		payloadMu.Lock()
+		payloadQueue = previous
+		payloadMu.Unlock()
+	})
+	return queue
+}
+
+func TestPayloadTransport(t *testing.T) {
+	queue := capturePayloads(t)
+	client := &http.Client{Transport: &payloadTransport{next: &replayTransport{dir: filepath.Join("..", defaultFixturesDir)}}}
+
+	resp, err := client.Get(FINNHUB_BASE_URL + "/quote?symbol=aapl&token=secret")
+	if err != nil {
+		t.Fatalf("❌ error inesperado: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	select {
+	case p := <-queue:
+		if p.Ticker != "AAPL" || p.Provider != "finnhub" || p.Endpoint != "quote" || p.StatusCode != resp.StatusCode {
+			t.Errorf("❌ respuesta capturada inesperada: %+v", p)
+		}
+		if string(p.Body) != string(body) || len(body) == 0 {
+			t.Errorf("❌ el llamador y la captura deberían ver el mismo cuerpo: %q vs %q", body, p.Body)
+		}
+		if strings.Contains(string(p.Body), "secret") {
+			t.Error("❌ la captura no debería incluir la API key")
+		}
+	default:
+		t.Fatal("❌ no se capturó la respuesta de Finnhub")
+	}
+
+	// Karenai no es una petición por ticker y no se captura.
+	if resp, err := client.Get(KARENAI_API_URL); err == nil {
+		resp.Body.Close()
+	}
+	if len(queue) != 0 {
+		t.Errorf("❌ no se deberían capturar respuestas sin ticker, hay %d", len(queue))
+	}
+}
+
+func TestPayloadKey(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, ALPHA_VANTAGE_BASE_URL+"?function=GLOBAL_QUOTE&symbol=SAP.DE&apikey=secret", nil)
+	if provider, endpoint, ticker := payloadKey(req); provider != "alphavantage" || endpoint != "GLOBAL_QUOTE" || ticker != "SAP.DE" {
+		t.Errorf("❌ clave inesperada: %s %s %s", provider, endpoint, ticker)
+	}
+	req, _ = http.NewRequest(http.MethodGet, FINNHUB_BASE_URL+"/stock/metric?symbol=AAPL&metricType=all", nil)
+	if provider, endpoint, ticker := payloadKey(req); provider != "finnhub" || endpoint != "stock/metric" || ticker != "AAPL" {
+		t.Errorf("❌ clave inesperada: %s %s %s", provider, endpoint, ticker)
+	}
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+)
+
+// ErrorKind clasifica los fallos de los proveedores externos, para que quien llama decida
+// si reintentar, saltar el ticker o avisar sin interpretar el texto del error.
+type ErrorKind string
+
+const (
+	KindAuth        ErrorKind = "auth"         // Clave rechazada (401/403) o ausente
+	KindRateLimited ErrorKind = "rate_limited" // 429 o aviso de límite de Alpha Vantage
+	KindNotFound    ErrorKind = "not_found"    // El proveedor no conoce el ticker o no tiene datos
+	KindMalformed   ErrorKind = "malformed"    // Respuesta que no se puede decodificar
+	KindNetwork     ErrorKind = "network"      // Conexión, DNS o tiempo de espera agotado
+	KindUpstream    ErrorKind = "upstream"     // Error 5xx del proveedor
+	KindOther       ErrorKind = "other"
+)
+
+var (
+	// ErrUnauthorized indica que el proveedor rechazó la clave de API.
+	ErrUnauthorized = errors.New("el proveedor rechazó la clave de API")
+	// ErrMalformedResponse indica que la respuesta no tiene el formato esperado.
+	ErrMalformedResponse = errors.New("respuesta del proveedor con formato inesperado")
+)
+
+// ClassifyError devuelve el tipo de fallo de err, o "" si err es nil. Se apoya en los
+// errores que devuelven los clientes de este paquete (ErrNoData, ErrRateLimited,
+// StatusError...) y en los errores de red y de JSON que envuelven.
+func ClassifyError(err error) ErrorKind {
+	var statusErr *StatusError
+	var netErr net.Error
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrUnauthorized), errors.Is(err, ErrMissingAPIKey):
+		return KindAuth
+	case errors.Is(err, ErrRateLimited):
+		return KindRateLimited
+	case errors.Is(err, ErrNoData), errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound:
+		return KindNotFound
+	case errors.Is(err, ErrMalformedResponse), errors.As(err, &syntaxErr), errors.As(err, &typeErr):
+		return KindMalformed
+	case errors.As(err, &statusErr) && statusErr.StatusCode >= 500:
+		return KindUpstream
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr):
+		return KindNetwork
+	default:
+		return KindOther
+	}
+}
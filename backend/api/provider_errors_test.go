@@ -0,0 +1,43 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"testing"
+)
+
+func TestClassifyError(t *testing.T) {
+	var syntaxErr *json.SyntaxError
+	decodeErr := json.Unmarshal([]byte("{"), &struct{}{})
+	if !errors.As(decodeErr, &syntaxErr) {
+		t.Fatalf("❌ se esperaba un *json.SyntaxError, se obtuvo %T", decodeErr)
+	}
+
+	cases := []struct {
+		name string
+		err  error
+		want ErrorKind
+	}{
+		{"sin error", nil, ""},
+		{"401", newStatusError(401, "no autorizado"), KindAuth},
+		{"403", newStatusError(403, "prohibido"), KindAuth},
+		{"sin clave", fmt.Errorf("FINNHUB_API_KEY %w", ErrMissingAPIKey), KindAuth},
+		{"429", newStatusError(429, "demasiadas peticiones"), KindRateLimited},
+		{"aviso de Alpha Vantage", fmt.Errorf("Alpha Vantage API note/warning: límite (%w)", ErrRateLimited), KindRateLimited},
+		{"404", newStatusError(404, "no encontrado"), KindNotFound},
+		{"sin datos", fmt.Errorf("Finnhub no devolvió cotización para XYZ: %w", ErrNoData), KindNotFound},
+		{"JSON inválido", fmt.Errorf("error al decodificar JSON: %w", decodeErr), KindMalformed},
+		{"formato inesperado", fmt.Errorf("'Global Quote' no encontrado: %w", ErrMalformedResponse), KindMalformed},
+		{"503", newStatusError(503, "no disponible"), KindUpstream},
+		{"conexión rechazada", fmt.Errorf("error al consultar: %w", &url.Error{Op: "Get", URL: "https://finnhub.io", Err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}}), KindNetwork},
+		{"400", newStatusError(400, "petición inválida"), KindOther},
+	}
+	for _, c := range cases {
+		if got := ClassifyError(c.err); got != c.want {
+			t.Errorf("❌ %s: ClassifyError = %q, se esperaba %q", c.name, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Ajustes del transporte HTTP de cada proveedor. Los workers del enricher llaman al mismo
+// host a la vez, así que se mantienen abiertas tantas conexiones inactivas por host como
+// workers puede haber: con las 2 de http.DefaultTransport, cada petición de más abría una
+// conexión nueva (con su handshake TLS) que se cerraba al terminar.
+const (
+	providerMaxIdleConnsPerHost   = 32
+	providerIdleConnTimeout       = 90 * time.Second
+	providerDialTimeout           = 10 * time.Second
+	providerKeepAlive             = 30 * time.Second
+	providerTLSHandshakeTimeout   = 10 * time.Second
+	providerResponseHeaderTimeout = 20 * time.Second
+)
+
+// newProviderTransport crea el transporte de un proveedor, con su propio pool de
+// conexiones reutilizables (keep-alive) y plazos para cada fase de la petición.
+func newProviderTransport() *http.Transport {
+	dialer := &net.Dialer{Timeout: providerDialTimeout, KeepAlive: providerKeepAlive}
+	return &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          providerMaxIdleConnsPerHost,
+		MaxIdleConnsPerHost:   providerMaxIdleConnsPerHost,
+		IdleConnTimeout:       providerIdleConnTimeout,
+		TLSHandshakeTimeout:   providerTLSHandshakeTimeout,
+		ResponseHeaderTimeout: providerResponseHeaderTimeout,
+		ExpectContinueTimeout: time.Second,
+	}
+}
+
+// providerForURL devuelve el nombre del proveedor al que va rawURL (ver providerHosts), o
+// "" si no es de ninguno.
+func providerForURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return providerHosts[u.Host]
+}
+
+// redactURL devuelve rawURL con el valor de las claves de API (secretQueryParams) sustituido
+// por REDACTED, para poder registrarla en los logs sin filtrar las claves.
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "[URL no válida]"
+	}
+	query := u.Query()
+	redacted := false
+	for key := range query {
+		if secretQueryParams[strings.ToLower(key)] {
+			query.Set(key, "REDACTED")
+			redacted = true
+		}
+	}
+	if !redacted {
+		return rawURL
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// redactRequestError quita las claves de API de la URL que net/http incluye en los errores
+// de una petición (*url.Error), que si no acabarían en los logs de quien registre el error.
+func redactRequestError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		urlErr.URL = redactURL(urlErr.URL)
+	}
+	return err
+}
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestProviderTransport_ReusesConnections(t *testing.T) {
+	const workers = 8
+	var conns atomic.Int32
+	// En las tandas, cada respuesta espera a que lleguen las demás, así que cada tanda
+	// necesita una conexión por petición.
+	var barrier atomic.Pointer[sync.WaitGroup]
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if wg := barrier.Load(); wg != nil {
+			wg.Done()
+			wg.Wait()
+		}
+		w.Write([]byte(`{}`))
+	}))
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	srv.Start()
+	defer srv.Close()
+
+	client := &http.Client{Transport: newProviderTransport()}
+	get := func() {
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			t.Errorf("❌ Error en la petición: %v", err)
+			return
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+
+	// Peticiones seguidas: todas usan la misma conexión.
+	for i := 0; i < 5; i++ {
+		get()
+	}
+	if got := conns.Load(); got != 1 {
+		t.Fatalf("❌ %d conexiones para 5 peticiones seguidas, se esperaba 1", got)
+	}
+
+	// Tantas peticiones a la vez como workers: las conexiones quedan en el pool y la
+	// siguiente tanda no abre ninguna nueva.
+	burst := func() {
+		arrived := &sync.WaitGroup{}
+		arrived.Add(workers)
+		barrier.Store(arrived)
+		var wg sync.WaitGroup
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func() { defer wg.Done(); get() }()
+		}
+		wg.Wait()
+	}
+	burst()
+	if got := conns.Load(); got != workers {
+		t.Fatalf("❌ %d conexiones tras la primera tanda, se esperaban %d", got, workers)
+	}
+	burst()
+	if got := conns.Load(); got != workers {
+		t.Errorf("❌ La segunda tanda abrió %d conexiones nuevas, se esperaba 0", got-workers)
+	}
+}
+
+func TestProviderForURL(t *testing.T) {
+	cases := map[string]string{
+		"https://finnhub.io/api/v1/quote?symbol=AAPL":             "finnhub",
+		"https://www.alphavantage.co/query?function=GLOBAL_QUOTE": "alphavantage",
+		"https://api.karenai.click/swechallenge/list":             "karenai",
+		"https://example.com/otra":                                "",
+	}
+	for rawURL, want := range cases {
+		if got := providerForURL(rawURL); got != want {
+			t.Errorf("❌ providerForURL(%q) = %q, se esperaba %q", rawURL, got, want)
+		}
+	}
+}
+
+func TestProviderClient_OnePerProvider(t *testing.T) {
+	finnhub := providerClient("finnhub")
+	if providerClient("finnhub") != finnhub {
+		t.Error("❌ Se creó un cliente nuevo para el mismo proveedor")
+	}
+	if providerClient("alphavantage") == finnhub {
+		t.Error("❌ Dos proveedores comparten el mismo cliente")
+	}
+}
+
+func TestRedactURL(t *testing.T) {
+	cases := map[string]string{
+		"https://finnhub.io/api/v1/quote?symbol=AAPL&token=secreto":                    "https://finnhub.io/api/v1/quote?symbol=AAPL&token=REDACTED",
+		"https://www.alphavantage.co/query?apikey=secreto&function=OVERVIEW&symbol=KO": "https://www.alphavantage.co/query?apikey=REDACTED&function=OVERVIEW&symbol=KO",
+		"https://finnhub.io/api/v1/quote?symbol=AAPL":                                  "https://finnhub.io/api/v1/quote?symbol=AAPL",
+		"https://www.alphavantage.co/query?APIKEY=secreto&function=OVERVIEW":           "https://www.alphavantage.co/query?APIKEY=REDACTED&function=OVERVIEW",
+	}
+	for raw, want := range cases {
+		if got := redactURL(raw); got != want {
+			t.Errorf("❌ redactURL(%q) = %q, se esperaba %q", raw, got, want)
+		}
+	}
+}
+
+func TestRedactRequestError(t *testing.T) {
+	_, err := http.Get("http://127.0.0.1:0/query?function=OVERVIEW&apikey=secreto")
+	if err == nil {
+		t.Fatal("❌ se esperaba un error al conectar con el puerto 0")
+	}
+	if msg := redactRequestError(err).Error(); strings.Contains(msg, "secreto") {
+		t.Errorf("❌ el error sigue incluyendo la clave de API: %s", msg)
+	}
+}
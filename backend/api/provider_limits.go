@@ -0,0 +1,98 @@
+package api
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/clock"
+	"github.com/jannin2/stock-app/backend/config"
+)
+
+// tokenBucket reparte los turnos para llamar a un proveedor a ritmo constante. Tiene
+// capacidad para un solo token, así que las peticiones simultáneas esperan cada una el
+// suyo en lugar de salir en ráfaga: los proveedores cuentan su límite por minuto.
+type tokenBucket struct {
+	mu   sync.Mutex
+	next time.Time // Momento en que queda libre el siguiente token
+}
+
+// reserve reserva el siguiente token, que se repone cada interval, y devuelve cuánto hay
+// que esperar desde now para usarlo.
+func (b *tokenBucket) reserve(now time.Time, interval time.Duration) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	at := b.next
+	if at.Before(now) {
+		at = now
+	}
+	b.next = at.Add(interval)
+	return at.Sub(now)
+}
+
+// limitTransport hace esperar cada petición a su turno en el cubo de su proveedor, según
+// config.ProviderDelay, que se consulta en cada petición para aplicar el límite recargado
+// con SIGHUP. Así varios workers del enricher comparten el límite de cada proveedor.
+//
+// El plazo timeout de cada petición empieza a contar tras la espera, que puede durar
+// minutos con muchos workers; por eso no se usa http.Client.Timeout, que la incluiría.
+type limitTransport struct {
+	next    http.RoundTripper
+	clock   clock.Clock
+	timeout time.Duration
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket // Por proveedor
+}
+
+func newLimitTransport(next http.RoundTripper, clk clock.Clock, timeout time.Duration) *limitTransport {
+	return &limitTransport{next: next, clock: clk, timeout: timeout, buckets: map[string]*tokenBucket{}}
+}
+
+func (t *limitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	provider := providerHosts[req.URL.Host]
+	if interval := config.Current().ProviderDelay(provider); interval > 0 {
+		if wait := t.bucket(provider).reserve(t.clock.Now(), interval); wait > 0 {
+			select {
+			case <-t.clock.After(wait):
+			case <-req.Context().Done():
+				return nil, req.Context().Err()
+			}
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+	resp, err := t.next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	// El plazo cubre también la lectura del cuerpo, como el de http.Client.
+	resp.Body = cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelOnClose libera el contexto de la petición al cerrar el cuerpo de la respuesta.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+func (t *limitTransport) bucket(provider string) *tokenBucket {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	b, ok := t.buckets[provider]
+	if !ok {
+		b = &tokenBucket{}
+		t.buckets[provider] = b
+	}
+	return b
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/clock"
+	"github.com/jannin2/stock-app/backend/config"
+)
+
+func TestTokenBucket_Reserve(t *testing.T) {
+	var b tokenBucket
+	now := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	for i, want := range []time.Duration{0, 12 * time.Second, 24 * time.Second} {
+		if got := b.reserve(now, 12*time.Second); got != want {
+			t.Errorf("❌ reserva %d: espera %s, se esperaba %s", i, got, want)
+		}
+	}
+	// Pasado el turno reservado, el siguiente token está libre de inmediato.
+	if got := b.reserve(now.Add(time.Minute), 12*time.Second); got != 0 {
+		t.Errorf("❌ espera %s tras un minuto sin peticiones, se esperaba 0", got)
+	}
+}
+
+// roundTripFunc adapta una función a http.RoundTripper.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestLimitTransport(t *testing.T) {
+	prev := config.Current()
+	t.Cleanup(func() { config.Set(prev) })
+	cfg := config.Default()
+	cfg.AlphaVantageRequestsPerMin = 5
+	cfg.FinnhubRequestsPerMin = 0
+	config.Set(cfg)
+
+	var calls atomic.Int32
+	next := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		calls.Add(1)
+		rec := httptest.NewRecorder()
+		rec.WriteString("{}")
+		return rec.Result(), nil
+	})
+	mock := clock.NewMock()
+	client := &http.Client{Transport: newLimitTransport(next, mock, time.Second)}
+	get := func(url string) {
+		resp, err := client.Get(url)
+		if err != nil {
+			t.Errorf("❌ error inesperado en %s: %v", url, err)
+			return
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+
+	// Finnhub no tiene límite y Alpha Vantage da su primer turno sin esperar.
+	get(FINNHUB_BASE_URL + "/quote?symbol=AAPL")
+	get(FINNHUB_BASE_URL + "/quote?symbol=KO")
+	get(ALPHA_VANTAGE_BASE_URL + "?function=GLOBAL_QUOTE&symbol=AAPL")
+	if calls.Load() != 3 {
+		t.Fatalf("❌ se esperaban 3 llamadas sin espera, hubo %d", calls.Load())
+	}
+
+	// La segunda llamada a Alpha Vantage espera a que se reponga el token (12s con 5/min).
+	done := make(chan struct{})
+	go func() {
+		get(ALPHA_VANTAGE_BASE_URL + "?function=GLOBAL_QUOTE&symbol=KO")
+		close(done)
+	}()
+	mock.BlockUntil(1)
+	mock.Add(11 * time.Second)
+	if calls.Load() != 3 {
+		t.Errorf("❌ la llamada a Alpha Vantage no debería salir antes de su turno")
+	}
+	mock.Add(time.Second)
+	<-done
+	if calls.Load() != 4 {
+		t.Errorf("❌ se esperaban 4 llamadas tras el turno, hubo %d", calls.Load())
+	}
+}
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"io"
+	"log"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/clock"
+	"github.com/jannin2/stock-app/backend/config"
+)
+
+// maxRetryAfter es la mayor espera pedida con Retry-After que se respeta dentro de una
+// petición. Si el proveedor pide más, se devuelve su respuesta sin reintentar y el
+// enricher vuelve a intentarlo más tarde (ver RateLimitRetries).
+const maxRetryAfter = time.Minute
+
+// retryTransport repite las peticiones a los proveedores que fallan de forma transitoria
+// (un error de red, un 429 o un 5xx), con backoff exponencial y jitter según
+// config.ProviderRetries, ProviderRetryBackoff y ProviderRetryJitter, que se consultan en
+// cada petición para aplicar la configuración recargada con SIGHUP.
+//
+// Va por encima de limitTransport, así que cada reintento espera también su turno en el
+// límite por minuto del proveedor.
+type retryTransport struct {
+	next  http.RoundTripper
+	clock clock.Clock
+	rand  func() float64 // Entre 0 y 1, para el jitter
+}
+
+func newRetryTransport(next http.RoundTripper, clk clock.Clock) *retryTransport {
+	return &retryTransport{next: next, clock: clk, rand: rand.Float64}
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	cfg := config.Current()
+	if req.Body != nil && req.GetBody == nil {
+		return t.next.RoundTrip(req) // El cuerpo no se puede volver a enviar
+	}
+
+	attemptReq := req
+	for attempt := 1; ; attempt++ {
+		resp, err := t.next.RoundTrip(attemptReq)
+		if attempt > cfg.ProviderRetries || !retryable(req, resp, err) {
+			return resp, err
+		}
+		wait, ok := t.backoff(cfg, attempt, resp)
+		if !ok {
+			return resp, err
+		}
+
+		reason := "error de red"
+		if err == nil {
+			reason = resp.Status
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10)) // Para reutilizar la conexión
+			resp.Body.Close()
+		}
+		log.Printf("Reintento %d/%d de la petición a %s%s en %s (%s)",
+			attempt, cfg.ProviderRetries, req.URL.Host, req.URL.Path, wait.Round(time.Millisecond), reason)
+		select {
+		case <-t.clock.After(wait):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attemptReq = req.Clone(req.Context())
+			attemptReq.Body = body
+		}
+	}
+}
+
+// retryable indica si merece la pena repetir una petición que terminó con resp o err.
+func retryable(req *http.Request, resp *http.Response, err error) bool {
+	if err != nil {
+		return req.Context().Err() == nil // Si la cancelamos nosotros, no es transitorio
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// backoff devuelve cuánto esperar antes del reintento attempt (desde 1). Es false si el
+// proveedor pide con Retry-After una espera mayor que maxRetryAfter.
+func (t *retryTransport) backoff(cfg config.Config, attempt int, resp *http.Response) (time.Duration, bool) {
+	wait := time.Duration(float64(cfg.ProviderRetryBackoff) * math.Pow(2, float64(attempt-1)))
+	if resp != nil {
+		if after, ok := parseRetryAfter(resp.Header.Get("Retry-After"), t.clock.Now()); ok {
+			if after > maxRetryAfter {
+				return 0, false
+			}
+			wait = max(wait, after)
+		}
+	}
+	// ±jitter al azar: los workers que fallaron a la vez no reintentan a la vez.
+	return time.Duration(float64(wait) * (1 + cfg.ProviderRetryJitter*(2*t.rand()-1))), true
+}
+
+// parseRetryAfter interpreta una cabecera Retry-After, en segundos o como fecha HTTP.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(at.Sub(now), 0), true
+	}
+	return 0, false
+}
@@ -0,0 +1,135 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/clock"
+	"github.com/jannin2/stock-app/backend/config"
+)
+
+// retryCall es una petición en curso por un retryTransport contra un proveedor falso.
+type retryCall struct {
+	mock  *clock.Mock
+	calls atomic.Int32
+	resp  *http.Response
+	err   error
+	done  chan struct{}
+}
+
+// startRetryCall lanza una petición cuyas respuestas sucesivas son statuses (0 es un error
+// de red; la última se repite), todas con header.
+func startRetryCall(statuses []int, header http.Header) *retryCall {
+	c := &retryCall{mock: clock.NewMock(), done: make(chan struct{})}
+	next := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		status := statuses[min(int(c.calls.Add(1)), len(statuses))-1]
+		if status == 0 {
+			return nil, errors.New("connection reset by peer")
+		}
+		rec := httptest.NewRecorder()
+		for name, values := range header {
+			rec.Header()[name] = values
+		}
+		rec.WriteHeader(status)
+		return rec.Result(), nil
+	})
+	transport := newRetryTransport(next, c.mock)
+	transport.rand = func() float64 { return 1 } // Jitter máximo
+	go func() {
+		defer close(c.done)
+		req, _ := http.NewRequest(http.MethodGet, FINNHUB_BASE_URL+"/quote?symbol=AAPL", nil)
+		c.resp, c.err = transport.RoundTrip(req)
+	}()
+	return c
+}
+
+func TestRetryTransport(t *testing.T) {
+	prev := config.Current()
+	t.Cleanup(func() { config.Set(prev) })
+	cfg := config.Default()
+	cfg.ProviderRetries = 2
+	cfg.ProviderRetryBackoff = time.Second
+	cfg.ProviderRetryJitter = 0.5
+	config.Set(cfg)
+
+	t.Run("backoff exponencial con jitter", func(t *testing.T) {
+		c := startRetryCall([]int{http.StatusBadGateway, 0, http.StatusOK}, nil)
+		c.mock.BlockUntil(1)
+		c.mock.Add(1499 * time.Millisecond) // 1s + 50%
+		if c.calls.Load() != 1 {
+			t.Fatalf("❌ Reintento antes de tiempo: %d llamadas", c.calls.Load())
+		}
+		c.mock.Add(time.Millisecond)
+		c.mock.BlockUntil(1)
+		c.mock.Add(3 * time.Second) // 2s + 50%
+		<-c.done
+		if c.err != nil || c.resp.StatusCode != http.StatusOK || c.calls.Load() != 3 {
+			t.Errorf("❌ %d llamadas con error %v, se esperaban 3 terminadas en 200", c.calls.Load(), c.err)
+		}
+	})
+
+	t.Run("agotados los reintentos se devuelve el último fallo", func(t *testing.T) {
+		c := startRetryCall([]int{http.StatusServiceUnavailable}, nil)
+		for i := 0; i < cfg.ProviderRetries; i++ {
+			c.mock.BlockUntil(1)
+			c.mock.Add(time.Minute)
+		}
+		<-c.done
+		if c.err != nil || c.resp.StatusCode != http.StatusServiceUnavailable || c.calls.Load() != 3 {
+			t.Errorf("❌ %d llamadas con error %v, se esperaban 3 terminadas en 503", c.calls.Load(), c.err)
+		}
+	})
+
+	t.Run("un 404 no se reintenta", func(t *testing.T) {
+		c := startRetryCall([]int{http.StatusNotFound}, nil)
+		<-c.done
+		if c.calls.Load() != 1 {
+			t.Errorf("❌ %d llamadas, se esperaba 1", c.calls.Load())
+		}
+	})
+
+	t.Run("Retry-After", func(t *testing.T) {
+		c := startRetryCall([]int{http.StatusTooManyRequests, http.StatusOK}, http.Header{"Retry-After": {"10"}})
+		c.mock.BlockUntil(1)
+		c.mock.Add(10 * time.Second)
+		if c.calls.Load() != 1 {
+			t.Fatalf("❌ Reintento antes del Retry-After más el jitter")
+		}
+		c.mock.Add(5 * time.Second)
+		<-c.done
+		if c.calls.Load() != 2 {
+			t.Errorf("❌ %d llamadas, se esperaban 2", c.calls.Load())
+		}
+
+		// Si el proveedor pide esperar más de maxRetryAfter, no se reintenta.
+		c = startRetryCall([]int{http.StatusTooManyRequests}, http.Header{"Retry-After": {"3600"}})
+		<-c.done
+		if c.calls.Load() != 1 || c.resp.StatusCode != http.StatusTooManyRequests {
+			t.Errorf("❌ %d llamadas, se esperaba 1 que devolviera el 429", c.calls.Load())
+		}
+	})
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	cases := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"", 0, false},
+		{"30", 30 * time.Second, true},
+		{now.Add(time.Minute).Format(http.TimeFormat), time.Minute, true},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0, true},
+		{"pronto", 0, false},
+	}
+	for _, tc := range cases {
+		if got, ok := parseRetryAfter(tc.value, now); got != tc.want || ok != tc.ok {
+			t.Errorf("❌ parseRetryAfter(%q) = %s, %t; se esperaba %s, %t", tc.value, got, ok, tc.want, tc.ok)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/config"
+)
+
+// RequestIDHeader es la cabecera con la que se identifica cada petición a un proveedor.
+const RequestIDHeader = "X-Request-ID"
+
+// providerRequestIDHeaders son las cabeceras en las que los proveedores (o la CDN que
+// tienen delante) devuelven su identificador de la petición, por orden de preferencia.
+var providerRequestIDHeaders = []string{"X-Request-Id", "X-Amzn-Requestid", "X-Amz-Cf-Id", "Cf-Ray"}
+
+// tagTransport identifica las peticiones a los proveedores: les pone el User-Agent de la
+// configuración vigente y un X-Request-ID propio, y registra ambos junto al identificador
+// que devuelve el proveedor. Las respuestas de error se registran siempre, para poder
+// citarlas si un proveedor nos limita; las demás, solo con LOG_LEVEL=debug.
+type tagTransport struct {
+	next http.RoundTripper
+}
+
+func (t *tagTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	cfg := config.Current()
+	req = req.Clone(req.Context()) // Un RoundTripper no debe modificar la petición recibida
+	req.Header.Set("User-Agent", cfg.UserAgent)
+	requestID := req.Header.Get(RequestIDHeader)
+	if requestID == "" {
+		requestID = uuid.NewString()
+		req.Header.Set(RequestIDHeader, requestID)
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		log.Printf("ADVERTENCIA: petición %s a %s%s fallida: %v", requestID, req.URL.Host, req.URL.Path, err)
+		return nil, err
+	}
+	if resp.StatusCode >= http.StatusBadRequest || cfg.LogEnabled(config.LogLevelDebug) {
+		log.Printf("Petición %s a %s%s: %s (id del proveedor: %s)",
+			requestID, req.URL.Host, req.URL.Path, resp.Status, providerRequestID(resp.Header))
+	}
+	return resp, nil
+}
+
+// providerRequestID devuelve el identificador de la petición que da el proveedor en
+// header, o "-" si no da ninguno.
+func providerRequestID(header http.Header) string {
+	for _, name := range providerRequestIDHeaders {
+		if id := header.Get(name); id != "" {
+			return id
+		}
+	}
+	return "-"
+}
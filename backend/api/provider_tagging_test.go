@@ -0,0 +1,71 @@
+package api
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/jannin2/stock-app/backend/config"
+)
+
+func TestTagTransport(t *testing.T) {
+	prev := config.Current()
+	t.Cleanup(func() { config.Set(prev) })
+	cfg := config.Default()
+	cfg.UserAgent = "stock-app-test/1.0"
+	config.Set(cfg)
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	var userAgent, requestID string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent, requestID = r.Header.Get("User-Agent"), r.Header.Get(RequestIDHeader)
+		w.Header().Set("X-Request-Id", "prov-123")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: &tagTransport{next: http.DefaultTransport}}
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/api/v1/quote?symbol=AAPL&token=secreto", nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("❌ Error en la petición: %v", err)
+	}
+	resp.Body.Close()
+
+	if userAgent != "stock-app-test/1.0" {
+		t.Errorf("❌ User-Agent %q, se esperaba el de la configuración", userAgent)
+	}
+	if requestID == "" {
+		t.Fatal("❌ La petición salió sin X-Request-ID")
+	}
+	if req.Header.Get(RequestIDHeader) != "" {
+		t.Error("❌ Se modificó la petición original")
+	}
+	// Un 429 se registra con los dos identificadores y sin los parámetros de la URL.
+	out := logs.String()
+	if !strings.Contains(out, requestID) || !strings.Contains(out, "prov-123") {
+		t.Errorf("❌ El log no tiene los identificadores de la petición: %s", out)
+	}
+	if strings.Contains(out, "secreto") {
+		t.Errorf("❌ El log incluye la API key: %s", out)
+	}
+}
+
+func TestProviderRequestID(t *testing.T) {
+	header := http.Header{}
+	if got := providerRequestID(header); got != "-" {
+		t.Errorf("❌ id %q sin cabeceras, se esperaba -", got)
+	}
+	header.Set("Cf-Ray", "8a1b2c3d-MAD")
+	header.Set("X-Amzn-Requestid", "amzn-1")
+	if got := providerRequestID(header); got != "amzn-1" {
+		t.Errorf("❌ id %q, se esperaba el de X-Amzn-Requestid", got)
+	}
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/apierror"
+	"github.com/jannin2/stock-app/backend/clock"
+	"github.com/jannin2/stock-app/backend/config"
+)
+
+// rateLimitWindow es la duración de cada ventana del limitador.
+const rateLimitWindow = time.Minute
+
+// RateLimiter cuenta las peticiones de cada cliente (por IP) con ventanas fijas de un
+// minuto, alineadas para todos los clientes. El límite es config.APIRequestsPerMin y solo
+// se hace cumplir con config.APIRateLimitEnforce; ambos se consultan en cada petición para
+// respetar las recargas de configuración.
+type RateLimiter struct {
+	clock clock.Clock
+
+	mu          sync.Mutex
+	windowStart time.Time
+	counts      map[string]int // Peticiones de cada cliente en la ventana actual
+}
+
+// NewRateLimiter crea un limitador que usa clk como fuente de tiempo.
+func NewRateLimiter(clk clock.Clock) *RateLimiter {
+	return &RateLimiter{clock: clk, counts: make(map[string]int)}
+}
+
+// take cuenta una petición de client y devuelve cuántas lleva en la ventana actual y
+// cuándo empieza la siguiente.
+func (rl *RateLimiter) take(client string) (count int, reset time.Time) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	start := rl.clock.Now().Truncate(rateLimitWindow)
+	if !start.Equal(rl.windowStart) {
+		// Nueva ventana: los contadores anteriores ya no sirven y se descartan todos.
+		rl.windowStart = start
+		rl.counts = make(map[string]int)
+	}
+	rl.counts[client]++
+	return rl.counts[client], start.Add(rateLimitWindow)
+}
+
+// Middleware añade X-RateLimit-Limit, X-RateLimit-Remaining y X-RateLimit-Reset (segundos
+// Unix) a todas las respuestas, para que los clientes puedan espaciar sus peticiones. Solo
+// si config.APIRateLimitEnforce está activo responde 429 con Retry-After al superarlo.
+func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cfg := config.Current()
+		limit := cfg.APIRequestsPerMin
+		if limit <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		count, reset := rl.take(clientIP(r))
+		w.Header().Set("X-RateLimit-Limit", strconv.Itoa(limit))
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(max(limit-count, 0)))
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+
+		if count > limit && cfg.APIRateLimitEnforce {
+			retryAfter := int(reset.Sub(rl.clock.Now()).Seconds() + 0.999) // Redondeo hacia arriba
+			w.Header().Set("Retry-After", strconv.Itoa(max(retryAfter, 1)))
+			apierror.HTTPError(w, "Límite de peticiones excedido, inténtelo de nuevo más tarde", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// clientIP devuelve la IP de la conexión, sin el puerto.
+func clientIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/clock"
+	"github.com/jannin2/stock-app/backend/config"
+)
+
+func TestRateLimiter(t *testing.T) {
+	prev := config.Current()
+	t.Cleanup(func() { config.Set(prev) })
+	cfg := config.Default()
+	cfg.APIRequestsPerMin = 2
+	cfg.APIRateLimitEnforce = true
+	config.Set(cfg)
+
+	mockClock := clock.NewMock()
+	mockClock.Set(time.Date(2025, 1, 6, 15, 0, 10, 0, time.UTC))
+	handler := NewRateLimiter(mockClock).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	get := func(remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/stocks", nil)
+		req.RemoteAddr = remoteAddr
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+	reset := strconv.FormatInt(time.Date(2025, 1, 6, 15, 1, 0, 0, time.UTC).Unix(), 10)
+
+	for i, wantRemaining := range []string{"1", "0"} {
+		rr := get("10.0.0.1:5000")
+		if rr.Code != http.StatusOK || rr.Header().Get("X-RateLimit-Remaining") != wantRemaining {
+			t.Errorf("❌ petición %d: estado %d, remaining %q; se esperaba 200 y %s", i+1, rr.Code, rr.Header().Get("X-RateLimit-Remaining"), wantRemaining)
+		}
+		if rr.Header().Get("X-RateLimit-Limit") != "2" || rr.Header().Get("X-RateLimit-Reset") != reset {
+			t.Errorf("❌ petición %d: cabeceras inesperadas %v", i+1, rr.Header())
+		}
+	}
+
+	rr := get("10.0.0.1:5001") // Mismo cliente desde otro puerto
+	if rr.Code != http.StatusTooManyRequests || rr.Header().Get("Retry-After") != "50" {
+		t.Errorf("❌ estado %d, Retry-After %q; se esperaba 429 y 50", rr.Code, rr.Header().Get("Retry-After"))
+	}
+	if rr := get("10.0.0.2:5000"); rr.Code != http.StatusOK {
+		t.Errorf("❌ otro cliente no debería estar limitado, estado %d", rr.Code)
+	}
+
+	// En la siguiente ventana el contador vuelve a empezar.
+	mockClock.Add(time.Minute)
+	if rr := get("10.0.0.1:5000"); rr.Code != http.StatusOK || rr.Header().Get("X-RateLimit-Remaining") != "1" {
+		t.Errorf("❌ nueva ventana: estado %d, remaining %q", rr.Code, rr.Header().Get("X-RateLimit-Remaining"))
+	}
+
+	// Sin APIRateLimitEnforce solo se informa: el cliente agotado sigue recibiendo 200.
+	cfg.APIRateLimitEnforce = false
+	config.Set(cfg)
+	get("10.0.0.1:5000")
+	if rr := get("10.0.0.1:5000"); rr.Code != http.StatusOK || rr.Header().Get("X-RateLimit-Remaining") != "0" || rr.Header().Get("Retry-After") != "" {
+		t.Errorf("❌ sin hacer cumplir el límite: estado %d, cabeceras %v; se esperaba 200 con remaining 0", rr.Code, rr.Header())
+	}
+
+	// Con límite 0 no se limita ni se envían cabeceras.
+	cfg.APIRequestsPerMin = 0
+	config.Set(cfg)
+	if rr := get("10.0.0.1:5000"); rr.Code != http.StatusOK || rr.Header().Get("X-RateLimit-Limit") != "" {
+		t.Errorf("❌ sin límite: estado %d, cabeceras %v", rr.Code, rr.Header())
+	}
+}
@@ -0,0 +1,228 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/chaos"
+	"github.com/jannin2/stock-app/backend/clock"
+)
+
+// Modos soportados por la variable de entorno PROVIDERS_MODE.
+const (
+	ProvidersModeLive   = "live"   // Llamadas reales a los proveedores (por defecto)
+	ProvidersModeReplay = "replay" // Respuestas grabadas leídas desde disco, sin red ni API keys
+	ProvidersModeRecord = "record" // Llamadas reales que además se graban en disco para reproducirlas después
+)
+
+// defaultFixturesDir es el directorio de respuestas grabadas si PROVIDERS_FIXTURES_DIR no está configurada.
+const defaultFixturesDir = "fixtures/providers"
+
+// secretQueryParams son los parámetros con los que los proveedores reciben la clave de API,
+// en minúsculas. Nunca forman parte del nombre de un fixture y redactURL los oculta en los
+// logs.
+var secretQueryParams = map[string]bool{"token": true, "apikey": true}
+
+// providerHosts asocia cada host con el nombre de carpeta de su proveedor.
+var providerHosts = map[string]string{
+	"api.karenai.click":   "karenai",
+	"finnhub.io":          "finnhub",
+	"www.alphavantage.co": "alphavantage",
+}
+
+// providerTimeout es el plazo de cada petición a un proveedor, sin contar la espera a su
+// límite por minuto.
+const providerTimeout = 30 * time.Second
+
+var (
+	providerClientsMu sync.Mutex
+	providerClients   = map[string]*http.Client{} // Por proveedor, ver providerClient
+)
+
+// ProvidersMode devuelve el modo configurado en PROVIDERS_MODE (live por defecto).
+func ProvidersMode() string {
+	switch mode := strings.ToLower(os.Getenv("PROVIDERS_MODE")); mode {
+	case ProvidersModeReplay, ProvidersModeRecord:
+		return mode
+	default:
+		return ProvidersModeLive
+	}
+}
+
+// IsReplayMode indica si las respuestas de los proveedores se sirven desde disco.
+func IsReplayMode() bool {
+	return ProvidersMode() == ProvidersModeReplay
+}
+
+// fixturesDir devuelve el directorio donde se leen/graban las respuestas de los proveedores.
+func fixturesDir() string {
+	if dir := os.Getenv("PROVIDERS_FIXTURES_DIR"); dir != "" {
+		return dir
+	}
+	return defaultFixturesDir
+}
+
+// providerClient devuelve el cliente HTTP de provider (ver providerHosts), configurado
+// según PROVIDERS_MODE. Cada proveedor tiene el suyo, creado la primera vez que se usa y
+// compartido por todas sus peticiones, para reutilizar las conexiones abiertas.
+func providerClient(provider string) *http.Client {
+	providerClientsMu.Lock()
+	defer providerClientsMu.Unlock()
+	if client, ok := providerClients[provider]; ok {
+		return client
+	}
+
+	var transport http.RoundTripper
+	switch ProvidersMode() {
+	case ProvidersModeReplay:
+		transport = &replayTransport{dir: fixturesDir()}
+		log.Printf("Proveedor %s en modo replay: respuestas servidas desde %s", provider, fixturesDir())
+	case ProvidersModeRecord:
+		transport = &recordTransport{dir: fixturesDir(), next: &tagTransport{next: newProviderTransport()}}
+		log.Printf("Proveedor %s en modo record: respuestas grabadas en %s", provider, fixturesDir())
+	default:
+		transport = &tagTransport{next: newProviderTransport()}
+	}
+	// Se capturan las respuestas reales (o reproducidas), no los fallos inyectados por el
+	// modo chaos, que solo actúa si está activado en la configuración vigente.
+	transport = chaos.Transport(&payloadTransport{next: transport})
+
+	client := &http.Client{Timeout: providerTimeout, Transport: transport}
+	if ProvidersMode() != ProvidersModeReplay {
+		// Las respuestas grabadas no gastan cuota ni fallan de forma transitoria; las demás
+		// esperan su turno (ver limitTransport) y se reintentan (ver retryTransport).
+		clk := clock.New()
+		client = &http.Client{Transport: newRetryTransport(newLimitTransport(transport, clk, providerTimeout), clk)}
+	}
+	providerClients[provider] = client
+	return client
+}
+
+// providerAPIKey devuelve el valor de la variable de entorno envName.
+// En modo replay las claves no son necesarias, por lo que se devuelve un valor ficticio.
+func providerAPIKey(envName string) (string, error) {
+	key := os.Getenv(envName)
+	if key != "" {
+		return key, nil
+	}
+	if IsReplayMode() {
+		return "replay", nil
+	}
+	return "", fmt.Errorf("%s %w", envName, ErrMissingAPIKey)
+}
+
+// fixturePath calcula la ruta del fixture de una petición a partir del proveedor, la ruta
+// y los parámetros no secretos de la URL (ej. finnhub/api/v1/quote/AAPL.json).
+func fixturePath(dir string, u *url.URL) (string, error) {
+	provider, ok := providerHosts[u.Host]
+	if !ok {
+		return "", fmt.Errorf("host sin proveedor conocido para fixtures: %s", u.Host)
+	}
+
+	query := u.Query()
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		if !secretQueryParams[strings.ToLower(key)] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, key := range keys {
+		parts = append(parts, sanitizeFixtureName(query.Get(key)))
+	}
+	name := "index"
+	if len(parts) > 0 {
+		name = strings.Join(parts, "_")
+	}
+
+	return filepath.Join(dir, provider, filepath.FromSlash(strings.Trim(u.Path, "/")), name+".json"), nil
+}
+
+// sanitizeFixtureName reemplaza los caracteres que no son seguros en un nombre de archivo.
+func sanitizeFixtureName(value string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-':
+			return r
+		default:
+			return '_'
+		}
+	}, value)
+}
+
+// replayTransport responde a las peticiones con los fixtures grabados en disco.
+// Si no existe un fixture para la petición responde 404, como lo haría un proveedor real.
+type replayTransport struct {
+	dir string
+}
+
+func (t *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	path, err := fixturePath(t.dir, req.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	status := http.StatusOK
+	body, err := os.ReadFile(path)
+	if err != nil {
+		status = http.StatusNotFound
+		body = []byte(fmt.Sprintf(`{"error":"fixture no encontrado: %s"}`, filepath.ToSlash(path)))
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// recordTransport realiza la petición real y graba las respuestas 200 como fixtures.
+type recordTransport struct {
+	dir  string
+	next http.RoundTripper
+}
+
+func (t *recordTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return resp, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	path, err := fixturePath(t.dir, req.URL)
+	if err != nil {
+		log.Printf("ADVERTENCIA: no se grabó la respuesta de %s: %v", req.URL.Host, err)
+		return resp, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err == nil {
+		err = os.WriteFile(path, body, 0o644)
+	}
+	if err != nil {
+		log.Printf("ADVERTENCIA: no se pudo grabar el fixture %s: %v", path, err)
+	}
+	return resp, nil
+}
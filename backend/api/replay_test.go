@@ -0,0 +1,74 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"testing"
+)
+
+func TestFixturePath(t *testing.T) {
+	tests := []struct {
+		name     string
+		rawURL   string
+		expected string
+	}{
+		{
+			name:     "Finnhub quote ignores token",
+			rawURL:   FINNHUB_BASE_URL + "/quote?symbol=AAPL&token=secret",
+			expected: "finnhub/api/v1/quote/AAPL.json",
+		},
+		{
+			name:     "Finnhub metrics sorted by param name",
+			rawURL:   FINNHUB_BASE_URL + "/stock/metric?symbol=AAPL&metricType=all&token=secret",
+			expected: "finnhub/api/v1/stock/metric/all_AAPL.json",
+		},
+		{
+			name:     "Alpha Vantage ignores apikey",
+			rawURL:   ALPHA_VANTAGE_BASE_URL + "?function=GLOBAL_QUOTE&symbol=SAP.DE&apikey=secret",
+			expected: "alphavantage/query/GLOBAL_QUOTE_SAP.DE.json",
+		},
+		{
+			name:     "Karenai without params",
+			rawURL:   KARENAI_API_URL,
+			expected: "karenai/swechallenge/list/index.json",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, _ := url.Parse(tt.rawURL)
+			path, err := fixturePath("fixtures", u)
+			if err != nil {
+				t.Fatalf("❌ error inesperado: %v", err)
+			}
+			if expected := filepath.Join("fixtures", filepath.FromSlash(tt.expected)); path != expected {
+				t.Errorf("❌ se esperaba %s, se obtuvo %s", expected, path)
+			}
+		})
+	}
+}
+
+func TestReplayTransport(t *testing.T) {
+	client := &http.Client{Transport: &replayTransport{dir: filepath.Join("..", defaultFixturesDir)}}
+
+	resp, err := client.Get(FINNHUB_BASE_URL + "/quote?symbol=AAPL&token=replay")
+	if err != nil {
+		t.Fatalf("❌ error inesperado: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || len(body) == 0 {
+		t.Errorf("❌ se esperaba el fixture de AAPL, estado %d, cuerpo %q", resp.StatusCode, body)
+	}
+
+	resp, err = client.Get(FINNHUB_BASE_URL + "/quote?symbol=NOPE&token=replay")
+	if err != nil {
+		t.Fatalf("❌ error inesperado: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("❌ se esperaba 404 para un fixture inexistente, se obtuvo %d", resp.StatusCode)
+	}
+}
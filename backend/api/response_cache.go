@@ -0,0 +1,197 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"sync"
+
+	"github.com/jannin2/stock-app/backend/auth"
+	"github.com/jannin2/stock-app/backend/handlers"
+)
+
+// WarmPaths son las consultas que se precalientan tras cada ejecución del enricher: el
+// listado por defecto, los recomendados y el mapa de calor del mercado (el endpoint de
+// estadísticas agregadas). Son las primeras que pide el frontend al cargar.
+var WarmPaths = []string{"/api/v1/stocks", "/api/v1/stocks/recommended", "/api/v1/market/heatmap"}
+
+// maxCachedResponses limita el número de respuestas en caché, ya que cada combinación de
+// parámetros es una entrada distinta. Al llegar al límite dejan de guardarse nuevas.
+const maxCachedResponses = 256
+
+// cachedHeaders son las cabeceras fijadas por los manejadores que se guardan con la
+// respuesta. Las de los middlewares externos (Cache-Control, X-RateLimit-*) se calculan
+// en cada petición.
+var cachedHeaders = []string{"Content-Type", "X-Total-Count", "X-Total-Count-Approximate", "X-Data-As-Of", "X-As-Of", "Link"}
+
+// ResponseCache guarda en memoria las respuestas públicas de las consultas más frecuentes.
+// Los datos solo cambian cuando el enricher guarda una ejecución, así que las entradas se
+// conservan hasta el siguiente Purge o Warm.
+type ResponseCache struct {
+	mu       sync.RWMutex
+	entries  map[string]cachedResponse // Por URI de la petición
+	lastGood map[string]cachedResponse // Última respuesta correcta de las rutas con Fallback; Purge no las descarta
+	ready    func() bool               // Estado de la base de datos para Fallback; nil = siempre disponible
+}
+
+type cachedResponse struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+// NewResponseCache crea una caché de respuestas vacía.
+func NewResponseCache() *ResponseCache {
+	return &ResponseCache{entries: make(map[string]cachedResponse), lastGood: make(map[string]cachedResponse)}
+}
+
+// Middleware sirve desde la caché las peticiones GET con scope público y guarda las
+// respuestas 200 que no estén en ella. Las peticiones de administrador no se cachean,
+// porque incluyen campos que el público no ve.
+func (c *ResponseCache) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || auth.ScopeFromContext(r.Context()) != auth.ScopePublic {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key := cacheKey(r)
+		c.mu.RLock()
+		entry, ok := c.entries[key]
+		c.mu.RUnlock()
+		if ok {
+			for name, values := range entry.header {
+				w.Header()[name] = values
+			}
+			w.WriteHeader(entry.status)
+			w.Write(entry.body)
+			return
+		}
+
+		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		if rec.wroteHeader && rec.status == http.StatusOK {
+			c.store(key, cachedResponse{status: rec.status, header: rec.header, body: rec.body})
+		}
+	})
+}
+
+// cacheKey identifica la respuesta a r: su URI y, si la pide, el formato de paginación
+// anterior al sobre (handlers.LegacyPaginationHeader).
+func cacheKey(r *http.Request) string {
+	key := r.URL.RequestURI()
+	if r.Header.Get(handlers.LegacyPaginationHeader) == "true" {
+		key += " legacy"
+	}
+	return key
+}
+
+func (c *ResponseCache) store(key string, entry cachedResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) < maxCachedResponses {
+		c.entries[key] = entry
+	}
+}
+
+// Purge descarta todas las respuestas guardadas.
+func (c *ResponseCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]cachedResponse)
+}
+
+// PurgeAfter descarta las respuestas guardadas tras cada petición a next. Se usa en las
+// rutas que modifican stocks fuera de una ejecución del enricher, para que los listados
+// cacheados no oculten el cambio.
+func (c *ResponseCache) PurgeAfter(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r)
+		c.Purge()
+	})
+}
+
+// Len devuelve el número de respuestas guardadas.
+func (c *ResponseCache) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.entries)
+}
+
+// Warm descarta las respuestas guardadas y vuelve a generar las de paths haciendo una
+// petición interna a handler (el router completo), para que el primer usuario no pague
+// la latencia de la consulta en frío.
+func (c *ResponseCache) Warm(handler http.Handler, paths ...string) {
+	c.Purge()
+	for _, path := range paths {
+		req, err := http.NewRequest(http.MethodGet, path, nil)
+		if err != nil {
+			log.Printf("Advertencia: ruta de precalentamiento inválida %s: %v", path, err)
+			continue
+		}
+		req.RemoteAddr = "127.0.0.1:0"
+		w := &discardWriter{header: http.Header{}}
+		handler.ServeHTTP(w, req)
+		if w.status != http.StatusOK {
+			log.Printf("Advertencia: el precalentamiento de %s respondió %d", path, w.status)
+		}
+	}
+	log.Printf("Caché de respuestas precalentada (%d rutas).", len(paths))
+}
+
+// responseRecorder copia la respuesta mientras se escribe al cliente.
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	header      http.Header
+	body        []byte
+	wroteHeader bool
+}
+
+func (rr *responseRecorder) WriteHeader(status int) {
+	if !rr.wroteHeader {
+		rr.wroteHeader = true
+		rr.status = status
+		rr.header = http.Header{}
+		for _, name := range cachedHeaders {
+			if values := rr.ResponseWriter.Header().Values(name); len(values) > 0 {
+				rr.header[http.CanonicalHeaderKey(name)] = append([]string(nil), values...)
+			}
+		}
+	}
+	rr.ResponseWriter.WriteHeader(status)
+}
+
+func (rr *responseRecorder) Write(b []byte) (int, error) {
+	if !rr.wroteHeader {
+		rr.WriteHeader(http.StatusOK)
+	}
+	rr.body = append(rr.body, b...)
+	return rr.ResponseWriter.Write(b)
+}
+
+// Unwrap permite a http.ResponseController acceder al ResponseWriter original.
+func (rr *responseRecorder) Unwrap() http.ResponseWriter {
+	return rr.ResponseWriter
+}
+
+// discardWriter es el ResponseWriter de las peticiones de precalentamiento: solo
+// interesa que la respuesta quede en la caché.
+type discardWriter struct {
+	header http.Header
+	status int
+}
+
+func (w *discardWriter) Header() http.Header { return w.header }
+
+func (w *discardWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *discardWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return len(b), nil
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jannin2/stock-app/backend/auth"
+	"github.com/jannin2/stock-app/backend/handlers"
+)
+
+func TestResponseCache(t *testing.T) {
+	t.Setenv("ADMIN_API_KEY", "secreto")
+
+	calls := 0
+	cache := NewResponseCache()
+	r := chi.NewRouter()
+	r.Route("/api/v1", func(r chi.Router) {
+		r.Use(auth.Middleware)
+		r.Use(CacheHeaders)
+		r.With(cache.Middleware).Get("/stocks", func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("X-Total-Count", strconv.Itoa(calls))
+			w.Write([]byte(`[]`))
+		})
+	})
+	get := func(path, adminKey string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if adminKey != "" {
+			req.Header.Set(auth.AdminKeyHeader, adminKey)
+		}
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	get("/api/v1/stocks", "")
+	rr := get("/api/v1/stocks", "")
+	if calls != 1 || rr.Header().Get("X-Total-Count") != "1" || rr.Body.String() != "[]" {
+		t.Errorf("❌ la segunda petición debería servirse desde la caché: %d llamadas, cabeceras %v", calls, rr.Header())
+	}
+	if got := rr.Header().Get("Cache-Control"); got != "public, max-age=30" {
+		t.Errorf("❌ Cache-Control = %q en una respuesta cacheada", got)
+	}
+
+	// Otra consulta es otra entrada, y las peticiones de administrador no se cachean.
+	get("/api/v1/stocks?limit=5", "")
+	get("/api/v1/stocks", "secreto")
+	if calls != 3 || cache.Len() != 2 {
+		t.Errorf("❌ se esperaban 3 llamadas y 2 entradas, se obtuvieron %d y %d", calls, cache.Len())
+	}
+
+	// El formato de paginación anterior al sobre es otra entrada.
+	legacy := httptest.NewRequest(http.MethodGet, "/api/v1/stocks", nil)
+	legacy.Header.Set(handlers.LegacyPaginationHeader, "true")
+	r.ServeHTTP(httptest.NewRecorder(), legacy)
+	if calls != 4 || cache.Len() != 3 {
+		t.Errorf("❌ se esperaban 4 llamadas y 3 entradas, se obtuvieron %d y %d", calls, cache.Len())
+	}
+
+	// Warm descarta lo guardado y regenera las rutas indicadas.
+	cache.Warm(r, "/api/v1/stocks")
+	if calls != 5 || cache.Len() != 1 {
+		t.Errorf("❌ tras Warm se esperaban 5 llamadas y 1 entrada, se obtuvieron %d y %d", calls, cache.Len())
+	}
+	if rr := get("/api/v1/stocks", ""); calls != 5 || rr.Header().Get("X-Total-Count") != "5" {
+		t.Errorf("❌ la respuesta precalentada no se sirvió desde la caché: %d llamadas", calls)
+	}
+
+	// Una escritura con PurgeAfter descarta lo guardado.
+	cache.PurgeAfter(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).
+		ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/v1/stocks", nil))
+	if cache.Len() != 0 {
+		t.Errorf("❌ tras PurgeAfter quedan %d entradas", cache.Len())
+	}
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/cors"
+
+	"github.com/jannin2/stock-app/backend/auth"
+	"github.com/jannin2/stock-app/backend/config"
+	"github.com/jannin2/stock-app/backend/database"
+	"github.com/jannin2/stock-app/backend/handlers"
+)
+
+// Server reúne los manejadores y el estado compartido que monta SetupServer.
+type Server struct {
+	Stocks        *handlers.StockHandlers
+	Quotes        *handlers.QuoteHandlers
+	Streams       *handlers.StreamHandlers
+	Users         *handlers.UserHandlers
+	Status        *handlers.StatusHandlers
+	Webhooks      *handlers.WebhookHandlers
+	Watchlists    *handlers.WatchlistHandlers
+	Jobs          *handlers.JobHandlers
+	Health        *handlers.HealthHandlers
+	Pool          *database.PoolMonitor
+	ResponseCache *ResponseCache
+}
+
+// SetupServer monta en r la aplicación completa: log de peticiones, recuperación de
+// pánicos, CORS, sondas de salud, bloqueo de /api mientras la base de datos no esté lista
+// o el pool esté saturado y las rutas de SetupRouter. Lo usan tanto el servidor como el
+// smoke test, para que este compruebe el mismo cableado que se despliega.
+func SetupServer(r *chi.Mux, s Server) {
+	r.Use(requestLogger)
+	r.Use(middleware.Recoverer)
+
+	// Antes de cualquier ruta, para que las respuestas de error también lleven CORS
+	r.Use(cors.Handler(cors.Options{
+		AllowedOrigins: []string{"http://localhost:5173"}, // Allow your frontend origin
+		AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders: []string{
+			"Accept", "Authorization", "Content-Type", "X-CSRF-Token", auth.AdminKeyHeader,
+			auth.ImpersonateHeader, auth.ImpersonationReasonHeader, auth.AdminActorHeader,
+			handlers.LegacyPaginationHeader,
+		},
+		ExposedHeaders: []string{
+			"Link", "X-Total-Count", "X-Total-Count-Approximate", "ETag", "Content-Range", "X-Next-Page-Token", "X-Data-As-Of", "X-As-Of",
+			"X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset", "Retry-After", DegradedHeader, "Warning",
+			auth.ImpersonatedUserHeader, auth.ImpersonatedEmailHeader, auth.ImpersonatedByHeader,
+			"Deprecation", "Sunset",
+		},
+		AllowCredentials: true,
+		MaxAge:           300,
+	}))
+
+	// Sondas de salud y bloqueo de /api mientras la base de datos no esté lista
+	r.Use(s.Health.RequireReady)
+	r.Use(handlers.RequirePoolCapacity(s.Pool))
+	r.Get(handlers.LivenessPath, s.Health.Liveness)
+	r.Get(handlers.ReadinessPath, s.Health.Readiness)
+
+	SetupRouter(r, s.Stocks, s.Quotes, s.Streams, s.Users, s.Status, s.Webhooks, s.Watchlists, s.Jobs, s.ResponseCache)
+}
+
+// requestLogger registra cada petición HTTP solo si LOG_LEVEL es debug o info, consultando
+// el nivel en cada petición para respetar las recargas de configuración.
+func requestLogger(next http.Handler) http.Handler {
+	logged := middleware.Logger(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if config.Current().LogEnabled(config.LogLevelInfo) {
+			logged.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
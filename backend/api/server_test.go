@@ -0,0 +1,58 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jannin2/stock-app/backend/database"
+	"github.com/jannin2/stock-app/backend/handlers"
+)
+
+// TestSetupServer comprueba que SetupServer monta, además de las rutas, las sondas de
+// salud, el bloqueo mientras la base de datos no está lista o el pool está saturado y CORS.
+func TestSetupServer(t *testing.T) {
+	readiness := &database.Readiness{}
+	pool := database.NewPoolMonitor(nil)
+	r := chi.NewRouter()
+	SetupServer(r, Server{
+		Health:        handlers.NewHealthHandlers(readiness, DegradedPaths...),
+		Pool:          pool,
+		ResponseCache: NewResponseCache(),
+	})
+
+	serve := func(method, path string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := serve(http.MethodGet, handlers.LivenessPath, nil); rec.Code != http.StatusOK {
+		t.Errorf("❌ %s devolvió %d, se esperaba 200", handlers.LivenessPath, rec.Code)
+	}
+	if rec := serve(http.MethodGet, "/api/v1/stocks/schema", nil); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("❌ Con la base de datos no lista se obtuvo %d, se esperaba 503", rec.Code)
+	}
+
+	readiness.SetReady(true)
+	if rec := serve(http.MethodGet, "/api/v1/stocks/schema", nil); rec.Code != http.StatusOK {
+		t.Errorf("❌ Con la base de datos lista se obtuvo %d, se esperaba 200", rec.Code)
+	}
+	pool.SetSaturated(true)
+	if rec := serve(http.MethodGet, "/api/v1/stocks/schema", nil); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("❌ Con el pool saturado se obtuvo %d, se esperaba 503", rec.Code)
+	}
+
+	preflight := serve(http.MethodOptions, "/api/v1/stocks", http.Header{
+		"Origin":                        {"http://localhost:5173"},
+		"Access-Control-Request-Method": {http.MethodGet},
+	})
+	if got := preflight.Header().Get("Access-Control-Allow-Origin"); got != "http://localhost:5173" {
+		t.Errorf("❌ Access-Control-Allow-Origin = %q, se esperaba el origen del frontend", got)
+	}
+}
@@ -0,0 +1,131 @@
+// Package apierror da a todas las respuestas de error de la API el mismo formato JSON:
+//
+//	{"code": "not_found", "message": "Watchlist no encontrada", "details": {...}}
+//
+// code es estable y pensado para los clientes; message es el texto para el usuario; details
+// es opcional y depende del error. Los errores internos (SQL, proveedores, ...) nunca llegan
+// al cliente: se registran en el log y se responde un mensaje genérico.
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// Códigos que no se deducen del estado HTTP (ver CodeFor).
+const (
+	CodeQuotaExceeded     = "quota_exceeded"
+	CodeTwoFactorRequired = "two_factor_required"
+)
+
+// Error es una respuesta de error de la API. Se puede devolver como error desde cualquier
+// capa y escribirse con Write o WriteError.
+type Error struct {
+	Status  int         `json:"-"`
+	Code    string      `json:"code"`
+	Message string      `json:"message"`
+	Details interface{} `json:"details,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// New crea un error con el código que corresponde a status.
+func New(status int, message string) *Error {
+	return &Error{Status: status, Code: CodeFor(status), Message: message}
+}
+
+// WithCode devuelve una copia de e con otro código.
+func (e *Error) WithCode(code string) *Error {
+	c := *e
+	c.Code = code
+	return &c
+}
+
+// WithDetails devuelve una copia de e con details.
+func (e *Error) WithDetails(details interface{}) *Error {
+	c := *e
+	c.Details = details
+	return &c
+}
+
+// CodeFor devuelve el código por defecto de un estado HTTP: su texto en minúsculas con
+// guiones bajos ("not_found", "too_many_requests", "internal_server_error", ...).
+func CodeFor(status int) string {
+	text := http.StatusText(status)
+	if text == "" {
+		return "error"
+	}
+	return strings.ReplaceAll(strings.ToLower(text), " ", "_")
+}
+
+// Write escribe e como respuesta. Como http.Error, borra Content-Length y marca la respuesta
+// como nosniff; las demás cabeceras que ya se fijaron se mantienen.
+func Write(w http.ResponseWriter, e *Error) {
+	header := w.Header()
+	header.Del("Content-Length")
+	header.Set("Content-Type", "application/json; charset=utf-8")
+	header.Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(e.Status)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.Encode(e) // Solo falla si el cliente se desconectó
+}
+
+// HTTPError sustituye a http.Error: mismo uso, pero responde con el formato común.
+func HTTPError(w http.ResponseWriter, message string, status int) {
+	Write(w, New(status, message))
+}
+
+// NotFound sustituye a http.NotFound y sirve como manejador de las rutas que no existen.
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	HTTPError(w, "Ruta no encontrada", http.StatusNotFound)
+}
+
+// Internal responde 500 con message y registra err, que no se envía porque puede contener
+// detalles internos como consultas SQL.
+func Internal(w http.ResponseWriter, message string, err error) {
+	log.Printf("ERROR: %s: %v", message, err)
+	Write(w, New(http.StatusInternalServerError, message))
+}
+
+// Mapping asocia un error conocido (comparado con errors.Is) con su respuesta.
+type Mapping struct {
+	Err     error
+	Status  int
+	Code    string // Vacío: el de CodeFor(Status)
+	Message string // Texto para el usuario
+}
+
+// From devuelve la respuesta de err: el propio *Error si lo es o lo envuelve, o la del
+// primer elemento de mappings que coincide. ok es false si err no es un error conocido.
+func From(err error, mappings []Mapping) (e *Error, ok bool) {
+	if errors.As(err, &e) {
+		return e, true
+	}
+	for _, m := range mappings {
+		if !errors.Is(err, m.Err) {
+			continue
+		}
+		e = New(m.Status, m.Message)
+		if m.Code != "" {
+			e.Code = m.Code
+		}
+		return e, true
+	}
+	return nil, false
+}
+
+// WriteError responde con el error conocido que corresponde a err (ver From) o, si no lo
+// es, con un 500 con message como Internal.
+func WriteError(w http.ResponseWriter, err error, mappings []Mapping, message string) {
+	if e, ok := From(err, mappings); ok {
+		Write(w, e)
+		return
+	}
+	Internal(w, message, err)
+}
@@ -0,0 +1,86 @@
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCodeFor(t *testing.T) {
+	cases := map[int]string{
+		http.StatusNotFound:            "not_found",
+		http.StatusTooManyRequests:     "too_many_requests",
+		http.StatusInternalServerError: "internal_server_error",
+		599:                            "error",
+	}
+	for status, want := range cases {
+		if got := CodeFor(status); got != want {
+			t.Errorf("❌ CodeFor(%d) = %q, se esperaba %q", status, got, want)
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	rec := httptest.NewRecorder()
+	rec.Header().Set("Content-Length", "3")
+	Write(rec, New(http.StatusUnprocessableEntity, "Límite <alcanzado>").
+		WithCode(CodeQuotaExceeded).
+		WithDetails(map[string]int{"limit": 2}))
+
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("❌ estado %d, se esperaba 422", rec.Code)
+	}
+	if got := rec.Header().Get("Content-Type"); got != "application/json; charset=utf-8" {
+		t.Errorf("❌ Content-Type = %q", got)
+	}
+	if rec.Header().Get("Content-Length") != "" {
+		t.Error("❌ Content-Length debería borrarse")
+	}
+	want := `{"code":"quota_exceeded","message":"Límite <alcanzado>","details":{"limit":2}}`
+	if got := strings.TrimSpace(rec.Body.String()); got != want {
+		t.Errorf("❌ cuerpo %s, se esperaba %s", got, want)
+	}
+}
+
+func TestWriteError(t *testing.T) {
+	errMissing := errors.New("no existe")
+	mappings := []Mapping{
+		{Err: errMissing, Status: http.StatusNotFound, Message: "Recurso no encontrado"},
+		{Err: errMissing, Status: http.StatusGone, Message: "No debería usarse: gana el primero"},
+	}
+	decode := func(rec *httptest.ResponseRecorder) Error {
+		var e Error
+		if err := json.Unmarshal(rec.Body.Bytes(), &e); err != nil {
+			t.Fatalf("❌ el cuerpo no es JSON: %v: %s", err, rec.Body)
+		}
+		return e
+	}
+
+	// Un error conocido, aunque venga envuelto, responde con su Mapping
+	rec := httptest.NewRecorder()
+	WriteError(rec, fmt.Errorf("consulta: %w", errMissing), mappings, "Error al leer")
+	if e := decode(rec); rec.Code != http.StatusNotFound || e.Code != "not_found" || e.Message != "Recurso no encontrado" {
+		t.Errorf("❌ error conocido: estado %d, %+v", rec.Code, e)
+	}
+
+	// Un *Error envuelto se responde tal cual
+	rec = httptest.NewRecorder()
+	WriteError(rec, fmt.Errorf("validación: %w", New(http.StatusConflict, "Ya existe")), mappings, "Error al leer")
+	if e := decode(rec); rec.Code != http.StatusConflict || e.Code != "conflict" || e.Message != "Ya existe" {
+		t.Errorf("❌ *Error envuelto: estado %d, %+v", rec.Code, e)
+	}
+
+	// El resto es un 500 que no deja ver el error original
+	rec = httptest.NewRecorder()
+	WriteError(rec, errors.New(`pq: relation "stocks" does not exist`), mappings, "Error al leer")
+	if e := decode(rec); rec.Code != http.StatusInternalServerError || e.Code != "internal_server_error" || e.Message != "Error al leer" {
+		t.Errorf("❌ error interno: estado %d, %+v", rec.Code, e)
+	}
+	if strings.Contains(rec.Body.String(), "pq:") {
+		t.Errorf("❌ la respuesta filtra el error interno: %s", rec.Body)
+	}
+}
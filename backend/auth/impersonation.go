@@ -0,0 +1,139 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/apierror"
+	"github.com/jannin2/stock-app/backend/database"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// Cabeceras de la suplantación. Un administrador (con AdminKeyHeader) que envía
+// ImpersonateHeader y ImpersonationReasonHeader hace la petición como ese usuario.
+const (
+	ImpersonateHeader         = "X-Impersonate-User"     // ID del usuario suplantado
+	ImpersonationReasonHeader = "X-Impersonation-Reason" // Obligatoria; ej. el ticket de soporte
+	AdminActorHeader          = "X-Admin-Actor"          // Quién es el administrador, para la auditoría
+)
+
+// Cabeceras de respuesta con las que el frontend muestra el aviso de suplantación.
+const (
+	ImpersonatedUserHeader  = "X-Impersonated-User"
+	ImpersonatedEmailHeader = "X-Impersonated-Email"
+	ImpersonatedByHeader    = "X-Impersonated-By"
+)
+
+// AuditLog registra las acciones de los administradores sobre cuentas de usuario.
+type AuditLog interface {
+	RecordAudit(ctx context.Context, entry models.AuditEntry) error
+}
+
+var (
+	auditMu  sync.RWMutex
+	auditLog AuditLog
+)
+
+// SetAuditLog registra el registro de auditoría. Sin él, la suplantación está desactivada.
+func SetAuditLog(l AuditLog) {
+	auditMu.Lock()
+	defer auditMu.Unlock()
+	auditLog = l
+}
+
+func currentAuditLog() AuditLog {
+	auditMu.RLock()
+	defer auditMu.RUnlock()
+	return auditLog
+}
+
+// Impersonation describe una petición de un administrador hecha como otro usuario.
+type Impersonation struct {
+	UserID uuid.UUID
+	Email  string
+	Actor  string
+	Reason string
+}
+
+type impersonationContextKey struct{}
+
+// ImpersonationFromContext devuelve la suplantación en curso en la petición, si la hay.
+func ImpersonationFromContext(ctx context.Context) (Impersonation, bool) {
+	imp, ok := ctx.Value(impersonationContextKey{}).(Impersonation)
+	return imp, ok
+}
+
+// impersonate atiende la petición de un administrador como el usuario de ImpersonateHeader.
+// La suplantación es de solo lectura (GET y HEAD) y con el scope del usuario, no el de
+// administrador. Cada petición queda en el registro de auditoría antes de atenderse: si no
+// se puede registrar, no se atiende.
+func impersonate(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	userID, err := uuid.Parse(r.Header.Get(ImpersonateHeader))
+	if err != nil {
+		apierror.HTTPError(w, "Cabecera "+ImpersonateHeader+" inválida: debe ser el ID del usuario", http.StatusBadRequest)
+		return
+	}
+	reason := strings.TrimSpace(r.Header.Get(ImpersonationReasonHeader))
+	if reason == "" {
+		apierror.HTTPError(w, "La suplantación requiere la cabecera "+ImpersonationReasonHeader, http.StatusBadRequest)
+		return
+	}
+	store, audit := currentUserStore(), currentAuditLog()
+	if store == nil || audit == nil {
+		apierror.HTTPError(w, "La suplantación no está disponible", http.StatusServiceUnavailable)
+		return
+	}
+
+	creds, err := store.GetCredentials(r.Context(), userID)
+	if errors.Is(err, database.ErrUserNotFound) {
+		apierror.HTTPError(w, "Usuario no encontrado", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("ERROR: no se pudo obtener el usuario a suplantar %s: %v", userID, err)
+		apierror.HTTPError(w, "Error al obtener el usuario a suplantar", http.StatusInternalServerError)
+		return
+	}
+
+	actor := strings.TrimSpace(r.Header.Get(AdminActorHeader))
+	if actor == "" {
+		actor = "admin"
+	}
+	imp := Impersonation{UserID: userID, Email: creds.Email, Actor: actor, Reason: reason}
+	readOnly := r.Method == http.MethodGet || r.Method == http.MethodHead
+
+	entry := models.AuditEntry{
+		Actor:        actor,
+		Action:       models.AuditImpersonation,
+		TargetUserID: &userID,
+		Method:       r.Method,
+		Path:         r.URL.RequestURI(),
+		Reason:       reason,
+		RemoteAddr:   r.RemoteAddr,
+	}
+	if !readOnly {
+		entry.Action = models.AuditImpersonationDenied
+	}
+	if err := audit.RecordAudit(r.Context(), entry); err != nil {
+		log.Printf("ERROR: no se pudo auditar la suplantación de %s por %s: %v", userID, actor, err)
+		apierror.HTTPError(w, "No se pudo registrar la suplantación en el registro de auditoría", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set(ImpersonatedUserHeader, userID.String())
+	w.Header().Set(ImpersonatedEmailHeader, creds.Email)
+	w.Header().Set(ImpersonatedByHeader, actor)
+	if !readOnly {
+		apierror.HTTPError(w, "La suplantación es de solo lectura", http.StatusForbidden)
+		return
+	}
+
+	ctx := WithUser(r.Context(), userID)
+	ctx = context.WithValue(ctx, impersonationContextKey{}, imp)
+	next.ServeHTTP(w, r.WithContext(WithScope(ctx, ScopeUser)))
+}
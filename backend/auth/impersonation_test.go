@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// memoryAuditLog guarda las entradas en memoria, o falla con err.
+type memoryAuditLog struct {
+	entries []models.AuditEntry
+	err     error
+}
+
+func (l *memoryAuditLog) RecordAudit(_ context.Context, entry models.AuditEntry) error {
+	if l.err != nil {
+		return l.err
+	}
+	l.entries = append(l.entries, entry)
+	return nil
+}
+
+func TestMiddleware_Impersonation(t *testing.T) {
+	t.Setenv("ADMIN_API_KEY", "clave-admin")
+	userID := uuid.New()
+	audit := &memoryAuditLog{}
+	SetUserStore(keyStore{HashAPIKey("clave-de-ana"): userID})
+	SetAuditLog(audit)
+	t.Cleanup(func() {
+		SetUserStore(nil)
+		SetAuditLog(nil)
+	})
+
+	var gotScope Scope
+	var gotUser uuid.UUID
+	var gotImp Impersonation
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotScope = ScopeFromContext(r.Context())
+		gotUser, _ = UserFromContext(r.Context())
+		gotImp, _ = ImpersonationFromContext(r.Context())
+	}))
+	serve := func(method, target, reason string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/v1/me/data", nil)
+		req.Header.Set(AdminKeyHeader, "clave-admin")
+		req.Header.Set(AdminActorHeader, "soporte@stock-app.local")
+		req.Header.Set(ImpersonateHeader, target)
+		if reason != "" {
+			req.Header.Set(ImpersonationReasonHeader, reason)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := serve(http.MethodGet, userID.String(), "Ticket #123: watchlist incorrecta")
+	if rr.Code != http.StatusOK || gotScope != ScopeUser || gotUser != userID || gotImp.Actor != "soporte@stock-app.local" {
+		t.Fatalf("❌ suplantación: estado %d, scope %v, usuario %s, %+v", rr.Code, gotScope, gotUser, gotImp)
+	}
+	if rr.Header().Get(ImpersonatedUserHeader) != userID.String() || rr.Header().Get(ImpersonatedByHeader) != "soporte@stock-app.local" {
+		t.Errorf("❌ faltan las cabeceras del aviso de suplantación: %v", rr.Header())
+	}
+	if len(audit.entries) != 1 || audit.entries[0].Action != models.AuditImpersonation || *audit.entries[0].TargetUserID != userID || audit.entries[0].Reason != "Ticket #123: watchlist incorrecta" {
+		t.Errorf("❌ entrada de auditoría inesperada: %+v", audit.entries)
+	}
+
+	// Las escrituras se rechazan, pero también se auditan
+	if rr := serve(http.MethodDelete, userID.String(), "Ticket #123"); rr.Code != http.StatusForbidden {
+		t.Errorf("❌ escritura suplantando: estado %d, se esperaba 403", rr.Code)
+	}
+	if len(audit.entries) != 2 || audit.entries[1].Action != models.AuditImpersonationDenied {
+		t.Errorf("❌ la escritura rechazada debería auditarse: %+v", audit.entries)
+	}
+
+	tests := []struct {
+		name, target, reason string
+		wantCode             int
+	}{
+		{"sin motivo", userID.String(), "", http.StatusBadRequest},
+		{"ID inválido", "ana", "Ticket #123", http.StatusBadRequest},
+		{"usuario inexistente", uuid.New().String(), "Ticket #123", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if rr := serve(http.MethodGet, tt.target, tt.reason); rr.Code != tt.wantCode {
+			t.Errorf("❌ %s: estado %d, se esperaba %d", tt.name, rr.Code, tt.wantCode)
+		}
+	}
+
+	// Sin auditoría no hay suplantación
+	audit.err = errors.New("base de datos caída")
+	gotUser = uuid.Nil
+	if rr := serve(http.MethodGet, userID.String(), "Ticket #123"); rr.Code != http.StatusServiceUnavailable || gotUser != uuid.Nil {
+		t.Errorf("❌ sin auditoría: estado %d, se esperaba 503 sin atender la petición", rr.Code)
+	}
+}
@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AccessTokenTTL es la validez de los tokens de sesión que emite POST /auth/login.
+const AccessTokenTTL = 24 * time.Hour
+
+// jwtHeader es la cabecera de los tokens de sesión, firmados con HMAC-SHA256 (tokenMAC).
+// Solo se acepta esta cabecera: un token con otro "alg" (incluido "none") es inválido.
+var jwtHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// AccessClaims son los datos de un token de sesión (JWT). Fingerprint es la huella del
+// hash de la contraseña al iniciar sesión: cambiar la contraseña cierra todas las sesiones.
+// SecondFactorAt es cuándo verificó el segundo factor esta sesión (ver
+// SignVerifiedAccessToken); la verificación no se comparte con otras sesiones ni con la
+// clave de API del usuario.
+type AccessClaims struct {
+	Subject        uuid.UUID `json:"sub"`
+	IssuedAt       int64     `json:"iat"` // Unix, en segundos
+	ExpiresAt      int64     `json:"exp"`
+	Fingerprint    string    `json:"fp"`
+	SecondFactorAt int64     `json:"2fa_at,omitempty"` // Unix, en segundos; 0 = sin verificar
+}
+
+// SignAccessToken emite un token de sesión para el usuario, firmado con la misma clave que
+// los tokens de SignToken, y devuelve cuándo caduca.
+func SignAccessToken(userID uuid.UUID, fingerprint string, now time.Time) (string, time.Time) {
+	return signAccessToken(AccessClaims{Subject: userID, Fingerprint: fingerprint}, now)
+}
+
+// SignVerifiedAccessToken vuelve a emitir el token de sesión de claims marcando que acaba
+// de verificar el segundo factor (POST /me/2fa/verify).
+func SignVerifiedAccessToken(claims AccessClaims, now time.Time) (string, time.Time) {
+	claims.SecondFactorAt = now.Unix()
+	return signAccessToken(claims, now)
+}
+
+func signAccessToken(claims AccessClaims, now time.Time) (string, time.Time) {
+	expiresAt := now.Add(AccessTokenTTL)
+	claims.IssuedAt, claims.ExpiresAt = now.Unix(), expiresAt.Unix()
+	payload, _ := json.Marshal(claims)
+	signed := jwtHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(tokenMAC(signed)), expiresAt
+}
+
+// VerifyAccessToken comprueba el algoritmo, la firma y la caducidad de un token de sesión
+// y devuelve sus datos. La huella la comprueba quien lo usa, contra la cuenta actual.
+func VerifyAccessToken(token string, now time.Time) (AccessClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != jwtHeader {
+		return AccessClaims{}, ErrInvalidToken
+	}
+	gotMAC, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(gotMAC, tokenMAC(parts[0]+"."+parts[1])) {
+		return AccessClaims{}, ErrInvalidToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return AccessClaims{}, ErrInvalidToken
+	}
+	var claims AccessClaims
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Subject == uuid.Nil {
+		return AccessClaims{}, ErrInvalidToken
+	}
+	if now.Unix() >= claims.ExpiresAt {
+		return AccessClaims{}, fmt.Errorf("%w (caducó el %s)", ErrExpiredToken, time.Unix(claims.ExpiresAt, 0).UTC().Format(time.RFC3339))
+	}
+	return claims, nil
+}
+
+// isAccessToken distingue un JWT de una clave de API, que no contiene puntos.
+func isAccessToken(token string) bool {
+	return strings.Count(token, ".") == 2
+}
@@ -0,0 +1,101 @@
+package auth
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/database"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+func TestSignAndVerifyAccessToken(t *testing.T) {
+	now := time.Date(2025, 1, 6, 12, 0, 0, 0, time.UTC)
+	userID := uuid.New()
+	token, expiresAt := SignAccessToken(userID, Fingerprint("hash"), now)
+	if !expiresAt.Equal(now.Add(AccessTokenTTL)) || !isAccessToken(token) {
+		t.Fatalf("❌ token inesperado: %s, caduca %v", token, expiresAt)
+	}
+
+	claims, err := VerifyAccessToken(token, now)
+	if err != nil || claims.Subject != userID || claims.Fingerprint != Fingerprint("hash") {
+		t.Fatalf("❌ token válido rechazado: %+v, %v", claims, err)
+	}
+	if _, err := VerifyAccessToken(token, expiresAt); !errors.Is(err, ErrExpiredToken) {
+		t.Errorf("❌ se esperaba ErrExpiredToken, se obtuvo %v", err)
+	}
+
+	// Ni otro algoritmo ni un contenido cambiado pasan la comprobación
+	parts := strings.Split(token, ".")
+	none := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`))
+	forged, _ := SignAccessToken(uuid.New(), Fingerprint("hash"), now)
+	for _, bad := range []string{
+		none + "." + parts[1] + ".",
+		parts[0] + "." + strings.Split(forged, ".")[1] + "." + parts[2],
+		parts[0] + "." + parts[1],
+		SignToken(PurposeVerifyEmail, userID, Fingerprint("hash"), now.Add(time.Hour)),
+	} {
+		if _, err := VerifyAccessToken(bad, now); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("❌ token manipulado %q aceptado: %v", bad, err)
+		}
+	}
+}
+
+// passwordStore es un UserStore con una sola cuenta y su hash de contraseña.
+type passwordStore struct{ creds models.UserCredentials }
+
+func (s *passwordStore) UserIDForAPIKey(context.Context, string) (uuid.UUID, error) {
+	return uuid.Nil, database.ErrUserNotFound
+}
+
+func (s *passwordStore) GetCredentials(_ context.Context, userID uuid.UUID) (models.UserCredentials, error) {
+	if userID != s.creds.ID {
+		return models.UserCredentials{}, database.ErrUserNotFound
+	}
+	return s.creds, nil
+}
+
+func TestMiddleware_SessionToken(t *testing.T) {
+	store := &passwordStore{creds: models.UserCredentials{User: models.User{ID: uuid.New()}, PasswordHash: "hash-1"}}
+	SetUserStore(store)
+	t.Cleanup(func() { SetUserStore(nil) })
+
+	var gotUser uuid.UUID
+	var gotSession AccessClaims
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUser, _ = UserFromContext(r.Context())
+		gotSession, _ = SessionFromContext(r.Context())
+	}))
+	token, _ := SignAccessToken(store.creds.ID, Fingerprint("hash-1"), time.Now())
+	authenticate := func() uuid.UUID {
+		gotUser = uuid.Nil
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/me/watchlists", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		return gotUser
+	}
+
+	if got := authenticate(); got != store.creds.ID {
+		t.Errorf("❌ token de sesión rechazado: usuario %s", got)
+	}
+	if gotSession.Subject != store.creds.ID || gotSession.SecondFactorAt != 0 {
+		t.Errorf("❌ sesión inesperada en el contexto: %+v", gotSession)
+	}
+	// Un token reemitido tras verificar el segundo factor lo indica en la sesión
+	now := time.Now()
+	token, _ = SignVerifiedAccessToken(gotSession, now)
+	if got := authenticate(); got != store.creds.ID || gotSession.SecondFactorAt != now.Unix() {
+		t.Errorf("❌ token verificado: usuario %s, sesión %+v", got, gotSession)
+	}
+	// Cambiar la contraseña cierra las sesiones abiertas
+	store.creds.PasswordHash = "hash-2"
+	if got := authenticate(); got != uuid.Nil {
+		t.Errorf("❌ token de una contraseña anterior aceptado: usuario %s", got)
+	}
+}
@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"os"
+
+	"github.com/jannin2/stock-app/backend/apierror"
+)
+
+// Scope representa el nivel de acceso de quien realiza la petición.
+// Los scopes están ordenados: un scope mayor incluye los permisos de los menores.
+type Scope int
+
+const (
+	ScopePublic Scope = iota // Petición sin autenticar
+	ScopeUser                // Usuario autenticado
+	ScopeAdmin               // Operador / herramientas internas
+)
+
+// AdminKeyHeader es la cabecera donde las herramientas internas envían ADMIN_API_KEY.
+const AdminKeyHeader = "X-Admin-Key"
+
+// String devuelve el nombre del scope tal y como se usa en las etiquetas `scope:"..."`.
+func (s Scope) String() string {
+	switch s {
+	case ScopeUser:
+		return "user"
+	case ScopeAdmin:
+		return "admin"
+	default:
+		return "public"
+	}
+}
+
+// ParseScope convierte el nombre de un scope en su valor. Los nombres desconocidos
+// se interpretan como ScopeAdmin para que un error tipográfico en una etiqueta
+// oculte el campo en lugar de exponerlo.
+func ParseScope(name string) Scope {
+	switch name {
+	case "", "public":
+		return ScopePublic
+	case "user":
+		return ScopeUser
+	default:
+		return ScopeAdmin
+	}
+}
+
+type scopeContextKey struct{}
+
+// WithScope devuelve una copia de ctx que transporta el scope indicado.
+func WithScope(ctx context.Context, scope Scope) context.Context {
+	return context.WithValue(ctx, scopeContextKey{}, scope)
+}
+
+// ScopeFromContext devuelve el scope de la petición, o ScopePublic si no se estableció ninguno.
+func ScopeFromContext(ctx context.Context) Scope {
+	if scope, ok := ctx.Value(scopeContextKey{}).(Scope); ok {
+		return scope
+	}
+	return ScopePublic
+}
+
+// Middleware determina el scope de cada petición y lo guarda en su contexto: ScopeAdmin
+// con ADMIN_API_KEY, ScopeUser con un token de sesión o la clave de API de un usuario activo
+// (que además queda en el contexto, ver UserFromContext) y ScopePublic en otro caso. Un administrador que
+// envía ImpersonateHeader actúa como ese usuario (ver impersonate).
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		scope := ScopePublic
+		if userID, session, ok := authenticateUser(r); ok {
+			scope = ScopeUser
+			ctx = WithUser(ctx, userID)
+			if session != nil {
+				ctx = WithSession(ctx, *session)
+			}
+		}
+		if isAdminKey(r.Header.Get(AdminKeyHeader)) {
+			if r.Header.Get(ImpersonateHeader) != "" {
+				impersonate(w, r, next)
+				return
+			}
+			scope = ScopeAdmin
+		}
+		next.ServeHTTP(w, r.WithContext(WithScope(ctx, scope)))
+	})
+}
+
+// RequireScope rechaza las peticiones cuyo scope sea menor que el indicado.
+func RequireScope(scope Scope) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			current := ScopeFromContext(r.Context())
+			if current < scope {
+				status := http.StatusForbidden
+				if current == ScopePublic {
+					status = http.StatusUnauthorized
+				}
+				apierror.HTTPError(w, "No autorizado para acceder a este recurso", status)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// isAdminKey compara la clave recibida con ADMIN_API_KEY en tiempo constante.
+// Si ADMIN_API_KEY no está configurada, ninguna petición obtiene el scope de administrador.
+func isAdminKey(key string) bool {
+	adminKey := os.Getenv("ADMIN_API_KEY")
+	if adminKey == "" || key == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(key), []byte(adminKey)) == 1
+}
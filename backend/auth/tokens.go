@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Propósitos de los tokens firmados. Un token solo es válido para el propósito con el que
+// se firmó.
+const (
+	PurposeVerifyEmail   = "verify_email"
+	PurposeResetPassword = "reset_password"
+)
+
+var (
+	// ErrInvalidToken indica que el token está mal formado, su firma no es válida o es de
+	// otro propósito.
+	ErrInvalidToken = errors.New("token inválido")
+	// ErrExpiredToken indica que el token es válido pero ya caducó.
+	ErrExpiredToken = errors.New("token caducado")
+)
+
+// TokenClaims son los datos firmados en un token. Fingerprint liga el token al estado de
+// la cuenta cuando se emitió (ej. el hash de la contraseña en un token de reseteo), de modo
+// que deja de ser válido en cuanto ese estado cambia: así un token de reseteo solo sirve
+// una vez.
+type TokenClaims struct {
+	Purpose     string    `json:"p"`
+	UserID      uuid.UUID `json:"u"`
+	Fingerprint string    `json:"f"`
+	ExpiresAt   int64     `json:"e"` // Unix, en segundos
+}
+
+// tokenSecret es la clave HMAC de los tokens: AUTH_TOKEN_SECRET o, si no está definida,
+// una aleatoria por proceso (los tokens emitidos dejan de valer al reiniciar).
+var tokenSecret = sync.OnceValue(func() []byte {
+	if secret := os.Getenv("AUTH_TOKEN_SECRET"); secret != "" {
+		return []byte(secret)
+	}
+	log.Println("Advertencia: AUTH_TOKEN_SECRET no está configurada; los enlaces de verificación y reseteo y las sesiones caducarán al reiniciar el servidor.")
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		log.Fatalf("Error al generar la clave de los tokens: %v", err)
+	}
+	return secret
+})
+
+// Fingerprint devuelve una huella corta de value para TokenClaims.Fingerprint.
+func Fingerprint(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:8])
+}
+
+// SignToken firma un token de purpose para el usuario, válido hasta expiresAt.
+func SignToken(purpose string, userID uuid.UUID, fingerprint string, expiresAt time.Time) string {
+	payload, _ := json.Marshal(TokenClaims{Purpose: purpose, UserID: userID, Fingerprint: fingerprint, ExpiresAt: expiresAt.Unix()})
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(tokenMAC(encoded))
+}
+
+// VerifyToken comprueba la firma, el propósito y la caducidad de un token y devuelve sus
+// datos. La huella la debe comprobar quien lo usa, contra el estado actual de la cuenta.
+func VerifyToken(token, purpose string, now time.Time) (TokenClaims, error) {
+	encoded, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return TokenClaims{}, ErrInvalidToken
+	}
+	gotMAC, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(gotMAC, tokenMAC(encoded)) {
+		return TokenClaims{}, ErrInvalidToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return TokenClaims{}, ErrInvalidToken
+	}
+	var claims TokenClaims
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Purpose != purpose {
+		return TokenClaims{}, ErrInvalidToken
+	}
+	if now.Unix() >= claims.ExpiresAt {
+		return TokenClaims{}, fmt.Errorf("%w (caducó el %s)", ErrExpiredToken, time.Unix(claims.ExpiresAt, 0).UTC().Format(time.RFC3339))
+	}
+	return claims, nil
+}
+
+func tokenMAC(encoded string) []byte {
+	mac := hmac.New(sha256.New, tokenSecret())
+	mac.Write([]byte(encoded))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestSignAndVerifyToken(t *testing.T) {
+	now := time.Date(2025, 1, 6, 12, 0, 0, 0, time.UTC)
+	userID := uuid.New()
+	token := SignToken(PurposeResetPassword, userID, Fingerprint("hash"), now.Add(time.Hour))
+
+	claims, err := VerifyToken(token, PurposeResetPassword, now)
+	if err != nil || claims.UserID != userID || claims.Fingerprint != Fingerprint("hash") {
+		t.Fatalf("❌ token válido rechazado: %+v, %v", claims, err)
+	}
+	if _, err := VerifyToken(token, PurposeVerifyEmail, now); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("❌ un token de reseteo no debería servir para verificar el e-mail: %v", err)
+	}
+	if _, err := VerifyToken(token, PurposeResetPassword, now.Add(time.Hour)); !errors.Is(err, ErrExpiredToken) {
+		t.Errorf("❌ se esperaba ErrExpiredToken, se obtuvo %v", err)
+	}
+
+	// Cambiar el contenido invalida la firma
+	payload, sig, _ := strings.Cut(token, ".")
+	forged := SignToken(PurposeResetPassword, uuid.New(), Fingerprint("hash"), now.Add(time.Hour))
+	forgedPayload, _, _ := strings.Cut(forged, ".")
+	for _, bad := range []string{forgedPayload + "." + sig, payload, payload + ".", ""} {
+		if _, err := VerifyToken(bad, PurposeResetPassword, now); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("❌ token manipulado %q aceptado: %v", bad, err)
+		}
+	}
+}
+
+func TestHashAndCheckPassword(t *testing.T) {
+	prev := passwordIterations
+	passwordIterations = 1000
+	t.Cleanup(func() { passwordIterations = prev })
+
+	hash, err := HashPassword("correct horse battery")
+	if err != nil {
+		t.Fatalf("❌ error inesperado: %v", err)
+	}
+	other, _ := HashPassword("correct horse battery")
+	if hash == other {
+		t.Errorf("❌ dos hashes de la misma contraseña deberían tener sales distintas")
+	}
+	if !CheckPassword("correct horse battery", hash) {
+		t.Errorf("❌ la contraseña correcta no coincide con su hash")
+	}
+	for _, stored := range []string{hash, "", "md5$abc", "pbkdf2-sha256$x$y$z"} {
+		if CheckPassword("incorrect horse battery", stored) {
+			t.Errorf("❌ contraseña incorrecta aceptada contra %q", stored)
+		}
+	}
+	if err := ValidatePassword("corta"); !errors.Is(err, ErrWeakPassword) {
+		t.Errorf("❌ se esperaba ErrWeakPassword, se obtuvo %v", err)
+	}
+}
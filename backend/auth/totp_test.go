@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// Vectores del RFC 6238 (SHA-1), truncados a 6 dígitos.
+func TestTOTPCode_RFC6238(t *testing.T) {
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ" // "12345678901234567890"
+	for unix, want := range map[int64]string{59: "287082", 1111111109: "081804", 2000000000: "279037"} {
+		got, err := TOTPCode(secret, time.Unix(unix, 0))
+		if err != nil || got != want {
+			t.Errorf("❌ TOTPCode(%d) = %s, %v; se esperaba %s", unix, got, err, want)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret, err := NewTOTPSecret()
+	if err != nil {
+		t.Fatalf("❌ error inesperado: %v", err)
+	}
+	now := time.Date(2025, 1, 6, 12, 0, 0, 0, time.UTC)
+	code, _ := TOTPCode(secret, now)
+
+	if step, ok := ValidateTOTP(secret, code, now.Add(25*time.Second)); !ok || step != totpStep(now) {
+		t.Errorf("❌ un código del paso anterior debería aceptarse por el desfase de reloj")
+	}
+	if _, ok := ValidateTOTP(secret, code, now.Add(2*time.Minute)); ok {
+		t.Errorf("❌ un código de hace dos minutos no debería aceptarse")
+	}
+	if _, ok := ValidateTOTP(secret, "12345", now); ok {
+		t.Errorf("❌ un código de 5 dígitos no debería aceptarse")
+	}
+}
+
+func TestHashRecoveryCode_IgnoresFormatting(t *testing.T) {
+	codes, err := NewRecoveryCodes()
+	if err != nil || len(codes) != RecoveryCodeCount {
+		t.Fatalf("❌ NewRecoveryCodes = %v, %v", codes, err)
+	}
+	if HashRecoveryCode(codes[0]) != HashRecoveryCode(" "+strings.ToUpper(strings.ReplaceAll(codes[0], "-", ""))+" ") {
+		t.Errorf("❌ el hash debería ignorar mayúsculas, espacios y guiones")
+	}
+}
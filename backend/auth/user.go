@@ -0,0 +1,151 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/database"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// UserStore resuelve la clave de API de un usuario en su ID. Solo recibe el hash de la
+// clave (ver HashAPIKey); las claves en claro no se guardan. Devuelve
+// database.ErrUserNotFound si la clave no pertenece a ningún usuario activo.
+// GetCredentials se usa para comprobar los tokens de sesión y el usuario suplantado por
+// un administrador.
+type UserStore interface {
+	UserIDForAPIKey(ctx context.Context, keyHash string) (uuid.UUID, error)
+	GetCredentials(ctx context.Context, userID uuid.UUID) (models.UserCredentials, error)
+}
+
+var (
+	storeMu sync.RWMutex
+	store   UserStore
+)
+
+// SetUserStore registra el almacén de usuarios que consulta Middleware. Sin almacén,
+// ninguna petición se autentica como usuario.
+func SetUserStore(s UserStore) {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+	store = s
+}
+
+func currentUserStore() UserStore {
+	storeMu.RLock()
+	defer storeMu.RUnlock()
+	return store
+}
+
+// NewAPIKey genera una clave de API aleatoria. Se muestra al usuario una sola vez; solo
+// se guarda su hash.
+func NewAPIKey() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("error al generar la clave de API: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashAPIKey devuelve el hash (SHA-256 en hexadecimal) con el que se guarda una clave de API.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+type userContextKey struct{}
+
+// WithUser devuelve una copia de ctx que transporta el ID del usuario autenticado.
+func WithUser(ctx context.Context, userID uuid.UUID) context.Context {
+	return context.WithValue(ctx, userContextKey{}, userID)
+}
+
+// UserFromContext devuelve el ID del usuario autenticado en la petición, si lo hay.
+func UserFromContext(ctx context.Context) (uuid.UUID, bool) {
+	userID, ok := ctx.Value(userContextKey{}).(uuid.UUID)
+	return userID, ok
+}
+
+type sessionContextKey struct{}
+
+// WithSession devuelve una copia de ctx que transporta los datos del token de sesión con el
+// que se autenticó la petición.
+func WithSession(ctx context.Context, claims AccessClaims) context.Context {
+	return context.WithValue(ctx, sessionContextKey{}, claims)
+}
+
+// SessionFromContext devuelve los datos del token de sesión de la petición; no hay si se
+// autenticó con una clave de API o no se autenticó.
+func SessionFromContext(ctx context.Context) (AccessClaims, bool) {
+	claims, ok := ctx.Value(sessionContextKey{}).(AccessClaims)
+	return claims, ok
+}
+
+// authenticateUser busca al usuario del token de sesión o la clave de API enviados como
+// "Authorization: Bearer <token>" y, con un token de sesión, devuelve también sus datos. Un
+// token caducado, una clave desconocida o de una cuenta borrada dejan la petición sin
+// autenticar.
+func authenticateUser(r *http.Request) (uuid.UUID, *AccessClaims, bool) {
+	key, ok := bearerToken(r.Header.Get("Authorization"))
+	s := currentUserStore()
+	if !ok || s == nil {
+		return uuid.Nil, nil, false
+	}
+	if isAccessToken(key) {
+		claims, ok := authenticateSession(r.Context(), s, key)
+		if !ok {
+			return uuid.Nil, nil, false
+		}
+		return claims.Subject, &claims, true
+	}
+	userID, err := s.UserIDForAPIKey(r.Context(), HashAPIKey(key))
+	if err != nil {
+		if !errors.Is(err, database.ErrUserNotFound) {
+			log.Printf("Advertencia: no se pudo autenticar la clave de API: %v", err)
+		}
+		return uuid.Nil, nil, false
+	}
+	return userID, nil, true
+}
+
+// authenticateSession valida un token de sesión contra la cuenta actual: la cuenta no
+// puede estar borrada y su contraseña no puede haber cambiado desde que se emitió.
+func authenticateSession(ctx context.Context, s UserStore, token string) (AccessClaims, bool) {
+	claims, err := VerifyAccessToken(token, time.Now())
+	if err != nil {
+		return AccessClaims{}, false
+	}
+	creds, err := s.GetCredentials(ctx, claims.Subject)
+	if err != nil {
+		if !errors.Is(err, database.ErrUserNotFound) {
+			log.Printf("Advertencia: no se pudo autenticar el token de sesión: %v", err)
+		}
+		return AccessClaims{}, false
+	}
+	if !hmac.Equal([]byte(claims.Fingerprint), []byte(Fingerprint(creds.PasswordHash))) {
+		return AccessClaims{}, false
+	}
+	return claims, true
+}
+
+// bearerToken extrae el token de una cabecera Authorization con esquema Bearer.
+func bearerToken(header string) (string, bool) {
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/database"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// keyStore resuelve los hashes de claves conocidas.
+type keyStore map[string]uuid.UUID
+
+func (s keyStore) UserIDForAPIKey(_ context.Context, keyHash string) (uuid.UUID, error) {
+	if id, ok := s[keyHash]; ok {
+		return id, nil
+	}
+	return uuid.Nil, database.ErrUserNotFound
+}
+
+func (s keyStore) GetCredentials(_ context.Context, userID uuid.UUID) (models.UserCredentials, error) {
+	for _, id := range s {
+		if id == userID {
+			return models.UserCredentials{User: models.User{ID: id, Email: id.String()[:8] + "@example.com"}}, nil
+		}
+	}
+	return models.UserCredentials{}, database.ErrUserNotFound
+}
+
+func TestMiddleware_UserAPIKey(t *testing.T) {
+	userID := uuid.New()
+	SetUserStore(keyStore{HashAPIKey("clave-de-ana"): userID})
+	t.Cleanup(func() { SetUserStore(nil) })
+
+	var gotScope Scope
+	var gotUser uuid.UUID
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotScope = ScopeFromContext(r.Context())
+		gotUser, _ = UserFromContext(r.Context())
+	}))
+
+	tests := []struct {
+		header    string
+		wantScope Scope
+		wantUser  uuid.UUID
+	}{
+		{"Bearer clave-de-ana", ScopeUser, userID},
+		{"bearer  clave-de-ana ", ScopeUser, userID},
+		{"Bearer otra-clave", ScopePublic, uuid.Nil},
+		{"Basic clave-de-ana", ScopePublic, uuid.Nil},
+		{"", ScopePublic, uuid.Nil},
+	}
+	for _, tt := range tests {
+		gotScope, gotUser = ScopeAdmin, uuid.Nil
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/me/data", nil)
+		req.Header.Set("Authorization", tt.header)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		if gotScope != tt.wantScope || gotUser != tt.wantUser {
+			t.Errorf("❌ %q: scope %s y usuario %s, se esperaba %s y %s", tt.header, gotScope, gotUser, tt.wantScope, tt.wantUser)
+		}
+	}
+}
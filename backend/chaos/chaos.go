@@ -0,0 +1,121 @@
+// Package chaos inyecta fallos (latencia, errores 5xx y JSON malformado) en la API y en
+// las llamadas a proveedores, para comprobar en desarrollo que los reintentos, los
+// valores nulos y el manejo de errores funcionan antes de una caída real.
+//
+// Solo actúa con el feature flag "chaos" activado; las tasas se leen de config.Chaos en
+// cada petición, así que se pueden ajustar recargando la configuración.
+package chaos
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/apierror"
+	"github.com/jannin2/stock-app/backend/config"
+)
+
+// Header marca las respuestas con un fallo inyectado, para distinguirlas de fallos reales.
+const Header = "X-Chaos"
+
+// malformedBody es JSON truncado, como el de una conexión cortada a mitad de respuesta.
+const malformedBody = `{"items":[{"ticker":"AAPL","current_price":`
+
+// Sustituibles en los tests.
+var (
+	randFloat = rand.Float64
+	sleep     = time.Sleep
+)
+
+// fault es el fallo elegido para una petición.
+type fault int
+
+const (
+	faultNone fault = iota
+	faultError
+	faultMalformed
+)
+
+// decide aplica la latencia (si toca) y elige si la petición falla y cómo. Cada tipo de
+// fallo se sortea por separado con su propia tasa.
+func decide(target string) (fault, string) {
+	cfg := config.Current()
+	if !cfg.ChaosActive(target) {
+		return faultNone, ""
+	}
+
+	injected := ""
+	if cfg.Chaos.LatencyRate > 0 && randFloat() < cfg.Chaos.LatencyRate {
+		sleep(cfg.Chaos.Latency)
+		injected = "latency"
+	}
+	switch {
+	case cfg.Chaos.ErrorRate > 0 && randFloat() < cfg.Chaos.ErrorRate:
+		return faultError, "error"
+	case cfg.Chaos.MalformedRate > 0 && randFloat() < cfg.Chaos.MalformedRate:
+		return faultMalformed, "malformed"
+	}
+	return faultNone, injected
+}
+
+// Middleware inyecta fallos en las respuestas de la API.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f, injected := decide(config.ChaosTargetAPI)
+		if injected != "" {
+			w.Header().Set(Header, injected)
+		}
+		switch f {
+		case faultError:
+			apierror.HTTPError(w, "chaos: error inyectado", http.StatusServiceUnavailable)
+		case faultMalformed:
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			io.WriteString(w, malformedBody)
+		default:
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// Transport envuelve next para inyectar fallos en las llamadas a proveedores.
+func Transport(next http.RoundTripper) http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		f, injected := decide(config.ChaosTargetProviders)
+		switch f {
+		case faultError:
+			log.Printf("chaos: error inyectado en %s", req.URL.Host)
+			return syntheticResponse(req, http.StatusBadGateway, `{"error":"chaos: error inyectado"}`, injected), nil
+		case faultMalformed:
+			log.Printf("chaos: JSON malformado inyectado en %s", req.URL.Host)
+			return syntheticResponse(req, http.StatusOK, malformedBody, injected), nil
+		}
+		resp, err := next.RoundTrip(req)
+		if err == nil && injected != "" {
+			resp.Header.Set(Header, injected)
+		}
+		return resp, err
+	})
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func syntheticResponse(req *http.Request, status int, body, injected string) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}, Header: []string{injected}},
+		Body:          io.NopCloser(bytes.NewReader([]byte(body))),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,124 @@
+package chaos
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/config"
+)
+
+// withChaos activa el modo chaos con cfg y sorteos fijos para la duración del test.
+func withChaos(t *testing.T, chaosCfg config.ChaosConfig, roll float64) *time.Duration {
+	t.Helper()
+	prevConfig, prevRand, prevSleep := config.Current(), randFloat, sleep
+	t.Cleanup(func() {
+		config.Set(prevConfig)
+		randFloat, sleep = prevRand, prevSleep
+	})
+
+	cfg := config.Default()
+	cfg.FeatureFlags = map[string]bool{config.FlagChaos: true}
+	cfg.Chaos = chaosCfg
+	config.Set(cfg)
+
+	var slept time.Duration
+	randFloat = func() float64 { return roll }
+	sleep = func(d time.Duration) { slept += d }
+	return &slept
+}
+
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"ok":true}`))
+})
+
+func TestMiddleware(t *testing.T) {
+	targets := []string{config.ChaosTargetAPI}
+
+	t.Run("latencia", func(t *testing.T) {
+		slept := withChaos(t, config.ChaosConfig{LatencyRate: 0.5, Latency: time.Second, Targets: targets}, 0.1)
+		rr := httptest.NewRecorder()
+		Middleware(okHandler).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/stocks", nil))
+		if *slept != time.Second || rr.Code != http.StatusOK || rr.Header().Get(Header) != "latency" {
+			t.Errorf("❌ latencia %s, estado %d, %s %q", *slept, rr.Code, Header, rr.Header().Get(Header))
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		withChaos(t, config.ChaosConfig{ErrorRate: 0.5, Targets: targets}, 0.1)
+		rr := httptest.NewRecorder()
+		Middleware(okHandler).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/stocks", nil))
+		if rr.Code != http.StatusServiceUnavailable || rr.Header().Get(Header) != "error" {
+			t.Errorf("❌ estado %d, %s %q; se esperaba 503 y error", rr.Code, Header, rr.Header().Get(Header))
+		}
+	})
+
+	t.Run("JSON malformado", func(t *testing.T) {
+		withChaos(t, config.ChaosConfig{MalformedRate: 0.5, Targets: targets}, 0.1)
+		rr := httptest.NewRecorder()
+		Middleware(okHandler).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/stocks", nil))
+		var v interface{}
+		if rr.Code != http.StatusOK || json.Unmarshal(rr.Body.Bytes(), &v) == nil {
+			t.Errorf("❌ se esperaba un 200 con JSON inválido, se obtuvo %d: %s", rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("sorteo por encima de la tasa", func(t *testing.T) {
+		withChaos(t, config.ChaosConfig{ErrorRate: 0.5, MalformedRate: 0.5, Targets: targets}, 0.9)
+		rr := httptest.NewRecorder()
+		Middleware(okHandler).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/stocks", nil))
+		if rr.Code != http.StatusOK || rr.Body.String() != `{"ok":true}` {
+			t.Errorf("❌ no debería inyectarse ningún fallo: %d %s", rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("sin el feature flag", func(t *testing.T) {
+		withChaos(t, config.ChaosConfig{ErrorRate: 1, Targets: targets}, 0)
+		cfg := config.Current()
+		cfg.FeatureFlags = map[string]bool{}
+		config.Set(cfg)
+		rr := httptest.NewRecorder()
+		Middleware(okHandler).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/stocks", nil))
+		if rr.Code != http.StatusOK {
+			t.Errorf("❌ chaos desactivado no debería inyectar fallos, estado %d", rr.Code)
+		}
+	})
+}
+
+func TestTransport(t *testing.T) {
+	server := httptest.NewServer(okHandler)
+	defer server.Close()
+
+	withChaos(t, config.ChaosConfig{ErrorRate: 0.5, Targets: []string{config.ChaosTargetProviders}}, 0.1)
+	client := &http.Client{Transport: Transport(http.DefaultTransport)}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("❌ error inesperado: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadGateway || resp.Header.Get(Header) != "error" {
+		t.Errorf("❌ estado %d, se esperaba 502 inyectado", resp.StatusCode)
+	}
+
+	// El middleware de la API no se ve afectado: solo providers está en los destinos.
+	rr := httptest.NewRecorder()
+	Middleware(okHandler).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/stocks", nil))
+	if rr.Code != http.StatusOK {
+		t.Errorf("❌ la API no debería recibir fallos, estado %d", rr.Code)
+	}
+
+	withChaos(t, config.ChaosConfig{MalformedRate: 1, Targets: []string{config.ChaosTargetProviders}}, 0.1)
+	resp, err = client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("❌ error inesperado: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != malformedBody {
+		t.Errorf("❌ cuerpo %q, se esperaba el JSON malformado", body)
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/api"
+	"github.com/jannin2/stock-app/backend/config"
+	enricher "github.com/jannin2/stock-app/backend/cron"
+	"github.com/jannin2/stock-app/backend/database"
+	"github.com/jannin2/stock-app/backend/providers"
+)
+
+// checkTimeout limita las comprobaciones de la base de datos de `backend check`.
+const checkTimeout = 10 * time.Second
+
+// Estados de una comprobación. Solo checkFailed hace que `backend check` termine con error.
+const (
+	checkOK      = "✅"
+	checkWarning = "⚠️"
+	checkFailed  = "❌"
+)
+
+// checkResult es una línea del informe de `backend check`.
+type checkResult struct {
+	name   string
+	status string
+	detail string
+}
+
+// providerCheck es la llamada más barata con la que se comprueba la clave de un proveedor.
+type providerCheck struct {
+	name     string
+	required bool // Sin clave, el enricher no puede funcionar
+	call     func() error
+}
+
+var providerChecks = []providerCheck{
+	{providers.Karenai, true, func() error {
+		_, err := api.GetRecommendationsFromKarenai()
+		return err
+	}},
+	{providers.Finnhub, false, func() error {
+		_, err := api.GetFinnhubQuote("AAPL")
+		return err
+	}},
+	{providers.AlphaVantage, false, func() error {
+		_, err := api.GetAlphaVantageOverview("IBM")
+		return err
+	}},
+}
+
+// runCheck implementa `backend check`: valida la configuración, la conexión a la base de
+// datos, el esquema y las claves de los proveedores (una llamada barata a cada uno) y
+// escribe el informe en out. Pensado para los pipelines de despliegue y para comprobar una
+// instalación nueva. Devuelve el código de salida: 1 si falla alguna comprobación.
+func runCheck(out io.Writer) int {
+	// Los clientes de los proveedores registran cada petición; el informe ya resume el resultado.
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	results := []checkResult{checkConfig()}
+	results = append(results, checkDatabase()...)
+	for _, p := range providerChecks {
+		results = append(results, checkProvider(p))
+	}
+
+	fmt.Fprintln(out, "Comprobación del backend")
+	failed := 0
+	for _, r := range results {
+		fmt.Fprintf(out, "%s %-16s %s\n", r.status, r.name, r.detail)
+		if r.status == checkFailed {
+			failed++
+		}
+	}
+	if failed > 0 {
+		fmt.Fprintf(out, "%d comprobaciones fallidas\n", failed)
+		return 1
+	}
+	fmt.Fprintln(out, "Todo correcto")
+	return 0
+}
+
+// checkConfig valida la configuración con las mismas reglas que el arranque del servidor.
+func checkConfig() checkResult {
+	cfg, err := config.FromEnv()
+	if err == nil {
+		err = enricher.ValidateSteps(cfg.EnrichmentSteps)
+	}
+	if err == nil {
+		err = providers.ValidateChains(cfg.ProviderChains)
+	}
+	if err != nil {
+		return checkResult{"configuración", checkFailed, err.Error()}
+	}
+	config.Set(cfg)
+	return checkResult{"configuración", checkOK, "válida"}
+}
+
+// checkDatabase comprueba la conexión a DATABASE_URL y, si responde, que existan las
+// tablas del esquema.
+func checkDatabase() []checkResult {
+	connStr := os.Getenv("DATABASE_URL")
+	if connStr == "" {
+		return []checkResult{{"base de datos", checkFailed, "DATABASE_URL no está configurada"}}
+	}
+	db, err := database.OpenDB(connStr, config.Current().DBPool)
+	if err != nil {
+		return []checkResult{{"base de datos", checkFailed, err.Error()}}
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+	defer cancel()
+	start := time.Now()
+	if err := db.PingContext(ctx); err != nil {
+		return []checkResult{{"base de datos", checkFailed, err.Error()}}
+	}
+	results := []checkResult{{"base de datos", checkOK, fmt.Sprintf("responde en %s", time.Since(start).Round(time.Millisecond))}}
+
+	missing, err := database.MissingTables(ctx, db)
+	switch {
+	case err != nil:
+		results = append(results, checkResult{"esquema", checkFailed, err.Error()})
+	case len(missing) > 0:
+		// El servidor crea las tablas al arrancar: en una instalación nueva es lo esperado.
+		results = append(results, checkResult{"esquema", checkWarning,
+			fmt.Sprintf("faltan %d tablas (%v); se crearán al arrancar el servidor", len(missing), missing)})
+	default:
+		results = append(results, checkResult{"esquema", checkOK, "todas las tablas existen"})
+	}
+	return results
+}
+
+// checkProvider hace la llamada de comprobación de p. Sin clave es un aviso, salvo para los
+// proveedores imprescindibles; una clave rechazada o un proveedor caído es un fallo.
+func checkProvider(p providerCheck) checkResult {
+	start := time.Now()
+	err := p.call()
+	switch {
+	case err == nil || errors.Is(err, api.ErrNoData):
+		return checkResult{p.name, checkOK, fmt.Sprintf("responde en %s", time.Since(start).Round(time.Millisecond))}
+	case errors.Is(err, api.ErrMissingAPIKey) && !p.required:
+		return checkResult{p.name, checkWarning, err.Error()}
+	default:
+		return checkResult{p.name, checkFailed, err.Error()}
+	}
+}
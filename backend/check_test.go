@@ -0,0 +1,32 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jannin2/stock-app/backend/api"
+)
+
+func TestCheckProvider(t *testing.T) {
+	tests := []struct {
+		name     string
+		required bool
+		err      error
+		want     string
+	}{
+		{"responde", false, nil, checkOK},
+		{"sin datos para el símbolo", false, api.ErrNoData, checkOK},
+		{"sin clave opcional", false, fmt.Errorf("FINNHUB_API_KEY %w", api.ErrMissingAPIKey), checkWarning},
+		{"sin clave imprescindible", true, fmt.Errorf("KARENAI_API_KEY %w", api.ErrMissingAPIKey), checkFailed},
+		{"clave rechazada", false, errors.New("401 Unauthorized"), checkFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := checkProvider(providerCheck{name: "prueba", required: tt.required, call: func() error { return tt.err }})
+			if got.status != tt.want {
+				t.Errorf("❌ estado %s, se esperaba %s (%s)", got.status, tt.want, got.detail)
+			}
+		})
+	}
+}
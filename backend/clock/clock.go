@@ -0,0 +1,158 @@
+// Package clock abstrae el paso del tiempo para que la lógica de planificación
+// (enriquecimiento periódico, comprobaciones de antigüedad, sellos updated_at)
+// pueda probarse de forma determinista con un reloj simulado.
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock es la interfaz que usan los componentes que dependen de la hora actual o de temporizadores.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+	Sleep(d time.Duration)
+	NewTicker(d time.Duration) *Ticker
+}
+
+// Ticker envía la hora por C a intervalos regulares, como time.Ticker.
+type Ticker struct {
+	C    <-chan time.Time
+	stop func()
+}
+
+// Stop detiene el ticker. No cierra C.
+func (t *Ticker) Stop() {
+	t.stop()
+}
+
+// New devuelve un Clock respaldado por el paquete time.
+func New() Clock {
+	return realClock{}
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (realClock) Sleep(d time.Duration)                  { time.Sleep(d) }
+
+func (realClock) NewTicker(d time.Duration) *Ticker {
+	t := time.NewTicker(d)
+	return &Ticker{C: t.C, stop: t.Stop}
+}
+
+// Mock es un Clock cuyo tiempo solo avanza al llamar a Add o Set.
+// Es seguro para uso concurrente.
+type Mock struct {
+	mu      sync.Mutex
+	changed *sync.Cond
+	now     time.Time
+	timers  []*mockTimer
+}
+
+type mockTimer struct {
+	next   time.Time
+	period time.Duration // 0 para temporizadores de un solo disparo
+	ch     chan time.Time
+}
+
+// NewMock devuelve un reloj simulado que empieza en una fecha fija, para que los tests
+// no dependan de la hora real.
+func NewMock() *Mock {
+	m := &Mock{now: time.Date(2025, time.January, 6, 9, 0, 0, 0, time.UTC)}
+	m.changed = sync.NewCond(&m.mu)
+	return m
+}
+
+// Now devuelve la hora simulada actual.
+func (m *Mock) Now() time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.now
+}
+
+// After devuelve un canal que recibe la hora simulada cuando esta avance d.
+func (m *Mock) After(d time.Duration) <-chan time.Time {
+	return m.addTimer(d, 0).ch
+}
+
+// Sleep bloquea hasta que la hora simulada avance d.
+func (m *Mock) Sleep(d time.Duration) {
+	<-m.After(d)
+}
+
+// NewTicker devuelve un ticker que se dispara cada d de tiempo simulado.
+func (m *Mock) NewTicker(d time.Duration) *Ticker {
+	t := m.addTimer(d, d)
+	return &Ticker{C: t.ch, stop: func() { m.removeTimer(t) }}
+}
+
+// Add avanza la hora simulada d, disparando en orden los temporizadores y tickers vencidos.
+func (m *Mock) Add(d time.Duration) {
+	m.mu.Lock()
+	target := m.now.Add(d)
+	m.mu.Unlock()
+	m.Set(target)
+}
+
+// Set mueve la hora simulada a t, disparando en orden los temporizadores y tickers vencidos.
+func (m *Mock) Set(t time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for {
+		sort.Slice(m.timers, func(i, j int) bool { return m.timers[i].next.Before(m.timers[j].next) })
+		if len(m.timers) == 0 || m.timers[0].next.After(t) {
+			break
+		}
+		timer := m.timers[0]
+		m.now = timer.next
+		select {
+		case timer.ch <- m.now:
+		default: // Igual que time.Ticker, se descartan los ticks que nadie ha leído
+		}
+		if timer.period > 0 {
+			timer.next = timer.next.Add(timer.period)
+		} else {
+			m.timers = m.timers[1:]
+		}
+	}
+	if t.After(m.now) {
+		m.now = t
+	}
+	m.changed.Broadcast()
+}
+
+// BlockUntil espera hasta que haya al menos n temporizadores o tickers activos.
+// Permite a los tests sincronizarse con goroutines que aún no han creado su ticker.
+func (m *Mock) BlockUntil(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for len(m.timers) < n {
+		m.changed.Wait()
+	}
+}
+
+func (m *Mock) addTimer(d, period time.Duration) *mockTimer {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t := &mockTimer{next: m.now.Add(d), period: period, ch: make(chan time.Time, 1)}
+	m.timers = append(m.timers, t)
+	m.changed.Broadcast()
+	return t
+}
+
+func (m *Mock) removeTimer(t *mockTimer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, timer := range m.timers {
+		if timer == t {
+			m.timers = append(m.timers[:i], m.timers[i+1:]...)
+			break
+		}
+	}
+	m.changed.Broadcast()
+}
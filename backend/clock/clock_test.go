@@ -0,0 +1,52 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMock_TickerFiresOnAdd(t *testing.T) {
+	m := NewMock()
+	start := m.Now()
+	ticker := m.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	m.Add(30 * time.Minute)
+	select {
+	case <-ticker.C:
+		t.Fatal("❌ el ticker no debería dispararse antes de su intervalo")
+	default:
+	}
+
+	m.Add(30 * time.Minute)
+	select {
+	case tick := <-ticker.C:
+		if !tick.Equal(start.Add(time.Hour)) {
+			t.Errorf("❌ tick inesperado: %v", tick)
+		}
+	default:
+		t.Fatal("❌ el ticker debería haberse disparado tras una hora")
+	}
+}
+
+func TestMock_AfterAndStop(t *testing.T) {
+	m := NewMock()
+	after := m.After(10 * time.Second)
+	ticker := m.NewTicker(time.Second)
+	ticker.Stop()
+
+	m.Add(time.Minute)
+	select {
+	case <-after:
+	default:
+		t.Fatal("❌ After debería haberse disparado")
+	}
+	select {
+	case <-ticker.C:
+		t.Fatal("❌ un ticker detenido no debería dispararse")
+	default:
+	}
+	if got := m.Now(); !got.Equal(NewMock().Now().Add(time.Minute)) {
+		t.Errorf("❌ hora simulada inesperada: %v", got)
+	}
+}
@@ -0,0 +1,185 @@
+package clock
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule es una expresión cron de cinco campos (minuto, hora, día del mes, mes y día de
+// la semana), como "0 6 * * 1-5". Se crea con ParseSchedule.
+type Schedule struct {
+	expr                     string
+	minute, hour, dom, month uint64 // Bit i activo si el valor i coincide
+	dow                      uint64 // 0 = domingo
+	domAny, dowAny           bool   // Si el campo empezaba por "*"
+	loc                      *time.Location
+}
+
+// scheduleMacros son las abreviaturas admitidas en lugar de los cinco campos.
+var scheduleMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var (
+	monthNames = []string{"", "JAN", "FEB", "MAR", "APR", "MAY", "JUN", "JUL", "AUG", "SEP", "OCT", "NOV", "DEC"}
+	dowNames   = []string{"SUN", "MON", "TUE", "WED", "THU", "FRI", "SAT"}
+)
+
+// ParseSchedule interpreta una expresión cron estándar. Cada campo admite "*", valores,
+// listas ("1,15"), rangos ("1-5"), pasos ("*/15", "9-17/2") y, en el mes y el día de la
+// semana, nombres en inglés ("MON-FRI", "JAN"); el domingo es 0 o 7. También admite las
+// abreviaturas @daily, @hourly, @weekly, @monthly y @yearly.
+//
+// Las horas son UTC salvo que la expresión empiece por CRON_TZ=<zona>, por ejemplo
+// "CRON_TZ=America/New_York 30 16 * * 1-5" para las 16:30 de Nueva York. Como en cron, si
+// el día del mes y el de la semana están restringidos basta con que coincida uno de ellos.
+func ParseSchedule(expr string) (*Schedule, error) {
+	s := &Schedule{expr: expr, loc: time.UTC}
+	fields := strings.Fields(expr)
+	if len(fields) > 0 && strings.HasPrefix(fields[0], "CRON_TZ=") {
+		loc, err := time.LoadLocation(strings.TrimPrefix(fields[0], "CRON_TZ="))
+		if err != nil {
+			return nil, fmt.Errorf("zona horaria inválida: %w", err)
+		}
+		s.loc = loc
+		fields = fields[1:]
+	}
+	if len(fields) == 1 {
+		macro, ok := scheduleMacros[strings.ToLower(fields[0])]
+		if !ok {
+			return nil, fmt.Errorf("abreviatura desconocida: %s", fields[0])
+		}
+		fields = strings.Fields(macro)
+	}
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("se esperaban 5 campos (minuto hora día mes día-semana), hay %d", len(fields))
+	}
+
+	var err error
+	if s.minute, err = parseScheduleField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("minuto: %w", err)
+	}
+	if s.hour, err = parseScheduleField(fields[1], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("hora: %w", err)
+	}
+	if s.dom, err = parseScheduleField(fields[2], 1, 31, nil); err != nil {
+		return nil, fmt.Errorf("día del mes: %w", err)
+	}
+	if s.month, err = parseScheduleField(fields[3], 1, 12, monthNames); err != nil {
+		return nil, fmt.Errorf("mes: %w", err)
+	}
+	if s.dow, err = parseScheduleField(fields[4], 0, 7, dowNames); err != nil {
+		return nil, fmt.Errorf("día de la semana: %w", err)
+	}
+	if s.dow&(1<<7) != 0 {
+		s.dow = s.dow&^(1<<7) | 1 // 7 también es domingo
+	}
+	s.domAny, s.dowAny = strings.HasPrefix(fields[2], "*"), strings.HasPrefix(fields[4], "*")
+
+	if s.Next(time.Date(2000, 1, 1, 0, 0, 0, 0, s.loc)).IsZero() {
+		return nil, fmt.Errorf("la expresión %q nunca se cumple", expr)
+	}
+	return s, nil
+}
+
+// parseScheduleField devuelve los valores entre min y max que cumple field. names, si no
+// es nil, da el valor de cada nombre por su posición.
+func parseScheduleField(field string, min, max int, names []string) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("paso inválido: %q", part)
+			}
+			step = n
+		}
+
+		lo, hi := min, max
+		if rangePart != "*" {
+			from, to, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if lo, err = scheduleValue(from, min, max, names); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if isRange {
+				if hi, err = scheduleValue(to, min, max, names); err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				hi = max // "5/15" equivale a "5-max/15"
+			}
+			if hi < lo {
+				return 0, fmt.Errorf("rango invertido: %q", part)
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, nil
+}
+
+func scheduleValue(value string, min, max int, names []string) (int, error) {
+	for i, name := range names {
+		if name != "" && strings.EqualFold(value, name) {
+			return i, nil
+		}
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < min || n > max {
+		return 0, fmt.Errorf("valor inválido: %q (entre %d y %d)", value, min, max)
+	}
+	return n, nil
+}
+
+// String devuelve la expresión original, o "" si s es nil.
+func (s *Schedule) String() string {
+	if s == nil {
+		return ""
+	}
+	return s.expr
+}
+
+// Next devuelve el primer instante posterior a t que cumple la expresión, o el instante
+// cero si no hay ninguno en los próximos cinco años.
+func (s *Schedule) Next(t time.Time) time.Time {
+	t = t.In(s.loc).Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, s.loc)
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, s.loc)
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, s.loc)
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func (s *Schedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domAny || s.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
@@ -0,0 +1,73 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSchedule_Next(t *testing.T) {
+	// Lunes 6 de enero de 2025, 09:00 UTC, como NewMock.
+	from := time.Date(2025, time.January, 6, 9, 0, 0, 0, time.UTC)
+	ny, _ := time.LoadLocation("America/New_York")
+
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"0 6 * * 1-5", time.Date(2025, 1, 7, 6, 0, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2025, 1, 6, 9, 15, 0, 0, time.UTC)},
+		{"0 9 * * *", time.Date(2025, 1, 7, 9, 0, 0, 0, time.UTC)}, // Estrictamente posterior
+		{"30 22 * * MON-FRI", time.Date(2025, 1, 6, 22, 30, 0, 0, time.UTC)},
+		{"0 0 * * 0", time.Date(2025, 1, 12, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2025, 1, 12, 0, 0, 0, 0, time.UTC)},
+		{"0 12 1 FEB *", time.Date(2025, 2, 1, 12, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 13 * 5", time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)}, // Día 13 o viernes
+		{"5/20 10-12 * * *", time.Date(2025, 1, 6, 10, 5, 0, 0, time.UTC)},
+		{"@daily", time.Date(2025, 1, 7, 0, 0, 0, 0, time.UTC)},
+		{"CRON_TZ=America/New_York 30 16 * * 1-5", time.Date(2025, 1, 6, 16, 30, 0, 0, ny)},
+	}
+	for _, tt := range tests {
+		s, err := ParseSchedule(tt.expr)
+		if err != nil {
+			t.Errorf("❌ ParseSchedule(%q): error inesperado: %v", tt.expr, err)
+			continue
+		}
+		if got := s.Next(from); !got.Equal(tt.want) {
+			t.Errorf("❌ Next de %q = %v, se esperaba %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestParseSchedule_SkipsWeekends(t *testing.T) {
+	s, err := ParseSchedule("0 6 * * 1-5")
+	if err != nil {
+		t.Fatalf("❌ error inesperado: %v", err)
+	}
+	friday := time.Date(2025, time.January, 10, 6, 0, 0, 0, time.UTC)
+	if got, want := s.Next(friday), friday.AddDate(0, 0, 3); !got.Equal(want) {
+		t.Errorf("❌ tras el viernes se esperaba el lunes %v, se obtuvo %v", want, got)
+	}
+}
+
+func TestParseSchedule_Invalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"0 6 * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"0 0 0 * *",
+		"0 0 * 13 *",
+		"0 0 * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"0 0 * * FUN",
+		"@often",
+		"0 0 30 2 *",
+		"CRON_TZ=Marte/Olympus 0 6 * * *",
+	} {
+		if _, err := ParseSchedule(expr); err == nil {
+			t.Errorf("❌ ParseSchedule(%q) debería fallar", expr)
+		}
+	}
+}
@@ -0,0 +1,302 @@
+// Command smoketest arranca el backend contra una base de datos temporal y ejecuta una
+// secuencia de comprobaciones de extremo a extremo (importar fixtures, listar, obtener por
+// ID, recomendados, cotizaciones y refresco). Termina con código distinto de cero si algo
+// no coincide, por lo que puede usarse como puerta de despliegue.
+//
+// Uso:
+//
+//	go run ./cmd/smoketest -database-url "postgres://root@localhost:26257/defaultdb?sslmode=disable"
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/jannin2/stock-app/backend/api"
+	"github.com/jannin2/stock-app/backend/auth"
+	"github.com/jannin2/stock-app/backend/clock"
+	"github.com/jannin2/stock-app/backend/config"
+	enricher "github.com/jannin2/stock-app/backend/cron"
+	"github.com/jannin2/stock-app/backend/database"
+	"github.com/jannin2/stock-app/backend/handlers"
+	"github.com/jannin2/stock-app/backend/jobs"
+	"github.com/jannin2/stock-app/backend/mail"
+	"github.com/jannin2/stock-app/backend/models"
+	"github.com/jannin2/stock-app/backend/providers"
+	"github.com/jannin2/stock-app/backend/quotes"
+	"github.com/jannin2/stock-app/backend/stream"
+)
+
+// fixtureTickers son los tickers incluidos en fixtures/providers/karenai.
+var fixtureTickers = []string{"AAPL", "KO", "MSFT", "PFE"}
+
+func main() {
+	databaseURL := flag.String("database-url", os.Getenv("DATABASE_URL"), "URL de un servidor Postgres/CockroachDB donde crear la base de datos temporal")
+	fixtures := flag.String("fixtures", "fixtures/providers", "directorio con las respuestas grabadas de los proveedores")
+	keep := flag.Bool("keep", false, "no eliminar la base de datos temporal al terminar")
+	flag.Parse()
+
+	if *databaseURL == "" {
+		log.Fatal("❌ Se requiere -database-url o DATABASE_URL")
+	}
+
+	// Los proveedores se sirven siempre desde disco: el smoke test no consume cuota ni requiere API keys.
+	os.Setenv("PROVIDERS_MODE", api.ProvidersModeReplay)
+	os.Setenv("PROVIDERS_FIXTURES_DIR", *fixtures)
+
+	tempURL, cleanup, err := createTempDatabase(*databaseURL)
+	if err != nil {
+		log.Fatalf("❌ Error al crear la base de datos temporal: %v", err)
+	}
+	if !*keep {
+		defer cleanup()
+	}
+
+	ok := run(tempURL)
+	if !*keep {
+		cleanup()
+	}
+	if !ok {
+		log.Println("❌ Smoke test FALLIDO")
+		os.Exit(1)
+	}
+	log.Println("✅ Smoke test completado correctamente")
+}
+
+// run arranca el servidor contra la base de datos indicada y ejecuta los pasos en orden.
+// Devuelve false si algún paso falla; los pasos posteriores a un fallo no se ejecutan.
+func run(databaseURL string) bool {
+	os.Setenv("DATABASE_URL", databaseURL)
+	dbConn, err := database.ConnectDB()
+	if err != nil {
+		log.Printf("❌ Error al conectar a la base de datos temporal: %v", err)
+		return false
+	}
+	defer database.CloseDB(dbConn)
+
+	if _, err := database.MigrateUp(context.Background(), dbConn); err != nil {
+		log.Printf("❌ Error al migrar el esquema: %v", err)
+		return false
+	}
+
+	dbClient := database.NewStockDB(dbConn)
+	quoteCache := quotes.NewCache()
+	enricherJob := enricher.NewEnricher(dbClient, enricher.WithQuoteCache(quoteCache))
+	jobQueue := jobs.NewQueue(clock.New(), 1, 1)
+	readiness := &database.Readiness{}
+	readiness.SetReady(true)
+	hub := stream.NewHub()
+	userDB := database.NewUserDB(dbConn)
+	auth.SetUserStore(userDB)
+	auth.SetAuditLog(userDB)
+	responseCache := api.NewResponseCache()
+	responseCache.SetReadiness(readiness.Ready)
+	// El mismo cableado que main.go: autenticación, sondas de salud, límite del pool y CORS
+	router := chi.NewRouter()
+	api.SetupServer(router, api.Server{
+		Stocks:        handlers.NewStockHandlers(dbClient, jobQueue),
+		Quotes:        handlers.NewQuoteHandlers(quoteCache),
+		Streams:       handlers.NewStreamHandlers(hub, userDB, clock.New()),
+		Users:         handlers.NewUserHandlers(userDB, jobQueue, mail.LogMailer{}),
+		Status:        handlers.NewStatusHandlers(readiness, enricherJob, providers.HealthReport, jobQueue),
+		Webhooks:      handlers.NewWebhookHandlers(userDB, enricherJob.Trigger),
+		Watchlists:    handlers.NewWatchlistHandlers(userDB, dbClient, hub),
+		Jobs:          handlers.NewJobHandlers(jobQueue),
+		Health:        handlers.NewHealthHandlers(readiness, api.DegradedPaths...),
+		Pool:          database.NewPoolMonitor(dbConn),
+		ResponseCache: responseCache,
+	})
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	s := &smoke{baseURL: server.URL + "/api/v1"}
+	steps := []struct {
+		name string
+		fn   func() error
+	}{
+		{"importar fixtures", func() error { return enricherJob.RunOnce(context.Background()) }},
+		{"listar stocks", s.checkList},
+		{"obtener stock por ID", s.checkGetByID},
+		{"stocks recomendados", s.checkRecommended},
+		{"cotizaciones en caché", s.checkQuotes},
+		{"refrescar datos", s.refresh(enricherJob)},
+	}
+
+	for _, step := range steps {
+		start := time.Now()
+		if err := step.fn(); err != nil {
+			log.Printf("❌ %s: %v", step.name, err)
+			return false
+		}
+		log.Printf("✅ %s (%s)", step.name, time.Since(start).Round(time.Millisecond))
+	}
+	return true
+}
+
+// smoke guarda el estado compartido entre los pasos del smoke test.
+type smoke struct {
+	baseURL string
+	listed  map[string]models.Stock // Stocks del último listado, por ticker
+}
+
+func (s *smoke) checkList() error {
+	var page struct {
+		Data  []models.Stock `json:"data"`
+		Total int            `json:"total"`
+	}
+	if _, err := s.getJSON("/stocks?limit=50&sortBy=ticker&order=asc", &page); err != nil {
+		return err
+	}
+
+	stocks := page.Data
+	if page.Total != len(fixtureTickers) {
+		return fmt.Errorf("total = %d, se esperaba %d", page.Total, len(fixtureTickers))
+	}
+	if len(stocks) != len(fixtureTickers) {
+		return fmt.Errorf("se esperaban %d stocks, se obtuvieron %d", len(fixtureTickers), len(stocks))
+	}
+
+	s.listed = make(map[string]models.Stock, len(stocks))
+	for i, stock := range stocks {
+		if stock.Ticker != fixtureTickers[i] {
+			return fmt.Errorf("posición %d: se esperaba %s, se obtuvo %s", i, fixtureTickers[i], stock.Ticker)
+		}
+		if stock.CurrentPrice <= 0 || !stock.RecommendationScore.Valid {
+			return fmt.Errorf("%s no fue enriquecido (precio %.2f, score válido %t)", stock.Ticker, stock.CurrentPrice, stock.RecommendationScore.Valid)
+		}
+		s.listed[stock.Ticker] = stock
+	}
+	return nil
+}
+
+func (s *smoke) checkGetByID() error {
+	expected := s.listed["AAPL"]
+	var stock models.Stock
+	if _, err := s.getJSON("/stocks/"+expected.ID.String(), &stock); err != nil {
+		return err
+	}
+	if stock.ID != expected.ID || stock.Ticker != "AAPL" {
+		return fmt.Errorf("se esperaba AAPL (%s), se obtuvo %s (%s)", expected.ID, stock.Ticker, stock.ID)
+	}
+	if stock.Sector != "Technology" {
+		return fmt.Errorf("sector = %q, se esperaba Technology", stock.Sector)
+	}
+	return nil
+}
+
+func (s *smoke) checkRecommended() error {
+	var stocks []models.Stock
+	if _, err := s.getJSON("/stocks/recommended?limit=2", &stocks); err != nil {
+		return err
+	}
+	if len(stocks) != 2 {
+		return fmt.Errorf("se esperaban 2 recomendados, se obtuvieron %d", len(stocks))
+	}
+	if stocks[0].RecommendationScore.Float64 < stocks[1].RecommendationScore.Float64 {
+		return fmt.Errorf("recomendados no ordenados por score: %.2f < %.2f", stocks[0].RecommendationScore.Float64, stocks[1].RecommendationScore.Float64)
+	}
+	return nil
+}
+
+func (s *smoke) checkQuotes() error {
+	var got []quotes.Quote
+	if _, err := s.getJSON("/quotes?tickers=MSFT,AAPL", &got); err != nil {
+		return err
+	}
+	if len(got) != 2 || got[0].Ticker != "MSFT" || got[1].Ticker != "AAPL" {
+		return fmt.Errorf("se esperaban MSFT y AAPL, se obtuvo %+v", got)
+	}
+	if got[1].Price != s.listed["AAPL"].CurrentPrice {
+		return fmt.Errorf("precio de AAPL = %.2f, se esperaba %.2f", got[1].Price, s.listed["AAPL"].CurrentPrice)
+	}
+	return nil
+}
+
+// refresh vuelve a ejecutar el enriquecimiento y comprueba que los stocks se actualizaron
+// en lugar de duplicarse.
+func (s *smoke) refresh(e *enricher.Enricher) func() error {
+	return func() error {
+		before := s.listed
+		if err := e.RunOnce(context.Background()); err != nil {
+			return err
+		}
+		if err := s.checkList(); err != nil {
+			return fmt.Errorf("después del refresco: %w", err)
+		}
+		for ticker, stock := range s.listed {
+			if stock.ID != before[ticker].ID {
+				return fmt.Errorf("%s cambió de ID tras el refresco (%s -> %s)", ticker, before[ticker].ID, stock.ID)
+			}
+			if !stock.UpdatedAt.After(before[ticker].UpdatedAt) {
+				return fmt.Errorf("%s no actualizó updated_at tras el refresco", ticker)
+			}
+		}
+		return nil
+	}
+}
+
+func (s *smoke) getJSON(path string, v interface{}) (*http.Response, error) {
+	resp, err := http.Get(s.baseURL + path)
+	if err != nil {
+		return nil, fmt.Errorf("GET %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("GET %s: error al leer la respuesta: %w", path, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: estado %s: %s", path, resp.Status, body)
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return nil, fmt.Errorf("GET %s: JSON inválido: %w", path, err)
+	}
+	return resp, nil
+}
+
+// createTempDatabase crea una base de datos con nombre único en el servidor de serverURL y
+// devuelve su URL junto con una función que la elimina.
+func createTempDatabase(serverURL string) (string, func(), error) {
+	u, err := url.Parse(serverURL)
+	if err != nil {
+		return "", nil, fmt.Errorf("URL de base de datos inválida: %w", err)
+	}
+
+	admin, err := database.OpenDB(serverURL, config.DBPoolConfig{})
+	if err != nil {
+		return "", nil, err
+	}
+
+	name := fmt.Sprintf("smoketest_%d", time.Now().UnixNano())
+	if _, err := admin.Exec("CREATE DATABASE " + name); err != nil {
+		admin.Close()
+		return "", nil, fmt.Errorf("CREATE DATABASE %s: %w", name, err)
+	}
+	log.Printf("Base de datos temporal creada: %s", name)
+
+	u.Path = "/" + name
+	dropped := false
+	cleanup := func() {
+		if dropped {
+			return
+		}
+		dropped = true
+		if _, err := admin.Exec("DROP DATABASE IF EXISTS " + name + " CASCADE"); err != nil {
+			log.Printf("ADVERTENCIA: no se pudo eliminar la base de datos temporal %s: %v", name, err)
+		}
+		admin.Close()
+	}
+	return u.String(), cleanup, nil
+}
@@ -0,0 +1,969 @@
+// Package config contiene la configuración que puede recargarse en caliente (SIGHUP o
+// endpoint de administración) sin reiniciar el servidor.
+//
+// Solo incluye valores que es seguro cambiar con el servidor en marcha; DATABASE_URL, PORT
+// o las API keys de los proveedores se siguen leyendo una vez al arrancar.
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/clock"
+	"github.com/jannin2/stock-app/backend/models"
+	"github.com/joho/godotenv"
+)
+
+// Niveles de log soportados por LOG_LEVEL, de más a menos detallado.
+const (
+	LogLevelDebug = "debug"
+	LogLevelInfo  = "info"
+	LogLevelWarn  = "warn"
+	LogLevelError = "error"
+)
+
+var logLevelRank = map[string]int{LogLevelDebug: 0, LogLevelInfo: 1, LogLevelWarn: 2, LogLevelError: 3}
+
+// Config es una instantánea inmutable de la configuración recargable.
+type Config struct {
+	LogLevel                   string          `json:"log_level"`                      // LOG_LEVEL
+	EnrichmentInterval         time.Duration   `json:"enrichment_interval"`            // ENRICHMENT_INTERVAL (ej. 1h)
+	AlphaVantageRequestsPerMin int             `json:"alpha_vantage_requests_per_min"` // ALPHA_VANTAGE_RATE_LIMIT
+	FinnhubRequestsPerMin      int             `json:"finnhub_requests_per_min"`       // FINNHUB_RATE_LIMIT
+	FeatureFlags               map[string]bool `json:"feature_flags"`                  // FEATURE_FLAGS (ej. "heatmap,-chaos")
+	APIRequestsPerMin          int             `json:"api_requests_per_min"`           // RATE_LIMIT_PER_MIN, por cliente (0 = sin cabeceras ni límite)
+
+	// APIRateLimitEnforce hace que superar APIRequestsPerMin responda 429 (RATE_LIMIT_ENFORCE).
+	// Por defecto el límite solo se anuncia en las cabeceras X-RateLimit-*: el cliente se
+	// identifica por la IP de la conexión y, detrás de un proxy o balanceador, todos los
+	// usuarios compartirían el mismo contador.
+	APIRateLimitEnforce bool `json:"api_rate_limit_enforce"`
+
+	// EnrichmentTiers es cada cuánto se refresca un stock según su tier (hot, standard,
+	// archived). ENRICHMENT_TIERS, ej. "hot=1h,standard=24h,archived=168h". El enricher se
+	// ejecuta cada EnrichmentInterval y solo refresca los stocks cuyo tier ya lo requiere,
+	// por lo que EnrichmentInterval debería ser el intervalo del tier más frecuente.
+	EnrichmentTiers map[string]time.Duration `json:"enrichment_tiers"`
+
+	// EnrichCron es una expresión cron que, si está definida, sustituye a EnrichmentInterval
+	// para decidir cuándo se ejecuta el enricher, p. ej. "0 22 * * 1-5" para después del
+	// cierre de los días laborables (ver clock.ParseSchedule). ENRICH_CRON.
+	EnrichCron string `json:"enrich_cron"`
+
+	// UserDataRetention es cuánto se conservan los datos de una cuenta borrada con
+	// DELETE /me antes de que el job de purga los elimine definitivamente.
+	// USER_DATA_RETENTION (ej. 720h).
+	UserDataRetention time.Duration `json:"user_data_retention"`
+
+	// ProviderPayloadRetention es cuánto se conserva la última respuesta cruda de Finnhub y
+	// Alpha Vantage de cada ticker, para depurar discrepancias de datos. 0 desactiva su
+	// captura. PROVIDER_PAYLOAD_RETENTION (ej. 72h).
+	ProviderPayloadRetention time.Duration `json:"provider_payload_retention"`
+
+	// ShadowSampleRate es la fracción de peticiones a GET /stocks (0 a 1) que, con el feature
+	// flag "shadow", se repiten con la consulta v2 para comparar resultados y latencias.
+	// SHADOW_SAMPLE_RATE.
+	ShadowSampleRate float64 `json:"shadow_sample_rate"`
+
+	// Chaos son las tasas de fallos inyectados por el modo chaos, activo solo con el feature
+	// flag "chaos" y nunca con APP_ENV=production.
+	Chaos ChaosConfig `json:"chaos"`
+
+	// ProviderChains es el orden de proveedores consultados por tipo de dato; si uno no
+	// devuelve datos se prueba el siguiente. PROVIDER_CHAIN_QUOTE, PROVIDER_CHAIN_FUNDAMENTALS,
+	// PROVIDER_CHAIN_ALPHA, PROVIDER_CHAIN_MENTIONS, PROVIDER_CHAIN_ESG, PROVIDER_CHAIN_SHORT_INTEREST,
+	// PROVIDER_CHAIN_OPTIONS, PROVIDER_CHAIN_CORPORATE_ACTIONS, PROVIDER_CHAIN_IPOS,
+	// PROVIDER_CHAIN_MACRO. Los nombres se comprueban contra los proveedores registrados en
+	// el paquete providers, que config no conoce.
+	ProviderChains map[string][]string `json:"provider_chains"`
+
+	// EnrichmentSteps son los pasos por stock del pipeline de enriquecimiento, en orden,
+	// entre la descarga del feed y el guardado. ENRICHMENT_STEPS, ej.
+	// "quotes,fundamentals,indicators,sentiment,score"; cada nombre debe estar registrado en el
+	// enricher (enricher.RegisterStep), lo que se comprueba al arrancar.
+	EnrichmentSteps []string `json:"enrichment_steps"`
+
+	// UserQuotas es cuántos recursos de cada tipo (watchlists, alerts) puede crear
+	// un usuario; 0 = sin límite. USER_QUOTAS, ej. "watchlists=10,alerts=200". Evita que un
+	// solo usuario cree millones de reglas que el evaluador de alertas tendría que recorrer.
+	UserQuotas map[string]int `json:"user_quotas"`
+
+	// NotifyMaxPerHour es cuántas notificaciones de alertas recibe como máximo un usuario por
+	// hora en cada canal; 0 = sin límite. Las que no caben se agrupan en un resumen.
+	// NOTIFY_MAX_PER_HOUR, ej. "email=10,push=30". Cada usuario puede fijar un límite menor.
+	NotifyMaxPerHour map[string]int `json:"notify_max_per_hour"`
+
+	// RateLimitRetries es cuántas veces, como máximo, se vuelven a enriquecer en la misma
+	// ejecución los tickers que un proveedor rechazó por límite de peticiones (p. ej. el
+	// "Note" de Alpha Vantage). Antes de cada reintento se espera RateLimitRetryWindow, para
+	// que la ventana del límite haya pasado. 0 los deja con métricas nulas hasta la siguiente
+	// ejecución. RATE_LIMIT_RETRIES y RATE_LIMIT_RETRY_WINDOW (ej. 1m).
+	RateLimitRetries     int           `json:"rate_limit_retries"`
+	RateLimitRetryWindow time.Duration `json:"rate_limit_retry_window"`
+
+	// ProviderRetries es cuántas veces se repite una petición a un proveedor que falla por un
+	// error de red, un 429 o un 5xx, antes de dar el dato por perdido en esta ejecución. La
+	// espera antes del reintento n es ProviderRetryBackoff·2^(n-1), o la que pida el
+	// proveedor con Retry-After, variada al azar en ±ProviderRetryJitter para que los
+	// workers no reintenten a la vez. PROVIDER_RETRIES, PROVIDER_RETRY_BACKOFF (ej. 500ms) y
+	// PROVIDER_RETRY_JITTER (fracción entre 0 y 1).
+	ProviderRetries      int           `json:"provider_retries"`
+	ProviderRetryBackoff time.Duration `json:"provider_retry_backoff"`
+	ProviderRetryJitter  float64       `json:"provider_retry_jitter"`
+
+	// EnrichmentWorkers es cuántos tickers se enriquecen a la vez. Los límites por minuto de
+	// cada proveedor se respetan igualmente: los workers se turnan para llamar a Alpha
+	// Vantage mientras los demás avanzan con Finnhub. ENRICHMENT_WORKERS.
+	EnrichmentWorkers int `json:"enrichment_workers"`
+
+	// UserAgent es la cabecera User-Agent de todas las peticiones salientes (proveedores,
+	// webhooks y push), para que los proveedores puedan identificarnos si nos limitan.
+	// USER_AGENT.
+	UserAgent string `json:"user_agent"`
+
+	// StreamMaxSubscriptions es cuántos tickers puede seguir a la vez cada conexión al stream
+	// de actualizaciones (GET /api/v1/stream), y StreamMessagesPerMin cuántos mensajes
+	// (altas y bajas de suscripciones) puede enviar por minuto; 0 = sin límite.
+	// STREAM_MAX_SUBSCRIPTIONS y STREAM_MESSAGES_PER_MIN.
+	StreamMaxSubscriptions int `json:"stream_max_subscriptions"`
+	StreamMessagesPerMin   int `json:"stream_messages_per_min"`
+
+	// DefaultPageSize es cuántos stocks devuelve GET /stocks cuando la petición no indica
+	// limit. DEFAULT_PAGE_SIZE, entre 1 y MaxDefaultPageSize.
+	DefaultPageSize int `json:"default_page_size"`
+
+	// ScoreBands clasifica el recommendation_score en bandas para mostrarlo (ej. un color por
+	// banda), de la más alta a la más baja: un stock pertenece a la primera cuyo mínimo
+	// alcanza, y a ninguna si queda por debajo de todas. SCORE_BANDS, ej. "strong=7,moderate=3";
+	// sustituye a la lista completa.
+	ScoreBands []ScoreBand `json:"score_bands"`
+
+	// DBPool es el tamaño del pool de conexiones a la base de datos y los umbrales con los
+	// que se vigila su saturación (ver database.PoolMonitor).
+	DBPool DBPoolConfig `json:"db_pool"`
+}
+
+// DBPoolConfig configura el pool de conexiones. Se aplica al arrancar y en cada recarga:
+// database/sql admite cambiar los límites con el pool en uso.
+type DBPoolConfig struct {
+	MaxOpenConns    int           `json:"max_open_conns"`     // DB_MAX_OPEN_CONNS
+	MaxIdleConns    int           `json:"max_idle_conns"`     // DB_MAX_IDLE_CONNS, como mucho MaxOpenConns
+	ConnMaxLifetime time.Duration `json:"conn_max_lifetime"`  // DB_CONN_MAX_LIFETIME (ej. 5m)
+	ConnMaxIdleTime time.Duration `json:"conn_max_idle_time"` // DB_CONN_MAX_IDLE_TIME; 0 = sin límite
+	QueryTimeout    time.Duration `json:"query_timeout"`      // DB_QUERY_TIMEOUT, por consulta; 0 = sin límite
+
+	// UpsertBatchSize es cuántos stocks escribe cada sentencia de UpsertStocks: uno por fila
+	// de un INSERT de varias filas, en lugar de un viaje a la base de datos por stock.
+	// DB_UPSERT_BATCH_SIZE.
+	UpsertBatchSize int `json:"upsert_batch_size"`
+
+	// StatementCacheSize es cuántas sentencias preparadas guarda cada conexión
+	// (DB_STATEMENT_CACHE_SIZE); 0 = sin caché. Solo se aplica al abrir el pool, no en las
+	// recargas.
+	StatementCacheSize int `json:"statement_cache_size"`
+
+	// WaitWarning es la espera media por conexión a partir de la cual se registra un aviso.
+	// DB_POOL_WAIT_WARNING (ej. 100ms).
+	WaitWarning time.Duration `json:"wait_warning"`
+	// SaturationWait es la espera media por conexión, con todas en uso, a partir de la cual
+	// el pool se considera saturado y la API responde 503 en lugar de encolar más
+	// peticiones. DB_POOL_SATURATION_WAIT (ej. 1s); 0 = no rechazar nunca.
+	SaturationWait time.Duration `json:"saturation_wait"`
+}
+
+// ScoreBand es una banda del recommendation_score: los stocks con score >= Min.
+type ScoreBand struct {
+	Name string  `json:"name"`
+	Min  float64 `json:"min"`
+}
+
+// Band devuelve el nombre de la banda de score, o "" si no alcanza ninguna.
+func (c Config) Band(score float64) string {
+	for _, band := range c.ScoreBands {
+		if score >= band.Min {
+			return band.Name
+		}
+	}
+	return ""
+}
+
+// FlagShadow es el feature flag que activa el tráfico sombra de la consulta v2 de stocks.
+const FlagShadow = "shadow"
+
+// FlagChaos es el feature flag que activa la inyección de fallos (paquete chaos).
+const FlagChaos = "chaos"
+
+// FlagIPOAutoAdd es el feature flag que añade a los stocks seguidos los tickers que acaban
+// de salir a bolsa según el calendario de IPOs.
+const FlagIPOAutoAdd = "ipo_auto_add"
+
+// ChaosConfig define qué fallos inyecta el modo chaos y con qué probabilidad (0 a 1) por
+// petición, tanto en la API (Targets "api") como en las llamadas a proveedores ("providers").
+type ChaosConfig struct {
+	LatencyRate   float64       `json:"latency_rate"`   // CHAOS_LATENCY_RATE
+	Latency       time.Duration `json:"latency"`        // CHAOS_LATENCY, latencia añadida (ej. 2s)
+	ErrorRate     float64       `json:"error_rate"`     // CHAOS_ERROR_RATE, respuestas 5xx
+	MalformedRate float64       `json:"malformed_rate"` // CHAOS_MALFORMED_RATE, JSON truncado
+	Targets       []string      `json:"targets"`        // CHAOS_TARGETS (ej. "api,providers")
+}
+
+// Destinos del modo chaos.
+const (
+	ChaosTargetAPI       = "api"
+	ChaosTargetProviders = "providers"
+)
+
+// ChaosActive indica si el modo chaos debe inyectar fallos en target.
+func (c Config) ChaosActive(target string) bool {
+	if !c.Enabled(FlagChaos) {
+		return false
+	}
+	for _, t := range c.Chaos.Targets {
+		if t == target {
+			return true
+		}
+	}
+	return false
+}
+
+// Tipos de dato con cadena de proveedores configurable.
+const (
+	DataTypeQuote            = "quote"
+	DataTypeFundamentals     = "fundamentals"
+	DataTypeAlpha            = "alpha"
+	DataTypeMentions         = "mentions" // Menciones en redes sociales, para el campo buzz
+	DataTypeESG              = "esg"
+	DataTypeShortInterest    = "short_interest"
+	DataTypeOptions          = "options"
+	DataTypeCorporateActions = "corporate_actions" // Splits, fusiones y cambios de ticker
+	DataTypeIPOs             = "ipos"              // Calendario de salidas a bolsa, de todo el mercado
+	DataTypeMacro            = "macro"             // Calendario económico (IPC, FOMC, empleo...)
+)
+
+// maxRateLimitRetries acota RATE_LIMIT_RETRIES: cada reintento alarga la ejecución en
+// RateLimitRetryWindow.
+const maxRateLimitRetries = 5
+
+// MaxUpsertBatchSize acota DB_UPSERT_BATCH_SIZE: cada stock ocupa 26 parámetros de la
+// sentencia y el protocolo de PostgreSQL admite como mucho 65535.
+const MaxUpsertBatchSize = 2000
+
+// maxProviderRetries acota PROVIDER_RETRIES: con el backoff exponencial, más reintentos
+// dejarían a un worker esperando minutos por un solo dato.
+const maxProviderRetries = 6
+
+// DefaultUserAgent identifica a la aplicación y dónde encontrarla si USER_AGENT no está
+// configurada.
+const DefaultUserAgent = "stock-app/1.0 (+https://github.com/jannin2/stock-app)"
+
+// MaxStreamSubscriptions acota STREAM_MAX_SUBSCRIPTIONS: cada suscripción ocupa memoria en
+// el hub y recibe una copia de cada actualización de su ticker.
+const MaxStreamSubscriptions = 1000
+
+// MaxDefaultPageSize acota DEFAULT_PAGE_SIZE.
+const MaxDefaultPageSize = 100
+
+// maxEnrichmentWorkers acota ENRICHMENT_WORKERS: más workers no aceleran la ejecución una
+// vez que todos esperan el límite de los proveedores.
+const maxEnrichmentWorkers = 32
+
+// Default devuelve la configuración usada cuando las variables de entorno no están definidas.
+func Default() Config {
+	return Config{
+		LogLevel:                   LogLevelInfo,
+		EnrichmentInterval:         time.Hour,
+		AlphaVantageRequestsPerMin: 5,  // Límite del plan gratuito de Alpha Vantage
+		FinnhubRequestsPerMin:      60, // Límite del plan gratuito de Finnhub
+		EnrichmentWorkers:          4,
+		UserAgent:                  DefaultUserAgent,
+		FeatureFlags:               map[string]bool{},
+		APIRequestsPerMin:          120,
+		StreamMaxSubscriptions:     100,
+		StreamMessagesPerMin:       60,
+		DefaultPageSize:            10,
+		ShadowSampleRate:           0.05,
+		UserDataRetention:          30 * 24 * time.Hour,
+		ProviderPayloadRetention:   72 * time.Hour,
+		RateLimitRetries:           2,
+		RateLimitRetryWindow:       time.Minute, // Alpha Vantage limita por minuto
+		ProviderRetries:            3,
+		ProviderRetryBackoff:       500 * time.Millisecond,
+		ProviderRetryJitter:        0.2,
+		DBPool: DBPoolConfig{
+			MaxOpenConns:       20,
+			MaxIdleConns:       10,
+			ConnMaxLifetime:    5 * time.Minute,
+			QueryTimeout:       30 * time.Second,
+			UpsertBatchSize:    200,
+			StatementCacheSize: 512,
+			WaitWarning:        100 * time.Millisecond,
+			SaturationWait:     time.Second,
+		},
+		Chaos: ChaosConfig{
+			Latency: 2 * time.Second,
+			Targets: []string{ChaosTargetAPI, ChaosTargetProviders},
+		},
+		EnrichmentTiers: map[string]time.Duration{
+			models.EnrichmentTierHot:      time.Hour,
+			models.EnrichmentTierStandard: 24 * time.Hour,
+			models.EnrichmentTierArchived: 7 * 24 * time.Hour,
+		},
+		ProviderChains: map[string][]string{
+			DataTypeQuote:            {"finnhub", "alphavantage"},
+			DataTypeFundamentals:     {"finnhub", "alphavantage"},
+			DataTypeAlpha:            {"alphavantage"},
+			DataTypeMentions:         {"finnhub"},
+			DataTypeESG:              {"finnhub"},
+			DataTypeShortInterest:    {"finnhub"},
+			DataTypeOptions:          {"finnhub"},
+			DataTypeCorporateActions: {"finnhub"},
+			DataTypeIPOs:             {"finnhub"},
+			DataTypeMacro:            {"finnhub"},
+		},
+		EnrichmentSteps: []string{"quotes", "fundamentals", "indicators", "score"},
+		// Con scoring.DefaultWeights, strong es una recomendación de compra con potencial
+		// de subida y moderate solo una de las dos
+		ScoreBands: []ScoreBand{{Name: "strong", Min: 7}, {Name: "moderate", Min: 3}},
+		UserQuotas: map[string]int{
+			models.QuotaWatchlists: 10,
+			models.QuotaAlerts:     200,
+		},
+		NotifyMaxPerHour: map[string]int{
+			models.NotificationChannelEmail:   10,
+			models.NotificationChannelPush:    30,
+			models.NotificationChannelWebhook: 60,
+			models.NotificationChannelLog:     0,
+		},
+	}
+}
+
+// LogEnabled indica si los mensajes de nivel level deben registrarse.
+func (c Config) LogEnabled(level string) bool {
+	rank, ok := logLevelRank[level]
+	return ok && rank >= logLevelRank[c.LogLevel]
+}
+
+// Enabled indica si el feature flag name está activado.
+func (c Config) Enabled(name string) bool {
+	return c.FeatureFlags[strings.ToLower(name)]
+}
+
+// ProviderDelay devuelve el tiempo entre peticiones al proveedor (por su nombre, ej.
+// "alphavantage") que respeta su límite configurado, o 0 si no tiene límite.
+func (c Config) ProviderDelay(provider string) time.Duration {
+	perMin := 0
+	switch provider {
+	case "alphavantage":
+		perMin = c.AlphaVantageRequestsPerMin
+	case "finnhub":
+		perMin = c.FinnhubRequestsPerMin
+	}
+	if perMin <= 0 {
+		return 0
+	}
+	return time.Minute / time.Duration(perMin)
+}
+
+// FromEnv construye la configuración a partir de las variables de entorno, usando Default
+// para las que no están definidas. Devuelve error si algún valor es inválido.
+func FromEnv() (Config, error) {
+	cfg := Default()
+
+	if level := strings.ToLower(os.Getenv("LOG_LEVEL")); level != "" {
+		if _, ok := logLevelRank[level]; !ok {
+			return Config{}, fmt.Errorf("LOG_LEVEL inválido: %q (use debug, info, warn o error)", level)
+		}
+		cfg.LogLevel = level
+	}
+
+	if value := os.Getenv("ENRICHMENT_INTERVAL"); value != "" {
+		interval, err := time.ParseDuration(value)
+		if err != nil || interval < time.Minute {
+			return Config{}, fmt.Errorf("ENRICHMENT_INTERVAL inválido: %q (duración de al menos 1m, ej. 24h)", value)
+		}
+		cfg.EnrichmentInterval = interval
+	}
+
+	if value := strings.TrimSpace(os.Getenv("ENRICH_CRON")); value != "" {
+		if _, err := clock.ParseSchedule(value); err != nil {
+			return Config{}, fmt.Errorf("ENRICH_CRON inválido: %q (%v)", value, err)
+		}
+		cfg.EnrichCron = value
+	}
+
+	if value := os.Getenv("ALPHA_VANTAGE_RATE_LIMIT"); value != "" {
+		perMin, err := strconv.Atoi(value)
+		if err != nil || perMin < 0 {
+			return Config{}, fmt.Errorf("ALPHA_VANTAGE_RATE_LIMIT inválido: %q (peticiones por minuto, 0 = sin límite)", value)
+		}
+		cfg.AlphaVantageRequestsPerMin = perMin
+	}
+
+	if value := os.Getenv("FINNHUB_RATE_LIMIT"); value != "" {
+		perMin, err := strconv.Atoi(value)
+		if err != nil || perMin < 0 {
+			return Config{}, fmt.Errorf("FINNHUB_RATE_LIMIT inválido: %q (peticiones por minuto, 0 = sin límite)", value)
+		}
+		cfg.FinnhubRequestsPerMin = perMin
+	}
+
+	if value := os.Getenv("ENRICHMENT_WORKERS"); value != "" {
+		workers, err := strconv.Atoi(value)
+		if err != nil || workers < 1 || workers > maxEnrichmentWorkers {
+			return Config{}, fmt.Errorf("ENRICHMENT_WORKERS inválido: %q (entero entre 1 y %d)", value, maxEnrichmentWorkers)
+		}
+		cfg.EnrichmentWorkers = workers
+	}
+
+	if value := strings.TrimSpace(os.Getenv("USER_AGENT")); value != "" {
+		if strings.IndexFunc(value, func(r rune) bool { return r < ' ' || r > '~' }) >= 0 {
+			return Config{}, fmt.Errorf("USER_AGENT inválido: %q (solo caracteres ASCII imprimibles)", value)
+		}
+		cfg.UserAgent = value
+	}
+
+	if value := os.Getenv("RATE_LIMIT_PER_MIN"); value != "" {
+		perMin, err := strconv.Atoi(value)
+		if err != nil || perMin < 0 {
+			return Config{}, fmt.Errorf("RATE_LIMIT_PER_MIN inválido: %q (peticiones por minuto y cliente, 0 = sin límite)", value)
+		}
+		cfg.APIRequestsPerMin = perMin
+	}
+	if value := os.Getenv("RATE_LIMIT_ENFORCE"); value != "" {
+		enforce, err := strconv.ParseBool(value)
+		if err != nil {
+			return Config{}, fmt.Errorf("RATE_LIMIT_ENFORCE inválido: %q (true o false)", value)
+		}
+		cfg.APIRateLimitEnforce = enforce
+	}
+
+	if value := os.Getenv("STREAM_MAX_SUBSCRIPTIONS"); value != "" {
+		subscriptions, err := strconv.Atoi(value)
+		if err != nil || subscriptions < 1 || subscriptions > MaxStreamSubscriptions {
+			return Config{}, fmt.Errorf("STREAM_MAX_SUBSCRIPTIONS inválido: %q (entero entre 1 y %d)", value, MaxStreamSubscriptions)
+		}
+		cfg.StreamMaxSubscriptions = subscriptions
+	}
+
+	if value := os.Getenv("STREAM_MESSAGES_PER_MIN"); value != "" {
+		perMin, err := strconv.Atoi(value)
+		if err != nil || perMin < 0 {
+			return Config{}, fmt.Errorf("STREAM_MESSAGES_PER_MIN inválido: %q (mensajes por minuto y conexión, 0 = sin límite)", value)
+		}
+		cfg.StreamMessagesPerMin = perMin
+	}
+
+	if value := os.Getenv("DEFAULT_PAGE_SIZE"); value != "" {
+		size, err := strconv.Atoi(value)
+		if err != nil || size < 1 || size > MaxDefaultPageSize {
+			return Config{}, fmt.Errorf("DEFAULT_PAGE_SIZE inválido: %q (entero entre 1 y %d)", value, MaxDefaultPageSize)
+		}
+		cfg.DefaultPageSize = size
+	}
+
+	if value := os.Getenv("SCORE_BANDS"); value != "" {
+		bands, err := parseScoreBands(value)
+		if err != nil {
+			return Config{}, fmt.Errorf("SCORE_BANDS inválido: %w", err)
+		}
+		cfg.ScoreBands = bands
+	}
+
+	if value := os.Getenv("ENRICHMENT_TIERS"); value != "" {
+		if err := parseEnrichmentTiers(value, cfg.EnrichmentTiers); err != nil {
+			return Config{}, fmt.Errorf("ENRICHMENT_TIERS inválido: %w", err)
+		}
+	}
+
+	if value := os.Getenv("USER_QUOTAS"); value != "" {
+		if err := parseLimits(value, cfg.UserQuotas); err != nil {
+			return Config{}, fmt.Errorf("USER_QUOTAS inválido: %w", err)
+		}
+	}
+
+	if value := os.Getenv("NOTIFY_MAX_PER_HOUR"); value != "" {
+		if err := parseLimits(value, cfg.NotifyMaxPerHour); err != nil {
+			return Config{}, fmt.Errorf("NOTIFY_MAX_PER_HOUR inválido: %w", err)
+		}
+	}
+
+	cfg.FeatureFlags = parseFeatureFlags(os.Getenv("FEATURE_FLAGS"))
+
+	if value := os.Getenv("USER_DATA_RETENTION"); value != "" {
+		retention, err := time.ParseDuration(value)
+		if err != nil || retention < 24*time.Hour {
+			return Config{}, fmt.Errorf("USER_DATA_RETENTION inválido: %q (duración de al menos 24h, ej. 720h)", value)
+		}
+		cfg.UserDataRetention = retention
+	}
+
+	if value := os.Getenv("PROVIDER_PAYLOAD_RETENTION"); value != "" {
+		retention, err := time.ParseDuration(value)
+		if err != nil || retention < 0 {
+			return Config{}, fmt.Errorf("PROVIDER_PAYLOAD_RETENTION inválido: %q (duración, ej. 72h, o 0 para no guardar las respuestas)", value)
+		}
+		cfg.ProviderPayloadRetention = retention
+	}
+
+	if value := os.Getenv("SHADOW_SAMPLE_RATE"); value != "" {
+		rate, err := strconv.ParseFloat(value, 64)
+		if err != nil || rate < 0 || rate > 1 {
+			return Config{}, fmt.Errorf("SHADOW_SAMPLE_RATE inválido: %q (fracción entre 0 y 1)", value)
+		}
+		cfg.ShadowSampleRate = rate
+	}
+
+	if value := os.Getenv("RATE_LIMIT_RETRIES"); value != "" {
+		retries, err := strconv.Atoi(value)
+		if err != nil || retries < 0 || retries > maxRateLimitRetries {
+			return Config{}, fmt.Errorf("RATE_LIMIT_RETRIES inválido: %q (entero entre 0 y %d)", value, maxRateLimitRetries)
+		}
+		cfg.RateLimitRetries = retries
+	}
+
+	if value := os.Getenv("RATE_LIMIT_RETRY_WINDOW"); value != "" {
+		window, err := time.ParseDuration(value)
+		if err != nil || window < time.Second || window > time.Hour {
+			return Config{}, fmt.Errorf("RATE_LIMIT_RETRY_WINDOW inválido: %q (duración entre 1s y 1h, ej. 1m)", value)
+		}
+		cfg.RateLimitRetryWindow = window
+	}
+
+	if value := os.Getenv("PROVIDER_RETRIES"); value != "" {
+		retries, err := strconv.Atoi(value)
+		if err != nil || retries < 0 || retries > maxProviderRetries {
+			return Config{}, fmt.Errorf("PROVIDER_RETRIES inválido: %q (entero entre 0 y %d)", value, maxProviderRetries)
+		}
+		cfg.ProviderRetries = retries
+	}
+
+	if value := os.Getenv("PROVIDER_RETRY_BACKOFF"); value != "" {
+		backoff, err := time.ParseDuration(value)
+		if err != nil || backoff < 10*time.Millisecond || backoff > time.Minute {
+			return Config{}, fmt.Errorf("PROVIDER_RETRY_BACKOFF inválido: %q (duración entre 10ms y 1m, ej. 500ms)", value)
+		}
+		cfg.ProviderRetryBackoff = backoff
+	}
+
+	if value := os.Getenv("PROVIDER_RETRY_JITTER"); value != "" {
+		jitter, err := strconv.ParseFloat(value, 64)
+		if err != nil || jitter < 0 || jitter > 1 {
+			return Config{}, fmt.Errorf("PROVIDER_RETRY_JITTER inválido: %q (fracción entre 0 y 1)", value)
+		}
+		cfg.ProviderRetryJitter = jitter
+	}
+
+	if err := parseChaos(&cfg.Chaos); err != nil {
+		return Config{}, err
+	}
+	if err := parseDBPool(&cfg.DBPool); err != nil {
+		return Config{}, err
+	}
+	if cfg.Enabled(FlagChaos) && strings.EqualFold(os.Getenv("APP_ENV"), "production") {
+		return Config{}, fmt.Errorf("FEATURE_FLAGS inválido: el modo chaos no puede activarse con APP_ENV=production")
+	}
+
+	for dataType, env := range map[string]string{
+		DataTypeQuote:            "PROVIDER_CHAIN_QUOTE",
+		DataTypeFundamentals:     "PROVIDER_CHAIN_FUNDAMENTALS",
+		DataTypeAlpha:            "PROVIDER_CHAIN_ALPHA",
+		DataTypeMentions:         "PROVIDER_CHAIN_MENTIONS",
+		DataTypeESG:              "PROVIDER_CHAIN_ESG",
+		DataTypeShortInterest:    "PROVIDER_CHAIN_SHORT_INTEREST",
+		DataTypeOptions:          "PROVIDER_CHAIN_OPTIONS",
+		DataTypeCorporateActions: "PROVIDER_CHAIN_CORPORATE_ACTIONS",
+		DataTypeIPOs:             "PROVIDER_CHAIN_IPOS",
+		DataTypeMacro:            "PROVIDER_CHAIN_MACRO",
+	} {
+		value := os.Getenv(env)
+		if value == "" {
+			continue
+		}
+		chain, err := parseProviderChain(value)
+		if err != nil {
+			return Config{}, fmt.Errorf("%s inválido: %w", env, err)
+		}
+		cfg.ProviderChains[dataType] = chain
+	}
+
+	if value := os.Getenv("ENRICHMENT_STEPS"); value != "" {
+		steps, err := parseEnrichmentSteps(value)
+		if err != nil {
+			return Config{}, fmt.Errorf("ENRICHMENT_STEPS inválido: %w", err)
+		}
+		cfg.EnrichmentSteps = steps
+	}
+	return cfg, nil
+}
+
+// parseChaos lee las variables CHAOS_* sobre chaos.
+func parseChaos(chaos *ChaosConfig) error {
+	for env, rate := range map[string]*float64{
+		"CHAOS_LATENCY_RATE":   &chaos.LatencyRate,
+		"CHAOS_ERROR_RATE":     &chaos.ErrorRate,
+		"CHAOS_MALFORMED_RATE": &chaos.MalformedRate,
+	} {
+		value := os.Getenv(env)
+		if value == "" {
+			continue
+		}
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil || parsed < 0 || parsed > 1 {
+			return fmt.Errorf("%s inválido: %q (probabilidad entre 0 y 1)", env, value)
+		}
+		*rate = parsed
+	}
+
+	if value := os.Getenv("CHAOS_LATENCY"); value != "" {
+		latency, err := time.ParseDuration(value)
+		if err != nil || latency < 0 {
+			return fmt.Errorf("CHAOS_LATENCY inválido: %q (duración, ej. 2s)", value)
+		}
+		chaos.Latency = latency
+	}
+
+	if value := os.Getenv("CHAOS_TARGETS"); value != "" {
+		chaos.Targets = nil
+		for _, target := range strings.Split(value, ",") {
+			target = strings.ToLower(strings.TrimSpace(target))
+			if target == "" {
+				continue
+			}
+			if target != ChaosTargetAPI && target != ChaosTargetProviders {
+				return fmt.Errorf("CHAOS_TARGETS inválido: destino desconocido %q (use api o providers)", target)
+			}
+			chaos.Targets = append(chaos.Targets, target)
+		}
+	}
+	return nil
+}
+
+// parseDBPool lee las variables DB_* del pool de conexiones sobre pool.
+func parseDBPool(pool *DBPoolConfig) error {
+	for env, size := range map[string]*int{
+		"DB_MAX_OPEN_CONNS": &pool.MaxOpenConns,
+		"DB_MAX_IDLE_CONNS": &pool.MaxIdleConns,
+	} {
+		value := os.Getenv(env)
+		if value == "" {
+			continue
+		}
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			return fmt.Errorf("%s inválido: %q (entero positivo)", env, value)
+		}
+		*size = parsed
+	}
+	if value := os.Getenv("DB_UPSERT_BATCH_SIZE"); value != "" {
+		size, err := strconv.Atoi(value)
+		if err != nil || size < 1 || size > MaxUpsertBatchSize {
+			return fmt.Errorf("DB_UPSERT_BATCH_SIZE inválido: %q (entero entre 1 y %d)", value, MaxUpsertBatchSize)
+		}
+		pool.UpsertBatchSize = size
+	}
+	if value := os.Getenv("DB_STATEMENT_CACHE_SIZE"); value != "" {
+		size, err := strconv.Atoi(value)
+		if err != nil || size < 0 {
+			return fmt.Errorf("DB_STATEMENT_CACHE_SIZE inválido: %q (entero, 0 para desactivar la caché)", value)
+		}
+		pool.StatementCacheSize = size
+	}
+	if pool.MaxIdleConns > pool.MaxOpenConns {
+		return fmt.Errorf("DB_MAX_IDLE_CONNS inválido: %d es mayor que DB_MAX_OPEN_CONNS (%d)", pool.MaxIdleConns, pool.MaxOpenConns)
+	}
+
+	for env, duration := range map[string]*time.Duration{
+		"DB_CONN_MAX_LIFETIME":    &pool.ConnMaxLifetime,
+		"DB_CONN_MAX_IDLE_TIME":   &pool.ConnMaxIdleTime,
+		"DB_QUERY_TIMEOUT":        &pool.QueryTimeout,
+		"DB_POOL_WAIT_WARNING":    &pool.WaitWarning,
+		"DB_POOL_SATURATION_WAIT": &pool.SaturationWait,
+	} {
+		value := os.Getenv(env)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed < 0 {
+			return fmt.Errorf("%s inválido: %q (duración, ej. 5m, o 0 para desactivarlo)", env, value)
+		}
+		*duration = parsed
+	}
+	return nil
+}
+
+// parseFeatureFlags interpreta una lista separada por comas; un "-" delante desactiva el flag.
+func parseFeatureFlags(value string) map[string]bool {
+	flags := map[string]bool{}
+	for _, name := range strings.Split(value, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		enabled := !strings.HasPrefix(name, "-")
+		name = strings.TrimPrefix(name, "-")
+		if name != "" {
+			flags[name] = enabled
+		}
+	}
+	return flags
+}
+
+// parseEnrichmentTiers interpreta una lista "tier=duración" separada por comas y la
+// aplica sobre tiers. Los tiers no incluidos conservan su intervalo.
+func parseEnrichmentTiers(value string, tiers map[string]time.Duration) error {
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, durationStr, ok := strings.Cut(pair, "=")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !ok {
+			return fmt.Errorf("%q no tiene el formato tier=duración", pair)
+		}
+		if _, known := tiers[name]; !known {
+			return fmt.Errorf("tier desconocido %q (use hot, standard o archived)", name)
+		}
+		interval, err := time.ParseDuration(strings.TrimSpace(durationStr))
+		if err != nil || interval < time.Minute {
+			return fmt.Errorf("intervalo inválido para %s: %q (duración de al menos 1m)", name, durationStr)
+		}
+		tiers[name] = interval
+	}
+	return nil
+}
+
+// parseLimits interpreta una lista "nombre=límite" separada por comas y la aplica sobre
+// limits. Solo se aceptan los nombres que ya están en limits; los no incluidos conservan
+// su límite.
+func parseLimits(value string, limits map[string]int) error {
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, limitStr, ok := strings.Cut(pair, "=")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !ok {
+			return fmt.Errorf("%q no tiene el formato nombre=límite", pair)
+		}
+		if _, known := limits[name]; !known {
+			names := make([]string, 0, len(limits))
+			for known := range limits {
+				names = append(names, known)
+			}
+			sort.Strings(names)
+			return fmt.Errorf("nombre desconocido %q (use %s)", name, strings.Join(names, ", "))
+		}
+		limit, err := strconv.Atoi(strings.TrimSpace(limitStr))
+		if err != nil || limit < 0 {
+			return fmt.Errorf("límite inválido para %s: %q (entero, 0 = sin límite)", name, limitStr)
+		}
+		limits[name] = limit
+	}
+	return nil
+}
+
+// parseScoreBands interpreta una lista "nombre=mínimo" separada por comas, de la banda más
+// alta a la más baja.
+func parseScoreBands(value string) ([]ScoreBand, error) {
+	var bands []ScoreBand
+	seen := map[string]bool{}
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, minStr, ok := strings.Cut(pair, "=")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !ok || name == "" {
+			return nil, fmt.Errorf("%q no tiene el formato nombre=mínimo", pair)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("banda repetida %q", name)
+		}
+		seen[name] = true
+		min, err := strconv.ParseFloat(strings.TrimSpace(minStr), 64)
+		if err != nil || math.IsInf(min, 0) || math.IsNaN(min) {
+			return nil, fmt.Errorf("mínimo inválido para %s: %q", name, minStr)
+		}
+		if len(bands) > 0 && min >= bands[len(bands)-1].Min {
+			return nil, fmt.Errorf("las bandas deben ir de mayor a menor mínimo (%s tras %s)", name, bands[len(bands)-1].Name)
+		}
+		bands = append(bands, ScoreBand{Name: name, Min: min})
+	}
+	if len(bands) == 0 {
+		return nil, fmt.Errorf("la lista de bandas está vacía")
+	}
+	return bands, nil
+}
+
+// parseProviderChain interpreta una lista de proveedores separada por comas, en orden de
+// prioridad. Los nombres los valida providers.ValidateChains.
+func parseProviderChain(value string) ([]string, error) {
+	var chain []string
+	for _, name := range strings.Split(value, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		chain = append(chain, name)
+	}
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("la cadena de proveedores está vacía")
+	}
+	return chain, nil
+}
+
+// parseEnrichmentSteps lee una lista de pasos separados por comas. Los nombres se
+// comprueban contra los pasos registrados en el enricher, que config no conoce.
+func parseEnrichmentSteps(value string) ([]string, error) {
+	var steps []string
+	seen := map[string]bool{}
+	for _, name := range strings.Split(value, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("paso repetido %q", name)
+		}
+		seen[name] = true
+		steps = append(steps, name)
+	}
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("la lista de pasos está vacía")
+	}
+	return steps, nil
+}
+
+var (
+	current atomic.Pointer[Config]
+
+	subscribersMu sync.Mutex
+	subscribers   []func(Config)
+)
+
+// Current devuelve la configuración vigente. Antes de la primera carga devuelve Default.
+func Current() Config {
+	if cfg := current.Load(); cfg != nil {
+		return *cfg
+	}
+	return Default()
+}
+
+// Set reemplaza la configuración vigente y notifica a los suscriptores.
+func Set(cfg Config) {
+	current.Store(&cfg)
+
+	subscribersMu.Lock()
+	subs := append([]func(Config){}, subscribers...)
+	subscribersMu.Unlock()
+	for _, fn := range subs {
+		fn(cfg)
+	}
+}
+
+// OnReload registra fn para que se llame con la nueva configuración tras cada Set o Reload.
+func OnReload(fn func(Config)) {
+	subscribersMu.Lock()
+	defer subscribersMu.Unlock()
+	subscribers = append(subscribers, fn)
+}
+
+// Reload vuelve a leer el archivo .env (si existe) y las variables de entorno y, si la
+// configuración es válida, la aplica. Si es inválida se conserva la configuración anterior.
+func Reload() (Config, error) {
+	if err := godotenv.Overload(); err != nil && !os.IsNotExist(err) {
+		log.Printf("Advertencia: no se pudo releer el archivo .env: %v", err)
+	}
+
+	cfg, err := FromEnv()
+	if err != nil {
+		return Current(), err
+	}
+	Set(cfg)
+	log.Printf("Configuración recargada: %s", cfg)
+	return cfg, nil
+}
+
+// EnrichSchedule devuelve la planificación de EnrichCron, o nil si no está definida y el
+// enricher se ejecuta cada EnrichmentInterval.
+func (c Config) EnrichSchedule() *clock.Schedule {
+	if c.EnrichCron == "" {
+		return nil
+	}
+	s, err := clock.ParseSchedule(c.EnrichCron)
+	if err != nil { // FromEnv ya la validó; solo puede fallar con una Config construida a mano
+		log.Printf("ADVERTENCIA: ENRICH_CRON inválido, se usará el intervalo: %v", err)
+		return nil
+	}
+	return s
+}
+
+// MarshalJSON serializa EnrichmentInterval, EnrichmentTiers, las retenciones y la ventana
+// de reintento como duraciones legibles (ej. "24h0m0s").
+func (c Config) MarshalJSON() ([]byte, error) {
+	type plain Config
+	tiers := make(map[string]string, len(c.EnrichmentTiers))
+	for name, interval := range c.EnrichmentTiers {
+		tiers[name] = interval.String()
+	}
+	return json.Marshal(struct {
+		plain
+		EnrichmentInterval string            `json:"enrichment_interval"`
+		EnrichmentTiers    map[string]string `json:"enrichment_tiers"`
+		UserDataRetention  string            `json:"user_data_retention"`
+		PayloadRetention   string            `json:"provider_payload_retention"`
+		RetryWindow        string            `json:"rate_limit_retry_window"`
+		RetryBackoff       string            `json:"provider_retry_backoff"`
+	}{plain(c), c.EnrichmentInterval.String(), tiers, c.UserDataRetention.String(), c.ProviderPayloadRetention.String(), c.RateLimitRetryWindow.String(), c.ProviderRetryBackoff.String()})
+}
+
+// MarshalJSON serializa Latency como duración legible (ej. "2s").
+func (c ChaosConfig) MarshalJSON() ([]byte, error) {
+	type plain ChaosConfig
+	return json.Marshal(struct {
+		plain
+		Latency string `json:"latency"`
+	}{plain(c), c.Latency.String()})
+}
+
+// MarshalJSON serializa las duraciones como texto legible (ej. "5m0s").
+func (p DBPoolConfig) MarshalJSON() ([]byte, error) {
+	type plain DBPoolConfig
+	return json.Marshal(struct {
+		plain
+		ConnMaxLifetime string `json:"conn_max_lifetime"`
+		ConnMaxIdleTime string `json:"conn_max_idle_time"`
+		QueryTimeout    string `json:"query_timeout"`
+		WaitWarning     string `json:"wait_warning"`
+		SaturationWait  string `json:"saturation_wait"`
+	}{plain(p), p.ConnMaxLifetime.String(), p.ConnMaxIdleTime.String(), p.QueryTimeout.String(), p.WaitWarning.String(), p.SaturationWait.String()})
+}
+
+// String resume la configuración para los logs.
+func (c Config) String() string {
+	flags := make([]string, 0, len(c.FeatureFlags))
+	for name, enabled := range c.FeatureFlags {
+		if enabled {
+			flags = append(flags, name)
+		}
+	}
+	sort.Strings(flags)
+	return fmt.Sprintf("log_level=%s enrichment_interval=%s enrich_cron=%q enrichment_workers=%d alpha_vantage_rate_limit=%d/min finnhub_rate_limit=%d/min user_agent=%q provider_retries=%d api_rate_limit=%d/min api_rate_limit_enforce=%t stream=%d subs,%d msgs/min feature_flags=[%s] provider_chain_quote=%s provider_chain_fundamentals=%s provider_chain_alpha=%s provider_chain_mentions=%s provider_chain_esg=%s provider_chain_short_interest=%s provider_chain_options=%s provider_chain_corporate_actions=%s provider_chain_ipos=%s provider_chain_macro=%s enrichment_steps=%s db_pool=%d/%d",
+		c.LogLevel, c.EnrichmentInterval, c.EnrichCron, c.EnrichmentWorkers, c.AlphaVantageRequestsPerMin, c.FinnhubRequestsPerMin, c.UserAgent, c.ProviderRetries, c.APIRequestsPerMin, c.APIRateLimitEnforce, c.StreamMaxSubscriptions, c.StreamMessagesPerMin, strings.Join(flags, ","),
+		strings.Join(c.ProviderChains[DataTypeQuote], ","), strings.Join(c.ProviderChains[DataTypeFundamentals], ","),
+		strings.Join(c.ProviderChains[DataTypeAlpha], ","), strings.Join(c.ProviderChains[DataTypeMentions], ","),
+		strings.Join(c.ProviderChains[DataTypeESG], ","), strings.Join(c.ProviderChains[DataTypeShortInterest], ","),
+		strings.Join(c.ProviderChains[DataTypeOptions], ","), strings.Join(c.ProviderChains[DataTypeCorporateActions], ","),
+		strings.Join(c.ProviderChains[DataTypeIPOs], ","), strings.Join(c.ProviderChains[DataTypeMacro], ","),
+		strings.Join(c.EnrichmentSteps, ","), c.DBPool.MaxOpenConns, c.DBPool.MaxIdleConns)
+}
@@ -0,0 +1,318 @@
+package config
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv("LOG_LEVEL", "WARN")
+	t.Setenv("ENRICHMENT_INTERVAL", "6h")
+	t.Setenv("ALPHA_VANTAGE_RATE_LIMIT", "30")
+	t.Setenv("RATE_LIMIT_PER_MIN", "0")
+	t.Setenv("RATE_LIMIT_ENFORCE", "true")
+	t.Setenv("SHADOW_SAMPLE_RATE", "0.5")
+	t.Setenv("USER_DATA_RETENTION", "168h")
+	t.Setenv("PROVIDER_PAYLOAD_RETENTION", "0")
+	t.Setenv("ENRICHMENT_STEPS", "Quotes, esg ,score")
+	t.Setenv("RATE_LIMIT_RETRIES", "0")
+	t.Setenv("RATE_LIMIT_RETRY_WINDOW", "90s")
+	t.Setenv("FEATURE_FLAGS", "heatmap, -chaos,Shadow")
+	t.Setenv("ENRICH_CRON", " 0 6 * * 1-5 ")
+	t.Setenv("FINNHUB_RATE_LIMIT", "0")
+	t.Setenv("ENRICHMENT_WORKERS", "8")
+	t.Setenv("USER_AGENT", "stock-app-staging/2.3 (ops@example.com)")
+	t.Setenv("PROVIDER_RETRIES", "0")
+	t.Setenv("PROVIDER_RETRY_BACKOFF", "250ms")
+	t.Setenv("PROVIDER_RETRY_JITTER", "0")
+	t.Setenv("STREAM_MAX_SUBSCRIPTIONS", "20")
+	t.Setenv("STREAM_MESSAGES_PER_MIN", "0")
+	t.Setenv("DEFAULT_PAGE_SIZE", "25")
+	t.Setenv("SCORE_BANDS", "High=6, low=-1")
+
+	cfg, err := FromEnv()
+	if err != nil {
+		t.Fatalf("❌ error inesperado: %v", err)
+	}
+	if cfg.LogLevel != LogLevelWarn || cfg.LogEnabled(LogLevelInfo) || !cfg.LogEnabled(LogLevelError) {
+		t.Errorf("❌ nivel de log inesperado: %s", cfg.LogLevel)
+	}
+	if cfg.EnrichmentInterval != 6*time.Hour {
+		t.Errorf("❌ intervalo %s, se esperaba 6h", cfg.EnrichmentInterval)
+	}
+	if cfg.EnrichCron != "0 6 * * 1-5" || cfg.EnrichSchedule() == nil || Default().EnrichSchedule() != nil {
+		t.Errorf("❌ planificación %q inesperada", cfg.EnrichCron)
+	}
+	if got := cfg.ProviderDelay("alphavantage"); got != 2*time.Second {
+		t.Errorf("❌ espera de Alpha Vantage %s, se esperaba 2s", got)
+	}
+	if got := cfg.ProviderDelay("finnhub"); got != 0 || cfg.EnrichmentWorkers != 8 {
+		t.Errorf("❌ espera de Finnhub %s con %d workers, se esperaba 0 (sin límite) con 8", got, cfg.EnrichmentWorkers)
+	}
+	if cfg.UserAgent != "stock-app-staging/2.3 (ops@example.com)" || Default().UserAgent != DefaultUserAgent {
+		t.Errorf("❌ User-Agent %q inesperado", cfg.UserAgent)
+	}
+	if cfg.ProviderRetries != 0 || cfg.ProviderRetryBackoff != 250*time.Millisecond || cfg.ProviderRetryJitter != 0 {
+		t.Errorf("❌ reintentos de proveedores %d cada %s (jitter %v), se esperaba 0 cada 250ms sin jitter",
+			cfg.ProviderRetries, cfg.ProviderRetryBackoff, cfg.ProviderRetryJitter)
+	}
+	if cfg.StreamMaxSubscriptions != 20 || cfg.StreamMessagesPerMin != 0 {
+		t.Errorf("❌ stream con %d suscripciones y %d mensajes/min, se esperaban 20 y 0 (sin límite)",
+			cfg.StreamMaxSubscriptions, cfg.StreamMessagesPerMin)
+	}
+	if cfg.DefaultPageSize != 25 {
+		t.Errorf("❌ tamaño de página %d, se esperaba 25", cfg.DefaultPageSize)
+	}
+	if cfg.Band(6) != "high" || cfg.Band(-0.5) != "low" || cfg.Band(-3) != "" || Default().Band(5) != "moderate" {
+		t.Errorf("❌ bandas de score inesperadas: %v", cfg.ScoreBands)
+	}
+	if cfg.UserDataRetention != 7*24*time.Hour {
+		t.Errorf("❌ retención de datos de usuario %s, se esperaba 168h", cfg.UserDataRetention)
+	}
+	if cfg.ProviderPayloadRetention != 0 {
+		t.Errorf("❌ retención de respuestas de proveedores %s, se esperaba 0 (sin captura)", cfg.ProviderPayloadRetention)
+	}
+	if got := strings.Join(cfg.EnrichmentSteps, ","); got != "quotes,esg,score" {
+		t.Errorf("❌ pasos de enriquecimiento %s, se esperaba quotes,esg,score", got)
+	}
+	if cfg.ShadowSampleRate != 0.5 {
+		t.Errorf("❌ muestreo de tráfico sombra %v, se esperaba 0.5", cfg.ShadowSampleRate)
+	}
+	if cfg.APIRequestsPerMin != 0 || !cfg.APIRateLimitEnforce {
+		t.Errorf("❌ límite de la API %d (enforce=%t), se esperaba 0 y enforce=true", cfg.APIRequestsPerMin, cfg.APIRateLimitEnforce)
+	}
+	if cfg.RateLimitRetries != 0 || cfg.RateLimitRetryWindow != 90*time.Second {
+		t.Errorf("❌ reintentos por límite %d cada %s, se esperaban 0 cada 1m30s", cfg.RateLimitRetries, cfg.RateLimitRetryWindow)
+	}
+	if !cfg.Enabled("heatmap") || !cfg.Enabled("shadow") || cfg.Enabled("chaos") || cfg.Enabled("otro") {
+		t.Errorf("❌ feature flags inesperados: %v", cfg.FeatureFlags)
+	}
+}
+
+func TestFromEnv_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"LOG_LEVEL":                  "verbose",
+		"ENRICHMENT_INTERVAL":        "10s",
+		"ALPHA_VANTAGE_RATE_LIMIT":   "-1",
+		"RATE_LIMIT_PER_MIN":         "muchas",
+		"RATE_LIMIT_ENFORCE":         "a veces",
+		"SHADOW_SAMPLE_RATE":         "2",
+		"USER_DATA_RETENTION":        "1h",
+		"PROVIDER_PAYLOAD_RETENTION": "-1h",
+		"ENRICHMENT_STEPS":           "quotes, score,quotes",
+		"RATE_LIMIT_RETRIES":         "50",
+		"RATE_LIMIT_RETRY_WINDOW":    "100ms",
+		"ENRICH_CRON":                "0 6 * *",
+		"FINNHUB_RATE_LIMIT":         "rápido",
+		"ENRICHMENT_WORKERS":         "0",
+		"USER_AGENT":                 "stock-app\r\nX-Inyectada: 1",
+		"PROVIDER_RETRIES":           "10",
+		"PROVIDER_RETRY_BACKOFF":     "5ms",
+		"PROVIDER_RETRY_JITTER":      "1.5",
+		"STREAM_MAX_SUBSCRIPTIONS":   "0",
+		"STREAM_MESSAGES_PER_MIN":    "-5",
+		"DEFAULT_PAGE_SIZE":          "500",
+		"SCORE_BANDS":                "strong=3,moderate=7",
+	}
+	for env, value := range tests {
+		t.Run(env, func(t *testing.T) {
+			t.Setenv(env, value)
+			if _, err := FromEnv(); err == nil || !strings.Contains(err.Error(), env) {
+				t.Errorf("❌ se esperaba un error sobre %s, se obtuvo %v", env, err)
+			}
+		})
+	}
+}
+
+func TestSet_NotifiesSubscribers(t *testing.T) {
+	var got Config
+	OnReload(func(c Config) { got = c })
+
+	cfg := Default()
+	cfg.EnrichmentInterval = time.Hour
+	Set(cfg)
+
+	if got.EnrichmentInterval != time.Hour || Current().EnrichmentInterval != time.Hour {
+		t.Errorf("❌ la nueva configuración no se propagó: suscriptor %s, vigente %s", got.EnrichmentInterval, Current().EnrichmentInterval)
+	}
+
+	body, err := json.Marshal(Current())
+	if err != nil || !strings.Contains(string(body), `"enrichment_interval":"1h0m0s"`) {
+		t.Errorf("❌ JSON inesperado: %s (%v)", body, err)
+	}
+}
+
+func TestFromEnv_ProviderChains(t *testing.T) {
+	t.Setenv("PROVIDER_CHAIN_FUNDAMENTALS", "AlphaVantage, finnhub")
+
+	cfg, err := FromEnv()
+	if err != nil {
+		t.Fatalf("❌ error inesperado: %v", err)
+	}
+	if got := strings.Join(cfg.ProviderChains[DataTypeFundamentals], ","); got != "alphavantage,finnhub" {
+		t.Errorf("❌ cadena de fundamentales %s, se esperaba alphavantage,finnhub", got)
+	}
+	if got := strings.Join(cfg.ProviderChains[DataTypeQuote], ","); got != "finnhub,alphavantage" {
+		t.Errorf("❌ cadena de cotización %s, se esperaba la por defecto", got)
+	}
+	if got := strings.Join(cfg.ProviderChains[DataTypeMentions], ","); got != "finnhub" {
+		t.Errorf("❌ cadena de menciones %s, se esperaba finnhub", got)
+	}
+
+	// Los nombres los valida providers.ValidateChains: config acepta proveedores nuevos
+	t.Setenv("PROVIDER_CHAIN_QUOTE", "Polygon, finnhub")
+	if cfg, err := FromEnv(); err != nil || strings.Join(cfg.ProviderChains[DataTypeQuote], ",") != "polygon,finnhub" {
+		t.Errorf("❌ cadena de cotización inesperada: %v (%v)", cfg.ProviderChains[DataTypeQuote], err)
+	}
+
+	t.Setenv("PROVIDER_CHAIN_QUOTE", " , ")
+	if _, err := FromEnv(); err == nil || !strings.Contains(err.Error(), "PROVIDER_CHAIN_QUOTE") {
+		t.Errorf("❌ se esperaba un error por cadena vacía, se obtuvo %v", err)
+	}
+}
+
+func TestFromEnv_EnrichmentTiers(t *testing.T) {
+	t.Setenv("ENRICHMENT_TIERS", "hot=30m, Archived=720h")
+
+	cfg, err := FromEnv()
+	if err != nil {
+		t.Fatalf("❌ error inesperado: %v", err)
+	}
+	want := map[string]time.Duration{
+		models.EnrichmentTierHot:      30 * time.Minute,
+		models.EnrichmentTierStandard: 24 * time.Hour,
+		models.EnrichmentTierArchived: 720 * time.Hour,
+	}
+	for tier, interval := range want {
+		if cfg.EnrichmentTiers[tier] != interval {
+			t.Errorf("❌ intervalo de %s = %s, se esperaba %s", tier, cfg.EnrichmentTiers[tier], interval)
+		}
+	}
+	if Default().EnrichmentTiers[models.EnrichmentTierHot] != time.Hour {
+		t.Errorf("❌ FromEnv no debería modificar los valores por defecto")
+	}
+
+	body, err := json.Marshal(cfg)
+	if err != nil || !strings.Contains(string(body), `"hot":"30m0s"`) {
+		t.Errorf("❌ JSON inesperado: %s (%v)", body, err)
+	}
+
+	for _, value := range []string{"cold=1h", "hot=10s", "hot"} {
+		t.Setenv("ENRICHMENT_TIERS", value)
+		if _, err := FromEnv(); err == nil || !strings.Contains(err.Error(), "ENRICHMENT_TIERS") {
+			t.Errorf("❌ %q: se esperaba un error sobre ENRICHMENT_TIERS, se obtuvo %v", value, err)
+		}
+	}
+}
+
+func TestFromEnv_UserQuotas(t *testing.T) {
+	t.Setenv("USER_QUOTAS", "Watchlists=25, alerts=0")
+
+	cfg, err := FromEnv()
+	if err != nil {
+		t.Fatalf("❌ error inesperado: %v", err)
+	}
+	want := map[string]int{models.QuotaWatchlists: 25, models.QuotaAlerts: 0}
+	for resource, limit := range want {
+		if cfg.UserQuotas[resource] != limit {
+			t.Errorf("❌ cuota de %s = %d, se esperaba %d", resource, cfg.UserQuotas[resource], limit)
+		}
+	}
+
+	for _, value := range []string{"rules=10", "screens=5", "alerts=-1", "alerts=muchas", "alerts"} {
+		t.Setenv("USER_QUOTAS", value)
+		if _, err := FromEnv(); err == nil || !strings.Contains(err.Error(), "USER_QUOTAS") {
+			t.Errorf("❌ %q: se esperaba un error sobre USER_QUOTAS, se obtuvo %v", value, err)
+		}
+	}
+}
+
+func TestFromEnv_NotifyMaxPerHour(t *testing.T) {
+	t.Setenv("NOTIFY_MAX_PER_HOUR", "email=3")
+	cfg, err := FromEnv()
+	if err != nil || cfg.NotifyMaxPerHour[models.NotificationChannelEmail] != 3 {
+		t.Errorf("❌ límite de e-mail inesperado: %v (%v)", cfg.NotifyMaxPerHour, err)
+	}
+
+	t.Setenv("NOTIFY_MAX_PER_HOUR", "sms=3")
+	if _, err := FromEnv(); err == nil || !strings.Contains(err.Error(), "NOTIFY_MAX_PER_HOUR") {
+		t.Errorf("❌ se esperaba un error por canal desconocido, se obtuvo %v", err)
+	}
+}
+
+func TestFromEnv_Chaos(t *testing.T) {
+	t.Setenv("FEATURE_FLAGS", "chaos")
+	t.Setenv("CHAOS_ERROR_RATE", "0.25")
+	t.Setenv("CHAOS_LATENCY", "500ms")
+	t.Setenv("CHAOS_TARGETS", "providers")
+
+	cfg, err := FromEnv()
+	if err != nil {
+		t.Fatalf("❌ error inesperado: %v", err)
+	}
+	if cfg.Chaos.ErrorRate != 0.25 || cfg.Chaos.Latency != 500*time.Millisecond {
+		t.Errorf("❌ configuración chaos inesperada: %+v", cfg.Chaos)
+	}
+	if !cfg.ChaosActive(ChaosTargetProviders) || cfg.ChaosActive(ChaosTargetAPI) {
+		t.Errorf("❌ chaos debería estar activo solo para providers: %v", cfg.Chaos.Targets)
+	}
+	if body, err := json.Marshal(cfg); err != nil || !strings.Contains(string(body), `"latency":"500ms"`) {
+		t.Errorf("❌ JSON inesperado: %s (%v)", body, err)
+	}
+
+	t.Setenv("APP_ENV", "production")
+	if _, err := FromEnv(); err == nil || !strings.Contains(err.Error(), "production") {
+		t.Errorf("❌ se esperaba un error por chaos en producción, se obtuvo %v", err)
+	}
+
+	t.Setenv("APP_ENV", "")
+	t.Setenv("CHAOS_ERROR_RATE", "1.5")
+	if _, err := FromEnv(); err == nil || !strings.Contains(err.Error(), "CHAOS_ERROR_RATE") {
+		t.Errorf("❌ se esperaba un error sobre CHAOS_ERROR_RATE, se obtuvo %v", err)
+	}
+}
+
+func TestFromEnv_DBPool(t *testing.T) {
+	cfg, err := FromEnv()
+	if err != nil {
+		t.Fatalf("❌ error inesperado: %v", err)
+	}
+	if cfg.DBPool != Default().DBPool {
+		t.Errorf("❌ pool por defecto inesperado: %+v", cfg.DBPool)
+	}
+
+	t.Setenv("DB_MAX_OPEN_CONNS", "40")
+	t.Setenv("DB_MAX_IDLE_CONNS", "15")
+	t.Setenv("DB_CONN_MAX_IDLE_TIME", "1m")
+	t.Setenv("DB_POOL_SATURATION_WAIT", "0")
+	t.Setenv("DB_UPSERT_BATCH_SIZE", "500")
+	cfg, err = FromEnv()
+	if err != nil {
+		t.Fatalf("❌ error inesperado: %v", err)
+	}
+	if cfg.DBPool.MaxOpenConns != 40 || cfg.DBPool.MaxIdleConns != 15 || cfg.DBPool.ConnMaxIdleTime != time.Minute || cfg.DBPool.SaturationWait != 0 || cfg.DBPool.UpsertBatchSize != 500 {
+		t.Errorf("❌ pool inesperado: %+v", cfg.DBPool)
+	}
+	if body, err := json.Marshal(cfg); err != nil || !strings.Contains(string(body), `"conn_max_lifetime":"5m0s"`) {
+		t.Errorf("❌ JSON inesperado: %s (%v)", body, err)
+	}
+
+	t.Setenv("DB_MAX_IDLE_CONNS", "50")
+	if _, err := FromEnv(); err == nil || !strings.Contains(err.Error(), "DB_MAX_IDLE_CONNS") {
+		t.Errorf("❌ se esperaba un error sobre DB_MAX_IDLE_CONNS, se obtuvo %v", err)
+	}
+	t.Setenv("DB_MAX_IDLE_CONNS", "")
+	t.Setenv("DB_POOL_WAIT_WARNING", "pronto")
+	if _, err := FromEnv(); err == nil || !strings.Contains(err.Error(), "DB_POOL_WAIT_WARNING") {
+		t.Errorf("❌ se esperaba un error sobre DB_POOL_WAIT_WARNING, se obtuvo %v", err)
+	}
+	t.Setenv("DB_POOL_WAIT_WARNING", "")
+	t.Setenv("DB_UPSERT_BATCH_SIZE", "5000")
+	if _, err := FromEnv(); err == nil || !strings.Contains(err.Error(), "DB_UPSERT_BATCH_SIZE") {
+		t.Errorf("❌ se esperaba un error sobre DB_UPSERT_BATCH_SIZE, se obtuvo %v", err)
+	}
+}
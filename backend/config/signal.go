@@ -0,0 +1,29 @@
+package config
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// WatchSIGHUP recarga la configuración cada vez que el proceso recibe SIGHUP, hasta que se
+// cancela ctx. Un error de recarga se registra y deja la configuración anterior intacta.
+func WatchSIGHUP(ctx context.Context) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+			log.Println("SIGHUP recibido, recargando configuración...")
+			if _, err := Reload(); err != nil {
+				log.Printf("❌ Error al recargar la configuración: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package enricher
+
+import (
+	"database/sql"
+	"log"
+
+	"github.com/jannin2/stock-app/backend/models"
+	"github.com/jannin2/stock-app/backend/providers"
+)
+
+// StepBuzz counts today's social media mentions through the mentions provider chain
+// (config.ProviderChains). Like the sentiment step it costs one more call per stock and is
+// not in the default pipeline.
+const StepBuzz = "buzz"
+
+func init() {
+	RegisterStep(StepBuzz, buzzStep)
+}
+
+// buzzStep fills Buzz with the mentions of the current UTC day. persist also saves them to
+// the daily mention history.
+func buzzStep(ctx *StepContext, stock *models.Stock) error {
+	mentions, source, failures, err := providers.FetchMentions(stock.Ticker, ctx.Now())
+	for _, f := range failures {
+		ctx.ProviderError(f.Provider+" mentions", f.Err)
+	}
+	if err != nil {
+		log.Printf("Error getting mentions for %s: %v. Assigning null buzz.", stock.Ticker, err)
+		stock.Buzz = models.NullFloat64{NullFloat64: sql.NullFloat64{Valid: false}}
+		return nil
+	}
+
+	stock.Buzz = models.NewNullFloat64(float64(mentions.Count))
+	stock.Provenance.Set(source, ctx.Now(), "buzz")
+	log.Printf("Mentions for %s from %s: %d", stock.Ticker, source, mentions.Count)
+	return nil
+}
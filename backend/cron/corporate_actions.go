@@ -0,0 +1,47 @@
+package enricher
+
+import (
+	"log"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/models"
+	"github.com/jannin2/stock-app/backend/providers"
+)
+
+// StepCorporateActions fetches splits, mergers and symbol changes through the corporate
+// actions provider chain (config.ProviderChains). persist saves them to their own table and
+// renames the stock when a new symbol change is already effective; they are served by
+// GET /stocks/{ticker}/corporate-actions. Not in the default pipeline.
+const StepCorporateActions = "corporate_actions"
+
+// corporateActionsLookback is how far back actions are fetched. Those already saved are
+// ignored, so the overlap between runs costs nothing but the request.
+const corporateActionsLookback = 90 * 24 * time.Hour
+
+func init() {
+	RegisterStep(StepCorporateActions, corporateActionsStep)
+}
+
+func corporateActionsStep(ctx *StepContext, stock *models.Stock) error {
+	now := ctx.Now()
+	actions, source, failures, err := providers.FetchCorporateActions(stock.Ticker, now.Add(-corporateActionsLookback), now)
+	for _, f := range failures {
+		ctx.ProviderError(f.Provider+" corporate actions", f.Err)
+	}
+	if err != nil {
+		log.Printf("Error getting corporate actions for %s: %v. Skipping them.", stock.Ticker, err)
+		return nil
+	}
+
+	stock.CorporateActions = make([]models.CorporateAction, 0, len(actions))
+	for _, a := range actions {
+		action := models.CorporateAction{Ticker: stock.Ticker, Type: a.Type, EffectiveDate: a.Date,
+			NewTicker: a.NewTicker, Source: source, RecordedAt: now}
+		if a.Type == models.CorporateActionSplit {
+			action.SplitFrom, action.SplitTo = models.NewNullFloat64(a.SplitFrom), models.NewNullFloat64(a.SplitTo)
+		}
+		stock.CorporateActions = append(stock.CorporateActions, action)
+	}
+	log.Printf("Corporate actions for %s from %s: %d", stock.Ticker, source, len(actions))
+	return nil
+}
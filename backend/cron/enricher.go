@@ -1,145 +1,792 @@
 package enricher
 
 import (
-	"database/sql"
+	"context"
+	"errors"
+	"fmt"
 	"log"
+	"slices"
+	"sort"
+	"sync"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/jannin2/stock-app/backend/api"
+	"github.com/jannin2/stock-app/backend/clock"
+	"github.com/jannin2/stock-app/backend/config"
 	"github.com/jannin2/stock-app/backend/database"
 	"github.com/jannin2/stock-app/backend/models"
+	"github.com/jannin2/stock-app/backend/providers"
+	"github.com/jannin2/stock-app/backend/quotes"
+	"github.com/jannin2/stock-app/backend/scoring"
+	"github.com/jannin2/stock-app/backend/stream"
 )
 
+// checkpointBatchSize is how many tickers are enriched and saved between cursor checkpoints.
+const checkpointBatchSize = 10
+
 // Enricher handles fetching and updating stock data periodically.
 type Enricher struct {
 	dbClient database.StockDB // This is where your database interface is held
+	clock    clock.Clock      // Source of time for scheduling and updated_at stamping
+	quotes   *quotes.Cache    // Refreshed after each saved batch when set
+	stream   *stream.Hub      // Gets the new quote of each saved stock when set
+	afterRun func()           // Called after each successful run when set
+	steps    []string         // Stock steps to run; nil means config.EnrichmentSteps
+	workers  int              // Stocks enriched at once; 0 means config.EnrichmentWorkers
+
+	mu              sync.Mutex
+	interval        time.Duration   // Time between scheduled runs
+	schedule        *clock.Schedule // Cron schedule of the runs; nil runs them every interval
+	intervalChanged chan struct{}   // Signals StartFetching to reschedule after SetInterval or SetSchedule
+	runRequested    chan struct{}   // Signals StartFetching to run now, see Trigger
+	lastSuccess     time.Time       // When the last run finished without errors
+
+	started  bool               // Whether StartFetching has been called (guarded by mu)
+	runCtx   context.Context    // Context of the runs started by StartFetching
+	abortRun context.CancelFunc // Cancels runCtx when Stop gives up waiting
+	stop     chan struct{}      // Closed by Stop to end StartFetching
+	stopOnce sync.Once
+	done     chan struct{} // Closed when StartFetching returns
+}
+
+// EnricherOption customizes an Enricher created with NewEnricher.
+type EnricherOption func(*Enricher)
+
+// WithClock replaces the real clock, typically with a clock.Mock in tests.
+func WithClock(c clock.Clock) EnricherOption {
+	return func(e *Enricher) {
+		e.clock = c
+	}
+}
+
+// WithInterval sets the time between scheduled runs (24h by default).
+func WithInterval(d time.Duration) EnricherOption {
+	return func(e *Enricher) {
+		e.interval = d
+	}
+}
+
+// WithSchedule runs the enricher on a cron schedule, e.g. after the market close on
+// weekdays, instead of every interval. A nil schedule keeps the interval.
+func WithSchedule(s *clock.Schedule) EnricherOption {
+	return func(e *Enricher) {
+		e.schedule = s
+	}
+}
+
+// WithQuoteCache makes the enricher refresh c with the prices of every saved batch.
+func WithQuoteCache(c *quotes.Cache) EnricherOption {
+	return func(e *Enricher) {
+		e.quotes = c
+	}
+}
+
+// WithStream makes the enricher publish the new quote of every saved stock to hub, so
+// clients subscribed to its ticker get it as soon as it is saved.
+func WithStream(hub *stream.Hub) EnricherOption {
+	return func(e *Enricher) {
+		e.stream = hub
+	}
+}
+
+// WithAfterRun registers fn to be called after each successful run, e.g. to warm caches
+// with the new data. fn runs on the enricher's goroutine, so slow work should be started
+// in a goroutine of its own.
+func WithAfterRun(fn func()) EnricherOption {
+	return func(e *Enricher) {
+		e.afterRun = fn
+	}
+}
+
+// WithSteps sets the stock steps of the pipeline instead of config.EnrichmentSteps.
+func WithSteps(names ...string) EnricherOption {
+	return func(e *Enricher) {
+		e.steps = names
+	}
+}
+
+// WithWorkers sets how many stocks are enriched at once instead of config.EnrichmentWorkers.
+func WithWorkers(n int) EnricherOption {
+	return func(e *Enricher) {
+		e.workers = n
+	}
 }
 
 // NewEnricher creates a new Enricher instance.
 // It receives the StockDB interface as a dependency.
-func NewEnricher(dbClient database.StockDB) *Enricher {
-	return &Enricher{
+func NewEnricher(dbClient database.StockDB, opts ...EnricherOption) *Enricher {
+	e := &Enricher{
 		dbClient: dbClient,
+		clock:    clock.New(),
+		interval: 24 * time.Hour,
+
+		intervalChanged: make(chan struct{}, 1),
+		runRequested:    make(chan struct{}, 1),
+		stop:            make(chan struct{}),
+		done:            make(chan struct{}),
 	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	e.runCtx, e.abortRun = context.WithCancel(context.Background())
+	return e
 }
 
 // StartFetching initiates the cron job to fetch and update stock data.
 // This is the entry point for the periodic task.
+// It returns after Stop is called.
 func (e *Enricher) StartFetching() {
+	e.mu.Lock()
+	select {
+	case <-e.stop:
+		e.mu.Unlock()
+		return
+	default:
+	}
+	e.started = true
+	e.mu.Unlock()
+	defer close(e.done)
+
 	// Execute immediately once at startup
 	log.Println("🔄 Starting initial stock data enrichment...")
-	e.fetchAndEnrichStocks() // Calls the method that contains all the logic
+	e.RunOnce(e.runCtx) // Calls the method that contains all the logic
+
+	// Then, execute on each ticker tick (e.g., every 24 hours, or at each match of the schedule)
+	ticker := e.clock.NewTicker(e.nextRunIn())
+	defer func() { ticker.Stop() }()
+	reschedule := func() {
+		ticker.Stop()
+		ticker = e.clock.NewTicker(e.nextRunIn())
+	}
+
+	for {
+		select {
+		case <-ticker.C:
+			log.Println("⏰ Executing scheduled stock data enrichment...")
+			if e.Schedule() != nil {
+				// The time between matches varies (e.g. over weekends), so each tick sets the next one.
+				reschedule()
+			}
+			e.RunOnce(e.runCtx) // Calls the method that contains all the logic
+		case <-e.stop:
+			return
+		case <-e.intervalChanged:
+			// Restart the schedule so the new interval applies from now on.
+			reschedule()
+			log.Printf("Enrichment schedule changed, next run in %s", e.nextRunIn())
+		case <-e.runRequested:
+			log.Println("📬 Executing requested stock data enrichment...")
+			e.RunOnce(e.runCtx)
+			// The run just happened, so the next scheduled one is a full interval away.
+			reschedule()
+		}
+	}
+}
+
+// Stop ends StartFetching and waits for an in-progress run to finish, or for ctx to
+// expire, in which case the run is aborted: its pending database queries are canceled and
+// the next run resumes from the last checkpoint. It is safe to call more than once.
+func (e *Enricher) Stop(ctx context.Context) error {
+	e.stopOnce.Do(func() { close(e.stop) })
+
+	e.mu.Lock()
+	started := e.started
+	e.mu.Unlock()
+	if !started {
+		return nil
+	}
+
+	select {
+	case <-e.done:
+		return nil
+	case <-ctx.Done():
+		e.abortRun()
+		return ctx.Err()
+	}
+}
+
+// Interval returns the time between scheduled runs.
+func (e *Enricher) Interval() time.Duration {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.interval
+}
+
+// SetInterval changes the time between scheduled runs without restarting StartFetching.
+// The next run is rescheduled to d from the moment of the change.
+func (e *Enricher) SetInterval(d time.Duration) {
+	e.mu.Lock()
+	changed := d != e.interval
+	e.interval = d
+	e.mu.Unlock()
+
+	if changed {
+		select {
+		case e.intervalChanged <- struct{}{}:
+		default: // A reschedule is already pending and will pick up the latest interval.
+		}
+	}
+}
 
-	// Then, execute on each ticker tick (e.g., every 24 hours)
-	ticker := time.NewTicker(24 * time.Hour)
-	defer ticker.Stop()
+// Schedule returns the cron schedule of the runs, or nil when they run every interval.
+func (e *Enricher) Schedule() *clock.Schedule {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.schedule
+}
 
-	for range ticker.C {
-		log.Println("⏰ Executing scheduled stock data enrichment...")
-		e.fetchAndEnrichStocks() // Calls the method that contains all the logic
+// SetSchedule changes the cron schedule of the runs without restarting StartFetching; nil
+// goes back to running every interval.
+func (e *Enricher) SetSchedule(s *clock.Schedule) {
+	e.mu.Lock()
+	changed := s.String() != e.schedule.String()
+	e.schedule = s
+	e.mu.Unlock()
+
+	if changed {
+		select {
+		case e.intervalChanged <- struct{}{}:
+		default: // A reschedule is already pending and will pick up the latest schedule.
+		}
 	}
 }
 
-// fetchAndEnrichStocks contains the logic to fetch data from external APIs and update it in the DB.
-// This method is now part of the Enricher, allowing it to access e.dbClient.
-func (e *Enricher) fetchAndEnrichStocks() {
+// nextRunIn returns the time until the next scheduled run: the interval, or the time until
+// the next match of the schedule when there is one.
+func (e *Enricher) nextRunIn() time.Duration {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.schedule == nil {
+		return e.interval
+	}
+	now := e.clock.Now()
+	return e.schedule.Next(now).Sub(now)
+}
+
+// Trigger asks StartFetching to run as soon as possible, e.g. when a provider pushes a
+// notice that new data is available. It never blocks: requests made while a run is
+// pending or in progress collapse into a single extra run.
+func (e *Enricher) Trigger() {
+	select {
+	case e.runRequested <- struct{}{}:
+	default:
+	}
+}
+
+// RunOnce executes a single enrichment run synchronously and returns its error, if any.
+// Errors are also logged, so callers that only need the side effect can ignore the result.
+// Canceling ctx stops the run after the batch in progress and cancels its database queries.
+func (e *Enricher) RunOnce(ctx context.Context) error {
+	err := e.fetchAndEnrichStocks(ctx)
+	// Persist the provider call stats of this run (and of any quote served since the last one).
+	if flushErr := providers.FlushStats(ctx, e.dbClient); flushErr != nil {
+		log.Printf("Could not save provider stats: %v", flushErr)
+	}
+	if err != nil {
+		log.Printf("Stock data enrichment failed: %v", err)
+		return err
+	}
+
+	e.mu.Lock()
+	e.lastSuccess = e.clock.Now()
+	e.mu.Unlock()
+
+	log.Println("Stock data enriched and saved to the database successfully.")
+	// The heavy aggregates are served from materialized views: recompute them before the
+	// after-run hook warms the response cache.
+	if err := e.dbClient.RefreshAggregateViews(ctx); err != nil {
+		log.Printf("Could not refresh the aggregate views: %v", err)
+	}
+	if e.afterRun != nil {
+		e.afterRun()
+	}
+	return nil
+}
+
+// LastSuccess returns when the last enrichment run completed successfully (zero if none has).
+func (e *Enricher) LastSuccess() time.Time {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.lastSuccess
+}
+
+// IsStale reports whether the data is older than maxAge, i.e. no run has succeeded within maxAge.
+func (e *Enricher) IsStale(maxAge time.Duration) bool {
+	last := e.LastSuccess()
+	return last.IsZero() || e.clock.Now().Sub(last) > maxAge
+}
+
+// fetchAndEnrichStocks runs the enrichment pipeline (see pipeline.go): it fetches the
+// feed, normalizes it, enriches the due stocks with the configured steps and persists
+// them in batches. The run is recorded in the run history (see startRun and finishRun).
+func (e *Enricher) fetchAndEnrichStocks(ctx context.Context) (err error) {
 	log.Println("Starting stock data enrichment...")
+	cursor := e.startOrResumeRun(ctx)
+	run := e.startRun(ctx, cursor)
+	failures := newRunFailures()
+	defer func() { e.finishRun(ctx, &run, failures, err) }()
 
-	stocksFromKarenai, err := api.GetRecommendationsFromKarenai()
+	pipeline, err := resolveSteps(e.stepNames())
 	if err != nil {
-		log.Printf("Error getting recommendations from Karenai.click: %v", err)
-		return
+		return err
 	}
-	log.Printf("Received %d recommendations from Karenai.click", len(stocksFromKarenai))
-
-	for i := range stocksFromKarenai {
-		ticker := stocksFromKarenai[i].Ticker
-		log.Printf("Enriching data for ticker: %s", ticker)
-
-		// --- Get Current Price and Finnhub Metrics ---
-		finnhubMetrics, err := api.GetFinnhubMetricsAndQuote(ticker)
-		if err != nil {
-			log.Printf("Error getting metrics/price from Finnhub for %s: %v. Assigning null/default values.", ticker, err)
-			stocksFromKarenai[i].PERatio = models.NullFloat64{sql.NullFloat64{Valid: false}}
-			stocksFromKarenai[i].DividendYield = models.NullFloat64{sql.NullFloat64{Valid: false}}
-			stocksFromKarenai[i].MarketCapitalization = models.NullFloat64{sql.NullFloat64{Valid: false}}
-			stocksFromKarenai[i].CurrentPrice = 0.0
-			stocksFromKarenai[i].LatestTradingDay = models.NullTime{NullTime: sql.NullTime{Valid: false}}
-		} else {
-			stocksFromKarenai[i].PERatio = models.NullFloat64{sql.NullFloat64{Float64: finnhubMetrics.PE_Ratio, Valid: true}}
-			stocksFromKarenai[i].DividendYield = models.NullFloat64{sql.NullFloat64{Float64: finnhubMetrics.DividendYield, Valid: true}}
-			stocksFromKarenai[i].MarketCapitalization = models.NullFloat64{sql.NullFloat64{Float64: finnhubMetrics.MarketCapitalization, Valid: true}}
-			stocksFromKarenai[i].CurrentPrice = finnhubMetrics.CurrentPrice
-
-			if !finnhubMetrics.LatestTradingDay.IsZero() {
-				stocksFromKarenai[i].LatestTradingDay = models.NullTime{NullTime: sql.NullTime{Time: finnhubMetrics.LatestTradingDay, Valid: true}}
-			} else {
-				stocksFromKarenai[i].LatestTradingDay = models.NullTime{NullTime: sql.NullTime{Valid: false}}
-			}
+	e.loadScoringRules(ctx)
 
-			log.Printf("Finnhub data for %s: Price: %.2f, PE: %.2f, Div Yield: %.4f, Market Cap: %.2f, Trading Day (Finnhub): %v",
-				ticker, stocksFromKarenai[i].CurrentPrice, finnhubMetrics.PE_Ratio, finnhubMetrics.DividendYield, finnhubMetrics.MarketCapitalization, stocksFromKarenai[i].LatestTradingDay.Time.Format("2006-01-02"))
+	stocks, err := e.fetchFeed(failures)
+	if err != nil {
+		return err
+	}
+	e.syncIPOs(ctx)
+	e.syncMacroEvents(ctx)
+	listings := e.newListings(ctx, stocks)
+	stocks = append(stocks, listings...)
+	pending := e.normalize(ctx, stocks, cursor)
+	benchmarks := map[string]bool{}                 // Sector ETFs whose price was recorded in this run
+	var rateLimited []models.Stock                  // Saved with null metrics, to enrich again after the limit window
+	var prefetched map[string]providers.QuoteResult // Quotes of the upcoming tickers, see prefetchQuotes
+
+	// Enrich and persist in batches, checkpointing the cursor after each batch so a
+	// restart mid-run does not re-spend provider quota on tickers already saved.
+	for len(pending) > 0 {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("enrichment run %s interrupted: %w", cursor.RunID, err)
 		}
+		batch := pending[:min(checkpointBatchSize, len(pending))]
+		prefetched = e.prefetchQuotes(pipeline, prefetched, batch, pending)
+		pending = pending[len(batch):]
 
-		// --- Alpha Vantage Alpha ---
-		alphaVantageData, err := api.GetAlphaAndLatestTradingDayFromAlphaVantage(ticker)
-		if err != nil {
-			log.Printf("Error getting Alpha from Alpha Vantage for %s: %v. Assigning null value.", ticker, err)
-			stocksFromKarenai[i].Alpha = models.NullFloat64{sql.NullFloat64{Valid: false}}
-		} else {
-			stocksFromKarenai[i].Alpha = models.NullFloat64{sql.NullFloat64{Float64: alphaVantageData.Alpha, Valid: true}}
-			log.Printf("Alpha Vantage data for %s: Alpha: %.4f", ticker, alphaVantageData.Alpha)
+		limited := e.enrichAll(pipeline, batch, failures, prefetched)
+		e.computeRelativeStrength(ctx, batch, benchmarks)
+		if err := e.persist(ctx, batch, &cursor); err != nil {
+			return err
+		}
+		run.StocksProcessed += len(batch)
+		for _, i := range limited {
+			rateLimited = append(rateLimited, batch[i])
 		}
+	}
 
-		// --- Calculate Recommendation Score ---
-		scoreVal := CalculateRecommendationScore(stocksFromKarenai[i])
+	if err := e.retryRateLimited(ctx, pipeline, rateLimited, cursor.RunID, benchmarks, failures); err != nil {
+		return err
+	}
 
-		stocksFromKarenai[i].RecommendationScore = models.NullFloat64{sql.NullFloat64{Float64: scoreVal, Valid: true}}
-		log.Printf("Recommendation score calculated for %s: %.2f", ticker, scoreVal)
+	cursor.Completed = true
+	e.saveCursor(ctx, cursor)
+	if len(listings) > 0 {
+		e.markListed(ctx, listings)
+	}
+	return nil
+}
 
-		stocksFromKarenai[i].UpdatedAt = time.Now()
+// stepNames returns the stock steps to run: those set with WithSteps, or else the ones
+// in the current configuration.
+func (e *Enricher) stepNames() []string {
+	if e.steps != nil {
+		return e.steps
+	}
+	return config.Current().EnrichmentSteps
+}
 
-		log.Printf("Processed and Enriched %s: Price: %.2f, PE: %.2f (Valid: %t), Div Yield: %.4f (Valid: %t), Market Cap: %.2f (Valid: %t), Alpha: %.4f (Valid: %t), Rec Score: %.2f (Valid: %t), Trading Day: %v (Valid: %t)",
-			ticker, stocksFromKarenai[i].CurrentPrice,
-			stocksFromKarenai[i].PERatio.Float64, stocksFromKarenai[i].PERatio.Valid,
-			stocksFromKarenai[i].DividendYield.Float64, stocksFromKarenai[i].DividendYield.Valid,
-			stocksFromKarenai[i].MarketCapitalization.Float64, stocksFromKarenai[i].MarketCapitalization.Valid,
-			stocksFromKarenai[i].Alpha.Float64, stocksFromKarenai[i].Alpha.Valid,
-			stocksFromKarenai[i].RecommendationScore.Float64, stocksFromKarenai[i].RecommendationScore.Valid,
-			func() string {
-				if stocksFromKarenai[i].LatestTradingDay.Valid {
-					return stocksFromKarenai[i].LatestTradingDay.Time.Format("2006-01-02")
-				}
-				return "0001-01-01"
-			}(), stocksFromKarenai[i].LatestTradingDay.Valid)
+// fetchFeed is the feed step: it fetches the Karenai.click recommendations and records
+// where their fields came from. A failure is also recorded in failures.
+func (e *Enricher) fetchFeed(failures *runFailures) ([]models.Stock, error) {
+	karenaiStart := e.clock.Now()
+	stocks, err := api.GetRecommendationsFromKarenai()
+	providers.RecordCall(providers.Karenai, e.clock.Now().Sub(karenaiStart), err)
+	if err != nil {
+		failures.record(providers.Karenai+" feed", api.ClassifyError(err), err)
+		return nil, fmt.Errorf("error getting recommendations from Karenai.click: %w", err)
+	}
+	log.Printf("Received %d recommendations from Karenai.click", len(stocks))
+
+	fetchedAt := e.clock.Now()
+	for i := range stocks {
+		stocks[i].Provenance = models.Provenance{}
+		stocks[i].Provenance.Set(providers.Karenai, fetchedAt, karenaiFields...)
+	}
+	return stocks, nil
+}
+
+// normalize is the normalize step: it orders the stocks by ticker, keeps those that are
+// due and skips the ones an interrupted run (cursor) already saved.
+func (e *Enricher) normalize(ctx context.Context, stocks []models.Stock, cursor models.EnrichmentCursor) []models.Stock {
+	// Process tickers in a stable order so the persisted cursor is meaningful across restarts.
+	sort.SliceStable(stocks, func(i, j int) bool {
+		return stocks[i].Ticker < stocks[j].Ticker
+	})
+
+	pending := e.dueStocks(ctx, stocks)
+	if cursor.LastTicker != "" {
+		skip := sort.Search(len(pending), func(i int) bool { return pending[i].Ticker > cursor.LastTicker })
+		log.Printf("Resuming enrichment run %s after ticker %s (%d tickers already enriched)", cursor.RunID, cursor.LastTicker, skip)
+		pending = pending[skip:]
+	}
+	return pending
+}
+
+// persist is the persist step: it saves an enriched batch (see save) and checkpoints the
+// cursor after it.
+func (e *Enricher) persist(ctx context.Context, batch []models.Stock, cursor *models.EnrichmentCursor) error {
+	if err := e.save(ctx, batch, cursor.RunID); err != nil {
+		return err
 	}
+	cursor.LastTicker = batch[len(batch)-1].Ticker
+	e.saveCursor(ctx, *cursor)
+	return nil
+}
 
+// save saves enriched stocks, their per-ticker results in run runID, prices, mentions,
+// options summaries, corporate actions and quotes. Every write is idempotent, so stocks
+// enriched again in the same run (see retryRateLimited) can be saved again.
+func (e *Enricher) save(ctx context.Context, stocks []models.Stock, runID uuid.UUID) error {
 	// ✅ THE KEY CORRECTION: Call UpsertStocks via the dbClient instance
-	err = e.dbClient.UpsertStocks(stocksFromKarenai)
+	if err := e.dbClient.UpsertStocks(ctx, stocks); err != nil {
+		return fmt.Errorf("error saving/updating stocks in the database: %w", err)
+	}
+	e.saveResults(ctx, runID, stocks)
+	e.recordPrices(ctx, stocks)
+	e.recordMentions(ctx, stocks)
+	e.saveOptions(ctx, stocks)
+	e.saveCorporateActions(ctx, stocks)
+	if e.quotes != nil {
+		e.quotes.PutStocks(stocks)
+	}
+	if e.stream != nil {
+		e.publishQuotes(stocks)
+	}
+	return nil
+}
+
+// publishQuotes publishes the quote of each stock with a price to the stream hub.
+func (e *Enricher) publishQuotes(stocks []models.Stock) {
+	for _, s := range stocks {
+		if q, ok := quotes.FromStock(s); ok {
+			e.stream.Publish(stream.Update{Type: stream.UpdateQuote, Ticker: s.Ticker, Data: q})
+		}
+	}
+}
+
+// retryRateLimited enriches again the stocks that a provider rejected for its rate limit,
+// waiting config.RateLimitRetryWindow before each attempt so the limit window has passed,
+// up to config.RateLimitRetries times. The stocks were already saved with the metrics of
+// that provider null; a retry that is rate limited again leaves them for the next attempt.
+func (e *Enricher) retryRateLimited(ctx context.Context, pipeline []namedStep, stocks []models.Stock, runID uuid.UUID, benchmarks map[string]bool, failures *runFailures) error {
+	cfg := config.Current()
+	for attempt := 1; len(stocks) > 0 && attempt <= cfg.RateLimitRetries; attempt++ {
+		log.Printf("%d tickers were rate limited; retrying them in %s (attempt %d of %d)", len(stocks), cfg.RateLimitRetryWindow, attempt, cfg.RateLimitRetries)
+		select {
+		case <-e.clock.After(cfg.RateLimitRetryWindow):
+		case <-ctx.Done():
+			return fmt.Errorf("enrichment run %s interrupted: %w", runID, ctx.Err())
+		}
+
+		var limited []models.Stock
+		for _, i := range e.enrichAll(pipeline, stocks, failures, nil) {
+			limited = append(limited, stocks[i])
+		}
+		e.computeRelativeStrength(ctx, stocks, benchmarks)
+		if err := e.save(ctx, stocks, runID); err != nil {
+			return err
+		}
+		stocks = limited
+	}
+	if len(stocks) > 0 {
+		log.Printf("%d tickers are still rate limited; their metrics stay null until the next run", len(stocks))
+	}
+	return nil
+}
+
+// enrichAll runs the pipeline on each stock, enriching several at once (see WithWorkers).
+// The workers share the per-minute limit of each provider (see api.limitTransport), so a
+// worker waiting for its Alpha Vantage turn does not hold back the Finnhub calls of the
+// others. It returns the positions in stocks of those a provider rate limited, in order.
+func (e *Enricher) enrichAll(pipeline []namedStep, stocks []models.Stock, failures *runFailures, prefetched map[string]providers.QuoteResult) []int {
+	workers := e.workers
+	if workers <= 0 {
+		workers = config.Current().EnrichmentWorkers
+	}
+	rateLimited := make([]bool, len(stocks))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range min(max(workers, 1), len(stocks)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				rateLimited[i] = e.runSteps(pipeline, &stocks[i], failures, prefetched)
+			}
+		}()
+	}
+	for i := range stocks {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	var limited []int
+	for i, ok := range rateLimited {
+		if ok {
+			limited = append(limited, i)
+		}
+	}
+	return limited
+}
+
+// prefetchQuotes returns the quotes for batch, the head of upcoming. When a provider of
+// the quote chain supports batch quotes and prefetched lacks a ticker of batch, the quotes of
+// the next providers.QuoteBatchSize tickers of upcoming are fetched in one go, so several
+// checkpoint batches share one provider call. Otherwise it returns nil and the quotes step
+// fetches each quote on its own.
+func (e *Enricher) prefetchQuotes(pipeline []namedStep, prefetched map[string]providers.QuoteResult, batch, upcoming []models.Stock) map[string]providers.QuoteResult {
+	if !slices.ContainsFunc(pipeline, func(s namedStep) bool { return s.name == StepQuotes }) || !providers.HasBatchQuotes() {
+		return nil
+	}
+	missing := slices.ContainsFunc(batch, func(s models.Stock) bool {
+		_, ok := prefetched[s.Ticker]
+		return !ok
+	})
+	if !missing {
+		return prefetched
+	}
+
+	window := upcoming[:min(providers.QuoteBatchSize, len(upcoming))]
+	tickers := make([]string, 0, len(window))
+	for _, s := range window {
+		tickers = append(tickers, s.Ticker)
+	}
+	log.Printf("Fetching quotes for the next %d tickers", len(tickers))
+	return providers.FetchQuotes(tickers)
+}
+
+// saveResults records the outcome of each ticker of the batch in run runID, for
+// GET /admin/enrich/runs/{id}/details. Failures are logged but do not fail the run.
+func (e *Enricher) saveResults(ctx context.Context, runID uuid.UUID, stocks []models.Stock) {
+	results := make([]models.EnrichmentResult, 0, len(stocks))
+	for _, s := range stocks {
+		results = append(results, models.NewEnrichmentResult(runID, s))
+	}
+	if err := e.dbClient.SaveEnrichmentResults(ctx, results); err != nil {
+		log.Printf("Warning: could not save enrichment results for run %s: %v", runID, err)
+	}
+}
+
+// recordPrices appends the batch's current prices to the price history used by analytics.
+// Failures are logged but do not fail the run: the stocks themselves are already saved.
+func (e *Enricher) recordPrices(ctx context.Context, stocks []models.Stock) {
+	points := make([]models.PricePoint, 0, len(stocks))
+	for _, s := range stocks {
+		if p, ok := models.PricePointFromStock(s, e.clock.Now()); ok {
+			points = append(points, p)
+		}
+	}
+	if err := e.dbClient.RecordPrices(ctx, points); err != nil {
+		log.Printf("Warning: could not record price history: %v", err)
+	}
+}
+
+// recordMentions appends the batch's buzz to the daily mention history. Like recordPrices,
+// failures are only logged.
+func (e *Enricher) recordMentions(ctx context.Context, stocks []models.Stock) {
+	var counts []models.MentionCount
+	for _, s := range stocks {
+		if m, ok := models.MentionCountFromStock(s); ok {
+			counts = append(counts, m)
+		}
+	}
+	if err := e.dbClient.RecordMentions(ctx, counts); err != nil {
+		log.Printf("Warning: could not record mention history: %v", err)
+	}
+}
+
+// saveOptions saves the options summaries of the batch. Like recordPrices, failures are
+// only logged.
+func (e *Enricher) saveOptions(ctx context.Context, stocks []models.Stock) {
+	var summaries []models.OptionsSummary
+	for _, s := range stocks {
+		if s.Options != nil {
+			summaries = append(summaries, *s.Options)
+		}
+	}
+	if err := e.dbClient.SaveOptionsSummaries(ctx, summaries); err != nil {
+		log.Printf("Warning: could not save options summaries: %v", err)
+	}
+}
+
+// saveCorporateActions saves the corporate actions of the batch and, for the new ones that
+// move a stock to another ticker and are already effective, renames it. Like recordPrices,
+// failures are only logged; a rename that fails (e.g. the new ticker is already tracked)
+// is left for an admin.
+func (e *Enricher) saveCorporateActions(ctx context.Context, stocks []models.Stock) {
+	var actions []models.CorporateAction
+	for _, s := range stocks {
+		actions = append(actions, s.CorporateActions...)
+	}
+	inserted, err := e.dbClient.SaveCorporateActions(ctx, actions)
 	if err != nil {
-		log.Printf("Error saving/updating stocks in the database: %v", err)
+		log.Printf("Warning: could not save corporate actions: %v", err)
 		return
 	}
-	log.Println("Stock data enriched and saved to the database successfully.")
+	now := e.clock.Now()
+	for _, a := range inserted {
+		if !a.Renames() || a.EffectiveDate.After(now) {
+			continue
+		}
+		if err := e.dbClient.RenameTicker(ctx, a.Ticker, a.NewTicker); err != nil {
+			log.Printf("Warning: could not rename %s to %s after its %s: %v", a.Ticker, a.NewTicker, a.Type, err)
+			continue
+		}
+		log.Printf("Renamed %s to %s after its %s effective %s", a.Ticker, a.NewTicker, a.Type, a.EffectiveDate.Format("2006-01-02"))
+	}
 }
 
-// CalculateRecommendationScore remains an auxiliary function that does not require the DB instance.
-func CalculateRecommendationScore(stock models.Stock) float64 {
-	scoreVal := 0.0
+// startOrResumeRun returns the cursor of an interrupted run that is still within the
+// scheduling interval, or starts (and persists) a new run otherwise. Cursor storage
+// failures are logged but never block enrichment.
+func (e *Enricher) startOrResumeRun(ctx context.Context) models.EnrichmentCursor {
+	now := e.clock.Now()
+	cursor, err := e.dbClient.GetEnrichmentCursor(ctx)
+	if err != nil {
+		log.Printf("Warning: could not load enrichment cursor, starting a new run: %v", err)
+	} else if cursor.RunID != uuid.Nil && !cursor.Completed && now.Sub(cursor.StartedAt) < e.Interval() {
+		return cursor
+	}
 
-	// Condition 1: Based on the action
-	if stock.Action == "Buy" || stock.Action == "Strong Buy" {
-		scoreVal += 5.0
+	if err == nil && cursor.RunID != uuid.Nil && !cursor.Completed {
+		e.abandonRun(ctx, cursor.RunID)
 	}
 
-	// Condition 2: Based on target price vs. current price
-	// Ensure CurrentPrice is positive to avoid division by zero or nonsensical logic
-	if stock.CurrentPrice > 0 && stock.TargetTo.Valid && stock.TargetTo.Float64 > stock.CurrentPrice*1.1 {
-		scoreVal += 3.0
+	cursor = models.EnrichmentCursor{RunID: uuid.New(), StartedAt: now}
+	log.Printf("Starting enrichment run %s", cursor.RunID)
+	e.saveCursor(ctx, cursor)
+	return cursor
+}
+
+// startRun records the run of cursor as running in the run history and returns its
+// record. A resumed run keeps the counts of its earlier attempts.
+func (e *Enricher) startRun(ctx context.Context, cursor models.EnrichmentCursor) models.EnrichmentRun {
+	run, err := e.dbClient.GetEnrichmentRun(ctx, cursor.RunID)
+	if err != nil {
+		if !errors.Is(err, database.ErrEnrichmentRunNotFound) {
+			log.Printf("Warning: could not load enrichment run %s, starting its record over: %v", cursor.RunID, err)
+		}
+		run = models.EnrichmentRun{ID: cursor.RunID, StartedAt: cursor.StartedAt, ProviderErrors: map[string]int{}}
 	}
+	run.Status = models.EnrichmentRunRunning
+	run.FinishedAt = nil
+	run.Error = ""
+	e.saveRun(ctx, run)
+	return run
+}
 
-	// Alpha contribution removed as per discussion.
-	// If you ever integrate a real Alpha, re-add it here.
+// finishRun records the outcome of the run in the run history: its status, the error
+// that failed it and its provider failures, which are also logged.
+func (e *Enricher) finishRun(ctx context.Context, run *models.EnrichmentRun, failures *runFailures, runErr error) {
+	if summary := failures.summary(); summary != "" {
+		log.Printf("Provider failures in run %s: %s", run.ID, summary)
+	}
+	for provider, n := range failures.byProvider() {
+		run.ProviderErrors[provider] += n
+	}
+	finishedAt := e.clock.Now()
+	run.FinishedAt = &finishedAt
+	run.Status = models.EnrichmentRunSucceeded
+	if runErr != nil {
+		run.Status = models.EnrichmentRunFailed
+		run.Error = runErr.Error()
+	}
+	// The record is written even when ctx was canceled, which is how most runs are interrupted.
+	e.saveRun(context.WithoutCancel(ctx), *run)
+}
+
+// abandonRun marks an interrupted run that is not going to be resumed.
+func (e *Enricher) abandonRun(ctx context.Context, id uuid.UUID) {
+	run, err := e.dbClient.GetEnrichmentRun(ctx, id)
+	if err != nil || run.Status != models.EnrichmentRunRunning && run.Status != models.EnrichmentRunFailed {
+		return
+	}
+	log.Printf("Enrichment run %s was interrupted and will not be resumed", id)
+	run.Status = models.EnrichmentRunAbandoned
+	e.saveRun(ctx, run)
+}
+
+// saveRun persists a run record, logging (not returning) storage failures.
+func (e *Enricher) saveRun(ctx context.Context, run models.EnrichmentRun) {
+	if err := e.dbClient.SaveEnrichmentRun(ctx, run); err != nil {
+		log.Printf("Warning: could not save enrichment run %s: %v", run.ID, err)
+	}
+}
 
-	return scoreVal
+// saveCursor stamps and persists the cursor, logging (not returning) storage failures.
+func (e *Enricher) saveCursor(ctx context.Context, cursor models.EnrichmentCursor) {
+	cursor.UpdatedAt = e.clock.Now()
+	if err := e.dbClient.SaveEnrichmentCursor(ctx, cursor); err != nil {
+		log.Printf("Warning: could not checkpoint enrichment cursor for run %s at %q: %v", cursor.RunID, cursor.LastTicker, err)
+	}
+}
+
+// dueStocks keeps the stocks whose enrichment tier calls for a refresh: those whose tier
+// interval has elapsed since they were last enriched, with half a scheduling interval of
+// slack so a stock saved late in one run is not pushed back to the run after the next.
+// New stocks are always due. If the schedule cannot be loaded every stock is enriched.
+func (e *Enricher) dueStocks(ctx context.Context, stocks []models.Stock) []models.Stock {
+	schedule, err := e.dbClient.GetEnrichmentSchedule(ctx)
+	if err != nil {
+		log.Printf("Warning: could not load the enrichment schedule, enriching every stock: %v", err)
+		return stocks
+	}
+
+	tiers := config.Current().EnrichmentTiers
+	now := e.clock.Now()
+	slack := e.Interval() / 2
+	due := make([]models.Stock, 0, len(stocks))
+	skipped := make(map[string]int)
+	for _, s := range stocks {
+		sched, known := schedule[s.Ticker]
+		if !known {
+			due = append(due, s)
+			continue
+		}
+		tier := sched.EffectiveTier()
+		interval, ok := tiers[tier]
+		if !ok || now.Sub(sched.LastEnrichedAt) >= interval-slack {
+			due = append(due, s)
+			continue
+		}
+		skipped[tier]++
+	}
+
+	if len(skipped) > 0 {
+		log.Printf("Enriching %d of %d stocks; not yet due by tier: %v", len(due), len(stocks), skipped)
+	}
+	return due
+}
+
+// karenaiFields are the stock fields that come from the Karenai.click recommendations.
+var karenaiFields = []string{"company", "brokerage", "action", "rating_from", "rating_to", "target_from", "target_to"}
+
+// CalculateRecommendationScore scores the stock with the active scoring rules (see
+// loadScoringRules), or the built-in ones if none are stored.
+func CalculateRecommendationScore(stock models.Stock) float64 {
+	return scoring.Current().Explain(stock).Score
+}
+
+// loadScoringRules activates the scoring rules stored in the database, so changes made
+// through another instance apply from the next run. On failure the current rules are kept.
+func (e *Enricher) loadScoringRules(ctx context.Context) {
+	stored, err := e.dbClient.GetScoringRules(ctx)
+	if err != nil {
+		log.Printf("Error loading scoring rules: %v. Keeping the current rules.", err)
+		return
+	}
+	if len(stored.Rules) == 0 {
+		scoring.SetRules(nil)
+		return
+	}
+	rules, err := scoring.CompileRules(stored.Rules)
+	if err != nil {
+		log.Printf("Stored scoring rules are invalid: %v. Keeping the current rules.", err)
+		return
+	}
+	scoring.SetRules(rules)
 }
@@ -1,9 +1,23 @@
 package enricher
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/api"
+	"github.com/jannin2/stock-app/backend/clock"
+	"github.com/jannin2/stock-app/backend/config"
+	"github.com/jannin2/stock-app/backend/database"
 	"github.com/jannin2/stock-app/backend/models"
 )
 
@@ -18,7 +32,7 @@ func TestCalculateRecommendationScore(t *testing.T) {
 			stock: models.Stock{
 				Action:       "Buy",
 				CurrentPrice: 100.0,
-				TargetTo:     models.NullFloat64{sql.NullFloat64{Float64: 120.0, Valid: true}}, // 120 is > 100 * 1.1 (110)
+				TargetTo:     models.NullFloat64{NullFloat64: sql.NullFloat64{Float64: 120.0, Valid: true}}, // 120 is > 100 * 1.1 (110)
 			},
 			expectedScore: 8.0, // 5 (Buy) + 3 (Target)
 		},
@@ -27,7 +41,7 @@ func TestCalculateRecommendationScore(t *testing.T) {
 			stock: models.Stock{
 				Action:       "Strong Buy",
 				CurrentPrice: 50.0,
-				TargetTo:     models.NullFloat64{sql.NullFloat64{Float64: 60.0, Valid: true}}, // 60 is > 50 * 1.1 (55)
+				TargetTo:     models.NullFloat64{NullFloat64: sql.NullFloat64{Float64: 60.0, Valid: true}}, // 60 is > 50 * 1.1 (55)
 			},
 			expectedScore: 8.0, // 5 (Strong Buy) + 3 (Target)
 		},
@@ -36,7 +50,7 @@ func TestCalculateRecommendationScore(t *testing.T) {
 			stock: models.Stock{
 				Action:       "Hold",
 				CurrentPrice: 100.0,
-				TargetTo:     models.NullFloat64{sql.NullFloat64{Float64: 120.0, Valid: true}},
+				TargetTo:     models.NullFloat64{NullFloat64: sql.NullFloat64{Float64: 120.0, Valid: true}},
 			},
 			expectedScore: 3.0, // 0 (Hold) + 3 (Target)
 		},
@@ -45,7 +59,7 @@ func TestCalculateRecommendationScore(t *testing.T) {
 			stock: models.Stock{
 				Action:       "Buy",
 				CurrentPrice: 100.0,
-				TargetTo:     models.NullFloat64{sql.NullFloat64{Float64: 105.0, Valid: true}}, // 105 is NOT > 100 * 1.1 (110)
+				TargetTo:     models.NullFloat64{NullFloat64: sql.NullFloat64{Float64: 105.0, Valid: true}}, // 105 is NOT > 100 * 1.1 (110)
 			},
 			expectedScore: 5.0, // 5 (Buy) + 0 (Target)
 		},
@@ -54,7 +68,7 @@ func TestCalculateRecommendationScore(t *testing.T) {
 			stock: models.Stock{
 				Action:       "Buy",
 				CurrentPrice: 100.0,
-				TargetTo:     models.NullFloat64{sql.NullFloat64{Valid: false}},
+				TargetTo:     models.NullFloat64{NullFloat64: sql.NullFloat64{Valid: false}},
 			},
 			expectedScore: 5.0, // 5 (Buy) + 0 (Target)
 		},
@@ -63,7 +77,7 @@ func TestCalculateRecommendationScore(t *testing.T) {
 			stock: models.Stock{
 				Action:       "Buy",
 				CurrentPrice: 100.0,
-				TargetTo:     models.NullFloat64{sql.NullFloat64{Float64: 0.0, Valid: true}},
+				TargetTo:     models.NullFloat64{NullFloat64: sql.NullFloat64{Float64: 0.0, Valid: true}},
 			},
 			expectedScore: 5.0, // 5 (Buy) + 0 (Target)
 		},
@@ -72,7 +86,7 @@ func TestCalculateRecommendationScore(t *testing.T) {
 			stock: models.Stock{
 				Action:       "Neutral",
 				CurrentPrice: 100.0,
-				TargetTo:     models.NullFloat64{sql.NullFloat64{Float64: 105.0, Valid: true}},
+				TargetTo:     models.NullFloat64{NullFloat64: sql.NullFloat64{Float64: 105.0, Valid: true}},
 			},
 			expectedScore: 0.0, // 0 (Neutral) + 0 (Target)
 		},
@@ -81,7 +95,7 @@ func TestCalculateRecommendationScore(t *testing.T) {
 			stock: models.Stock{
 				Action:       "Sell",
 				CurrentPrice: 100.0,
-				TargetTo:     models.NullFloat64{sql.NullFloat64{Float64: 120.0, Valid: true}},
+				TargetTo:     models.NullFloat64{NullFloat64: sql.NullFloat64{Float64: 120.0, Valid: true}},
 			},
 			expectedScore: 3.0, // 0 (Sell) + 3 (Target)
 		},
@@ -90,7 +104,7 @@ func TestCalculateRecommendationScore(t *testing.T) {
 			stock: models.Stock{
 				Action:       "target lowered by", // Example's action
 				CurrentPrice: 122.06,
-				TargetTo:     models.NullFloat64{sql.NullFloat64{Float64: 0.0, Valid: true}}, // Example's target to
+				TargetTo:     models.NullFloat64{NullFloat64: sql.NullFloat64{Float64: 0.0, Valid: true}}, // Example's target to
 			},
 			expectedScore: 0.0,
 		},
@@ -99,7 +113,7 @@ func TestCalculateRecommendationScore(t *testing.T) {
 			stock: models.Stock{
 				Action:       "Buy",
 				CurrentPrice: 0.0, // CurrentPrice is 0, so target condition is skipped
-				TargetTo:     models.NullFloat64{sql.NullFloat64{Float64: 10.0, Valid: true}},
+				TargetTo:     models.NullFloat64{NullFloat64: sql.NullFloat64{Float64: 10.0, Valid: true}},
 			},
 			expectedScore: 5.0, // Only Buy action contributes
 		},
@@ -114,3 +128,864 @@ func TestCalculateRecommendationScore(t *testing.T) {
 		})
 	}
 }
+
+// fakeStockDB records upserts, per-ticker results, price points, mentions, options summaries, corporate actions, renames and the
+// IPO and economic calendars and keeps the enrichment cursor and schedule in memory; any other StockDB method
+// panics if called.
+type fakeStockDB struct {
+	database.StockDB
+	upserts   chan []models.Stock
+	results   []models.EnrichmentResult
+	runs      map[uuid.UUID]models.EnrichmentRun
+	cursor    models.EnrichmentCursor
+	prices    []models.PricePoint
+	mentions  []models.MentionCount
+	options   []models.OptionsSummary
+	actions   []models.CorporateAction
+	renames   map[string]string // Old ticker to new ticker
+	ipos      []models.IPO
+	added     []string // Symbols passed to MarkIPOsAdded
+	macro     []models.MacroEvent
+	schedule  map[string]models.EnrichmentSchedule // Stocks already stored; nil means none
+	refreshes int                                  // Calls to RefreshAggregateViews
+}
+
+func (f *fakeStockDB) RefreshAggregateViews(ctx context.Context) error {
+	f.refreshes++
+	return nil
+}
+
+func (f *fakeStockDB) GetEnrichmentSchedule(ctx context.Context) (map[string]models.EnrichmentSchedule, error) {
+	return f.schedule, nil
+}
+
+func (f *fakeStockDB) UpsertStocks(ctx context.Context, stocks []models.Stock) error {
+	f.upserts <- append([]models.Stock(nil), stocks...)
+	return nil
+}
+
+func (f *fakeStockDB) SaveEnrichmentResults(ctx context.Context, results []models.EnrichmentResult) error {
+	f.results = append(f.results, results...)
+	return nil
+}
+
+func (f *fakeStockDB) RecordPrices(ctx context.Context, points []models.PricePoint) error {
+	f.prices = append(f.prices, points...)
+	return nil
+}
+
+// GetPriceHistory returns the recorded prices of tickers, ignoring since.
+func (f *fakeStockDB) GetPriceHistory(ctx context.Context, tickers []string, since time.Time) (map[string][]models.PricePoint, error) {
+	history := map[string][]models.PricePoint{}
+	for _, ticker := range tickers {
+		for _, p := range f.prices {
+			if p.Ticker == ticker {
+				history[ticker] = append(history[ticker], p)
+			}
+		}
+	}
+	return history, nil
+}
+
+func (f *fakeStockDB) RecordMentions(ctx context.Context, counts []models.MentionCount) error {
+	f.mentions = append(f.mentions, counts...)
+	return nil
+}
+
+func (f *fakeStockDB) SaveOptionsSummaries(ctx context.Context, summaries []models.OptionsSummary) error {
+	f.options = append(f.options, summaries...)
+	return nil
+}
+
+func (f *fakeStockDB) SaveCorporateActions(ctx context.Context, actions []models.CorporateAction) ([]models.CorporateAction, error) {
+	f.actions = append(f.actions, actions...)
+	return actions, nil
+}
+
+func (f *fakeStockDB) RenameTicker(ctx context.Context, oldTicker, newTicker string) error {
+	if f.renames == nil {
+		f.renames = map[string]string{}
+	}
+	f.renames[oldTicker] = newTicker
+	return nil
+}
+
+func (f *fakeStockDB) UpsertIPOs(ctx context.Context, ipos []models.IPO) error {
+	f.ipos = ipos
+	return nil
+}
+
+func (f *fakeStockDB) PendingListings(ctx context.Context, since, asOf time.Time) ([]models.IPO, error) {
+	var pending []models.IPO
+	for _, ipo := range f.ipos {
+		if ipo.Listed(asOf) && !ipo.Date.Before(since) {
+			pending = append(pending, ipo)
+		}
+	}
+	return pending, nil
+}
+
+func (f *fakeStockDB) MarkIPOsAdded(ctx context.Context, symbols []string, at time.Time) error {
+	f.added = append(f.added, symbols...)
+	return nil
+}
+
+func (f *fakeStockDB) UpsertMacroEvents(ctx context.Context, events []models.MacroEvent) error {
+	f.macro = events
+	return nil
+}
+
+func (f *fakeStockDB) MergeProviderStats(ctx context.Context, stats []models.ProviderDayStats) error {
+	return nil
+}
+
+func (f *fakeStockDB) GetScoringRules(ctx context.Context) (models.ScoringRules, error) {
+	return models.ScoringRules{}, nil
+}
+
+func (f *fakeStockDB) GetEnrichmentCursor(ctx context.Context) (models.EnrichmentCursor, error) {
+	return f.cursor, nil
+}
+
+func (f *fakeStockDB) SaveEnrichmentCursor(ctx context.Context, cursor models.EnrichmentCursor) error {
+	f.cursor = cursor
+	return nil
+}
+
+func (f *fakeStockDB) SaveEnrichmentRun(ctx context.Context, run models.EnrichmentRun) error {
+	if f.runs == nil {
+		f.runs = map[uuid.UUID]models.EnrichmentRun{}
+	}
+	f.runs[run.ID] = run
+	return nil
+}
+
+func (f *fakeStockDB) GetEnrichmentRun(ctx context.Context, id uuid.UUID) (models.EnrichmentRun, error) {
+	run, ok := f.runs[id]
+	if !ok {
+		return models.EnrichmentRun{}, database.ErrEnrichmentRunNotFound
+	}
+	return run, nil
+}
+
+func TestEnricher_ResumesInterruptedRun(t *testing.T) {
+	t.Setenv("PROVIDERS_MODE", api.ProvidersModeReplay)
+	t.Setenv("PROVIDERS_FIXTURES_DIR", filepath.Join("..", "fixtures", "providers"))
+
+	mockClock := clock.NewMock()
+	runID := uuid.New()
+	db := &fakeStockDB{
+		upserts: make(chan []models.Stock, 10),
+		// A previous process saved AAPL and KO before restarting.
+		cursor: models.EnrichmentCursor{RunID: runID, LastTicker: "KO", StartedAt: mockClock.Now().Add(-time.Hour)},
+		runs: map[uuid.UUID]models.EnrichmentRun{runID: {
+			ID: runID, Status: models.EnrichmentRunRunning, StocksProcessed: 2, ProviderErrors: map[string]int{"finnhub": 1},
+		}},
+	}
+	afterRuns := 0
+	e := NewEnricher(db, WithClock(mockClock), WithAfterRun(func() { afterRuns++ }))
+
+	if err := e.RunOnce(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if afterRuns != 1 || db.refreshes != 1 {
+		t.Errorf("Expected the after-run hook and the view refresh once, got %d and %d", afterRuns, db.refreshes)
+	}
+
+	stocks := <-db.upserts
+	if len(stocks) != 2 || stocks[0].Ticker != "MSFT" || stocks[1].Ticker != "PFE" {
+		t.Errorf("Expected only MSFT and PFE to be enriched, got %+v", stocks)
+	}
+	if src := stocks[0].Provenance["current_price"]; src.Source != "finnhub" || !src.FetchedAt.Equal(mockClock.Now()) {
+		t.Errorf("Expected current_price provenance from finnhub at %v, got %+v", mockClock.Now(), src)
+	}
+	if src := stocks[0].Provenance["company"]; src.Source != "karenai" {
+		t.Errorf("Expected company provenance from karenai, got %+v", src)
+	}
+	// The sector ETFs of MSFT and PFE are recorded first, for their relative strength.
+	prices := db.prices[len(db.prices)-2:]
+	if len(db.prices) != 4 || prices[0].Ticker != "MSFT" || prices[0].Close != stocks[0].CurrentPrice {
+		t.Errorf("Expected price history for XLK, XLV, MSFT and PFE, got %+v", db.prices)
+	}
+	if candle := prices[0].DayCandle; candle.Open != 410.3 || candle.High != 406.1 || candle.Low != 404.1 {
+		t.Errorf("Expected the day candle of the Finnhub quote in the price history, got %+v", candle)
+	}
+	if db.cursor.RunID != runID || !db.cursor.Completed || db.cursor.LastTicker != "PFE" {
+		t.Errorf("Expected the resumed run to complete, got cursor %+v", db.cursor)
+	}
+	if len(db.results) != 2 || db.results[0].RunID != runID || db.results[0].Ticker != "MSFT" {
+		t.Fatalf("Expected results for MSFT and PFE in run %s, got %+v", runID, db.results)
+	}
+	if src := db.results[0].Fields["current_price"]; src.Source != "finnhub" {
+		t.Errorf("Expected the MSFT result to record current_price from finnhub, got %+v", db.results[0].Fields)
+	}
+	if run := db.runs[runID]; run.Status != models.EnrichmentRunSucceeded || run.StocksProcessed != 4 || run.FinishedAt == nil {
+		t.Errorf("Expected the resumed run to succeed with 4 stocks processed, got %+v", run)
+	}
+
+	// A completed run is not resumed: the next run starts over with a new ID.
+	if err := e.RunOnce(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if stocks := <-db.upserts; len(stocks) != 4 {
+		t.Errorf("Expected a new run to enrich all 4 fixture tickers, got %d", len(stocks))
+	}
+	if db.cursor.RunID == runID {
+		t.Errorf("Expected a new run ID after a completed run")
+	}
+	if run := db.runs[db.cursor.RunID]; run.Status != models.EnrichmentRunSucceeded || run.StocksProcessed != 4 {
+		t.Errorf("Expected the new run to be recorded, got %+v", run)
+	}
+}
+
+func TestEnricher_AbandonsStaleRun(t *testing.T) {
+	t.Setenv("PROVIDERS_MODE", api.ProvidersModeReplay)
+	t.Setenv("PROVIDERS_FIXTURES_DIR", filepath.Join("..", "fixtures", "providers"))
+
+	mockClock := clock.NewMock()
+	staleID := uuid.New()
+	db := &fakeStockDB{
+		upserts: make(chan []models.Stock, 10),
+		// Interrupted longer ago than the enrichment interval: too stale to resume.
+		cursor: models.EnrichmentCursor{RunID: staleID, LastTicker: "KO", StartedAt: mockClock.Now().Add(-48 * time.Hour)},
+		runs:   map[uuid.UUID]models.EnrichmentRun{staleID: {ID: staleID, Status: models.EnrichmentRunRunning}},
+	}
+	e := NewEnricher(db, WithClock(mockClock))
+
+	if err := e.RunOnce(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if run := db.runs[staleID]; run.Status != models.EnrichmentRunAbandoned {
+		t.Errorf("Expected the stale run to be abandoned, got %+v", run)
+	}
+	if db.cursor.RunID == staleID || db.runs[db.cursor.RunID].Status != models.EnrichmentRunSucceeded {
+		t.Errorf("Expected a new run to be recorded, got cursor %+v and runs %+v", db.cursor, db.runs)
+	}
+}
+
+func TestEnricher_SkipsStocksNotDueByTier(t *testing.T) {
+	t.Setenv("PROVIDERS_MODE", api.ProvidersModeReplay)
+	t.Setenv("PROVIDERS_FIXTURES_DIR", filepath.Join("..", "fixtures", "providers"))
+
+	mockClock := clock.NewMock()
+	now := mockClock.Now()
+	db := &fakeStockDB{
+		upserts: make(chan []models.Stock, 10),
+		schedule: map[string]models.EnrichmentSchedule{
+			// Mega cap, hot tier (hourly): due after 2h.
+			"AAPL": {Ticker: "AAPL", MarketCapitalization: models.NewNullFloat64(3.0e6), LastEnrichedAt: now.Add(-2 * time.Hour)},
+			// Large cap, standard tier (daily): not due after 1h.
+			"KO": {Ticker: "KO", MarketCapitalization: models.NewNullFloat64(1.3e5), LastEnrichedAt: now.Add(-time.Hour)},
+			// Archived (weekly): not due after 3 days, even though it is a mega cap.
+			"MSFT": {Ticker: "MSFT", Tier: models.EnrichmentTierArchived, MarketCapitalization: models.NewNullFloat64(3.1e6), LastEnrichedAt: now.Add(-72 * time.Hour)},
+			// PFE is not stored yet, so it is always due.
+		},
+	}
+	e := NewEnricher(db, WithClock(mockClock), WithInterval(time.Hour))
+
+	if err := e.RunOnce(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	stocks := <-db.upserts
+	if len(stocks) != 2 || stocks[0].Ticker != "AAPL" || stocks[1].Ticker != "PFE" {
+		t.Errorf("Expected only AAPL and PFE to be due, got %+v", stocks)
+	}
+}
+
+// registerTestStep registers fn as the stock step name for the duration of the test, so
+// the same test can run again (go test -count=2) without a duplicate registration.
+func registerTestStep(t *testing.T, name string, fn StepFunc) {
+	t.Helper()
+	RegisterStep(name, fn)
+	t.Cleanup(func() {
+		stepsMu.Lock()
+		defer stepsMu.Unlock()
+		delete(steps, name)
+	})
+}
+
+func TestEnricher_RunsRegisteredSteps(t *testing.T) {
+	t.Setenv("PROVIDERS_MODE", api.ProvidersModeReplay)
+	t.Setenv("PROVIDERS_FIXTURES_DIR", filepath.Join("..", "fixtures", "providers"))
+
+	// A step added by configuration runs between the built-in ones it is listed with.
+	registerTestStep(t, "test_esg", func(ctx *StepContext, stock *models.Stock) error {
+		if stock.Provenance["current_price"].Source == "" {
+			return errors.New("quotes should have run first")
+		}
+		stock.Provenance.Set("test", ctx.Now(), "esg")
+		if stock.Ticker == "KO" {
+			ctx.ProviderError("test esg", errors.New("no coverage"))
+		}
+		return nil
+	})
+
+	db := &fakeStockDB{upserts: make(chan []models.Stock, 10)}
+	e := NewEnricher(db, WithClock(clock.NewMock()), WithSteps(StepQuotes, "test_esg"))
+	if err := e.RunOnce(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	stocks := <-db.upserts
+	for _, s := range stocks {
+		if s.Provenance["esg"].Source != "test" {
+			t.Errorf("Expected the registered step to run for %s, got provenance %+v", s.Ticker, s.Provenance)
+		}
+		if s.RecommendationScore.Valid {
+			t.Errorf("Expected no score for %s without the score step", s.Ticker)
+		}
+		if wantErr := s.Ticker == "KO"; strings.Contains(s.ProviderErrors, "test esg: no coverage") != wantErr {
+			t.Errorf("Unexpected provider errors for %s: %q", s.Ticker, s.ProviderErrors)
+		}
+	}
+
+	e = NewEnricher(db, WithClock(clock.NewMock()), WithSteps(StepQuotes, "not_a_step"))
+	if err := e.RunOnce(context.Background()); err == nil || !strings.Contains(err.Error(), `unknown enrichment step "not_a_step"`) {
+		t.Errorf("Expected an unknown step to fail the run, got %v", err)
+	}
+}
+
+func TestEnricher_EnrichesStocksConcurrently(t *testing.T) {
+	t.Setenv("PROVIDERS_MODE", api.ProvidersModeReplay)
+	t.Setenv("PROVIDERS_FIXTURES_DIR", filepath.Join("..", "fixtures", "providers"))
+
+	// Each stock waits for the other three: with a single worker the barrier never opens.
+	var active atomic.Int32
+	barrier := make(chan struct{})
+	registerTestStep(t, "test_barrier", func(ctx *StepContext, stock *models.Stock) error {
+		if active.Add(1) == 4 {
+			close(barrier)
+		}
+		select {
+		case <-barrier:
+			stock.Provenance.Set("test", ctx.Now(), "esg")
+		case <-time.After(time.Second):
+		}
+		return nil
+	})
+
+	db := &fakeStockDB{upserts: make(chan []models.Stock, 10)}
+	e := NewEnricher(db, WithClock(clock.NewMock()), WithSteps("test_barrier"), WithWorkers(4))
+	if err := e.RunOnce(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	stocks := <-db.upserts
+	for _, s := range stocks {
+		if s.Provenance["esg"].Source != "test" {
+			t.Errorf("Expected %s to be enriched alongside the other stocks", s.Ticker)
+		}
+	}
+	if len(stocks) != 4 || stocks[0].Ticker != "AAPL" || stocks[3].Ticker != "PFE" {
+		t.Errorf("Expected the 4 fixture tickers in order, got %+v", stocks)
+	}
+}
+
+func TestEnricher_RetriesRateLimitedTickers(t *testing.T) {
+	t.Setenv("PROVIDERS_MODE", api.ProvidersModeReplay)
+	t.Setenv("PROVIDERS_FIXTURES_DIR", filepath.Join("..", "fixtures", "providers"))
+
+	// KO is rate limited on its first call and AAPL on every call.
+	var mu sync.Mutex
+	calls := map[string]int{}
+	registerTestStep(t, "test_limited", func(ctx *StepContext, stock *models.Stock) error {
+		mu.Lock()
+		calls[stock.Ticker]++
+		first := calls[stock.Ticker] == 1
+		mu.Unlock()
+		if stock.Ticker == "AAPL" || (stock.Ticker == "KO" && first) {
+			ctx.ProviderError("test alpha", fmt.Errorf("note (%w)", api.ErrRateLimited))
+			return nil
+		}
+		stock.Provenance.Set("test", ctx.Now(), "alpha")
+		return nil
+	})
+
+	mockClock := clock.NewMock()
+	db := &fakeStockDB{upserts: make(chan []models.Stock, 10)}
+	e := NewEnricher(db, WithClock(mockClock), WithSteps("test_limited"))
+	done := make(chan error)
+	go func() { done <- e.RunOnce(context.Background()) }()
+
+	if first := <-db.upserts; len(first) != 4 {
+		t.Fatalf("Expected the 4 fixture tickers to be saved before retrying, got %d", len(first))
+	}
+	// Each retry waits for the limit window, and only the rate limited tickers are retried.
+	mockClock.BlockUntil(1)
+	mockClock.Add(time.Minute)
+	retried := <-db.upserts
+	if len(retried) != 2 || retried[0].Ticker != "AAPL" || retried[1].Ticker != "KO" {
+		t.Fatalf("Expected AAPL and KO to be retried, got %+v", retried)
+	}
+	if retried[1].Provenance["alpha"].Source != "test" || retried[1].ProviderErrors != "" {
+		t.Errorf("Expected the KO retry to succeed, got provenance %+v and errors %q", retried[1].Provenance, retried[1].ProviderErrors)
+	}
+	mockClock.BlockUntil(1)
+	mockClock.Add(time.Minute)
+	if again := <-db.upserts; len(again) != 1 || again[0].Ticker != "AAPL" {
+		t.Fatalf("Expected only AAPL in the second retry, got %+v", again)
+	}
+
+	if err := <-done; err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := 1 + config.Current().RateLimitRetries
+	if calls["AAPL"] != want || calls["KO"] != 2 {
+		t.Errorf("Expected AAPL to be tried %d times and KO twice, got %v", want, calls)
+	}
+	if !db.cursor.Completed || db.cursor.LastTicker != "PFE" {
+		t.Errorf("Expected the retries to keep the cursor at the last ticker, got %+v", db.cursor)
+	}
+	// Retried stocks are counted once, and every rate limited call is a provider error.
+	run := db.runs[db.cursor.RunID]
+	if run.StocksProcessed != 4 || run.ProviderErrors["test"] != want+1 {
+		t.Errorf("Expected 4 stocks processed and %d test errors, got %+v", want+1, run)
+	}
+}
+
+func TestEnricher_SentimentStep(t *testing.T) {
+	t.Setenv("PROVIDERS_MODE", api.ProvidersModeReplay)
+	t.Setenv("PROVIDERS_FIXTURES_DIR", filepath.Join("..", "fixtures", "providers"))
+
+	db := &fakeStockDB{upserts: make(chan []models.Stock, 10)}
+	e := NewEnricher(db, WithClock(clock.NewMock()), WithSteps(StepSentiment))
+	if err := e.RunOnce(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	stocks := <-db.upserts
+	for _, s := range stocks {
+		if s.Ticker != "AAPL" {
+			// Only AAPL has recorded news; the others fail and keep a null sentiment.
+			if s.Sentiment.Valid || !strings.Contains(s.ProviderErrors, "finnhub news") {
+				t.Errorf("Expected a null sentiment and a provider error for %s, got %+v", s.Ticker, s)
+			}
+			continue
+		}
+		// A fresh positive headline, a 2-day-old negative one (half weight) and a neutral
+		// 3-day-old one: (1 - 0.5) / (1 + 0.5 + 0.354) = 0.27.
+		if !s.Sentiment.Valid || s.Sentiment.Float64 < 0.26 || s.Sentiment.Float64 > 0.28 {
+			t.Errorf("Expected AAPL sentiment around 0.27, got %+v", s.Sentiment)
+		}
+		if src := s.Provenance["sentiment"]; src.Source != "finnhub" {
+			t.Errorf("Expected sentiment provenance from finnhub, got %+v", src)
+		}
+	}
+}
+
+func TestEnricher_BuzzStep(t *testing.T) {
+	t.Setenv("PROVIDERS_MODE", api.ProvidersModeReplay)
+	t.Setenv("PROVIDERS_FIXTURES_DIR", filepath.Join("..", "fixtures", "providers"))
+
+	mockClock := clock.NewMock()
+	db := &fakeStockDB{upserts: make(chan []models.Stock, 10)}
+	e := NewEnricher(db, WithClock(mockClock), WithSteps(StepBuzz))
+	if err := e.RunOnce(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	stocks := <-db.upserts
+	for _, s := range stocks {
+		if s.Ticker != "AAPL" {
+			if s.Buzz.Valid || !strings.Contains(s.ProviderErrors, "finnhub mentions") {
+				t.Errorf("Expected a null buzz and a provider error for %s, got %+v", s.Ticker, s)
+			}
+			continue
+		}
+		// Reddit and Twitter mentions of every hour of the day are added up.
+		if !s.Buzz.Valid || s.Buzz.Float64 != 187 {
+			t.Errorf("Expected AAPL buzz of 187, got %+v", s.Buzz)
+		}
+	}
+	day := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	want := models.MentionCount{Ticker: "AAPL", Day: day, Source: "finnhub", Mentions: 187}
+	if len(db.mentions) != 1 || db.mentions[0] != want {
+		t.Errorf("Expected the AAPL count in the mention history, got %+v", db.mentions)
+	}
+}
+
+func TestEnricher_ESGStep(t *testing.T) {
+	t.Setenv("PROVIDERS_MODE", api.ProvidersModeReplay)
+	t.Setenv("PROVIDERS_FIXTURES_DIR", filepath.Join("..", "fixtures", "providers"))
+
+	db := &fakeStockDB{upserts: make(chan []models.Stock, 10)}
+	e := NewEnricher(db, WithClock(clock.NewMock()), WithSteps(StepESG))
+	if err := e.RunOnce(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, s := range <-db.upserts {
+		switch {
+		case s.Ticker == "AAPL" && (!s.ESGScore.Valid || s.ESGScore.Float64 != 61.93 || s.Provenance["esg_score"].Source != "finnhub"):
+			t.Errorf("Expected AAPL ESG score 61.93 from finnhub, got %+v", s)
+		case s.Ticker != "AAPL" && (s.ESGScore.Valid || !strings.Contains(s.ProviderErrors, "finnhub esg")):
+			t.Errorf("Expected a null ESG score and a provider error for %s, got %+v", s.Ticker, s)
+		}
+	}
+}
+
+func TestEnricher_ShortInterestStep(t *testing.T) {
+	t.Setenv("PROVIDERS_MODE", api.ProvidersModeReplay)
+	t.Setenv("PROVIDERS_FIXTURES_DIR", filepath.Join("..", "fixtures", "providers"))
+
+	db := &fakeStockDB{upserts: make(chan []models.Stock, 10)}
+	e := NewEnricher(db, WithClock(clock.NewMock()), WithSteps(StepShortInterest))
+	if err := e.RunOnce(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, s := range <-db.upserts {
+		if s.Ticker != "AAPL" {
+			continue
+		}
+		// The latest of the two reports, over a 10-day average volume of 50M shares.
+		if !s.ShortInterest.Valid || s.ShortInterest.Float64 != 125e6 {
+			t.Errorf("Expected the latest AAPL short interest of 125M shares, got %+v", s.ShortInterest)
+		}
+		if !s.DaysToCover.Valid || s.DaysToCover.Float64 != 2.5 {
+			t.Errorf("Expected 2.5 days to cover, got %+v", s.DaysToCover)
+		}
+		return
+	}
+	t.Error("Expected AAPL to be enriched")
+}
+
+func TestEnricher_OptionsStep(t *testing.T) {
+	t.Setenv("PROVIDERS_MODE", api.ProvidersModeReplay)
+	t.Setenv("PROVIDERS_FIXTURES_DIR", filepath.Join("..", "fixtures", "providers"))
+
+	db := &fakeStockDB{upserts: make(chan []models.Stock, 10)}
+	e := NewEnricher(db, WithClock(clock.NewMock()), WithSteps(StepOptions))
+	if err := e.RunOnce(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	<-db.upserts
+	if len(db.options) != 1 {
+		t.Fatalf("Expected only the AAPL options summary, got %+v", db.options)
+	}
+	got := db.options[0]
+	// The January 3 expiration is already past on January 6: the nearest is January 10.
+	if got.Ticker != "AAPL" || got.Expirations != 3 || !got.NearestExpiration.Equal(time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected summary: %+v", got)
+	}
+	if got.ImpliedVolatility.Float64 != 24.5 || got.PutCallRatio.Float64 != 0.75 || got.PutCallOpenInterestRatio.Float64 != 1.25 {
+		t.Errorf("Unexpected ratios: %+v", got)
+	}
+}
+
+func TestEnricher_CorporateActionsStep(t *testing.T) {
+	t.Setenv("PROVIDERS_MODE", api.ProvidersModeReplay)
+	t.Setenv("PROVIDERS_FIXTURES_DIR", filepath.Join("..", "fixtures", "providers"))
+
+	db := &fakeStockDB{upserts: make(chan []models.Stock, 10)}
+	e := NewEnricher(db, WithClock(clock.NewMock()), WithSteps(StepCorporateActions))
+	if err := e.RunOnce(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	<-db.upserts
+
+	// Only AAPL and PFE have split fixtures; the market-wide symbol change list renames PFE.
+	if len(db.actions) != 2 {
+		t.Fatalf("Expected the AAPL split and the PFE symbol change, got %+v", db.actions)
+	}
+	split, change := db.actions[0], db.actions[1]
+	if split.Ticker != "AAPL" || split.Type != models.CorporateActionSplit || split.SplitFrom.Float64 != 1 || split.SplitTo.Float64 != 4 {
+		t.Errorf("Unexpected split: %+v", split)
+	}
+	if change.Ticker != "PFE" || change.Type != models.CorporateActionSymbolChange || change.NewTicker != "PFEX" ||
+		!change.EffectiveDate.Equal(time.Date(2025, 1, 3, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected symbol change: %+v", change)
+	}
+	if len(db.renames) != 1 || db.renames["PFE"] != "PFEX" {
+		t.Errorf("Expected PFE to be renamed to PFEX, got %v", db.renames)
+	}
+}
+
+func TestEnricher_RelativeStrength(t *testing.T) {
+	t.Setenv("PROVIDERS_MODE", api.ProvidersModeReplay)
+	t.Setenv("PROVIDERS_FIXTURES_DIR", filepath.Join("..", "fixtures", "providers"))
+
+	// The quote fixtures are for 2025-10-15; 30 days earlier AAPL closed at 170 and XLK at 200.
+	monthAgo := time.Date(2025, 9, 15, 0, 0, 0, 0, time.UTC)
+	db := &fakeStockDB{upserts: make(chan []models.Stock, 10), prices: []models.PricePoint{
+		{Ticker: "AAPL", TradingDay: monthAgo, Close: 170},
+		{Ticker: "XLK", TradingDay: monthAgo, Close: 200},
+	}}
+	e := NewEnricher(db, WithClock(clock.NewMock()), WithSteps(StepQuotes, StepIndicators))
+	if err := e.RunOnce(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	upserted := <-db.upserts
+
+	var aapl, ko *models.Stock
+	for i := range upserted {
+		switch upserted[i].Ticker {
+		case "AAPL":
+			aapl = &upserted[i]
+		case "KO":
+			ko = &upserted[i]
+		}
+	}
+	if aapl == nil || ko == nil {
+		t.Fatalf("Expected AAPL and KO to be upserted, got %+v", upserted)
+	}
+	// AAPL gained 15% (170 to 195.5) while XLK gained 5% (200 to 210).
+	if rs := aapl.RelativeStrength.Days30; !rs.Valid || math.Abs(rs.Float64-10) > 1e-9 {
+		t.Errorf("Expected a 30-day relative strength of 10 points for AAPL, got %+v", rs)
+	}
+	if aapl.RelativeStrength.Days90.Valid {
+		t.Errorf("Expected no 90-day relative strength without 90 days of history, got %+v", aapl.RelativeStrength.Days90)
+	}
+	if ko.RelativeStrength.Days30.Valid {
+		t.Errorf("Expected no relative strength for KO without history, got %+v", ko.RelativeStrength)
+	}
+
+	etfs := map[string]int{}
+	for _, p := range db.prices {
+		etfs[p.Ticker]++
+	}
+	if etfs["XLK"] != 2 || etfs["XLP"] != 1 || etfs["XLV"] != 1 {
+		t.Errorf("Expected each sector ETF price to be recorded once per run, got %v", etfs)
+	}
+}
+
+func TestEnricher_IPOCalendar(t *testing.T) {
+	t.Setenv("PROVIDERS_MODE", api.ProvidersModeReplay)
+	t.Setenv("PROVIDERS_FIXTURES_DIR", filepath.Join("..", "fixtures", "providers"))
+	prev := config.Current()
+	t.Cleanup(func() { config.Set(prev) })
+
+	run := func(autoAdd bool) (*fakeStockDB, []models.Stock) {
+		cfg := config.Default()
+		cfg.FeatureFlags = map[string]bool{config.FlagIPOAutoAdd: autoAdd}
+		config.Set(cfg)
+		db := &fakeStockDB{upserts: make(chan []models.Stock, 10)}
+		e := NewEnricher(db, WithClock(clock.NewMock()), WithSteps(StepScore))
+		if err := e.RunOnce(context.Background()); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var upserted []models.Stock
+		for len(db.upserts) > 0 {
+			upserted = append(upserted, <-db.upserts...)
+		}
+		return db, upserted
+	}
+	tracked := func(stocks []models.Stock, ticker string) *models.Stock {
+		for i := range stocks {
+			if stocks[i].Ticker == ticker {
+				return &stocks[i]
+			}
+		}
+		return nil
+	}
+
+	db, upserted := run(false)
+	if len(db.ipos) != 3 {
+		t.Fatalf("Expected the 3 calendar entries to be saved, got %+v", db.ipos)
+	}
+	upcoming := db.ipos[1]
+	if upcoming.Symbol != "UPCM" || upcoming.PriceLow.Float64 != 20 || upcoming.PriceHigh.Float64 != 22 || upcoming.Source != "finnhub" {
+		t.Errorf("Unexpected upcoming IPO: %+v", upcoming)
+	}
+	if tracked(upserted, "FRSH") != nil || len(db.added) != 0 {
+		t.Errorf("Expected no listing to be added without the ipo_auto_add flag")
+	}
+
+	// With the flag, the IPO that started trading on January 3 joins the run.
+	db, upserted = run(true)
+	fresh := tracked(upserted, "FRSH")
+	if fresh == nil || fresh.Company != "Fresh Listing Corp" {
+		t.Fatalf("Expected FRSH to be added and enriched, got %+v", fresh)
+	}
+	if tracked(upserted, "UPCM") != nil {
+		t.Error("Expected the upcoming IPO not to be added before it lists")
+	}
+	if len(db.added) != 1 || db.added[0] != "FRSH" {
+		t.Errorf("Expected FRSH to be marked as added, got %v", db.added)
+	}
+}
+
+func TestEnricher_MacroCalendar(t *testing.T) {
+	t.Setenv("PROVIDERS_MODE", api.ProvidersModeReplay)
+	t.Setenv("PROVIDERS_FIXTURES_DIR", filepath.Join("..", "fixtures", "providers"))
+
+	db := &fakeStockDB{upserts: make(chan []models.Stock, 10)}
+	e := NewEnricher(db, WithClock(clock.NewMock()), WithSteps(StepScore))
+	if err := e.RunOnce(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The low impact, uncategorized Japanese release is dropped.
+	if len(db.macro) != 3 {
+		t.Fatalf("Expected the 3 key events to be saved, got %+v", db.macro)
+	}
+	payrolls, cpi, minutes := db.macro[0], db.macro[1], db.macro[2]
+	if payrolls.Category != models.MacroCategoryJobs || payrolls.Actual.Float64 != 256 || payrolls.Source != "finnhub" {
+		t.Errorf("Unexpected payrolls event: %+v", payrolls)
+	}
+	if cpi.Category != models.MacroCategoryInflation || cpi.Actual.Valid || cpi.Estimate.Float64 != 0.3 {
+		t.Errorf("Unexpected CPI event: %+v", cpi)
+	}
+	if minutes.Category != models.MacroCategoryRates || !minutes.Time.Equal(time.Date(2025, 1, 8, 19, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected FOMC event: %+v", minutes)
+	}
+}
+
+func TestEnricher_ScheduledRunsWithMockClock(t *testing.T) {
+	t.Setenv("PROVIDERS_MODE", api.ProvidersModeReplay)
+	t.Setenv("PROVIDERS_FIXTURES_DIR", filepath.Join("..", "fixtures", "providers"))
+
+	mockClock := clock.NewMock()
+	db := &fakeStockDB{upserts: make(chan []models.Stock, 1)}
+	e := NewEnricher(db, WithClock(mockClock))
+
+	if !e.IsStale(time.Hour) {
+		t.Errorf("Expected data to be stale before the first run")
+	}
+
+	go e.StartFetching()
+
+	// Initial run happens immediately and stamps updated_at with the mock time.
+	stocks := <-db.upserts
+	if len(stocks) == 0 {
+		t.Fatal("Expected the initial run to upsert fixture stocks")
+	}
+	if !stocks[0].UpdatedAt.Equal(mockClock.Now()) {
+		t.Errorf("Expected UpdatedAt %v, got %v", mockClock.Now(), stocks[0].UpdatedAt)
+	}
+
+	// The next run only happens once the interval elapses on the mock clock.
+	mockClock.BlockUntil(1)
+	mockClock.Add(23 * time.Hour)
+	select {
+	case <-db.upserts:
+		t.Fatal("Expected no run before the interval elapsed")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if e.IsStale(24 * time.Hour) {
+		t.Errorf("Expected data not to be stale 23h after a successful run")
+	}
+
+	mockClock.Add(time.Hour)
+	stocks = <-db.upserts
+	if !stocks[0].UpdatedAt.Equal(mockClock.Now()) {
+		t.Errorf("Expected UpdatedAt %v, got %v", mockClock.Now(), stocks[0].UpdatedAt)
+	}
+}
+
+func TestEnricher_SetIntervalReschedules(t *testing.T) {
+	t.Setenv("PROVIDERS_MODE", api.ProvidersModeReplay)
+	t.Setenv("PROVIDERS_FIXTURES_DIR", filepath.Join("..", "fixtures", "providers"))
+
+	mockClock := clock.NewMock()
+	db := &fakeStockDB{upserts: make(chan []models.Stock, 1)}
+	e := NewEnricher(db, WithClock(mockClock), WithInterval(24*time.Hour))
+
+	go e.StartFetching()
+	<-db.upserts
+	mockClock.BlockUntil(1)
+
+	e.SetInterval(2 * time.Hour)
+	if got := e.Interval(); got != 2*time.Hour {
+		t.Fatalf("Expected interval 2h, got %s", got)
+	}
+
+	// The reschedule happens asynchronously, so advance in 2h steps until the run fires;
+	// with the old 24h schedule it would take twelve steps.
+	for elapsed := 2 * time.Hour; elapsed < 24*time.Hour; elapsed += 2 * time.Hour {
+		mockClock.Add(2 * time.Hour)
+		select {
+		case <-db.upserts:
+			return
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+	t.Fatal("Expected a run on the new 2h interval before 24h elapsed")
+}
+
+func TestEnricher_CronScheduleSkipsWeekends(t *testing.T) {
+	t.Setenv("PROVIDERS_MODE", api.ProvidersModeReplay)
+	t.Setenv("PROVIDERS_FIXTURES_DIR", filepath.Join("..", "fixtures", "providers"))
+
+	schedule, err := clock.ParseSchedule("0 6 * * 1-5")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	mockClock := clock.NewMock()
+	mockClock.Set(time.Date(2025, time.January, 10, 7, 0, 0, 0, time.UTC)) // Friday after the run time
+	db := &fakeStockDB{upserts: make(chan []models.Stock, 1)}
+	e := NewEnricher(db, WithClock(mockClock), WithSchedule(schedule))
+
+	go e.StartFetching()
+	<-db.upserts // Initial run
+	mockClock.BlockUntil(1)
+
+	mockClock.Add(70 * time.Hour) // Monday 05:00
+	select {
+	case <-db.upserts:
+		t.Fatal("Expected no run over the weekend")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	mockClock.Add(time.Hour)
+	stocks := <-db.upserts
+	if monday := time.Date(2025, time.January, 13, 6, 0, 0, 0, time.UTC); !stocks[0].UpdatedAt.Equal(monday) {
+		t.Errorf("Expected the next run on Monday at 06:00, got %v", stocks[0].UpdatedAt)
+	}
+
+	// The next run is Tuesday's, a day later rather than another 71h.
+	mockClock.BlockUntil(1)
+	mockClock.Add(24 * time.Hour)
+	select {
+	case <-db.upserts:
+	case <-time.After(time.Second):
+		t.Fatal("Expected a run on Tuesday at 06:00")
+	}
+	e.Stop(context.Background())
+}
+
+func TestEnricher_Stop(t *testing.T) {
+	t.Setenv("PROVIDERS_MODE", api.ProvidersModeReplay)
+	t.Setenv("PROVIDERS_FIXTURES_DIR", filepath.Join("..", "fixtures", "providers"))
+
+	// Stopping an enricher that never started returns immediately.
+	idle := NewEnricher(&fakeStockDB{upserts: make(chan []models.Stock, 1)}, WithClock(clock.NewMock()))
+	if err := idle.Stop(context.Background()); err != nil {
+		t.Errorf("Expected no error stopping an idle enricher, got %v", err)
+	}
+
+	mockClock := clock.NewMock()
+	db := &fakeStockDB{upserts: make(chan []models.Stock, 1)}
+	e := NewEnricher(db, WithClock(mockClock))
+
+	returned := make(chan struct{})
+	go func() {
+		e.StartFetching()
+		close(returned)
+	}()
+	<-db.upserts
+	mockClock.BlockUntil(1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := e.Stop(ctx); err != nil {
+		t.Fatalf("Expected StartFetching to stop, got %v", err)
+	}
+	<-returned
+}
+
+func TestEnricher_TriggerRunsImmediately(t *testing.T) {
+	t.Setenv("PROVIDERS_MODE", api.ProvidersModeReplay)
+	t.Setenv("PROVIDERS_FIXTURES_DIR", filepath.Join("..", "fixtures", "providers"))
+
+	mockClock := clock.NewMock()
+	db := &fakeStockDB{upserts: make(chan []models.Stock, 1)}
+	e := NewEnricher(db, WithClock(mockClock))
+
+	go e.StartFetching()
+	defer e.Stop(context.Background())
+	<-db.upserts
+	mockClock.BlockUntil(1)
+
+	// A triggered run does not wait for the 24h interval on the mock clock.
+	e.Trigger()
+	select {
+	case <-db.upserts:
+	case <-time.After(time.Second):
+		t.Fatal("Expected Trigger to start a run without advancing the clock")
+	}
+}
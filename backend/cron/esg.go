@@ -0,0 +1,36 @@
+package enricher
+
+import (
+	"database/sql"
+	"log"
+
+	"github.com/jannin2/stock-app/backend/models"
+	"github.com/jannin2/stock-app/backend/providers"
+)
+
+// StepESG fills the ESG score through the ESG provider chain (config.ProviderChains).
+// ESG ratings change a few times a year, so it is not in the default pipeline; list it
+// before "score" to weigh it in the recommendation score.
+const StepESG = "esg"
+
+func init() {
+	RegisterStep(StepESG, esgStep)
+}
+
+func esgStep(ctx *StepContext, stock *models.Stock) error {
+	esg, source, failures, err := providers.FetchESG(stock.Ticker)
+	for _, f := range failures {
+		ctx.ProviderError(f.Provider+" esg", f.Err)
+	}
+	if err != nil {
+		log.Printf("Error getting ESG for %s: %v. Assigning null value.", stock.Ticker, err)
+		stock.ESGScore = models.NullFloat64{NullFloat64: sql.NullFloat64{Valid: false}}
+		return nil
+	}
+
+	stock.ESGScore = models.NewNullFloat64(esg.Total)
+	stock.Provenance.Set(source, ctx.Now(), "esg_score")
+	log.Printf("ESG for %s from %s: %.2f (E %.2f, S %.2f, G %.2f)",
+		stock.Ticker, source, esg.Total, esg.Environmental, esg.Social, esg.Governance)
+	return nil
+}
@@ -0,0 +1,108 @@
+package enricher
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/jannin2/stock-app/backend/api"
+)
+
+// failureAction is how the enricher reacts to a provider failure. It is decided from the
+// api.ErrorKind of the error, never from its message.
+type failureAction string
+
+const (
+	actionSkip  failureAction = "skip"  // The provider has nothing for the ticker; its fields stay null
+	actionRetry failureAction = "retry" // Transient; a later attempt may succeed
+	actionAlert failureAction = "alert" // Needs an operator: a rejected key or a changed response format
+)
+
+// actionFor returns the reaction to a failure of the given kind.
+func actionFor(kind api.ErrorKind) failureAction {
+	switch kind {
+	case api.KindNotFound:
+		return actionSkip
+	case api.KindRateLimited, api.KindNetwork, api.KindUpstream:
+		return actionRetry
+	case api.KindAuth, api.KindMalformed:
+		return actionAlert
+	default:
+		return actionRetry
+	}
+}
+
+// runFailures collects the provider failures of one enrichment run by source and kind.
+// Alerts are logged the first time a source fails with a given kind, so a rejected key
+// is reported once per run instead of once per ticker.
+type runFailures struct {
+	mu     sync.Mutex
+	counts map[failureKey]int
+}
+
+type failureKey struct {
+	source string
+	kind   api.ErrorKind
+}
+
+func newRunFailures() *runFailures {
+	return &runFailures{counts: map[failureKey]int{}}
+}
+
+// record counts err, of the given kind. source identifies the provider and data, e.g.
+// "finnhub quote".
+func (f *runFailures) record(source string, kind api.ErrorKind, err error) {
+	action := actionFor(kind)
+
+	f.mu.Lock()
+	key := failureKey{source: source, kind: kind}
+	f.counts[key]++
+	first := f.counts[key] == 1
+	f.mu.Unlock()
+
+	if action == actionAlert && first {
+		logAlert(source, kind, err)
+	}
+}
+
+func logAlert(source string, kind api.ErrorKind, err error) {
+	log.Printf("ALERT: %s is failing with %s errors and needs attention: %v", source, kind, err)
+}
+
+// byProvider returns the number of failures of each provider, the first word of their
+// source ("finnhub" for "finnhub quote").
+func (f *runFailures) byProvider() map[string]int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	counts := map[string]int{}
+	for key, n := range f.counts {
+		provider, _, _ := strings.Cut(key.source, " ")
+		counts[provider] += n
+	}
+	return counts
+}
+
+// summary describes the failures of the run, grouped by action, e.g.
+// "retry: alphavantage alpha rate_limited×3; skip: finnhub esg not_found×12". It is empty
+// when nothing failed.
+func (f *runFailures) summary() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	byAction := map[failureAction][]string{}
+	for key, n := range f.counts {
+		action := actionFor(key.kind)
+		byAction[action] = append(byAction[action], fmt.Sprintf("%s %s×%d", key.source, key.kind, n))
+	}
+	var parts []string
+	for _, action := range []failureAction{actionAlert, actionRetry, actionSkip} {
+		if entries := byAction[action]; len(entries) > 0 {
+			sort.Strings(entries)
+			parts = append(parts, string(action)+": "+strings.Join(entries, ", "))
+		}
+	}
+	return strings.Join(parts, "; ")
+}
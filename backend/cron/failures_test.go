@@ -0,0 +1,26 @@
+package enricher
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/jannin2/stock-app/backend/api"
+)
+
+func TestRunFailures_Summary(t *testing.T) {
+	f := newRunFailures()
+	record := func(source string, err error) { f.record(source, api.ClassifyError(err), err) }
+	if got := f.summary(); got != "" {
+		t.Errorf("summary with no failures = %q, want empty", got)
+	}
+
+	record("alphavantage alpha", fmt.Errorf("note (%w)", api.ErrRateLimited))
+	record("alphavantage alpha", fmt.Errorf("note (%w)", api.ErrRateLimited))
+	record("finnhub esg", fmt.Errorf("no ESG: %w", api.ErrNoData))
+	record("finnhub quote", fmt.Errorf("FINNHUB_API_KEY %w", api.ErrMissingAPIKey))
+
+	want := "alert: finnhub quote auth×1; retry: alphavantage alpha rate_limited×2; skip: finnhub esg not_found×1"
+	if got := f.summary(); got != want {
+		t.Errorf("summary = %q, want %q", got, want)
+	}
+}
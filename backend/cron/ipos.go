@@ -0,0 +1,98 @@
+package enricher
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/config"
+	"github.com/jannin2/stock-app/backend/models"
+	"github.com/jannin2/stock-app/backend/providers"
+)
+
+// The IPO calendar is synced at the start of every run, before the feed is enriched. With
+// the ipo_auto_add feature flag the tickers that started trading recently join the run as
+// new stocks, so they are enriched and tracked like those from the feed.
+const (
+	ipoLookback  = 7 * 24 * time.Hour  // Recent listings, also how long a listing can wait to be added
+	ipoLookahead = 30 * 24 * time.Hour // Upcoming IPOs served by GET /ipos
+)
+
+// syncIPOs saves the IPO calendar around the current date. Failures are only logged: the
+// calendar is not needed to enrich the feed.
+func (e *Enricher) syncIPOs(ctx context.Context) {
+	now := e.clock.Now()
+	calendar, source, failures, err := providers.FetchIPOs(now.Add(-ipoLookback), now.Add(ipoLookahead))
+	for _, f := range failures {
+		log.Printf("Warning: %s IPO calendar: %v", f.Provider, f.Err)
+	}
+	if err != nil {
+		log.Printf("Warning: could not get the IPO calendar: %v", err)
+		return
+	}
+
+	ipos := make([]models.IPO, 0, len(calendar))
+	for _, c := range calendar {
+		ipo := models.IPO{Symbol: c.Symbol, Company: c.Company, Exchange: c.Exchange, Date: c.Date,
+			Status: c.Status, Source: source, UpdatedAt: now}
+		if c.PriceLow > 0 {
+			ipo.PriceLow = models.NewNullFloat64(c.PriceLow)
+		}
+		if c.PriceHigh > 0 {
+			ipo.PriceHigh = models.NewNullFloat64(c.PriceHigh)
+		}
+		if c.Shares > 0 {
+			ipo.Shares = models.NewNullFloat64(c.Shares)
+		}
+		ipos = append(ipos, ipo)
+	}
+	if err := e.dbClient.UpsertIPOs(ctx, ipos); err != nil {
+		log.Printf("Warning: could not save the IPO calendar: %v", err)
+		return
+	}
+	log.Printf("IPO calendar from %s: %d entries", source, len(ipos))
+}
+
+// newListings returns a stock for each recent listing that is not tracked yet and not in
+// the feed, when the ipo_auto_add feature flag is on.
+func (e *Enricher) newListings(ctx context.Context, feed []models.Stock) []models.Stock {
+	if !config.Current().Enabled(config.FlagIPOAutoAdd) {
+		return nil
+	}
+	now := e.clock.Now()
+	pending, err := e.dbClient.PendingListings(ctx, now.Add(-ipoLookback), now)
+	if err != nil {
+		log.Printf("Warning: could not load the new listings: %v", err)
+		return nil
+	}
+
+	inFeed := make(map[string]bool, len(feed))
+	for _, s := range feed {
+		inFeed[s.Ticker] = true
+	}
+	var stocks []models.Stock
+	for _, ipo := range pending {
+		if inFeed[ipo.Symbol] {
+			continue
+		}
+		stock := models.Stock{Ticker: ipo.Symbol, Company: ipo.Company, Provenance: models.Provenance{}}
+		stock.Provenance.Set(ipo.Source, now, "company")
+		stocks = append(stocks, stock)
+	}
+	if len(stocks) > 0 {
+		log.Printf("Adding %d new listings from the IPO calendar", len(stocks))
+	}
+	return stocks
+}
+
+// markListed records that the listings were added, once the run that enriched them has
+// finished, so they are not added again.
+func (e *Enricher) markListed(ctx context.Context, listings []models.Stock) {
+	symbols := make([]string, 0, len(listings))
+	for _, s := range listings {
+		symbols = append(symbols, s.Ticker)
+	}
+	if err := e.dbClient.MarkIPOsAdded(ctx, symbols, e.clock.Now()); err != nil {
+		log.Printf("Warning: could not mark the new listings as added: %v", err)
+	}
+}
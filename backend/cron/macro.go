@@ -0,0 +1,47 @@
+package enricher
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/models"
+	"github.com/jannin2/stock-app/backend/providers"
+)
+
+// The economic calendar is synced at the start of every run, like the IPO calendar. The
+// lookback keeps re-reading recent events so their actual figures are filled in once
+// released.
+const (
+	macroLookback  = 7 * 24 * time.Hour
+	macroLookahead = 14 * 24 * time.Hour // Upcoming events served by GET /macro/events
+)
+
+// syncMacroEvents saves the key events of the economic calendar around the current date.
+// Failures are only logged: the calendar is not needed to enrich the feed.
+func (e *Enricher) syncMacroEvents(ctx context.Context) {
+	now := e.clock.Now()
+	calendar, source, failures, err := providers.FetchMacroEvents(now.Add(-macroLookback), now.Add(macroLookahead))
+	for _, f := range failures {
+		log.Printf("Warning: %s economic calendar: %v", f.Provider, f.Err)
+	}
+	if err != nil {
+		log.Printf("Warning: could not get the economic calendar: %v", err)
+		return
+	}
+
+	events := make([]models.MacroEvent, 0, len(calendar))
+	for _, c := range calendar {
+		event := models.MacroEvent{Country: c.Country, Event: c.Event, Category: models.MacroCategory(c.Event),
+			Impact: c.Impact, Time: c.Time, Actual: c.Actual, Estimate: c.Estimate, Previous: c.Previous,
+			Unit: c.Unit, Source: source, UpdatedAt: now}
+		if event.Key() {
+			events = append(events, event)
+		}
+	}
+	if err := e.dbClient.UpsertMacroEvents(ctx, events); err != nil {
+		log.Printf("Warning: could not save the economic calendar: %v", err)
+		return
+	}
+	log.Printf("Economic calendar from %s: %d key events of %d", source, len(events), len(calendar))
+}
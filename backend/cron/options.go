@@ -0,0 +1,68 @@
+package enricher
+
+import (
+	"log"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/models"
+	"github.com/jannin2/stock-app/backend/providers"
+)
+
+// StepOptions summarizes the options chain through the options provider chain
+// (config.ProviderChains). The summary is saved to its own table by persist and served by
+// GET /stocks/{ticker}/options-summary. Not in the default pipeline.
+const StepOptions = "options"
+
+func init() {
+	RegisterStep(StepOptions, optionsStep)
+}
+
+func optionsStep(ctx *StepContext, stock *models.Stock) error {
+	expirations, source, failures, err := providers.FetchOptions(stock.Ticker)
+	for _, f := range failures {
+		ctx.ProviderError(f.Provider+" options", f.Err)
+	}
+	if err != nil {
+		log.Printf("Error getting options for %s: %v. Keeping the previous summary.", stock.Ticker, err)
+		return nil
+	}
+
+	stock.Options = summarizeOptions(stock.Ticker, source, expirations, ctx.Now())
+	log.Printf("Options for %s from %s: %d expirations, put/call %.2f (Valid: %t), IV %.2f%% (Valid: %t)",
+		stock.Ticker, source, stock.Options.Expirations, stock.Options.PutCallRatio.Float64, stock.Options.PutCallRatio.Valid,
+		stock.Options.ImpliedVolatility.Float64, stock.Options.ImpliedVolatility.Valid)
+	return nil
+}
+
+// summarizeOptions adds up the volume and open interest of every expiration and takes the
+// implied volatility of the nearest one that has not expired yet.
+func summarizeOptions(ticker, source string, expirations []providers.OptionsExpiration, now time.Time) *models.OptionsSummary {
+	summary := &models.OptionsSummary{Ticker: ticker, Source: source, Expirations: len(expirations), FetchedAt: now}
+	today := now.UTC().Truncate(24 * time.Hour)
+	var nearest *providers.OptionsExpiration
+	for i, e := range expirations {
+		summary.PutVolume += e.PutVolume
+		summary.CallVolume += e.CallVolume
+		summary.PutOpenInterest += e.PutOpenInterest
+		summary.CallOpenInterest += e.CallOpenInterest
+		if !e.Date.Before(today) && (nearest == nil || e.Date.Before(nearest.Date)) {
+			nearest = &expirations[i]
+		}
+	}
+	if nearest != nil {
+		summary.NearestExpiration = nearest.Date
+		if nearest.ImpliedVolatility > 0 {
+			summary.ImpliedVolatility = models.NewNullFloat64(nearest.ImpliedVolatility)
+		}
+	}
+	summary.PutCallRatio = ratio(summary.PutVolume, summary.CallVolume)
+	summary.PutCallOpenInterestRatio = ratio(summary.PutOpenInterest, summary.CallOpenInterest)
+	return summary
+}
+
+func ratio(a, b float64) models.NullFloat64 {
+	if b <= 0 {
+		return models.NullFloat64{}
+	}
+	return models.NewNullFloat64(a / b)
+}
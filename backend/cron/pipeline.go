@@ -0,0 +1,289 @@
+package enricher
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/api"
+	"github.com/jannin2/stock-app/backend/clock"
+	"github.com/jannin2/stock-app/backend/models"
+	"github.com/jannin2/stock-app/backend/providers"
+)
+
+// An enrichment run is a pipeline of named steps:
+//
+//	feed → normalize → <stock steps> → persist
+//
+// feed fetches the Karenai recommendations, normalize orders them and drops the stocks
+// that are not due, and persist saves each batch and checkpoints the run cursor. Those
+// three frame the run and are always present. Right before persist, the relative strength
+// of each stock against its sector ETF is computed from the price history. The stock steps in between enrich one stock
+// at a time; they are registered with RegisterStep and run in the order listed in
+// config.EnrichmentSteps, so new data sources can be added to the pipeline by
+// registering a step and listing it in ENRICHMENT_STEPS.
+
+// StepFunc enriches a single stock in place. Provider failures should be recorded with
+// StepContext.ProviderError rather than returned: a failing source leaves its fields
+// null but never stops the run. A returned error is recorded the same way under the
+// step name.
+//
+// Several stocks are enriched at once, so a step must be safe for concurrent use.
+type StepFunc func(ctx *StepContext, stock *models.Stock) error
+
+// StepContext is what a step gets besides the stock.
+type StepContext struct {
+	clock       clock.Clock
+	errors      []string                         // Raw provider errors, stored for admins to debug null metrics
+	failures    *runFailures                     // Failures of the whole run, by source and kind
+	rateLimited bool                             // Whether a provider rejected a call for its rate limit
+	quotes      map[string]providers.QuoteResult // Prefetched quotes by ticker; nil to fetch per ticker
+}
+
+// Now returns the enricher's current time, to stamp provenance.
+func (c *StepContext) Now() time.Time {
+	return c.clock.Now()
+}
+
+// ProviderError records a provider failure in the stock's ProviderErrors, e.g.
+// ProviderError("finnhub profile", err), and classifies it for the run (see failures.go).
+func (c *StepContext) ProviderError(source string, err error) {
+	c.errors = append(c.errors, source+": "+err.Error())
+	kind := api.ClassifyError(err)
+	if kind == api.KindRateLimited {
+		c.rateLimited = true
+	}
+	if c.failures != nil {
+		c.failures.record(source, kind, err)
+	}
+}
+
+var (
+	stepsMu sync.RWMutex
+	steps   = map[string]StepFunc{}
+)
+
+// Built-in stock steps, in their default order.
+const (
+	StepQuotes       = "quotes"
+	StepFundamentals = "fundamentals"
+	StepIndicators   = "indicators"
+	StepScore        = "score"
+)
+
+func init() {
+	RegisterStep(StepQuotes, quotesStep)
+	RegisterStep(StepFundamentals, fundamentalsStep)
+	RegisterStep(StepIndicators, indicatorsStep)
+	RegisterStep(StepScore, scoreStep)
+}
+
+// RegisterStep makes fn available as the stock step name. It is meant to be called from
+// an init function and panics if name is empty or already registered.
+func RegisterStep(name string, fn StepFunc) {
+	stepsMu.Lock()
+	defer stepsMu.Unlock()
+	if name == "" || fn == nil {
+		panic("enricher: RegisterStep needs a name and a function")
+	}
+	if _, dup := steps[name]; dup {
+		panic("enricher: step registered twice: " + name)
+	}
+	steps[name] = fn
+}
+
+// RegisteredSteps returns the names of the registered stock steps, sorted.
+func RegisteredSteps() []string {
+	stepsMu.RLock()
+	defer stepsMu.RUnlock()
+	names := make([]string, 0, len(steps))
+	for name := range steps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// namedStep is a stock step resolved from the registry.
+type namedStep struct {
+	name string
+	fn   StepFunc
+}
+
+// ValidateSteps checks that every name in names is a registered stock step.
+func ValidateSteps(names []string) error {
+	_, err := resolveSteps(names)
+	return err
+}
+
+// resolveSteps looks up the stock steps listed in names, in order. It fails on the first
+// name that is not registered, so a typo in ENRICHMENT_STEPS is reported instead of
+// silently skipping a data source.
+func resolveSteps(names []string) ([]namedStep, error) {
+	registered := RegisteredSteps()
+	stepsMu.RLock()
+	defer stepsMu.RUnlock()
+	resolved := make([]namedStep, 0, len(names))
+	for _, name := range names {
+		fn, ok := steps[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown enrichment step %q (registered: %s)", name, strings.Join(registered, ", "))
+		}
+		resolved = append(resolved, namedStep{name: name, fn: fn})
+	}
+	return resolved, nil
+}
+
+// runSteps enriches the stock with each step in order, recording provider failures in
+// failures. quotes holds the quotes prefetched for the quotes step, if any (see
+// prefetchQuotes). It reports whether a provider rejected a call for its rate limit, in
+// which case the stock is worth enriching again once the limit window has passed.
+func (e *Enricher) runSteps(pipeline []namedStep, stock *models.Stock, failures *runFailures, quotes map[string]providers.QuoteResult) bool {
+	log.Printf("Enriching data for ticker: %s", stock.Ticker)
+	if stock.Provenance == nil {
+		stock.Provenance = models.Provenance{}
+	}
+	stock.SetExchange()
+	ctx := &StepContext{clock: e.clock, failures: failures, quotes: quotes}
+	for _, step := range pipeline {
+		if err := step.fn(ctx, stock); err != nil {
+			log.Printf("Enrichment step %s failed for %s: %v", step.name, stock.Ticker, err)
+			ctx.ProviderError(step.name, err)
+		}
+	}
+	stock.ProviderErrors = strings.Join(ctx.errors, "; ")
+	stock.UpdatedAt = e.clock.Now()
+	logEnriched(*stock)
+	return ctx.rateLimited
+}
+
+// quotesStep fills the price fields from the first provider of the quote chain that has
+// them, or from the quotes prefetched for the run.
+func quotesStep(ctx *StepContext, stock *models.Stock) error {
+	ticker := stock.Ticker
+	var quote providers.Quote
+	var quoteSource string
+	var failures []providers.Failure
+	var err error
+	if res, ok := ctx.quotes[ticker]; ok {
+		quote, quoteSource, failures, err = res.Quote, res.Source, res.Failures, res.Err
+	} else {
+		quote, quoteSource, failures, err = providers.FetchQuote(ticker)
+	}
+	for _, f := range failures {
+		ctx.ProviderError(f.Provider+" quote", f.Err)
+	}
+	if err != nil {
+		log.Printf("Error getting quote for %s: %v. Assigning null/default values.", ticker, err)
+		stock.CurrentPrice = 0.0
+		stock.DayCandle = nil
+		stock.PreviousClose = models.NullFloat64{NullFloat64: sql.NullFloat64{Valid: false}}
+		stock.LatestTradingDay = models.NullTime{NullTime: sql.NullTime{Valid: false}}
+		return nil
+	}
+
+	stock.CurrentPrice = quote.Price
+	stock.PreviousClose = models.NullFloat64{NullFloat64: sql.NullFloat64{Float64: quote.PreviousClose, Valid: quote.PreviousClose > 0}}
+	if !quote.LatestTradingDay.IsZero() {
+		stock.LatestTradingDay = models.NullTime{NullTime: sql.NullTime{Time: quote.LatestTradingDay, Valid: true}}
+	} else {
+		stock.LatestTradingDay = models.NullTime{NullTime: sql.NullTime{Valid: false}}
+	}
+	stock.Provenance.Set(quoteSource, ctx.Now(), "current_price", "previous_close", "latest_trading_day")
+	stock.DayCandle = dayCandle(quote)
+
+	log.Printf("Quote for %s from %s: Price: %.2f, Trading Day: %v",
+		ticker, quoteSource, stock.CurrentPrice, stock.LatestTradingDay.Time.Format("2006-01-02"))
+	return nil
+}
+
+// dayCandle returns the rest of the quote's daily candle for the price history, or nil if
+// the provider did not report it.
+func dayCandle(quote providers.Quote) *models.DayCandle {
+	if quote.Open <= 0 && quote.High <= 0 && quote.Low <= 0 {
+		return nil
+	}
+	return &models.DayCandle{Open: quote.Open, High: quote.High, Low: quote.Low, Volume: quote.Volume}
+}
+
+// fundamentalsStep fills the valuation fields from the first provider of the
+// fundamentals chain that has them.
+func fundamentalsStep(ctx *StepContext, stock *models.Stock) error {
+	ticker := stock.Ticker
+	fundamentals, fundamentalsSource, failures, err := providers.FetchFundamentals(ticker)
+	for _, f := range failures {
+		ctx.ProviderError(f.Provider+" fundamentals", f.Err)
+	}
+	if err != nil {
+		log.Printf("Error getting fundamentals for %s: %v. Assigning null values.", ticker, err)
+		stock.PERatio = models.NullFloat64{NullFloat64: sql.NullFloat64{Valid: false}}
+		stock.DividendYield = models.NullFloat64{NullFloat64: sql.NullFloat64{Valid: false}}
+		stock.MarketCapitalization = models.NullFloat64{NullFloat64: sql.NullFloat64{Valid: false}}
+		return nil
+	}
+
+	stock.PERatio = models.NullFloat64{NullFloat64: sql.NullFloat64{Float64: fundamentals.PERatio, Valid: true}}
+	stock.DividendYield = models.NullFloat64{NullFloat64: sql.NullFloat64{Float64: fundamentals.DividendYield, Valid: true}}
+	stock.MarketCapitalization = models.NullFloat64{NullFloat64: sql.NullFloat64{Float64: fundamentals.MarketCapitalization, Valid: true}}
+	stock.Provenance.Set(fundamentalsSource, ctx.Now(), "pe_ratio", "dividend_yield", "market_capitalization")
+
+	log.Printf("Fundamentals for %s from %s: PE: %.2f, Div Yield: %.4f, Market Cap: %.2f",
+		ticker, fundamentalsSource, fundamentals.PERatio, fundamentals.DividendYield, fundamentals.MarketCapitalization)
+	return nil
+}
+
+// indicatorsStep fills the Finnhub sector and the alpha from the first provider of the
+// alpha chain that has it.
+func indicatorsStep(ctx *StepContext, stock *models.Stock) error {
+	ticker := stock.Ticker
+	sector, err := api.GetFinnhubSector(ticker)
+	if err != nil {
+		log.Printf("Error getting sector from Finnhub for %s: %v. Leaving sector empty.", ticker, err)
+		ctx.ProviderError("finnhub profile", err)
+	} else {
+		stock.Sector = sector
+		stock.Provenance.Set(providers.Finnhub, ctx.Now(), "sector")
+	}
+
+	// Providers that do not cover the ticker's exchange are skipped without a failure.
+	alpha, alphaSource, failures, err := providers.FetchAlpha(ticker)
+	for _, f := range failures {
+		ctx.ProviderError(f.Provider+" alpha", f.Err)
+	}
+	if err != nil {
+		log.Printf("Error getting alpha for %s: %v. Assigning null value.", ticker, err)
+		stock.Alpha = models.NullFloat64{NullFloat64: sql.NullFloat64{Valid: false}}
+		return nil
+	}
+	stock.Alpha = models.NullFloat64{NullFloat64: sql.NullFloat64{Float64: alpha, Valid: true}}
+	stock.Provenance.Set(alphaSource, ctx.Now(), "alpha")
+	log.Printf("Alpha for %s from %s: %.4f", ticker, alphaSource, alpha)
+	return nil
+}
+
+// scoreStep computes the recommendation score from the fields filled by the previous steps.
+func scoreStep(_ *StepContext, stock *models.Stock) error {
+	scoreVal := CalculateRecommendationScore(*stock)
+	stock.RecommendationScore = models.NullFloat64{NullFloat64: sql.NullFloat64{Float64: scoreVal, Valid: true}}
+	log.Printf("Recommendation score calculated for %s: %.2f", stock.Ticker, scoreVal)
+	return nil
+}
+
+func logEnriched(stock models.Stock) {
+	tradingDay := "0001-01-01"
+	if stock.LatestTradingDay.Valid {
+		tradingDay = stock.LatestTradingDay.Time.Format("2006-01-02")
+	}
+	log.Printf("Processed and Enriched %s: Price: %.2f, PE: %.2f (Valid: %t), Div Yield: %.4f (Valid: %t), Market Cap: %.2f (Valid: %t), Alpha: %.4f (Valid: %t), Rec Score: %.2f (Valid: %t), Trading Day: %v (Valid: %t)",
+		stock.Ticker, stock.CurrentPrice,
+		stock.PERatio.Float64, stock.PERatio.Valid,
+		stock.DividendYield.Float64, stock.DividendYield.Valid,
+		stock.MarketCapitalization.Float64, stock.MarketCapitalization.Valid,
+		stock.Alpha.Float64, stock.Alpha.Valid,
+		stock.RecommendationScore.Float64, stock.RecommendationScore.Valid,
+		tradingDay, stock.LatestTradingDay.Valid)
+}
@@ -0,0 +1,99 @@
+package enricher
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/analytics"
+	"github.com/jannin2/stock-app/backend/models"
+	"github.com/jannin2/stock-app/backend/providers"
+)
+
+// relativeStrengthHistory is how much price history is loaded to compute the relative
+// strength: the longest period plus a week for its base close.
+const relativeStrengthHistory = (models.RelativeStrengthLong + 7) * 24 * time.Hour
+
+// computeRelativeStrength sets the relative strength of each stock of the batch against
+// its sector ETF, from the stored price history and the price just fetched. The ETF
+// prices are fetched and recorded the first time a batch of the run needs them; benchmarks
+// holds the ETFs already recorded in this run. Stocks without a sector ETF, or whose
+// history does not cover a period yet, get a null relative strength.
+func (e *Enricher) computeRelativeStrength(ctx context.Context, batch []models.Stock, benchmarks map[string]bool) {
+	var tickers []string
+	inBatch := map[string]bool{}
+	for i := range batch {
+		batch[i].RelativeStrength = models.RelativeStrength{}
+		etf := models.SectorETF(batch[i].Sector)
+		if etf == "" {
+			continue
+		}
+		tickers = append(tickers, batch[i].Ticker)
+		if !inBatch[etf] {
+			inBatch[etf] = true
+			tickers = append(tickers, etf)
+			if !benchmarks[etf] {
+				benchmarks[etf] = true
+				e.recordBenchmark(ctx, etf)
+			}
+		}
+	}
+	if len(tickers) == 0 {
+		return
+	}
+
+	now := e.clock.Now()
+	history, err := e.dbClient.GetPriceHistory(ctx, tickers, now.Add(-relativeStrengthHistory))
+	if err != nil {
+		log.Printf("Warning: could not load the price history for relative strength: %v", err)
+		return
+	}
+	for i := range batch {
+		stock := &batch[i]
+		etf := models.SectorETF(stock.Sector)
+		latest, ok := models.PricePointFromStock(*stock, now)
+		if etf == "" || !ok {
+			continue
+		}
+		points := withLatest(history[stock.Ticker], latest)
+		stock.RelativeStrength = models.RelativeStrength{
+			Days30: analytics.RelativeStrength(points, history[etf], latest.TradingDay, models.RelativeStrengthShort),
+			Days90: analytics.RelativeStrength(points, history[etf], latest.TradingDay, models.RelativeStrengthLong),
+		}
+	}
+}
+
+// recordBenchmark fetches the current price of a sector ETF and adds it to the price
+// history. Failures are only logged: the stocks of the sector get a null relative strength.
+func (e *Enricher) recordBenchmark(ctx context.Context, etf string) {
+	quote, _, failures, err := providers.FetchQuote(etf)
+	for _, f := range failures {
+		log.Printf("Warning: %s quote for sector ETF %s: %v", f.Provider, etf, f.Err)
+	}
+	if err != nil {
+		log.Printf("Warning: could not get the price of sector ETF %s: %v", etf, err)
+		return
+	}
+	stock := models.Stock{Ticker: etf, CurrentPrice: quote.Price, DayCandle: dayCandle(quote)}
+	if !quote.LatestTradingDay.IsZero() {
+		stock.LatestTradingDay = models.NullTime{NullTime: sql.NullTime{Time: quote.LatestTradingDay, Valid: true}}
+	}
+	point, ok := models.PricePointFromStock(stock, e.clock.Now())
+	if !ok {
+		return
+	}
+	if err := e.dbClient.RecordPrices(ctx, []models.PricePoint{point}); err != nil {
+		log.Printf("Warning: could not record the price of sector ETF %s: %v", etf, err)
+	}
+}
+
+// withLatest adds latest to points, sorted by day, replacing the point of the same day.
+func withLatest(points []models.PricePoint, latest models.PricePoint) []models.PricePoint {
+	points = append([]models.PricePoint(nil), points...)
+	if n := len(points); n > 0 && points[n-1].TradingDay.Equal(latest.TradingDay) {
+		points[n-1] = latest
+		return points
+	}
+	return append(points, latest)
+}
@@ -0,0 +1,44 @@
+package enricher
+
+import (
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/api"
+	"github.com/jannin2/stock-app/backend/models"
+	"github.com/jannin2/stock-app/backend/providers"
+	"github.com/jannin2/stock-app/backend/sentiment"
+)
+
+// StepSentiment scores recent news headlines. It is not in the default pipeline because it
+// costs one more Finnhub call per stock; list it before "score" in ENRICHMENT_STEPS so that
+// scoring rules can use the sentiment variable.
+const StepSentiment = "sentiment"
+
+func init() {
+	RegisterStep(StepSentiment, sentimentStep)
+}
+
+// sentimentStep fills Sentiment with the rolling lexicon score of the Finnhub headlines
+// published within sentiment.Window. No headlines leaves it null rather than neutral.
+func sentimentStep(ctx *StepContext, stock *models.Stock) error {
+	now := ctx.Now()
+	news, err := api.GetFinnhubCompanyNews(stock.Ticker, now.Add(-sentiment.Window), now)
+	if err != nil {
+		log.Printf("Error getting news from Finnhub for %s: %v. Assigning null sentiment.", stock.Ticker, err)
+		ctx.ProviderError("finnhub news", err)
+		stock.Sentiment = models.NullFloat64{NullFloat64: sql.NullFloat64{Valid: false}}
+		return nil
+	}
+
+	headlines := make([]sentiment.Headline, 0, len(news))
+	for _, n := range news {
+		headlines = append(headlines, sentiment.Headline{Text: n.Headline, PublishedAt: time.Unix(n.Datetime, 0)})
+	}
+	value, ok := sentiment.Rolling(headlines, now)
+	stock.Sentiment = models.NullFloat64{NullFloat64: sql.NullFloat64{Float64: value, Valid: ok}}
+	stock.Provenance.Set(providers.Finnhub, now, "sentiment")
+	log.Printf("Sentiment for %s from %d headlines: %.3f (Valid: %t)", stock.Ticker, len(headlines), value, ok)
+	return nil
+}
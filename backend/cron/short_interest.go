@@ -0,0 +1,38 @@
+package enricher
+
+import (
+	"database/sql"
+	"log"
+
+	"github.com/jannin2/stock-app/backend/models"
+	"github.com/jannin2/stock-app/backend/providers"
+)
+
+// StepShortInterest fills short interest and days to cover through the short interest
+// provider chain (config.ProviderChains). Reports come out twice a month, so it is not in
+// the default pipeline.
+const StepShortInterest = "short_interest"
+
+func init() {
+	RegisterStep(StepShortInterest, shortInterestStep)
+}
+
+func shortInterestStep(ctx *StepContext, stock *models.Stock) error {
+	short, source, failures, err := providers.FetchShortInterest(stock.Ticker, ctx.Now())
+	for _, f := range failures {
+		ctx.ProviderError(f.Provider+" short interest", f.Err)
+	}
+	if err != nil {
+		log.Printf("Error getting short interest for %s: %v. Assigning null values.", stock.Ticker, err)
+		stock.ShortInterest = models.NullFloat64{NullFloat64: sql.NullFloat64{Valid: false}}
+		stock.DaysToCover = models.NullFloat64{NullFloat64: sql.NullFloat64{Valid: false}}
+		return nil
+	}
+
+	stock.ShortInterest = models.NewNullFloat64(short.Shares)
+	stock.DaysToCover = models.NullFloat64{NullFloat64: sql.NullFloat64{Float64: short.DaysToCover, Valid: short.DaysToCover > 0}}
+	stock.Provenance.Set(source, ctx.Now(), "short_interest", "days_to_cover")
+	log.Printf("Short interest for %s from %s settled %s: %.0f shares, %.2f days to cover",
+		stock.Ticker, source, short.SettlementDate.Format("2006-01-02"), short.Shares, short.DaysToCover)
+	return nil
+}
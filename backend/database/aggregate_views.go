@@ -0,0 +1,43 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Vistas materializadas de los agregados pesados. Se crean con las migraciones (ver
+// migrations/) y se recalculan con RefreshAggregateViews al final de cada ejecución del
+// enricher, de modo que los endpoints leen filas ya agregadas en lugar de repetir los
+// GROUP BY en cada petición. Las escrituras fuera del enricher (CRUD, importaciones) se
+// reflejan en la siguiente ejecución. Para cambiar una definición, una migración nueva crea
+// la vista con la versión siguiente en el nombre y borra la anterior.
+const (
+	heatmapView        = "market_heatmap_v1"
+	sectorStatsView    = "sector_stats_v1"
+	brokerageStatsView = "brokerage_stats_v1"
+)
+
+// aggregateViews son las vistas materializadas que se refrescan, en orden.
+var aggregateViews = []string{heatmapView, sectorStatsView, brokerageStatsView}
+
+// RefreshAggregateViews recalcula las vistas materializadas de los agregados. Una vista
+// que falla no impide refrescar las demás; se devuelve el primer error.
+func (c *cockroachDB) RefreshAggregateViews(ctx context.Context) error {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	var firstErr error
+	for _, view := range aggregateViews {
+		start := time.Now()
+		if _, err := c.db.ExecContext(ctx, "REFRESH MATERIALIZED VIEW "+view); err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("error al refrescar la vista materializada '%s': %w", view, err)
+			}
+			continue
+		}
+		log.Printf("DEBUG: vista materializada %s refrescada en %s", view, time.Since(start).Round(time.Millisecond))
+	}
+	return firstErr
+}
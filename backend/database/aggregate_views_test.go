@@ -0,0 +1,45 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+func TestRefreshAggregateViews(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	sdb := NewStockDB(db)
+	// Un fallo no impide refrescar el resto de vistas
+	mock.ExpectExec(regexp.QuoteMeta("REFRESH MATERIALIZED VIEW " + heatmapView)).WillReturnError(errors.New("timeout"))
+	mock.ExpectExec(regexp.QuoteMeta("REFRESH MATERIALIZED VIEW " + sectorStatsView)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("REFRESH MATERIALIZED VIEW " + brokerageStatsView)).WillReturnResult(sqlmock.NewResult(0, 0))
+	if err := sdb.RefreshAggregateViews(context.Background()); err == nil {
+		t.Error("❌ se esperaba el error del refresco del heatmap")
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta("FROM " + brokerageStatsView)).WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"brokerage", "ratings", "upgrades", "downgrades", "target_raises", "target_cuts", "avg_target_upside"}).
+			AddRow("The Goldman Sachs Group", 12, 3, 1, 5, 2, 8.5).
+			AddRow("Barclays", 7, 0, 2, 1, 3, nil))
+	stats, err := sdb.GetBrokerageStats(context.Background(), 2)
+	if err != nil {
+		t.Fatalf("❌ error inesperado: %v", err)
+	}
+	want := models.BrokerageStats{Brokerage: "The Goldman Sachs Group", Ratings: 12, Upgrades: 3, Downgrades: 1, TargetRaises: 5, TargetCuts: 2, AvgTargetUpside: models.NewNullFloat64(8.5)}
+	if len(stats) != 2 || stats[0] != want || stats[1].AvgTargetUpside.Valid {
+		t.Errorf("❌ estadísticas inesperadas: %+v", stats)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("⚠️ expectativas no cumplidas en TestRefreshAggregateViews: %s", err)
+	}
+}
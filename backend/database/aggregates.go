@@ -0,0 +1,133 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// AggregateSampleSize es el número de stocks que lee GetStockAggregates en modo muestreo.
+const AggregateSampleSize = 2000
+
+// aggregateColumns son las columnas de stocks que agregan las consultas de
+// GetStockAggregates.
+var aggregateColumns = "id, COALESCE(NULLIF(sector, ''), 'Unknown') AS sector_key, market_capitalization, " +
+	changePercentExpr + " AS change, pe_ratio, dividend_yield, recommendation_score"
+
+// aggregateQuery agrega por sector las filas de la consulta que recibe. Sin búsqueda ni
+// filtros el resultado es el de la vista materializada sectorStatsView.
+const aggregateQuery = `SELECT sector_key, COUNT(*) AS stock_count, COALESCE(SUM(market_capitalization), 0) AS market_cap,
+        AVG(change) AS avg_change, AVG(pe_ratio) AS avg_pe_ratio, AVG(dividend_yield) AS avg_dividend_yield,
+        AVG(recommendation_score) AS avg_recommendation_score, MAX(id::STRING) AS max_id
+    FROM (%s) s
+    GROUP BY sector_key`
+
+// sectorStatsQuery lee los agregados de todos los stocks de su vista materializada.
+const sectorStatsQuery = `SELECT sector_key, stock_count, market_cap, avg_change, avg_pe_ratio,
+        avg_dividend_yield, avg_recommendation_score, max_id
+    FROM ` + sectorStatsView
+
+// GetStockAggregates resume por sector los stocks que cumplen la búsqueda y los filtros.
+//
+// Con sample, en lugar de recorrer todas las coincidencias lee solo las primeras
+// AggregateSampleSize en el orden de la clave primaria. CockroachDB no admite TABLESAMPLE,
+// pero los IDs son UUID aleatorios: las primeras filas por ID son una muestra uniforme y
+// la posición del último ID leído en el espacio de claves es la fracción de la tabla
+// recorrida, de la que se estima el total. Si hay menos coincidencias que la muestra, el
+// resultado es exacto y no se marca como muestreado.
+//
+// Sin búsqueda ni filtros se lee la vista materializada, exacta y al día de la última
+// ejecución del enricher, con o sin sample.
+func (c *cockroachDB) GetStockAggregates(ctx context.Context, search string, filters StockFilters, sample bool) (models.StockAggregates, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	var query string
+	var args []interface{}
+	if search == "" && filters == (StockFilters{}) {
+		query, sample = sectorStatsQuery, false
+	} else {
+		q := newStockQuery(aggregateColumns).Search(search).Filter(filters)
+		if sample {
+			q.orderBy = " ORDER BY id"
+			q.tail = " LIMIT " + q.arg(AggregateSampleSize)
+		}
+		inner, innerArgs := q.SQL()
+		query, args = fmt.Sprintf(aggregateQuery, inner), innerArgs
+	}
+
+	rows, err := c.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return models.StockAggregates{}, fmt.Errorf("error al agregar los stocks: %w", err)
+	}
+	defer rows.Close()
+
+	var result models.StockAggregates
+	result.Sectors = []models.SectorAggregate{}
+	var lastID string
+	for rows.Next() {
+		var s models.SectorAggregate
+		var change, pe, dividend, score sql.NullFloat64
+		var maxID string
+		if err := rows.Scan(&s.Sector, &s.StockCount, &s.MarketCap, &change, &pe, &dividend, &score, &maxID); err != nil {
+			return models.StockAggregates{}, fmt.Errorf("error al escanear el agregado de un sector: %w", err)
+		}
+		s.AvgChangePercent = models.NullFloat64{NullFloat64: change}
+		s.AvgPERatio = models.NullFloat64{NullFloat64: pe}
+		s.AvgDividendYield = models.NullFloat64{NullFloat64: dividend}
+		s.AvgRecommendationScore = models.NullFloat64{NullFloat64: score}
+		result.Sectors = append(result.Sectors, s)
+		result.Total += s.StockCount
+		if maxID > lastID {
+			lastID = maxID
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return models.StockAggregates{}, fmt.Errorf("error después de iterar los agregados: %w", err)
+	}
+
+	if sample && result.Total >= AggregateSampleSize {
+		if err := scaleSample(&result, lastID); err != nil {
+			return models.StockAggregates{}, err
+		}
+	}
+	sort.Slice(result.Sectors, func(i, j int) bool {
+		a, b := result.Sectors[i], result.Sectors[j]
+		if a.StockCount != b.StockCount {
+			return a.StockCount > b.StockCount
+		}
+		return a.Sector < b.Sector
+	})
+	return result, nil
+}
+
+// scaleSample convierte los recuentos y sumas de una muestra en estimaciones del total.
+// Con n filas y el último ID en la fracción f del espacio de claves, (n-1)/f estima sin
+// sesgo el número de coincidencias (f es el n-ésimo estadístico de orden de una uniforme).
+func scaleSample(result *models.StockAggregates, lastID string) error {
+	id, err := uuid.Parse(lastID)
+	if err != nil {
+		return fmt.Errorf("ID de stock no válido en la muestra: %w", err)
+	}
+	fraction := float64(binary.BigEndian.Uint64(id[:8])) / math.Exp2(64)
+	if fraction == 0 {
+		return nil
+	}
+	n := result.Total
+	estimate := float64(n-1) / fraction
+	scale := estimate / float64(n)
+
+	result.Sampled, result.SampleSize, result.Total = true, n, int(math.Round(estimate))
+	for i := range result.Sectors {
+		s := &result.Sectors[i]
+		s.StockCount = int(math.Round(float64(s.StockCount) * scale))
+		s.MarketCap *= scale
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package database
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestGetStockAggregates(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	sdb := NewStockDB(db)
+	columns := []string{"sector_key", "count", "market_cap", "change", "pe_ratio", "dividend_yield", "score", "max_id"}
+	minESG := 50.0
+
+	// Sin muestreo se agregan todas las coincidencias
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT sector_key, COUNT(*)")).
+		WithArgs("%a%", 50.0).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow("Energy", 1, 900.0, nil, 11.0, 3.5, 4.0, "c0000000-0000-4000-8000-000000000000").
+			AddRow("Technology", 2, 5000.0, 1.5, 30.0, 0.5, 4.5, "80000000-0000-4000-8000-000000000000"))
+	exact, err := sdb.GetStockAggregates(context.Background(), "a", StockFilters{MinESG: &minESG}, false)
+	if err != nil {
+		t.Fatalf("❌ error inesperado: %v", err)
+	}
+	if exact.Sampled || exact.Total != 3 || len(exact.Sectors) != 2 || exact.Sectors[0].Sector != "Technology" || exact.Sectors[1].AvgChangePercent.Valid {
+		t.Errorf("❌ agregados exactos inesperados: %+v", exact)
+	}
+
+	// Con muestreo se leen las primeras filas por ID. El último ID está a un cuarto del
+	// espacio de claves: el total estimado es (2000-1)/0,25.
+	mock.ExpectQuery(regexp.QuoteMeta("ORDER BY id LIMIT $2) s")).
+		WithArgs("%a%", AggregateSampleSize).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow("Technology", 1500, 3000.0, 1.0, 25.0, 0.7, 4.0, "40000000-0000-4000-8000-000000000000").
+			AddRow("Energy", 500, 1000.0, -0.5, 9.0, 4.0, 3.0, "3fffffff-0000-4000-8000-000000000000"))
+	sampled, err := sdb.GetStockAggregates(context.Background(), "a", StockFilters{}, true)
+	if err != nil {
+		t.Fatalf("❌ error inesperado: %v", err)
+	}
+	if !sampled.Sampled || sampled.SampleSize != 2000 || sampled.Total != 7996 {
+		t.Errorf("❌ estimación inesperada: %+v", sampled)
+	}
+	if tech := sampled.Sectors[0]; tech.StockCount != 5997 || tech.AvgPERatio.Float64 != 25 {
+		t.Errorf("❌ sector escalado inesperado: %+v", tech)
+	}
+
+	// Menos coincidencias que la muestra: el resultado ya es exacto
+	mock.ExpectQuery(regexp.QuoteMeta("ORDER BY id LIMIT $2) s")).
+		WithArgs("%a%", AggregateSampleSize).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow("Energy", 40, 100.0, nil, nil, nil, nil, "f0000000-0000-4000-8000-000000000000"))
+	if small, err := sdb.GetStockAggregates(context.Background(), "a", StockFilters{}, true); err != nil || small.Sampled || small.Total != 40 {
+		t.Errorf("❌ una muestra incompleta debería ser exacta: %+v (%v)", small, err)
+	}
+
+	// Sin búsqueda ni filtros se lee la vista materializada, aunque se pida muestreo
+	mock.ExpectQuery(regexp.QuoteMeta(sectorStatsQuery)).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow("Energy", 4000, 100.0, nil, nil, nil, nil, "10000000-0000-4000-8000-000000000000"))
+	if all, err := sdb.GetStockAggregates(context.Background(), "", StockFilters{}, true); err != nil || all.Sampled || all.Total != 4000 {
+		t.Errorf("❌ agregados de la vista inesperados: %+v (%v)", all, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("⚠️ expectativas no cumplidas en TestGetStockAggregates: %s", err)
+	}
+}
@@ -0,0 +1,244 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// ErrAlertNotFound indica que la alerta no existe, está borrada o no es del usuario.
+var ErrAlertNotFound = errors.New("alerta no encontrada")
+
+// metricValueSQL es el valor actual de la métrica metric (una expresión SQL), calculado a
+// partir del stock s. Una métrica desconocida o sin datos da NULL.
+func metricValueSQL(metric string) string {
+	return `(CASE ` + metric + `
+            WHEN 'price' THEN s.current_price
+            WHEN 'change_pct' THEN (s.current_price - s.previous_close) / NULLIF(s.previous_close, 0) * 100
+            WHEN 'pe_ratio' THEN s.pe_ratio
+            WHEN 'dividend_yield' THEN s.dividend_yield
+            WHEN 'recommendation_score' THEN s.recommendation_score
+        END)::FLOAT8`
+}
+
+// alertValueSQL es el valor actual de la métrica de la regla a. Si es NULL la regla no se
+// evalúa.
+var alertValueSQL = metricValueSQL("a.metric")
+
+// alertConditionSQL se cumple si el valor de la métrica cruza el umbral de la regla o, en
+// una regla changes_by, si se ha movido al menos el umbral desde su valor de referencia.
+var alertConditionSQL = `(CASE a.operator
+            WHEN 'above' THEN ` + alertValueSQL + ` > a.threshold
+            WHEN 'below' THEN ` + alertValueSQL + ` < a.threshold
+            WHEN 'changes_by' THEN abs(` + alertValueSQL + ` - a.baseline) >= a.threshold
+        END)`
+
+// fireAlertsSQL dispara las reglas armadas cuya condición se cumple y registra un evento
+// por cada una, todo en una sola sentencia. Una regla changes_by toma como nueva
+// referencia el valor con el que se dispara.
+var fireAlertsSQL = `
+    WITH fired AS (
+        UPDATE alerts AS a SET triggered_at = $1,
+            baseline = CASE WHEN a.operator = 'changes_by' THEN ` + alertValueSQL + ` ELSE a.baseline END
+        FROM stocks AS s
+        WHERE s.ticker = a.ticker AND a.deleted_at IS NULL AND a.triggered_at IS NULL
+            AND ` + alertConditionSQL + `
+        RETURNING a.id, a.user_id, a.ticker, a.metric, a.operator, a.threshold, ` + alertValueSQL + ` AS value
+    )
+    INSERT INTO alert_events (alert_id, user_id, ticker, metric, operator, threshold, value, triggered_at)
+    SELECT id, user_id, ticker, metric, operator, threshold, value, $1 FROM fired
+    RETURNING id, alert_id, user_id, ticker, metric, operator, threshold, value, triggered_at`
+
+// rearmAlertsSQL vuelve a armar las reglas disparadas en evaluaciones anteriores cuya
+// condición ya no se cumple. Si falta el dato (condición NULL) la regla sigue disparada.
+// Las disparadas en esta evaluación ($1) se rearman en la siguiente: una regla changes_by
+// deja de cumplir la condición en cuanto cambia su referencia.
+var rearmAlertsSQL = `
+    UPDATE alerts AS a SET triggered_at = NULL
+    FROM stocks AS s
+    WHERE s.ticker = a.ticker AND a.deleted_at IS NULL AND a.triggered_at < $1
+        AND NOT ` + alertConditionSQL
+
+// seedBaselinesSQL fija el valor de referencia de las reglas changes_by que aún no lo
+// tienen porque, al crearlas, el stock no estaba seguido o la métrica no tenía valor.
+var seedBaselinesSQL = `
+    UPDATE alerts AS a SET baseline = ` + alertValueSQL + `
+    FROM stocks AS s
+    WHERE s.ticker = a.ticker AND a.deleted_at IS NULL AND a.operator = 'changes_by' AND a.baseline IS NULL`
+
+// EvaluateAlerts evalúa todas las reglas de alerta contra los datos actuales de los stocks
+// con dos sentencias sobre el conjunto completo, en lugar de recorrer las reglas en Go.
+// Devuelve las alertas disparadas (registradas con fecha at) y cuántas se rearmaron.
+func (c *cockroachDB) EvaluateAlerts(ctx context.Context, at time.Time) ([]models.AlertEvent, int64, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error al iniciar la transacción de evaluación de alertas: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, fireAlertsSQL, at)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error al disparar las alertas: %w", err)
+	}
+	var fired []models.AlertEvent
+	for rows.Next() {
+		var e models.AlertEvent
+		if err := rows.Scan(&e.ID, &e.AlertID, &e.UserID, &e.Ticker, &e.Metric, &e.Operator, &e.Threshold, &e.Value, &e.TriggeredAt); err != nil {
+			rows.Close()
+			return nil, 0, fmt.Errorf("error al leer las alertas disparadas: %w", err)
+		}
+		fired = append(fired, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error al disparar las alertas: %w", err)
+	}
+
+	res, err := tx.ExecContext(ctx, rearmAlertsSQL, at)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error al rearmar las alertas: %w", err)
+	}
+	rearmed, _ := res.RowsAffected()
+	if _, err := tx.ExecContext(ctx, seedBaselinesSQL); err != nil {
+		return nil, 0, fmt.Errorf("error al fijar la referencia de las alertas: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, 0, fmt.Errorf("error al confirmar la evaluación de alertas: %w", err)
+	}
+	return fired, rearmed, nil
+}
+
+// alertColumns son las columnas que lee scanAlert.
+const alertColumns = "id, ticker, metric, operator, threshold, baseline, triggered_at, created_at"
+
+// createAlertSQL crea una regla para un usuario activo. La referencia de una regla
+// changes_by es el valor actual de la métrica, si el stock ya se sigue.
+var createAlertSQL = `
+    INSERT INTO alerts (user_id, ticker, metric, operator, threshold, baseline)
+    SELECT u.id, a.ticker, a.metric, a.operator, a.threshold,
+        CASE WHEN a.operator = 'changes_by' THEN ` + alertValueSQL + ` END
+    FROM (SELECT $2::VARCHAR AS ticker, $3::TEXT AS metric, $4::TEXT AS operator, $5::FLOAT8 AS threshold) AS a
+    JOIN users AS u ON u.id = $1 AND u.deleted_at IS NULL
+    LEFT JOIN stocks AS s ON s.ticker = a.ticker
+    RETURNING ` + alertColumns
+
+// updateAlertSQL cambia una regla del usuario y la rearma, con una referencia nueva.
+var updateAlertSQL = `
+    UPDATE alerts AS a SET ticker = $3, metric = $4, operator = $5, threshold = $6, triggered_at = NULL,
+        baseline = CASE WHEN $5 = 'changes_by' THEN (SELECT ` + metricValueSQL("$4") + ` FROM stocks AS s WHERE s.ticker = $3) END
+    WHERE a.id = $2 AND a.user_id = $1 AND a.deleted_at IS NULL
+    RETURNING ` + alertColumns
+
+// scanAlert lee una fila con las columnas de alertColumns.
+func scanAlert(row rowScanner, userID uuid.UUID) (models.Alert, error) {
+	a := models.Alert{UserID: userID}
+	var baseline sql.NullFloat64
+	var triggeredAt sql.NullTime
+	if err := row.Scan(&a.ID, &a.Ticker, &a.Metric, &a.Operator, &a.Threshold, &baseline, &triggeredAt, &a.CreatedAt); err != nil {
+		return models.Alert{}, err
+	}
+	if baseline.Valid {
+		a.Baseline = &baseline.Float64
+	}
+	if triggeredAt.Valid {
+		a.TriggeredAt = &triggeredAt.Time
+	}
+	return a, nil
+}
+
+// CreateAlert crea una regla de alerta para un usuario activo.
+func (c *cockroachDB) CreateAlert(ctx context.Context, userID uuid.UUID, alert models.Alert) (models.Alert, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	created, err := scanAlert(c.db.QueryRowContext(ctx, createAlertSQL,
+		userID, alert.Ticker, alert.Metric, alert.Operator, alert.Threshold), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Alert{}, ErrUserNotFound
+	}
+	if err != nil {
+		return models.Alert{}, fmt.Errorf("error al crear la alerta del usuario %s: %w", userID, err)
+	}
+	return created, nil
+}
+
+// ListAlerts devuelve las reglas de alerta del usuario, de la más antigua a la más reciente.
+func (c *cockroachDB) ListAlerts(ctx context.Context, userID uuid.UUID) ([]models.Alert, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	rows, err := c.db.QueryContext(ctx,
+		"SELECT "+alertColumns+" FROM alerts WHERE user_id = $1 AND deleted_at IS NULL ORDER BY created_at", userID)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener las alertas del usuario %s: %w", userID, err)
+	}
+	defer rows.Close()
+
+	alerts := []models.Alert{}
+	for rows.Next() {
+		a, err := scanAlert(rows, userID)
+		if err != nil {
+			return nil, fmt.Errorf("error al leer las alertas del usuario %s: %w", userID, err)
+		}
+		alerts = append(alerts, a)
+	}
+	return alerts, rows.Err()
+}
+
+// GetAlert devuelve una regla de alerta del usuario.
+func (c *cockroachDB) GetAlert(ctx context.Context, userID, alertID uuid.UUID) (models.Alert, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	a, err := scanAlert(c.db.QueryRowContext(ctx,
+		"SELECT "+alertColumns+" FROM alerts WHERE id = $2 AND user_id = $1 AND deleted_at IS NULL", userID, alertID), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Alert{}, ErrAlertNotFound
+	}
+	if err != nil {
+		return models.Alert{}, fmt.Errorf("error al obtener la alerta %s: %w", alertID, err)
+	}
+	return a, nil
+}
+
+// UpdateAlert sustituye el ticker, la métrica, el operador y el umbral de una regla del
+// usuario. La regla vuelve a quedar armada.
+func (c *cockroachDB) UpdateAlert(ctx context.Context, userID, alertID uuid.UUID, alert models.Alert) (models.Alert, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	updated, err := scanAlert(c.db.QueryRowContext(ctx, updateAlertSQL,
+		userID, alertID, alert.Ticker, alert.Metric, alert.Operator, alert.Threshold), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Alert{}, ErrAlertNotFound
+	}
+	if err != nil {
+		return models.Alert{}, fmt.Errorf("error al actualizar la alerta %s: %w", alertID, err)
+	}
+	return updated, nil
+}
+
+// DeleteAlert borra una regla de alerta del usuario. Sus eventos se conservan.
+func (c *cockroachDB) DeleteAlert(ctx context.Context, userID, alertID uuid.UUID) error {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	res, err := c.db.ExecContext(ctx,
+		"UPDATE alerts SET deleted_at = now() WHERE id = $2 AND user_id = $1 AND deleted_at IS NULL", userID, alertID)
+	if err != nil {
+		return fmt.Errorf("error al borrar la alerta %s: %w", alertID, err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrAlertNotFound
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+func TestEvaluateAlerts(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	udb := NewUserDB(db)
+
+	at := time.Date(2025, 1, 6, 12, 0, 0, 0, time.UTC)
+	eventID, alertID, userID := uuid.New(), uuid.New(), uuid.New()
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(fireAlertsSQL)).WithArgs(at).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "alert_id", "user_id", "ticker", "metric", "operator", "threshold", "value", "triggered_at"}).
+			AddRow(eventID, alertID, userID, "AAPL", "price", "above", 200.0, 201.5, at))
+	mock.ExpectExec(regexp.QuoteMeta(rearmAlertsSQL)).WithArgs(at).WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec(regexp.QuoteMeta(seedBaselinesSQL)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	fired, rearmed, err := udb.EvaluateAlerts(context.Background(), at)
+	if err != nil || rearmed != 3 || len(fired) != 1 {
+		t.Fatalf("❌ EvaluateAlerts = %+v, %d, %v", fired, rearmed, err)
+	}
+	if e := fired[0]; e.ID != eventID || e.AlertID != alertID || e.UserID != userID || e.Value != 201.5 || !e.TriggeredAt.Equal(at) {
+		t.Errorf("❌ evento inesperado: %+v", e)
+	}
+
+	// Si falla el rearme no se confirma nada
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(fireAlertsSQL)).WithArgs(at).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "alert_id", "user_id", "ticker", "metric", "operator", "threshold", "value", "triggered_at"}))
+	mock.ExpectExec(regexp.QuoteMeta(rearmAlertsSQL)).WithArgs(at).WillReturnError(errors.New("timeout"))
+	mock.ExpectRollback()
+	if _, _, err := udb.EvaluateAlerts(context.Background(), at); err == nil {
+		t.Errorf("❌ se esperaba un error al fallar el rearme")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("⚠️ expectativas no cumplidas en TestEvaluateAlerts: %s", err)
+	}
+}
+
+func TestCreateAndUpdateAlert(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	udb := NewUserDB(db)
+
+	userID, alertID := uuid.New(), uuid.New()
+	now := time.Date(2025, 1, 6, 12, 0, 0, 0, time.UTC)
+	columns := []string{"id", "ticker", "metric", "operator", "threshold", "baseline", "triggered_at", "created_at"}
+	rule := models.Alert{Ticker: "AAPL", Metric: models.AlertMetricRecommendationScore, Operator: models.AlertChangesBy, Threshold: 0.5}
+
+	mock.ExpectQuery(regexp.QuoteMeta(createAlertSQL)).WithArgs(userID, "AAPL", "recommendation_score", "changes_by", 0.5).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(alertID, "AAPL", "recommendation_score", "changes_by", 0.5, 3.2, nil, now))
+	created, err := udb.CreateAlert(context.Background(), userID, rule)
+	if err != nil || created.ID != alertID || created.UserID != userID || created.Baseline == nil || *created.Baseline != 3.2 || created.TriggeredAt != nil {
+		t.Errorf("❌ alerta creada inesperada: %+v (%v)", created, err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(createAlertSQL)).WithArgs(userID, "AAPL", "recommendation_score", "changes_by", 0.5).
+		WillReturnRows(sqlmock.NewRows(columns))
+	if _, err := udb.CreateAlert(context.Background(), userID, rule); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("❌ crear una alerta de un usuario borrado devolvió %v, se esperaba ErrUserNotFound", err)
+	}
+
+	rule.Operator, rule.Threshold = models.AlertAbove, 4
+	mock.ExpectQuery(regexp.QuoteMeta(updateAlertSQL)).WithArgs(userID, alertID, "AAPL", "recommendation_score", "above", 4.0).
+		WillReturnRows(sqlmock.NewRows(columns))
+	if _, err := udb.UpdateAlert(context.Background(), userID, alertID, rule); !errors.Is(err, ErrAlertNotFound) {
+		t.Errorf("❌ actualizar una alerta ajena devolvió %v, se esperaba ErrAlertNotFound", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("⚠️ expectativas no cumplidas en TestCreateAndUpdateAlert: %s", err)
+	}
+}
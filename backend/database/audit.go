@@ -0,0 +1,53 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// RecordAudit añade una entrada al registro de auditoría.
+func (c *cockroachDB) RecordAudit(ctx context.Context, entry models.AuditEntry) error {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	_, err := c.db.ExecContext(ctx,
+		`INSERT INTO audit_log (actor, action, target_user_id, method, path, reason, remote_addr)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		entry.Actor, entry.Action, entry.TargetUserID, entry.Method, entry.Path, entry.Reason, entry.RemoteAddr)
+	if err != nil {
+		return fmt.Errorf("error al registrar la entrada de auditoría %s: %w", entry.Action, err)
+	}
+	return nil
+}
+
+// GetAuditLog devuelve las últimas limit entradas del registro de auditoría, de la más
+// reciente a la más antigua, solo las del usuario indicado si targetUserID no es nil.
+func (c *cockroachDB) GetAuditLog(ctx context.Context, targetUserID *uuid.UUID, limit int) ([]models.AuditEntry, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	rows, err := c.db.QueryContext(ctx,
+		`SELECT id, occurred_at, actor, action, target_user_id, method, path, reason, remote_addr
+        FROM audit_log WHERE ($1::UUID IS NULL OR target_user_id = $1)
+        ORDER BY occurred_at DESC LIMIT $2`, targetUserID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error al consultar el registro de auditoría: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []models.AuditEntry{}
+	for rows.Next() {
+		var e models.AuditEntry
+		if err := rows.Scan(&e.ID, &e.OccurredAt, &e.Actor, &e.Action, &e.TargetUserID, &e.Method, &e.Path, &e.Reason, &e.RemoteAddr); err != nil {
+			return nil, fmt.Errorf("error al escanear el registro de auditoría: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error al iterar el registro de auditoría: %w", err)
+	}
+	return entries, nil
+}
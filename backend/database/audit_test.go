@@ -0,0 +1,43 @@
+package database
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+func TestAuditLog(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	udb := NewUserDB(db)
+
+	userID := uuid.New()
+	entry := models.AuditEntry{Actor: "soporte", Action: models.AuditImpersonation, TargetUserID: &userID, Method: "GET", Path: "/api/v1/me/data", Reason: "Ticket #123", RemoteAddr: "10.0.0.1:5000"}
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO audit_log (actor, action, target_user_id, method, path, reason, remote_addr)")).
+		WithArgs("soporte", models.AuditImpersonation, &userID, "GET", "/api/v1/me/data", "Ticket #123", "10.0.0.1:5000").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	columns := []string{"id", "occurred_at", "actor", "action", "target_user_id", "method", "path", "reason", "remote_addr"}
+	selectAudit := regexp.QuoteMeta("FROM audit_log WHERE ($1::UUID IS NULL OR target_user_id = $1)")
+	mock.ExpectQuery(selectAudit).WithArgs(&userID, 50).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(uuid.New().String(), time.Now(), "soporte", models.AuditImpersonation, userID.String(), "GET", "/api/v1/me/data", "Ticket #123", "10.0.0.1:5000"))
+
+	if err := udb.RecordAudit(context.Background(), entry); err != nil {
+		t.Errorf("❌ error inesperado al auditar: %v", err)
+	}
+	entries, err := udb.GetAuditLog(context.Background(), &userID, 50)
+	if err != nil || len(entries) != 1 || *entries[0].TargetUserID != userID {
+		t.Errorf("❌ GetAuditLog = %+v, %v", entries, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("⚠️ expectativas no cumplidas en TestAuditLog: %s", err)
+	}
+}
@@ -0,0 +1,44 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// brokerageStatsQuery lee las estadísticas de su vista materializada, empezando por las
+// casas con más recomendaciones.
+const brokerageStatsQuery = `SELECT brokerage, ratings, upgrades, downgrades, target_raises, target_cuts, avg_target_upside
+    FROM ` + brokerageStatsView + `
+    ORDER BY ratings DESC, brokerage ASC
+    LIMIT $1`
+
+// GetBrokerageStats devuelve las estadísticas de las limit casas de análisis con más
+// recomendaciones, al día de la última ejecución del enricher.
+func (c *cockroachDB) GetBrokerageStats(ctx context.Context, limit int) ([]models.BrokerageStats, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	rows, err := c.db.QueryContext(ctx, brokerageStatsQuery, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error al consultar las estadísticas por casa de análisis: %w", err)
+	}
+	defer rows.Close()
+
+	stats := []models.BrokerageStats{}
+	for rows.Next() {
+		var s models.BrokerageStats
+		var upside sql.NullFloat64
+		if err := rows.Scan(&s.Brokerage, &s.Ratings, &s.Upgrades, &s.Downgrades, &s.TargetRaises, &s.TargetCuts, &upside); err != nil {
+			return nil, fmt.Errorf("error al escanear las estadísticas de una casa de análisis: %w", err)
+		}
+		s.AvgTargetUpside = models.NullFloat64{NullFloat64: upside}
+		stats = append(stats, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error después de iterar las estadísticas por casa de análisis: %w", err)
+	}
+	return stats, nil
+}
@@ -0,0 +1,131 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/jannin2/stock-app/backend/config"
+)
+
+// RetryConfig controla los reintentos de conexión a la base de datos.
+type RetryConfig struct {
+	InitialBackoff time.Duration // Espera tras el primer intento fallido
+	MaxBackoff     time.Duration // Tope de la espera entre intentos
+	MaxAttempts    int           // 0 = reintentar hasta que se cancele el contexto
+}
+
+// DefaultRetryConfig reintenta indefinidamente con backoff exponencial de 500ms a 30s,
+// suficiente para esperar a que Postgres/CockroachDB arranque en docker-compose.
+var DefaultRetryConfig = RetryConfig{
+	InitialBackoff: 500 * time.Millisecond,
+	MaxBackoff:     30 * time.Second,
+}
+
+// OpenDB crea el pool de conexiones, con los tamaños de pool, sin comprobar que la base de
+// datos esté disponible. database/sql abre las conexiones bajo demanda y reemplaza las
+// rotas, así que el mismo *sql.DB sigue siendo válido cuando la base de datos vuelve tras
+// una caída.
+//
+// El driver es pgx/v5 a través de database/sql. Cada conexión prepara y guarda en caché
+// hasta pool.StatementCacheSize sentencias, así que una consulta repetida se planifica una
+// sola vez por conexión; con 0 cada consulta se describe y ejecuta sin caché.
+func OpenDB(connStr string, pool config.DBPoolConfig) (*sql.DB, error) {
+	connConfig, err := pgx.ParseConfig(connStr)
+	if err != nil {
+		return nil, fmt.Errorf("error al interpretar la cadena de conexión a la base de datos: %w", err)
+	}
+	if pool.StatementCacheSize > 0 {
+		connConfig.DefaultQueryExecMode = pgx.QueryExecModeCacheStatement
+		connConfig.StatementCacheCapacity = pool.StatementCacheSize
+	} else {
+		connConfig.DefaultQueryExecMode = pgx.QueryExecModeDescribeExec
+		connConfig.StatementCacheCapacity = 0
+	}
+
+	db := stdlib.OpenDB(*connConfig)
+	ConfigurePool(db, pool)
+	return db, nil
+}
+
+// WaitForDB hace ping a la base de datos hasta que responde, esperando entre intentos
+// con backoff exponencial. Devuelve error si se agotan los intentos o se cancela ctx.
+func WaitForDB(ctx context.Context, db *sql.DB, cfg RetryConfig) error {
+	backoff := cfg.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		err := db.PingContext(ctx)
+		if err == nil {
+			if attempt > 1 {
+				log.Printf("Base de datos disponible tras %d intentos.", attempt)
+			}
+			return nil
+		}
+		if cfg.MaxAttempts > 0 && attempt >= cfg.MaxAttempts {
+			return fmt.Errorf("error al conectar con la base de datos tras %d intentos: %w", attempt, err)
+		}
+
+		log.Printf("⚠️ Base de datos no disponible (intento %d): %v. Reintentando en %s", attempt, err, backoff)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("espera de la base de datos cancelada: %w", ctx.Err())
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if cfg.MaxBackoff > 0 && backoff > cfg.MaxBackoff {
+			backoff = cfg.MaxBackoff
+		}
+	}
+}
+
+// Readiness indica si la base de datos está lista para atender peticiones.
+// Es seguro para uso concurrente.
+type Readiness struct {
+	ready atomic.Bool
+}
+
+// Ready indica si la base de datos respondió en la última comprobación.
+func (r *Readiness) Ready() bool {
+	return r.ready.Load()
+}
+
+// SetReady marca la base de datos como disponible o no disponible.
+func (r *Readiness) SetReady(ready bool) {
+	r.ready.Store(ready)
+}
+
+// Monitor hace ping a la base de datos cada interval y actualiza el estado de r hasta que
+// se cancela ctx. Las transiciones (caída y recuperación) se registran en el log.
+func (r *Readiness) Monitor(ctx context.Context, db *sql.DB, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.check(ctx, db, interval)
+		}
+	}
+}
+
+// check hace un único ping con un timeout de interval y actualiza el estado.
+func (r *Readiness) check(ctx context.Context, db *sql.DB, timeout time.Duration) {
+	pingCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err := db.PingContext(pingCtx)
+	wasReady := r.ready.Swap(err == nil)
+	switch {
+	case err != nil && wasReady:
+		log.Printf("❌ Se perdió la conexión con la base de datos: %v", err)
+	case err == nil && !wasReady:
+		log.Println("✅ Conexión con la base de datos restablecida.")
+	}
+}
@@ -0,0 +1,69 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestWaitForDB_RetriesUntilReachable(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectPing().WillReturnError(errors.New("connection refused"))
+	mock.ExpectPing().WillReturnError(errors.New("connection refused"))
+	mock.ExpectPing()
+
+	cfg := RetryConfig{InitialBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond}
+	if err := WaitForDB(context.Background(), db, cfg); err != nil {
+		t.Errorf("❌ se esperaba conectar tras reintentar, error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("⚠️ expectativas no cumplidas en TestWaitForDB_RetriesUntilReachable: %s", err)
+	}
+}
+
+func TestWaitForDB_GivesUpAfterMaxAttempts(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectPing().WillReturnError(errors.New("connection refused"))
+	mock.ExpectPing().WillReturnError(errors.New("connection refused"))
+
+	cfg := RetryConfig{InitialBackoff: time.Millisecond, MaxAttempts: 2}
+	if err := WaitForDB(context.Background(), db, cfg); err == nil {
+		t.Error("❌ se esperaba un error tras agotar los intentos")
+	}
+}
+
+func TestReadiness_CheckTracksTransitions(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	r := &Readiness{}
+	r.SetReady(true)
+
+	mock.ExpectPing().WillReturnError(errors.New("connection reset"))
+	r.check(context.Background(), db, time.Second)
+	if r.Ready() {
+		t.Error("❌ se esperaba not ready tras un ping fallido")
+	}
+
+	mock.ExpectPing()
+	r.check(context.Background(), db, time.Second)
+	if !r.Ready() {
+		t.Error("❌ se esperaba ready tras recuperar la conexión")
+	}
+}
@@ -0,0 +1,147 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// ErrTickerExists indica que el ticker nuevo de un renombrado ya tiene su propio stock.
+var ErrTickerExists = errors.New("ya existe un stock con ese ticker")
+
+const insertCorporateActionSQL = `
+    INSERT INTO corporate_actions (ticker, type, effective_date, split_from, split_to, new_ticker, source, recorded_at)
+    VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, $8)
+    ON CONFLICT (ticker, type, effective_date) DO NOTHING;`
+
+// SaveCorporateActions guarda los eventos que no estaban ya registrados y los devuelve: los
+// proveedores repiten los eventos en cada consulta, y solo los nuevos deben aplicarse.
+func (c *cockroachDB) SaveCorporateActions(ctx context.Context, actions []models.CorporateAction) ([]models.CorporateAction, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	if len(actions) == 0 {
+		return nil, nil
+	}
+
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error al iniciar transacción de los eventos corporativos: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, insertCorporateActionSQL)
+	if err != nil {
+		return nil, fmt.Errorf("error al preparar la inserción de eventos corporativos: %w", err)
+	}
+	defer stmt.Close()
+
+	var inserted []models.CorporateAction
+	for _, a := range actions {
+		result, err := stmt.ExecContext(ctx, a.Ticker, a.Type, a.EffectiveDate,
+			a.SplitFrom.NullFloat64, a.SplitTo.NullFloat64, a.NewTicker, a.Source, a.RecordedAt)
+		if err != nil {
+			return nil, fmt.Errorf("error al guardar el evento %s de %s: %w", a.Type, a.Ticker, err)
+		}
+		if n, err := result.RowsAffected(); err == nil && n > 0 {
+			inserted = append(inserted, a)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error al confirmar los eventos corporativos: %w", err)
+	}
+	return inserted, nil
+}
+
+const selectCorporateActionsSQL = `
+    SELECT ticker, type, effective_date, split_from, split_to, COALESCE(new_ticker, ''), source, recorded_at
+    FROM corporate_actions
+    WHERE ticker = $1 OR new_ticker = $1
+    ORDER BY effective_date DESC, type`
+
+// GetCorporateActions devuelve los eventos del ticker, del más reciente al más antiguo.
+// Incluye los cambios de ticker que llevaron a él, así que tras un renombrado se ve de
+// dónde viene el stock.
+func (c *cockroachDB) GetCorporateActions(ctx context.Context, ticker string) ([]models.CorporateAction, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	rows, err := c.db.QueryContext(ctx, selectCorporateActionsSQL, ticker)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener los eventos corporativos de %s: %w", ticker, err)
+	}
+	defer rows.Close()
+
+	actions := []models.CorporateAction{}
+	for rows.Next() {
+		var a models.CorporateAction
+		var splitFrom, splitTo sql.NullFloat64
+		if err := rows.Scan(&a.Ticker, &a.Type, &a.EffectiveDate, &splitFrom, &splitTo, &a.NewTicker, &a.Source, &a.RecordedAt); err != nil {
+			return nil, fmt.Errorf("error al leer un evento corporativo de %s: %w", ticker, err)
+		}
+		a.SplitFrom = models.NullFloat64{NullFloat64: splitFrom}
+		a.SplitTo = models.NullFloat64{NullFloat64: splitTo}
+		actions = append(actions, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error al recorrer los eventos corporativos de %s: %w", ticker, err)
+	}
+	return actions, nil
+}
+
+// renameTickerSQLs mueven a $2 todo lo que guarda el ticker $1: el stock, sus históricos,
+// sus eventos corporativos (salvo el propio cambio de ticker) y las referencias de los
+// usuarios en alertas, notas y watchlists.
+var renameTickerSQLs = []string{
+	`UPDATE stocks SET ticker = $2, updated_at = now() WHERE ticker = $1`,
+	`UPDATE stock_prices SET ticker = $2 WHERE ticker = $1`,
+	`UPDATE stock_mentions SET ticker = $2 WHERE ticker = $1`,
+	`UPDATE options_summaries SET ticker = $2 WHERE ticker = $1`,
+	`UPDATE provider_payloads SET ticker = $2 WHERE ticker = $1`,
+	`UPDATE corporate_actions SET ticker = $2 WHERE ticker = $1 AND new_ticker IS NULL`,
+	`UPDATE alerts SET ticker = $2 WHERE ticker = $1`,
+	`UPDATE notes SET ticker = $2 WHERE ticker = $1`,
+	`UPDATE watchlists SET tickers = array_replace(tickers, $1, $2), updated_at = now() WHERE $1 = ANY (tickers)`,
+}
+
+// RenameTicker cambia el ticker oldTicker por newTicker en una transacción. Falla con
+// ErrTickerExists si newTicker ya tiene su propio stock: fusionar los dos requiere decidir
+// qué datos conservar, y eso lo hace un administrador.
+func (c *cockroachDB) RenameTicker(ctx context.Context, oldTicker, newTicker string) error {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error al iniciar transacción del renombrado de %s: %w", oldTicker, err)
+	}
+	defer tx.Rollback()
+
+	// Bloqueados los dos tickers, ninguna otra escritura puede crear el stock de newTicker o
+	// tocar el de oldTicker entre la comprobación y el renombrado.
+	if err := lockTickers(ctx, tx, []string{oldTicker, newTicker}); err != nil {
+		return err
+	}
+	var exists bool
+	if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM stocks WHERE ticker = $1)`, newTicker).Scan(&exists); err != nil {
+		return fmt.Errorf("error al comprobar el ticker %s: %w", newTicker, err)
+	}
+	if exists {
+		return fmt.Errorf("no se puede renombrar %s a %s: %w", oldTicker, newTicker, ErrTickerExists)
+	}
+
+	for _, query := range renameTickerSQLs {
+		if _, err := tx.ExecContext(ctx, query, oldTicker, newTicker); err != nil {
+			return fmt.Errorf("error al renombrar %s a %s: %w", oldTicker, newTicker, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error al confirmar el renombrado de %s a %s: %w", oldTicker, newTicker, err)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+func TestSaveCorporateActions_ReturnsOnlyNewOnes(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	sdb := NewStockDB(db)
+	day := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+	recordedAt := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	split := models.CorporateAction{Ticker: "NVDA", Type: models.CorporateActionSplit, EffectiveDate: day,
+		SplitFrom: models.NewNullFloat64(1), SplitTo: models.NewNullFloat64(10), Source: "finnhub", RecordedAt: recordedAt}
+	change := models.CorporateAction{Ticker: "FB", Type: models.CorporateActionSymbolChange, EffectiveDate: day,
+		NewTicker: "META", Source: "finnhub", RecordedAt: recordedAt}
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare(regexp.QuoteMeta(insertCorporateActionSQL))
+	mock.ExpectExec(regexp.QuoteMeta(insertCorporateActionSQL)).
+		WithArgs("NVDA", "split", day, split.SplitFrom.NullFloat64, split.SplitTo.NullFloat64, "", "finnhub", recordedAt).
+		WillReturnResult(sqlmock.NewResult(0, 0)) // Ya registrado
+	mock.ExpectExec(regexp.QuoteMeta(insertCorporateActionSQL)).
+		WithArgs("FB", "symbol_change", day, change.SplitFrom.NullFloat64, change.SplitTo.NullFloat64, "META", "finnhub", recordedAt).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	inserted, err := sdb.SaveCorporateActions(context.Background(), []models.CorporateAction{split, change})
+	if err != nil {
+		t.Fatalf("❌ error inesperado al guardar los eventos: %v", err)
+	}
+	if len(inserted) != 1 || inserted[0].NewTicker != "META" {
+		t.Errorf("❌ se esperaba solo el cambio de ticker como nuevo, se obtuvo %+v", inserted)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("⚠️ expectativas no cumplidas en TestSaveCorporateActions_ReturnsOnlyNewOnes: %s", err)
+	}
+}
+
+func TestRenameTicker(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	sdb := NewStockDB(db)
+	exists := regexp.QuoteMeta(`SELECT EXISTS (SELECT 1 FROM stocks WHERE ticker = $1)`)
+
+	mock.ExpectBegin()
+	expectLockTickers(mock, "{FB,META}").WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectQuery(exists).WithArgs("META").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	for _, query := range renameTickerSQLs {
+		mock.ExpectExec(regexp.QuoteMeta(query)).WithArgs("FB", "META").WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectCommit()
+	if err := sdb.RenameTicker(context.Background(), "FB", "META"); err != nil {
+		t.Errorf("❌ error inesperado al renombrar: %v", err)
+	}
+
+	// Si el ticker nuevo ya tiene stock no se toca nada.
+	mock.ExpectBegin()
+	expectLockTickers(mock, "{FB,META}").WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectQuery(exists).WithArgs("META").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectRollback()
+	if err := sdb.RenameTicker(context.Background(), "FB", "META"); !errors.Is(err, ErrTickerExists) {
+		t.Errorf("❌ se esperaba ErrTickerExists, se obtuvo %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("⚠️ expectativas no cumplidas en TestRenameTicker: %s", err)
+	}
+}
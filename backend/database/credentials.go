@@ -0,0 +1,115 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// ErrEmailTaken indica que ya existe una cuenta con ese e-mail.
+var ErrEmailTaken = errors.New("ya existe una cuenta con ese e-mail")
+
+const credentialsColumns = "id, email, email_verified_at, created_at, COALESCE(password_hash, '')"
+
+// CreateUser crea una cuenta sin verificar con el e-mail y el hash de contraseña indicados.
+// Devuelve ErrEmailTaken si el e-mail ya está registrado (aunque la cuenta esté borrada y
+// pendiente de purga).
+func (c *cockroachDB) CreateUser(ctx context.Context, email, passwordHash string) (models.User, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	user := models.User{Email: email}
+	err := c.db.QueryRowContext(ctx,
+		"INSERT INTO users (email, password_hash) VALUES ($1, $2) RETURNING id, created_at", email, passwordHash).
+		Scan(&user.ID, &user.CreatedAt)
+	if isUniqueViolation(err) {
+		return models.User{}, ErrEmailTaken
+	}
+	if err != nil {
+		return models.User{}, fmt.Errorf("error al crear el usuario: %w", err)
+	}
+	return user, nil
+}
+
+// GetCredentialsByEmail devuelve el usuario activo con ese e-mail y su hash de contraseña.
+func (c *cockroachDB) GetCredentialsByEmail(ctx context.Context, email string) (models.UserCredentials, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	return c.getCredentials(ctx, "email = $1", email)
+}
+
+// GetCredentials devuelve el usuario activo con ese ID y su hash de contraseña.
+func (c *cockroachDB) GetCredentials(ctx context.Context, userID uuid.UUID) (models.UserCredentials, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	return c.getCredentials(ctx, "id = $1", userID)
+}
+
+func (c *cockroachDB) getCredentials(ctx context.Context, where string, arg interface{}) (models.UserCredentials, error) {
+	var creds models.UserCredentials
+	err := c.db.QueryRowContext(ctx,
+		"SELECT "+credentialsColumns+" FROM users WHERE "+where+" AND deleted_at IS NULL", arg).
+		Scan(&creds.ID, &creds.Email, &creds.EmailVerifiedAt, &creds.CreatedAt, &creds.PasswordHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.UserCredentials{}, ErrUserNotFound
+	}
+	if err != nil {
+		return models.UserCredentials{}, fmt.Errorf("error al obtener las credenciales del usuario: %w", err)
+	}
+	return creds, nil
+}
+
+// MarkEmailVerified marca como verificado el e-mail del usuario y devuelve cuándo se
+// verificó. Si ya estaba verificado conserva la fecha original.
+func (c *cockroachDB) MarkEmailVerified(ctx context.Context, userID uuid.UUID) (time.Time, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	var verifiedAt time.Time
+	err := c.db.QueryRowContext(ctx,
+		"UPDATE users SET email_verified_at = COALESCE(email_verified_at, now()) WHERE id = $1 AND deleted_at IS NULL RETURNING email_verified_at",
+		userID).Scan(&verifiedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, ErrUserNotFound
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("error al verificar el e-mail del usuario %s: %w", userID, err)
+	}
+	return verifiedAt, nil
+}
+
+// SetAPIKeyHash sustituye la clave de API del usuario; la anterior deja de autenticar.
+func (c *cockroachDB) SetAPIKeyHash(ctx context.Context, userID uuid.UUID, keyHash string) error {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	return c.updateActiveUser(ctx, userID, "api_key_hash = $2", keyHash)
+}
+
+// ResetPassword cambia el hash de contraseña del usuario y revoca su clave de API, para
+// cerrar cualquier sesión abierta por quien conociera la contraseña anterior.
+func (c *cockroachDB) ResetPassword(ctx context.Context, userID uuid.UUID, passwordHash string) error {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	return c.updateActiveUser(ctx, userID, "password_hash = $2, api_key_hash = NULL", passwordHash)
+}
+
+func (c *cockroachDB) updateActiveUser(ctx context.Context, userID uuid.UUID, set string, value string) error {
+	res, err := c.db.ExecContext(ctx,
+		"UPDATE users SET "+set+" WHERE id = $1 AND deleted_at IS NULL", userID, value)
+	if err != nil {
+		return fmt.Errorf("error al actualizar el usuario %s: %w", userID, err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestCreateUser(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	udb := NewUserDB(db)
+
+	insertUser := regexp.QuoteMeta("INSERT INTO users (email, password_hash) VALUES ($1, $2) RETURNING id, created_at")
+	userID := uuid.New()
+	created := time.Date(2025, 1, 6, 12, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(insertUser).WithArgs("ana@example.com", "hash").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(userID.String(), created))
+	mock.ExpectQuery(insertUser).WithArgs("ana@example.com", "hash").
+		WillReturnError(&pgconn.PgError{Code: uniqueViolation})
+
+	user, err := udb.CreateUser(context.Background(), "ana@example.com", "hash")
+	if err != nil || user.ID != userID || user.EmailVerifiedAt != nil {
+		t.Errorf("❌ CreateUser = %+v, %v", user, err)
+	}
+	if _, err := udb.CreateUser(context.Background(), "ana@example.com", "hash"); !errors.Is(err, ErrEmailTaken) {
+		t.Errorf("❌ se esperaba ErrEmailTaken, se obtuvo %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("⚠️ expectativas no cumplidas en TestCreateUser: %s", err)
+	}
+}
+
+func TestResetPassword_RevokesAPIKey(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	udb := NewUserDB(db)
+
+	userID := uuid.New()
+	resetPassword := regexp.QuoteMeta("UPDATE users SET password_hash = $2, api_key_hash = NULL WHERE id = $1 AND deleted_at IS NULL")
+	mock.ExpectExec(resetPassword).WithArgs(userID, "nuevo-hash").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(resetPassword).WithArgs(userID, "nuevo-hash").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := udb.ResetPassword(context.Background(), userID, "nuevo-hash"); err != nil {
+		t.Errorf("❌ error inesperado al cambiar la contraseña: %v", err)
+	}
+	if err := udb.ResetPassword(context.Background(), userID, "nuevo-hash"); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("❌ con la cuenta borrada se esperaba ErrUserNotFound, se obtuvo %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("⚠️ expectativas no cumplidas en TestResetPassword_RevokesAPIKey: %s", err)
+	}
+}
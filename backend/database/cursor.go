@@ -0,0 +1,205 @@
+package database
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// ErrCursorMismatch indica que el cursor se generó con otro orden que el de la consulta.
+var ErrCursorMismatch = errors.New("el cursor no corresponde a este orden")
+
+// StockCursor es la posición en un listado paginado por cursor (keyset): los valores de
+// los campos de orden de la última fila devuelta y su id, que desempata. A diferencia de
+// OFFSET, la página siguiente empieza justo después de esa fila aunque el enricher haya
+// añadido o movido filas entretanto, y la consulta no recorre las filas anteriores.
+type StockCursor struct {
+	Sort   string        `json:"s"` // Orden normalizado con el que se generó, ver keysetSort
+	Values []interface{} `json:"v"`
+	ID     uuid.UUID     `json:"id"`
+}
+
+// Encode devuelve el cursor como token opaco para la URL.
+func (c StockCursor) Encode() string {
+	data, _ := json.Marshal(c) // Solo contiene números, cadenas, nil y un UUID
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeStockCursor interpreta un token de StockCursor.Encode.
+func DecodeStockCursor(token string) (StockCursor, error) {
+	var c StockCursor
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return c, errors.New("cursor inválido")
+	}
+	decoder := json.NewDecoder(strings.NewReader(string(data)))
+	decoder.UseNumber() // Los valores vuelven a la consulta tal cual, sin pasar por float64
+	if err := decoder.Decode(&c); err != nil || c.Sort == "" || c.ID == uuid.Nil {
+		return c, errors.New("cursor inválido")
+	}
+	for i, v := range c.Values {
+		switch value := v.(type) {
+		case json.Number:
+			c.Values[i] = value.String()
+		case string, nil:
+		default:
+			return c, errors.New("cursor inválido")
+		}
+	}
+	return c, nil
+}
+
+// keysetSort valida sortBy y order como ValidateSort y devuelve los campos con su
+// dirección ya resuelta, y el orden normalizado ("campo:dir,...") que se guarda en el
+// cursor. Sin sortBy ordena por ticker. staleness no se admite: depende de la hora de cada
+// consulta, así que su valor en el cursor no sirve para la siguiente.
+func keysetSort(sortBy, order string) ([]sortKey, string, error) {
+	if sortBy == "" {
+		sortBy = "ticker"
+	}
+	if err := ValidateSort(sortBy); err != nil {
+		return nil, "", err
+	}
+	keys := splitSort(sortBy, order)
+	normalized := make([]string, len(keys))
+	for i, key := range keys {
+		if key.order != "desc" {
+			keys[i].order = "asc"
+		}
+		if key.field == "staleness" {
+			return nil, "", errors.New("el orden por staleness no admite paginación por cursor")
+		}
+		normalized[i] = keys[i].field + ":" + keys[i].order
+	}
+	return keys, strings.Join(normalized, ","), nil
+}
+
+// ValidateKeysetSort comprueba que sortBy y order admitan paginación por cursor.
+func ValidateKeysetSort(sortBy, order string) error {
+	_, _, err := keysetSort(sortBy, order)
+	return err
+}
+
+// sortExpr devuelve la expresión SQL de un campo de orden ya validado.
+func sortExpr(field string) string {
+	if expr, ok := computedSortFields[field]; ok {
+		return expr
+	}
+	return field
+}
+
+// Keyset ordena por keys y el id con NULLS LAST en todos los campos, para que el orden
+// sea total y no dependa de dónde coloque los NULL la base de datos, y si after no es nil
+// se queda con las filas posteriores a esa posición.
+func (q *stockQuery) Keyset(keys []sortKey, after *StockCursor) *stockQuery {
+	terms := make([]string, 0, len(keys)+1)
+	for _, key := range keys {
+		terms = append(terms, sortExpr(key.field)+" "+strings.ToUpper(key.order)+" NULLS LAST")
+	}
+	q.orderBy = " ORDER BY " + strings.Join(terms, ", ") + ", id ASC"
+	if after == nil {
+		return q
+	}
+
+	// (k1 posterior) OR (k1 igual AND k2 posterior) OR ... OR (todos iguales AND id mayor)
+	var alternatives, equal []string
+	for i, key := range keys {
+		expr, value := sortExpr(key.field), after.Values[i]
+		if value == nil {
+			// Tras un NULL solo quedan otros NULL, que ya se ordenan por los campos siguientes
+			equal = append(equal, expr+" IS NULL")
+			continue
+		}
+		op := ">"
+		if key.order == "desc" {
+			op = "<"
+		}
+		later := fmt.Sprintf("(%s %s %s OR %s IS NULL)", expr, op, q.arg(value), expr)
+		alternatives = append(alternatives, strings.Join(append(equal[:len(equal):len(equal)], later), " AND "))
+		equal = append(equal, expr+" = "+q.arg(value))
+	}
+	alternatives = append(alternatives, strings.Join(append(equal, "id > "+q.arg(after.ID)), " AND "))
+	return q.Where("(" + strings.Join(alternatives, " OR ") + ")")
+}
+
+// GetStocksByCursor devuelve hasta opts.Limit stocks a continuación de after (desde el
+// principio si es nil) con búsqueda, filtros y orden como GetStocksPage, y el cursor de la
+// página siguiente, o nil si es la última. after debe venir de una consulta con el mismo
+// orden; si no, devuelve ErrCursorMismatch.
+func (c *cockroachDB) GetStocksByCursor(ctx context.Context, opts StockQueryOptions, after *StockCursor) ([]models.Stock, *StockCursor, error) {
+	keys, sort, err := keysetSort(opts.SortBy, opts.Order)
+	if err != nil {
+		return nil, nil, err
+	}
+	if after != nil && (after.Sort != sort || len(after.Values) != len(keys)) {
+		return nil, nil, ErrCursorMismatch
+	}
+
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	// Los valores de orden de cada fila se leen con ella para construir el cursor
+	columns := make([]string, 0, len(keys)+1)
+	for i, key := range keys {
+		columns = append(columns, fmt.Sprintf("%s AS sort_%d", sortExpr(key.field), i))
+	}
+	q := newStockQuery(strings.Join(append(columns, stockColumns), ", "))
+	q.asOf = c.asOfClause()
+	q.Search(opts.Search).Filter(opts.Filters).Keyset(keys, after)
+	q.tail = " LIMIT " + q.arg(opts.Limit+1) // Una de más para saber si hay página siguiente
+	query, args := q.SQL()
+
+	rows, err := c.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error al consultar la página de stocks por cursor: %w", err)
+	}
+	defer rows.Close()
+
+	var stocks []models.Stock
+	var last []interface{}
+	for rows.Next() {
+		values := make([]interface{}, len(keys))
+		dest := make([]interface{}, len(keys))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		s, err := scanStock(rows, dest...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error al escanear fila de stock: %w", err)
+		}
+		if len(stocks) == opts.Limit {
+			// La fila de más: hay página siguiente, que empieza después de la última devuelta
+			next := &StockCursor{Sort: sort, Values: last, ID: stocks[len(stocks)-1].ID}
+			return stocks, next, nil
+		}
+		stocks = append(stocks, s)
+		last = cursorValues(values)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("error después de iterar filas: %w", err)
+	}
+	return stocks, nil, nil
+}
+
+// cursorValues convierte los valores leídos de la base de datos en valores que sobreviven
+// a JSON sin perder precisión: los DECIMAL llegan como []byte y se guardan como cadena.
+func cursorValues(values []interface{}) []interface{} {
+	converted := make([]interface{}, len(values))
+	for i, v := range values {
+		switch value := v.(type) {
+		case []byte:
+			converted[i] = string(value)
+		case float64, string, nil:
+			converted[i] = value
+		default:
+			converted[i] = fmt.Sprint(value)
+		}
+	}
+	return converted
+}
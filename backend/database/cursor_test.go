@@ -0,0 +1,125 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+)
+
+func TestStockCursor_EncodeDecode(t *testing.T) {
+	cursor := StockCursor{Sort: "current_price:desc,ticker:asc", Values: []interface{}{"101.25", 4.5, "AAPL", nil}, ID: uuid.New()}
+	decoded, err := DecodeStockCursor(cursor.Encode())
+	if err != nil {
+		t.Fatalf("❌ error inesperado: %v", err)
+	}
+	// Los números vuelven como cadena para no perder precisión
+	want := []interface{}{"101.25", "4.5", "AAPL", nil}
+	if decoded.Sort != cursor.Sort || decoded.ID != cursor.ID || !reflect.DeepEqual(decoded.Values, want) {
+		t.Errorf("❌ cursor decodificado inesperado: %+v", decoded)
+	}
+
+	for _, token := range []string{"", "%%%", StockCursor{Sort: "ticker:asc"}.Encode(), "eyJzIjoidGlja2VyOmFzYyIsInYiOlt7fV19"} {
+		if _, err := DecodeStockCursor(token); err == nil {
+			t.Errorf("❌ se esperaba un error para el cursor %q", token)
+		}
+	}
+}
+
+func TestKeysetSort(t *testing.T) {
+	keys, normalized, err := keysetSort("target_upside:desc,company", "")
+	if err != nil || normalized != "target_upside:desc,company:asc" || len(keys) != 2 {
+		t.Errorf("❌ orden inesperado: %v %q (%v)", keys, normalized, err)
+	}
+	if _, normalized, _ := keysetSort("", "desc"); normalized != "ticker:desc" {
+		t.Errorf("❌ sin sortBy se esperaba ticker:desc, se obtuvo %q", normalized)
+	}
+	for _, sortBy := range []string{"staleness", "bogus", "ticker:up"} {
+		if err := ValidateKeysetSort(sortBy, ""); err == nil {
+			t.Errorf("❌ se esperaba un error para %q", sortBy)
+		}
+	}
+}
+
+func TestStockQuery_Keyset(t *testing.T) {
+	keys, _, _ := keysetSort("current_price:desc,buzz,ticker", "")
+	id := uuid.New()
+	query, args := newStockQuery("ticker").
+		Keyset(keys, &StockCursor{Values: []interface{}{"101.25", nil, "AAPL"}, ID: id}).
+		SQL()
+
+	want := "SELECT ticker FROM stocks WHERE (" +
+		"(current_price < $1 OR current_price IS NULL)" +
+		" OR current_price = $2 AND buzz IS NULL AND (ticker > $3 OR ticker IS NULL)" +
+		" OR current_price = $2 AND buzz IS NULL AND ticker = $4 AND id > $5)" +
+		" ORDER BY current_price DESC NULLS LAST, buzz ASC NULLS LAST, ticker ASC NULLS LAST, id ASC"
+	if query != want {
+		t.Errorf("❌ consulta inesperada:\n%s\nse esperaba:\n%s", query, want)
+	}
+	if len(args) != 5 || args[0] != "101.25" || args[2] != "AAPL" || args[4] != id {
+		t.Errorf("❌ argumentos inesperados: %v", args)
+	}
+
+	if query, _ := newStockQuery("ticker").Keyset(keys[2:], nil).SQL(); query != "SELECT ticker FROM stocks ORDER BY ticker ASC NULLS LAST, id ASC" {
+		t.Errorf("❌ consulta de la primera página inesperada: %s", query)
+	}
+}
+
+func TestGetStocksByCursor(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	sdb := NewStockDB(db)
+	mockTime := time.Now()
+	columns := []string{"sort_0", "id", "ticker", "company", "brokerage", "action", "rating_from", "rating_to", "target_from", "target_to", "current_price", "pe_ratio", "dividend_yield", "market_capitalization", "alpha", "latest_trading_day", "recommendation_score", "sector", "previous_close", "sentiment", "buzz", "esg_score", "short_interest", "days_to_cover", "relative_strength_30d", "relative_strength_90d", "provider_errors", "provenance", "enrichment_tier", "created_at", "updated_at"}
+	ids := []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+	rows := sqlmock.NewRows(columns)
+	for i, price := range []string{"120.50", "99.00", "99.00"} {
+		rows.AddRow([]byte(price), ids[i].String(), "T"+string(rune('A'+i)), "Company", "", "", "", "", nil, nil, price, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, mockTime, mockTime)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT current_price AS sort_0, "+stockColumns+" FROM stocks WHERE (ticker ILIKE $1 OR company ILIKE $1)"+
+		" ORDER BY current_price DESC NULLS LAST, id ASC LIMIT $2")).
+		WithArgs("%T%", 3).
+		WillReturnRows(rows)
+
+	opts := StockQueryOptions{Search: "T", SortBy: "current_price", Order: "desc", Limit: 2}
+	stocks, next, err := sdb.GetStocksByCursor(context.Background(), opts, nil)
+	if err != nil {
+		t.Fatalf("❌ error inesperado: %v", err)
+	}
+	if len(stocks) != 2 || next == nil {
+		t.Fatalf("❌ se esperaban 2 stocks y cursor siguiente, se obtuvo %d y %v", len(stocks), next)
+	}
+	if next.Sort != "current_price:desc" || next.ID != ids[1] || !reflect.DeepEqual(next.Values, []interface{}{"99.00"}) {
+		t.Errorf("❌ cursor siguiente inesperado: %+v", next)
+	}
+
+	// La página siguiente empieza después de la última fila devuelta; es la última
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT current_price AS sort_0, "+stockColumns+" FROM stocks WHERE (ticker ILIKE $1 OR company ILIKE $1)"+
+		" AND ((current_price < $2 OR current_price IS NULL) OR current_price = $3 AND id > $4)")).
+		WithArgs("%T%", "99.00", "99.00", ids[1], 3).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow([]byte("99.00"), ids[2].String(), "TC", "Company", "", "", "", "", nil, nil, "99.00", nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, mockTime, mockTime))
+
+	stocks, next, err = sdb.GetStocksByCursor(context.Background(), opts, next)
+	if err != nil || len(stocks) != 1 || next != nil {
+		t.Errorf("❌ última página inesperada: %d stocks, cursor %v (%v)", len(stocks), next, err)
+	}
+
+	other := StockCursor{Sort: "ticker:asc", Values: []interface{}{"AAPL"}, ID: ids[0]}
+	if _, _, err := sdb.GetStocksByCursor(context.Background(), opts, &other); !errors.Is(err, ErrCursorMismatch) {
+		t.Errorf("❌ se esperaba ErrCursorMismatch, se obtuvo %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("⚠️ expectativas no cumplidas en TestGetStocksByCursor: %s", err)
+	}
+}
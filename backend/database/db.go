@@ -6,11 +6,13 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/config"
 	"github.com/jannin2/stock-app/backend/models"
-
-	_ "github.com/lib/pq" // PostgreSQL driver
 )
 
 // StockDB interface defines the methods for stock-related database operations.
@@ -21,7 +23,8 @@ var DB *sql.DB
 
 // cockroachDB implements the StockDB interface.
 type cockroachDB struct {
-	db *sql.DB // The actual database connection encapsulated within the struct
+	db   *sql.DB   // The actual database connection encapsulated within the struct
+	asOf time.Time // Instantánea de los listados (ver AsOf); cero para el estado actual
 }
 
 // NewStockDB creates a new instance of StockDB.
@@ -39,15 +42,11 @@ func ConnectDB() (*sql.DB, error) {
 		log.Println("DATABASE_URL no está configurada, usando valor por defecto.")
 	}
 
-	db, err := sql.Open("postgres", connStr) // Use a local variable
+	db, err := OpenDB(connStr, config.Current().DBPool)
 	if err != nil {
-		return nil, fmt.Errorf("error al abrir la conexión a la base de datos: %w", err)
+		return nil, err
 	}
 
-	db.SetMaxOpenConns(20)
-	db.SetMaxIdleConns(10)
-	db.SetConnMaxLifetime(5 * time.Minute)
-
 	if err = db.Ping(); err != nil {
 		db.Close() // Close on ping failure
 		return nil, fmt.Errorf("error al conectar con la base de datos: %w", err)
@@ -65,120 +64,148 @@ func CloseDB(db *sql.DB) {
 	}
 }
 
-// InitSchema inicializa el esquema de la base de datos.
-// This function still uses the global DB.
-func InitSchema(dbConn *sql.DB) error {
-	createTableSQL := `
-    CREATE TABLE IF NOT EXISTS stocks (
-        id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-        ticker VARCHAR(10) NOT NULL UNIQUE,
-        company TEXT,
-        brokerage TEXT,
-        action TEXT,
-        rating_from TEXT,
-        rating_to TEXT,
-        target_from NUMERIC(10, 2)NULL,
-        target_to NUMERIC(10, 2)NULL,
-        current_price DECIMAL(10, 2),
-        pe_ratio DECIMAL(10, 2),
-        dividend_yield DECIMAL(10, 4),
-        market_capitalization DECIMAL(20, 2),
-        alpha DECIMAL(10, 4),
-        latest_trading_day TIMESTAMP WITH TIME ZONE,
-        recommendation_score DECIMAL(5, 2),
-        created_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
-        updated_at TIMESTAMP WITH TIME ZONE DEFAULT now()
-    );`
-
-	_, err := dbConn.Exec(createTableSQL)
-	if err != nil {
-		return fmt.Errorf("error al crear/verificar la tabla 'stocks': %w", err)
-	}
+// --- Métodos de *cockroachDB que implementan la interfaz StockDB ---
 
-	addUniqueConstraintSQL := `
-    ALTER TABLE stocks ADD CONSTRAINT IF NOT EXISTS stocks_ticker_key UNIQUE (ticker);`
+// stockColumns es la lista de columnas que se seleccionan para construir un models.Stock.
+// El orden debe coincidir con el de scanStock.
+const stockColumns = "id, ticker, company, brokerage, action, rating_from, rating_to, target_from, target_to, current_price, pe_ratio, dividend_yield, market_capitalization, alpha, latest_trading_day, recommendation_score, sector, previous_close, sentiment, buzz, esg_score, short_interest, days_to_cover, relative_strength_30d, relative_strength_90d, provider_errors, provenance, enrichment_tier, created_at, updated_at"
 
-	_, err = dbConn.Exec(addUniqueConstraintSQL)
-	if err != nil {
-		log.Printf("Advertencia/Error al añadir o verificar la restricción UNIQUE a 'ticker': %v", err)
-	}
+// rowScanner es implementado tanto por *sql.Row como por *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
 
-	alterTableSQLs := []string{
-		`ALTER TABLE stocks ADD COLUMN IF NOT EXISTS pe_ratio DECIMAL(10, 2);`,
-		`ALTER TABLE stocks ADD COLUMN IF NOT EXISTS dividend_yield DECIMAL(10, 4);`,
-		`ALTER TABLE stocks ADD COLUMN IF NOT EXISTS market_capitalization DECIMAL(20, 2);`,
-		`ALTER TABLE stocks ADD COLUMN IF NOT EXISTS alpha DECIMAL(10, 4);`,
-		`ALTER TABLE stocks ADD COLUMN IF NOT EXISTS recommendation_score DECIMAL(5, 2);`,
-	}
+// scanStock lee una fila con las columnas de stockColumns y la convierte en un models.Stock.
+// Las columnas adicionales (por ejemplo, la clave de un bucket) se pueden pasar en extra
+// y se escanean antes de las columnas del stock.
+func scanStock(row rowScanner, extra ...interface{}) (models.Stock, error) {
+	var s models.Stock
+	var latestTradingDay sql.NullTime
+	var targetFrom, targetTo, peRatio, dividendYield, marketCap, alpha, recScore, previousClose, sentiment, buzz, esgScore, shortInterest, daysToCover, rs30, rs90 sql.NullFloat64
+	var sector, providerErrors, enrichmentTier sql.NullString
 
-	for _, sql := range alterTableSQLs {
-		_, err := dbConn.Exec(sql)
-		if err != nil {
-			log.Printf("Advertencia: No se pudo añadir/alterar columna con SQL: %s, Error: %v", sql, err)
-		}
+	dest := append(extra,
+		&s.ID, &s.Ticker, &s.Company, &s.Brokerage, &s.Action,
+		&s.RatingFrom, &s.RatingTo, &targetFrom, &targetTo, &s.CurrentPrice,
+		&peRatio, &dividendYield, &marketCap, &alpha,
+		&latestTradingDay, &recScore, &sector, &previousClose, &sentiment, &buzz, &esgScore, &shortInterest, &daysToCover, &rs30, &rs90, &providerErrors,
+		&s.Provenance, &enrichmentTier, &s.CreatedAt, &s.UpdatedAt,
+	)
+	if err := row.Scan(dest...); err != nil {
+		return models.Stock{}, err
 	}
 
-	log.Println("Esquema de la base de datos inicializado (tabla 'stocks' y columnas verificadas/creadas).")
-	return nil
+	// Assign to models.Null* types
+	s.TargetFrom = models.NullFloat64{NullFloat64: targetFrom}
+	s.TargetTo = models.NullFloat64{NullFloat64: targetTo}
+	s.PERatio = models.NullFloat64{NullFloat64: peRatio}
+	s.DividendYield = models.NullFloat64{NullFloat64: dividendYield}
+	s.MarketCapitalization = models.NullFloat64{NullFloat64: marketCap}
+	s.Alpha = models.NullFloat64{NullFloat64: alpha}
+	s.LatestTradingDay = models.NullTime{NullTime: latestTradingDay}
+	s.RecommendationScore = models.NullFloat64{NullFloat64: recScore}
+	s.Sector = sector.String
+	s.PreviousClose = models.NullFloat64{NullFloat64: previousClose}
+	s.Sentiment = models.NullFloat64{NullFloat64: sentiment}
+	s.Buzz = models.NullFloat64{NullFloat64: buzz}
+	s.ESGScore = models.NullFloat64{NullFloat64: esgScore}
+	s.ShortInterest = models.NullFloat64{NullFloat64: shortInterest}
+	s.DaysToCover = models.NullFloat64{NullFloat64: daysToCover}
+	s.RelativeStrength.Days30 = models.NullFloat64{NullFloat64: rs30}
+	s.RelativeStrength.Days90 = models.NullFloat64{NullFloat64: rs90}
+	s.ProviderErrors = providerErrors.String
+	s.EnrichmentTier = enrichmentTier.String
+	s.SetExchange()
+
+	return s, nil
 }
 
-// --- Métodos de *cockroachDB que implementan la interfaz StockDB ---
+// GetStockCount returns the total count of stocks, optionally filtered by a search query
+// and the value filters.
+func (c *cockroachDB) GetStockCount(ctx context.Context, searchQuery string, filters StockFilters) (int, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
 
-// GetStockCount returns the total count of stocks, optionally filtered by a search query.
-func (c *cockroachDB) GetStockCount(searchQuery string) (int, error) {
-	query := "SELECT COUNT(*) FROM stocks"
+	query := "SELECT COUNT(*) FROM stocks" + c.asOfClause()
 	args := []interface{}{}
+	var where []string
 	if searchQuery != "" {
-		query += " WHERE ticker ILIKE $1 OR company ILIKE $2"         // FIX: Changed $1 to $2 for company
-		args = append(args, "%"+searchQuery+"%", "%"+searchQuery+"%") // FIX: Added the second argument
+		where = append(where, "(ticker ILIKE $1 OR company ILIKE $2)")
+		args = append(args, "%"+searchQuery+"%", "%"+searchQuery+"%")
+	}
+	where = append(where, filters.conditions(func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	})...)
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
 	}
 
 	var count int
-	err := c.db.QueryRowContext(context.Background(), query, args...).Scan(&count) // Use c.db and context
+	err := c.db.QueryRowContext(ctx, query, args...).Scan(&count) // Use c.db and context
 	if err != nil {
 		return 0, fmt.Errorf("error al obtener el recuento de stocks: %w", err)
 	}
 	return count, nil
 }
 
-// GetAllStocks fetches all stocks from the database with pagination, search, and sorting.
-func (c *cockroachDB) GetAllStocks(opts StockQueryOptions) ([]models.Stock, error) {
-	// First, get the total count for pagination metadata (if needed by your API response)
-	// This call will execute the COUNT(*) query
-	_, err := c.GetStockCount(opts.Search) // Execute GetStockCount here
-	if err != nil {
-		log.Printf("Advertencia: No se pudo obtener el recuento de stocks: %v", err)
-		// Decide if this should be a fatal error or just logged.
-		// For now, it's just a warning, but if count is essential for your API, return error.
+// ApproximateCountThreshold es el número de filas estimadas a partir del cual un listado
+// sin búsqueda ni filtros usa la estimación de las estadísticas en lugar de COUNT(*).
+// Por debajo, el recuento exacto es barato y se prefiere.
+const ApproximateCountThreshold = 10000
+
+// EstimateStockCount devuelve el total de stocks para paginar un listado e indica si es
+// aproximado. Sin búsqueda ni filtros usa pg_class.reltuples, que se mantiene con las
+// estadísticas de la tabla y no la recorre, si la tabla tiene al menos
+// ApproximateCountThreshold filas; en los demás casos, o si la estimación no está
+// disponible, devuelve el recuento exacto de GetStockCount. La estimación no depende de
+// AsOf: las estadísticas no tienen versiones.
+func (c *cockroachDB) EstimateStockCount(ctx context.Context, searchQuery string, filters StockFilters) (int, bool, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	if searchQuery == "" && filters == (StockFilters{}) {
+		var estimate float64
+		err := c.db.QueryRowContext(ctx,
+			"SELECT reltuples FROM pg_class WHERE oid = 'stocks'::REGCLASS").Scan(&estimate)
+		if err != nil {
+			log.Printf("Advertencia: no se pudo estimar el número de stocks, se cuentan: %v", err)
+		} else if estimate >= ApproximateCountThreshold {
+			return int(estimate), true, nil
+		}
 	}
+	count, err := c.GetStockCount(ctx, searchQuery, filters)
+	return count, false, err
+}
+
+// GetAllStocks fetches all stocks from the database with pagination, search, and sorting.
+func (c *cockroachDB) GetAllStocks(ctx context.Context, opts StockQueryOptions) ([]models.Stock, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
 
-	query := "SELECT id, ticker, company, brokerage, action, rating_from, rating_to, target_from, target_to, current_price, pe_ratio, dividend_yield, market_capitalization, alpha, latest_trading_day, recommendation_score, created_at, updated_at FROM stocks"
+	query := "SELECT " + stockColumns + " FROM stocks" + c.asOfClause()
 	args := []interface{}{}
 	argCounter := 1 // Start counter for positional arguments
 
-	// Add search filter
+	// Add search and value filters
+	var where []string
 	if opts.Search != "" {
-		query += fmt.Sprintf(" WHERE ticker ILIKE $%d OR company ILIKE $%d", argCounter, argCounter+1)
+		where = append(where, fmt.Sprintf("(ticker ILIKE $%d OR company ILIKE $%d)", argCounter, argCounter+1))
 		args = append(args, "%"+opts.Search+"%", "%"+opts.Search+"%")
 		argCounter += 2
 	}
+	where = append(where, opts.Filters.conditions(func(v interface{}) string {
+		args = append(args, v)
+		argCounter++
+		return fmt.Sprintf("$%d", argCounter-1)
+	})...)
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
 
-	// Add sorting
+	// Add sorting (columnas o campos calculados, ver sort.go)
 	if opts.SortBy != "" {
-		validSortColumns := map[string]bool{
-			"ticker": true, "company": true, "current_price": true,
-			"action": true, "recommendation_score": true, "pe_ratio": true,
-			"dividend_yield": true, "market_capitalization": true, "alpha": true,
-		}
-		if !validSortColumns[opts.SortBy] {
-			opts.SortBy = "ticker" // Default to a safe column
-		}
-
-		order := "ASC"
-		if opts.Order == "desc" {
-			order = "DESC"
-		}
-		query += fmt.Sprintf(" ORDER BY %s %s", opts.SortBy, order)
+		query += orderByClause(opts.SortBy, opts.Order)
 	} else {
 		query += " ORDER BY ticker ASC" // Default sort
 	}
@@ -186,7 +213,7 @@ func (c *cockroachDB) GetAllStocks(opts StockQueryOptions) ([]models.Stock, erro
 	query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", argCounter, argCounter+1)
 	args = append(args, opts.Limit, opts.Offset)
 
-	rows, err := c.db.QueryContext(context.Background(), query, args...) // Use c.db and context
+	rows, err := c.db.QueryContext(ctx, query, args...) // Use c.db and context
 	if err != nil {
 		return nil, fmt.Errorf("error al consultar todos los stocks: %w", err)
 	}
@@ -194,29 +221,10 @@ func (c *cockroachDB) GetAllStocks(opts StockQueryOptions) ([]models.Stock, erro
 
 	var stocks []models.Stock
 	for rows.Next() {
-		var s models.Stock
-		var latestTradingDay sql.NullTime
-		var targetFrom, targetTo, peRatio, dividendYield, marketCap, alpha, recScore sql.NullFloat64 // Define here for scanning
-		err := rows.Scan(
-			&s.ID, &s.Ticker, &s.Company, &s.Brokerage, &s.Action,
-			&s.RatingFrom, &s.RatingTo, &targetFrom, &targetTo, &s.CurrentPrice,
-			&peRatio, &dividendYield, &marketCap, &alpha,
-			&latestTradingDay, &recScore,
-			&s.CreatedAt, &s.UpdatedAt,
-		)
+		s, err := scanStock(rows)
 		if err != nil {
 			return nil, fmt.Errorf("error al escanear fila de stock: %w", err)
 		}
-		// Assign to models.Null* types
-		s.TargetFrom = models.NullFloat64{NullFloat64: targetFrom}
-		s.TargetTo = models.NullFloat64{NullFloat64: targetTo}
-		s.PERatio = models.NullFloat64{NullFloat64: peRatio}
-		s.DividendYield = models.NullFloat64{NullFloat64: dividendYield}
-		s.MarketCapitalization = models.NullFloat64{NullFloat64: marketCap}
-		s.Alpha = models.NullFloat64{NullFloat64: alpha}
-		s.LatestTradingDay = models.NullTime{NullTime: latestTradingDay}
-		s.RecommendationScore = models.NullFloat64{NullFloat64: recScore}
-
 		stocks = append(stocks, s)
 	}
 
@@ -228,42 +236,37 @@ func (c *cockroachDB) GetAllStocks(opts StockQueryOptions) ([]models.Stock, erro
 }
 
 // GetStockByID fetches a single stock by its ID.
-func (c *cockroachDB) GetStockByID(id string) (models.Stock, error) {
-	query := `SELECT id, ticker, company, brokerage, action, rating_from, rating_to, target_from, target_to, current_price, pe_ratio, dividend_yield, market_capitalization, alpha, latest_trading_day, recommendation_score, created_at, updated_at FROM stocks WHERE id = $1`
-	var s models.Stock
-	var latestTradingDay sql.NullTime
-	var targetFrom, targetTo, peRatio, dividendYield, marketCap, alpha, recScore sql.NullFloat64
+func (c *cockroachDB) GetStockByID(ctx context.Context, id string) (models.Stock, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	// Un ID que no es un UUID no puede existir; sin esta comprobación la base de datos
+	// respondería con un error de conversión en lugar de "no encontrado".
+	if _, err := uuid.Parse(id); err != nil {
+		return models.Stock{}, fmt.Errorf("stock con ID %s: %w", id, ErrStockNotFound)
+	}
 
-	err := c.db.QueryRowContext(context.Background(), query, id).Scan( // Use c.db and context
-		&s.ID, &s.Ticker, &s.Company, &s.Brokerage, &s.Action,
-		&s.RatingFrom, &s.RatingTo, &targetFrom, &targetTo, &s.CurrentPrice,
-		&peRatio, &dividendYield, &marketCap, &alpha,
-		&latestTradingDay, &recScore,
-		&s.CreatedAt, &s.UpdatedAt,
-	)
+	query := "SELECT " + stockColumns + " FROM stocks WHERE id = $1"
+
+	s, err := scanStock(c.db.QueryRowContext(ctx, query, id)) // Use c.db and context
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return models.Stock{}, fmt.Errorf("stock con ID %s no encontrado", id)
+			return models.Stock{}, fmt.Errorf("stock con ID %s: %w", id, ErrStockNotFound)
 		}
 		return models.Stock{}, fmt.Errorf("error al obtener stock por ID %s: %w", id, err)
 	}
-	s.TargetFrom = models.NullFloat64{NullFloat64: targetFrom}
-	s.TargetTo = models.NullFloat64{NullFloat64: targetTo}
-	s.PERatio = models.NullFloat64{NullFloat64: peRatio}
-	s.DividendYield = models.NullFloat64{NullFloat64: dividendYield}
-	s.MarketCapitalization = models.NullFloat64{NullFloat64: marketCap}
-	s.Alpha = models.NullFloat64{NullFloat64: alpha}
-	s.LatestTradingDay = models.NullTime{NullTime: latestTradingDay}
-	s.RecommendationScore = models.NullFloat64{NullFloat64: recScore}
 
 	return s, nil
 }
 
 // GetRecommendedStocks fetches a limited number of stocks ordered by recommendation_score.
-func (c *cockroachDB) GetRecommendedStocks(limit int) ([]models.Stock, error) {
-	query := `SELECT id, ticker, company, brokerage, action, rating_from, rating_to, target_from, target_to, current_price, pe_ratio, dividend_yield, market_capitalization, alpha, latest_trading_day, recommendation_score, created_at, updated_at FROM stocks ORDER BY recommendation_score DESC NULLS LAST LIMIT $1`
+func (c *cockroachDB) GetRecommendedStocks(ctx context.Context, limit int) ([]models.Stock, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	query := "SELECT " + stockColumns + " FROM stocks" + c.asOfClause() + " ORDER BY recommendation_score DESC NULLS LAST LIMIT $1"
 
-	rows, err := c.db.QueryContext(context.Background(), query, limit) // Use c.db and context
+	rows, err := c.db.QueryContext(ctx, query, limit) // Use c.db and context
 	if err != nil {
 		return nil, fmt.Errorf("error al consultar stocks recomendados: %w", err)
 	}
@@ -271,28 +274,10 @@ func (c *cockroachDB) GetRecommendedStocks(limit int) ([]models.Stock, error) {
 
 	var stocks []models.Stock
 	for rows.Next() {
-		var s models.Stock
-		var latestTradingDay sql.NullTime
-		var targetFrom, targetTo, peRatio, dividendYield, marketCap, alpha, recScore sql.NullFloat64
-		err := rows.Scan(
-			&s.ID, &s.Ticker, &s.Company, &s.Brokerage, &s.Action,
-			&s.RatingFrom, &s.RatingTo, &targetFrom, &targetTo, &s.CurrentPrice,
-			&peRatio, &dividendYield, &marketCap, &alpha,
-			&latestTradingDay, &recScore,
-			&s.CreatedAt, &s.UpdatedAt,
-		)
+		s, err := scanStock(rows)
 		if err != nil {
 			return nil, fmt.Errorf("error al escanear fila de stock recomendado: %w", err)
 		}
-		s.TargetFrom = models.NullFloat64{NullFloat64: targetFrom}
-		s.TargetTo = models.NullFloat64{NullFloat64: targetTo}
-		s.PERatio = models.NullFloat64{NullFloat64: peRatio}
-		s.DividendYield = models.NullFloat64{NullFloat64: dividendYield}
-		s.MarketCapitalization = models.NullFloat64{NullFloat64: marketCap}
-		s.Alpha = models.NullFloat64{NullFloat64: alpha}
-		s.LatestTradingDay = models.NullTime{NullTime: latestTradingDay}
-		s.RecommendationScore = models.NullFloat64{NullFloat64: recScore}
-
 		stocks = append(stocks, s)
 	}
 
@@ -304,25 +289,99 @@ func (c *cockroachDB) GetRecommendedStocks(limit int) ([]models.Stock, error) {
 }
 
 // UpsertStocks inserts new stocks or updates existing ones based on their ticker.
-func (c *cockroachDB) UpsertStocks(stocks []models.Stock) error {
+// Concurrent upserts touching the same ticker are serialized per ticker, also across
+// processes (see lockTickers), and rows are always written in ticker order so that overlapping transactions (even from other
+// processes) acquire row locks in the same order and cannot deadlock. Each statement
+// writes up to DBPool.UpsertBatchSize stocks, so thousands of tickers take a handful of
+// round-trips instead of one per stock.
+func (c *cockroachDB) UpsertStocks(ctx context.Context, stocks []models.Stock) error {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
 	if len(stocks) == 0 {
 		return nil // Nothing to upsert
 	}
 
-	tx, err := c.db.BeginTx(context.Background(), nil) // Use c.db and context for transaction
+	// Sort a copy so the caller's slice order is preserved. The sort is stable, so if a
+	// ticker appears twice the later entry is the one kept: a multi-row ON CONFLICT
+	// statement cannot update the same row twice.
+	sorted := append([]models.Stock(nil), stocks...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Ticker < sorted[j].Ticker })
+	stocks = sorted[:0]
+	for i, s := range sorted {
+		if i+1 < len(sorted) && sorted[i+1].Ticker == s.Ticker {
+			continue
+		}
+		stocks = append(stocks, s)
+	}
+
+	tickers := make([]string, len(stocks))
+	for i, s := range stocks {
+		tickers[i] = s.Ticker
+	}
+	tx, err := c.db.BeginTx(ctx, nil) // Use c.db and context for transaction
 	if err != nil {
 		return fmt.Errorf("error al iniciar la transacción para upsert: %w", err)
 	}
 	defer tx.Rollback() // Rollback on error or if commit fails
 
-	stmt, err := tx.PrepareContext(context.Background(), ` // Use context for prepare
+	if err := lockTickers(ctx, tx, tickers); err != nil {
+		return err
+	}
+
+	batchSize := max(config.Current().DBPool.UpsertBatchSize, 1)
+	for start := 0; start < len(stocks); start += batchSize {
+		batch := stocks[start:min(start+batchSize, len(stocks))]
+		args := make([]interface{}, 0, len(batch)*upsertStockParams)
+		for _, s := range batch {
+			args = append(args, upsertStockArgs(s)...)
+		}
+		if _, err := tx.ExecContext(ctx, upsertStocksSQL(len(batch)), args...); err != nil {
+			first, last := batch[0].Ticker, batch[len(batch)-1].Ticker
+			log.Printf("ERROR UPSERT para el lote de %d stocks de %s a %s: %v", len(batch), first, last, err)
+			return fmt.Errorf("error al ejecutar upsert para los tickers de %s a %s: %w", first, last, err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("error al confirmar la transacción upsert: %w", err)
+	}
+
+	return nil
+}
+
+// upsertStockParams es cuántos argumentos ocupa cada stock en upsertStocksSQL (ver
+// upsertStockArgs).
+const upsertStockParams = 26
+
+// upsertStocksSQL devuelve la sentencia que inserta n stocks, o actualiza los existentes
+// con el mismo ticker, con los argumentos de upsertStockArgs de cada uno seguidos.
+func upsertStocksSQL(n int) string {
+	var b strings.Builder
+	b.WriteString(`
         INSERT INTO stocks (
             ticker, company, brokerage, action, rating_from, rating_to,
             target_from, target_to, current_price, pe_ratio, dividend_yield,
-            market_capitalization, alpha, latest_trading_day, recommendation_score, created_at, updated_at
-        ) VALUES (
-            $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, now(), now()
-        )
+            market_capitalization, alpha, latest_trading_day, recommendation_score, sector, previous_close,
+            sentiment, buzz, esg_score, short_interest, days_to_cover, relative_strength_30d, relative_strength_90d,
+            provider_errors, provenance, created_at, updated_at
+        ) VALUES `)
+	for row := 0; row < n; row++ {
+		if row > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString("(")
+		for i := 1; i <= upsertStockParams; i++ {
+			fmt.Fprintf(&b, "$%d, ", row*upsertStockParams+i)
+		}
+		b.WriteString("now(), now())")
+	}
+	b.WriteString(upsertStockConflictSQL)
+	return b.String()
+}
+
+// upsertStockConflictSQL actualiza el stock existente con el mismo ticker.
+const upsertStockConflictSQL = `
         ON CONFLICT (ticker) DO UPDATE SET
             company = EXCLUDED.company,
             brokerage = EXCLUDED.brokerage,
@@ -338,45 +397,43 @@ func (c *cockroachDB) UpsertStocks(stocks []models.Stock) error {
             alpha = EXCLUDED.alpha,
             latest_trading_day = EXCLUDED.latest_trading_day,
             recommendation_score = EXCLUDED.recommendation_score,
+            sector = EXCLUDED.sector,
+            previous_close = EXCLUDED.previous_close,
+            sentiment = EXCLUDED.sentiment,
+            buzz = EXCLUDED.buzz,
+            esg_score = EXCLUDED.esg_score,
+            short_interest = EXCLUDED.short_interest,
+            days_to_cover = EXCLUDED.days_to_cover,
+            relative_strength_30d = EXCLUDED.relative_strength_30d,
+            relative_strength_90d = EXCLUDED.relative_strength_90d,
+            provider_errors = EXCLUDED.provider_errors,
+            provenance = EXCLUDED.provenance,
             updated_at = now();
-    `)
-	if err != nil {
-		return fmt.Errorf("error al preparar la declaración upsert: %w", err)
-	}
-	defer stmt.Close()
-
-	for _, s := range stocks {
-		_, err := stmt.ExecContext(context.Background(), // Use context for exec
-			s.Ticker, s.Company, s.Brokerage, s.Action, s.RatingFrom, s.RatingTo,
-			s.TargetFrom.NullFloat64,
-			s.TargetTo.NullFloat64,
-			s.CurrentPrice,
-			s.PERatio.NullFloat64,
-			s.DividendYield.NullFloat64,
-			s.MarketCapitalization.NullFloat64,
-			s.Alpha.NullFloat64,
-			s.LatestTradingDay.NullTime,
-			s.RecommendationScore.NullFloat64,
-		)
-		if err != nil {
-			log.Printf("ERROR UPSERT para ticker %s: %v. Valores de depuración: TargetFrom.Float64=%.2f (Valid:%t), TargetTo.Float64=%.2f (Valid:%t), CurrentPrice=%.2f, PERatio.Float64=%.2f (Valid:%t), DividendYield.Float64=%.4f (Valid:%t), MarketCapitalization.Float64=%.2f (Valid:%t), Alpha.Float64=%.4f (Valid:%t), LatestTradingDay.Time=%v (Valid:%t), RecommendationScore.Float64=%.2f (Valid:%t)",
-				s.Ticker, err,
-				s.TargetFrom.Float64, s.TargetFrom.Valid,
-				s.TargetTo.Float64, s.TargetTo.Valid,
-				s.CurrentPrice,
-				s.PERatio.Float64, s.PERatio.Valid,
-				s.DividendYield.Float64, s.DividendYield.Valid,
-				s.MarketCapitalization.Float64, s.MarketCapitalization.Valid,
-				s.Alpha.Float64, s.Alpha.Valid,
-				s.LatestTradingDay.Time, s.LatestTradingDay.Valid,
-				s.RecommendationScore.Float64, s.RecommendationScore.Valid)
-			return fmt.Errorf("error al ejecutar upsert para el ticker %s: %w", s.Ticker, err)
-		}
+    `
+
+// upsertStockArgs devuelve los argumentos posicionales de un stock en upsertStocksSQL.
+func upsertStockArgs(s models.Stock) []interface{} {
+	return []interface{}{
+		s.Ticker, s.Company, s.Brokerage, s.Action, s.RatingFrom, s.RatingTo,
+		s.TargetFrom.NullFloat64,
+		s.TargetTo.NullFloat64,
+		s.CurrentPrice,
+		s.PERatio.NullFloat64,
+		s.DividendYield.NullFloat64,
+		s.MarketCapitalization.NullFloat64,
+		s.Alpha.NullFloat64,
+		s.LatestTradingDay.NullTime,
+		s.RecommendationScore.NullFloat64,
+		sql.NullString{String: s.Sector, Valid: s.Sector != ""},
+		s.PreviousClose.NullFloat64,
+		s.Sentiment.NullFloat64,
+		sql.NullInt64{Int64: int64(s.Buzz.Float64), Valid: s.Buzz.Valid},
+		s.ESGScore.NullFloat64,
+		sql.NullInt64{Int64: int64(s.ShortInterest.Float64), Valid: s.ShortInterest.Valid},
+		s.DaysToCover.NullFloat64,
+		s.RelativeStrength.Days30.NullFloat64,
+		s.RelativeStrength.Days90.NullFloat64,
+		sql.NullString{String: s.ProviderErrors, Valid: s.ProviderErrors != ""},
+		s.Provenance,
 	}
-
-	if err = tx.Commit(); err != nil {
-		return fmt.Errorf("error al confirmar la transacción upsert: %w", err)
-	}
-
-	return nil
 }
@@ -1,7 +1,10 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
 
 	"regexp"
 	"testing"
@@ -9,6 +12,7 @@ import (
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/config"
 	"github.com/jannin2/stock-app/backend/models"
 )
 
@@ -28,58 +32,6 @@ func TestConnectDB(t *testing.T) {
 	t.Skip("Skipping ConnectDB test, typically requires real DB or more complex mocking.")
 }
 
-func TestInitSchema(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	if err != nil {
-		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
-	}
-	defer db.Close()
-
-	// Adjust CREATE TABLE SQL regex to match exactly, removing unnecessary leading/trailing newlines for robustness
-	createTableSQL := `CREATE TABLE IF NOT EXISTS stocks (
-        id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-        ticker VARCHAR(10) NOT NULL UNIQUE,
-        company TEXT,
-        brokerage TEXT,
-        action TEXT,
-        rating_from TEXT,
-        rating_to TEXT,
-        target_from NUMERIC(10, 2)NULL,
-        target_to NUMERIC(10, 2)NULL,
-        current_price DECIMAL(10, 2),
-        pe_ratio DECIMAL(10, 2),
-        dividend_yield DECIMAL(10, 4),
-        market_capitalization DECIMAL(20, 2),
-        alpha DECIMAL(10, 4),
-        latest_trading_day TIMESTAMP WITH TIME ZONE,
-        recommendation_score DECIMAL(5, 2),
-        created_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
-        updated_at TIMESTAMP WITH TIME ZONE DEFAULT now()
-    );`
-	mock.ExpectExec(regexp.QuoteMeta(createTableSQL)).WillReturnResult(sqlmock.NewResult(0, 0))
-
-	// Expect the ALTER TABLE ADD CONSTRAINT statement
-	mock.ExpectExec(regexp.QuoteMeta(`ALTER TABLE stocks ADD CONSTRAINT IF NOT EXISTS stocks_ticker_key UNIQUE (ticker);`)).WillReturnResult(sqlmock.NewResult(0, 0))
-
-	// Expect all ALTER TABLE ADD COLUMN statements
-	mock.ExpectExec(regexp.QuoteMeta(`ALTER TABLE stocks ADD COLUMN IF NOT EXISTS pe_ratio DECIMAL(10, 2);`)).WillReturnResult(sqlmock.NewResult(0, 0))
-	mock.ExpectExec(regexp.QuoteMeta(`ALTER TABLE stocks ADD COLUMN IF NOT EXISTS dividend_yield DECIMAL(10, 4);`)).WillReturnResult(sqlmock.NewResult(0, 0))
-	mock.ExpectExec(regexp.QuoteMeta(`ALTER TABLE stocks ADD COLUMN IF NOT EXISTS market_capitalization DECIMAL(20, 2);`)).WillReturnResult(sqlmock.NewResult(0, 0))
-	mock.ExpectExec(regexp.QuoteMeta(`ALTER TABLE stocks ADD COLUMN IF NOT EXISTS alpha DECIMAL(10, 4);`)).WillReturnResult(sqlmock.NewResult(0, 0))
-	mock.ExpectExec(regexp.QuoteMeta(`ALTER TABLE stocks ADD COLUMN IF NOT EXISTS recommendation_score DECIMAL(5, 2);`)).WillReturnResult(sqlmock.NewResult(0, 0))
-
-	// Call InitSchema with the MOCKED database connection
-	err = InitSchema(db)
-	if err != nil {
-		t.Errorf("❌ error inesperado al inicializar el esquema: %v", err)
-	}
-
-	// Ensure all expectations were met
-	if err := mock.ExpectationsWereMet(); err != nil {
-		t.Errorf("⚠️ expectativas no cumplidas en TestInitSchema: %s", err)
-	}
-}
-
 func TestUpsertStocks(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -107,6 +59,7 @@ func TestUpsertStocks(t *testing.T) {
 			Alpha:                newNullFloat64(0.005),
 			LatestTradingDay:     newNullTime(mockTime),
 			RecommendationScore:  newNullFloat64(4.0),
+			Provenance:           models.Provenance{"current_price": {Source: "finnhub"}},
 		},
 		{
 			Ticker:               "TEST2",
@@ -127,59 +80,43 @@ func TestUpsertStocks(t *testing.T) {
 		},
 	}
 
-	// Expect a transaction begin *before* preparing the statement
+	// Both stocks go in a single multi-row statement, after locking their tickers
 	mock.ExpectBegin()
-
-	// The regex for PrepareContext must match the exact string, including comments/newlines
-	expectedSQL := ` // Use context for prepare
-        INSERT INTO stocks (
-            ticker, company, brokerage, action, rating_from, rating_to,
-            target_from, target_to, current_price, pe_ratio, dividend_yield,
-            market_capitalization, alpha, latest_trading_day, recommendation_score, created_at, updated_at
-        ) VALUES (
-            $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, now(), now()
-        )
-        ON CONFLICT (ticker) DO UPDATE SET
-            company = EXCLUDED.company,
-            brokerage = EXCLUDED.brokerage,
-            action = EXCLUDED.action,
-            rating_from = EXCLUDED.rating_from,
-            rating_to = EXCLUDED.rating_to,
-            target_from = EXCLUDED.target_from,
-            target_to = EXCLUDED.target_to,
-            current_price = EXCLUDED.current_price,
-            pe_ratio = EXCLUDED.pe_ratio,
-            dividend_yield = EXCLUDED.dividend_yield,
-            market_capitalization = EXCLUDED.market_capitalization,
-            alpha = EXCLUDED.alpha,
-            latest_trading_day = EXCLUDED.latest_trading_day,
-            recommendation_score = EXCLUDED.recommendation_score,
-            updated_at = now();
-    `
-	mock.ExpectPrepare(regexp.QuoteMeta(expectedSQL))
-
-	// Expect each Exec call for the prepared statement
+	expectLockTickers(mock, "{TEST1,TEST2}").WillReturnResult(sqlmock.NewResult(0, 2))
+	var args []driver.Value
 	for _, s := range testStocks {
-		mock.ExpectExec(regexp.QuoteMeta(expectedSQL)). // Match the prepared statement regex
-								WithArgs(
-				s.Ticker, s.Company, s.Brokerage, s.Action, s.RatingFrom, s.RatingTo,
-				s.TargetFrom.NullFloat64,
-				s.TargetTo.NullFloat64,
-				s.CurrentPrice,
-				s.PERatio.NullFloat64,
-				s.DividendYield.NullFloat64,
-				s.MarketCapitalization.NullFloat64,
-				s.Alpha.NullFloat64,
-				s.LatestTradingDay.NullTime,
-				s.RecommendationScore.NullFloat64,
-			).
-			WillReturnResult(sqlmock.NewResult(1, 1))
+		args = append(args,
+			s.Ticker, s.Company, s.Brokerage, s.Action, s.RatingFrom, s.RatingTo,
+			s.TargetFrom.NullFloat64,
+			s.TargetTo.NullFloat64,
+			s.CurrentPrice,
+			s.PERatio.NullFloat64,
+			s.DividendYield.NullFloat64,
+			s.MarketCapitalization.NullFloat64,
+			s.Alpha.NullFloat64,
+			s.LatestTradingDay.NullTime,
+			s.RecommendationScore.NullFloat64,
+			sql.NullString{String: s.Sector, Valid: s.Sector != ""},
+			s.PreviousClose.NullFloat64,
+			s.Sentiment.NullFloat64,
+			sql.NullInt64{Int64: int64(s.Buzz.Float64), Valid: s.Buzz.Valid},
+			s.ESGScore.NullFloat64,
+			sql.NullInt64{Int64: int64(s.ShortInterest.Float64), Valid: s.ShortInterest.Valid},
+			s.DaysToCover.NullFloat64,
+			s.RelativeStrength.Days30.NullFloat64,
+			s.RelativeStrength.Days90.NullFloat64,
+			sql.NullString{String: s.ProviderErrors, Valid: s.ProviderErrors != ""},
+			s.Provenance,
+		)
 	}
+	mock.ExpectExec(regexp.QuoteMeta(upsertStocksSQL(2))).
+		WithArgs(args...).
+		WillReturnResult(sqlmock.NewResult(2, 2))
 
 	// Expect a commit
 	mock.ExpectCommit()
 
-	err = sdb.UpsertStocks(testStocks)
+	err = sdb.UpsertStocks(context.Background(), testStocks)
 	if err != nil {
 		t.Errorf("❌ error inesperado al upsertar stocks: %v", err)
 	}
@@ -203,30 +140,25 @@ func TestGetAllStocks(t *testing.T) {
 		Offset: 0,
 		SortBy: "ticker",
 		Order:  "asc",
-		Search: "Test Company", // Set a search term to trigger the WHERE clause in GetStockCount
+		Search: "Test Company", // Set a search term to trigger the WHERE clause
 	}
 
-	// FIX: Expect the COUNT(*) query first, as GetStockCount is called first in GetAllStocks
-	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM stocks WHERE ticker ILIKE $1 OR company ILIKE $2")). // Updated to $1 and $2
-															WithArgs("%"+opts.Search+"%", "%"+opts.Search+"%"). // Two arguments for $1 and $2
-															WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
-
-	columns := []string{"id", "ticker", "company", "brokerage", "action", "rating_from", "rating_to", "target_from", "target_to", "current_price", "pe_ratio", "dividend_yield", "market_capitalization", "alpha", "latest_trading_day", "recommendation_score", "created_at", "updated_at"}
+	columns := []string{"id", "ticker", "company", "brokerage", "action", "rating_from", "rating_to", "target_from", "target_to", "current_price", "pe_ratio", "dividend_yield", "market_capitalization", "alpha", "latest_trading_day", "recommendation_score", "sector", "previous_close", "sentiment", "buzz", "esg_score", "short_interest", "days_to_cover", "relative_strength_30d", "relative_strength_90d", "provider_errors", "provenance", "enrichment_tier", "created_at", "updated_at"}
 	mockTime := time.Now()
 
 	rows := sqlmock.NewRows(columns).
-		AddRow(uuid.New().String(), "TEST1", "Test Company 1", "BrokerX", "Buy", "Strong Buy", "Buy", nil, 100.50, 100.00, 20.0, 0.015, 1.0e9, 0.005, mockTime, 4.0, mockTime, mockTime)
+		AddRow(uuid.New().String(), "TEST1", "Test Company 1", "BrokerX", "Buy", "Strong Buy", "Buy", nil, 100.50, 100.00, 20.0, 0.015, 1.0e9, 0.005, mockTime, 4.0, "Technology", 99.5, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, mockTime, mockTime)
 
 	// Then expect the main SELECT query for GetAllStocks
 	mock.ExpectQuery(regexp.QuoteMeta(
-		"SELECT id, ticker, company, brokerage, action, rating_from, rating_to, target_from, target_to, current_price, pe_ratio, dividend_yield, market_capitalization, alpha, latest_trading_day, recommendation_score, created_at, updated_at FROM stocks WHERE ticker ILIKE $1 OR company ILIKE $2 ORDER BY ticker ASC LIMIT $3 OFFSET $4")).
+		"SELECT id, ticker, company, brokerage, action, rating_from, rating_to, target_from, target_to, current_price, pe_ratio, dividend_yield, market_capitalization, alpha, latest_trading_day, recommendation_score, sector, previous_close, sentiment, buzz, esg_score, short_interest, days_to_cover, relative_strength_30d, relative_strength_90d, provider_errors, provenance, enrichment_tier, created_at, updated_at FROM stocks WHERE (ticker ILIKE $1 OR company ILIKE $2) ORDER BY ticker ASC LIMIT $3 OFFSET $4")).
 		WithArgs(
 			"%"+opts.Search+"%", "%"+opts.Search+"%", // Args for search (for $1 and $2)
 			opts.Limit, opts.Offset, // Args for pagination ($3 and $4)
 		).
 		WillReturnRows(rows)
 
-	stocks, err := sdb.GetAllStocks(opts)
+	stocks, err := sdb.GetAllStocks(context.Background(), opts)
 	if err != nil {
 		t.Errorf("❌ error inesperado al obtener stocks: %v", err)
 	}
@@ -253,15 +185,15 @@ func TestGetStockByID(t *testing.T) {
 
 	mockTime := time.Now()
 
-	columns := []string{"id", "ticker", "company", "brokerage", "action", "rating_from", "rating_to", "target_from", "target_to", "current_price", "pe_ratio", "dividend_yield", "market_capitalization", "alpha", "latest_trading_day", "recommendation_score", "created_at", "updated_at"}
+	columns := []string{"id", "ticker", "company", "brokerage", "action", "rating_from", "rating_to", "target_from", "target_to", "current_price", "pe_ratio", "dividend_yield", "market_capitalization", "alpha", "latest_trading_day", "recommendation_score", "sector", "previous_close", "sentiment", "buzz", "esg_score", "short_interest", "days_to_cover", "relative_strength_30d", "relative_strength_90d", "provider_errors", "provenance", "enrichment_tier", "created_at", "updated_at"}
 	rows := sqlmock.NewRows(columns).
-		AddRow(testID.String(), "MSFT", "Microsoft", "BrokerC", "Buy", "Hold", "Buy", nil, nil, 405.10, 32.0, 0.007, 3.2e12, 0.008, mockTime, 4.7, mockTime, mockTime)
+		AddRow(testID.String(), "MSFT", "Microsoft", "BrokerC", "Buy", "Hold", "Buy", nil, nil, 405.10, 32.0, 0.007, 3.2e12, 0.008, mockTime, 4.7, "Technology", 99.5, nil, nil, nil, nil, nil, nil, nil, nil, []byte(`{"pe_ratio":{"source":"alphavantage","fetched_at":"2025-01-06T14:30:00Z"}}`), nil, mockTime, mockTime)
 
-	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, ticker, company, brokerage, action, rating_from, rating_to, target_from, target_to, current_price, pe_ratio, dividend_yield, market_capitalization, alpha, latest_trading_day, recommendation_score, created_at, updated_at FROM stocks WHERE id = $1`)).
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, ticker, company, brokerage, action, rating_from, rating_to, target_from, target_to, current_price, pe_ratio, dividend_yield, market_capitalization, alpha, latest_trading_day, recommendation_score, sector, previous_close, sentiment, buzz, esg_score, short_interest, days_to_cover, relative_strength_30d, relative_strength_90d, provider_errors, provenance, enrichment_tier, created_at, updated_at FROM stocks WHERE id = $1`)).
 		WithArgs(testID.String()).
 		WillReturnRows(rows)
 
-	stock, err := sdb.GetStockByID(testID.String())
+	stock, err := sdb.GetStockByID(context.Background(), testID.String())
 	if err != nil {
 		t.Errorf("❌ error inesperado al obtener stock por ID: %v", err)
 	}
@@ -269,12 +201,37 @@ func TestGetStockByID(t *testing.T) {
 	if stock.ID.String() != testID.String() {
 		t.Errorf("❌ ID de stock inesperado: se esperaba %s, se obtuvo %s", testID.String(), stock.ID.String())
 	}
+	if got := stock.Provenance["pe_ratio"].Source; got != "alphavantage" {
+		t.Errorf("❌ origen de pe_ratio inesperado: se esperaba alphavantage, se obtuvo %q", got)
+	}
 
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("⚠️ expectativas no cumplidas en TestGetStockByID: %s", err)
 	}
 }
 
+func TestGetStockByID_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("❌ no se pudo crear el mock de la base de datos: %v", err)
+	}
+	defer db.Close()
+	sdb := NewStockDB(db)
+
+	missing := uuid.New().String()
+	mock.ExpectQuery(regexp.QuoteMeta("FROM stocks WHERE id = $1")).WithArgs(missing).WillReturnError(sql.ErrNoRows)
+	if _, err := sdb.GetStockByID(context.Background(), missing); !errors.Is(err, ErrStockNotFound) {
+		t.Errorf("❌ un stock inexistente devolvió %v, se esperaba ErrStockNotFound", err)
+	}
+	// Un ID que no es un UUID no llega a consultarse
+	if _, err := sdb.GetStockByID(context.Background(), "no-es-un-uuid"); !errors.Is(err, ErrStockNotFound) {
+		t.Errorf("❌ un ID inválido devolvió %v, se esperaba ErrStockNotFound", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("⚠️ expectativas no cumplidas: %s", err)
+	}
+}
+
 func TestGetRecommendedStocks(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -286,16 +243,16 @@ func TestGetRecommendedStocks(t *testing.T) {
 	limit := 2
 	mockTime := time.Now()
 
-	columns := []string{"id", "ticker", "company", "brokerage", "action", "rating_from", "rating_to", "target_from", "target_to", "current_price", "pe_ratio", "dividend_yield", "market_capitalization", "alpha", "latest_trading_day", "recommendation_score", "created_at", "updated_at"}
+	columns := []string{"id", "ticker", "company", "brokerage", "action", "rating_from", "rating_to", "target_from", "target_to", "current_price", "pe_ratio", "dividend_yield", "market_capitalization", "alpha", "latest_trading_day", "recommendation_score", "sector", "previous_close", "sentiment", "buzz", "esg_score", "short_interest", "days_to_cover", "relative_strength_30d", "relative_strength_90d", "provider_errors", "provenance", "enrichment_tier", "created_at", "updated_at"}
 	rows := sqlmock.NewRows(columns).
-		AddRow(uuid.New().String(), "MSFT", "Microsoft", "BrokerC", "Buy", "Hold", "Buy", nil, nil, 405.10, 32.0, 0.007, 3.2e12, 0.008, mockTime, 4.7, mockTime, mockTime).
-		AddRow(uuid.New().String(), "AAPL", "Apple", "BrokerA", "Buy", "Neutral", "Buy", nil, nil, 195.50, 28.5, 0.005, 3.0e12, 0.01, mockTime, 4.5, mockTime, mockTime)
+		AddRow(uuid.New().String(), "MSFT", "Microsoft", "BrokerC", "Buy", "Hold", "Buy", nil, nil, 405.10, 32.0, 0.007, 3.2e12, 0.008, mockTime, 4.7, "Technology", 99.5, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, mockTime, mockTime).
+		AddRow(uuid.New().String(), "AAPL", "Apple", "BrokerA", "Buy", "Neutral", "Buy", nil, nil, 195.50, 28.5, 0.005, 3.0e12, 0.01, mockTime, 4.5, "Technology", 99.5, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, mockTime, mockTime)
 
-	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, ticker, company, brokerage, action, rating_from, rating_to, target_from, target_to, current_price, pe_ratio, dividend_yield, market_capitalization, alpha, latest_trading_day, recommendation_score, created_at, updated_at FROM stocks ORDER BY recommendation_score DESC NULLS LAST LIMIT $1`)).
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, ticker, company, brokerage, action, rating_from, rating_to, target_from, target_to, current_price, pe_ratio, dividend_yield, market_capitalization, alpha, latest_trading_day, recommendation_score, sector, previous_close, sentiment, buzz, esg_score, short_interest, days_to_cover, relative_strength_30d, relative_strength_90d, provider_errors, provenance, enrichment_tier, created_at, updated_at FROM stocks ORDER BY recommendation_score DESC NULLS LAST LIMIT $1`)).
 		WithArgs(limit).
 		WillReturnRows(rows)
 
-	stocks, err := sdb.GetRecommendedStocks(limit)
+	stocks, err := sdb.GetRecommendedStocks(context.Background(), limit)
 	if err != nil {
 		t.Errorf("❌ error inesperado al obtener stocks recomendados: %v", err)
 	}
@@ -308,3 +265,319 @@ func TestGetRecommendedStocks(t *testing.T) {
 		t.Errorf("⚠️ expectativas no cumplidas en TestGetRecommendedStocks: %s", err)
 	}
 }
+
+func TestGetRecommendedBuckets(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	sdb := NewStockDB(db)
+	perBucket := 2
+	mockTime := time.Now()
+
+	columns := []string{"bucket", "id", "ticker", "company", "brokerage", "action", "rating_from", "rating_to", "target_from", "target_to", "current_price", "pe_ratio", "dividend_yield", "market_capitalization", "alpha", "latest_trading_day", "recommendation_score", "sector", "previous_close", "sentiment", "buzz", "esg_score", "short_interest", "days_to_cover", "relative_strength_30d", "relative_strength_90d", "provider_errors", "provenance", "enrichment_tier", "created_at", "updated_at"}
+	rows := sqlmock.NewRows(columns).
+		AddRow("Healthcare", uuid.New().String(), "PFE", "Pfizer", "BrokerB", "Buy", "Hold", "Buy", nil, nil, 28.10, 12.0, 0.06, 1.6e5, 0.001, mockTime, 3.0, "Healthcare", 99.5, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, mockTime, mockTime).
+		AddRow("Technology", uuid.New().String(), "MSFT", "Microsoft", "BrokerC", "Buy", "Hold", "Buy", nil, nil, 405.10, 32.0, 0.007, 3.2e6, 0.008, mockTime, 4.7, "Technology", 99.5, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, mockTime, mockTime).
+		AddRow("Technology", uuid.New().String(), "AAPL", "Apple", "BrokerA", "Buy", "Neutral", "Buy", nil, nil, 195.50, 28.5, 0.005, 3.0e6, 0.01, mockTime, 4.5, "Technology", 99.5, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, mockTime, mockTime)
+
+	mock.ExpectQuery(`ROW_NUMBER\(\) OVER \(PARTITION BY COALESCE\(NULLIF\(sector, ''\), 'Unknown'\)`).
+		WithArgs(perBucket).
+		WillReturnRows(rows)
+
+	buckets, err := sdb.GetRecommendedBuckets(context.Background(), "sector", perBucket)
+	if err != nil {
+		t.Fatalf("❌ error inesperado al obtener buckets recomendados: %v", err)
+	}
+
+	if len(buckets) != 2 {
+		t.Fatalf("❌ se esperaban 2 buckets, se obtuvieron %d", len(buckets))
+	}
+	if buckets[0].Key != "Healthcare" || len(buckets[0].Stocks) != 1 {
+		t.Errorf("❌ bucket inesperado: %s con %d stocks", buckets[0].Key, len(buckets[0].Stocks))
+	}
+	if buckets[1].Key != "Technology" || len(buckets[1].Stocks) != 2 {
+		t.Errorf("❌ bucket inesperado: %s con %d stocks", buckets[1].Key, len(buckets[1].Stocks))
+	}
+
+	if _, err := sdb.GetRecommendedBuckets(context.Background(), "unknown", perBucket); err == nil {
+		t.Errorf("❌ se esperaba un error para una agrupación no soportada")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("⚠️ expectativas no cumplidas en TestGetRecommendedBuckets: %s", err)
+	}
+}
+
+func TestUpsertStocks_WritesInTickerOrder(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	previous := config.Current()
+	defer config.Set(previous)
+	cfg := config.Default()
+	cfg.DBPool.UpsertBatchSize = 2
+	config.Set(cfg)
+
+	sdb := NewStockDB(db)
+	testStocks := []models.Stock{{Ticker: "ZTS"}, {Ticker: "AAPL", Company: "Old"}, {Ticker: "MSFT"}, {Ticker: "AAPL", Company: "Apple"}}
+
+	// Lotes de 2 en orden de ticker; del AAPL repetido solo se escribe el último.
+	mock.ExpectBegin()
+	expectLockTickers(mock, "{AAPL,MSFT,ZTS}").WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec(regexp.QuoteMeta(upsertStocksSQL(2))).
+		WithArgs(append(append([]driver.Value{"AAPL", "Apple"}, anyArgs(24)...), append([]driver.Value{"MSFT"}, anyArgs(25)...)...)...).
+		WillReturnResult(sqlmock.NewResult(2, 2))
+	mock.ExpectExec(regexp.QuoteMeta(upsertStocksSQL(1))).
+		WithArgs(append([]driver.Value{"ZTS"}, anyArgs(25)...)...).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	if err := sdb.UpsertStocks(context.Background(), testStocks); err != nil {
+		t.Errorf("❌ error inesperado al upsertar stocks: %v", err)
+	}
+	if testStocks[0].Ticker != "ZTS" {
+		t.Errorf("❌ UpsertStocks no debería reordenar el slice del llamador")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("⚠️ expectativas no cumplidas en TestUpsertStocks_WritesInTickerOrder: %s", err)
+	}
+}
+
+// anyArgs devuelve n comodines sqlmock.AnyArg para los argumentos que el test no verifica.
+func anyArgs(n int) []driver.Value {
+	args := make([]driver.Value, n)
+	for i := range args {
+		args[i] = sqlmock.AnyArg()
+	}
+	return args
+}
+
+func TestExportStocks(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	sdb := NewStockDB(db)
+	asOf := time.Unix(0, 1736154000123456789)
+
+	rows := sqlmock.NewRows([]string{"id", "ticker", "company", "brokerage", "action", "rating_from", "rating_to", "target_from", "target_to", "current_price", "pe_ratio", "dividend_yield", "market_capitalization", "alpha", "latest_trading_day", "recommendation_score", "sector", "previous_close", "sentiment", "buzz", "esg_score", "short_interest", "days_to_cover", "relative_strength_30d", "relative_strength_90d", "provider_errors", "provenance", "enrichment_tier", "created_at", "updated_at"}).
+		AddRow(uuid.New(), "MSFT", "Microsoft", "", "", "", "", nil, nil, 410.0, nil, nil, nil, nil, nil, nil, "Technology", nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, time.Now(), time.Now())
+
+	expectedSQL := "SELECT " + stockColumns + " FROM stocks AS OF SYSTEM TIME '1736154000123456789' WHERE ticker > $1 ORDER BY ticker ASC LIMIT $2"
+	mock.ExpectQuery(regexp.QuoteMeta(expectedSQL)).WithArgs("KO", 2).WillReturnRows(rows)
+
+	var tickers []string
+	err = sdb.ExportStocks(context.Background(), asOf, "KO", 2, func(s models.Stock) error {
+		tickers = append(tickers, s.Ticker)
+		return nil
+	})
+	if err != nil {
+		t.Errorf("❌ error inesperado al exportar stocks: %v", err)
+	}
+	if len(tickers) != 1 || tickers[0] != "MSFT" {
+		t.Errorf("❌ se esperaba [MSFT], se obtuvo %v", tickers)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("⚠️ expectativas no cumplidas en TestExportStocks: %s", err)
+	}
+}
+
+func TestGetMarketHeatmap(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	sdb := NewStockDB(db)
+
+	rows := sqlmock.NewRows([]string{"sector_key", "ticker", "company", "market_capitalization", "change", "sector_stock_count", "sector_market_cap", "sector_change"}).
+		AddRow("Technology", "MSFT", "Microsoft", 3000000.0, 1.0, 2, 6000000.0, 1.5).
+		AddRow("Technology", "AAPL", "Apple", 3000000.0, 2.0, 2, 6000000.0, 1.5).
+		AddRow("Unknown", "XYZ", "Xyz Corp", nil, nil, 1, 0.0, nil)
+	mock.ExpectQuery(regexp.QuoteMeta(heatmapQuery)).WillReturnRows(rows)
+
+	sectors, err := sdb.GetMarketHeatmap(context.Background())
+	if err != nil {
+		t.Fatalf("❌ error inesperado al obtener el heatmap: %v", err)
+	}
+
+	if len(sectors) != 2 {
+		t.Fatalf("❌ se esperaban 2 sectores, se obtuvieron %d", len(sectors))
+	}
+	tech := sectors[0]
+	if tech.Sector != "Technology" || len(tech.Stocks) != 2 || tech.StockCount != 2 || tech.ChangePercent.Float64 != 1.5 {
+		t.Errorf("❌ sector Technology inesperado: %+v", tech)
+	}
+	if sectors[1].ChangePercent.Valid || sectors[1].Stocks[0].MarketCap.Valid {
+		t.Errorf("❌ se esperaban valores nulos en el sector Unknown: %+v", sectors[1])
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("⚠️ expectativas no cumplidas en TestGetMarketHeatmap: %s", err)
+	}
+}
+
+func TestRecordPricesAndGetPriceHistory(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	sdb := NewStockDB(db)
+	day := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+
+	// Todo el lote en una sentencia; el precio repetido de AAPL se queda con el último.
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(upsertPricesSQL)).
+		WithArgs("{AAPL,MSFT}", "{2025-01-06,2025-01-06}", "{185.5,410}",
+			"{184,0}", "{186,0}", "{183.2,0}", "{52000000,0}").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	points := []models.PricePoint{
+		{Ticker: "AAPL", TradingDay: day, Close: 184},
+		{Ticker: "MSFT", TradingDay: day, Close: 410},
+		{Ticker: "AAPL", TradingDay: day.Add(20 * time.Hour), Close: 185.5, DayCandle: models.DayCandle{Open: 184, High: 186, Low: 183.2, Volume: 52000000}},
+	}
+	if err := sdb.RecordPrices(context.Background(), points); err != nil {
+		t.Errorf("❌ error inesperado al guardar precios: %v", err)
+	}
+
+	rows := sqlmock.NewRows([]string{"ticker", "trading_day", "close"}).
+		AddRow("AAPL", day, 185.3000030517578). // Un REAL leído como float64
+		AddRow("AAPL", day.AddDate(0, 0, 1), 187.0).
+		AddRow("MSFT", day, 410.0)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT ticker, trading_day, close FROM stock_prices")).
+		WithArgs(sqlmock.AnyArg(), day).
+		WillReturnRows(rows)
+
+	history, err := sdb.GetPriceHistory(context.Background(), []string{"AAPL", "MSFT"}, day)
+	if err != nil {
+		t.Fatalf("❌ error inesperado al obtener el histórico: %v", err)
+	}
+	if len(history["AAPL"]) != 2 || len(history["MSFT"]) != 1 {
+		t.Errorf("❌ histórico inesperado: %+v", history)
+	}
+	if got := history["AAPL"][0].Close; got != 185.3 {
+		t.Errorf("❌ cierre %v, se esperaba 185.3 redondeado al céntimo", got)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("⚠️ expectativas no cumplidas en TestRecordPricesAndGetPriceHistory: %s", err)
+	}
+}
+
+func TestGetPriceBars(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	sdb := NewStockDB(db)
+	jan, feb := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	rows := sqlmock.NewRows([]string{"start", "close"}).
+		AddRow(feb, 190.1999969482422).
+		AddRow(jan, 185.5)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT date_trunc($2, trading_day::TIMESTAMP)::DATE AS start")).
+		WithArgs("AAPL", models.ResolutionMonth, 2).
+		WillReturnRows(rows)
+
+	bars, err := sdb.GetPriceBars(context.Background(), "AAPL", models.ResolutionMonth, 2)
+	if err != nil {
+		t.Fatalf("❌ error inesperado al obtener el histórico agregado: %v", err)
+	}
+	// La consulta trae las más recientes primero; se devuelven en orden cronológico.
+	if len(bars) != 2 || !bars[0].Start.Equal(jan) || bars[1].Close != 190.2 {
+		t.Errorf("❌ barras inesperadas: %+v", bars)
+	}
+	if _, err := sdb.GetPriceBars(context.Background(), "AAPL", "hour", 2); err == nil {
+		t.Errorf("❌ se esperaba un error para una resolución no soportada")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("⚠️ expectativas no cumplidas en TestGetPriceBars: %s", err)
+	}
+}
+
+func TestGetCandles(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	sdb := NewStockDB(db)
+	monday := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	rows := sqlmock.NewRows([]string{"start", "open", "high", "low", "close", "volume"}).
+		AddRow(monday.AddDate(0, 0, 7), 190.0, 193.5, 189.0, 192.1999969482422, 0).
+		AddRow(monday, 184.0, 191.0, 183.0, 190.0, 250000000)
+	mock.ExpectQuery(regexp.QuoteMeta("max(COALESCE(high, close))")).
+		WithArgs("AAPL", models.ResolutionWeek, 2).
+		WillReturnRows(rows)
+
+	candles, err := sdb.GetCandles(context.Background(), "AAPL", models.ResolutionWeek, 2)
+	if err != nil {
+		t.Fatalf("❌ error inesperado al obtener las velas: %v", err)
+	}
+	want := []models.Candle{
+		{Start: monday, Open: 184, High: 191, Low: 183, Close: 190, Volume: 250000000},
+		{Start: monday.AddDate(0, 0, 7), Open: 190, High: 193.5, Low: 189, Close: 192.2},
+	}
+	if len(candles) != len(want) || candles[0] != want[0] || candles[1] != want[1] {
+		t.Errorf("❌ velas %+v, se esperaban %+v", candles, want)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("⚠️ expectativas no cumplidas en TestGetCandles: %s", err)
+	}
+}
+
+func TestEstimateStockCount(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	sdb := NewStockDB(db)
+
+	estimate := regexp.QuoteMeta("SELECT reltuples FROM pg_class WHERE oid = 'stocks'::REGCLASS")
+	count := regexp.QuoteMeta("SELECT COUNT(*) FROM stocks")
+
+	// Tabla grande sin filtros: la estimación, sin COUNT(*)
+	mock.ExpectQuery(estimate).WillReturnRows(sqlmock.NewRows([]string{"reltuples"}).AddRow(125000.0))
+	if n, approximate, err := sdb.EstimateStockCount(context.Background(), "", StockFilters{}); err != nil || n != 125000 || !approximate {
+		t.Errorf("❌ se esperaba la estimación 125000: %d, %v (%v)", n, approximate, err)
+	}
+
+	// Tabla pequeña (o sin estadísticas todavía): el recuento exacto
+	mock.ExpectQuery(estimate).WillReturnRows(sqlmock.NewRows([]string{"reltuples"}).AddRow(-1.0))
+	mock.ExpectQuery(count + "$").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(42))
+	if n, approximate, err := sdb.EstimateStockCount(context.Background(), "", StockFilters{}); err != nil || n != 42 || approximate {
+		t.Errorf("❌ se esperaba el recuento exacto 42: %d, %v (%v)", n, approximate, err)
+	}
+
+	// Con filtros la estimación de la tabla no sirve
+	minESG := 50.0
+	mock.ExpectQuery(count + regexp.QuoteMeta(" WHERE esg_score >= $1")).WithArgs(minESG).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(7))
+	if n, approximate, err := sdb.EstimateStockCount(context.Background(), "", StockFilters{MinESG: &minESG}); err != nil || n != 7 || approximate {
+		t.Errorf("❌ se esperaba el recuento exacto filtrado 7: %d, %v (%v)", n, approximate, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("⚠️ expectativas no cumplidas en TestEstimateStockCount: %s", err)
+	}
+}
@@ -0,0 +1,117 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// ErrDeviceNotFound indica que el dispositivo no existe o no es del usuario.
+var ErrDeviceNotFound = errors.New("dispositivo no encontrado")
+
+// RegisterDevice registra (o vuelve a activar) el dispositivo con token para un usuario.
+func (c *cockroachDB) RegisterDevice(ctx context.Context, userID uuid.UUID, platform, token, name string) (models.Device, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	d := models.Device{UserID: userID, Platform: platform, Token: token, Name: name}
+	err := c.db.QueryRowContext(ctx,
+		`INSERT INTO devices (user_id, platform, token, name) VALUES ($1, $2, $3, $4)
+        ON CONFLICT (token) DO UPDATE SET user_id = excluded.user_id, platform = excluded.platform,
+            name = excluded.name, updated_at = now(), disabled_at = NULL
+        RETURNING id, created_at`, userID, platform, token, name).Scan(&d.ID, &d.CreatedAt)
+	if err != nil {
+		return models.Device{}, fmt.Errorf("error al registrar el dispositivo del usuario %s: %w", userID, err)
+	}
+	return d, nil
+}
+
+// ListDevices devuelve los dispositivos de un usuario, incluidos los desactivados, con su
+// última entrega.
+func (c *cockroachDB) ListDevices(ctx context.Context, userID uuid.UUID) ([]models.Device, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	rows, err := c.db.QueryContext(ctx, `
+        SELECT d.id, d.platform, d.token, d.name, d.created_at, d.disabled_at,
+            pd.status, pd.message_id, pd.error, pd.events, pd.sent_at
+        FROM devices AS d
+        LEFT JOIN LATERAL (
+            SELECT status, message_id, error, events, sent_at FROM push_deliveries
+            WHERE device_id = d.id ORDER BY sent_at DESC LIMIT 1
+        ) AS pd ON true
+        WHERE d.user_id = $1
+        ORDER BY d.created_at`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener los dispositivos del usuario %s: %w", userID, err)
+	}
+	defer rows.Close()
+
+	devices := []models.Device{}
+	for rows.Next() {
+		d := models.Device{UserID: userID}
+		var status, messageID, deliveryErr sql.NullString
+		var events sql.NullInt64
+		var sentAt sql.NullTime
+		if err := rows.Scan(&d.ID, &d.Platform, &d.Token, &d.Name, &d.CreatedAt, &d.DisabledAt,
+			&status, &messageID, &deliveryErr, &events, &sentAt); err != nil {
+			return nil, fmt.Errorf("error al leer los dispositivos del usuario %s: %w", userID, err)
+		}
+		if status.Valid {
+			d.LastDelivery = &models.PushDelivery{DeviceID: d.ID, Status: status.String, MessageID: messageID.String,
+				Error: deliveryErr.String, Events: int(events.Int64), SentAt: sentAt.Time}
+		}
+		devices = append(devices, d)
+	}
+	return devices, rows.Err()
+}
+
+// DeleteDevice borra un dispositivo del usuario y su registro de entregas.
+func (c *cockroachDB) DeleteDevice(ctx context.Context, userID, deviceID uuid.UUID) error {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	res, err := c.db.ExecContext(ctx,
+		"DELETE FROM devices WHERE id = $2 AND user_id = $1", userID, deviceID)
+	if err != nil {
+		return fmt.Errorf("error al borrar el dispositivo %s: %w", deviceID, err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrDeviceNotFound
+	}
+	return nil
+}
+
+// RecordPushDelivery registra un intento de entrega a un dispositivo. Si el proveedor
+// rechazó el token, el dispositivo se desactiva para no volver a intentarlo.
+func (c *cockroachDB) RecordPushDelivery(ctx context.Context, delivery models.PushDelivery) error {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error al iniciar la transacción de la entrega push: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO push_deliveries (device_id, status, message_id, error, events, sent_at)
+        VALUES ($1, $2, $3, $4, $5, $6)`,
+		delivery.DeviceID, delivery.Status, delivery.MessageID, delivery.Error, delivery.Events, delivery.SentAt); err != nil {
+		return fmt.Errorf("error al registrar la entrega al dispositivo %s: %w", delivery.DeviceID, err)
+	}
+	if delivery.Status == models.PushStatusInvalidToken {
+		if _, err := tx.ExecContext(ctx,
+			"UPDATE devices SET disabled_at = $2 WHERE id = $1 AND disabled_at IS NULL", delivery.DeviceID, delivery.SentAt); err != nil {
+			return fmt.Errorf("error al desactivar el dispositivo %s: %w", delivery.DeviceID, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error al confirmar la entrega al dispositivo %s: %w", delivery.DeviceID, err)
+	}
+	return nil
+}
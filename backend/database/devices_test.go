@@ -0,0 +1,86 @@
+package database
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+func TestRegisterAndListDevices(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	udb := NewUserDB(db)
+
+	userID, deviceID, otherID := uuid.New(), uuid.New(), uuid.New()
+	created := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO devices (user_id, platform, token, name) VALUES ($1, $2, $3, $4)")).
+		WithArgs(userID, models.PushPlatformIOS, "tok-ios", "iPhone").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(deviceID, created))
+	mock.ExpectQuery(regexp.QuoteMeta("FROM devices AS d")).WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "platform", "token", "name", "created_at", "disabled_at",
+			"status", "message_id", "error", "events", "sent_at"}).
+			AddRow(deviceID, models.PushPlatformIOS, "tok-ios", "iPhone", created, nil,
+				models.PushStatusDelivered, "apns-1", "", 1, created.Add(time.Hour)).
+			AddRow(otherID, models.PushPlatformAndroid, "tok-fcm", "", created, nil, nil, nil, nil, nil, nil))
+
+	d, err := udb.RegisterDevice(context.Background(), userID, models.PushPlatformIOS, "tok-ios", "iPhone")
+	if err != nil || d.ID != deviceID || !d.CreatedAt.Equal(created) {
+		t.Fatalf("❌ RegisterDevice = %+v, %v", d, err)
+	}
+	devices, err := udb.ListDevices(context.Background(), userID)
+	if err != nil || len(devices) != 2 {
+		t.Fatalf("❌ ListDevices = %+v, %v", devices, err)
+	}
+	if last := devices[0].LastDelivery; last == nil || last.MessageID != "apns-1" || last.Events != 1 {
+		t.Errorf("❌ última entrega inesperada: %+v", last)
+	}
+	if devices[1].LastDelivery != nil {
+		t.Errorf("❌ un dispositivo sin entregas no debería tener última entrega: %+v", devices[1].LastDelivery)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("⚠️ expectativas no cumplidas en TestRegisterAndListDevices: %s", err)
+	}
+}
+
+func TestRecordPushDelivery_DisablesInvalidToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	udb := NewUserDB(db)
+
+	deviceID := uuid.New()
+	sentAt := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	insert := regexp.QuoteMeta("INSERT INTO push_deliveries (device_id, status, message_id, error, events, sent_at)")
+	mock.ExpectBegin()
+	mock.ExpectExec(insert).WithArgs(deviceID, models.PushStatusDelivered, "m-1", "", 2, sentAt).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec(insert).WithArgs(deviceID, models.PushStatusInvalidToken, "", "Unregistered", 1, sentAt).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE devices SET disabled_at = $2 WHERE id = $1 AND disabled_at IS NULL")).
+		WithArgs(deviceID, sentAt).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM devices WHERE id = $2 AND user_id = $1")).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := udb.RecordPushDelivery(context.Background(), models.PushDelivery{DeviceID: deviceID, Status: models.PushStatusDelivered, MessageID: "m-1", Events: 2, SentAt: sentAt}); err != nil {
+		t.Fatalf("❌ error inesperado: %v", err)
+	}
+	if err := udb.RecordPushDelivery(context.Background(), models.PushDelivery{DeviceID: deviceID, Status: models.PushStatusInvalidToken, Error: "Unregistered", Events: 1, SentAt: sentAt}); err != nil {
+		t.Fatalf("❌ error inesperado: %v", err)
+	}
+	if err := udb.DeleteDevice(context.Background(), uuid.New(), deviceID); err != ErrDeviceNotFound {
+		t.Errorf("❌ se esperaba ErrDeviceNotFound al borrar un dispositivo ajeno, se obtuvo %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("⚠️ expectativas no cumplidas en TestRecordPushDelivery_DisablesInvalidToken: %s", err)
+	}
+}
@@ -0,0 +1,218 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// ErrEnrichmentRunNotFound indica que no hay ninguna ejecución del enriquecimiento con ese ID.
+var ErrEnrichmentRunNotFound = errors.New("ejecución del enriquecimiento no encontrada")
+
+// enrichmentCursorID identifica la única fila de enrichment_cursor (solo hay un enricher).
+const enrichmentCursorID = "default"
+
+// GetEnrichmentCursor devuelve el cursor de la última ejecución del enriquecimiento.
+// Si nunca se ha guardado ninguno devuelve un cursor vacío (RunID nulo) sin error.
+func (c *cockroachDB) GetEnrichmentCursor(ctx context.Context) (models.EnrichmentCursor, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	query := `SELECT run_id, last_ticker, started_at, updated_at, completed FROM enrichment_cursor WHERE id = $1`
+
+	var cursor models.EnrichmentCursor
+	err := c.db.QueryRowContext(ctx, query, enrichmentCursorID).Scan(
+		&cursor.RunID, &cursor.LastTicker, &cursor.StartedAt, &cursor.UpdatedAt, &cursor.Completed,
+	)
+	if err == sql.ErrNoRows {
+		return models.EnrichmentCursor{}, nil
+	}
+	if err != nil {
+		return models.EnrichmentCursor{}, fmt.Errorf("error al obtener el cursor de enriquecimiento: %w", err)
+	}
+	return cursor, nil
+}
+
+// SaveEnrichmentCursor guarda (o reemplaza) el cursor de la ejecución actual. Al empezar
+// una ejecución nueva fija snapshot_at con el reloj de la base de datos (ver
+// EnrichmentSnapshot); al reanudarla lo conserva.
+func (c *cockroachDB) SaveEnrichmentCursor(ctx context.Context, cursor models.EnrichmentCursor) error {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	query := `
+        INSERT INTO enrichment_cursor (id, run_id, last_ticker, started_at, updated_at, completed, snapshot_at)
+        VALUES ($1, $2, $3, $4, $5, $6, now())
+        ON CONFLICT (id) DO UPDATE SET
+            snapshot_at = CASE WHEN enrichment_cursor.run_id = EXCLUDED.run_id
+                THEN enrichment_cursor.snapshot_at ELSE now() END,
+            run_id = EXCLUDED.run_id,
+            last_ticker = EXCLUDED.last_ticker,
+            started_at = EXCLUDED.started_at,
+            updated_at = EXCLUDED.updated_at,
+            completed = EXCLUDED.completed;`
+
+	_, err := c.db.ExecContext(ctx, query,
+		enrichmentCursorID, cursor.RunID, cursor.LastTicker, cursor.StartedAt, cursor.UpdatedAt, cursor.Completed)
+	if err != nil {
+		return fmt.Errorf("error al guardar el cursor de enriquecimiento: %w", err)
+	}
+	return nil
+}
+
+// SaveEnrichmentResults guarda los resultados de un lote de tickers. Un ticker que se
+// vuelve a enriquecer en la misma ejecución (al reanudarla) reemplaza su resultado.
+func (c *cockroachDB) SaveEnrichmentResults(ctx context.Context, results []models.EnrichmentResult) error {
+	if len(results) == 0 {
+		return nil
+	}
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	values := make([]string, 0, len(results))
+	args := make([]interface{}, 0, len(results)*6)
+	for i, r := range results {
+		n := i * 6
+		values = append(values, fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d)", n+1, n+2, n+3, n+4, n+5, n+6))
+		args = append(args, r.RunID, r.Ticker, r.Fields, pgArray(r.NullFields), r.Errors, r.EnrichedAt)
+	}
+	query := "UPSERT INTO enrichment_results (run_id, ticker, fields, null_fields, errors, enriched_at) VALUES " +
+		strings.Join(values, ", ")
+	if _, err := c.db.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("error al guardar los resultados del enriquecimiento: %w", err)
+	}
+	return nil
+}
+
+// GetEnrichmentResults devuelve los resultados por ticker de la ejecución runID, ordenados
+// por ticker. Una ejecución desconocida (o ya purgada) devuelve una lista vacía.
+func (c *cockroachDB) GetEnrichmentResults(ctx context.Context, runID uuid.UUID) ([]models.EnrichmentResult, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	rows, err := c.db.QueryContext(ctx, `
+        SELECT run_id, ticker, fields, null_fields, errors, enriched_at
+        FROM enrichment_results WHERE run_id = $1
+        ORDER BY ticker`, runID)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener los resultados de la ejecución %s: %w", runID, err)
+	}
+	defer rows.Close()
+
+	results := []models.EnrichmentResult{}
+	for rows.Next() {
+		var r models.EnrichmentResult
+		if err := rows.Scan(&r.RunID, &r.Ticker, &r.Fields, scanArray(&r.NullFields), &r.Errors, &r.EnrichedAt); err != nil {
+			return nil, fmt.Errorf("error al escanear el resultado del enriquecimiento: %w", err)
+		}
+		if r.Fields == nil {
+			r.Fields = models.Provenance{}
+		}
+		if r.NullFields == nil {
+			r.NullFields = []string{}
+		}
+		results = append(results, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error al iterar los resultados del enriquecimiento: %w", err)
+	}
+	return results, nil
+}
+
+// PurgeEnrichmentResults borra los resultados enriquecidos antes de before y devuelve
+// cuántos eran.
+func (c *cockroachDB) PurgeEnrichmentResults(ctx context.Context, before time.Time) (int64, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	res, err := c.db.ExecContext(ctx, "DELETE FROM enrichment_results WHERE enriched_at < $1", before)
+	if err != nil {
+		return 0, fmt.Errorf("error al purgar los resultados del enriquecimiento: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+const enrichmentRunColumns = "id, status, started_at, finished_at, stocks_processed, provider_errors, error"
+
+// SaveEnrichmentRun guarda (o reemplaza) el registro de una ejecución.
+func (c *cockroachDB) SaveEnrichmentRun(ctx context.Context, run models.EnrichmentRun) error {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	providerErrors, err := json.Marshal(run.ProviderErrors)
+	if err != nil {
+		return fmt.Errorf("error al serializar los errores de la ejecución %s: %w", run.ID, err)
+	}
+	_, err = c.db.ExecContext(ctx, "UPSERT INTO enrichment_runs ("+enrichmentRunColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7)",
+		run.ID, run.Status, run.StartedAt, run.FinishedAt, run.StocksProcessed, string(providerErrors), run.Error)
+	if err != nil {
+		return fmt.Errorf("error al guardar la ejecución %s: %w", run.ID, err)
+	}
+	return nil
+}
+
+// GetEnrichmentRun devuelve la ejecución id, o ErrEnrichmentRunNotFound si no existe.
+func (c *cockroachDB) GetEnrichmentRun(ctx context.Context, id uuid.UUID) (models.EnrichmentRun, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	run, err := scanEnrichmentRun(c.db.QueryRowContext(ctx, "SELECT "+enrichmentRunColumns+" FROM enrichment_runs WHERE id = $1", id))
+	if err == sql.ErrNoRows {
+		return models.EnrichmentRun{}, ErrEnrichmentRunNotFound
+	}
+	if err != nil {
+		return models.EnrichmentRun{}, fmt.Errorf("error al obtener la ejecución %s: %w", id, err)
+	}
+	return run, nil
+}
+
+// ListEnrichmentRuns devuelve las últimas limit ejecuciones, de la más reciente a la más antigua.
+func (c *cockroachDB) ListEnrichmentRuns(ctx context.Context, limit int) ([]models.EnrichmentRun, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	rows, err := c.db.QueryContext(ctx, "SELECT "+enrichmentRunColumns+" FROM enrichment_runs ORDER BY started_at DESC LIMIT $1", limit)
+	if err != nil {
+		return nil, fmt.Errorf("error al listar las ejecuciones del enriquecimiento: %w", err)
+	}
+	defer rows.Close()
+
+	runs := []models.EnrichmentRun{}
+	for rows.Next() {
+		run, err := scanEnrichmentRun(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error al escanear la ejecución del enriquecimiento: %w", err)
+		}
+		runs = append(runs, run)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error al iterar las ejecuciones del enriquecimiento: %w", err)
+	}
+	return runs, nil
+}
+
+func scanEnrichmentRun(row rowScanner) (models.EnrichmentRun, error) {
+	var run models.EnrichmentRun
+	var finishedAt sql.NullTime
+	var providerErrors []byte
+	if err := row.Scan(&run.ID, &run.Status, &run.StartedAt, &finishedAt, &run.StocksProcessed, &providerErrors, &run.Error); err != nil {
+		return models.EnrichmentRun{}, err
+	}
+	if finishedAt.Valid {
+		run.FinishedAt = &finishedAt.Time
+	}
+	run.ProviderErrors = map[string]int{}
+	if len(providerErrors) > 0 {
+		if err := json.Unmarshal(providerErrors, &run.ProviderErrors); err != nil {
+			return models.EnrichmentRun{}, fmt.Errorf("errores de la ejecución %s inválidos: %w", run.ID, err)
+		}
+	}
+	return run, nil
+}
@@ -0,0 +1,102 @@
+package database
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// TestEnrichmentResults_RoundTrip comprueba que los resultados de un lote se guardan en un
+// solo UPSERT y se leen con sus campos, métricas nulas y errores.
+func TestEnrichmentResults_RoundTrip(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	sdb := NewStockDB(db)
+
+	runID := uuid.New()
+	at := time.Date(2025, 1, 6, 3, 0, 0, 0, time.UTC)
+	results := []models.EnrichmentResult{
+		{RunID: runID, Ticker: "AAPL", Fields: models.Provenance{"current_price": {Source: "finnhub", FetchedAt: at}}, NullFields: []string{}, EnrichedAt: at},
+		{RunID: runID, Ticker: "KO", NullFields: []string{"alpha"}, Errors: "alphavantage alpha: límite", EnrichedAt: at},
+	}
+	mock.ExpectExec(regexp.QuoteMeta("UPSERT INTO enrichment_results (run_id, ticker, fields, null_fields, errors, enriched_at) VALUES ($1, $2, $3, $4, $5, $6), ($7,")).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	if err := sdb.SaveEnrichmentResults(context.Background(), results); err != nil {
+		t.Fatalf("❌ error inesperado al guardar: %v", err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta("FROM enrichment_results WHERE run_id = $1")).WithArgs(runID).
+		WillReturnRows(sqlmock.NewRows([]string{"run_id", "ticker", "fields", "null_fields", "errors", "enriched_at"}).
+			AddRow(runID, "AAPL", `{"current_price":{"source":"finnhub","fetched_at":"2025-01-06T03:00:00Z"}}`, "{}", "", at).
+			AddRow(runID, "KO", nil, "{alpha}", "alphavantage alpha: límite", at))
+
+	got, err := sdb.GetEnrichmentResults(context.Background(), runID)
+	if err != nil || len(got) != 2 {
+		t.Fatalf("❌ resultados inesperados: %+v (%v)", got, err)
+	}
+	if got[0].Fields["current_price"].Source != "finnhub" || len(got[0].NullFields) != 0 {
+		t.Errorf("❌ resultado de AAPL inesperado: %+v", got[0])
+	}
+	if got[1].Fields == nil || len(got[1].NullFields) != 1 || got[1].NullFields[0] != "alpha" || got[1].Errors == "" {
+		t.Errorf("❌ resultado de KO inesperado: %+v", got[1])
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("⚠️ expectativas no cumplidas en TestEnrichmentResults_RoundTrip: %s", err)
+	}
+}
+
+// TestEnrichmentRuns_RoundTrip comprueba que una ejecución se guarda y se lee con sus
+// errores por proveedor, y que una ejecución inexistente devuelve ErrEnrichmentRunNotFound.
+func TestEnrichmentRuns_RoundTrip(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	sdb := NewStockDB(db)
+
+	runID := uuid.New()
+	started := time.Date(2025, 1, 6, 3, 0, 0, 0, time.UTC)
+	finished := started.Add(5 * time.Minute)
+	run := models.EnrichmentRun{ID: runID, Status: models.EnrichmentRunSucceeded, StartedAt: started, FinishedAt: &finished,
+		StocksProcessed: 4, ProviderErrors: map[string]int{"alphavantage": 3}}
+	mock.ExpectExec(regexp.QuoteMeta("UPSERT INTO enrichment_runs")).
+		WithArgs(runID, "succeeded", started, &finished, 4, `{"alphavantage":3}`, "").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	if err := sdb.SaveEnrichmentRun(context.Background(), run); err != nil {
+		t.Fatalf("❌ error inesperado al guardar: %v", err)
+	}
+
+	columns := []string{"id", "status", "started_at", "finished_at", "stocks_processed", "provider_errors", "error"}
+	mock.ExpectQuery(regexp.QuoteMeta("FROM enrichment_runs ORDER BY started_at DESC LIMIT $1")).WithArgs(20).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(uuid.New(), "running", finished, nil, 1, nil, "").
+			AddRow(runID, "succeeded", started, finished, 4, `{"alphavantage":3}`, ""))
+	runs, err := sdb.ListEnrichmentRuns(context.Background(), 20)
+	if err != nil || len(runs) != 2 {
+		t.Fatalf("❌ ejecuciones inesperadas: %+v (%v)", runs, err)
+	}
+	if runs[0].FinishedAt != nil || runs[0].ProviderErrors == nil {
+		t.Errorf("❌ la ejecución en curso no debería tener fin: %+v", runs[0])
+	}
+	if runs[1].FinishedAt == nil || !runs[1].FinishedAt.Equal(finished) || runs[1].ProviderErrors["alphavantage"] != 3 {
+		t.Errorf("❌ ejecución terminada inesperada: %+v", runs[1])
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta("FROM enrichment_runs WHERE id = $1")).WithArgs(runID).
+		WillReturnRows(sqlmock.NewRows(columns))
+	if _, err := sdb.GetEnrichmentRun(context.Background(), runID); err != ErrEnrichmentRunNotFound {
+		t.Errorf("❌ se esperaba ErrEnrichmentRunNotFound, se obtuvo %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("⚠️ expectativas no cumplidas en TestEnrichmentRuns_RoundTrip: %s", err)
+	}
+}
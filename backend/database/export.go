@@ -0,0 +1,62 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// ExportSnapshot devuelve la hora actual de la base de datos, que sirve como instantánea
+// para ExportStocks. Exportar dos veces con la misma instantánea produce las mismas filas.
+func (c *cockroachDB) ExportSnapshot(ctx context.Context) (time.Time, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	var snapshot time.Time
+	if err := c.db.QueryRowContext(ctx, "SELECT now()").Scan(&snapshot); err != nil {
+		return time.Time{}, fmt.Errorf("error al obtener la instantánea para exportar: %w", err)
+	}
+	return snapshot, nil
+}
+
+// ExportStocks recorre, ordenados por ticker, los stocks tal como estaban en asOf (AS OF
+// SYSTEM TIME de CockroachDB) y llama a fn con cada uno. Solo incluye los tickers mayores
+// que afterTicker y, si limit > 0, como máximo limit stocks. Las filas se leen de una en
+// una, por lo que el tamaño de la exportación no está limitado por la memoria.
+//
+// No se aplica DBPool.QueryTimeout: la consulta dura lo que tarde fn en escribir la
+// exportación. Se aborta al cancelar ctx.
+func (c *cockroachDB) ExportStocks(ctx context.Context, asOf time.Time, afterTicker string, limit int, fn func(models.Stock) error) error {
+	// AS OF SYSTEM TIME no admite placeholders; el valor es un entero generado aquí, no
+	// una entrada del usuario.
+	query := fmt.Sprintf("SELECT %s FROM stocks AS OF SYSTEM TIME '%d' WHERE ticker > $1 ORDER BY ticker ASC",
+		stockColumns, asOf.UnixNano())
+	args := []interface{}{afterTicker}
+	if limit > 0 {
+		query += " LIMIT $2"
+		args = append(args, limit)
+	}
+
+	rows, err := c.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("error al consultar stocks para exportar: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		s, err := scanStock(rows)
+		if err != nil {
+			return fmt.Errorf("error al escanear fila de stock para exportar: %w", err)
+		}
+		if err := fn(s); err != nil {
+			return err
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("error después de iterar stocks para exportar: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package database
+
+import "strings"
+
+// StockFilters son filtros opcionales sobre los campos de los stocks. Un filtro nil (o
+// vacío, los de texto) no se aplica; los stocks con el campo a NULL no cumplen ningún
+// filtro activo.
+type StockFilters struct {
+	MinESG              *float64 // esg_score mínimo, de 0 a 100
+	MinShortInterest    *float64 // Acciones vendidas en corto mínimas
+	MinDaysToCover      *float64 // days_to_cover mínimo
+	MinDividendYield    *float64 // dividend_yield mínimo, en porcentaje
+	MinMarketCap        *float64 // market_capitalization mínima, en millones de USD
+	MinPERatio          *float64 // pe_ratio mínimo
+	MaxPERatio          *float64 // pe_ratio máximo; excluye los PER negativos (empresas con pérdidas)
+	MinRelativeStrength *float64 // Fuerza relativa a 90 días mínima, en puntos porcentuales
+	MinPrice            *float64 // current_price mínimo
+	MaxPrice            *float64 // current_price máximo
+
+	Action    string // Acción de la última recomendación (ej. "upgraded by"), sin distinguir mayúsculas; "" no filtra
+	Brokerage string // Casa de bolsa de la última recomendación, sin distinguir mayúsculas; "" no filtra
+}
+
+// conditions devuelve las condiciones SQL de los filtros activos. arg registra cada valor
+// como argumento de la consulta y devuelve su placeholder.
+func (f StockFilters) conditions(arg func(interface{}) string) []string {
+	var conditions []string
+	if f.MinESG != nil {
+		conditions = append(conditions, "esg_score >= "+arg(*f.MinESG))
+	}
+	if f.MinShortInterest != nil {
+		conditions = append(conditions, "short_interest >= "+arg(*f.MinShortInterest))
+	}
+	if f.MinDaysToCover != nil {
+		conditions = append(conditions, "days_to_cover >= "+arg(*f.MinDaysToCover))
+	}
+	if f.MinDividendYield != nil {
+		conditions = append(conditions, "dividend_yield >= "+arg(*f.MinDividendYield))
+	}
+	if f.MinMarketCap != nil {
+		conditions = append(conditions, "market_capitalization >= "+arg(*f.MinMarketCap))
+	}
+	if f.MinPERatio != nil {
+		conditions = append(conditions, "pe_ratio >= "+arg(*f.MinPERatio))
+	}
+	if f.MaxPERatio != nil {
+		conditions = append(conditions, "pe_ratio > 0 AND pe_ratio <= "+arg(*f.MaxPERatio))
+	}
+	if f.MinRelativeStrength != nil {
+		conditions = append(conditions, "relative_strength_90d >= "+arg(*f.MinRelativeStrength))
+	}
+	if f.MinPrice != nil {
+		conditions = append(conditions, "current_price >= "+arg(*f.MinPrice))
+	}
+	if f.MaxPrice != nil {
+		conditions = append(conditions, "current_price <= "+arg(*f.MaxPrice))
+	}
+	if f.Action != "" {
+		conditions = append(conditions, "LOWER(action) = "+arg(strings.ToLower(f.Action)))
+	}
+	if f.Brokerage != "" {
+		conditions = append(conditions, "LOWER(brokerage) = "+arg(strings.ToLower(f.Brokerage)))
+	}
+	return conditions
+}
@@ -0,0 +1,106 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// ErrImportMappingNotFound indica que no existe ninguna plantilla de importación con ese nombre.
+var ErrImportMappingNotFound = errors.New("plantilla de importación no encontrada")
+
+const importMappingColumns = "name, has_header, delimiter, columns, created_at, updated_at"
+
+func scanImportMapping(row rowScanner) (models.ImportMapping, error) {
+	var m models.ImportMapping
+	var columns []byte
+	if err := row.Scan(&m.Name, &m.HasHeader, &m.Delimiter, &columns, &m.CreatedAt, &m.UpdatedAt); err != nil {
+		return m, err
+	}
+	if err := json.Unmarshal(columns, &m.Columns); err != nil {
+		return m, fmt.Errorf("columnas inválidas en la plantilla %s: %w", m.Name, err)
+	}
+	return m, nil
+}
+
+// ListImportMappings devuelve todas las plantillas de importación ordenadas por nombre.
+func (c *cockroachDB) ListImportMappings(ctx context.Context) ([]models.ImportMapping, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	rows, err := c.db.QueryContext(ctx,
+		"SELECT "+importMappingColumns+" FROM import_mappings ORDER BY name")
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener las plantillas de importación: %w", err)
+	}
+	defer rows.Close()
+
+	mappings := []models.ImportMapping{}
+	for rows.Next() {
+		m, err := scanImportMapping(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error al escanear la plantilla de importación: %w", err)
+		}
+		mappings = append(mappings, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error al iterar las plantillas de importación: %w", err)
+	}
+	return mappings, nil
+}
+
+// GetImportMapping devuelve la plantilla name o ErrImportMappingNotFound.
+func (c *cockroachDB) GetImportMapping(ctx context.Context, name string) (models.ImportMapping, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	m, err := scanImportMapping(c.db.QueryRowContext(ctx,
+		"SELECT "+importMappingColumns+" FROM import_mappings WHERE name = $1", name))
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.ImportMapping{}, ErrImportMappingNotFound
+	}
+	if err != nil {
+		return models.ImportMapping{}, fmt.Errorf("error al obtener la plantilla de importación %s: %w", name, err)
+	}
+	return m, nil
+}
+
+// SaveImportMapping crea la plantilla o reemplaza la que tenga el mismo nombre, conservando
+// su fecha de creación. Devuelve la plantilla guardada.
+func (c *cockroachDB) SaveImportMapping(ctx context.Context, m models.ImportMapping) (models.ImportMapping, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	columns, err := json.Marshal(m.Columns)
+	if err != nil {
+		return models.ImportMapping{}, err
+	}
+	saved, err := scanImportMapping(c.db.QueryRowContext(ctx,
+		`INSERT INTO import_mappings (name, has_header, delimiter, columns) VALUES ($1, $2, $3, $4)
+        ON CONFLICT (name) DO UPDATE SET has_header = excluded.has_header, delimiter = excluded.delimiter,
+            columns = excluded.columns, updated_at = now()
+        RETURNING `+importMappingColumns, m.Name, m.HasHeader, m.Delimiter, columns))
+	if err != nil {
+		return models.ImportMapping{}, fmt.Errorf("error al guardar la plantilla de importación %s: %w", m.Name, err)
+	}
+	return saved, nil
+}
+
+// DeleteImportMapping borra la plantilla name. Devuelve ErrImportMappingNotFound si no existe.
+func (c *cockroachDB) DeleteImportMapping(ctx context.Context, name string) error {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	res, err := c.db.ExecContext(ctx, "DELETE FROM import_mappings WHERE name = $1", name)
+	if err != nil {
+		return fmt.Errorf("error al borrar la plantilla de importación %s: %w", name, err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrImportMappingNotFound
+	}
+	return nil
+}
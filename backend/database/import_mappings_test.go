@@ -0,0 +1,60 @@
+package database
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+func TestSaveImportMapping(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	sdb := NewStockDB(db)
+
+	now := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	columns := `{"3":"target_to","Symbol":"ticker"}`
+	mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO import_mappings (name, has_header, delimiter, columns) VALUES ($1, $2, $3, $4)")).
+		WithArgs("broker-x", true, ";", []byte(columns)).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "has_header", "delimiter", "columns", "created_at", "updated_at"}).
+			AddRow("broker-x", true, ";", []byte(columns), now, now))
+
+	saved, err := sdb.SaveImportMapping(context.Background(), models.ImportMapping{Name: "broker-x", HasHeader: true, Delimiter: ";",
+		Columns: map[string]string{"Symbol": "ticker", "3": "target_to"}})
+	if err != nil || saved.Columns["3"] != "target_to" || !saved.CreatedAt.Equal(now) {
+		t.Errorf("❌ plantilla guardada inesperada: %+v (%v)", saved, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("⚠️ expectativas no cumplidas en TestSaveImportMapping: %s", err)
+	}
+}
+
+func TestGetImportMapping_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	sdb := NewStockDB(db)
+
+	mock.ExpectQuery(regexp.QuoteMeta("FROM import_mappings WHERE name = $1")).WithArgs("nope").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "has_header", "delimiter", "columns", "created_at", "updated_at"}))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM import_mappings WHERE name = $1")).WithArgs("nope").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if _, err := sdb.GetImportMapping(context.Background(), "nope"); err != ErrImportMappingNotFound {
+		t.Errorf("❌ se esperaba ErrImportMappingNotFound, se obtuvo %v", err)
+	}
+	if err := sdb.DeleteImportMapping(context.Background(), "nope"); err != ErrImportMappingNotFound {
+		t.Errorf("❌ al borrar se esperaba ErrImportMappingNotFound, se obtuvo %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("⚠️ expectativas no cumplidas en TestGetImportMapping_NotFound: %s", err)
+	}
+}
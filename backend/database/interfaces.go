@@ -1,23 +1,143 @@
 package database
 
-import "github.com/jannin2/stock-app/backend/models"
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/models"
+)
 
 // StockDB define las operaciones que cualquier base de datos de stocks debe implementar.
 // Esto permite que el código que interactúa con la base de datos sea independiente de la implementación específica.
+// Todas las operaciones reciben el contexto de quien las pide (la petición HTTP o la
+// ejecución del enricher): si se cancela, la consulta se aborta. Cada consulta tiene además
+// un tiempo máximo propio (DBPool.QueryTimeout).
 type StockDB interface {
-	GetAllStocks(opts StockQueryOptions) ([]models.Stock, error)
-	GetStockByID(id string) (models.Stock, error)
-	UpsertStocks(stocks []models.Stock) error
-	GetStockCount(searchQuery string) (int, error)
-	GetRecommendedStocks(limit int) ([]models.Stock, error)
+	GetAllStocks(ctx context.Context, opts StockQueryOptions) ([]models.Stock, error)
+	GetStocksPage(ctx context.Context, opts StockQueryOptions) ([]models.Stock, int, error)
+	GetStocksByCursor(ctx context.Context, opts StockQueryOptions, after *StockCursor) ([]models.Stock, *StockCursor, error)
+	GetStockByID(ctx context.Context, id string) (models.Stock, error)
+	GetStocksByTickers(ctx context.Context, tickers []string) ([]models.Stock, error)
+	CreateStock(ctx context.Context, stock models.Stock) (models.Stock, error)
+	UpdateStock(ctx context.Context, id string, stock models.Stock) (models.Stock, error)
+	DeleteStock(ctx context.Context, id string) error
+	UpsertStocks(ctx context.Context, stocks []models.Stock) error
+	GetStockCount(ctx context.Context, searchQuery string, filters StockFilters) (int, error)
+	EstimateStockCount(ctx context.Context, searchQuery string, filters StockFilters) (int, bool, error)
+	GetStockAggregates(ctx context.Context, search string, filters StockFilters, sample bool) (models.StockAggregates, error)
+	GetRecommendedStocks(ctx context.Context, limit int) ([]models.Stock, error)
+	GetRecommendedBuckets(ctx context.Context, groupBy string, perBucket int) ([]models.StockBucket, error)
+	GetEnrichmentCursor(ctx context.Context) (models.EnrichmentCursor, error)
+	SaveEnrichmentCursor(ctx context.Context, cursor models.EnrichmentCursor) error
+	SaveEnrichmentResults(ctx context.Context, results []models.EnrichmentResult) error
+	GetEnrichmentResults(ctx context.Context, runID uuid.UUID) ([]models.EnrichmentResult, error)
+	PurgeEnrichmentResults(ctx context.Context, before time.Time) (int64, error)
+	SaveEnrichmentRun(ctx context.Context, run models.EnrichmentRun) error
+	GetEnrichmentRun(ctx context.Context, id uuid.UUID) (models.EnrichmentRun, error)
+	ListEnrichmentRuns(ctx context.Context, limit int) ([]models.EnrichmentRun, error)
+	GetMarketHeatmap(ctx context.Context) ([]models.HeatmapSector, error)
+	GetBrokerageStats(ctx context.Context, limit int) ([]models.BrokerageStats, error)
+	RefreshAggregateViews(ctx context.Context) error
+	GetMarketMovers(ctx context.Context, day time.Time, limit int) (models.MarketMovers, error)
+	RecordPrices(ctx context.Context, points []models.PricePoint) error
+	GetPriceHistory(ctx context.Context, tickers []string, since time.Time) (map[string][]models.PricePoint, error)
+	GetPriceBars(ctx context.Context, ticker, resolution string, limit int) ([]models.PriceBar, error)
+	GetCandles(ctx context.Context, ticker, resolution string, limit int) ([]models.Candle, error)
+	RecordMentions(ctx context.Context, counts []models.MentionCount) error
+	SaveOptionsSummaries(ctx context.Context, summaries []models.OptionsSummary) error
+	GetOptionsSummary(ctx context.Context, ticker string) (models.OptionsSummary, error)
+	SaveCorporateActions(ctx context.Context, actions []models.CorporateAction) ([]models.CorporateAction, error)
+	GetCorporateActions(ctx context.Context, ticker string) ([]models.CorporateAction, error)
+	RenameTicker(ctx context.Context, oldTicker, newTicker string) error
+	UpsertIPOs(ctx context.Context, ipos []models.IPO) error
+	GetIPOs(ctx context.Context, from, to time.Time) ([]models.IPO, error)
+	PendingListings(ctx context.Context, since, asOf time.Time) ([]models.IPO, error)
+	MarkIPOsAdded(ctx context.Context, symbols []string, at time.Time) error
+	UpsertMacroEvents(ctx context.Context, events []models.MacroEvent) error
+	GetMacroEvents(ctx context.Context, from, to time.Time, filters MacroEventFilters) ([]models.MacroEvent, error)
+	ExportSnapshot(ctx context.Context) (time.Time, error)
+	ExportStocks(ctx context.Context, asOf time.Time, afterTicker string, limit int, fn func(models.Stock) error) error
+	GetEnrichmentSchedule(ctx context.Context) (map[string]models.EnrichmentSchedule, error)
+	SetEnrichmentTier(ctx context.Context, ticker, tier string) error
+	MergeProviderStats(ctx context.Context, stats []models.ProviderDayStats) error
+	GetProviderStats(ctx context.Context, since time.Time) ([]models.ProviderDayStats, error)
+	EnrichmentSnapshot(ctx context.Context) (time.Time, error)
+	AsOf(t time.Time) StockDB
+	ListImportMappings(ctx context.Context) ([]models.ImportMapping, error)
+	GetImportMapping(ctx context.Context, name string) (models.ImportMapping, error)
+	SaveImportMapping(ctx context.Context, m models.ImportMapping) (models.ImportMapping, error)
+	DeleteImportMapping(ctx context.Context, name string) error
+	SaveProviderPayload(ctx context.Context, p models.ProviderPayload) error
+	GetProviderPayloads(ctx context.Context, ticker string) ([]models.ProviderPayload, error)
+	PurgeProviderPayloads(ctx context.Context, before time.Time) (int64, error)
+	GetScoringRules(ctx context.Context) (models.ScoringRules, error)
+	SaveScoringRules(ctx context.Context, rules []models.ScoringRule) (models.ScoringRules, error)
 }
 
 // StockQueryOptions define los parámetros para consultar stocks.
 // Incluye opciones de búsqueda, ordenamiento y paginación.
 type StockQueryOptions struct {
 	Search string // Término de búsqueda para filtrar por ticker o compañía
-	SortBy string // Columna por la cual ordenar (ej. "ticker", "current_price")
-	Order  string // Orden del sort: "asc" (ascendente) o "desc" (descendente)
+	SortBy string // Campo o lista de campos por los que ordenar (ej. "target_upside", "recommendation_score:desc,ticker:asc")
+	Order  string // Orden de los campos de SortBy que no indican el suyo: "asc" (ascendente) o "desc" (descendente)
 	Limit  int    // Número máximo de resultados a devolver
-	Offset int    // Número de resultados a omitir (para paginación)
+	Offset int    // Número de resultados a omitir (para paginación); GetStocksByCursor no lo usa
+
+	Filters StockFilters // Filtros por valor de los campos enriquecidos
+}
+
+// UserDB define las operaciones sobre las cuentas de usuario, sus credenciales y sus datos
+// (watchlists, notas, carteras y alertas).
+type UserDB interface {
+	UserIDForAPIKey(ctx context.Context, keyHash string) (uuid.UUID, error)
+	CreateUser(ctx context.Context, email, passwordHash string) (models.User, error)
+	GetCredentialsByEmail(ctx context.Context, email string) (models.UserCredentials, error)
+	GetCredentials(ctx context.Context, userID uuid.UUID) (models.UserCredentials, error)
+	MarkEmailVerified(ctx context.Context, userID uuid.UUID) (time.Time, error)
+	SetAPIKeyHash(ctx context.Context, userID uuid.UUID, keyHash string) error
+	ResetPassword(ctx context.Context, userID uuid.UUID, passwordHash string) error
+	GetTwoFactor(ctx context.Context, userID uuid.UUID) (models.TwoFactor, error)
+	SetPendingTOTPSecret(ctx context.Context, userID uuid.UUID, secret string) error
+	EnableTwoFactor(ctx context.Context, userID uuid.UUID, recoveryCodeHashes []string) error
+	DisableTwoFactor(ctx context.Context, userID uuid.UUID) error
+	RecordSecondFactor(ctx context.Context, userID uuid.UUID, totpStep int64) error
+	UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error)
+	DeletePortfolio(ctx context.Context, userID, portfolioID uuid.UUID) error
+	RecordAudit(ctx context.Context, entry models.AuditEntry) error
+	GetAuditLog(ctx context.Context, targetUserID *uuid.UUID, limit int) ([]models.AuditEntry, error)
+	GetUserData(ctx context.Context, userID uuid.UUID) (models.UserData, error)
+	CountUserResources(ctx context.Context, userID uuid.UUID, resource string) (int, error)
+	CreateWatchlist(ctx context.Context, userID uuid.UUID, name string, tickers []string) (models.Watchlist, error)
+	ListWatchlists(ctx context.Context, userID uuid.UUID) ([]models.Watchlist, error)
+	GetWatchlist(ctx context.Context, userID, watchlistID uuid.UUID) (models.Watchlist, error)
+	DeleteWatchlist(ctx context.Context, userID, watchlistID uuid.UUID) error
+	UpdateWatchlistTickers(ctx context.Context, userID, watchlistID uuid.UUID, add, remove []string, maxTickers int) (models.Watchlist, error)
+	ListWatchlistMembers(ctx context.Context, userID, watchlistID uuid.UUID) ([]models.WatchlistMember, error)
+	SetWatchlistMember(ctx context.Context, ownerID, watchlistID, memberID uuid.UUID, role string) (models.WatchlistMember, error)
+	RemoveWatchlistMember(ctx context.Context, userID, watchlistID, memberID uuid.UUID) error
+	CreateAlert(ctx context.Context, userID uuid.UUID, alert models.Alert) (models.Alert, error)
+	ListAlerts(ctx context.Context, userID uuid.UUID) ([]models.Alert, error)
+	GetAlert(ctx context.Context, userID, alertID uuid.UUID) (models.Alert, error)
+	UpdateAlert(ctx context.Context, userID, alertID uuid.UUID, alert models.Alert) (models.Alert, error)
+	DeleteAlert(ctx context.Context, userID, alertID uuid.UUID) error
+	EvaluateAlerts(ctx context.Context, at time.Time) ([]models.AlertEvent, int64, error)
+	GetNotificationSettings(ctx context.Context, userID uuid.UUID) (models.NotificationSettings, error)
+	SaveNotificationSettings(ctx context.Context, userID uuid.UUID, settings models.NotificationSettings) error
+	EnqueueNotifications(ctx context.Context, eventIDs []uuid.UUID, channels []string) (int64, error)
+	PendingNotifications(ctx context.Context) ([]models.PendingNotification, error)
+	SentNotificationBatches(ctx context.Context, since time.Time) (map[uuid.UUID]map[string]int, error)
+	MarkNotificationsSent(ctx context.Context, ids []uuid.UUID, batchID uuid.UUID, sentAt time.Time) error
+	RegisterDevice(ctx context.Context, userID uuid.UUID, platform, token, name string) (models.Device, error)
+	ListDevices(ctx context.Context, userID uuid.UUID) ([]models.Device, error)
+	DeleteDevice(ctx context.Context, userID, deviceID uuid.UUID) error
+	RecordPushDelivery(ctx context.Context, delivery models.PushDelivery) error
+	CreateWebhook(ctx context.Context, userID uuid.UUID, url, secret string) (models.Webhook, error)
+	ListWebhooks(ctx context.Context, userID uuid.UUID) ([]models.Webhook, error)
+	GetWebhook(ctx context.Context, userID, webhookID uuid.UUID) (models.Webhook, error)
+	DeleteWebhook(ctx context.Context, userID, webhookID uuid.UUID) error
+	RememberWebhookNonce(ctx context.Context, nonce string, expiresAt time.Time) (bool, error)
+	PurgeWebhookNonces(ctx context.Context, before time.Time) (int64, error)
+	SoftDeleteUser(ctx context.Context, userID uuid.UUID) (time.Time, error)
+	PurgeDeletedUsers(ctx context.Context, deletedBefore time.Time) (int64, error)
 }
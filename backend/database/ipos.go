@@ -0,0 +1,125 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// upsertIPOSQL actualiza la entrada de la compañía sin tocar added_at, que solo cambia al
+// añadir el ticker a los stocks seguidos.
+const upsertIPOSQL = `
+    INSERT INTO ipos (company, symbol, exchange, date, price_low, price_high, shares, status, source, updated_at)
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+    ON CONFLICT (company) DO UPDATE SET
+        symbol = EXCLUDED.symbol,
+        exchange = EXCLUDED.exchange,
+        date = EXCLUDED.date,
+        price_low = EXCLUDED.price_low,
+        price_high = EXCLUDED.price_high,
+        shares = EXCLUDED.shares,
+        status = EXCLUDED.status,
+        source = EXCLUDED.source,
+        updated_at = EXCLUDED.updated_at;`
+
+const ipoColumns = "company, symbol, exchange, date, price_low, price_high, shares, status, source, added_at, updated_at"
+
+// UpsertIPOs guarda las entradas del calendario de IPOs.
+func (c *cockroachDB) UpsertIPOs(ctx context.Context, ipos []models.IPO) error {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	if len(ipos) == 0 {
+		return nil
+	}
+
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error al iniciar transacción del calendario de IPOs: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, upsertIPOSQL)
+	if err != nil {
+		return fmt.Errorf("error al preparar la inserción de IPOs: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, i := range ipos {
+		shares := sql.NullInt64{Int64: int64(i.Shares.Float64), Valid: i.Shares.Valid}
+		if _, err := stmt.ExecContext(ctx, i.Company, i.Symbol, i.Exchange, i.Date,
+			i.PriceLow.NullFloat64, i.PriceHigh.NullFloat64, shares, i.Status, i.Source, i.UpdatedAt); err != nil {
+			return fmt.Errorf("error al guardar la IPO de %s: %w", i.Company, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error al confirmar el calendario de IPOs: %w", err)
+	}
+	return nil
+}
+
+// GetIPOs devuelve las IPOs con fecha entre from y to (incluidas), por fecha y compañía.
+func (c *cockroachDB) GetIPOs(ctx context.Context, from, to time.Time) ([]models.IPO, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	return c.queryIPOs(ctx, "SELECT "+ipoColumns+" FROM ipos WHERE date BETWEEN $1 AND $2 ORDER BY date, company", from, to)
+}
+
+// PendingListings devuelve las IPOs ya cotizando (con precio y ticker, fecha entre since y
+// asOf) que todavía no se han añadido a los stocks seguidos.
+func (c *cockroachDB) PendingListings(ctx context.Context, since, asOf time.Time) ([]models.IPO, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	return c.queryIPOs(ctx, "SELECT "+ipoColumns+` FROM ipos
+        WHERE status = $1 AND symbol <> '' AND date BETWEEN $2 AND $3 AND added_at IS NULL
+        ORDER BY date, symbol`, models.IPOStatusPriced, since, asOf)
+}
+
+// MarkIPOsAdded registra que los tickers symbols se añadieron a los stocks seguidos en at.
+func (c *cockroachDB) MarkIPOsAdded(ctx context.Context, symbols []string, at time.Time) error {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	if len(symbols) == 0 {
+		return nil
+	}
+	if _, err := c.db.ExecContext(ctx,
+		`UPDATE ipos SET added_at = $2 WHERE symbol = ANY($1) AND added_at IS NULL`, pgArray(symbols), at); err != nil {
+		return fmt.Errorf("error al marcar las IPOs añadidas: %w", err)
+	}
+	return nil
+}
+
+func (c *cockroachDB) queryIPOs(ctx context.Context, query string, args ...interface{}) ([]models.IPO, error) {
+	rows, err := c.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener el calendario de IPOs: %w", err)
+	}
+	defer rows.Close()
+
+	ipos := []models.IPO{}
+	for rows.Next() {
+		var i models.IPO
+		var priceLow, priceHigh, shares sql.NullFloat64
+		var addedAt sql.NullTime
+		if err := rows.Scan(&i.Company, &i.Symbol, &i.Exchange, &i.Date, &priceLow, &priceHigh, &shares,
+			&i.Status, &i.Source, &addedAt, &i.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("error al leer una IPO: %w", err)
+		}
+		i.PriceLow = models.NullFloat64{NullFloat64: priceLow}
+		i.PriceHigh = models.NullFloat64{NullFloat64: priceHigh}
+		i.Shares = models.NullFloat64{NullFloat64: shares}
+		i.AddedAt = models.NullTime{NullTime: addedAt}
+		ipos = append(ipos, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error al recorrer el calendario de IPOs: %w", err)
+	}
+	return ipos, nil
+}
@@ -0,0 +1,51 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+func TestUpsertAndGetIPOs(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	sdb := NewStockDB(db)
+	day := time.Date(2025, 1, 8, 0, 0, 0, 0, time.UTC)
+	updatedAt := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	ipo := models.IPO{Symbol: "NEWC", Company: "NewCo Inc", Exchange: "NASDAQ", Date: day,
+		PriceLow: models.NewNullFloat64(14), PriceHigh: models.NewNullFloat64(16), Shares: models.NewNullFloat64(5e6),
+		Status: models.IPOStatusExpected, Source: "finnhub", UpdatedAt: updatedAt}
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare(regexp.QuoteMeta(upsertIPOSQL))
+	mock.ExpectExec(regexp.QuoteMeta(upsertIPOSQL)).
+		WithArgs("NewCo Inc", "NEWC", "NASDAQ", day, ipo.PriceLow.NullFloat64, ipo.PriceHigh.NullFloat64,
+			sql.NullInt64{Int64: 5000000, Valid: true}, "expected", "finnhub", updatedAt).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	if err := sdb.UpsertIPOs(context.Background(), []models.IPO{ipo}); err != nil {
+		t.Errorf("❌ error inesperado al guardar las IPOs: %v", err)
+	}
+
+	columns := []string{"company", "symbol", "exchange", "date", "price_low", "price_high", "shares", "status", "source", "added_at", "updated_at"}
+	from, to := day.AddDate(0, 0, -7), day.AddDate(0, 0, 7)
+	mock.ExpectQuery(regexp.QuoteMeta("FROM ipos WHERE date BETWEEN $1 AND $2 ORDER BY date, company")).WithArgs(from, to).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow("NewCo Inc", "NEWC", "NASDAQ", day, 14.0, 16.0, 5e6, "expected", "finnhub", nil, updatedAt))
+	got, err := sdb.GetIPOs(context.Background(), from, to)
+	if err != nil || len(got) != 1 || got[0].Symbol != "NEWC" || got[0].PriceHigh.Float64 != 16 || got[0].AddedAt.Valid {
+		t.Errorf("❌ IPOs inesperadas: %+v (%v)", got, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("⚠️ expectativas no cumplidas en TestUpsertAndGetIPOs: %s", err)
+	}
+}
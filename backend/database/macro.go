@@ -0,0 +1,114 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// upsertMacroEventSQL actualiza el evento con las cifras más recientes: actual se rellena
+// cuando se publica el dato y la estimación puede revisarse hasta entonces.
+const upsertMacroEventSQL = `
+    INSERT INTO macro_events (country, event, time, category, impact, actual, estimate, previous, unit, source, updated_at)
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+    ON CONFLICT (country, event, time) DO UPDATE SET
+        category = EXCLUDED.category,
+        impact = EXCLUDED.impact,
+        actual = EXCLUDED.actual,
+        estimate = EXCLUDED.estimate,
+        previous = EXCLUDED.previous,
+        unit = EXCLUDED.unit,
+        source = EXCLUDED.source,
+        updated_at = EXCLUDED.updated_at;`
+
+const macroEventColumns = "country, event, time, category, impact, actual, estimate, previous, unit, source, updated_at"
+
+// MacroEventFilters restringe los eventos que devuelve GetMacroEvents. Los campos vacíos
+// no filtran.
+type MacroEventFilters struct {
+	Country  string
+	Category string
+	Impact   string
+}
+
+// UpsertMacroEvents guarda los eventos del calendario económico.
+func (c *cockroachDB) UpsertMacroEvents(ctx context.Context, events []models.MacroEvent) error {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	if len(events) == 0 {
+		return nil
+	}
+
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error al iniciar transacción del calendario económico: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, upsertMacroEventSQL)
+	if err != nil {
+		return fmt.Errorf("error al preparar la inserción de eventos económicos: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, e := range events {
+		if _, err := stmt.ExecContext(ctx, e.Country, e.Event, e.Time, e.Category, e.Impact,
+			e.Actual.NullFloat64, e.Estimate.NullFloat64, e.Previous.NullFloat64, e.Unit, e.Source, e.UpdatedAt); err != nil {
+			return fmt.Errorf("error al guardar el evento económico %s (%s): %w", e.Event, e.Country, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error al confirmar el calendario económico: %w", err)
+	}
+	return nil
+}
+
+// GetMacroEvents devuelve los eventos programados entre from (incluido) y to (excluido)
+// que cumplen los filtros, por hora.
+func (c *cockroachDB) GetMacroEvents(ctx context.Context, from, to time.Time, filters MacroEventFilters) ([]models.MacroEvent, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	where := []string{"time >= $1", "time < $2"}
+	args := []interface{}{from, to}
+	for _, f := range []struct{ column, value string }{
+		{"country", filters.Country}, {"category", filters.Category}, {"impact", filters.Impact},
+	} {
+		if f.value != "" {
+			args = append(args, f.value)
+			where = append(where, fmt.Sprintf("%s = $%d", f.column, len(args)))
+		}
+	}
+	query := "SELECT " + macroEventColumns + " FROM macro_events WHERE " + strings.Join(where, " AND ") + " ORDER BY time, country, event"
+
+	rows, err := c.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener el calendario económico: %w", err)
+	}
+	defer rows.Close()
+
+	events := []models.MacroEvent{}
+	for rows.Next() {
+		var e models.MacroEvent
+		var actual, estimate, previous sql.NullFloat64
+		if err := rows.Scan(&e.Country, &e.Event, &e.Time, &e.Category, &e.Impact, &actual, &estimate, &previous,
+			&e.Unit, &e.Source, &e.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("error al leer un evento económico: %w", err)
+		}
+		e.Actual = models.NullFloat64{NullFloat64: actual}
+		e.Estimate = models.NullFloat64{NullFloat64: estimate}
+		e.Previous = models.NullFloat64{NullFloat64: previous}
+		e.SetSurprise()
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error al recorrer el calendario económico: %w", err)
+	}
+	return events, nil
+}
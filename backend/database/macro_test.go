@@ -0,0 +1,50 @@
+package database
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+func TestUpsertAndGetMacroEvents(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	sdb := NewStockDB(db)
+	at := time.Date(2025, 1, 15, 13, 30, 0, 0, time.UTC)
+	updatedAt := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	event := models.MacroEvent{Country: "US", Event: "CPI MoM", Category: models.MacroCategoryInflation, Impact: "high",
+		Time: at, Estimate: models.NewNullFloat64(0.3), Previous: models.NewNullFloat64(0.3), Unit: "%", Source: "finnhub", UpdatedAt: updatedAt}
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare(regexp.QuoteMeta(upsertMacroEventSQL))
+	mock.ExpectExec(regexp.QuoteMeta(upsertMacroEventSQL)).
+		WithArgs("US", "CPI MoM", at, "inflation", "high", event.Actual.NullFloat64, event.Estimate.NullFloat64,
+			event.Previous.NullFloat64, "%", "finnhub", updatedAt).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	if err := sdb.UpsertMacroEvents(context.Background(), []models.MacroEvent{event}); err != nil {
+		t.Errorf("❌ error inesperado al guardar el calendario económico: %v", err)
+	}
+
+	columns := []string{"country", "event", "time", "category", "impact", "actual", "estimate", "previous", "unit", "source", "updated_at"}
+	from, to := at.Truncate(24*time.Hour), at.Truncate(24*time.Hour).AddDate(0, 0, 1)
+	mock.ExpectQuery(regexp.QuoteMeta("FROM macro_events WHERE time >= $1 AND time < $2 AND country = $3 AND impact = $4 ORDER BY time, country, event")).
+		WithArgs(from, to, "US", "high").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow("US", "CPI MoM", at, "inflation", "high", 0.4, 0.3, 0.3, "%", "finnhub", updatedAt))
+	got, err := sdb.GetMacroEvents(context.Background(), from, to, MacroEventFilters{Country: "US", Impact: "high"})
+	if err != nil || len(got) != 1 || got[0].Actual.Float64 != 0.4 || !got[0].Surprise.Valid {
+		t.Errorf("❌ eventos inesperados: %+v (%v)", got, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("⚠️ expectativas no cumplidas en TestUpsertAndGetMacroEvents: %s", err)
+	}
+}
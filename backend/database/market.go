@@ -0,0 +1,111 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// heatmapQuery lee el heatmap de su vista materializada, agrupado por sector.
+var heatmapQuery = `SELECT sector_key, ticker, company, market_capitalization, change,
+        sector_stock_count, sector_market_cap, sector_change
+    FROM ` + heatmapView + `
+    ORDER BY sector_market_cap DESC, sector_key ASC, market_capitalization DESC NULLS LAST, ticker ASC`
+
+// GetMarketHeatmap devuelve los sectores ordenados por capitalización total, cada uno con su
+// variación diaria media ponderada por capitalización y sus stocks. Los datos son los de la
+// última ejecución del enricher (ver RefreshAggregateViews).
+func (c *cockroachDB) GetMarketHeatmap(ctx context.Context) ([]models.HeatmapSector, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	rows, err := c.db.QueryContext(ctx, heatmapQuery)
+	if err != nil {
+		return nil, fmt.Errorf("error al consultar el heatmap del mercado: %w", err)
+	}
+	defer rows.Close()
+
+	var sectors []models.HeatmapSector
+	for rows.Next() {
+		var sector string
+		var tile models.HeatmapTile
+		var marketCap, change, weightedChange sql.NullFloat64
+		var count int
+		var sectorMarketCap float64
+		if err := rows.Scan(&sector, &tile.Ticker, &tile.Company, &marketCap, &change, &count, &sectorMarketCap, &weightedChange); err != nil {
+			return nil, fmt.Errorf("error al escanear fila del heatmap: %w", err)
+		}
+		tile.MarketCap = models.NullFloat64{NullFloat64: marketCap}
+		tile.ChangePercent = models.NullFloat64{NullFloat64: change}
+
+		// Las filas llegan agrupadas por sector, así que basta con comparar con el último.
+		if len(sectors) == 0 || sectors[len(sectors)-1].Sector != sector {
+			sectors = append(sectors, models.HeatmapSector{
+				Sector:        sector,
+				StockCount:    count,
+				MarketCap:     sectorMarketCap,
+				ChangePercent: models.NullFloat64{NullFloat64: weightedChange},
+			})
+		}
+		last := &sectors[len(sectors)-1]
+		last.Stocks = append(last.Stocks, tile)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error después de iterar el heatmap: %w", err)
+	}
+
+	return sectors, nil
+}
+
+// moversQuery devuelve los limit stocks que más suben y los limit que más bajan en la última
+// sesión no posterior a $1, marcados con 'g' o 'l'.
+var moversQuery = `WITH day AS (
+        SELECT max(latest_trading_day::DATE) AS d FROM stocks WHERE latest_trading_day::DATE <= $1
+    ), changes AS (
+        SELECT ticker, company, current_price, previous_close, ` + changePercentExpr + ` AS change, day.d
+        FROM stocks, day
+        WHERE latest_trading_day::DATE = day.d AND previous_close > 0
+    )
+    (SELECT 'g', ticker, company, current_price, previous_close, change, d FROM changes WHERE change > 0 ORDER BY change DESC, ticker LIMIT $2)
+    UNION ALL
+    (SELECT 'l', ticker, company, current_price, previous_close, change, d FROM changes WHERE change < 0 ORDER BY change ASC, ticker LIMIT $2)`
+
+// GetMarketMovers devuelve los stocks con mayor subida y mayor bajada de la última sesión
+// hasta el día day inclusive (el "hoy" del cliente, que en fin de semana o festivo es la
+// sesión anterior).
+func (c *cockroachDB) GetMarketMovers(ctx context.Context, day time.Time, limit int) (models.MarketMovers, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	movers := models.MarketMovers{Gainers: []models.Mover{}, Losers: []models.Mover{}}
+	rows, err := c.db.QueryContext(ctx, moversQuery, day.Format("2006-01-02"), limit)
+	if err != nil {
+		return movers, fmt.Errorf("error al consultar los movers del mercado: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var kind string
+		var m models.Mover
+		var previousClose sql.NullFloat64
+		var tradingDay time.Time
+		if err := rows.Scan(&kind, &m.Ticker, &m.Company, &m.CurrentPrice, &previousClose, &m.ChangePercent, &tradingDay); err != nil {
+			return movers, fmt.Errorf("error al escanear fila de movers: %w", err)
+		}
+		m.PreviousClose = models.NullFloat64{NullFloat64: previousClose}
+		movers.TradingDay = models.NullTime{NullTime: sql.NullTime{Time: tradingDay, Valid: true}}
+		if kind == "g" {
+			movers.Gainers = append(movers.Gainers, m)
+		} else {
+			movers.Losers = append(movers.Losers, m)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return movers, fmt.Errorf("error después de iterar los movers: %w", err)
+	}
+	return movers, nil
+}
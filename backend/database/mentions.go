@@ -0,0 +1,49 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+const upsertMentionCountSQL = `
+    INSERT INTO stock_mentions (ticker, day, source, mentions, recorded_at)
+    VALUES ($1, $2, $3, $4, now())
+    ON CONFLICT (ticker, day, source) DO UPDATE SET
+        mentions = EXCLUDED.mentions,
+        recorded_at = now();`
+
+// RecordMentions guarda las menciones diarias. Si ya hay un recuento del mismo ticker, día y
+// fuente se reemplaza: el último recuento del día es el más completo.
+func (c *cockroachDB) RecordMentions(ctx context.Context, counts []models.MentionCount) error {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	if len(counts) == 0 {
+		return nil
+	}
+
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error al iniciar transacción del histórico de menciones: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, upsertMentionCountSQL)
+	if err != nil {
+		return fmt.Errorf("error al preparar la inserción en el histórico de menciones: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, m := range counts {
+		if _, err := stmt.ExecContext(ctx, m.Ticker, m.Day, m.Source, m.Mentions); err != nil {
+			return fmt.Errorf("error al guardar las menciones de %s del %s: %w", m.Ticker, m.Day.Format("2006-01-02"), err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error al confirmar el histórico de menciones: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package database
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+func TestRecordMentions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	sdb := NewStockDB(db)
+	day := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+
+	// Sin recuentos no se abre ninguna transacción.
+	if err := sdb.RecordMentions(context.Background(), nil); err != nil {
+		t.Errorf("❌ error inesperado sin menciones: %v", err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare(regexp.QuoteMeta(upsertMentionCountSQL))
+	mock.ExpectExec(regexp.QuoteMeta(upsertMentionCountSQL)).WithArgs("GME", day, "finnhub", 1520).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta(upsertMentionCountSQL)).WithArgs("AAPL", day, "finnhub", 0).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	err = sdb.RecordMentions(context.Background(), []models.MentionCount{
+		{Ticker: "GME", Day: day, Source: "finnhub", Mentions: 1520},
+		{Ticker: "AAPL", Day: day, Source: "finnhub", Mentions: 0},
+	})
+	if err != nil {
+		t.Errorf("❌ error inesperado al guardar menciones: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("⚠️ expectativas no cumplidas en TestRecordMentions: %s", err)
+	}
+}
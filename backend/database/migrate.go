@@ -0,0 +1,289 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// migrationFiles son las migraciones del esquema, embebidas en el binario. Cada versión es
+// un par NNNN_nombre.up.sql / NNNN_nombre.down.sql; las sentencias terminan con ; al final
+// de una línea (ver splitStatements).
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationFileName reconoce el nombre de un fichero de migración: versión, nombre y sentido.
+var migrationFileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// createMigrationsTableSQL registra las migraciones aplicadas.
+const createMigrationsTableSQL = `
+    CREATE TABLE IF NOT EXISTS schema_migrations (
+        version INT8 PRIMARY KEY,
+        name TEXT NOT NULL,
+        applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+    );`
+
+// Migration es una versión del esquema con las sentencias para aplicarla y revertirla.
+type Migration struct {
+	Version int
+	Name    string
+	up      []string
+	down    []string
+}
+
+// MigrationStatus es el estado de una migración en la base de datos.
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time // nil si está pendiente
+}
+
+// loadMigrations lee las migraciones de fsys (un directorio migrations/ con los ficheros
+// .sql), ordenadas por versión. Cada versión debe tener su up y su down con el mismo nombre.
+func loadMigrations(fsys fs.FS) ([]Migration, error) {
+	files, err := fs.Glob(fsys, "migrations/*.sql")
+	if err != nil {
+		return nil, fmt.Errorf("error al listar las migraciones: %w", err)
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, file := range files {
+		match := migrationFileName.FindStringSubmatch(path.Base(file))
+		if match == nil {
+			return nil, fmt.Errorf("nombre de migración inválido: %s (se esperaba NNNN_nombre.up.sql o NNNN_nombre.down.sql)", file)
+		}
+		version, _ := strconv.Atoi(match[1])
+		if version <= 0 {
+			return nil, fmt.Errorf("versión de migración inválida: %s (debe ser mayor que 0)", file)
+		}
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("la versión %d de las migraciones tiene dos nombres: %s y %s", version, m.Name, match[2])
+		}
+
+		script, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, fmt.Errorf("error al leer la migración %s: %w", file, err)
+		}
+		statements := splitStatements(string(script))
+		if len(statements) == 0 {
+			return nil, fmt.Errorf("la migración %s no tiene sentencias", file)
+		}
+		if match[3] == "up" {
+			m.up = statements
+		} else {
+			m.down = statements
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.up == nil || m.down == nil {
+			return nil, fmt.Errorf("a la migración %04d_%s le falta el fichero up o el down", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// splitStatements separa un script en sentencias: cada una termina en la línea que acaba con
+// ;. Se descartan los comentarios de línea completa (--) y las líneas vacías.
+func splitStatements(script string) []string {
+	var statements, current []string
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		current = append(current, strings.TrimRight(line, " \t\r"))
+		if strings.HasSuffix(trimmed, ";") {
+			statements = append(statements, strings.Join(current, "\n"))
+			current = nil
+		}
+	}
+	if len(current) > 0 {
+		statements = append(statements, strings.Join(current, "\n")) // Última sentencia sin ;
+	}
+	return statements
+}
+
+// MigrateUp aplica, en orden, las migraciones embebidas que aún no se han aplicado y
+// devuelve las aplicadas. Sustituye a la antigua InitSchema: en una base de datos creada
+// por ella, la migración 1 no cambia nada y solo queda registrada.
+func MigrateUp(ctx context.Context, db *sql.DB) ([]Migration, error) {
+	migrations, err := loadMigrations(migrationFiles)
+	if err != nil {
+		return nil, err
+	}
+	return migrateUp(ctx, db, migrations)
+}
+
+// MigrateDown revierte las últimas steps migraciones aplicadas, de la más reciente a la más
+// antigua, y devuelve las revertidas.
+func MigrateDown(ctx context.Context, db *sql.DB, steps int) ([]Migration, error) {
+	migrations, err := loadMigrations(migrationFiles)
+	if err != nil {
+		return nil, err
+	}
+	return migrateDown(ctx, db, migrations, steps)
+}
+
+// MigrationStatuses devuelve todas las migraciones embebidas, en orden, indicando cuáles se
+// han aplicado y cuándo.
+func MigrationStatuses(ctx context.Context, db *sql.DB) ([]MigrationStatus, error) {
+	migrations, err := loadMigrations(migrationFiles)
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]MigrationStatus, len(migrations))
+	for i, m := range migrations {
+		statuses[i] = MigrationStatus{Version: m.Version, Name: m.Name}
+		if at, ok := applied[m.Version]; ok {
+			statuses[i].AppliedAt = &at
+		}
+	}
+	return statuses, nil
+}
+
+// migrateUp aplica las migraciones pendientes de migrations. CockroachDB no admite todos los
+// cambios de esquema dentro de una transacción, así que cada sentencia se ejecuta por
+// separado y la versión se registra al terminar: una migración que falla a medias se repite
+// entera en el siguiente arranque, por eso se escriben con IF [NOT] EXISTS. Varias
+// instancias que arrancan a la vez pueden aplicar la misma migración; el registro no falla
+// por ello.
+func migrateUp(ctx context.Context, db *sql.DB, migrations []Migration) ([]Migration, error) {
+	applied, err := appliedMigrations(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	known := map[int]bool{}
+	for _, m := range migrations {
+		known[m.Version] = true
+	}
+	for version := range applied {
+		if !known[version] {
+			log.Printf("Advertencia: la migración %d está aplicada pero no existe en este binario (¿versión anterior del backend?).", version)
+		}
+	}
+
+	var done []Migration
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+		start := time.Now()
+		if err := execStatements(ctx, db, m.up); err != nil {
+			return done, fmt.Errorf("error al aplicar la migración %04d_%s: %w", m.Version, m.Name, err)
+		}
+		if _, err := db.ExecContext(ctx,
+			`INSERT INTO schema_migrations (version, name) VALUES ($1, $2) ON CONFLICT (version) DO NOTHING`,
+			m.Version, m.Name); err != nil {
+			return done, fmt.Errorf("error al registrar la migración %04d_%s: %w", m.Version, m.Name, err)
+		}
+		log.Printf("Migración %04d_%s aplicada en %s.", m.Version, m.Name, time.Since(start).Round(time.Millisecond))
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+// migrateDown revierte las últimas steps migraciones aplicadas de migrations.
+func migrateDown(ctx context.Context, db *sql.DB, migrations []Migration, steps int) ([]Migration, error) {
+	if steps <= 0 {
+		return nil, fmt.Errorf("número de migraciones a revertir inválido: %d", steps)
+	}
+	applied, err := appliedMigrations(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	versions := make([]int, 0, len(applied))
+	for version := range applied {
+		versions = append(versions, version)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(versions)))
+	if steps > len(versions) {
+		steps = len(versions)
+	}
+
+	byVersion := map[int]Migration{}
+	for _, m := range migrations {
+		byVersion[m.Version] = m
+	}
+	var done []Migration
+	for _, version := range versions[:steps] {
+		m, ok := byVersion[version]
+		if !ok {
+			return done, fmt.Errorf("la migración %d no existe en este binario: no se puede revertir", version)
+		}
+		start := time.Now()
+		if err := execStatements(ctx, db, m.down); err != nil {
+			return done, fmt.Errorf("error al revertir la migración %04d_%s: %w", m.Version, m.Name, err)
+		}
+		if _, err := db.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = $1`, m.Version); err != nil {
+			return done, fmt.Errorf("error al desregistrar la migración %04d_%s: %w", m.Version, m.Name, err)
+		}
+		log.Printf("Migración %04d_%s revertida en %s.", m.Version, m.Name, time.Since(start).Round(time.Millisecond))
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+// appliedMigrations crea schema_migrations si no existe y devuelve las versiones aplicadas
+// con su fecha.
+func appliedMigrations(ctx context.Context, db *sql.DB) (map[int]time.Time, error) {
+	if _, err := db.ExecContext(ctx, createMigrationsTableSQL); err != nil {
+		return nil, fmt.Errorf("error al crear/verificar la tabla 'schema_migrations': %w", err)
+	}
+	rows, err := db.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("error al consultar las migraciones aplicadas: %w", err)
+	}
+	defer rows.Close()
+
+	applied := map[int]time.Time{}
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("error al escanear la migración aplicada: %w", err)
+		}
+		applied[version] = appliedAt
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error después de iterar las migraciones aplicadas: %w", err)
+	}
+	return applied, nil
+}
+
+// execStatements ejecuta las sentencias en orden y se detiene en la primera que falla.
+func execStatements(ctx context.Context, db *sql.DB, statements []string) error {
+	for _, statement := range statements {
+		if _, err := db.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("%w (sentencia: %s)", err, firstLine(statement))
+		}
+	}
+	return nil
+}
+
+// firstLine devuelve la primera línea de una sentencia, para identificarla en los errores.
+func firstLine(statement string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(statement), "\n")
+	return line
+}
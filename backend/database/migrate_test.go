@@ -0,0 +1,279 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestLoadMigrations_Embedded(t *testing.T) {
+	migrations, err := loadMigrations(migrationFiles)
+	if err != nil {
+		t.Fatalf("❌ error al cargar las migraciones embebidas: %v", err)
+	}
+	if len(migrations) == 0 || migrations[0].Version != 1 || migrations[0].Name != "baseline" {
+		t.Fatalf("❌ la primera migración debe ser 0001_baseline: %+v", migrations)
+	}
+
+	// Las migraciones crean todas las tablas que comprueba MissingTables y su down borra lo
+	// que crea su up.
+	createTable := regexp.MustCompile(`^CREATE (TABLE|MATERIALIZED VIEW) IF NOT EXISTS (\w+)`)
+	dropTable := regexp.MustCompile(`^DROP (TABLE|MATERIALIZED VIEW) IF EXISTS (\w+)`)
+	created := map[string]bool{"schema_migrations": true}
+	for i, m := range migrations {
+		if i > 0 && m.Version <= migrations[i-1].Version {
+			t.Errorf("❌ migraciones desordenadas: %d después de %d", m.Version, migrations[i-1].Version)
+		}
+		var up, down []string
+		for _, statement := range m.up {
+			if match := createTable.FindStringSubmatch(statement); match != nil {
+				up = append(up, match[2])
+				created[match[2]] = true
+			}
+		}
+		for _, statement := range m.down {
+			if match := dropTable.FindStringSubmatch(statement); match != nil {
+				down = append(down, match[2])
+			}
+		}
+		for _, name := range up {
+			if !strings.Contains(" "+strings.Join(down, " ")+" ", " "+name+" ") {
+				t.Errorf("❌ el down de %04d_%s no borra %s", m.Version, m.Name, name)
+			}
+		}
+	}
+	for _, table := range schemaTables {
+		if !created[table] {
+			t.Errorf("❌ ninguna migración crea la tabla %s", table)
+		}
+	}
+	for _, view := range aggregateViews {
+		if !created[view] {
+			t.Errorf("❌ ninguna migración crea la vista materializada %s", view)
+		}
+	}
+}
+
+func TestLoadMigrations_Invalid(t *testing.T) {
+	file := func(sql string) *fstest.MapFile { return &fstest.MapFile{Data: []byte(sql)} }
+	tests := map[string]fstest.MapFS{
+		"nombre inválido": {
+			"migrations/1_init.up.sql":   file("SELECT 1;"),
+			"migrations/1_init.down.sql": file("SELECT 1;"),
+			"migrations/init.sql":        file("SELECT 1;"),
+		},
+		"versión cero": {
+			"migrations/0000_init.up.sql":   file("SELECT 1;"),
+			"migrations/0000_init.down.sql": file("SELECT 1;"),
+		},
+		"falta el down": {
+			"migrations/0001_init.up.sql": file("SELECT 1;"),
+		},
+		"dos nombres para la misma versión": {
+			"migrations/0001_init.up.sql":    file("SELECT 1;"),
+			"migrations/0001_other.down.sql": file("SELECT 1;"),
+		},
+		"sin sentencias": {
+			"migrations/0001_init.up.sql":   file("-- nada que hacer\n"),
+			"migrations/0001_init.down.sql": file("SELECT 1;"),
+		},
+	}
+	for name, fsys := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := loadMigrations(fsys); err == nil {
+				t.Error("❌ se esperaba un error")
+			}
+		})
+	}
+}
+
+func TestLoadMigrations_Order(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/0010_later.up.sql":   {Data: []byte("SELECT 10;")},
+		"migrations/0010_later.down.sql": {Data: []byte("SELECT -10;")},
+		"migrations/0002_first.up.sql":   {Data: []byte("SELECT 2;")},
+		"migrations/0002_first.down.sql": {Data: []byte("SELECT -2;")},
+	}
+	migrations, err := loadMigrations(fsys)
+	if err != nil {
+		t.Fatalf("❌ error inesperado: %v", err)
+	}
+	if len(migrations) != 2 || migrations[0].Version != 2 || migrations[1].Version != 10 {
+		t.Errorf("❌ se esperaban las versiones 2 y 10 en orden: %+v", migrations)
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	script := `-- Comentario
+CREATE TABLE a (
+    id INT -- columna
+);
+
+ALTER TABLE a ADD COLUMN b INT;
+SELECT 'sin punto y coma final'
+`
+	want := []string{
+		"CREATE TABLE a (\n    id INT -- columna\n);",
+		"ALTER TABLE a ADD COLUMN b INT;",
+		"SELECT 'sin punto y coma final'",
+	}
+	if got := splitStatements(script); !reflect.DeepEqual(got, want) {
+		t.Errorf("❌ sentencias = %q, se esperaba %q", got, want)
+	}
+}
+
+// testMigrations son dos migraciones de prueba con dos sentencias cada una.
+var testMigrations = []Migration{
+	{Version: 1, Name: "init", up: []string{"CREATE TABLE a (id INT);", "CREATE TABLE b (id INT);"},
+		down: []string{"DROP TABLE b;", "DROP TABLE a;"}},
+	{Version: 2, Name: "add_c", up: []string{"ALTER TABLE a ADD COLUMN c INT;", "CREATE INDEX a_c_idx ON a (c);"},
+		down: []string{"DROP INDEX a_c_idx;", "ALTER TABLE a DROP COLUMN c;"}},
+}
+
+// expectApplied espera la creación de schema_migrations y la consulta de las versiones aplicadas.
+func expectApplied(mock sqlmock.Sqlmock, versions ...int) {
+	mock.ExpectExec(regexp.QuoteMeta("CREATE TABLE IF NOT EXISTS schema_migrations (")).WillReturnResult(sqlmock.NewResult(0, 0))
+	rows := sqlmock.NewRows([]string{"version", "applied_at"})
+	for _, version := range versions {
+		rows.AddRow(version, time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+	}
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT version, applied_at FROM schema_migrations")).WillReturnRows(rows)
+}
+
+func TestMigrateUp(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	// La migración 1 ya está aplicada: solo se ejecuta la 2 y se registra
+	expectApplied(mock, 1)
+	mock.ExpectExec(regexp.QuoteMeta("ALTER TABLE a ADD COLUMN c INT;")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("CREATE INDEX a_c_idx ON a (c);")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO schema_migrations (version, name) VALUES ($1, $2) ON CONFLICT (version) DO NOTHING")).
+		WithArgs(2, "add_c").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	applied, err := migrateUp(context.Background(), db, testMigrations)
+	if err != nil {
+		t.Fatalf("❌ error inesperado: %v", err)
+	}
+	if len(applied) != 1 || applied[0].Version != 2 {
+		t.Errorf("❌ se esperaba aplicar solo la migración 2: %+v", applied)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ expectativas no cumplidas: %v", err)
+	}
+}
+
+func TestMigrateUp_FailureIsNotRecorded(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	// La 1 se aplica; la 2 falla en su segunda sentencia y no se registra ni sigue
+	expectApplied(mock)
+	mock.ExpectExec(regexp.QuoteMeta("CREATE TABLE a (id INT);")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("CREATE TABLE b (id INT);")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO schema_migrations")).WithArgs(1, "init").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("ALTER TABLE a ADD COLUMN c INT;")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("CREATE INDEX a_c_idx ON a (c);")).WillReturnError(errors.New("índice duplicado"))
+
+	applied, err := migrateUp(context.Background(), db, testMigrations)
+	if err == nil || !strings.Contains(err.Error(), "0002_add_c") || !strings.Contains(err.Error(), "CREATE INDEX a_c_idx") {
+		t.Errorf("❌ se esperaba un error que nombre la migración y la sentencia, se obtuvo: %v", err)
+	}
+	if len(applied) != 1 || applied[0].Version != 1 {
+		t.Errorf("❌ se esperaba haber aplicado solo la migración 1: %+v", applied)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ expectativas no cumplidas: %v", err)
+	}
+}
+
+func TestMigrateUp_Baseline(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	migrations, err := loadMigrations(migrationFiles)
+	if err != nil {
+		t.Fatalf("❌ error al cargar las migraciones embebidas: %v", err)
+	}
+
+	// En una base de datos vacía se ejecutan todas las sentencias de todas las migraciones
+	expectApplied(mock)
+	for _, m := range migrations {
+		for _, statement := range m.up {
+			mock.ExpectExec(regexp.QuoteMeta(statement)).WillReturnResult(sqlmock.NewResult(0, 0))
+		}
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO schema_migrations")).WithArgs(m.Version, m.Name).WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+
+	applied, err := MigrateUp(context.Background(), db)
+	if err != nil {
+		t.Fatalf("❌ error inesperado al migrar el esquema: %v", err)
+	}
+	if len(applied) != len(migrations) {
+		t.Errorf("❌ %d migraciones aplicadas, se esperaban %d", len(applied), len(migrations))
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("⚠️ expectativas no cumplidas en TestMigrateUp_Baseline: %s", err)
+	}
+}
+
+func TestMigrateDown(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	// Se revierte la más reciente de las aplicadas, con las sentencias de su down en orden
+	expectApplied(mock, 1, 2)
+	mock.ExpectExec(regexp.QuoteMeta("DROP INDEX a_c_idx;")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("ALTER TABLE a DROP COLUMN c;")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM schema_migrations WHERE version = $1")).WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	reverted, err := migrateDown(context.Background(), db, testMigrations, 1)
+	if err != nil {
+		t.Fatalf("❌ error inesperado: %v", err)
+	}
+	if len(reverted) != 1 || reverted[0].Version != 2 {
+		t.Errorf("❌ se esperaba revertir solo la migración 2: %+v", reverted)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ expectativas no cumplidas: %v", err)
+	}
+}
+
+func TestMigrateDown_UnknownVersion(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	// La versión 3 la aplicó un binario más nuevo: este no sabe revertirla
+	expectApplied(mock, 1, 2, 3)
+	if _, err := migrateDown(context.Background(), db, testMigrations, 1); err == nil {
+		t.Error("❌ se esperaba un error al revertir una migración desconocida")
+	}
+	if _, err := migrateDown(context.Background(), db, testMigrations, 0); err == nil {
+		t.Error("❌ se esperaba un error con 0 migraciones a revertir")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ expectativas no cumplidas: %v", err)
+	}
+}
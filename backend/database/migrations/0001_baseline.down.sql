@@ -0,0 +1,35 @@
+-- Borra todo el esquema de partida, con sus datos. Las vistas y las tablas que referencian
+-- a otras van primero.
+
+DROP MATERIALIZED VIEW IF EXISTS brokerage_stats_v1;
+DROP MATERIALIZED VIEW IF EXISTS sector_stats_v1;
+DROP MATERIALIZED VIEW IF EXISTS market_heatmap_v1;
+
+DROP TABLE IF EXISTS enrichment_runs;
+DROP TABLE IF EXISTS enrichment_results;
+DROP TABLE IF EXISTS macro_events;
+DROP TABLE IF EXISTS ipos;
+DROP TABLE IF EXISTS corporate_actions;
+DROP TABLE IF EXISTS options_summaries;
+DROP TABLE IF EXISTS stock_mentions;
+DROP TABLE IF EXISTS scoring_rules;
+DROP TABLE IF EXISTS provider_payloads;
+DROP TABLE IF EXISTS import_mappings;
+DROP TABLE IF EXISTS webhook_nonces;
+DROP TABLE IF EXISTS webhooks;
+DROP TABLE IF EXISTS push_deliveries;
+DROP TABLE IF EXISTS devices;
+DROP TABLE IF EXISTS notification_outbox;
+DROP TABLE IF EXISTS notification_settings;
+DROP TABLE IF EXISTS alert_events;
+DROP TABLE IF EXISTS alerts;
+DROP TABLE IF EXISTS audit_log;
+DROP TABLE IF EXISTS user_recovery_codes;
+DROP TABLE IF EXISTS portfolios;
+DROP TABLE IF EXISTS notes;
+DROP TABLE IF EXISTS watchlists;
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS provider_stats;
+DROP TABLE IF EXISTS stock_prices;
+DROP TABLE IF EXISTS enrichment_cursor;
+DROP TABLE IF EXISTS stocks;
@@ -0,0 +1,433 @@
+-- Esquema de partida: las tablas que creaba InitSchema antes de las migraciones. Todo es
+-- IF NOT EXISTS para que una base de datos creada por InitSchema adopte las migraciones
+-- sin cambios; los ALTER actualizan las creadas por versiones aún más antiguas.
+
+CREATE TABLE IF NOT EXISTS stocks (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    ticker VARCHAR(10) NOT NULL UNIQUE,
+    company TEXT,
+    brokerage TEXT,
+    action TEXT,
+    rating_from TEXT,
+    rating_to TEXT,
+    target_from NUMERIC(10, 2) NULL,
+    target_to NUMERIC(10, 2) NULL,
+    current_price DECIMAL(10, 2),
+    pe_ratio DECIMAL(10, 2),
+    dividend_yield DECIMAL(10, 4),
+    market_capitalization DECIMAL(20, 2),
+    alpha DECIMAL(10, 4),
+    latest_trading_day TIMESTAMP WITH TIME ZONE,
+    recommendation_score DECIMAL(5, 2),
+    sector TEXT,
+    previous_close DECIMAL(10, 2),
+    sentiment DECIMAL(4, 3),
+    buzz INT,
+    esg_score DECIMAL(5, 2),
+    short_interest INT8,
+    days_to_cover DECIMAL(6, 2),
+    relative_strength_30d DECIMAL(8, 2),
+    relative_strength_90d DECIMAL(8, 2),
+    provider_errors TEXT,
+    provenance JSONB,
+    enrichment_tier TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT now()
+);
+ALTER TABLE stocks ADD COLUMN IF NOT EXISTS pe_ratio DECIMAL(10, 2);
+ALTER TABLE stocks ADD COLUMN IF NOT EXISTS dividend_yield DECIMAL(10, 4);
+ALTER TABLE stocks ADD COLUMN IF NOT EXISTS market_capitalization DECIMAL(20, 2);
+ALTER TABLE stocks ADD COLUMN IF NOT EXISTS alpha DECIMAL(10, 4);
+ALTER TABLE stocks ADD COLUMN IF NOT EXISTS recommendation_score DECIMAL(5, 2);
+ALTER TABLE stocks ADD COLUMN IF NOT EXISTS sector TEXT;
+ALTER TABLE stocks ADD COLUMN IF NOT EXISTS previous_close DECIMAL(10, 2);
+ALTER TABLE stocks ADD COLUMN IF NOT EXISTS provider_errors TEXT;
+ALTER TABLE stocks ADD COLUMN IF NOT EXISTS provenance JSONB;
+ALTER TABLE stocks ADD COLUMN IF NOT EXISTS enrichment_tier TEXT;
+ALTER TABLE stocks ADD COLUMN IF NOT EXISTS sentiment DECIMAL(4, 3);
+ALTER TABLE stocks ADD COLUMN IF NOT EXISTS buzz INT;
+ALTER TABLE stocks ADD COLUMN IF NOT EXISTS esg_score DECIMAL(5, 2);
+ALTER TABLE stocks ADD COLUMN IF NOT EXISTS short_interest INT8;
+ALTER TABLE stocks ADD COLUMN IF NOT EXISTS days_to_cover DECIMAL(6, 2);
+ALTER TABLE stocks ADD COLUMN IF NOT EXISTS relative_strength_30d DECIMAL(8, 2);
+ALTER TABLE stocks ADD COLUMN IF NOT EXISTS relative_strength_90d DECIMAL(8, 2);
+
+-- Cursor del enricher. snapshot_at es el instante (reloj de la base de datos) en que empezó
+-- la ejecución en curso, que es la instantánea que leen los listados mientras dura.
+CREATE TABLE IF NOT EXISTS enrichment_cursor (
+    id TEXT PRIMARY KEY,
+    run_id UUID NOT NULL,
+    last_ticker TEXT NOT NULL DEFAULT '',
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    completed BOOLEAN NOT NULL DEFAULT false,
+    snapshot_at TIMESTAMP WITH TIME ZONE
+);
+ALTER TABLE enrichment_cursor ADD COLUMN IF NOT EXISTS snapshot_at TIMESTAMP WITH TIME ZONE;
+
+-- Vela diaria de cada ticker: el cierre y, si el proveedor de la cotización los dio, la
+-- apertura, el máximo, el mínimo y el volumen. Será la tabla más grande en cuanto haya
+-- backfill, así que los precios son REAL de 4 bytes en lugar de DECIMAL de longitud
+-- variable: sus 7 cifras significativas bastan para guardar al céntimo cualquier precio por
+-- debajo de 100.000 (ver roundCents). En las tablas creadas cuando el cierre era DECIMAL la
+-- columna se queda como está: CockroachDB solo la convierte con
+-- enable_experimental_alter_column_type_general, y todo funciona igual, sin el ahorro.
+CREATE TABLE IF NOT EXISTS stock_prices (
+    ticker VARCHAR(10) NOT NULL,
+    trading_day DATE NOT NULL,
+    close REAL NOT NULL,
+    open REAL,
+    high REAL,
+    low REAL,
+    volume INT8,
+    recorded_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    PRIMARY KEY (ticker, trading_day)
+);
+ALTER TABLE stock_prices ADD COLUMN IF NOT EXISTS open REAL;
+ALTER TABLE stock_prices ADD COLUMN IF NOT EXISTS high REAL;
+ALTER TABLE stock_prices ADD COLUMN IF NOT EXISTS low REAL;
+ALTER TABLE stock_prices ADD COLUMN IF NOT EXISTS volume INT8;
+
+-- Por proveedor y día (UTC), las llamadas, los errores por categoría y el histograma de
+-- latencias del que salen los percentiles.
+CREATE TABLE IF NOT EXISTS provider_stats (
+    provider TEXT NOT NULL,
+    day DATE NOT NULL,
+    calls INT8 NOT NULL DEFAULT 0,
+    errors INT8 NOT NULL DEFAULT 0,
+    error_categories JSONB NOT NULL DEFAULT '{}',
+    latency_histogram JSONB NOT NULL DEFAULT '[]',
+    max_ms INT8 NOT NULL DEFAULT 0,
+    p50_ms INT8 NOT NULL DEFAULT 0,
+    p95_ms INT8 NOT NULL DEFAULT 0,
+    p99_ms INT8 NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    PRIMARY KEY (provider, day)
+);
+
+-- Usuarios y sus datos. Todas las tablas de datos tienen deleted_at para el borrado lógico
+-- y se eliminan en cascada al purgar el usuario.
+CREATE TABLE IF NOT EXISTS users (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    email TEXT NOT NULL UNIQUE,
+    password_hash TEXT,
+    email_verified_at TIMESTAMP WITH TIME ZONE,
+    totp_secret TEXT,
+    totp_enabled_at TIMESTAMP WITH TIME ZONE,
+    totp_last_step INT8 NOT NULL DEFAULT 0,
+    second_factor_at TIMESTAMP WITH TIME ZONE,
+    api_key_hash TEXT UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+CREATE TABLE IF NOT EXISTS watchlists (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    tickers TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+CREATE TABLE IF NOT EXISTS notes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    ticker VARCHAR(10) NOT NULL,
+    body TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+CREATE TABLE IF NOT EXISTS portfolios (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    positions JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS password_hash TEXT;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_secret TEXT;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_enabled_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_last_step INT8 NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS second_factor_at TIMESTAMP WITH TIME ZONE;
+CREATE TABLE IF NOT EXISTS user_recovery_codes (
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    code_hash TEXT NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    PRIMARY KEY (user_id, code_hash)
+);
+
+-- Registro de auditoría. target_user_id no referencia a users para que las entradas
+-- sobrevivan a la purga de la cuenta.
+CREATE TABLE IF NOT EXISTS audit_log (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    occurred_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    actor TEXT NOT NULL,
+    action TEXT NOT NULL,
+    target_user_id UUID,
+    method TEXT NOT NULL DEFAULT '',
+    path TEXT NOT NULL DEFAULT '',
+    reason TEXT NOT NULL DEFAULT '',
+    remote_addr TEXT NOT NULL DEFAULT ''
+);
+
+-- Reglas de alerta y registro de alertas disparadas. El índice parcial por ticker es el que
+-- usa la evaluación para cruzar reglas y stocks.
+CREATE TABLE IF NOT EXISTS alerts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    ticker VARCHAR(10) NOT NULL,
+    metric TEXT NOT NULL,
+    operator TEXT NOT NULL,
+    threshold FLOAT8 NOT NULL,
+    baseline FLOAT8,
+    triggered_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+ALTER TABLE alerts ADD COLUMN IF NOT EXISTS baseline FLOAT8;
+CREATE INDEX IF NOT EXISTS alerts_ticker_idx ON alerts (ticker) WHERE deleted_at IS NULL;
+CREATE TABLE IF NOT EXISTS alert_events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    alert_id UUID NOT NULL REFERENCES alerts (id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    ticker VARCHAR(10) NOT NULL,
+    metric TEXT NOT NULL,
+    operator TEXT NOT NULL,
+    threshold FLOAT8 NOT NULL,
+    value FLOAT8 NOT NULL,
+    triggered_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+CREATE INDEX IF NOT EXISTS alert_events_user_idx ON alert_events (user_id, triggered_at DESC);
+
+-- Preferencias de notificación de cada usuario y bandeja de salida: una fila por alerta
+-- disparada y canal, pendiente hasta que se envía (sola o dentro de un resumen, con el mismo
+-- batch_id para todas las del resumen).
+CREATE TABLE IF NOT EXISTS notification_settings (
+    user_id UUID PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    settings JSONB NOT NULL DEFAULT '{}',
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+CREATE TABLE IF NOT EXISTS notification_outbox (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    channel TEXT NOT NULL,
+    event_id UUID NOT NULL REFERENCES alert_events (id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    sent_at TIMESTAMP WITH TIME ZONE,
+    batch_id UUID
+);
+CREATE INDEX IF NOT EXISTS notification_outbox_pending_idx ON notification_outbox (user_id, channel) WHERE sent_at IS NULL;
+CREATE INDEX IF NOT EXISTS notification_outbox_sent_idx ON notification_outbox (sent_at) WHERE sent_at IS NOT NULL;
+
+-- Dispositivos registrados para notificaciones push y registro de entregas a cada uno. El
+-- token es único: si la app se reinstala con otra cuenta, el dispositivo pasa a la nueva.
+CREATE TABLE IF NOT EXISTS devices (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    platform TEXT NOT NULL,
+    token TEXT NOT NULL UNIQUE,
+    name TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    disabled_at TIMESTAMP WITH TIME ZONE
+);
+CREATE TABLE IF NOT EXISTS push_deliveries (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    device_id UUID NOT NULL REFERENCES devices (id) ON DELETE CASCADE,
+    status TEXT NOT NULL,
+    message_id TEXT NOT NULL DEFAULT '',
+    error TEXT NOT NULL DEFAULT '',
+    events INT NOT NULL,
+    sent_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+CREATE INDEX IF NOT EXISTS push_deliveries_device_idx ON push_deliveries (device_id, sent_at DESC);
+
+-- Webhooks de los usuarios y nonces de los webhooks recibidos, que se guardan hasta que su
+-- timestamp sale de la tolerancia.
+CREATE TABLE IF NOT EXISTS webhooks (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+CREATE TABLE IF NOT EXISTS webhook_nonces (
+    nonce TEXT PRIMARY KEY,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+-- Plantillas que asocian las columnas de un CSV a los campos de los stocks, para no tener
+-- que indicarlo en cada importación.
+CREATE TABLE IF NOT EXISTS import_mappings (
+    name TEXT PRIMARY KEY,
+    has_header BOOL NOT NULL DEFAULT false,
+    delimiter TEXT NOT NULL DEFAULT '',
+    columns JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+-- Última respuesta cruda de cada endpoint de los proveedores por ticker, comprimida con
+-- gzip. Se purga con config.ProviderPayloadRetention.
+CREATE TABLE IF NOT EXISTS provider_payloads (
+    ticker TEXT NOT NULL,
+    provider TEXT NOT NULL,
+    endpoint TEXT NOT NULL,
+    status_code INT NOT NULL,
+    body BYTES NOT NULL,
+    truncated BOOL NOT NULL DEFAULT false,
+    fetched_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (ticker, provider, endpoint),
+    INDEX provider_payloads_fetched_at_idx (fetched_at)
+);
+
+-- Reglas de scoring en el lenguaje de expresiones, que producto puede cambiar sin desplegar.
+-- Sin fila se usan las reglas integradas.
+CREATE TABLE IF NOT EXISTS scoring_rules (
+    id TEXT PRIMARY KEY,
+    rules JSONB NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+-- Menciones diarias en redes sociales por ticker y fuente.
+CREATE TABLE IF NOT EXISTS stock_mentions (
+    ticker VARCHAR(10) NOT NULL,
+    day DATE NOT NULL,
+    source STRING NOT NULL,
+    mentions INT NOT NULL,
+    recorded_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    PRIMARY KEY (ticker, day, source)
+);
+
+-- Último resumen de la cadena de opciones de cada ticker.
+CREATE TABLE IF NOT EXISTS options_summaries (
+    ticker VARCHAR(10) PRIMARY KEY,
+    source STRING NOT NULL,
+    nearest_expiration DATE NOT NULL,
+    expirations INT NOT NULL,
+    implied_volatility DECIMAL(8, 4),
+    put_volume DECIMAL(16, 0) NOT NULL,
+    call_volume DECIMAL(16, 0) NOT NULL,
+    put_call_ratio DECIMAL(8, 4),
+    put_open_interest DECIMAL(16, 0) NOT NULL,
+    call_open_interest DECIMAL(16, 0) NOT NULL,
+    put_call_open_interest_ratio DECIMAL(8, 4),
+    fetched_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+-- Eventos corporativos (splits, fusiones y cambios de ticker). Cada ticker tiene como mucho
+-- un evento de cada tipo por fecha.
+CREATE TABLE IF NOT EXISTS corporate_actions (
+    ticker VARCHAR(10) NOT NULL,
+    type STRING NOT NULL,
+    effective_date DATE NOT NULL,
+    split_from DECIMAL(12, 4),
+    split_to DECIMAL(12, 4),
+    new_ticker VARCHAR(10),
+    source STRING NOT NULL,
+    recorded_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    PRIMARY KEY (ticker, type, effective_date),
+    INDEX corporate_actions_new_ticker_idx (new_ticker)
+);
+
+-- Calendario de salidas a bolsa, una fila por compañía.
+CREATE TABLE IF NOT EXISTS ipos (
+    company TEXT PRIMARY KEY,
+    symbol VARCHAR(10) NOT NULL DEFAULT '',
+    exchange TEXT NOT NULL DEFAULT '',
+    date DATE NOT NULL,
+    price_low DECIMAL(10, 2),
+    price_high DECIMAL(10, 2),
+    shares INT8,
+    status STRING NOT NULL,
+    source STRING NOT NULL,
+    added_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    INDEX ipos_date_idx (date)
+);
+
+-- Calendario económico. Cada país tiene como mucho un evento con el mismo nombre a la misma
+-- hora.
+CREATE TABLE IF NOT EXISTS macro_events (
+    country STRING NOT NULL,
+    event STRING NOT NULL,
+    time TIMESTAMP WITH TIME ZONE NOT NULL,
+    category STRING NOT NULL,
+    impact STRING NOT NULL DEFAULT '',
+    actual DECIMAL(18, 4),
+    estimate DECIMAL(18, 4),
+    previous DECIMAL(18, 4),
+    unit STRING NOT NULL DEFAULT '',
+    source STRING NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (country, event, time),
+    INDEX macro_events_time_idx (time)
+);
+
+-- Resultado de cada ticker en cada ejecución del enriquecimiento: los campos actualizados con
+-- su proveedor, las métricas que quedaron nulas y los errores de los proveedores. Se purga
+-- con retention.EnrichmentResultRetention.
+CREATE TABLE IF NOT EXISTS enrichment_results (
+    run_id UUID NOT NULL,
+    ticker TEXT NOT NULL,
+    fields JSONB,
+    null_fields TEXT[] NOT NULL DEFAULT ARRAY[],
+    errors TEXT NOT NULL DEFAULT '',
+    enriched_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (run_id, ticker),
+    INDEX enrichment_results_enriched_at_idx (enriched_at)
+);
+
+-- Historial de ejecuciones del enriquecimiento.
+CREATE TABLE IF NOT EXISTS enrichment_runs (
+    id UUID PRIMARY KEY,
+    status TEXT NOT NULL,
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    finished_at TIMESTAMP WITH TIME ZONE,
+    stocks_processed INT NOT NULL DEFAULT 0,
+    provider_errors JSONB,
+    error TEXT NOT NULL DEFAULT '',
+    INDEX enrichment_runs_started_at_idx (started_at DESC)
+);
+
+-- Vistas materializadas de los agregados pesados (ver aggregate_views.go). Van al final:
+-- leen columnas añadidas por los ALTER anteriores.
+
+-- Heatmap: una fila por stock junto con los agregados de su sector, calculados con
+-- funciones de ventana.
+CREATE MATERIALIZED VIEW IF NOT EXISTS market_heatmap_v1 AS SELECT sector_key, ticker, company, market_capitalization, change,
+    COUNT(*) OVER w AS sector_stock_count,
+    COALESCE(SUM(market_capitalization) OVER w, 0) AS sector_market_cap,
+    SUM(market_capitalization * change) OVER w /
+        NULLIF(SUM(CASE WHEN change IS NOT NULL THEN market_capitalization END) OVER w, 0) AS sector_change
+FROM (
+    SELECT COALESCE(NULLIF(sector, ''), 'Unknown') AS sector_key, ticker, company,
+        market_capitalization, (current_price - previous_close) / NULLIF(previous_close, 0) * 100 AS change
+    FROM stocks
+) s
+WINDOW w AS (PARTITION BY sector_key);
+
+-- Estadísticas por sector, con las mismas columnas que aggregateQuery.
+CREATE MATERIALIZED VIEW IF NOT EXISTS sector_stats_v1 AS SELECT sector_key, COUNT(*) AS stock_count, COALESCE(SUM(market_capitalization), 0) AS market_cap,
+    AVG(change) AS avg_change, AVG(pe_ratio) AS avg_pe_ratio, AVG(dividend_yield) AS avg_dividend_yield,
+    AVG(recommendation_score) AS avg_recommendation_score, MAX(id::STRING) AS max_id
+FROM (SELECT id, COALESCE(NULLIF(sector, ''), 'Unknown') AS sector_key, market_capitalization, (current_price - previous_close) / NULLIF(previous_close, 0) * 100 AS change, pe_ratio, dividend_yield, recommendation_score FROM stocks) s
+GROUP BY sector_key;
+
+-- Estadísticas por casa de análisis. Las acciones del feed son frases como "upgraded by" o
+-- "target raised by".
+CREATE MATERIALIZED VIEW IF NOT EXISTS brokerage_stats_v1 AS SELECT brokerage,
+    COUNT(*) AS ratings,
+    COUNT(*) FILTER (WHERE action ILIKE 'upgraded%') AS upgrades,
+    COUNT(*) FILTER (WHERE action ILIKE 'downgraded%') AS downgrades,
+    COUNT(*) FILTER (WHERE action ILIKE 'target raised%') AS target_raises,
+    COUNT(*) FILTER (WHERE action ILIKE 'target lowered%') AS target_cuts,
+    AVG((target_to - current_price) / NULLIF(current_price, 0) * 100) AS avg_target_upside
+FROM stocks
+WHERE brokerage <> ''
+GROUP BY brokerage;
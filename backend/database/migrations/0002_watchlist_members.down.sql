@@ -0,0 +1,3 @@
+-- Deja de compartir las watchlists; cada una sigue siendo de su propietario.
+
+DROP TABLE IF EXISTS watchlist_members;
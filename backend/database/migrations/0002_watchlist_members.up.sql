@@ -0,0 +1,11 @@
+-- Watchlists compartidas: los usuarios con acceso a una watchlist además de su propietario,
+-- con el rol que les dio (editor puede cambiar los tickers, viewer solo verlos).
+
+CREATE TABLE IF NOT EXISTS watchlist_members (
+    watchlist_id UUID NOT NULL REFERENCES watchlists (id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    role TEXT NOT NULL CHECK (role IN ('editor', 'viewer')),
+    added_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    PRIMARY KEY (watchlist_id, user_id)
+);
+CREATE INDEX IF NOT EXISTS watchlist_members_user_idx ON watchlist_members (user_id);
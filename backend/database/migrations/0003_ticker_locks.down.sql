@@ -0,0 +1,3 @@
+-- Las escrituras de stocks vuelven a depender solo de las restricciones de la tabla stocks.
+
+DROP TABLE IF EXISTS ticker_locks;
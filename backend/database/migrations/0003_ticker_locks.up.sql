@@ -0,0 +1,8 @@
+-- Una fila por ticker que se ha escrito alguna vez. Las escrituras de stocks la bloquean
+-- dentro de su transacción (ver lockTickers) para serializarse por ticker entre todas las
+-- instancias del backend, no solo dentro de un proceso.
+
+CREATE TABLE IF NOT EXISTS ticker_locks (
+    ticker VARCHAR(10) PRIMARY KEY,
+    locked_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
@@ -0,0 +1,154 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// GetNotificationSettings devuelve las preferencias de notificación de un usuario; si no
+// ha guardado ninguna, las vacías (sin horas de silencio ni límites propios).
+func (c *cockroachDB) GetNotificationSettings(ctx context.Context, userID uuid.UUID) (models.NotificationSettings, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	var settings models.NotificationSettings
+	err := c.db.QueryRowContext(ctx,
+		`SELECT COALESCE(ns.settings, '{}') FROM users u
+        LEFT JOIN notification_settings ns ON ns.user_id = u.id
+        WHERE u.id = $1 AND u.deleted_at IS NULL`, userID).Scan(&settings)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.NotificationSettings{}, ErrUserNotFound
+	}
+	if err != nil {
+		return models.NotificationSettings{}, fmt.Errorf("error al obtener las preferencias de notificación del usuario %s: %w", userID, err)
+	}
+	return settings, nil
+}
+
+// SaveNotificationSettings guarda las preferencias de notificación de un usuario.
+func (c *cockroachDB) SaveNotificationSettings(ctx context.Context, userID uuid.UUID, settings models.NotificationSettings) error {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	_, err := c.db.ExecContext(ctx,
+		`UPSERT INTO notification_settings (user_id, settings, updated_at) VALUES ($1, $2, now())`, userID, settings)
+	if err != nil {
+		return fmt.Errorf("error al guardar las preferencias de notificación del usuario %s: %w", userID, err)
+	}
+	return nil
+}
+
+// EnqueueNotifications añade a la bandeja de salida una notificación por cada alerta
+// disparada de eventIDs y cada canal de channels, en una sola sentencia.
+func (c *cockroachDB) EnqueueNotifications(ctx context.Context, eventIDs []uuid.UUID, channels []string) (int64, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	if len(eventIDs) == 0 || len(channels) == 0 {
+		return 0, nil
+	}
+	res, err := c.db.ExecContext(ctx,
+		`INSERT INTO notification_outbox (user_id, channel, event_id)
+        SELECT e.user_id, ch.channel, e.id
+        FROM alert_events AS e, unnest($2::TEXT[]) AS ch (channel)
+        WHERE e.id = ANY($1::UUID[])`, uuidArray(eventIDs), pgArray(channels))
+	if err != nil {
+		return 0, fmt.Errorf("error al encolar las notificaciones: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n, nil
+}
+
+// PendingNotifications devuelve las notificaciones aún sin enviar de usuarios activos,
+// con las preferencias de cada usuario, ordenadas por usuario, canal y antigüedad.
+func (c *cockroachDB) PendingNotifications(ctx context.Context) ([]models.PendingNotification, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	rows, err := c.db.QueryContext(ctx, `
+        SELECT o.id, o.user_id, u.email, o.channel, COALESCE(ns.settings, '{}'),
+            e.id, e.alert_id, e.ticker, e.metric, e.operator, e.threshold, e.value, e.triggered_at
+        FROM notification_outbox AS o
+        JOIN users AS u ON u.id = o.user_id AND u.deleted_at IS NULL
+        JOIN alert_events AS e ON e.id = o.event_id
+        LEFT JOIN notification_settings AS ns ON ns.user_id = o.user_id
+        WHERE o.sent_at IS NULL
+        ORDER BY o.user_id, o.channel, e.triggered_at, o.id`)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener las notificaciones pendientes: %w", err)
+	}
+	defer rows.Close()
+
+	var pending []models.PendingNotification
+	for rows.Next() {
+		var p models.PendingNotification
+		if err := rows.Scan(&p.ID, &p.UserID, &p.Email, &p.Channel, &p.Settings,
+			&p.Event.ID, &p.Event.AlertID, &p.Event.Ticker, &p.Event.Metric, &p.Event.Operator,
+			&p.Event.Threshold, &p.Event.Value, &p.Event.TriggeredAt); err != nil {
+			return nil, fmt.Errorf("error al leer las notificaciones pendientes: %w", err)
+		}
+		p.Event.UserID = p.UserID
+		pending = append(pending, p)
+	}
+	return pending, rows.Err()
+}
+
+// SentNotificationBatches cuenta los envíos (una alerta suelta o un resumen) hechos desde
+// since, por usuario y canal.
+func (c *cockroachDB) SentNotificationBatches(ctx context.Context, since time.Time) (map[uuid.UUID]map[string]int, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	rows, err := c.db.QueryContext(ctx, `
+        SELECT user_id, channel, count(DISTINCT batch_id) FROM notification_outbox
+        WHERE sent_at >= $1 GROUP BY user_id, channel`, since)
+	if err != nil {
+		return nil, fmt.Errorf("error al contar las notificaciones enviadas: %w", err)
+	}
+	defer rows.Close()
+
+	sent := map[uuid.UUID]map[string]int{}
+	for rows.Next() {
+		var userID uuid.UUID
+		var channel string
+		var n int
+		if err := rows.Scan(&userID, &channel, &n); err != nil {
+			return nil, fmt.Errorf("error al leer las notificaciones enviadas: %w", err)
+		}
+		if sent[userID] == nil {
+			sent[userID] = map[string]int{}
+		}
+		sent[userID][channel] = n
+	}
+	return sent, rows.Err()
+}
+
+// MarkNotificationsSent marca como enviadas en sentAt las notificaciones ids, que forman
+// un mismo envío batchID.
+func (c *cockroachDB) MarkNotificationsSent(ctx context.Context, ids []uuid.UUID, batchID uuid.UUID, sentAt time.Time) error {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	_, err := c.db.ExecContext(ctx,
+		"UPDATE notification_outbox SET sent_at = $2, batch_id = $3 WHERE id = ANY($1::UUID[])",
+		uuidArray(ids), sentAt, batchID)
+	if err != nil {
+		return fmt.Errorf("error al marcar las notificaciones como enviadas: %w", err)
+	}
+	return nil
+}
+
+// uuidArray convierte ids en un parámetro de tipo array para ANY($n::UUID[]).
+func uuidArray(ids []uuid.UUID) interface{} {
+	strs := make([]string, len(ids))
+	for i, id := range ids {
+		strs[i] = id.String()
+	}
+	return pgArray(strs)
+}
@@ -0,0 +1,81 @@
+package database
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+func TestNotificationSettings_RoundTrip(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	udb := NewUserDB(db)
+
+	userID := uuid.New()
+	settings := models.NotificationSettings{QuietHoursStart: "22:00", QuietHoursEnd: "07:00", Timezone: "Europe/Madrid"}
+	stored := `{"quiet_hours_start":"22:00","quiet_hours_end":"07:00","timezone":"Europe/Madrid"}`
+	mock.ExpectExec(regexp.QuoteMeta("UPSERT INTO notification_settings (user_id, settings, updated_at) VALUES ($1, $2, now())")).
+		WithArgs(userID, stored).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COALESCE(ns.settings, '{}') FROM users u")).WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"settings"}).AddRow([]byte(stored)))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COALESCE(ns.settings, '{}') FROM users u")).WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"settings"}))
+
+	if err := udb.SaveNotificationSettings(context.Background(), userID, settings); err != nil {
+		t.Fatalf("❌ error inesperado: %v", err)
+	}
+	got, err := udb.GetNotificationSettings(context.Background(), userID)
+	if err != nil || got.QuietHoursStart != "22:00" || got.Timezone != "Europe/Madrid" {
+		t.Errorf("❌ preferencias inesperadas: %+v (%v)", got, err)
+	}
+	if _, err := udb.GetNotificationSettings(context.Background(), userID); err != ErrUserNotFound {
+		t.Errorf("❌ se esperaba ErrUserNotFound, se obtuvo %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("⚠️ expectativas no cumplidas en TestNotificationSettings_RoundTrip: %s", err)
+	}
+}
+
+func TestNotificationOutbox(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	udb := NewUserDB(db)
+
+	eventID, userID, batchID := uuid.New(), uuid.New(), uuid.New()
+	since := time.Date(2025, 1, 6, 11, 0, 0, 0, time.UTC)
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO notification_outbox (user_id, channel, event_id)")).
+		WithArgs("{"+eventID.String()+"}", "{email}").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT user_id, channel, count(DISTINCT batch_id) FROM notification_outbox")).WithArgs(since).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "channel", "count"}).AddRow(userID, "email", 4))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE notification_outbox SET sent_at = $2, batch_id = $3 WHERE id = ANY($1::UUID[])")).
+		WithArgs("{"+eventID.String()+"}", since, batchID).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if n, err := udb.EnqueueNotifications(context.Background(), []uuid.UUID{eventID}, []string{"email"}); n != 1 || err != nil {
+		t.Errorf("❌ EnqueueNotifications = %d, %v", n, err)
+	}
+	// Sin alertas no se consulta la base de datos
+	if n, err := udb.EnqueueNotifications(context.Background(), nil, []string{"email"}); n != 0 || err != nil {
+		t.Errorf("❌ EnqueueNotifications(nil) = %d, %v", n, err)
+	}
+	sent, err := udb.SentNotificationBatches(context.Background(), since)
+	if err != nil || sent[userID]["email"] != 4 {
+		t.Errorf("❌ envíos inesperados: %v (%v)", sent, err)
+	}
+	if err := udb.MarkNotificationsSent(context.Background(), []uuid.UUID{eventID}, batchID, since); err != nil {
+		t.Errorf("❌ error inesperado: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("⚠️ expectativas no cumplidas en TestNotificationOutbox: %s", err)
+	}
+}
@@ -0,0 +1,78 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// ErrOptionsSummaryNotFound indica que el ticker no tiene resumen de opciones guardado.
+var ErrOptionsSummaryNotFound = errors.New("no hay resumen de opciones para el ticker")
+
+const optionsSummaryColumns = `ticker, source, nearest_expiration, expirations, implied_volatility, put_volume,
+        call_volume, put_call_ratio, put_open_interest, call_open_interest, put_call_open_interest_ratio, fetched_at`
+
+const upsertOptionsSummarySQL = `
+    UPSERT INTO options_summaries (` + optionsSummaryColumns + `)
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12);`
+
+// SaveOptionsSummaries guarda los resúmenes de opciones, reemplazando el anterior de cada ticker.
+func (c *cockroachDB) SaveOptionsSummaries(ctx context.Context, summaries []models.OptionsSummary) error {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	if len(summaries) == 0 {
+		return nil
+	}
+
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error al iniciar transacción de los resúmenes de opciones: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, upsertOptionsSummarySQL)
+	if err != nil {
+		return fmt.Errorf("error al preparar la inserción de resúmenes de opciones: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, o := range summaries {
+		if _, err := stmt.ExecContext(ctx, o.Ticker, o.Source, o.NearestExpiration, o.Expirations,
+			o.ImpliedVolatility.NullFloat64, o.PutVolume, o.CallVolume, o.PutCallRatio.NullFloat64,
+			o.PutOpenInterest, o.CallOpenInterest, o.PutCallOpenInterestRatio.NullFloat64, o.FetchedAt); err != nil {
+			return fmt.Errorf("error al guardar el resumen de opciones de %s: %w", o.Ticker, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error al confirmar los resúmenes de opciones: %w", err)
+	}
+	return nil
+}
+
+// GetOptionsSummary devuelve el resumen de opciones del ticker o ErrOptionsSummaryNotFound.
+func (c *cockroachDB) GetOptionsSummary(ctx context.Context, ticker string) (models.OptionsSummary, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	var o models.OptionsSummary
+	var iv, pcRatio, pcOIRatio sql.NullFloat64
+	err := c.db.QueryRowContext(ctx,
+		"SELECT "+optionsSummaryColumns+" FROM options_summaries WHERE ticker = $1", ticker).
+		Scan(&o.Ticker, &o.Source, &o.NearestExpiration, &o.Expirations, &iv, &o.PutVolume, &o.CallVolume,
+			&pcRatio, &o.PutOpenInterest, &o.CallOpenInterest, &pcOIRatio, &o.FetchedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.OptionsSummary{}, ErrOptionsSummaryNotFound
+	}
+	if err != nil {
+		return models.OptionsSummary{}, fmt.Errorf("error al obtener el resumen de opciones de %s: %w", ticker, err)
+	}
+	o.ImpliedVolatility = models.NullFloat64{NullFloat64: iv}
+	o.PutCallRatio = models.NullFloat64{NullFloat64: pcRatio}
+	o.PutCallOpenInterestRatio = models.NullFloat64{NullFloat64: pcOIRatio}
+	return o, nil
+}
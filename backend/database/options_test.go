@@ -0,0 +1,60 @@
+package database
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+func TestSaveAndGetOptionsSummary(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	sdb := NewStockDB(db)
+	expiration := time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)
+	fetchedAt := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	summary := models.OptionsSummary{
+		Ticker: "AAPL", Source: "finnhub", NearestExpiration: expiration, Expirations: 2,
+		ImpliedVolatility: models.NewNullFloat64(24.5), PutVolume: 900, CallVolume: 1200,
+		PutCallRatio: models.NewNullFloat64(0.75), PutOpenInterest: 5000, CallOpenInterest: 4000,
+		PutCallOpenInterestRatio: models.NewNullFloat64(1.25), FetchedAt: fetchedAt,
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare(regexp.QuoteMeta(upsertOptionsSummarySQL))
+	mock.ExpectExec(regexp.QuoteMeta(upsertOptionsSummarySQL)).
+		WithArgs("AAPL", "finnhub", expiration, 2, summary.ImpliedVolatility.NullFloat64, 900.0, 1200.0,
+			summary.PutCallRatio.NullFloat64, 5000.0, 4000.0, summary.PutCallOpenInterestRatio.NullFloat64, fetchedAt).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	if err := sdb.SaveOptionsSummaries(context.Background(), []models.OptionsSummary{summary}); err != nil {
+		t.Errorf("❌ error inesperado al guardar el resumen: %v", err)
+	}
+
+	columns := []string{"ticker", "source", "nearest_expiration", "expirations", "implied_volatility", "put_volume",
+		"call_volume", "put_call_ratio", "put_open_interest", "call_open_interest", "put_call_open_interest_ratio", "fetched_at"}
+	mock.ExpectQuery(regexp.QuoteMeta("FROM options_summaries WHERE ticker = $1")).WithArgs("AAPL").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow("AAPL", "finnhub", expiration, 2, 24.5, 900.0, 1200.0, 0.75, 5000.0, 4000.0, nil, fetchedAt))
+	got, err := sdb.GetOptionsSummary(context.Background(), "AAPL")
+	if err != nil || got.PutCallRatio.Float64 != 0.75 || got.PutCallOpenInterestRatio.Valid || !got.NearestExpiration.Equal(expiration) {
+		t.Errorf("❌ resumen inesperado: %+v (%v)", got, err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta("FROM options_summaries WHERE ticker = $1")).WithArgs("NOPE").
+		WillReturnRows(sqlmock.NewRows(columns))
+	if _, err := sdb.GetOptionsSummary(context.Background(), "NOPE"); err != ErrOptionsSummaryNotFound {
+		t.Errorf("❌ se esperaba ErrOptionsSummaryNotFound, se obtuvo %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("⚠️ expectativas no cumplidas en TestSaveAndGetOptionsSummary: %s", err)
+	}
+}
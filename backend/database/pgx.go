@@ -0,0 +1,55 @@
+package database
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// typeMap son los codecs de pgx con los que se leen y escriben los arrays de Postgres a
+// través de database/sql, que no sabe nada de arrays.
+var typeMap = pgtype.NewMap()
+
+// pgArray convierte values en el parámetro de un array de Postgres (TEXT[], FLOAT8[],
+// BIGINT[], ...). Un slice nil se envía como NULL.
+func pgArray[T any](values []T) driver.Valuer {
+	return arrayParam[T](values)
+}
+
+type arrayParam[T any] []T
+
+// Value devuelve el literal de texto del array ({a,b}); pgx lo convierte al tipo del
+// parámetro que indica la sentencia preparada.
+func (a arrayParam[T]) Value() (driver.Value, error) {
+	values := []T(a)
+	if values == nil {
+		return nil, nil
+	}
+	t, ok := typeMap.TypeForValue(values)
+	if !ok {
+		return nil, fmt.Errorf("tipo de array no soportado: %T", values)
+	}
+	buf, err := typeMap.Encode(t.OID, pgtype.TextFormatCode, values, nil)
+	if err != nil {
+		return nil, err
+	}
+	return string(buf), nil
+}
+
+// scanArray lee una columna de tipo array en dest (ej. *[]string).
+func scanArray(dest interface{}) sql.Scanner {
+	return typeMap.SQLScanner(dest)
+}
+
+// uniqueViolation es el código SQLSTATE de una violación de restricción UNIQUE.
+const uniqueViolation = "23505"
+
+// isUniqueViolation indica si err es una violación de una restricción UNIQUE.
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation
+}
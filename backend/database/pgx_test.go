@@ -0,0 +1,41 @@
+package database
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestPgArray(t *testing.T) {
+	for _, c := range []struct {
+		param driver.Valuer
+		want  driver.Value
+	}{
+		{pgArray([]string{"AAPL", "A,B"}), `{AAPL,"A,B"}`},
+		{pgArray([]float64{185.5, 0}), "{185.5,0}"},
+		{pgArray([]string{}), "{}"},
+		{pgArray([]string(nil)), nil},
+	} {
+		got, err := c.param.Value()
+		if err != nil || !reflect.DeepEqual(got, c.want) {
+			t.Errorf("❌ pgArray = %#v, %v; se esperaba %#v", got, err, c.want)
+		}
+	}
+
+	var tickers []string
+	if err := scanArray(&tickers).Scan(`{AAPL,"BRK B"}`); err != nil || !reflect.DeepEqual(tickers, []string{"AAPL", "BRK B"}) {
+		t.Errorf("❌ scanArray = %v, %v", tickers, err)
+	}
+}
+
+func TestIsUniqueViolation(t *testing.T) {
+	if !isUniqueViolation(fmt.Errorf("insert: %w", &pgconn.PgError{Code: uniqueViolation})) {
+		t.Error("❌ una violación UNIQUE envuelta no se reconoció")
+	}
+	if isUniqueViolation(&pgconn.PgError{Code: "23503"}) {
+		t.Error("❌ una violación de clave foránea no es una violación UNIQUE")
+	}
+}
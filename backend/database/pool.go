@@ -0,0 +1,103 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/config"
+)
+
+// ConfigurePool aplica los tamaños y tiempos de vida de pool a db. Se puede llamar con el
+// pool en uso, por ejemplo al recargar la configuración.
+func ConfigurePool(db *sql.DB, pool config.DBPoolConfig) {
+	db.SetMaxOpenConns(pool.MaxOpenConns)
+	db.SetMaxIdleConns(pool.MaxIdleConns)
+	db.SetConnMaxLifetime(pool.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(pool.ConnMaxIdleTime)
+}
+
+// queryContext limita ctx al tiempo máximo de una consulta (DBPool.QueryTimeout), para que
+// una base de datos lenta no acumule goroutines esperando. Con 0 solo se hereda ctx.
+func queryContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if timeout := config.Current().DBPool.QueryTimeout; timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// PoolMonitor vigila la espera por conexiones del pool. Si la espera media supera
+// DBPool.WaitWarning lo registra en el log; si además todas las conexiones están en uso y
+// la espera supera DBPool.SaturationWait, marca el pool como saturado para que la API
+// rechace peticiones en lugar de encolarlas sin límite. Es seguro para uso concurrente.
+type PoolMonitor struct {
+	stats     func() sql.DBStats
+	saturated atomic.Bool
+
+	mu   sync.Mutex
+	last sql.DBStats // Estadísticas de la comprobación anterior
+}
+
+// NewPoolMonitor crea el monitor del pool de db.
+func NewPoolMonitor(db *sql.DB) *PoolMonitor {
+	return &PoolMonitor{stats: db.Stats}
+}
+
+// Saturated indica si el pool estaba saturado en la última comprobación.
+func (m *PoolMonitor) Saturated() bool {
+	return m.saturated.Load()
+}
+
+// SetSaturated marca el pool como saturado o no, hasta la siguiente comprobación.
+func (m *PoolMonitor) SetSaturated(saturated bool) {
+	m.saturated.Store(saturated)
+}
+
+// Monitor comprueba el pool cada interval hasta que se cancela ctx.
+func (m *PoolMonitor) Monitor(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.check(config.Current().DBPool)
+		}
+	}
+}
+
+// check calcula la espera media por conexión desde la comprobación anterior y actualiza
+// el estado. Las transiciones de saturación se registran en el log.
+func (m *PoolMonitor) check(pool config.DBPoolConfig) {
+	m.mu.Lock()
+	stats := m.stats()
+	waits := stats.WaitCount - m.last.WaitCount
+	waited := stats.WaitDuration - m.last.WaitDuration
+	m.last = stats
+	m.mu.Unlock()
+
+	var avgWait time.Duration
+	if waits > 0 {
+		avgWait = waited / time.Duration(waits)
+	}
+	if pool.WaitWarning > 0 && avgWait >= pool.WaitWarning {
+		log.Printf("⚠️ Espera alta por conexiones de la base de datos: %s de media en %d esperas (en uso %d/%d)",
+			avgWait, waits, stats.InUse, stats.MaxOpenConnections)
+	}
+
+	saturated := pool.SaturationWait > 0 && avgWait >= pool.SaturationWait &&
+		stats.MaxOpenConnections > 0 && stats.InUse >= stats.MaxOpenConnections
+	wasSaturated := m.saturated.Swap(saturated)
+	switch {
+	case saturated && !wasSaturated:
+		log.Printf("❌ Pool de conexiones saturado: %s de espera media con %d/%d conexiones en uso. Se rechazan peticiones con 503.",
+			avgWait, stats.InUse, stats.MaxOpenConnections)
+	case !saturated && wasSaturated:
+		log.Println("✅ Pool de conexiones recuperado.")
+	}
+}
@@ -0,0 +1,82 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jannin2/stock-app/backend/config"
+)
+
+func TestPoolMonitor_Saturation(t *testing.T) {
+	pool := config.DBPoolConfig{MaxOpenConns: 4, WaitWarning: 100 * time.Millisecond, SaturationWait: time.Second}
+	var stats sql.DBStats
+	m := &PoolMonitor{stats: func() sql.DBStats { return stats }}
+
+	// Esperas largas pero con conexiones libres: solo un aviso
+	stats = sql.DBStats{MaxOpenConnections: 4, InUse: 2, WaitCount: 2, WaitDuration: 4 * time.Second}
+	m.check(pool)
+	if m.Saturated() {
+		t.Fatal("❌ El pool no debería estar saturado con conexiones libres")
+	}
+
+	// Todas en uso y 3 esperas de 1,5s de media desde la comprobación anterior
+	stats = sql.DBStats{MaxOpenConnections: 4, InUse: 4, WaitCount: 5, WaitDuration: 8500 * time.Millisecond}
+	m.check(pool)
+	if !m.Saturated() {
+		t.Fatal("❌ El pool debería estar saturado")
+	}
+
+	// Sin esperas nuevas el pool se recupera
+	m.check(pool)
+	if m.Saturated() {
+		t.Fatal("❌ El pool debería haberse recuperado sin esperas nuevas")
+	}
+
+	// SaturationWait = 0 desactiva el rechazo
+	stats = sql.DBStats{MaxOpenConnections: 4, InUse: 4, WaitCount: 10, WaitDuration: 60 * time.Second}
+	m.check(config.DBPoolConfig{})
+	if m.Saturated() {
+		t.Fatal("❌ Con SaturationWait = 0 el pool no debería marcarse como saturado")
+	}
+}
+
+func TestQueryContext_TimeoutAndCancellation(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	sdb := NewStockDB(db)
+
+	previous := config.Current()
+	defer config.Set(previous)
+	cfg := previous
+	cfg.DBPool.QueryTimeout = 20 * time.Millisecond
+	config.Set(cfg)
+
+	// Una consulta lenta se aborta al vencer DBPool.QueryTimeout
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT now()")).WillDelayFor(time.Second).
+		WillReturnRows(sqlmock.NewRows([]string{"now"}).AddRow(time.Now()))
+	start := time.Now()
+	if _, err := sdb.ExportSnapshot(context.Background()); err == nil {
+		t.Error("❌ se esperaba un error por el tiempo máximo de la consulta")
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("❌ la consulta tardó %s, no se aplicó el tiempo máximo", elapsed)
+	}
+
+	// Un contexto cancelado aborta la consulta aunque no haya vencido el tiempo máximo
+	cfg.DBPool.QueryTimeout = 0
+	config.Set(cfg)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT now()")).WillDelayFor(time.Second).
+		WillReturnRows(sqlmock.NewRows([]string{"now"}).AddRow(time.Now()))
+	if _, err := sdb.ExportSnapshot(ctx); err == nil {
+		t.Error("❌ se esperaba un error con el contexto cancelado")
+	}
+}
@@ -0,0 +1,215 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// upsertPricesSQL guarda un lote de precios en una sola sentencia: cada argumento es un
+// array con una columna del lote, con 0 en los datos de la vela que no se conocen. Una
+// cotización sin vela no borra la que ya se guardó ese día.
+const upsertPricesSQL = `
+    INSERT INTO stock_prices (ticker, trading_day, close, open, high, low, volume, recorded_at)
+    SELECT ticker, trading_day, close, NULLIF(open, 0), NULLIF(high, 0), NULLIF(low, 0), NULLIF(volume, 0), now()
+    FROM unnest($1::TEXT[], $2::DATE[], $3::REAL[], $4::REAL[], $5::REAL[], $6::REAL[], $7::INT8[])
+        AS p (ticker, trading_day, close, open, high, low, volume)
+    ON CONFLICT (ticker, trading_day) DO UPDATE SET
+        close = EXCLUDED.close,
+        open = COALESCE(EXCLUDED.open, stock_prices.open),
+        high = COALESCE(EXCLUDED.high, stock_prices.high),
+        low = COALESCE(EXCLUDED.low, stock_prices.low),
+        volume = COALESCE(EXCLUDED.volume, stock_prices.volume),
+        recorded_at = now();`
+
+// pricesBatchSize es cuántos precios se guardan por sentencia.
+const pricesBatchSize = 1000
+
+// RecordPrices guarda los puntos en el histórico de precios, en lotes de pricesBatchSize
+// dentro de una transacción. Si ya existe un precio para el mismo ticker y día, se
+// reemplaza (el último precio del día es el cierre); lo mismo entre puntos repetidos.
+func (c *cockroachDB) RecordPrices(ctx context.Context, points []models.PricePoint) error {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	points = latestPricePoints(points)
+	if len(points) == 0 {
+		return nil
+	}
+
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error al iniciar transacción del histórico de precios: %w", err)
+	}
+	defer tx.Rollback()
+
+	for start := 0; start < len(points); start += pricesBatchSize {
+		batch := points[start:min(start+pricesBatchSize, len(points))]
+		tickers := make([]string, len(batch))
+		days := make([]string, len(batch))
+		closes, opens, highs, lows := make([]float64, len(batch)), make([]float64, len(batch)), make([]float64, len(batch)), make([]float64, len(batch))
+		volumes := make([]int64, len(batch))
+		for i, p := range batch {
+			tickers[i], days[i], closes[i] = p.Ticker, p.TradingDay.Format("2006-01-02"), p.Close
+			opens[i], highs[i], lows[i], volumes[i] = p.Open, p.High, p.Low, p.Volume
+		}
+		if _, err := tx.ExecContext(ctx, upsertPricesSQL, pgArray(tickers), pgArray(days), pgArray(closes),
+			pgArray(opens), pgArray(highs), pgArray(lows), pgArray(volumes)); err != nil {
+			return fmt.Errorf("error al guardar %d precios en el histórico: %w", len(batch), err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error al confirmar el histórico de precios: %w", err)
+	}
+	return nil
+}
+
+// latestPricePoints quita los puntos repetidos para un mismo ticker y día, quedándose con
+// el último: una sentencia ON CONFLICT no puede actualizar dos veces la misma fila.
+func latestPricePoints(points []models.PricePoint) []models.PricePoint {
+	type key struct {
+		ticker string
+		day    string
+	}
+	index := make(map[key]int, len(points))
+	unique := make([]models.PricePoint, 0, len(points))
+	for _, p := range points {
+		k := key{p.Ticker, p.TradingDay.Format("2006-01-02")}
+		if i, ok := index[k]; ok {
+			unique[i] = p
+			continue
+		}
+		index[k] = len(unique)
+		unique = append(unique, p)
+	}
+	return unique
+}
+
+// roundCents redondea al céntimo un cierre leído como REAL, que 185.3 devuelve como
+// 185.3000030517578.
+func roundCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// GetPriceHistory devuelve, por ticker, los precios desde since (inclusive) ordenados por día.
+func (c *cockroachDB) GetPriceHistory(ctx context.Context, tickers []string, since time.Time) (map[string][]models.PricePoint, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	rows, err := c.db.QueryContext(ctx,
+		`SELECT ticker, trading_day, close FROM stock_prices
+        WHERE ticker = ANY($1) AND trading_day >= $2
+        ORDER BY ticker ASC, trading_day ASC`,
+		pgArray(tickers), since)
+	if err != nil {
+		return nil, fmt.Errorf("error al consultar el histórico de precios: %w", err)
+	}
+	defer rows.Close()
+
+	history := make(map[string][]models.PricePoint, len(tickers))
+	for rows.Next() {
+		var p models.PricePoint
+		if err := rows.Scan(&p.Ticker, &p.TradingDay, &p.Close); err != nil {
+			return nil, fmt.Errorf("error al escanear fila del histórico de precios: %w", err)
+		}
+		p.Close = roundCents(p.Close)
+		history[p.Ticker] = append(history[p.Ticker], p)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error después de iterar el histórico de precios: %w", err)
+	}
+	return history, nil
+}
+
+// GetPriceBars devuelve las últimas limit barras del histórico de ticker con la resolución
+// indicada (ver models.ValidResolution), en orden cronológico. La agregación se hace en la
+// base de datos: cada barra es el último cierre de su día, semana o mes, de modo que un
+// gráfico de diez años por meses son 120 filas y no 2.500.
+func (c *cockroachDB) GetPriceBars(ctx context.Context, ticker, resolution string, limit int) ([]models.PriceBar, error) {
+	if !models.ValidResolution(resolution) {
+		return nil, fmt.Errorf("resolución no soportada: %s", resolution)
+	}
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	rows, err := c.db.QueryContext(ctx,
+		`SELECT date_trunc($2, trading_day::TIMESTAMP)::DATE AS start,
+            (array_agg(close ORDER BY trading_day DESC))[1]
+        FROM stock_prices
+        WHERE ticker = $1
+        GROUP BY start
+        ORDER BY start DESC
+        LIMIT $3`,
+		ticker, resolution, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error al consultar el histórico de precios agregado: %w", err)
+	}
+	defer rows.Close()
+
+	bars := []models.PriceBar{}
+	for rows.Next() {
+		var b models.PriceBar
+		if err := rows.Scan(&b.Start, &b.Close); err != nil {
+			return nil, fmt.Errorf("error al escanear fila del histórico de precios agregado: %w", err)
+		}
+		b.Close = roundCents(b.Close)
+		bars = append(bars, b)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error después de iterar el histórico de precios agregado: %w", err)
+	}
+	slices.Reverse(bars)
+	return bars, nil
+}
+
+// GetCandles devuelve las últimas limit velas OHLCV de ticker con la resolución indicada
+// (ver models.ValidResolution), en orden cronológico, agregadas en la base de datos a
+// partir de las velas diarias (ver models.Candle).
+func (c *cockroachDB) GetCandles(ctx context.Context, ticker, resolution string, limit int) ([]models.Candle, error) {
+	if !models.ValidResolution(resolution) {
+		return nil, fmt.Errorf("resolución no soportada: %s", resolution)
+	}
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	rows, err := c.db.QueryContext(ctx,
+		`SELECT date_trunc($2, trading_day::TIMESTAMP)::DATE AS start,
+            (array_agg(COALESCE(open, close) ORDER BY trading_day ASC))[1],
+            max(COALESCE(high, close)),
+            min(COALESCE(low, close)),
+            (array_agg(close ORDER BY trading_day DESC))[1],
+            COALESCE(sum(volume), 0)
+        FROM stock_prices
+        WHERE ticker = $1
+        GROUP BY start
+        ORDER BY start DESC
+        LIMIT $3`,
+		ticker, resolution, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error al consultar las velas del histórico de precios: %w", err)
+	}
+	defer rows.Close()
+
+	candles := []models.Candle{}
+	for rows.Next() {
+		var candle models.Candle
+		if err := rows.Scan(&candle.Start, &candle.Open, &candle.High, &candle.Low, &candle.Close, &candle.Volume); err != nil {
+			return nil, fmt.Errorf("error al escanear vela del histórico de precios: %w", err)
+		}
+		for _, price := range []*float64{&candle.Open, &candle.High, &candle.Low, &candle.Close} {
+			*price = roundCents(*price)
+		}
+		candles = append(candles, candle)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error después de iterar las velas del histórico de precios: %w", err)
+	}
+	slices.Reverse(candles)
+	return candles, nil
+}
@@ -0,0 +1,91 @@
+package database
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+const upsertProviderPayloadSQL = `
+    UPSERT INTO provider_payloads (ticker, provider, endpoint, status_code, body, truncated, fetched_at)
+    VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+// SaveProviderPayload guarda p comprimida, reemplazando la anterior del mismo endpoint.
+func (c *cockroachDB) SaveProviderPayload(ctx context.Context, p models.ProviderPayload) error {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(p.Body); err != nil {
+		return fmt.Errorf("error al comprimir la respuesta de %s para %s: %w", p.Provider, p.Ticker, err)
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("error al comprimir la respuesta de %s para %s: %w", p.Provider, p.Ticker, err)
+	}
+	_, err := c.db.ExecContext(ctx, upsertProviderPayloadSQL,
+		p.Ticker, p.Provider, p.Endpoint, p.StatusCode, buf.Bytes(), p.Truncated, p.FetchedAt)
+	if err != nil {
+		return fmt.Errorf("error al guardar la respuesta de %s para %s: %w", p.Provider, p.Ticker, err)
+	}
+	return nil
+}
+
+// GetProviderPayloads devuelve las respuestas guardadas de ticker, descomprimidas y
+// ordenadas por proveedor y endpoint.
+func (c *cockroachDB) GetProviderPayloads(ctx context.Context, ticker string) ([]models.ProviderPayload, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	rows, err := c.db.QueryContext(ctx, `
+        SELECT ticker, provider, endpoint, status_code, body, truncated, fetched_at
+        FROM provider_payloads WHERE ticker = $1
+        ORDER BY provider, endpoint`, ticker)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener las respuestas de los proveedores para %s: %w", ticker, err)
+	}
+	defer rows.Close()
+
+	payloads := []models.ProviderPayload{}
+	for rows.Next() {
+		var p models.ProviderPayload
+		var compressed []byte
+		if err := rows.Scan(&p.Ticker, &p.Provider, &p.Endpoint, &p.StatusCode, &compressed, &p.Truncated, &p.FetchedAt); err != nil {
+			return nil, fmt.Errorf("error al escanear la respuesta del proveedor: %w", err)
+		}
+		if p.Body, err = gunzip(compressed); err != nil {
+			return nil, fmt.Errorf("respuesta de %s/%s para %s corrupta: %w", p.Provider, p.Endpoint, ticker, err)
+		}
+		payloads = append(payloads, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error al iterar las respuestas de los proveedores: %w", err)
+	}
+	return payloads, nil
+}
+
+// PurgeProviderPayloads borra las respuestas obtenidas antes de before y devuelve cuántas eran.
+func (c *cockroachDB) PurgeProviderPayloads(ctx context.Context, before time.Time) (int64, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	res, err := c.db.ExecContext(ctx, "DELETE FROM provider_payloads WHERE fetched_at < $1", before)
+	if err != nil {
+		return 0, fmt.Errorf("error al purgar las respuestas de los proveedores: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+func gunzip(compressed []byte) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
@@ -0,0 +1,82 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// TestProviderPayloads_RoundTrip comprueba que el cuerpo se guarda comprimido y se
+// devuelve tal cual.
+func TestProviderPayloads_RoundTrip(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	sdb := NewStockDB(db)
+
+	fetchedAt := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	body := []byte(`{"c":185.5,"d":1.2,"dp":0.65}`)
+	stored := &capturedBytes{}
+	mock.ExpectExec(regexp.QuoteMeta("UPSERT INTO provider_payloads")).
+		WithArgs("AAPL", "finnhub", "quote", 200, stored, false, fetchedAt).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	payload := models.ProviderPayload{Ticker: "AAPL", Provider: "finnhub", Endpoint: "quote", StatusCode: 200,
+		Body: body, FetchedAt: fetchedAt}
+	if err := sdb.SaveProviderPayload(context.Background(), payload); err != nil {
+		t.Fatalf("❌ error inesperado al guardar: %v", err)
+	}
+	if string(stored.value) == string(body) || len(stored.value) < 2 || stored.value[0] != 0x1f || stored.value[1] != 0x8b {
+		t.Errorf("❌ el cuerpo debería guardarse comprimido con gzip, se guardó %q", stored.value)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta("FROM provider_payloads WHERE ticker = $1")).WithArgs("AAPL").
+		WillReturnRows(sqlmock.NewRows([]string{"ticker", "provider", "endpoint", "status_code", "body", "truncated", "fetched_at"}).
+			AddRow("AAPL", "finnhub", "quote", 200, stored.value, false, fetchedAt))
+
+	payloads, err := sdb.GetProviderPayloads(context.Background(), "AAPL")
+	if err != nil || len(payloads) != 1 || string(payloads[0].Body) != string(body) || payloads[0].Endpoint != "quote" {
+		t.Errorf("❌ respuestas inesperadas: %+v (%v)", payloads, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("⚠️ expectativas no cumplidas en TestProviderPayloads_RoundTrip: %s", err)
+	}
+}
+
+func TestPurgeProviderPayloads(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	sdb := NewStockDB(db)
+
+	before := time.Date(2025, 1, 3, 9, 0, 0, 0, time.UTC)
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM provider_payloads WHERE fetched_at < $1")).WithArgs(before).
+		WillReturnResult(sqlmock.NewResult(0, 4))
+
+	if purged, err := sdb.PurgeProviderPayloads(context.Background(), before); err != nil || purged != 4 {
+		t.Errorf("❌ PurgeProviderPayloads = %d, %v; se esperaban 4", purged, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("⚠️ expectativas no cumplidas en TestPurgeProviderPayloads: %s", err)
+	}
+}
+
+// capturedBytes es un argumento de sqlmock que acepta cualquier []byte y lo guarda.
+type capturedBytes struct {
+	value []byte
+}
+
+func (c *capturedBytes) Match(v driver.Value) bool {
+	b, ok := v.([]byte)
+	c.value = b
+	return ok
+}
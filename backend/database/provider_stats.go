@@ -0,0 +1,99 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+const upsertProviderStatsSQL = `
+    INSERT INTO provider_stats (provider, day, calls, errors, error_categories, latency_histogram, max_ms, p50_ms, p95_ms, p99_ms, updated_at)
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, now())
+    ON CONFLICT (provider, day) DO UPDATE SET
+        calls = EXCLUDED.calls,
+        errors = EXCLUDED.errors,
+        error_categories = EXCLUDED.error_categories,
+        latency_histogram = EXCLUDED.latency_histogram,
+        max_ms = EXCLUDED.max_ms,
+        p50_ms = EXCLUDED.p50_ms,
+        p95_ms = EXCLUDED.p95_ms,
+        p99_ms = EXCLUDED.p99_ms,
+        updated_at = now();`
+
+// MergeProviderStats suma las estadísticas a las ya guardadas para el mismo proveedor y
+// día y recalcula los percentiles. Los histogramas no se pueden sumar en SQL, así que cada
+// fila se lee con FOR UPDATE y se combina en Go dentro de la misma transacción.
+func (c *cockroachDB) MergeProviderStats(ctx context.Context, stats []models.ProviderDayStats) error {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	if len(stats) == 0 {
+		return nil
+	}
+
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error al iniciar transacción de estadísticas de proveedores: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, s := range stats {
+		day := s.Day.UTC().Truncate(24 * time.Hour)
+		stored := models.ProviderDayStats{Provider: s.Provider, Day: day}
+		err := tx.QueryRowContext(ctx,
+			`SELECT calls, errors, error_categories, latency_histogram, max_ms FROM provider_stats
+            WHERE provider = $1 AND day = $2 FOR UPDATE`, s.Provider, day).
+			Scan(&stored.Calls, &stored.Errors, &stored.ErrorCategories, &stored.LatencyHistogram, &stored.MaxMs)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("error al leer las estadísticas de %s del %s: %w", s.Provider, day.Format("2006-01-02"), err)
+		}
+
+		merged := models.ProviderDayStats{Provider: s.Provider, Day: day}
+		merged.Merge(stored)
+		merged.Merge(s)
+
+		if _, err := tx.ExecContext(ctx, upsertProviderStatsSQL,
+			merged.Provider, merged.Day, merged.Calls, merged.Errors, merged.ErrorCategories,
+			merged.LatencyHistogram, merged.MaxMs, merged.P50Ms, merged.P95Ms, merged.P99Ms); err != nil {
+			return fmt.Errorf("error al guardar las estadísticas de %s del %s: %w", s.Provider, day.Format("2006-01-02"), err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error al confirmar las estadísticas de proveedores: %w", err)
+	}
+	return nil
+}
+
+// GetProviderStats devuelve las estadísticas diarias desde since (inclusive), ordenadas
+// por proveedor y día.
+func (c *cockroachDB) GetProviderStats(ctx context.Context, since time.Time) ([]models.ProviderDayStats, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	rows, err := c.db.QueryContext(ctx,
+		`SELECT provider, day, calls, errors, error_categories, latency_histogram, max_ms, p50_ms, p95_ms, p99_ms
+        FROM provider_stats WHERE day >= $1 ORDER BY provider ASC, day ASC`, since)
+	if err != nil {
+		return nil, fmt.Errorf("error al consultar las estadísticas de proveedores: %w", err)
+	}
+	defer rows.Close()
+
+	stats := []models.ProviderDayStats{}
+	for rows.Next() {
+		var s models.ProviderDayStats
+		if err := rows.Scan(&s.Provider, &s.Day, &s.Calls, &s.Errors, &s.ErrorCategories, &s.LatencyHistogram,
+			&s.MaxMs, &s.P50Ms, &s.P95Ms, &s.P99Ms); err != nil {
+			return nil, fmt.Errorf("error al escanear las estadísticas de proveedores: %w", err)
+		}
+		stats = append(stats, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error al iterar las estadísticas de proveedores: %w", err)
+	}
+	return stats, nil
+}
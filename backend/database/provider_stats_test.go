@@ -0,0 +1,53 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+func TestMergeProviderStats(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	sdb := NewStockDB(db)
+
+	day := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	selectStats := regexp.QuoteMeta("SELECT calls, errors, error_categories, latency_histogram, max_ms FROM provider_stats")
+	upsertStats := regexp.QuoteMeta("INSERT INTO provider_stats")
+
+	var finnhub, alpha models.ProviderDayStats
+	finnhub = models.ProviderDayStats{Provider: "finnhub", Day: day.Add(15 * time.Hour)}
+	finnhub.Observe(80*time.Millisecond, "")
+	finnhub.Observe(3*time.Second, models.ErrorCategoryTimeout)
+	alpha = models.ProviderDayStats{Provider: "alphavantage", Day: day}
+	alpha.Observe(200*time.Millisecond, models.ErrorCategoryRateLimited)
+
+	mock.ExpectBegin()
+	// finnhub ya tenía 2 llamadas ese día: se suman a las nuevas
+	mock.ExpectQuery(selectStats).WithArgs("finnhub", day).
+		WillReturnRows(sqlmock.NewRows([]string{"calls", "errors", "error_categories", "latency_histogram", "max_ms"}).
+			AddRow(2, 1, `{"timeout":1}`, `[0,0,0,2,0,0,0,0,0,0,0]`, 90))
+	mock.ExpectExec(upsertStats).
+		WithArgs("finnhub", day, int64(4), int64(2), sqlmock.AnyArg(), `[0,0,0,3,0,0,0,0,1,0,0]`, int64(3000), int64(100), int64(3000), int64(3000)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(selectStats).WithArgs("alphavantage", day).WillReturnError(sql.ErrNoRows)
+	mock.ExpectExec(upsertStats).
+		WithArgs("alphavantage", day, int64(1), int64(1), `{"rate_limited":1}`, sqlmock.AnyArg(), int64(200), int64(200), int64(200), int64(200)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if err := sdb.MergeProviderStats(context.Background(), []models.ProviderDayStats{finnhub, alpha}); err != nil {
+		t.Errorf("❌ error inesperado al guardar las estadísticas: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("⚠️ expectativas no cumplidas en TestMergeProviderStats: %s", err)
+	}
+}
@@ -0,0 +1,123 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// stockQuery construye un SELECT sobre stocks con argumentos posicionales numerados en
+// orden de aparición, para no llevar la cuenta de $n a mano en cada consulta.
+type stockQuery struct {
+	columns string
+	asOf    string // Cláusula AS OF SYSTEM TIME, si se lee una instantánea
+	where   []string
+	orderBy string
+	tail    string
+	args    []interface{}
+}
+
+func newStockQuery(columns string) *stockQuery {
+	return &stockQuery{columns: columns}
+}
+
+// arg registra un argumento y devuelve su placeholder ($1, $2, ...).
+func (q *stockQuery) arg(value interface{}) string {
+	q.args = append(q.args, value)
+	return fmt.Sprintf("$%d", len(q.args))
+}
+
+// Where añade una condición; varias condiciones se combinan con AND.
+func (q *stockQuery) Where(condition string) *stockQuery {
+	q.where = append(q.where, condition)
+	return q
+}
+
+// Search filtra por ticker o compañía. Un término vacío no añade condición.
+func (q *stockQuery) Search(term string) *stockQuery {
+	if term == "" {
+		return q
+	}
+	p := q.arg("%" + term + "%")
+	return q.Where(fmt.Sprintf("(ticker ILIKE %s OR company ILIKE %s)", p, p))
+}
+
+// Filter añade las condiciones de los filtros por valor activos.
+func (q *stockQuery) Filter(filters StockFilters) *stockQuery {
+	for _, condition := range filters.conditions(q.arg) {
+		q.Where(condition)
+	}
+	return q
+}
+
+// OrderBy ordena con las reglas de orderByClause; sin campo, por ticker ascendente.
+func (q *stockQuery) OrderBy(field, order string) *stockQuery {
+	if field == "" {
+		q.orderBy = " ORDER BY ticker ASC"
+	} else {
+		q.orderBy = orderByClause(field, order)
+	}
+	return q
+}
+
+// Page añade LIMIT y OFFSET.
+func (q *stockQuery) Page(limit, offset int) *stockQuery {
+	q.tail = fmt.Sprintf(" LIMIT %s OFFSET %s", q.arg(limit), q.arg(offset))
+	return q
+}
+
+// SQL devuelve la consulta y sus argumentos.
+func (q *stockQuery) SQL() (string, []interface{}) {
+	query := "SELECT " + q.columns + " FROM stocks" + q.asOf
+	if len(q.where) > 0 {
+		query += " WHERE " + strings.Join(q.where, " AND ")
+	}
+	return query + q.orderBy + q.tail, q.args
+}
+
+// GetStocksPage es la versión reescrita de GetAllStocks: devuelve la página y el total de
+// stocks que cumplen el filtro en una sola consulta, con COUNT(*) OVER() en lugar de un
+// COUNT separado. Mientras se valida, se ejecuta en modo sombra junto a la versión
+// anterior (ver handlers/shadow.go).
+func (c *cockroachDB) GetStocksPage(ctx context.Context, opts StockQueryOptions) ([]models.Stock, int, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	q := newStockQuery("COUNT(*) OVER() AS total_count, " + stockColumns)
+	q.asOf = c.asOfClause()
+	query, args := q.Search(opts.Search).
+		Filter(opts.Filters).
+		OrderBy(opts.SortBy, opts.Order).
+		Page(opts.Limit, opts.Offset).
+		SQL()
+
+	rows, err := c.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error al consultar la página de stocks: %w", err)
+	}
+	defer rows.Close()
+
+	var stocks []models.Stock
+	total := 0
+	for rows.Next() {
+		s, err := scanStock(rows, &total)
+		if err != nil {
+			return nil, 0, fmt.Errorf("error al escanear fila de stock: %w", err)
+		}
+		stocks = append(stocks, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error después de iterar filas: %w", err)
+	}
+
+	// Una página vacía más allá del final no trae la columna del total: se consulta aparte.
+	if len(stocks) == 0 && opts.Offset > 0 {
+		if total, err = c.GetStockCount(ctx, opts.Search, opts.Filters); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	return stocks, total, nil
+}
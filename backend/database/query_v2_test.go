@@ -0,0 +1,97 @@
+package database
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+)
+
+func TestStockQuery(t *testing.T) {
+	minESG := 60.0
+	query, args := newStockQuery("ticker").
+		Search("app").
+		Filter(StockFilters{MinESG: &minESG}).
+		OrderBy("target_upside", "desc").
+		Page(20, 40).
+		SQL()
+
+	want := "SELECT ticker FROM stocks WHERE (ticker ILIKE $1 OR company ILIKE $1) AND esg_score >= $2" +
+		" ORDER BY " + targetUpsideExpr + " DESC NULLS LAST, ticker ASC LIMIT $3 OFFSET $4"
+	if query != want {
+		t.Errorf("❌ consulta inesperada:\n%s\nse esperaba:\n%s", query, want)
+	}
+	if len(args) != 4 || args[0] != "%app%" || args[1] != 60.0 || args[2] != 20 || args[3] != 40 {
+		t.Errorf("❌ argumentos inesperados: %v", args)
+	}
+
+	maxPE := 12.0
+	if query, _ := newStockQuery("ticker").Filter(StockFilters{MaxPERatio: &maxPE}).SQL(); query != "SELECT ticker FROM stocks WHERE pe_ratio > 0 AND pe_ratio <= $1" {
+		t.Errorf("❌ consulta con PER máximo inesperada: %s", query)
+	}
+
+	minPrice, maxPrice := 10.0, 50.0
+	query, args = newStockQuery("ticker").
+		Filter(StockFilters{MinPrice: &minPrice, MaxPrice: &maxPrice, Action: "Upgraded By", Brokerage: "Goldman Sachs"}).
+		SQL()
+	want = "SELECT ticker FROM stocks WHERE current_price >= $1 AND current_price <= $2" +
+		" AND LOWER(action) = $3 AND LOWER(brokerage) = $4"
+	if query != want {
+		t.Errorf("❌ consulta con rango de precio, acción y casa de bolsa inesperada:\n%s", query)
+	}
+	if len(args) != 4 || args[2] != "upgraded by" || args[3] != "goldman sachs" {
+		t.Errorf("❌ argumentos inesperados: %v", args)
+	}
+
+	if query, _ := newStockQuery("ticker").OrderBy("", "").SQL(); query != "SELECT ticker FROM stocks ORDER BY ticker ASC" {
+		t.Errorf("❌ consulta sin filtros inesperada: %s", query)
+	}
+}
+
+func TestGetStocksPage(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	sdb := NewStockDB(db)
+	mockTime := time.Now()
+	columns := []string{"total_count", "id", "ticker", "company", "brokerage", "action", "rating_from", "rating_to", "target_from", "target_to", "current_price", "pe_ratio", "dividend_yield", "market_capitalization", "alpha", "latest_trading_day", "recommendation_score", "sector", "previous_close", "sentiment", "buzz", "esg_score", "short_interest", "days_to_cover", "relative_strength_30d", "relative_strength_90d", "provider_errors", "provenance", "enrichment_tier", "created_at", "updated_at"}
+	rows := sqlmock.NewRows(columns).
+		AddRow(7, uuid.New().String(), "TEST1", "Test Company 1", "BrokerX", "Buy", "Strong Buy", "Buy", nil, 100.50, 100.00, 20.0, 0.015, 1.0e9, 0.005, mockTime, 4.0, "Technology", 99.5, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, mockTime, mockTime)
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) OVER() AS total_count, "+stockColumns+" FROM stocks WHERE (ticker ILIKE $1 OR company ILIKE $1) ORDER BY ticker ASC LIMIT $2 OFFSET $3")).
+		WithArgs("%Test%", 10, 0).
+		WillReturnRows(rows)
+
+	stocks, total, err := sdb.GetStocksPage(context.Background(), StockQueryOptions{Search: "Test", Limit: 10})
+	if err != nil {
+		t.Fatalf("❌ error inesperado: %v", err)
+	}
+	if len(stocks) != 1 || stocks[0].Ticker != "TEST1" || total != 7 {
+		t.Errorf("❌ página inesperada: %d stocks, total %d", len(stocks), total)
+	}
+
+	// Más allá del final no hay filas de las que leer el total: se usa GetStockCount.
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) OVER() AS total_count, "+stockColumns+" FROM stocks ORDER BY ticker ASC LIMIT $1 OFFSET $2")).
+		WithArgs(10, 50).
+		WillReturnRows(sqlmock.NewRows(columns))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM stocks")).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(7))
+
+	stocks, total, err = sdb.GetStocksPage(context.Background(), StockQueryOptions{Limit: 10, Offset: 50})
+	if err != nil {
+		t.Fatalf("❌ error inesperado: %v", err)
+	}
+	if len(stocks) != 0 || total != 7 {
+		t.Errorf("❌ página vacía inesperada: %d stocks, total %d", len(stocks), total)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("⚠️ expectativas no cumplidas en TestGetStocksPage: %s", err)
+	}
+}
@@ -0,0 +1,55 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// quotaTables es la tabla en la que se cuenta cada recurso con cuota.
+var quotaTables = map[string]string{
+	models.QuotaWatchlists: "watchlists",
+	models.QuotaAlerts:     "alerts",
+}
+
+// CountUserResources devuelve cuántos recursos no borrados de tipo resource tiene un usuario.
+func (c *cockroachDB) CountUserResources(ctx context.Context, userID uuid.UUID, resource string) (int, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	table, ok := quotaTables[resource]
+	if !ok {
+		return 0, nil
+	}
+	var n int
+	err := c.db.QueryRowContext(ctx,
+		fmt.Sprintf("SELECT count(*) FROM %s WHERE user_id = $1 AND deleted_at IS NULL", table), userID).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("error al contar los %s del usuario %s: %w", resource, userID, err)
+	}
+	return n, nil
+}
+
+// CreateWatchlist crea una watchlist para un usuario activo.
+func (c *cockroachDB) CreateWatchlist(ctx context.Context, userID uuid.UUID, name string, tickers []string) (models.Watchlist, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	w := models.Watchlist{UserID: userID, Name: name, Tickers: tickers, Role: models.WatchlistOwner}
+	err := c.db.QueryRowContext(ctx,
+		`INSERT INTO watchlists (user_id, name, tickers)
+        SELECT $1, $2, $3 WHERE EXISTS (SELECT 1 FROM users WHERE id = $1 AND deleted_at IS NULL)
+        RETURNING id, created_at, updated_at`, userID, name, pgArray(tickers)).
+		Scan(&w.ID, &w.CreatedAt, &w.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Watchlist{}, ErrUserNotFound
+	}
+	if err != nil {
+		return models.Watchlist{}, fmt.Errorf("error al crear la watchlist del usuario %s: %w", userID, err)
+	}
+	return w, nil
+}
@@ -0,0 +1,60 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+func TestCountUserResources(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	udb := NewUserDB(db)
+
+	userID := uuid.New()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT count(*) FROM watchlists WHERE user_id = $1 AND deleted_at IS NULL")).
+		WithArgs(userID).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(7))
+
+	if n, err := udb.CountUserResources(context.Background(), userID, models.QuotaWatchlists); n != 7 || err != nil {
+		t.Errorf("❌ CountUserResources = %d, %v; se esperaba 7", n, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("⚠️ expectativas no cumplidas en TestCountUserResources: %s", err)
+	}
+}
+
+func TestCreateWatchlist(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	udb := NewUserDB(db)
+
+	userID, id := uuid.New(), uuid.New()
+	now := time.Date(2025, 1, 6, 12, 0, 0, 0, time.UTC)
+	insert := regexp.QuoteMeta("INSERT INTO watchlists (user_id, name, tickers)")
+	mock.ExpectQuery(insert).WithArgs(userID, "Tech", "{AAPL,MSFT}").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(id, now, now))
+	mock.ExpectQuery(insert).WithArgs(userID, "Tech", "{}").WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}))
+
+	w, err := udb.CreateWatchlist(context.Background(), userID, "Tech", []string{"AAPL", "MSFT"})
+	if err != nil || w.ID != id || w.UserID != userID || len(w.Tickers) != 2 {
+		t.Errorf("❌ watchlist inesperada: %+v (%v)", w, err)
+	}
+	if _, err := udb.CreateWatchlist(context.Background(), userID, "Tech", []string{}); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("❌ se esperaba ErrUserNotFound para un usuario borrado, se obtuvo %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("⚠️ expectativas no cumplidas en TestCreateWatchlist: %s", err)
+	}
+}
@@ -0,0 +1,99 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// marketCapTierExpr replica models.MarketCapTier en SQL para poder agrupar en la base de datos.
+const marketCapTierExpr = `CASE
+            WHEN market_capitalization IS NULL THEN 'unknown'
+            WHEN market_capitalization >= 200000 THEN 'mega'
+            WHEN market_capitalization >= 10000 THEN 'large'
+            WHEN market_capitalization >= 2000 THEN 'mid'
+            WHEN market_capitalization >= 300 THEN 'small'
+            ELSE 'micro'
+        END`
+
+// bucketGrouping describe cómo particionar y ordenar los stocks de una agrupación de recomendados.
+type bucketGrouping struct {
+	partition string // Expresión SQL que produce la clave del bucket
+	orderBy   string // Orden dentro de cada bucket
+	where     string // Filtro opcional aplicado antes de particionar
+}
+
+// recommendedGroupings contiene las agrupaciones soportadas por GetRecommendedBuckets.
+var recommendedGroupings = map[string]bucketGrouping{
+	"sector": {
+		partition: "COALESCE(NULLIF(sector, ''), 'Unknown')",
+		orderBy:   "recommendation_score DESC NULLS LAST, ticker ASC",
+	},
+	"market_cap_tier": {
+		partition: marketCapTierExpr,
+		orderBy:   "recommendation_score DESC NULLS LAST, ticker ASC",
+	},
+	"dividend": {
+		partition: "'dividend'",
+		orderBy:   "dividend_yield DESC, recommendation_score DESC NULLS LAST, ticker ASC",
+		where:     "dividend_yield > 0",
+	},
+}
+
+// IsValidRecommendedGrouping indica si groupBy es una agrupación soportada por GetRecommendedBuckets.
+func IsValidRecommendedGrouping(groupBy string) bool {
+	_, ok := recommendedGroupings[groupBy]
+	return ok
+}
+
+// GetRecommendedBuckets devuelve los mejores stocks de cada bucket de la agrupación indicada,
+// limitando a perBucket stocks por bucket mediante ROW_NUMBER() en una sola consulta.
+func (c *cockroachDB) GetRecommendedBuckets(ctx context.Context, groupBy string, perBucket int) ([]models.StockBucket, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	grouping, ok := recommendedGroupings[groupBy]
+	if !ok {
+		return nil, fmt.Errorf("agrupación de recomendados no soportada: %s", groupBy)
+	}
+
+	where := ""
+	if grouping.where != "" {
+		where = " WHERE " + grouping.where
+	}
+
+	query := fmt.Sprintf(`SELECT bucket, %[1]s FROM (
+        SELECT %[2]s AS bucket, %[1]s,
+            ROW_NUMBER() OVER (PARTITION BY %[2]s ORDER BY %[3]s) AS bucket_rank
+        FROM stocks%[4]s
+    ) ranked%[5]s WHERE bucket_rank <= $1 ORDER BY bucket ASC, bucket_rank ASC`,
+		stockColumns, grouping.partition, grouping.orderBy, where, c.asOfClause())
+
+	rows, err := c.db.QueryContext(ctx, query, perBucket)
+	if err != nil {
+		return nil, fmt.Errorf("error al consultar buckets de recomendados por %s: %w", groupBy, err)
+	}
+	defer rows.Close()
+
+	var buckets []models.StockBucket
+	for rows.Next() {
+		var key string
+		s, err := scanStock(rows, &key)
+		if err != nil {
+			return nil, fmt.Errorf("error al escanear fila de bucket recomendado: %w", err)
+		}
+		// Las filas llegan ordenadas por bucket, así que basta con comparar con el último.
+		if len(buckets) == 0 || buckets[len(buckets)-1].Key != key {
+			buckets = append(buckets, models.StockBucket{Key: key})
+		}
+		last := &buckets[len(buckets)-1]
+		last.Stocks = append(last.Stocks, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error después de iterar buckets recomendados: %w", err)
+	}
+
+	return buckets, nil
+}
@@ -0,0 +1,43 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// schemaTables son las tablas que crean las migraciones.
+var schemaTables = []string{
+	"alert_events", "alerts", "audit_log", "corporate_actions", "devices", "enrichment_cursor",
+	"enrichment_results", "enrichment_runs", "import_mappings", "ipos", "macro_events", "notes",
+	"notification_outbox", "notification_settings", "options_summaries", "portfolios",
+	"provider_payloads", "provider_stats", "push_deliveries", "schema_migrations", "scoring_rules",
+	"stock_mentions", "stock_prices", "stocks", "ticker_locks", "user_recovery_codes", "users", "watchlist_members",
+	"watchlists", "webhook_nonces", "webhooks",
+}
+
+// MissingTables devuelve, en orden alfabético, las tablas de las migraciones que aún no
+// existen en la base de datos. Sirve para comprobar un despliegue sin modificar el esquema.
+func MissingTables(ctx context.Context, db *sql.DB) ([]string, error) {
+	rows, err := db.QueryContext(ctx,
+		`SELECT name FROM unnest($1::TEXT[]) AS name
+        WHERE name NOT IN (SELECT table_name FROM information_schema.tables WHERE table_schema = current_schema())
+        ORDER BY name`, pgArray(schemaTables))
+	if err != nil {
+		return nil, fmt.Errorf("error al consultar las tablas del esquema: %w", err)
+	}
+	defer rows.Close()
+
+	var missing []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("error al escanear la tabla del esquema: %w", err)
+		}
+		missing = append(missing, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error después de iterar las tablas del esquema: %w", err)
+	}
+	return missing, nil
+}
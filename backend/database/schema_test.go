@@ -0,0 +1,33 @@
+package database
+
+import (
+	"context"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestMissingTables(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT name FROM unnest($1::TEXT[]) AS name")).
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("alerts").AddRow("webhooks"))
+
+	missing, err := MissingTables(context.Background(), db)
+	if err != nil {
+		t.Fatalf("❌ error inesperado: %v", err)
+	}
+	if want := []string{"alerts", "webhooks"}; !reflect.DeepEqual(missing, want) {
+		t.Errorf("❌ tablas que faltan = %v, se esperaba %v", missing, want)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("❌ expectativas no cumplidas: %v", err)
+	}
+}
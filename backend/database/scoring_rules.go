@@ -0,0 +1,63 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// scoringRulesID identifica la única fila de scoring_rules (hay un único conjunto activo).
+const scoringRulesID = "default"
+
+// GetScoringRules devuelve las reglas de scoring guardadas. Si no hay ninguna, Rules está
+// vacío y UpdatedAt es nulo.
+func (c *cockroachDB) GetScoringRules(ctx context.Context) (models.ScoringRules, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	var raw []byte
+	var updated models.NullTime
+	err := c.db.QueryRowContext(ctx,
+		"SELECT rules, updated_at FROM scoring_rules WHERE id = $1", scoringRulesID).Scan(&raw, &updated.NullTime)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.ScoringRules{Rules: []models.ScoringRule{}}, nil
+	}
+	if err != nil {
+		return models.ScoringRules{}, fmt.Errorf("error al obtener las reglas de scoring: %w", err)
+	}
+	rules := models.ScoringRules{UpdatedAt: updated}
+	if err := json.Unmarshal(raw, &rules.Rules); err != nil {
+		return models.ScoringRules{}, fmt.Errorf("reglas de scoring inválidas en la base de datos: %w", err)
+	}
+	return rules, nil
+}
+
+// SaveScoringRules reemplaza las reglas de scoring. Una lista vacía las borra y se vuelve a
+// las reglas integradas.
+func (c *cockroachDB) SaveScoringRules(ctx context.Context, rules []models.ScoringRule) (models.ScoringRules, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	if len(rules) == 0 {
+		if _, err := c.db.ExecContext(ctx, "DELETE FROM scoring_rules WHERE id = $1", scoringRulesID); err != nil {
+			return models.ScoringRules{}, fmt.Errorf("error al borrar las reglas de scoring: %w", err)
+		}
+		return models.ScoringRules{Rules: []models.ScoringRule{}}, nil
+	}
+	raw, err := json.Marshal(rules)
+	if err != nil {
+		return models.ScoringRules{}, err
+	}
+	saved := models.ScoringRules{Rules: rules}
+	err = c.db.QueryRowContext(ctx, `
+        UPSERT INTO scoring_rules (id, rules, updated_at) VALUES ($1, $2, now())
+        RETURNING updated_at`, scoringRulesID, raw).Scan(&saved.UpdatedAt.NullTime)
+	if err != nil {
+		return models.ScoringRules{}, fmt.Errorf("error al guardar las reglas de scoring: %w", err)
+	}
+	return saved, nil
+}
@@ -0,0 +1,47 @@
+package database
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+func TestScoringRules(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	sdb := NewStockDB(db)
+
+	// Sin fila se usan las reglas integradas.
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT rules, updated_at FROM scoring_rules WHERE id = $1")).WithArgs("default").
+		WillReturnRows(sqlmock.NewRows([]string{"rules", "updated_at"}))
+	if rules, err := sdb.GetScoringRules(context.Background()); err != nil || len(rules.Rules) != 0 || rules.UpdatedAt.Valid {
+		t.Errorf("❌ se esperaban reglas vacías, se obtuvo %+v (%v)", rules, err)
+	}
+
+	now := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	raw := `[{"name":"buy_action","when":"action == \"Buy\"","points":"5"}]`
+	mock.ExpectQuery(regexp.QuoteMeta("UPSERT INTO scoring_rules (id, rules, updated_at) VALUES ($1, $2, now())")).
+		WithArgs("default", []byte(raw)).
+		WillReturnRows(sqlmock.NewRows([]string{"updated_at"}).AddRow(now))
+	saved, err := sdb.SaveScoringRules(context.Background(), []models.ScoringRule{{Name: "buy_action", When: `action == "Buy"`, Points: "5"}})
+	if err != nil || !saved.UpdatedAt.Time.Equal(now) || len(saved.Rules) != 1 {
+		t.Errorf("❌ reglas guardadas inesperadas: %+v (%v)", saved, err)
+	}
+
+	// Guardar una lista vacía vuelve a las reglas integradas.
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM scoring_rules WHERE id = $1")).WithArgs("default").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	if _, err := sdb.SaveScoringRules(context.Background(), nil); err != nil {
+		t.Errorf("❌ error inesperado al borrar las reglas: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("⚠️ expectativas no cumplidas en TestScoringRules: %s", err)
+	}
+}
@@ -0,0 +1,50 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// maxSnapshotAge es la antigüedad máxima de la instantánea de lectura. Una ejecución del
+// enricher que lleva más tiempo abierta se da por abandonada y se lee el estado actual:
+// además, las lecturas AS OF SYSTEM TIME fallan pasado el gc.ttlseconds de la tabla.
+const maxSnapshotAge = 2 * time.Hour
+
+// EnrichmentSnapshot devuelve el instante que deben leer los listados para no mezclar filas
+// de dos ejecuciones del enricher: mientras una ejecución está a medias, el momento en que
+// empezó (sus lotes aún no son visibles); si no hay ninguna en curso, el actual.
+func (c *cockroachDB) EnrichmentSnapshot(ctx context.Context) (time.Time, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	var snapshot time.Time
+	err := c.db.QueryRowContext(ctx,
+		`SELECT COALESCE((
+            SELECT snapshot_at FROM enrichment_cursor
+            WHERE id = $1 AND NOT completed AND snapshot_at > now() - $2 * INTERVAL '1 second'
+        ), now())`, enrichmentCursorID, int64(maxSnapshotAge/time.Second)).Scan(&snapshot)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("error al obtener la instantánea de enriquecimiento: %w", err)
+	}
+	return snapshot, nil
+}
+
+// AsOf devuelve una vista de la base de datos cuyas consultas de listado (GetAllStocks,
+// GetStockCount, GetStocksPage y los recomendados) leen los stocks tal como estaban en t,
+// con AS OF SYSTEM TIME. Las escrituras no cambian. Con t cero se lee el estado actual.
+func (c *cockroachDB) AsOf(t time.Time) StockDB {
+	view := *c
+	view.asOf = t
+	return &view
+}
+
+// asOfClause es la cláusula que sigue al FROM de nivel superior de una consulta de listado
+// (CockroachDB no la admite dentro de subconsultas).
+func (c *cockroachDB) asOfClause() string {
+	if c.asOf.IsZero() {
+		return ""
+	}
+	// No admite placeholders; el valor es un entero generado aquí, no una entrada del usuario.
+	return fmt.Sprintf(" AS OF SYSTEM TIME '%d'", c.asOf.UnixNano())
+}
@@ -0,0 +1,52 @@
+package database
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestEnrichmentSnapshot(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	sdb := NewStockDB(db)
+
+	startedAt := time.Date(2025, 1, 6, 9, 0, 0, 123456789, time.UTC)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT snapshot_at FROM enrichment_cursor")).
+		WithArgs(enrichmentCursorID, int64(7200)).
+		WillReturnRows(sqlmock.NewRows([]string{"snapshot"}).AddRow(startedAt))
+
+	snapshot, err := sdb.EnrichmentSnapshot(context.Background())
+	if err != nil || !snapshot.Equal(startedAt) {
+		t.Fatalf("❌ instantánea inesperada: %v (%v)", snapshot, err)
+	}
+
+	// La página y el total de la vista se leen en la instantánea
+	view := sdb.AsOf(snapshot)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM stocks AS OF SYSTEM TIME '1736154000123456789' WHERE (ticker ILIKE $1")).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	if count, err := view.GetStockCount(context.Background(), "A", StockFilters{}); err != nil || count != 3 {
+		t.Errorf("❌ conteo inesperado en la instantánea: %d (%v)", count, err)
+	}
+	mock.ExpectQuery(regexp.QuoteMeta(") ranked AS OF SYSTEM TIME '1736154000123456789' WHERE bucket_rank <= $1")).
+		WillReturnRows(sqlmock.NewRows([]string{"bucket"}))
+	if _, err := view.GetRecommendedBuckets(context.Background(), "sector", 3); err != nil {
+		t.Errorf("❌ error inesperado en los buckets de la instantánea: %v", err)
+	}
+
+	// La vista no cambia la base de datos original
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM stocks") + "$").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(5))
+	if count, err := sdb.GetStockCount(context.Background(), "", StockFilters{}); err != nil || count != 5 {
+		t.Errorf("❌ conteo inesperado fuera de la instantánea: %d (%v)", count, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("⚠️ expectativas no cumplidas en TestEnrichmentSnapshot: %s", err)
+	}
+}
@@ -0,0 +1,164 @@
+package database
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Expresiones SQL de los campos calculados por los que se puede ordenar. Devuelven NULL
+// cuando falta alguno de los datos necesarios, y esos stocks se ordenan al final.
+const (
+	// targetUpsideExpr es el potencial de subida hasta el precio objetivo, en porcentaje.
+	targetUpsideExpr = "(target_to - current_price) / NULLIF(current_price, 0) * 100"
+	// changePercentExpr replica models.Stock.ChangePercent: variación diaria en porcentaje.
+	changePercentExpr = "(current_price - previous_close) / NULLIF(previous_close, 0) * 100"
+	// stalenessExpr es el tiempo transcurrido desde la última actualización del stock.
+	stalenessExpr = "now() - updated_at"
+)
+
+// sortColumns son las columnas por las que se puede ordenar directamente.
+var sortColumns = map[string]bool{
+	"ticker": true, "company": true, "current_price": true,
+	"action": true, "recommendation_score": true, "pe_ratio": true,
+	"dividend_yield": true, "market_capitalization": true, "alpha": true,
+}
+
+// computedSortFields asocia cada campo calculado con la expresión SQL que lo produce.
+// buzz, esg_score, short_interest y days_to_cover son columnas, pero solo las rellenan
+// pasos opcionales del enriquecimiento y se ordenan igual para que los stocks sin dato no
+// encabecen el orden ascendente. Lo mismo ocurre con la fuerza relativa hasta que el
+// histórico cubre el periodo; relative_strength ordena por la de 90 días.
+var computedSortFields = map[string]string{
+	"target_upside":         targetUpsideExpr,
+	"change_percent":        changePercentExpr,
+	"staleness":             stalenessExpr,
+	"buzz":                  "buzz",
+	"esg_score":             "esg_score",
+	"short_interest":        "short_interest",
+	"days_to_cover":         "days_to_cover",
+	"relative_strength":     "relative_strength_90d",
+	"relative_strength_30d": "relative_strength_30d",
+	"relative_strength_90d": "relative_strength_90d",
+}
+
+// Tipos de los campos de orden, tal como los describe GET /stocks/schema.
+const (
+	FieldTypeNumber   = "number"
+	FieldTypeString   = "string"
+	FieldTypeDuration = "duration" // Intervalo de tiempo
+)
+
+// textSortFields son los campos de orden de texto; los demás son numéricos salvo staleness.
+var textSortFields = map[string]bool{"ticker": true, "company": true, "action": true}
+
+// SortFieldType devuelve el tipo de un campo de orden (FieldTypeNumber, FieldTypeString o
+// FieldTypeDuration), o "" si no se puede ordenar por él.
+func SortFieldType(field string) string {
+	switch {
+	case !IsSortField(field):
+		return ""
+	case textSortFields[field]:
+		return FieldTypeString
+	case field == "staleness":
+		return FieldTypeDuration
+	}
+	return FieldTypeNumber
+}
+
+// MaxSortKeys es el número máximo de campos de un orden compuesto.
+const MaxSortKeys = 4
+
+// IsSortField indica si field es una columna o un campo calculado por el que se puede
+// ordenar. orderByClause ordena por ticker cualquier otro valor.
+func IsSortField(field string) bool {
+	_, computed := computedSortFields[field]
+	return computed || sortColumns[field]
+}
+
+// SortFields devuelve, en orden alfabético, los campos por los que se puede ordenar.
+func SortFields() []string {
+	fields := make([]string, 0, len(sortColumns)+len(computedSortFields))
+	for field := range sortColumns {
+		fields = append(fields, field)
+	}
+	for field := range computedSortFields {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// sortKey es uno de los campos de un orden compuesto, con su dirección ("asc" o "desc").
+type sortKey struct {
+	field, order string
+}
+
+// splitSort separa sortBy ("campo[:asc|desc],...", ej. "recommendation_score:desc,ticker")
+// en sus campos. Los que no indican dirección usan order.
+func splitSort(sortBy, order string) []sortKey {
+	var keys []sortKey
+	for _, part := range strings.Split(sortBy, ",") {
+		field, direction, found := strings.Cut(strings.TrimSpace(part), ":")
+		if !found {
+			direction = order
+		}
+		keys = append(keys, sortKey{field: strings.TrimSpace(field), order: strings.ToLower(strings.TrimSpace(direction))})
+	}
+	return keys
+}
+
+// ValidateSort comprueba que sortBy sea un campo por el que se puede ordenar o una lista de
+// hasta MaxSortKeys separados por comas, cada uno con :asc o :desc opcional y sin repetir.
+func ValidateSort(sortBy string) error {
+	keys := splitSort(sortBy, "")
+	if len(keys) > MaxSortKeys {
+		return fmt.Errorf("se puede ordenar como máximo por %d campos", MaxSortKeys)
+	}
+	seen := map[string]bool{}
+	for _, key := range keys {
+		if !IsSortField(key.field) {
+			return fmt.Errorf("campo de orden no soportado: %q", key.field)
+		}
+		if key.order != "" && key.order != "asc" && key.order != "desc" {
+			return fmt.Errorf("dirección de orden no soportada para %s: %q (use asc o desc)", key.field, key.order)
+		}
+		if seen[key.field] {
+			return fmt.Errorf("campo de orden repetido: %s", key.field)
+		}
+		seen[key.field] = true
+	}
+	return nil
+}
+
+// orderByClause construye la cláusula ORDER BY para sortBy (un campo o una lista, ver
+// splitSort) y order ("asc" o "desc"), la dirección de los campos que no indican la suya.
+// Los campos no soportados se ordenan por ticker. Los campos calculados dejan los NULL al
+// final y, si el orden no incluye ya el ticker, se desempata por ticker para que la
+// paginación sea estable.
+func orderByClause(sortBy, order string) string {
+	var terms []string
+	byTicker, computed := false, false
+	for _, key := range splitSort(sortBy, order) {
+		direction := "ASC"
+		if key.order == "desc" {
+			direction = "DESC"
+		}
+
+		if expr, ok := computedSortFields[key.field]; ok {
+			terms = append(terms, expr+" "+direction+" NULLS LAST")
+			computed = true
+			continue
+		}
+		field := key.field
+		if !sortColumns[field] {
+			field = "ticker" // Default to a safe column
+		}
+		byTicker = byTicker || field == "ticker"
+		terms = append(terms, field+" "+direction)
+	}
+	if computed && !byTicker {
+		terms = append(terms, "ticker ASC")
+	}
+	return " ORDER BY " + strings.Join(terms, ", ")
+}
@@ -0,0 +1,55 @@
+package database
+
+import "testing"
+
+func TestOrderByClause(t *testing.T) {
+	tests := []struct {
+		field, order string
+		want         string
+	}{
+		{"current_price", "desc", " ORDER BY current_price DESC"},
+		{"company", "", " ORDER BY company ASC"},
+		{"ticker; DROP TABLE stocks", "asc", " ORDER BY ticker ASC"},
+		{"target_upside", "desc", " ORDER BY " + targetUpsideExpr + " DESC NULLS LAST, ticker ASC"},
+		{"change_percent", "asc", " ORDER BY " + changePercentExpr + " ASC NULLS LAST, ticker ASC"},
+		{"staleness", "desc", " ORDER BY " + stalenessExpr + " DESC NULLS LAST, ticker ASC"},
+		{"buzz", "desc", " ORDER BY buzz DESC NULLS LAST, ticker ASC"},
+		{"relative_strength", "desc", " ORDER BY relative_strength_90d DESC NULLS LAST, ticker ASC"},
+		// Órdenes compuestos: cada campo con su dirección o la de order
+		{"recommendation_score:desc,ticker:asc", "", " ORDER BY recommendation_score DESC, ticker ASC"},
+		{"action,current_price:desc", "desc", " ORDER BY action DESC, current_price DESC"},
+		{"target_upside:desc, company", "", " ORDER BY " + targetUpsideExpr + " DESC NULLS LAST, company ASC, ticker ASC"},
+		{"buzz:desc,ticker:desc", "", " ORDER BY buzz DESC NULLS LAST, ticker DESC"},
+		{"company:desc;DROP TABLE stocks", "", " ORDER BY company ASC"},
+	}
+
+	for _, tt := range tests {
+		if got := orderByClause(tt.field, tt.order); got != tt.want {
+			t.Errorf("❌ orderByClause(%q, %q) = %q, se esperaba %q", tt.field, tt.order, got, tt.want)
+		}
+	}
+}
+
+func TestValidateSort(t *testing.T) {
+	for _, sortBy := range []string{"ticker", "recommendation_score:desc,ticker:asc", "target_upside:DESC, company"} {
+		if err := ValidateSort(sortBy); err != nil {
+			t.Errorf("❌ ValidateSort(%q): %v", sortBy, err)
+		}
+	}
+	for _, sortBy := range []string{
+		"bogus", "ticker,", "ticker:up", "ticker,ticker:desc", "company:desc;DROP TABLE stocks",
+		"ticker,company,action,alpha,pe_ratio",
+	} {
+		if err := ValidateSort(sortBy); err == nil {
+			t.Errorf("❌ se esperaba un error para %q", sortBy)
+		}
+	}
+}
+
+func TestSortFieldType(t *testing.T) {
+	for field, want := range map[string]string{"ticker": FieldTypeString, "target_upside": FieldTypeNumber, "staleness": FieldTypeDuration, "bogus": ""} {
+		if got := SortFieldType(field); got != want {
+			t.Errorf("❌ SortFieldType(%q) = %q, se esperaba %q", field, got, want)
+		}
+	}
+}
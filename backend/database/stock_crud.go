@@ -0,0 +1,121 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// stockWriteColumns son las columnas que escribe un cliente, en el orden de upsertStockArgs.
+const stockWriteColumns = `ticker, company, brokerage, action, rating_from, rating_to,
+            target_from, target_to, current_price, pe_ratio, dividend_yield,
+            market_capitalization, alpha, latest_trading_day, recommendation_score, sector, previous_close,
+            sentiment, buzz, esg_score, short_interest, days_to_cover, relative_strength_30d, relative_strength_90d,
+            provider_errors, provenance`
+
+const createStockSQL = `
+        INSERT INTO stocks (` + stockWriteColumns + `, created_at, updated_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24,
+            $25, $26, now(), now())
+        RETURNING ` + stockColumns
+
+const updateStockSQL = `
+        UPDATE stocks SET (` + stockWriteColumns + `, updated_at) =
+            ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24,
+            $25, $26, now())
+        WHERE id = $27
+        RETURNING ` + stockColumns
+
+// CreateStock inserta un stock nuevo y lo devuelve tal como quedó guardado. A diferencia de
+// UpsertStocks no pisa un stock existente: devuelve ErrTickerExists si el ticker ya está.
+func (c *cockroachDB) CreateStock(ctx context.Context, s models.Stock) (models.Stock, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Stock{}, fmt.Errorf("error al iniciar la transacción para crear %s: %w", s.Ticker, err)
+	}
+	defer tx.Rollback()
+
+	if err := lockTickers(ctx, tx, []string{s.Ticker}); err != nil {
+		return models.Stock{}, err
+	}
+	created, err := scanStock(tx.QueryRowContext(ctx, createStockSQL, upsertStockArgs(s)...))
+	if isUniqueViolation(err) {
+		return models.Stock{}, fmt.Errorf("no se puede crear %s: %w", s.Ticker, ErrTickerExists)
+	}
+	if err != nil {
+		return models.Stock{}, fmt.Errorf("error al crear el stock %s: %w", s.Ticker, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return models.Stock{}, fmt.Errorf("error al confirmar la creación de %s: %w", s.Ticker, err)
+	}
+	return created, nil
+}
+
+// UpdateStock reemplaza todos los campos del stock con ID id por los de s (el ID, la fecha
+// de creación y el nivel de enriquecimiento se conservan) y lo devuelve actualizado.
+// Devuelve ErrStockNotFound si no existe y ErrTickerExists si s cambia el ticker por el de
+// otro stock.
+func (c *cockroachDB) UpdateStock(ctx context.Context, id string, s models.Stock) (models.Stock, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Stock{}, fmt.Errorf("error al iniciar la transacción para actualizar %s: %w", id, err)
+	}
+	defer tx.Rollback()
+
+	// Se bloquean el ticker actual y el nuevo: si la actualización cambia el ticker, también
+	// escribe la fila del anterior.
+	if _, err := lockStockTicker(ctx, tx, id, s.Ticker); err != nil {
+		return models.Stock{}, err
+	}
+	args := append(upsertStockArgs(s), id)
+	updated, err := scanStock(tx.QueryRowContext(ctx, updateStockSQL, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Stock{}, fmt.Errorf("stock con ID %s: %w", id, ErrStockNotFound)
+	}
+	if isUniqueViolation(err) {
+		return models.Stock{}, fmt.Errorf("no se puede cambiar el ticker a %s: %w", s.Ticker, ErrTickerExists)
+	}
+	if err != nil {
+		return models.Stock{}, fmt.Errorf("error al actualizar el stock %s: %w", id, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return models.Stock{}, fmt.Errorf("error al confirmar la actualización de %s: %w", id, err)
+	}
+	return updated, nil
+}
+
+// DeleteStock borra el stock con ID id, o devuelve ErrStockNotFound si no existe. Sus
+// históricos (precios, menciones, eventos corporativos) se conservan por ticker: si el
+// stock se vuelve a crear, los recupera.
+func (c *cockroachDB) DeleteStock(ctx context.Context, id string) error {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error al iniciar la transacción para borrar %s: %w", id, err)
+	}
+	defer tx.Rollback()
+
+	// Con el ticker bloqueado, un enriquecimiento concurrente no puede volver a crear el stock
+	// entre la lectura y el borrado.
+	if _, err := lockStockTicker(ctx, tx, id); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM stocks WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("error al borrar el stock %s: %w", id, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error al confirmar el borrado de %s: %w", id, err)
+	}
+	return nil
+}
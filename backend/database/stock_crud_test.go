@@ -0,0 +1,106 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// stockWriteArgs espera los argumentos de createStockSQL/updateStockSQL comprobando solo el
+// ticker y, si se indica, el ID.
+func stockWriteArgs(ticker string, id ...string) []driver.Value {
+	args := make([]driver.Value, 26, 27)
+	for i := range args {
+		args[i] = sqlmock.AnyArg()
+	}
+	args[0] = ticker
+	for _, v := range id {
+		args = append(args, v)
+	}
+	return args
+}
+
+func TestCreateUpdateDeleteStock(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	sdb := NewStockDB(db)
+	id := uuid.New()
+	now := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	columns := []string{"id", "ticker", "company", "brokerage", "action", "rating_from", "rating_to", "target_from", "target_to", "current_price", "pe_ratio", "dividend_yield", "market_capitalization", "alpha", "latest_trading_day", "recommendation_score", "sector", "previous_close", "sentiment", "buzz", "esg_score", "short_interest", "days_to_cover", "relative_strength_30d", "relative_strength_90d", "provider_errors", "provenance", "enrichment_tier", "created_at", "updated_at"}
+	row := func(ticker string, price float64) *sqlmock.Rows {
+		return sqlmock.NewRows(columns).AddRow(id.String(), ticker, "Apple", "", "", "", "", nil, nil, price, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, []byte(`{}`), nil, now, now)
+	}
+
+	mock.ExpectBegin()
+	expectLockTickers(mock, "{AAPL}").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO stocks (ticker, company")).WithArgs(stockWriteArgs("AAPL")...).
+		WillReturnRows(row("AAPL", 190))
+	mock.ExpectCommit()
+	created, err := sdb.CreateStock(context.Background(), models.Stock{Ticker: "AAPL", Company: "Apple", CurrentPrice: 190})
+	if err != nil || created.ID != id || created.Exchange != models.ExchangeUS {
+		t.Errorf("❌ stock creado inesperado: %+v (%v)", created, err)
+	}
+
+	mock.ExpectBegin()
+	expectLockTickers(mock, "{AAPL}").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO stocks (ticker, company")).WithArgs(stockWriteArgs("AAPL")...).
+		WillReturnError(&pgconn.PgError{Code: uniqueViolation})
+	mock.ExpectRollback()
+	if _, err := sdb.CreateStock(context.Background(), models.Stock{Ticker: "AAPL"}); !errors.Is(err, ErrTickerExists) {
+		t.Errorf("❌ crear un ticker repetido devolvió %v, se esperaba ErrTickerExists", err)
+	}
+
+	// Al cambiar el ticker se bloquean el actual (FB) y el nuevo (META).
+	mock.ExpectBegin()
+	expectStockTicker(mock, id.String(), "FB")
+	expectLockTickers(mock, "{FB,META}").WillReturnResult(sqlmock.NewResult(0, 2))
+	expectStockTickerForUpdate(mock, id.String(), "FB")
+	mock.ExpectQuery(regexp.QuoteMeta("UPDATE stocks SET (ticker, company")).WithArgs(stockWriteArgs("META", id.String())...).
+		WillReturnRows(row("META", 195))
+	mock.ExpectCommit()
+	updated, err := sdb.UpdateStock(context.Background(), id.String(), models.Stock{Ticker: "META", Company: "Meta", CurrentPrice: 195})
+	if err != nil || updated.CurrentPrice != 195 {
+		t.Errorf("❌ stock actualizado inesperado: %+v (%v)", updated, err)
+	}
+
+	missing := uuid.New().String()
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(stockTickerSQL)).WithArgs(missing).WillReturnRows(sqlmock.NewRows([]string{"ticker"}))
+	mock.ExpectRollback()
+	if _, err := sdb.UpdateStock(context.Background(), missing, models.Stock{Ticker: "AAPL"}); !errors.Is(err, ErrStockNotFound) {
+		t.Errorf("❌ actualizar un stock inexistente devolvió %v, se esperaba ErrStockNotFound", err)
+	}
+
+	mock.ExpectBegin()
+	expectStockTicker(mock, id.String(), "AAPL")
+	expectLockTickers(mock, "{AAPL}").WillReturnResult(sqlmock.NewResult(0, 1))
+	expectStockTickerForUpdate(mock, id.String(), "AAPL")
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM stocks WHERE id = $1")).WithArgs(id.String()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	if err := sdb.DeleteStock(context.Background(), id.String()); err != nil {
+		t.Errorf("❌ error inesperado al borrar el stock: %v", err)
+	}
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(stockTickerSQL)).WithArgs(missing).WillReturnRows(sqlmock.NewRows([]string{"ticker"}))
+	mock.ExpectRollback()
+	if err := sdb.DeleteStock(context.Background(), missing); !errors.Is(err, ErrStockNotFound) {
+		t.Errorf("❌ borrar un stock inexistente devolvió %v, se esperaba ErrStockNotFound", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("⚠️ expectativas no cumplidas en TestCreateUpdateDeleteStock: %s", err)
+	}
+}
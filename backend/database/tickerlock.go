@@ -0,0 +1,84 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// lockTickersSQL bloquea la fila de ticker_locks de cada ticker, creándola si no existe,
+// hasta que termina la transacción. Las filas se escriben en el orden del array.
+const lockTickersSQL = `
+        INSERT INTO ticker_locks (ticker)
+        SELECT ticker FROM unnest($1::TEXT[]) WITH ORDINALITY AS t (ticker, n) ORDER BY n
+        ON CONFLICT (ticker) DO UPDATE SET locked_at = now()`
+
+// lockTickers serializa las escrituras concurrentes sobre los mismos tickers (por ejemplo,
+// el enriquecimiento diario y una actualización manual del mismo stock), también entre
+// distintas instancias del backend: bloquea su fila de ticker_locks dentro de tx, así que
+// el bloqueo se libera al confirmar o deshacer la transacción. Los tickers se bloquean
+// siempre en orden alfabético (y sin duplicados) para que dos escrituras con tickers en
+// común no puedan bloquearse mutuamente. Si ctx se cancela mientras espera, la espera se
+// interrumpe y devuelve el error.
+func lockTickers(ctx context.Context, tx *sql.Tx, tickers []string) error {
+	if _, err := tx.ExecContext(ctx, lockTickersSQL, pgArray(uniqueSorted(tickers))); err != nil {
+		return fmt.Errorf("error al bloquear los tickers: %w", err)
+	}
+	return nil
+}
+
+// uniqueSorted devuelve una copia ordenada de tickers sin duplicados.
+func uniqueSorted(tickers []string) []string {
+	seen := make(map[string]bool, len(tickers))
+	unique := make([]string, 0, len(tickers))
+	for _, ticker := range tickers {
+		if !seen[ticker] {
+			seen[ticker] = true
+			unique = append(unique, ticker)
+		}
+	}
+	sort.Strings(unique)
+	return unique
+}
+
+// stockTickerSQL lee el ticker actual de un stock.
+const stockTickerSQL = `SELECT ticker FROM stocks WHERE id = $1`
+
+// lockStockTicker bloquea dentro de tx el ticker actual del stock con ID id, junto con
+// tickers, y lo devuelve; ErrStockNotFound si no existe. Primero se bloquean los tickers y
+// después la fila del stock, en el mismo orden que UpsertStocks, para que las dos escrituras
+// no puedan esperarse mutuamente. Si el ticker cambió entre la lectura y el bloqueo, se
+// bloquea también el nuevo y se vuelve a comprobar.
+func lockStockTicker(ctx context.Context, tx *sql.Tx, id string, tickers ...string) (string, error) {
+	ticker, err := readStockTicker(ctx, tx, stockTickerSQL, id)
+	if err != nil {
+		return "", err
+	}
+	for {
+		if err := lockTickers(ctx, tx, append([]string{ticker}, tickers...)); err != nil {
+			return "", err
+		}
+		locked, err := readStockTicker(ctx, tx, stockTickerSQL+` FOR UPDATE`, id)
+		if err != nil {
+			return "", err
+		}
+		if locked == ticker {
+			return ticker, nil
+		}
+		ticker = locked
+	}
+}
+
+func readStockTicker(ctx context.Context, tx *sql.Tx, query, id string) (string, error) {
+	var ticker string
+	err := tx.QueryRowContext(ctx, query, id).Scan(&ticker)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("stock con ID %s: %w", id, ErrStockNotFound)
+	}
+	if err != nil {
+		return "", fmt.Errorf("error al leer el ticker del stock %s: %w", id, err)
+	}
+	return ticker, nil
+}
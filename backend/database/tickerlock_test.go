@@ -0,0 +1,104 @@
+package database
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// expectLockTickers espera el bloqueo de lockTickers con los tickers del literal de array.
+func expectLockTickers(mock sqlmock.Sqlmock, tickers string) *sqlmock.ExpectedExec {
+	return mock.ExpectExec(regexp.QuoteMeta(lockTickersSQL)).WithArgs(tickers)
+}
+
+// expectStockTicker espera la lectura del ticker actual del stock id por lockStockTicker.
+func expectStockTicker(mock sqlmock.Sqlmock, id, ticker string) {
+	mock.ExpectQuery(regexp.QuoteMeta(stockTickerSQL)).WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{"ticker"}).AddRow(ticker))
+}
+
+// expectStockTickerForUpdate espera la relectura con bloqueo de la fila tras bloquear los
+// tickers.
+func expectStockTickerForUpdate(mock sqlmock.Sqlmock, id, ticker string) {
+	mock.ExpectQuery(regexp.QuoteMeta(stockTickerSQL + ` FOR UPDATE`)).WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{"ticker"}).AddRow(ticker))
+}
+
+func TestLockTickers_SortedAndUnique(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	expectLockTickers(mock, "{AAPL,KO,MSFT}").WillReturnResult(sqlmock.NewResult(0, 3))
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := lockTickers(context.Background(), tx, []string{"MSFT", "KO", "AAPL", "AAPL"}); err != nil {
+		t.Errorf("❌ error inesperado al bloquear los tickers: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("⚠️ expectativas no cumplidas en TestLockTickers_SortedAndUnique: %s", err)
+	}
+}
+
+// TestLockTickers_RespectsContext comprueba que la espera por un ticker bloqueado por otra
+// transacción termina cuando se cancela ctx.
+func TestLockTickers_RespectsContext(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	expectLockTickers(mock, "{AAPL}").WillDelayFor(time.Minute).WillReturnResult(sqlmock.NewResult(0, 1))
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if err := lockTickers(ctx, tx, []string{"AAPL"}); err == nil {
+		t.Errorf("❌ lockTickers no devolvió error al cancelar ctx")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("❌ lockTickers tardó %s en rendirse tras cancelar ctx", elapsed)
+	}
+}
+
+// TestLockStockTicker_TickerChanged comprueba que, si otra escritura cambió el ticker del
+// stock antes de obtener el bloqueo, se bloquea también el nuevo.
+func TestLockStockTicker_TickerChanged(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	expectStockTicker(mock, "id-1", "FB")
+	expectLockTickers(mock, "{FB}").WillReturnResult(sqlmock.NewResult(0, 1))
+	expectStockTickerForUpdate(mock, "id-1", "META")
+	expectLockTickers(mock, "{META}").WillReturnResult(sqlmock.NewResult(0, 1))
+	expectStockTickerForUpdate(mock, "id-1", "META")
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ticker, err := lockStockTicker(context.Background(), tx, "id-1")
+	if err != nil || ticker != "META" {
+		t.Errorf("❌ lockStockTicker devolvió %q (%v), se esperaba META", ticker, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("⚠️ expectativas no cumplidas en TestLockStockTicker_TickerChanged: %s", err)
+	}
+}
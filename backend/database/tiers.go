@@ -0,0 +1,61 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// ErrStockNotFound indica que no existe ningún stock con el identificador indicado.
+var ErrStockNotFound = errors.New("stock no encontrado")
+
+// GetEnrichmentSchedule devuelve, por ticker, el tier asignado, la capitalización y la
+// fecha del último enriquecimiento de cada stock guardado.
+func (c *cockroachDB) GetEnrichmentSchedule(ctx context.Context) (map[string]models.EnrichmentSchedule, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	rows, err := c.db.QueryContext(ctx,
+		"SELECT ticker, enrichment_tier, market_capitalization, updated_at FROM stocks")
+	if err != nil {
+		return nil, fmt.Errorf("error al consultar la planificación de enriquecimiento: %w", err)
+	}
+	defer rows.Close()
+
+	schedule := make(map[string]models.EnrichmentSchedule)
+	for rows.Next() {
+		var s models.EnrichmentSchedule
+		var tier sql.NullString
+		if err := rows.Scan(&s.Ticker, &tier, &s.MarketCapitalization, &s.LastEnrichedAt); err != nil {
+			return nil, fmt.Errorf("error al escanear la planificación de enriquecimiento: %w", err)
+		}
+		s.Tier = tier.String
+		schedule[s.Ticker] = s
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error al iterar la planificación de enriquecimiento: %w", err)
+	}
+	return schedule, nil
+}
+
+// SetEnrichmentTier asigna un tier de enriquecimiento al stock con el ticker indicado. Un
+// tier vacío vuelve a la asignación automática por capitalización. Devuelve
+// ErrStockNotFound si el ticker no existe.
+func (c *cockroachDB) SetEnrichmentTier(ctx context.Context, ticker, tier string) error {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	res, err := c.db.ExecContext(ctx,
+		"UPDATE stocks SET enrichment_tier = $1 WHERE ticker = $2",
+		sql.NullString{String: tier, Valid: tier != ""}, ticker)
+	if err != nil {
+		return fmt.Errorf("error al asignar el tier de %s: %w", ticker, err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("%s: %w", ticker, ErrStockNotFound)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+func TestGetEnrichmentSchedule(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	updated := time.Date(2025, 1, 6, 12, 0, 0, 0, time.UTC)
+	rows := sqlmock.NewRows([]string{"ticker", "enrichment_tier", "market_capitalization", "updated_at"}).
+		AddRow("AAPL", nil, 3.0e6, updated).
+		AddRow("XYZ", "archived", nil, updated)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT ticker, enrichment_tier, market_capitalization, updated_at FROM stocks")).WillReturnRows(rows)
+
+	schedule, err := NewStockDB(db).GetEnrichmentSchedule(context.Background())
+	if err != nil {
+		t.Fatalf("❌ error inesperado: %v", err)
+	}
+	if got := schedule["AAPL"].EffectiveTier(); got != models.EnrichmentTierHot {
+		t.Errorf("❌ tier de AAPL = %s, se esperaba hot (mega cap)", got)
+	}
+	if got := schedule["XYZ"]; got.EffectiveTier() != models.EnrichmentTierArchived || !got.LastEnrichedAt.Equal(updated) {
+		t.Errorf("❌ planificación de XYZ inesperada: %+v", got)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("⚠️ expectativas no cumplidas: %s", err)
+	}
+}
+
+func TestSetEnrichmentTier(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	sdb := NewStockDB(db)
+
+	update := regexp.QuoteMeta("UPDATE stocks SET enrichment_tier = $1 WHERE ticker = $2")
+	mock.ExpectExec(update).WithArgs("archived", "XYZ").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(update).WithArgs(nil, "AAPL").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(update).WithArgs("hot", "NOPE").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := sdb.SetEnrichmentTier(context.Background(), "XYZ", "archived"); err != nil {
+		t.Errorf("❌ error inesperado: %v", err)
+	}
+	if err := sdb.SetEnrichmentTier(context.Background(), "AAPL", ""); err != nil {
+		t.Errorf("❌ error inesperado al volver al tier automático: %v", err)
+	}
+	if err := sdb.SetEnrichmentTier(context.Background(), "NOPE", "hot"); !errors.Is(err, ErrStockNotFound) {
+		t.Errorf("❌ se esperaba ErrStockNotFound, se obtuvo %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("⚠️ expectativas no cumplidas: %s", err)
+	}
+}
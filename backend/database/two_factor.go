@@ -0,0 +1,191 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+var (
+	// ErrTwoFactorEnabled indica que el usuario ya tiene el 2FA activado.
+	ErrTwoFactorEnabled = errors.New("la autenticación en dos pasos ya está activada")
+	// ErrSecondFactorReused indica que el código TOTP ya se usó (o uno posterior).
+	ErrSecondFactorReused = errors.New("el código ya se ha utilizado")
+	// ErrPortfolioNotFound indica que la cartera no existe o no es del usuario.
+	ErrPortfolioNotFound = errors.New("cartera no encontrada")
+)
+
+// GetTwoFactor devuelve el estado del 2FA de un usuario activo.
+func (c *cockroachDB) GetTwoFactor(ctx context.Context, userID uuid.UUID) (models.TwoFactor, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	var tf models.TwoFactor
+	err := c.db.QueryRowContext(ctx,
+		`SELECT COALESCE(totp_secret, ''), totp_enabled_at, totp_last_step, second_factor_at,
+            (SELECT count(*) FROM user_recovery_codes WHERE user_id = users.id AND used_at IS NULL)
+        FROM users WHERE id = $1 AND deleted_at IS NULL`, userID).
+		Scan(&tf.Secret, &tf.EnabledAt, &tf.LastStep, &tf.VerifiedAt, &tf.RecoveryCodesLeft)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.TwoFactor{}, ErrUserNotFound
+	}
+	if err != nil {
+		return models.TwoFactor{}, fmt.Errorf("error al obtener el 2FA del usuario %s: %w", userID, err)
+	}
+	return tf, nil
+}
+
+// SetPendingTOTPSecret guarda el secreto de un alta de 2FA aún sin confirmar, sustituyendo
+// cualquier alta anterior sin confirmar. Devuelve ErrTwoFactorEnabled si ya está activado.
+func (c *cockroachDB) SetPendingTOTPSecret(ctx context.Context, userID uuid.UUID, secret string) error {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	res, err := c.db.ExecContext(ctx,
+		"UPDATE users SET totp_secret = $2 WHERE id = $1 AND deleted_at IS NULL AND totp_enabled_at IS NULL", userID, secret)
+	if err != nil {
+		return fmt.Errorf("error al guardar el secreto TOTP del usuario %s: %w", userID, err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		if _, err := c.GetTwoFactor(ctx, userID); err != nil {
+			return err
+		}
+		return ErrTwoFactorEnabled
+	}
+	return nil
+}
+
+// EnableTwoFactor activa el 2FA (si no lo estaba) y sustituye los códigos de recuperación
+// por los de los hashes indicados; también sirve para regenerarlos.
+func (c *cockroachDB) EnableTwoFactor(ctx context.Context, userID uuid.UUID, recoveryCodeHashes []string) error {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error al iniciar la transacción del 2FA: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx,
+		"UPDATE users SET totp_enabled_at = COALESCE(totp_enabled_at, now()) WHERE id = $1 AND deleted_at IS NULL AND totp_secret IS NOT NULL", userID)
+	if err != nil {
+		return fmt.Errorf("error al activar el 2FA del usuario %s: %w", userID, err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrUserNotFound
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM user_recovery_codes WHERE user_id = $1", userID); err != nil {
+		return fmt.Errorf("error al borrar los códigos de recuperación: %w", err)
+	}
+	for _, hash := range recoveryCodeHashes {
+		if _, err := tx.ExecContext(ctx,
+			"INSERT INTO user_recovery_codes (user_id, code_hash) VALUES ($1, $2)", userID, hash); err != nil {
+			return fmt.Errorf("error al guardar los códigos de recuperación: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error al confirmar la activación del 2FA: %w", err)
+	}
+	return nil
+}
+
+// DisableTwoFactor desactiva el 2FA y borra el secreto y los códigos de recuperación.
+func (c *cockroachDB) DisableTwoFactor(ctx context.Context, userID uuid.UUID) error {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error al iniciar la transacción del 2FA: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx,
+		"UPDATE users SET totp_secret = NULL, totp_enabled_at = NULL, second_factor_at = NULL WHERE id = $1 AND deleted_at IS NULL", userID)
+	if err != nil {
+		return fmt.Errorf("error al desactivar el 2FA del usuario %s: %w", userID, err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrUserNotFound
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM user_recovery_codes WHERE user_id = $1", userID); err != nil {
+		return fmt.Errorf("error al borrar los códigos de recuperación: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error al confirmar la desactivación del 2FA: %w", err)
+	}
+	return nil
+}
+
+// RecordSecondFactor registra una verificación correcta del segundo factor con el paso
+// TOTP indicado. Devuelve ErrSecondFactorReused si ya se aceptó ese paso o uno posterior,
+// de modo que cada código solo sirve una vez.
+func (c *cockroachDB) RecordSecondFactor(ctx context.Context, userID uuid.UUID, totpStep int64) error {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	res, err := c.db.ExecContext(ctx,
+		"UPDATE users SET totp_last_step = $2, second_factor_at = now() WHERE id = $1 AND deleted_at IS NULL AND totp_last_step < $2",
+		userID, totpStep)
+	if err != nil {
+		return fmt.Errorf("error al registrar el segundo factor del usuario %s: %w", userID, err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrSecondFactorReused
+	}
+	return nil
+}
+
+// UseRecoveryCode consume el código de recuperación con el hash indicado y, si era válido,
+// registra la verificación del segundo factor. Devuelve false si no existe o ya se usó.
+func (c *cockroachDB) UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("error al iniciar la transacción del código de recuperación: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx,
+		"UPDATE user_recovery_codes SET used_at = now() WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL", userID, codeHash)
+	if err != nil {
+		return false, fmt.Errorf("error al usar el código de recuperación: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE users SET second_factor_at = now() WHERE id = $1", userID); err != nil {
+		return false, fmt.Errorf("error al registrar el segundo factor del usuario %s: %w", userID, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("error al confirmar el uso del código de recuperación: %w", err)
+	}
+	return true, nil
+}
+
+// DeletePortfolio marca como borrada una cartera del usuario.
+func (c *cockroachDB) DeletePortfolio(ctx context.Context, userID, portfolioID uuid.UUID) error {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	res, err := c.db.ExecContext(ctx,
+		"UPDATE portfolios SET deleted_at = now() WHERE id = $2 AND user_id = $1 AND deleted_at IS NULL", userID, portfolioID)
+	if err != nil {
+		return fmt.Errorf("error al borrar la cartera %s: %w", portfolioID, err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrPortfolioNotFound
+	}
+	return nil
+}
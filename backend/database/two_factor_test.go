@@ -0,0 +1,64 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+)
+
+func TestRecordSecondFactor_RejectsReplays(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	udb := NewUserDB(db)
+
+	userID := uuid.New()
+	record := regexp.QuoteMeta("UPDATE users SET totp_last_step = $2, second_factor_at = now() WHERE id = $1 AND deleted_at IS NULL AND totp_last_step < $2")
+	mock.ExpectExec(record).WithArgs(userID, int64(58000)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(record).WithArgs(userID, int64(58000)).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := udb.RecordSecondFactor(context.Background(), userID, 58000); err != nil {
+		t.Errorf("❌ error inesperado: %v", err)
+	}
+	if err := udb.RecordSecondFactor(context.Background(), userID, 58000); !errors.Is(err, ErrSecondFactorReused) {
+		t.Errorf("❌ se esperaba ErrSecondFactorReused, se obtuvo %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("⚠️ expectativas no cumplidas en TestRecordSecondFactor_RejectsReplays: %s", err)
+	}
+}
+
+func TestUseRecoveryCode(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	udb := NewUserDB(db)
+
+	userID := uuid.New()
+	useCode := regexp.QuoteMeta("UPDATE user_recovery_codes SET used_at = now() WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL")
+	mock.ExpectBegin()
+	mock.ExpectExec(useCode).WithArgs(userID, "hash").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE users SET second_factor_at = now() WHERE id = $1")).WithArgs(userID).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec(useCode).WithArgs(userID, "hash").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	if used, err := udb.UseRecoveryCode(context.Background(), userID, "hash"); !used || err != nil {
+		t.Errorf("❌ UseRecoveryCode = %v, %v; se esperaba true", used, err)
+	}
+	if used, err := udb.UseRecoveryCode(context.Background(), userID, "hash"); used || err != nil {
+		t.Errorf("❌ un código ya usado no debería aceptarse: %v, %v", used, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("⚠️ expectativas no cumplidas en TestUseRecoveryCode: %s", err)
+	}
+}
@@ -0,0 +1,173 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// ErrUserNotFound indica que el usuario no existe o ya está marcado para borrado.
+var ErrUserNotFound = errors.New("usuario no encontrado")
+
+// userDataTables son las tablas con datos de usuario que se marcan como borradas junto
+// con la cuenta.
+var userDataTables = []string{"watchlists", "notes", "portfolios", "alerts", "webhooks"}
+
+// NewUserDB crea una instancia de UserDB sobre la misma conexión que StockDB.
+func NewUserDB(dbConn *sql.DB) UserDB {
+	return &cockroachDB{db: dbConn}
+}
+
+// UserIDForAPIKey devuelve el usuario activo cuya clave de API tiene el hash indicado.
+func (c *cockroachDB) UserIDForAPIKey(ctx context.Context, keyHash string) (uuid.UUID, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	var id uuid.UUID
+	err := c.db.QueryRowContext(ctx,
+		"SELECT id FROM users WHERE api_key_hash = $1 AND deleted_at IS NULL", keyHash).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return uuid.Nil, ErrUserNotFound
+	}
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("error al buscar el usuario por clave de API: %w", err)
+	}
+	return id, nil
+}
+
+// GetUserData devuelve la cuenta y todos los datos no borrados de un usuario activo.
+func (c *cockroachDB) GetUserData(ctx context.Context, userID uuid.UUID) (models.UserData, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	var data models.UserData
+
+	err := c.db.QueryRowContext(ctx,
+		"SELECT id, email, email_verified_at, created_at FROM users WHERE id = $1 AND deleted_at IS NULL", userID).
+		Scan(&data.User.ID, &data.User.Email, &data.User.EmailVerifiedAt, &data.User.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.UserData{}, ErrUserNotFound
+	}
+	if err != nil {
+		return models.UserData{}, fmt.Errorf("error al obtener el usuario %s: %w", userID, err)
+	}
+
+	data.Watchlists = []models.Watchlist{}
+	err = c.queryUserRows(ctx, "SELECT id, name, tickers, created_at, updated_at FROM watchlists WHERE user_id = $1 AND deleted_at IS NULL ORDER BY created_at", userID,
+		func(rows *sql.Rows) error {
+			w := models.Watchlist{UserID: userID}
+			if err := rows.Scan(&w.ID, &w.Name, scanArray(&w.Tickers), &w.CreatedAt, &w.UpdatedAt); err != nil {
+				return err
+			}
+			data.Watchlists = append(data.Watchlists, w)
+			return nil
+		})
+	if err != nil {
+		return models.UserData{}, fmt.Errorf("error al obtener las watchlists del usuario %s: %w", userID, err)
+	}
+
+	data.Notes = []models.Note{}
+	err = c.queryUserRows(ctx, "SELECT id, ticker, body, created_at, updated_at FROM notes WHERE user_id = $1 AND deleted_at IS NULL ORDER BY created_at", userID,
+		func(rows *sql.Rows) error {
+			n := models.Note{UserID: userID}
+			if err := rows.Scan(&n.ID, &n.Ticker, &n.Body, &n.CreatedAt, &n.UpdatedAt); err != nil {
+				return err
+			}
+			data.Notes = append(data.Notes, n)
+			return nil
+		})
+	if err != nil {
+		return models.UserData{}, fmt.Errorf("error al obtener las notas del usuario %s: %w", userID, err)
+	}
+
+	data.Portfolios = []models.Portfolio{}
+	err = c.queryUserRows(ctx, "SELECT id, name, positions, created_at, updated_at FROM portfolios WHERE user_id = $1 AND deleted_at IS NULL ORDER BY created_at", userID,
+		func(rows *sql.Rows) error {
+			p := models.Portfolio{UserID: userID}
+			if err := rows.Scan(&p.ID, &p.Name, &p.Positions, &p.CreatedAt, &p.UpdatedAt); err != nil {
+				return err
+			}
+			data.Portfolios = append(data.Portfolios, p)
+			return nil
+		})
+	if err != nil {
+		return models.UserData{}, fmt.Errorf("error al obtener las carteras del usuario %s: %w", userID, err)
+	}
+
+	return data, nil
+}
+
+// queryUserRows ejecuta query con userID como único argumento y llama a fn por cada fila.
+func (c *cockroachDB) queryUserRows(ctx context.Context, query string, userID uuid.UUID, fn func(*sql.Rows) error) error {
+	rows, err := c.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := fn(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// SoftDeleteUser marca como borrados al usuario y todos sus datos, en una transacción, y
+// devuelve el momento del borrado. La cuenta deja de autenticarse de inmediato; los datos
+// se eliminan definitivamente con PurgeDeletedUsers.
+func (c *cockroachDB) SoftDeleteUser(ctx context.Context, userID uuid.UUID) (time.Time, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("error al iniciar la transacción de borrado: %w", err)
+	}
+	defer tx.Rollback()
+
+	var deletedAt time.Time
+	err = tx.QueryRowContext(ctx,
+		"UPDATE users SET deleted_at = now() WHERE id = $1 AND deleted_at IS NULL RETURNING deleted_at", userID).Scan(&deletedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, ErrUserNotFound
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("error al marcar como borrado al usuario %s: %w", userID, err)
+	}
+
+	for _, table := range userDataTables {
+		query := "UPDATE " + table + " SET deleted_at = $2 WHERE user_id = $1 AND deleted_at IS NULL"
+		if _, err := tx.ExecContext(ctx, query, userID, deletedAt); err != nil {
+			return time.Time{}, fmt.Errorf("error al marcar como borrados los datos de %s: %w", table, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return time.Time{}, fmt.Errorf("error al confirmar el borrado del usuario %s: %w", userID, err)
+	}
+	return deletedAt, nil
+}
+
+// PurgeDeletedUsers elimina definitivamente los usuarios borrados antes de deletedBefore;
+// sus datos se eliminan en cascada. Devuelve cuántos usuarios se purgaron.
+func (c *cockroachDB) PurgeDeletedUsers(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	result, err := c.db.ExecContext(ctx,
+		"DELETE FROM users WHERE deleted_at IS NOT NULL AND deleted_at < $1", deletedBefore)
+	if err != nil {
+		return 0, fmt.Errorf("error al purgar usuarios borrados: %w", err)
+	}
+	purged, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("error al contar los usuarios purgados: %w", err)
+	}
+	return purged, nil
+}
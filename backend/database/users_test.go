@@ -0,0 +1,119 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+)
+
+func TestSoftDeleteUser(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	udb := NewUserDB(db)
+
+	userID := uuid.New()
+	deletedAt := time.Date(2025, 1, 6, 12, 0, 0, 0, time.UTC)
+	deleteUser := regexp.QuoteMeta("UPDATE users SET deleted_at = now() WHERE id = $1 AND deleted_at IS NULL RETURNING deleted_at")
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(deleteUser).WithArgs(userID).WillReturnRows(sqlmock.NewRows([]string{"deleted_at"}).AddRow(deletedAt))
+	for _, table := range []string{"watchlists", "notes", "portfolios", "alerts", "webhooks"} {
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE "+table+" SET deleted_at = $2 WHERE user_id = $1 AND deleted_at IS NULL")).
+			WithArgs(userID, deletedAt).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+	}
+	mock.ExpectCommit()
+
+	got, err := udb.SoftDeleteUser(context.Background(), userID)
+	if err != nil || !got.Equal(deletedAt) {
+		t.Errorf("❌ SoftDeleteUser = %s, %v; se esperaba %s", got, err, deletedAt)
+	}
+
+	// Un usuario ya borrado (o inexistente) no se vuelve a marcar
+	mock.ExpectBegin()
+	mock.ExpectQuery(deleteUser).WithArgs(userID).WillReturnRows(sqlmock.NewRows([]string{"deleted_at"}))
+	mock.ExpectRollback()
+
+	if _, err := udb.SoftDeleteUser(context.Background(), userID); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("❌ se esperaba ErrUserNotFound, se obtuvo %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("⚠️ expectativas no cumplidas: %s", err)
+	}
+}
+
+func TestGetUserData(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	userID := uuid.New()
+	created := time.Date(2025, 1, 6, 12, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, email, email_verified_at, created_at FROM users WHERE id = $1 AND deleted_at IS NULL")).
+		WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "email_verified_at", "created_at"}).AddRow(userID.String(), "ana@example.com", created, created))
+	mock.ExpectQuery(regexp.QuoteMeta("FROM watchlists WHERE user_id = $1 AND deleted_at IS NULL")).
+		WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "tickers", "created_at", "updated_at"}).
+			AddRow(uuid.New().String(), "Dividendos", "{KO,PFE}", created, created))
+	mock.ExpectQuery(regexp.QuoteMeta("FROM notes WHERE user_id = $1 AND deleted_at IS NULL")).
+		WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "ticker", "body", "created_at", "updated_at"}))
+	mock.ExpectQuery(regexp.QuoteMeta("FROM portfolios WHERE user_id = $1 AND deleted_at IS NULL")).
+		WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "positions", "created_at", "updated_at"}).
+			AddRow(uuid.New().String(), "Principal", []byte(`[{"ticker":"KO","shares":10,"cost_basis":55.2}]`), created, created))
+
+	data, err := NewUserDB(db).GetUserData(context.Background(), userID)
+	if err != nil {
+		t.Fatalf("❌ error inesperado: %v", err)
+	}
+	if data.User.Email != "ana@example.com" || len(data.Watchlists) != 1 || len(data.Portfolios) != 1 {
+		t.Fatalf("❌ datos inesperados: %+v", data)
+	}
+	if got := data.Watchlists[0].Tickers; len(got) != 2 || got[1] != "PFE" {
+		t.Errorf("❌ tickers de la watchlist inesperados: %v", got)
+	}
+	if data.Notes == nil {
+		t.Errorf("❌ las notas deberían ser una lista vacía, no nil")
+	}
+	if got := data.Portfolios[0].Positions; len(got) != 1 || got[0].Shares != 10 {
+		t.Errorf("❌ posiciones inesperadas: %+v", got)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("⚠️ expectativas no cumplidas: %s", err)
+	}
+}
+
+func TestPurgeDeletedUsers(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	cutoff := time.Date(2024, 12, 7, 12, 0, 0, 0, time.UTC)
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM users WHERE deleted_at IS NOT NULL AND deleted_at < $1")).
+		WithArgs(cutoff).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	if purged, err := NewUserDB(db).PurgeDeletedUsers(context.Background(), cutoff); err != nil || purged != 3 {
+		t.Errorf("❌ PurgeDeletedUsers = %d, %v; se esperaban 3 usuarios purgados", purged, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("⚠️ expectativas no cumplidas: %s", err)
+	}
+}
@@ -0,0 +1,259 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+var (
+	// ErrWatchlistNotFound indica que la watchlist no existe, está borrada o el usuario no es
+	// ni su propietario ni miembro.
+	ErrWatchlistNotFound = errors.New("watchlist no encontrada")
+	// ErrWatchlistForbidden indica que el usuario es miembro de la watchlist pero su rol no le
+	// permite el cambio.
+	ErrWatchlistForbidden = errors.New("el rol del usuario en la watchlist no permite el cambio")
+	// ErrWatchlistFull indica que la watchlist superaría el máximo de tickers.
+	ErrWatchlistFull = errors.New("la watchlist superaría el máximo de tickers")
+	// ErrWatchlistMemberNotFound indica que el usuario no es miembro de la watchlist.
+	ErrWatchlistMemberNotFound = errors.New("el usuario no es miembro de la watchlist")
+)
+
+// watchlistAccessSQL selecciona las watchlists a las que accede el usuario $1, propias o
+// compartidas con él, con su rol en cada una.
+const watchlistAccessSQL = `SELECT w.id, w.user_id, w.name, w.tickers, COALESCE(m.role, '` + models.WatchlistOwner + `'), w.created_at, w.updated_at
+    FROM watchlists AS w LEFT JOIN watchlist_members AS m ON m.watchlist_id = w.id AND m.user_id = $1
+    WHERE w.deleted_at IS NULL AND (w.user_id = $1 OR m.user_id IS NOT NULL)`
+
+func scanWatchlist(row interface{ Scan(...interface{}) error }) (models.Watchlist, error) {
+	var w models.Watchlist
+	err := row.Scan(&w.ID, &w.UserID, &w.Name, scanArray(&w.Tickers), &w.Role, &w.CreatedAt, &w.UpdatedAt)
+	return w, err
+}
+
+// ListWatchlists devuelve las watchlists del usuario y las compartidas con él, de la más
+// antigua a la más reciente.
+func (c *cockroachDB) ListWatchlists(ctx context.Context, userID uuid.UUID) ([]models.Watchlist, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	rows, err := c.db.QueryContext(ctx, watchlistAccessSQL+" ORDER BY w.created_at", userID)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener las watchlists del usuario %s: %w", userID, err)
+	}
+	defer rows.Close()
+
+	watchlists := []models.Watchlist{}
+	for rows.Next() {
+		w, err := scanWatchlist(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error al leer las watchlists del usuario %s: %w", userID, err)
+		}
+		watchlists = append(watchlists, w)
+	}
+	return watchlists, rows.Err()
+}
+
+// GetWatchlist devuelve una watchlist del usuario o compartida con él.
+func (c *cockroachDB) GetWatchlist(ctx context.Context, userID, watchlistID uuid.UUID) (models.Watchlist, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	w, err := scanWatchlist(c.db.QueryRowContext(ctx, watchlistAccessSQL+" AND w.id = $2", userID, watchlistID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Watchlist{}, ErrWatchlistNotFound
+	}
+	if err != nil {
+		return models.Watchlist{}, fmt.Errorf("error al obtener la watchlist %s: %w", watchlistID, err)
+	}
+	return w, nil
+}
+
+// ownerOnly explica por qué el usuario no pudo hacer un cambio reservado al propietario de
+// la watchlist: ErrWatchlistForbidden si es miembro y ErrWatchlistNotFound si no la ve.
+func (c *cockroachDB) ownerOnly(ctx context.Context, userID, watchlistID uuid.UUID) error {
+	if _, err := c.GetWatchlist(ctx, userID, watchlistID); err != nil {
+		return err
+	}
+	return ErrWatchlistForbidden
+}
+
+// DeleteWatchlist borra una watchlist del usuario. Deja de contar para su cuota y de verse
+// para los miembros con los que la compartía.
+func (c *cockroachDB) DeleteWatchlist(ctx context.Context, userID, watchlistID uuid.UUID) error {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	res, err := c.db.ExecContext(ctx,
+		"UPDATE watchlists SET deleted_at = now() WHERE id = $2 AND user_id = $1 AND deleted_at IS NULL", userID, watchlistID)
+	if err != nil {
+		return fmt.Errorf("error al borrar la watchlist %s: %w", watchlistID, err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return c.ownerOnly(ctx, userID, watchlistID)
+	}
+	return nil
+}
+
+// UpdateWatchlistTickers quita de la watchlist los tickers de remove y añade al final los de
+// add que no tenía, si el usuario es su propietario o editor. Bloquea la fila mientras
+// tanto, así que dos miembros que la cambian a la vez no pierden los cambios del otro.
+// Devuelve ErrWatchlistFull, sin cambiarla, si acabaría con más de maxTickers.
+func (c *cockroachDB) UpdateWatchlistTickers(ctx context.Context, userID, watchlistID uuid.UUID, add, remove []string, maxTickers int) (models.Watchlist, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Watchlist{}, fmt.Errorf("error al iniciar la transacción de la watchlist %s: %w", watchlistID, err)
+	}
+	defer tx.Rollback()
+
+	w, err := scanWatchlist(tx.QueryRowContext(ctx, watchlistAccessSQL+" AND w.id = $2 FOR UPDATE OF w", userID, watchlistID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Watchlist{}, ErrWatchlistNotFound
+	}
+	if err != nil {
+		return models.Watchlist{}, fmt.Errorf("error al obtener la watchlist %s: %w", watchlistID, err)
+	}
+	if w.Role == models.WatchlistViewer {
+		return models.Watchlist{}, ErrWatchlistForbidden
+	}
+
+	removed := make(map[string]bool, len(remove))
+	for _, ticker := range remove {
+		removed[ticker] = true
+	}
+	seen := map[string]bool{}
+	tickers := []string{}
+	for _, ticker := range append(w.Tickers, add...) {
+		if !removed[ticker] && !seen[ticker] {
+			seen[ticker] = true
+			tickers = append(tickers, ticker)
+		}
+	}
+	if len(tickers) > maxTickers {
+		return models.Watchlist{}, ErrWatchlistFull
+	}
+
+	if err := tx.QueryRowContext(ctx, "UPDATE watchlists SET tickers = $2, updated_at = now() WHERE id = $1 RETURNING updated_at",
+		watchlistID, pgArray(tickers)).Scan(&w.UpdatedAt); err != nil {
+		return models.Watchlist{}, fmt.Errorf("error al actualizar la watchlist %s: %w", watchlistID, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return models.Watchlist{}, fmt.Errorf("error al confirmar los cambios de la watchlist %s: %w", watchlistID, err)
+	}
+	w.Tickers = tickers
+	return w, nil
+}
+
+// ListWatchlistMembers devuelve quién accede a la watchlist, empezando por su propietario y
+// después por orden de incorporación. Cualquier miembro puede verlos.
+func (c *cockroachDB) ListWatchlistMembers(ctx context.Context, userID, watchlistID uuid.UUID) ([]models.WatchlistMember, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	if _, err := c.GetWatchlist(ctx, userID, watchlistID); err != nil {
+		return nil, err
+	}
+	rows, err := c.db.QueryContext(ctx,
+		`SELECT u.id, u.email, '`+models.WatchlistOwner+`', w.created_at, 0 AS rank
+        FROM watchlists AS w JOIN users AS u ON u.id = w.user_id WHERE w.id = $1
+        UNION ALL
+        SELECT u.id, u.email, m.role, m.added_at, 1 AS rank
+        FROM watchlist_members AS m JOIN users AS u ON u.id = m.user_id WHERE m.watchlist_id = $1 AND u.deleted_at IS NULL
+        ORDER BY rank, 4`, watchlistID)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener los miembros de la watchlist %s: %w", watchlistID, err)
+	}
+	defer rows.Close()
+
+	members := []models.WatchlistMember{}
+	for rows.Next() {
+		var m models.WatchlistMember
+		var rank int
+		if err := rows.Scan(&m.UserID, &m.Email, &m.Role, &m.AddedAt, &rank); err != nil {
+			return nil, fmt.Errorf("error al leer los miembros de la watchlist %s: %w", watchlistID, err)
+		}
+		members = append(members, m)
+	}
+	return members, rows.Err()
+}
+
+// SetWatchlistMember comparte la watchlist de ownerID con memberID con el rol indicado
+// (editor o viewer), o le cambia el rol si ya era miembro.
+func (c *cockroachDB) SetWatchlistMember(ctx context.Context, ownerID, watchlistID, memberID uuid.UUID, role string) (models.WatchlistMember, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	m := models.WatchlistMember{UserID: memberID, Role: role}
+	err := c.db.QueryRowContext(ctx,
+		`INSERT INTO watchlist_members (watchlist_id, user_id, role)
+        SELECT id, $3, $4 FROM watchlists WHERE id = $2 AND user_id = $1 AND deleted_at IS NULL
+        ON CONFLICT (watchlist_id, user_id) DO UPDATE SET role = excluded.role
+        RETURNING added_at`, ownerID, watchlistID, memberID, role).Scan(&m.AddedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.WatchlistMember{}, c.ownerOnly(ctx, ownerID, watchlistID)
+	}
+	if err != nil {
+		return models.WatchlistMember{}, fmt.Errorf("error al compartir la watchlist %s: %w", watchlistID, err)
+	}
+	return m, nil
+}
+
+// RemoveWatchlistMember deja de compartir la watchlist con memberID. Puede hacerlo su
+// propietario o el propio miembro, para abandonarla.
+func (c *cockroachDB) RemoveWatchlistMember(ctx context.Context, userID, watchlistID, memberID uuid.UUID) error {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	res, err := c.db.ExecContext(ctx,
+		`DELETE FROM watchlist_members WHERE watchlist_id = $2 AND user_id = $3
+        AND ($3 = $1 OR EXISTS (SELECT 1 FROM watchlists WHERE id = $2 AND user_id = $1 AND deleted_at IS NULL))`,
+		userID, watchlistID, memberID)
+	if err != nil {
+		return fmt.Errorf("error al quitar el miembro %s de la watchlist %s: %w", memberID, watchlistID, err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n > 0 {
+		return nil
+	}
+	w, err := c.GetWatchlist(ctx, userID, watchlistID)
+	switch {
+	case err != nil:
+		return err
+	case w.Role != models.WatchlistOwner && memberID != userID:
+		return ErrWatchlistForbidden
+	}
+	return ErrWatchlistMemberNotFound
+}
+
+// GetStocksByTickers devuelve los stocks de los tickers indicados, ordenados por ticker.
+// Los tickers que no están en la base de datos se omiten.
+func (c *cockroachDB) GetStocksByTickers(ctx context.Context, tickers []string) ([]models.Stock, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	stocks := []models.Stock{}
+	if len(tickers) == 0 {
+		return stocks, nil
+	}
+	rows, err := c.db.QueryContext(ctx,
+		"SELECT "+stockColumns+" FROM stocks"+c.asOfClause()+" WHERE ticker = ANY($1) ORDER BY ticker", pgArray(tickers))
+	if err != nil {
+		return nil, fmt.Errorf("error al consultar los stocks de %d tickers: %w", len(tickers), err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		s, err := scanStock(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error al escanear fila de stock: %w", err)
+		}
+		stocks = append(stocks, s)
+	}
+	return stocks, rows.Err()
+}
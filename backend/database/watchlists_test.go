@@ -0,0 +1,173 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+)
+
+var watchlistColumns = []string{"id", "user_id", "name", "tickers", "role", "created_at", "updated_at"}
+
+func TestGetAndDeleteWatchlist(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	udb := NewUserDB(db)
+
+	userID, ownerID, watchlistID := uuid.New(), uuid.New(), uuid.New()
+	now := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	selectWatchlist := regexp.QuoteMeta("WHERE w.deleted_at IS NULL AND (w.user_id = $1 OR m.user_id IS NOT NULL) AND w.id = $2")
+	mock.ExpectQuery(selectWatchlist).WithArgs(userID, watchlistID).
+		WillReturnRows(sqlmock.NewRows(watchlistColumns).AddRow(watchlistID, ownerID, "Tech", "{AAPL,MSFT}", "editor", now, now))
+	mock.ExpectQuery(selectWatchlist).WithArgs(userID, watchlistID).
+		WillReturnRows(sqlmock.NewRows(watchlistColumns))
+
+	w, err := udb.GetWatchlist(context.Background(), userID, watchlistID)
+	if err != nil || w.Name != "Tech" || len(w.Tickers) != 2 || w.Tickers[1] != "MSFT" || w.UserID != ownerID || w.Role != "editor" {
+		t.Errorf("❌ watchlist inesperada: %+v (%v)", w, err)
+	}
+	if _, err := udb.GetWatchlist(context.Background(), userID, watchlistID); !errors.Is(err, ErrWatchlistNotFound) {
+		t.Errorf("❌ se esperaba ErrWatchlistNotFound, se obtuvo %v", err)
+	}
+
+	deleteWatchlist := regexp.QuoteMeta("UPDATE watchlists SET deleted_at = now() WHERE id = $2 AND user_id = $1 AND deleted_at IS NULL")
+	mock.ExpectExec(deleteWatchlist).WithArgs(userID, watchlistID).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(deleteWatchlist).WithArgs(userID, watchlistID).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(selectWatchlist).WithArgs(userID, watchlistID).WillReturnRows(sqlmock.NewRows(watchlistColumns))
+	mock.ExpectExec(deleteWatchlist).WithArgs(userID, watchlistID).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(selectWatchlist).WithArgs(userID, watchlistID).
+		WillReturnRows(sqlmock.NewRows(watchlistColumns).AddRow(watchlistID, ownerID, "Tech", "{}", "editor", now, now))
+	if err := udb.DeleteWatchlist(context.Background(), userID, watchlistID); err != nil {
+		t.Errorf("❌ error inesperado al borrar la watchlist: %v", err)
+	}
+	if err := udb.DeleteWatchlist(context.Background(), userID, watchlistID); !errors.Is(err, ErrWatchlistNotFound) {
+		t.Errorf("❌ borrar una watchlist ya borrada devolvió %v, se esperaba ErrWatchlistNotFound", err)
+	}
+	if err := udb.DeleteWatchlist(context.Background(), userID, watchlistID); !errors.Is(err, ErrWatchlistForbidden) {
+		t.Errorf("❌ un editor borró la watchlist: %v, se esperaba ErrWatchlistForbidden", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("⚠️ expectativas no cumplidas en TestGetAndDeleteWatchlist: %s", err)
+	}
+}
+
+func TestUpdateWatchlistTickers(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	udb := NewUserDB(db)
+
+	userID, watchlistID := uuid.New(), uuid.New()
+	now := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	selectForUpdate := regexp.QuoteMeta("AND w.id = $2 FOR UPDATE OF w")
+	row := func(role string) *sqlmock.Rows {
+		return sqlmock.NewRows(watchlistColumns).AddRow(watchlistID, uuid.New(), "Tech", "{AAPL,MSFT,NVDA}", role, now, now)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(selectForUpdate).WithArgs(userID, watchlistID).WillReturnRows(row("editor"))
+	mock.ExpectQuery(regexp.QuoteMeta("UPDATE watchlists SET tickers = $2, updated_at = now() WHERE id = $1 RETURNING updated_at")).
+		WithArgs(watchlistID, pgArray([]string{"AAPL", "NVDA", "TSLA"})).
+		WillReturnRows(sqlmock.NewRows([]string{"updated_at"}).AddRow(now.Add(time.Minute)))
+	mock.ExpectCommit()
+	w, err := udb.UpdateWatchlistTickers(context.Background(), userID, watchlistID, []string{"TSLA", "AAPL"}, []string{"MSFT"}, 3)
+	if err != nil || len(w.Tickers) != 3 || w.Tickers[2] != "TSLA" || !w.UpdatedAt.Equal(now.Add(time.Minute)) {
+		t.Errorf("❌ watchlist actualizada inesperada: %+v (%v)", w, err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(selectForUpdate).WithArgs(userID, watchlistID).WillReturnRows(row("owner"))
+	mock.ExpectRollback()
+	if _, err := udb.UpdateWatchlistTickers(context.Background(), userID, watchlistID, []string{"TSLA"}, nil, 3); !errors.Is(err, ErrWatchlistFull) {
+		t.Errorf("❌ se esperaba ErrWatchlistFull, se obtuvo %v", err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(selectForUpdate).WithArgs(userID, watchlistID).WillReturnRows(row("viewer"))
+	mock.ExpectRollback()
+	if _, err := udb.UpdateWatchlistTickers(context.Background(), userID, watchlistID, []string{"TSLA"}, nil, 10); !errors.Is(err, ErrWatchlistForbidden) {
+		t.Errorf("❌ se esperaba ErrWatchlistForbidden para un viewer, se obtuvo %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("⚠️ expectativas no cumplidas en TestUpdateWatchlistTickers: %s", err)
+	}
+}
+
+func TestRemoveWatchlistMember(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	udb := NewUserDB(db)
+
+	userID, memberID, watchlistID := uuid.New(), uuid.New(), uuid.New()
+	now := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	deleteMember := regexp.QuoteMeta("DELETE FROM watchlist_members WHERE watchlist_id = $2 AND user_id = $3")
+	selectWatchlist := regexp.QuoteMeta("AND w.id = $2")
+
+	mock.ExpectExec(deleteMember).WithArgs(userID, watchlistID, memberID).WillReturnResult(sqlmock.NewResult(0, 1))
+	if err := udb.RemoveWatchlistMember(context.Background(), userID, watchlistID, memberID); err != nil {
+		t.Errorf("❌ error inesperado al quitar el miembro: %v", err)
+	}
+
+	tests := []struct {
+		role string
+		want error
+	}{
+		{"owner", ErrWatchlistMemberNotFound},
+		{"editor", ErrWatchlistForbidden},
+	}
+	for _, tt := range tests {
+		mock.ExpectExec(deleteMember).WithArgs(userID, watchlistID, memberID).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(selectWatchlist).WithArgs(userID, watchlistID).
+			WillReturnRows(sqlmock.NewRows(watchlistColumns).AddRow(watchlistID, uuid.New(), "Tech", "{}", tt.role, now, now))
+		if err := udb.RemoveWatchlistMember(context.Background(), userID, watchlistID, memberID); !errors.Is(err, tt.want) {
+			t.Errorf("❌ quitar un miembro como %s devolvió %v, se esperaba %v", tt.role, err, tt.want)
+		}
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("⚠️ expectativas no cumplidas en TestRemoveWatchlistMember: %s", err)
+	}
+}
+
+func TestGetStocksByTickers(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	sdb := NewStockDB(db)
+
+	if stocks, err := sdb.GetStocksByTickers(context.Background(), nil); err != nil || stocks == nil || len(stocks) != 0 {
+		t.Errorf("❌ sin tickers se esperaba una lista vacía sin consultar: %v (%v)", stocks, err)
+	}
+
+	now := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	columns := []string{"id", "ticker", "company", "brokerage", "action", "rating_from", "rating_to", "target_from", "target_to", "current_price", "pe_ratio", "dividend_yield", "market_capitalization", "alpha", "latest_trading_day", "recommendation_score", "sector", "previous_close", "sentiment", "buzz", "esg_score", "short_interest", "days_to_cover", "relative_strength_30d", "relative_strength_90d", "provider_errors", "provenance", "enrichment_tier", "created_at", "updated_at"}
+	mock.ExpectQuery(regexp.QuoteMeta("FROM stocks WHERE ticker = ANY($1) ORDER BY ticker")).
+		WithArgs(pgArray([]string{"MSFT", "AAPL", "ZZZZ"})).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(uuid.New().String(), "AAPL", "Apple", "", "", "", "", nil, nil, 190.0, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, []byte(`{}`), nil, now, now).
+			AddRow(uuid.New().String(), "MSFT", "Microsoft", "", "", "", "", nil, nil, 410.0, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, []byte(`{}`), nil, now, now))
+
+	stocks, err := sdb.GetStocksByTickers(context.Background(), []string{"MSFT", "AAPL", "ZZZZ"})
+	if err != nil || len(stocks) != 2 || stocks[0].Ticker != "AAPL" || stocks[1].CurrentPrice != 410 {
+		t.Errorf("❌ stocks inesperados: %+v (%v)", stocks, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("⚠️ expectativas no cumplidas en TestGetStocksByTickers: %s", err)
+	}
+}
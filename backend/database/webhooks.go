@@ -0,0 +1,121 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// ErrWebhookNotFound indica que el webhook no existe, está borrado o no es del usuario.
+var ErrWebhookNotFound = errors.New("webhook no encontrado")
+
+// CreateWebhook registra un webhook del usuario con su secreto de firma.
+func (c *cockroachDB) CreateWebhook(ctx context.Context, userID uuid.UUID, url, secret string) (models.Webhook, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	w := models.Webhook{UserID: userID, URL: url, Secret: secret}
+	err := c.db.QueryRowContext(ctx,
+		`INSERT INTO webhooks (user_id, url, secret)
+        SELECT $1, $2, $3 FROM users WHERE id = $1 AND deleted_at IS NULL
+        RETURNING id, created_at`, userID, url, secret).Scan(&w.ID, &w.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Webhook{}, ErrUserNotFound
+	}
+	if err != nil {
+		return models.Webhook{}, fmt.Errorf("error al crear el webhook del usuario %s: %w", userID, err)
+	}
+	return w, nil
+}
+
+// ListWebhooks devuelve los webhooks del usuario, sin sus secretos.
+func (c *cockroachDB) ListWebhooks(ctx context.Context, userID uuid.UUID) ([]models.Webhook, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	rows, err := c.db.QueryContext(ctx,
+		"SELECT id, url, created_at FROM webhooks WHERE user_id = $1 AND deleted_at IS NULL ORDER BY created_at", userID)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener los webhooks del usuario %s: %w", userID, err)
+	}
+	defer rows.Close()
+
+	webhooks := []models.Webhook{}
+	for rows.Next() {
+		w := models.Webhook{UserID: userID}
+		if err := rows.Scan(&w.ID, &w.URL, &w.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error al leer los webhooks del usuario %s: %w", userID, err)
+		}
+		webhooks = append(webhooks, w)
+	}
+	return webhooks, rows.Err()
+}
+
+// GetWebhook devuelve un webhook del usuario con su secreto, para firmar las entregas.
+func (c *cockroachDB) GetWebhook(ctx context.Context, userID, webhookID uuid.UUID) (models.Webhook, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	w := models.Webhook{ID: webhookID, UserID: userID}
+	err := c.db.QueryRowContext(ctx,
+		"SELECT url, secret, created_at FROM webhooks WHERE id = $2 AND user_id = $1 AND deleted_at IS NULL",
+		userID, webhookID).Scan(&w.URL, &w.Secret, &w.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Webhook{}, ErrWebhookNotFound
+	}
+	if err != nil {
+		return models.Webhook{}, fmt.Errorf("error al obtener el webhook %s: %w", webhookID, err)
+	}
+	return w, nil
+}
+
+// DeleteWebhook borra un webhook del usuario.
+func (c *cockroachDB) DeleteWebhook(ctx context.Context, userID, webhookID uuid.UUID) error {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	res, err := c.db.ExecContext(ctx,
+		"UPDATE webhooks SET deleted_at = now() WHERE id = $2 AND user_id = $1 AND deleted_at IS NULL", userID, webhookID)
+	if err != nil {
+		return fmt.Errorf("error al borrar el webhook %s: %w", webhookID, err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+// RememberWebhookNonce guarda el nonce de un webhook recibido y devuelve false si ya
+// estaba, es decir, si la entrega es una repetición. Implementa webhook.NonceStore.
+func (c *cockroachDB) RememberWebhookNonce(ctx context.Context, nonce string, expiresAt time.Time) (bool, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	res, err := c.db.ExecContext(ctx,
+		"INSERT INTO webhook_nonces (nonce, expires_at) VALUES ($1, $2) ON CONFLICT (nonce) DO NOTHING", nonce, expiresAt)
+	if err != nil {
+		return false, fmt.Errorf("error al guardar el nonce del webhook: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error al comprobar el nonce del webhook: %w", err)
+	}
+	return n == 1, nil
+}
+
+// PurgeWebhookNonces elimina los nonces caducados antes de before y devuelve cuántos eran.
+func (c *cockroachDB) PurgeWebhookNonces(ctx context.Context, before time.Time) (int64, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	res, err := c.db.ExecContext(ctx, "DELETE FROM webhook_nonces WHERE expires_at < $1", before)
+	if err != nil {
+		return 0, fmt.Errorf("error al purgar los nonces de webhooks: %w", err)
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,55 @@
+package database
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+)
+
+func TestRememberWebhookNonce(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	udb := NewUserDB(db)
+
+	expires := time.Date(2025, 1, 6, 9, 5, 0, 0, time.UTC)
+	insert := regexp.QuoteMeta("INSERT INTO webhook_nonces (nonce, expires_at) VALUES ($1, $2) ON CONFLICT (nonce) DO NOTHING")
+	mock.ExpectExec(insert).WithArgs("karenai:n-1", expires).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(insert).WithArgs("karenai:n-1", expires).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if fresh, err := udb.RememberWebhookNonce(context.Background(), "karenai:n-1", expires); err != nil || !fresh {
+		t.Errorf("❌ un nonce nuevo debería aceptarse: %v, %v", fresh, err)
+	}
+	if fresh, err := udb.RememberWebhookNonce(context.Background(), "karenai:n-1", expires); err != nil || fresh {
+		t.Errorf("❌ un nonce repetido debería detectarse: %v, %v", fresh, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("⚠️ expectativas no cumplidas en TestRememberWebhookNonce: %s", err)
+	}
+}
+
+func TestGetWebhook_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	udb := NewUserDB(db)
+
+	userID, webhookID := uuid.New(), uuid.New()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT url, secret, created_at FROM webhooks WHERE id = $2 AND user_id = $1 AND deleted_at IS NULL")).
+		WithArgs(userID, webhookID).WillReturnRows(sqlmock.NewRows([]string{"url", "secret", "created_at"}))
+
+	if _, err := udb.GetWebhook(context.Background(), userID, webhookID); err != ErrWebhookNotFound {
+		t.Errorf("❌ se esperaba ErrWebhookNotFound, se obtuvo %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("⚠️ expectativas no cumplidas en TestGetWebhook_NotFound: %s", err)
+	}
+}
@@ -2,15 +2,21 @@ module github.com/jannin2/stock-app/backend
 
 go 1.24.5
 
-require github.com/lib/pq v1.10.9
-
 require github.com/go-chi/chi/v5 v5.2.2
 
 require github.com/DATA-DOG/go-sqlmock v1.5.2
 
-require github.com/google/uuid v1.6.0
+require (
+	github.com/go-chi/cors v1.2.2
+	github.com/google/uuid v1.6.0
+	github.com/jackc/pgx/v5 v5.8.0
+	github.com/joho/godotenv v1.5.1
+)
 
 require (
-	github.com/go-chi/cors v1.2.2 // indirect
-	github.com/joho/godotenv v1.5.1 // indirect
+	github.com/jackc/pgpassfile v1.0.0 // indirect
+	github.com/jackc/pgservicefile v0.0.0-20240606120523-5a60cdf6a761 // indirect
+	github.com/jackc/puddle/v2 v2.2.2 // indirect
+	golang.org/x/sync v0.17.0 // indirect
+	golang.org/x/text v0.29.0 // indirect
 )
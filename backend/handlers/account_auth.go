@@ -0,0 +1,314 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	netmail "net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/apierror"
+	"github.com/jannin2/stock-app/backend/auth"
+	"github.com/jannin2/stock-app/backend/database"
+	"github.com/jannin2/stock-app/backend/mail"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// Validez de los enlaces enviados por e-mail. El de reseteo es corto porque da acceso a
+// la cuenta.
+const (
+	verifyEmailTTL   = 48 * time.Hour
+	resetPasswordTTL = time.Hour
+)
+
+type credentialsRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	Code     string `json:"code,omitempty"` // Código TOTP o de recuperación, si la cuenta tiene 2FA
+}
+
+type emailRequest struct {
+	Email string `json:"email"`
+}
+
+type tokenRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password,omitempty"` // Solo en el reseteo de contraseña
+}
+
+// loginResponse es la respuesta de POST /auth/login: un token de sesión (JWT), que se envía
+// como "Authorization: Bearer ...". Las claves de API se crean aparte, con POST /me/api-key.
+type loginResponse struct {
+	Token          string      `json:"token"`
+	TokenExpiresAt time.Time   `json:"token_expires_at"`
+	User           models.User `json:"user"`
+}
+
+// Register maneja POST /auth/register: crea una cuenta sin verificar y envía el enlace de
+// verificación. No se puede iniciar sesión hasta verificar el e-mail.
+func (h *UserHandlers) Register(w http.ResponseWriter, r *http.Request) {
+	var req credentialsRequest
+	if !decodeAuthRequest(w, r, &req) {
+		return
+	}
+	email, ok := normalizeEmail(w, req.Email)
+	if !ok {
+		return
+	}
+	if err := auth.ValidatePassword(req.Password); err != nil {
+		apierror.HTTPError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	passwordHash, err := auth.HashPassword(req.Password)
+	if err != nil {
+		apierror.Internal(w, "Error al registrar la cuenta", err)
+		return
+	}
+	user, err := h.users.CreateUser(r.Context(), email, passwordHash)
+	if errors.Is(err, database.ErrEmailTaken) {
+		apierror.HTTPError(w, "Ya existe una cuenta con ese e-mail", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		apierror.Internal(w, "Error al registrar la cuenta", err)
+		return
+	}
+
+	h.sendVerification(user)
+	writeJSON(w, r, http.StatusCreated, user)
+}
+
+// VerifyEmail maneja POST /auth/verify-email con el token del enlace de verificación.
+func (h *UserHandlers) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req tokenRequest
+	if !decodeAuthRequest(w, r, &req) {
+		return
+	}
+	creds, ok := h.credentialsForToken(w, r, req.Token, auth.PurposeVerifyEmail)
+	if !ok {
+		return
+	}
+
+	verifiedAt, err := h.users.MarkEmailVerified(r.Context(), creds.ID)
+	if err != nil {
+		writeError(w, err, "Error al verificar el e-mail")
+		return
+	}
+	creds.User.EmailVerifiedAt = &verifiedAt
+	writeJSON(w, r, http.StatusOK, creds.User)
+}
+
+// ResendVerification maneja POST /auth/verify-email/resend. Responde 202 exista o no la
+// cuenta, para no revelar qué e-mails están registrados.
+func (h *UserHandlers) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	var req emailRequest
+	if !decodeAuthRequest(w, r, &req) {
+		return
+	}
+	email, ok := normalizeEmail(w, req.Email)
+	if !ok {
+		return
+	}
+
+	creds, err := h.users.GetCredentialsByEmail(r.Context(), email)
+	switch {
+	case err == nil && creds.EmailVerifiedAt == nil:
+		h.sendVerification(creds.User)
+	case err != nil && !errors.Is(err, database.ErrUserNotFound):
+		log.Printf("Advertencia: no se pudo reenviar la verificación de e-mail: %v", err)
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// Login maneja POST /auth/login: con e-mail verificado, contraseña correcta y, si la cuenta
+// tiene 2FA, un código válido, emite un token de sesión. La clave de API del usuario no
+// cambia: la usan otros clientes y solo se rota con POST /me/api-key.
+func (h *UserHandlers) Login(w http.ResponseWriter, r *http.Request) {
+	var req credentialsRequest
+	if !decodeAuthRequest(w, r, &req) {
+		return
+	}
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+
+	creds, err := h.users.GetCredentialsByEmail(r.Context(), email)
+	if err != nil && !errors.Is(err, database.ErrUserNotFound) {
+		apierror.Internal(w, "Error al iniciar sesión", err)
+		return
+	}
+	// Con una cuenta inexistente CheckPassword compara contra un hash de relleno: la
+	// respuesta tarda lo mismo y no revela si el e-mail está registrado.
+	if !auth.CheckPassword(req.Password, creds.PasswordHash) {
+		apierror.HTTPError(w, "E-mail o contraseña incorrectos", http.StatusUnauthorized)
+		return
+	}
+	if creds.EmailVerifiedAt == nil {
+		apierror.HTTPError(w, "Debes verificar tu e-mail antes de iniciar sesión", http.StatusForbidden)
+		return
+	}
+
+	tf, err := h.users.GetTwoFactor(r.Context(), creds.ID)
+	if err != nil {
+		writeError(w, err, "Error al iniciar sesión")
+		return
+	}
+	if tf.Enabled() {
+		if strings.TrimSpace(req.Code) == "" {
+			// El código two_factor_required indica al cliente que pida el código y repita el login
+			apierror.Write(w, apierror.New(http.StatusUnauthorized, "Falta el código de autenticación en dos pasos").WithCode(apierror.CodeTwoFactorRequired))
+			return
+		}
+		if err := h.checkSecondFactor(r.Context(), creds.ID, tf.Secret, req.Code); err != nil {
+			writeSecondFactorError(w, err)
+			return
+		}
+	}
+
+	token, expiresAt := auth.SignAccessToken(creds.ID, auth.Fingerprint(creds.PasswordHash), h.now())
+	writeJSON(w, r, http.StatusOK, loginResponse{Token: token, TokenExpiresAt: expiresAt, User: creds.User})
+}
+
+// ForgotPassword maneja POST /auth/password/forgot: envía un enlace de reseteo si la
+// cuenta existe. Responde 202 siempre, para no revelar qué e-mails están registrados.
+func (h *UserHandlers) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req emailRequest
+	if !decodeAuthRequest(w, r, &req) {
+		return
+	}
+	email, ok := normalizeEmail(w, req.Email)
+	if !ok {
+		return
+	}
+
+	creds, err := h.users.GetCredentialsByEmail(r.Context(), email)
+	switch {
+	case err == nil:
+		// La huella del hash actual hace que el token deje de valer al cambiar la contraseña.
+		expiresAt := h.now().Add(resetPasswordTTL)
+		token := auth.SignToken(auth.PurposeResetPassword, creds.ID, auth.Fingerprint(creds.PasswordHash), expiresAt)
+		h.sendLink(mail.TemplateResetPassword, creds.Email, "/reset-password", token, expiresAt)
+	case !errors.Is(err, database.ErrUserNotFound):
+		log.Printf("Advertencia: no se pudo procesar la solicitud de reseteo de contraseña: %v", err)
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// ResetPassword maneja POST /auth/password/reset con el token del enlace y la contraseña
+// nueva. Revoca la clave de API actual: hay que volver a iniciar sesión. Como el enlace
+// llega al e-mail, también lo da por verificado.
+func (h *UserHandlers) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req tokenRequest
+	if !decodeAuthRequest(w, r, &req) {
+		return
+	}
+	if err := auth.ValidatePassword(req.Password); err != nil {
+		apierror.HTTPError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	creds, ok := h.credentialsForToken(w, r, req.Token, auth.PurposeResetPassword)
+	if !ok {
+		return
+	}
+
+	passwordHash, err := auth.HashPassword(req.Password)
+	if err == nil {
+		err = h.users.ResetPassword(r.Context(), creds.ID, passwordHash)
+	}
+	if err == nil && creds.EmailVerifiedAt == nil {
+		_, err = h.users.MarkEmailVerified(r.Context(), creds.ID)
+	}
+	if err != nil {
+		writeError(w, err, "Error al cambiar la contraseña")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// credentialsForToken valida un token de purpose y comprueba que su huella coincide con el
+// estado actual de la cuenta (el e-mail para la verificación, el hash de la contraseña para
+// el reseteo). Responde 400 si no es válido.
+func (h *UserHandlers) credentialsForToken(w http.ResponseWriter, r *http.Request, token, purpose string) (models.UserCredentials, bool) {
+	claims, err := auth.VerifyToken(token, purpose, h.now())
+	if errors.Is(err, auth.ErrExpiredToken) {
+		apierror.HTTPError(w, "El enlace ha caducado; solicita uno nuevo", http.StatusBadRequest)
+		return models.UserCredentials{}, false
+	}
+	if err != nil {
+		apierror.HTTPError(w, "Enlace inválido", http.StatusBadRequest)
+		return models.UserCredentials{}, false
+	}
+
+	creds, err := h.users.GetCredentials(r.Context(), claims.UserID)
+	if errors.Is(err, database.ErrUserNotFound) {
+		apierror.HTTPError(w, "Enlace inválido", http.StatusBadRequest)
+		return models.UserCredentials{}, false
+	}
+	if err != nil {
+		apierror.Internal(w, "Error al validar el enlace", err)
+		return models.UserCredentials{}, false
+	}
+
+	fingerprinted := creds.Email
+	if purpose == auth.PurposeResetPassword {
+		fingerprinted = creds.PasswordHash
+	}
+	if claims.Fingerprint != auth.Fingerprint(fingerprinted) {
+		apierror.HTTPError(w, "Enlace inválido o ya utilizado", http.StatusBadRequest)
+		return models.UserCredentials{}, false
+	}
+	return creds, true
+}
+
+func (h *UserHandlers) sendVerification(user models.User) {
+	expiresAt := h.now().Add(verifyEmailTTL)
+	token := auth.SignToken(auth.PurposeVerifyEmail, user.ID, auth.Fingerprint(user.Email), expiresAt)
+	h.sendLink(mail.TemplateVerifyEmail, user.Email, "/verify-email", token, expiresAt)
+}
+
+// sendLink renderiza la plantilla con el enlace path?token=... del frontend y la entrega.
+func (h *UserHandlers) sendLink(template, to, path, token string, expiresAt time.Time) {
+	link := mail.BaseURL() + path + "?token=" + url.QueryEscape(token)
+	msg, err := mail.Render(template, to, mail.LinkData{Email: to, Link: link, ExpiresAt: expiresAt.UTC()})
+	if err != nil {
+		log.Printf("ERROR: %v", err)
+		return
+	}
+	h.deliver(msg)
+}
+
+// deliverAsync envía el e-mail en segundo plano, para que la respuesta no dependa del
+// servidor SMTP ni tarde distinto según exista o no la cuenta.
+func deliverAsync(mailer mail.Mailer) func(mail.Message) {
+	return func(msg mail.Message) {
+		go func() {
+			if err := mailer.Send(msg); err != nil {
+				log.Printf("ERROR: %v", err)
+			}
+		}()
+	}
+}
+
+// decodeAuthRequest decodifica el cuerpo JSON o responde 400.
+func decodeAuthRequest(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(v); err != nil {
+		apierror.HTTPError(w, fmt.Sprintf("Cuerpo JSON inválido: %v", err), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// normalizeEmail valida el e-mail (una dirección simple, sin nombre) y lo pasa a minúsculas,
+// o responde 400.
+func normalizeEmail(w http.ResponseWriter, email string) (string, bool) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	addr, err := netmail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		apierror.HTTPError(w, "E-mail inválido", http.StatusBadRequest)
+		return "", false
+	}
+	return email, true
+}
@@ -0,0 +1,247 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/auth"
+	"github.com/jannin2/stock-app/backend/database"
+	"github.com/jannin2/stock-app/backend/mail"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// accountUserDB guarda las cuentas, su 2FA y sus carteras en memoria.
+type accountUserDB struct {
+	database.UserDB
+	accounts   map[string]*models.UserCredentials
+	apiKeys    map[uuid.UUID]string
+	twoFactor  map[uuid.UUID]*models.TwoFactor
+	recovery   map[uuid.UUID]map[string]bool // Hash -> usado
+	portfolios map[uuid.UUID]uuid.UUID       // Cartera -> usuario
+	now        func() time.Time
+}
+
+func newAccountUserDB() *accountUserDB {
+	return &accountUserDB{
+		accounts:   map[string]*models.UserCredentials{},
+		apiKeys:    map[uuid.UUID]string{},
+		twoFactor:  map[uuid.UUID]*models.TwoFactor{},
+		recovery:   map[uuid.UUID]map[string]bool{},
+		portfolios: map[uuid.UUID]uuid.UUID{},
+		now:        time.Now,
+	}
+}
+
+func (db *accountUserDB) GetTwoFactor(_ context.Context, userID uuid.UUID) (models.TwoFactor, error) {
+	tf := models.TwoFactor{}
+	if stored, ok := db.twoFactor[userID]; ok {
+		tf = *stored
+	}
+	for _, used := range db.recovery[userID] {
+		if !used {
+			tf.RecoveryCodesLeft++
+		}
+	}
+	return tf, nil
+}
+
+func (db *accountUserDB) SetPendingTOTPSecret(_ context.Context, userID uuid.UUID, secret string) error {
+	if tf, ok := db.twoFactor[userID]; ok && tf.Enabled() {
+		return database.ErrTwoFactorEnabled
+	}
+	db.twoFactor[userID] = &models.TwoFactor{Secret: secret}
+	return nil
+}
+
+func (db *accountUserDB) EnableTwoFactor(_ context.Context, userID uuid.UUID, recoveryCodeHashes []string) error {
+	tf := db.twoFactor[userID]
+	if tf.EnabledAt == nil {
+		enabledAt := db.now()
+		tf.EnabledAt = &enabledAt
+	}
+	db.recovery[userID] = map[string]bool{}
+	for _, hash := range recoveryCodeHashes {
+		db.recovery[userID][hash] = false
+	}
+	return nil
+}
+
+func (db *accountUserDB) DisableTwoFactor(_ context.Context, userID uuid.UUID) error {
+	delete(db.twoFactor, userID)
+	delete(db.recovery, userID)
+	return nil
+}
+
+func (db *accountUserDB) RecordSecondFactor(_ context.Context, userID uuid.UUID, totpStep int64) error {
+	tf := db.twoFactor[userID]
+	if tf.LastStep >= totpStep {
+		return database.ErrSecondFactorReused
+	}
+	verifiedAt := db.now()
+	tf.LastStep, tf.VerifiedAt = totpStep, &verifiedAt
+	return nil
+}
+
+func (db *accountUserDB) UseRecoveryCode(_ context.Context, userID uuid.UUID, codeHash string) (bool, error) {
+	if used, ok := db.recovery[userID][codeHash]; !ok || used {
+		return false, nil
+	}
+	db.recovery[userID][codeHash] = true
+	verifiedAt := db.now()
+	db.twoFactor[userID].VerifiedAt = &verifiedAt
+	return true, nil
+}
+
+func (db *accountUserDB) DeletePortfolio(_ context.Context, userID, portfolioID uuid.UUID) error {
+	if db.portfolios[portfolioID] != userID {
+		return database.ErrPortfolioNotFound
+	}
+	delete(db.portfolios, portfolioID)
+	return nil
+}
+
+func (db *accountUserDB) CreateUser(_ context.Context, email, passwordHash string) (models.User, error) {
+	if _, ok := db.accounts[email]; ok {
+		return models.User{}, database.ErrEmailTaken
+	}
+	creds := &models.UserCredentials{User: models.User{ID: uuid.New(), Email: email}, PasswordHash: passwordHash}
+	db.accounts[email] = creds
+	return creds.User, nil
+}
+
+func (db *accountUserDB) GetCredentialsByEmail(_ context.Context, email string) (models.UserCredentials, error) {
+	if creds, ok := db.accounts[email]; ok {
+		return *creds, nil
+	}
+	return models.UserCredentials{}, database.ErrUserNotFound
+}
+
+func (db *accountUserDB) GetCredentials(_ context.Context, userID uuid.UUID) (models.UserCredentials, error) {
+	for _, creds := range db.accounts {
+		if creds.ID == userID {
+			return *creds, nil
+		}
+	}
+	return models.UserCredentials{}, database.ErrUserNotFound
+}
+
+func (db *accountUserDB) MarkEmailVerified(_ context.Context, userID uuid.UUID) (time.Time, error) {
+	creds, _ := db.GetCredentials(context.Background(), userID)
+	verifiedAt := time.Date(2025, 1, 6, 12, 0, 0, 0, time.UTC)
+	db.accounts[creds.Email].EmailVerifiedAt = &verifiedAt
+	return verifiedAt, nil
+}
+
+func (db *accountUserDB) SetAPIKeyHash(_ context.Context, userID uuid.UUID, keyHash string) error {
+	db.apiKeys[userID] = keyHash
+	return nil
+}
+
+func (db *accountUserDB) ResetPassword(_ context.Context, userID uuid.UUID, passwordHash string) error {
+	creds, _ := db.GetCredentials(context.Background(), userID)
+	db.accounts[creds.Email].PasswordHash = passwordHash
+	delete(db.apiKeys, userID)
+	return nil
+}
+
+var tokenInLink = regexp.MustCompile(`token=([^"&]+)`)
+
+func TestAccountAuthFlow(t *testing.T) {
+	db := newAccountUserDB()
+	h := NewUserHandlers(db, nil, nil)
+	var sent []mail.Message
+	h.deliver = func(msg mail.Message) { sent = append(sent, msg) }
+	now := time.Now()
+	h.now = func() time.Time { return now }
+
+	post := func(handler http.HandlerFunc, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		handler(rr, httptest.NewRequest(http.MethodPost, "/api/v1/auth", strings.NewReader(body)))
+		return rr
+	}
+	lastToken := func() string {
+		t.Helper()
+		match := tokenInLink.FindStringSubmatch(sent[len(sent)-1].HTML)
+		if match == nil {
+			t.Fatalf("❌ el e-mail no contiene un enlace con token: %s", sent[len(sent)-1].HTML)
+		}
+		token, _ := url.QueryUnescape(match[1])
+		return token
+	}
+
+	if rr := post(h.Register, `{"email":"Ana@Example.com","password":"corta"}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("❌ contraseña débil: estado %d, se esperaba 400", rr.Code)
+	}
+	if rr := post(h.Register, `{"email":"Ana@Example.com","password":"una contraseña larga"}`); rr.Code != http.StatusCreated || len(sent) != 1 || sent[0].To != "ana@example.com" {
+		t.Fatalf("❌ registro: estado %d, e-mails %+v", rr.Code, sent)
+	}
+	if rr := post(h.Register, `{"email":"ana@example.com","password":"otra contraseña larga"}`); rr.Code != http.StatusConflict {
+		t.Errorf("❌ e-mail repetido: estado %d, se esperaba 409", rr.Code)
+	}
+
+	login := `{"email":"ana@example.com","password":"una contraseña larga"}`
+	if rr := post(h.Login, login); rr.Code != http.StatusForbidden {
+		t.Errorf("❌ login sin verificar: estado %d, se esperaba 403", rr.Code)
+	}
+	if rr := post(h.VerifyEmail, `{"token":"`+lastToken()+`"}`); rr.Code != http.StatusOK {
+		t.Fatalf("❌ verificación: estado %d: %s", rr.Code, rr.Body)
+	}
+	userID := db.accounts["ana@example.com"].ID
+	db.apiKeys[userID] = "hash-de-la-clave" // Clave creada antes con POST /me/api-key
+	rr := post(h.Login, login)
+	if rr.Code != http.StatusOK || strings.Contains(rr.Body.String(), `"api_key"`) {
+		t.Fatalf("❌ login: estado %d: %s", rr.Code, rr.Body)
+	}
+	if db.apiKeys[userID] != "hash-de-la-clave" {
+		t.Errorf("❌ el login no debería cambiar la clave de API de otros clientes")
+	}
+	var session loginResponse
+	_ = json.Unmarshal(rr.Body.Bytes(), &session)
+	if claims, err := auth.VerifyAccessToken(session.Token, time.Now()); err != nil || claims.Subject != userID {
+		t.Errorf("❌ token de sesión inesperado: %+v (%v)", claims, err)
+	}
+	if rr := post(h.Login, `{"email":"ana@example.com","password":"incorrecta!!"}`); rr.Code != http.StatusUnauthorized {
+		t.Errorf("❌ contraseña incorrecta: estado %d, se esperaba 401", rr.Code)
+	}
+
+	// El olvido de contraseña responde igual exista o no la cuenta
+	if rr := post(h.ForgotPassword, `{"email":"nadie@example.com"}`); rr.Code != http.StatusAccepted || len(sent) != 1 {
+		t.Errorf("❌ e-mail desconocido: estado %d, %d e-mails", rr.Code, len(sent))
+	}
+	if rr := post(h.ForgotPassword, `{"email":"ana@example.com"}`); rr.Code != http.StatusAccepted || len(sent) != 2 {
+		t.Fatalf("❌ olvido de contraseña: estado %d, %d e-mails", rr.Code, len(sent))
+	}
+	reset := `{"token":"` + lastToken() + `","password":"contraseña nueva y larga"}`
+	if rr := post(h.ResetPassword, reset); rr.Code != http.StatusNoContent {
+		t.Fatalf("❌ reseteo: estado %d: %s", rr.Code, rr.Body)
+	}
+	if _, ok := db.apiKeys[userID]; ok {
+		t.Errorf("❌ el reseteo debería revocar la clave de API")
+	}
+	if rr := post(h.ResetPassword, reset); rr.Code != http.StatusBadRequest {
+		t.Errorf("❌ reutilizar el enlace de reseteo: estado %d, se esperaba 400", rr.Code)
+	}
+	if rr := post(h.Login, `{"email":"ana@example.com","password":"contraseña nueva y larga"}`); rr.Code != http.StatusOK {
+		t.Errorf("❌ login con la contraseña nueva: estado %d", rr.Code)
+	}
+
+	// Un enlace caducado se rechaza
+	post(h.ForgotPassword, `{"email":"ana@example.com"}`)
+	now = now.Add(resetPasswordTTL + time.Minute)
+	if rr := post(h.ResetPassword, `{"token":"`+lastToken()+`","password":"otra contraseña larga"}`); rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), "caducado") {
+		t.Errorf("❌ enlace caducado: estado %d: %s", rr.Code, rr.Body)
+	}
+
+	if !auth.CheckPassword("contraseña nueva y larga", db.accounts["ana@example.com"].PasswordHash) {
+		t.Errorf("❌ la contraseña guardada debería ser la del reseteo")
+	}
+}
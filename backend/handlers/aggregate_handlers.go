@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/jannin2/stock-app/backend/apierror"
+)
+
+// GetStockAggregates maneja GET /stocks/aggregates: recuento, capitalización y medias por
+// sector de los stocks que cumplen la búsqueda, los filtros y la pantalla (?screen=), para
+// los gráficos del listado. Con ?sample=true las cifras se estiman de una muestra, lo que
+// mantiene la respuesta rápida aunque el filtro coincida con decenas de miles de stocks.
+func (h *StockHandlers) GetStockAggregates(w http.ResponseWriter, r *http.Request) {
+	query, err := applyScreen(r.URL.Query())
+	if err != nil {
+		apierror.HTTPError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filters, err := parseStockFilters(query)
+	if err != nil {
+		apierror.HTTPError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	sample := false
+	if raw := query.Get("sample"); raw != "" {
+		if sample, err = strconv.ParseBool(raw); err != nil {
+			apierror.HTTPError(w, fmt.Sprintf("Valor de sample no soportado: %s (use true o false)", raw), http.StatusBadRequest)
+			return
+		}
+	}
+
+	aggregates, err := h.dbClient.GetStockAggregates(r.Context(), query.Get("search"), filters, sample)
+	if err != nil {
+		apierror.Internal(w, "Error al agregar los stocks", err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, aggregates)
+}
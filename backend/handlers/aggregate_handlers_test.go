@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jannin2/stock-app/backend/database"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// aggregateStockDB registra la consulta de GetStockAggregates.
+type aggregateStockDB struct {
+	database.StockDB
+	search  string
+	filters database.StockFilters
+	sample  bool
+}
+
+func (db *aggregateStockDB) GetStockAggregates(ctx context.Context, search string, filters database.StockFilters, sample bool) (models.StockAggregates, error) {
+	db.search, db.filters, db.sample = search, filters, sample
+	return models.StockAggregates{Sectors: []models.SectorAggregate{}}, nil
+}
+
+func TestGetStockAggregates(t *testing.T) {
+	db := &aggregateStockDB{}
+	h := NewStockHandlers(db, nil)
+
+	rr := httptest.NewRecorder()
+	h.GetStockAggregates(rr, httptest.NewRequest(http.MethodGet, "/api/v1/stocks/aggregates?screen=deep_value&search=bank&sample=true", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("❌ respuesta inesperada: %d %s", rr.Code, rr.Body.String())
+	}
+	if !db.sample || db.search != "bank" || db.filters.MaxPERatio == nil || *db.filters.MaxPERatio != 12 {
+		t.Errorf("❌ consulta inesperada: sample %t, search %q, filtros %+v", db.sample, db.search, db.filters)
+	}
+
+	for _, query := range []string{"sample=quizá", "min_esg=200"} {
+		rr := httptest.NewRecorder()
+		h.GetStockAggregates(rr, httptest.NewRequest(http.MethodGet, "/api/v1/stocks/aggregates?"+query, nil))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("❌ %s: código %d, se esperaba 400", query, rr.Code)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/apierror"
+	"github.com/jannin2/stock-app/backend/database"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// alertRequest es el cuerpo de POST /alerts y PUT /alerts/{id}.
+type alertRequest struct {
+	Ticker    string   `json:"ticker"`
+	Metric    string   `json:"metric"`   // Ver models.AlertMetrics
+	Operator  string   `json:"operator"` // above, below o changes_by
+	Threshold *float64 `json:"threshold"`
+}
+
+// decodeAlertRequest lee y valida la regla del cuerpo y responde 400 si no es válida.
+func decodeAlertRequest(w http.ResponseWriter, r *http.Request) (models.Alert, bool) {
+	var req alertRequest
+	if !decodeAuthRequest(w, r, &req) {
+		return models.Alert{}, false
+	}
+	ticker := strings.ToUpper(strings.TrimSpace(req.Ticker))
+	if ticker == "" || len(ticker) > 10 {
+		apierror.HTTPError(w, "Ticker inválido", http.StatusBadRequest)
+		return models.Alert{}, false
+	}
+	if req.Threshold == nil {
+		apierror.HTTPError(w, "El umbral (threshold) es obligatorio", http.StatusBadRequest)
+		return models.Alert{}, false
+	}
+	alert := models.Alert{Ticker: ticker, Metric: req.Metric, Operator: req.Operator, Threshold: *req.Threshold}
+	if err := alert.Validate(); err != nil {
+		apierror.HTTPError(w, "Regla de alerta inválida: "+err.Error(), http.StatusBadRequest)
+		return models.Alert{}, false
+	}
+	return alert, true
+}
+
+// CreateAlert maneja POST /alerts, sujeto a la cuota de alertas. Las reglas se evalúan
+// tras cada ejecución del enricher y las disparadas se notifican por los canales del
+// servidor (e-mail, push, webhooks del usuario).
+func (h *UserHandlers) CreateAlert(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUser(w, r)
+	if !ok {
+		return
+	}
+	alert, ok := decodeAlertRequest(w, r)
+	if !ok {
+		return
+	}
+	if !h.enforceQuota(w, r, userID, models.QuotaAlerts) {
+		return
+	}
+	created, err := h.users.CreateAlert(r.Context(), userID, alert)
+	if err != nil {
+		writeError(w, err, "Error al crear la alerta")
+		return
+	}
+	writeJSON(w, r, http.StatusCreated, created)
+}
+
+// ListAlerts maneja GET /alerts.
+func (h *UserHandlers) ListAlerts(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUser(w, r)
+	if !ok {
+		return
+	}
+	alerts, err := h.users.ListAlerts(r.Context(), userID)
+	if err != nil {
+		writeError(w, err, "Error al obtener las alertas")
+		return
+	}
+	writeJSON(w, r, http.StatusOK, alerts)
+}
+
+// GetAlert maneja GET /alerts/{id}.
+func (h *UserHandlers) GetAlert(w http.ResponseWriter, r *http.Request) {
+	userID, alertID, ok := requireAlertID(w, r)
+	if !ok {
+		return
+	}
+	alert, err := h.users.GetAlert(r.Context(), userID, alertID)
+	if err != nil {
+		writeAlertError(w, err, "Error al obtener la alerta")
+		return
+	}
+	writeJSON(w, r, http.StatusOK, alert)
+}
+
+// UpdateAlert maneja PUT /alerts/{id}: sustituye la regla y la vuelve a armar.
+func (h *UserHandlers) UpdateAlert(w http.ResponseWriter, r *http.Request) {
+	userID, alertID, ok := requireAlertID(w, r)
+	if !ok {
+		return
+	}
+	alert, ok := decodeAlertRequest(w, r)
+	if !ok {
+		return
+	}
+	updated, err := h.users.UpdateAlert(r.Context(), userID, alertID, alert)
+	if err != nil {
+		writeAlertError(w, err, "Error al actualizar la alerta")
+		return
+	}
+	writeJSON(w, r, http.StatusOK, updated)
+}
+
+// DeleteAlert maneja DELETE /alerts/{id}.
+func (h *UserHandlers) DeleteAlert(w http.ResponseWriter, r *http.Request) {
+	userID, alertID, ok := requireAlertID(w, r)
+	if !ok {
+		return
+	}
+	if err := h.users.DeleteAlert(r.Context(), userID, alertID); err != nil {
+		writeAlertError(w, err, "Error al borrar la alerta")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// requireAlertID devuelve el usuario autenticado y el {id} de la ruta, o responde 401/400.
+func requireAlertID(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	userID, ok := requireUser(w, r)
+	if !ok {
+		return uuid.Nil, uuid.Nil, false
+	}
+	alertID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		apierror.HTTPError(w, "ID de alerta inválido", http.StatusBadRequest)
+		return uuid.Nil, uuid.Nil, false
+	}
+	return userID, alertID, true
+}
+
+// writeAlertError responde 404 si la alerta no existe o no es del usuario.
+func writeAlertError(w http.ResponseWriter, err error, message string) {
+	if errors.Is(err, database.ErrAlertNotFound) {
+		apierror.HTTPError(w, "Alerta no encontrada", http.StatusNotFound)
+		return
+	}
+	writeError(w, err, message)
+}
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/auth"
+	"github.com/jannin2/stock-app/backend/config"
+	"github.com/jannin2/stock-app/backend/database"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// alertUserDB guarda en memoria las reglas de alerta.
+type alertUserDB struct {
+	database.UserDB
+	alerts []models.Alert
+}
+
+func (db *alertUserDB) CountUserResources(_ context.Context, userID uuid.UUID, resource string) (int, error) {
+	return len(db.alerts), nil
+}
+
+func (db *alertUserDB) CreateAlert(_ context.Context, userID uuid.UUID, alert models.Alert) (models.Alert, error) {
+	alert.ID, alert.UserID = uuid.New(), userID
+	db.alerts = append(db.alerts, alert)
+	return alert, nil
+}
+
+func (db *alertUserDB) UpdateAlert(_ context.Context, userID, alertID uuid.UUID, alert models.Alert) (models.Alert, error) {
+	for i, a := range db.alerts {
+		if a.ID == alertID && a.UserID == userID {
+			alert.ID, alert.UserID = alertID, userID
+			db.alerts[i] = alert
+			return alert, nil
+		}
+	}
+	return models.Alert{}, database.ErrAlertNotFound
+}
+
+func (db *alertUserDB) DeleteAlert(_ context.Context, userID, alertID uuid.UUID) error {
+	for i, a := range db.alerts {
+		if a.ID == alertID && a.UserID == userID {
+			db.alerts = append(db.alerts[:i], db.alerts[i+1:]...)
+			return nil
+		}
+	}
+	return database.ErrAlertNotFound
+}
+
+func TestAlertHandlers(t *testing.T) {
+	cfg := config.Default()
+	cfg.UserQuotas = map[string]int{models.QuotaAlerts: 1}
+	config.Set(cfg)
+	t.Cleanup(func() { config.Set(config.Default()) })
+
+	db := &alertUserDB{}
+	h := NewUserHandlers(db, nil, nil)
+	userID := uuid.New()
+	serve := func(handler http.HandlerFunc, method, id, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, "/alerts", strings.NewReader(body))
+		ctx := auth.WithUser(req.Context(), userID)
+		if id != "" {
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", id)
+			ctx = context.WithValue(ctx, chi.RouteCtxKey, rctx)
+		}
+		rr := httptest.NewRecorder()
+		handler(rr, req.WithContext(ctx))
+		return rr
+	}
+
+	for _, body := range []string{
+		`{"ticker":"AAPL","metric":"volume","operator":"above","threshold":1}`,
+		`{"ticker":"AAPL","metric":"price","operator":"crosses","threshold":1}`,
+		`{"ticker":"AAPL","metric":"price","operator":"above"}`,
+		`{"ticker":"AAPL","metric":"recommendation_score","operator":"changes_by","threshold":-1}`,
+		`{"ticker":"","metric":"price","operator":"above","threshold":1}`,
+	} {
+		if rr := serve(h.CreateAlert, http.MethodPost, "", body); rr.Code != http.StatusBadRequest {
+			t.Errorf("❌ regla %s: estado %d, se esperaba 400", body, rr.Code)
+		}
+	}
+
+	rr := serve(h.CreateAlert, http.MethodPost, "", `{"ticker":" aapl ","metric":"recommendation_score","operator":"changes_by","threshold":0.5}`)
+	var created models.Alert
+	if err := json.Unmarshal(rr.Body.Bytes(), &created); err != nil || rr.Code != http.StatusCreated {
+		t.Fatalf("❌ POST /alerts: estado %d: %s", rr.Code, rr.Body)
+	}
+	if created.Ticker != "AAPL" || created.Operator != models.AlertChangesBy || created.Threshold != 0.5 {
+		t.Errorf("❌ alerta creada inesperada: %+v", created)
+	}
+	if rr := serve(h.CreateAlert, http.MethodPost, "", `{"ticker":"MSFT","metric":"price","operator":"below","threshold":300}`); rr.Code != http.StatusUnprocessableEntity {
+		t.Errorf("❌ cuota superada: estado %d, se esperaba 422", rr.Code)
+	}
+
+	rr = serve(h.UpdateAlert, http.MethodPut, created.ID.String(), `{"ticker":"AAPL","metric":"price","operator":"above","threshold":250}`)
+	if rr.Code != http.StatusOK || db.alerts[0].Operator != models.AlertAbove || db.alerts[0].Threshold != 250 {
+		t.Errorf("❌ PUT /alerts/{id}: estado %d, reglas %+v", rr.Code, db.alerts)
+	}
+	if rr := serve(h.UpdateAlert, http.MethodPut, uuid.NewString(), `{"ticker":"AAPL","metric":"price","operator":"above","threshold":250}`); rr.Code != http.StatusNotFound {
+		t.Errorf("❌ actualizar una alerta inexistente: estado %d, se esperaba 404", rr.Code)
+	}
+	if rr := serve(h.DeleteAlert, http.MethodDelete, "1", ""); rr.Code != http.StatusBadRequest {
+		t.Errorf("❌ ID inválido: estado %d, se esperaba 400", rr.Code)
+	}
+	if rr := serve(h.DeleteAlert, http.MethodDelete, created.ID.String(), ""); rr.Code != http.StatusNoContent || len(db.alerts) != 0 {
+		t.Errorf("❌ DELETE /alerts/{id}: estado %d, reglas %+v", rr.Code, db.alerts)
+	}
+}
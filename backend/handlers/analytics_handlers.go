@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/analytics"
+	"github.com/jannin2/stock-app/backend/apierror"
+)
+
+const (
+	defaultCorrelationWindow = "90d"
+	maxCorrelationWindowDays = 365
+	maxCorrelationTickers    = 25
+)
+
+var windowPattern = regexp.MustCompile(`^(\d+)d$`)
+
+// correlationResponse es la respuesta de GET /analytics/correlation.
+type correlationResponse struct {
+	analytics.CorrelationMatrix
+	Window string    `json:"window"`
+	Since  time.Time `json:"since"`
+}
+
+// GetCorrelation maneja GET /analytics/correlation?tickers=AAPL,MSFT&window=90d y devuelve
+// la matriz de correlación de los rendimientos diarios dentro de la ventana indicada.
+func (h *StockHandlers) GetCorrelation(w http.ResponseWriter, r *http.Request) {
+	tickers := parseTickerList(r.URL.Query().Get("tickers"))
+	if len(tickers) < 2 || len(tickers) > maxCorrelationTickers {
+		apierror.HTTPError(w, fmt.Sprintf("El parámetro 'tickers' debe contener entre 2 y %d tickers separados por comas", maxCorrelationTickers), http.StatusBadRequest)
+		return
+	}
+
+	window := r.URL.Query().Get("window")
+	if window == "" {
+		window = defaultCorrelationWindow
+	}
+	days, err := parseWindowDays(window)
+	if err != nil {
+		apierror.HTTPError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Se incluye un día más para calcular el rendimiento del primer día de la ventana.
+	since := localDate(r.Context(), time.Now()).AddDate(0, 0, -days-1)
+	history, err := h.dbClient.GetPriceHistory(r.Context(), tickers, since)
+	if err != nil {
+		apierror.Internal(w, "Error al obtener el histórico de precios", err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, correlationResponse{
+		CorrelationMatrix: analytics.Correlation(tickers, history),
+		Window:            window,
+		Since:             since,
+	})
+}
+
+// parseTickerList separa una lista de tickers por comas, en mayúsculas y sin duplicados.
+func parseTickerList(value string) []string {
+	var tickers []string
+	seen := map[string]bool{}
+	for _, ticker := range strings.Split(value, ",") {
+		ticker = strings.ToUpper(strings.TrimSpace(ticker))
+		if ticker != "" && !seen[ticker] {
+			seen[ticker] = true
+			tickers = append(tickers, ticker)
+		}
+	}
+	return tickers
+}
+
+// parseWindowDays interpreta una ventana con el formato "<días>d" (ej. "90d").
+func parseWindowDays(window string) (int, error) {
+	match := windowPattern.FindStringSubmatch(window)
+	if match == nil {
+		return 0, fmt.Errorf("Ventana inválida: %s (use el formato <días>d, ej. 90d)", window)
+	}
+	days, _ := strconv.Atoi(match[1])
+	if days < 2 || days > maxCorrelationWindowDays {
+		return 0, fmt.Errorf("La ventana debe estar entre 2d y %dd", maxCorrelationWindowDays)
+	}
+	return days, nil
+}
+
+const (
+	defaultBrokerageLimit = 20
+	maxBrokerageLimit     = 100
+)
+
+// GetBrokerageStats maneja GET /analytics/brokerages?limit=20: recomendaciones, subidas y
+// bajadas de rating y de precio objetivo y potencial medio de cada casa de análisis.
+func (h *StockHandlers) GetBrokerageStats(w http.ResponseWriter, r *http.Request) {
+	limit := defaultBrokerageLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 || n > maxBrokerageLimit {
+			apierror.HTTPError(w, fmt.Sprintf("El parámetro 'limit' debe ser un entero entre 1 y %d", maxBrokerageLimit), http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	stats, err := h.dbClient.GetBrokerageStats(r.Context(), limit)
+	if err != nil {
+		apierror.Internal(w, "Error al obtener las estadísticas por casa de análisis", err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, stats)
+}
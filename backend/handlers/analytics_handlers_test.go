@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGetCorrelation_ValidatesParams(t *testing.T) {
+	h := &StockHandlers{}
+	tests := []string{
+		"/api/v1/analytics/correlation?tickers=AAPL",
+		"/api/v1/analytics/correlation?tickers=AAPL,aapl",
+		"/api/v1/analytics/correlation?tickers=AAPL,MSFT&window=3m",
+		"/api/v1/analytics/correlation?tickers=AAPL,MSFT&window=1000d",
+	}
+	for _, url := range tests {
+		rr := httptest.NewRecorder()
+		h.GetCorrelation(rr, httptest.NewRequest(http.MethodGet, url, nil))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("❌ %s: estado %d, se esperaba 400", url, rr.Code)
+		}
+	}
+}
+
+func TestRunProjection_ValidatesBody(t *testing.T) {
+	h := &StockHandlers{}
+	tests := []string{
+		`{}`,
+		`{"ticker":"AAPL","portfolio":[{"ticker":"MSFT","weight":1}]}`,
+		`{"portfolio":[{"ticker":"AAPL","weight":0}]}`,
+		`{"portfolio":[{"ticker":"AAPL","weight":1},{"ticker":"aapl","weight":1}]}`,
+		`{"ticker":"AAPL","paths":50}`,
+		`{"ticker":"AAPL","paths":20000,"horizon_days":252}`,
+		`not json`,
+	}
+	for _, body := range tests {
+		rr := httptest.NewRecorder()
+		h.RunProjection(rr, httptest.NewRequest(http.MethodPost, "/api/v1/analytics/projection", strings.NewReader(body)))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("❌ %s: estado %d, se esperaba 400", body, rr.Code)
+		}
+	}
+}
+
+func TestGetBrokerageStats_ValidatesLimit(t *testing.T) {
+	h := &StockHandlers{}
+	for _, limit := range []string{"0", "abc", "101"} {
+		rr := httptest.NewRecorder()
+		h.GetBrokerageStats(rr, httptest.NewRequest(http.MethodGet, "/api/v1/analytics/brokerages?limit="+limit, nil))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("❌ limit=%s: estado %d, se esperaba 400", limit, rr.Code)
+		}
+	}
+}
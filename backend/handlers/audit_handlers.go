@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/apierror"
+)
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+// GetAuditLog maneja GET /admin/audit?user_id=...&limit=100: las últimas entradas del
+// registro de auditoría (suplantaciones de administradores), opcionalmente de un usuario.
+func (h *UserHandlers) GetAuditLog(w http.ResponseWriter, r *http.Request) {
+	limit := defaultAuditLimit
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed < 1 || parsed > maxAuditLimit {
+			apierror.HTTPError(w, fmt.Sprintf("Parámetro 'limit' inválido: debe ser un entero entre 1 y %d", maxAuditLimit), http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	var userID *uuid.UUID
+	if userIDStr := r.URL.Query().Get("user_id"); userIDStr != "" {
+		parsed, err := uuid.Parse(userIDStr)
+		if err != nil {
+			apierror.HTTPError(w, "Parámetro 'user_id' inválido", http.StatusBadRequest)
+			return
+		}
+		userID = &parsed
+	}
+
+	entries, err := h.users.GetAuditLog(r.Context(), userID, limit)
+	if err != nil {
+		apierror.Internal(w, "Error al obtener el registro de auditoría", err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, entries)
+}
@@ -0,0 +1,469 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/apierror"
+	"github.com/jannin2/stock-app/backend/auth"
+	"github.com/jannin2/stock-app/backend/database"
+	"github.com/jannin2/stock-app/backend/jobs"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+const (
+	// importJobKind identifica en la cola los trabajos de importación de stocks.
+	importJobKind = "stock_import"
+
+	// bulkBatchSize es cuántas filas se escriben en cada llamada a UpsertStocks: lo
+	// bastante grande para amortizar la transacción y lo bastante pequeña para no retener
+	// los bloqueos por ticker mucho tiempo.
+	bulkBatchSize = 500
+	// maxBulkLineSize es el tamaño máximo de una línea NDJSON.
+	maxBulkLineSize = 64 << 10
+	// maxBulkErrors limita los errores por fila que se incluyen en el informe; todos se
+	// pueden descargar en el CSV de errores.
+	maxBulkErrors   = 100
+	maxTickerLength = 10
+
+	// bulkBatchFailed es el error que se informa en cada fila de un lote que no se pudo
+	// escribir. El error de la base de datos solo se registra en el log: puede incluir
+	// nombres de tablas, restricciones o valores de otras filas.
+	bulkBatchFailed = "error al escribir el lote; vuelve a importar estas filas"
+)
+
+// bulkRowError es el error de una fila del cuerpo de una importación.
+type bulkRowError struct {
+	Line   int    `json:"line"`
+	Ticker string `json:"ticker,omitempty"`
+	Error  string `json:"error"`
+}
+
+// bulkReport resume el resultado de una importación. Se publica como resultado del trabajo
+// tras cada lote, así que GET /jobs/{id} muestra los contadores mientras avanza.
+type bulkReport struct {
+	Received        int            `json:"received"` // Filas no vacías leídas
+	Upserted        int            `json:"upserted"`
+	Failed          int            `json:"failed"`
+	Batches         int            `json:"batches"`
+	Errors          []bulkRowError `json:"errors"`
+	ErrorsTruncated bool           `json:"errors_truncated,omitempty"`
+	Aborted         string         `json:"aborted,omitempty"` // El cuerpo dejó de poder leerse en este punto
+	DurationMS      int64          `json:"duration_ms"`
+}
+
+// bulkRow es una fila válida pendiente de escribir, con su línea para los errores.
+type bulkRow struct {
+	line  int
+	stock models.Stock
+}
+
+// importJob es el estado de una importación con el enlace para consultarla.
+type importJob struct {
+	jobs.Job
+	StatusURL string `json:"status_url"`
+}
+
+// BulkUpsertStocks maneja POST /stocks/bulk: recibe stocks en NDJSON (un objeto JSON por
+// línea, con los mismos campos que GET /stocks/{id}) o en CSV con ?mapping=<plantilla>, que
+// indica qué campo hay en cada columna. Guarda el cuerpo en disco y encola su importación.
+// Responde 202 con el trabajo; el progreso, el informe y el CSV con los errores por fila se
+// consultan en GET /jobs/{id}.
+func (h *StockHandlers) BulkUpsertStocks(w http.ResponseWriter, r *http.Request) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	mappingName := r.URL.Query().Get("mapping")
+	var mapping *models.ImportMapping
+	switch mediaType {
+	case "application/x-ndjson", "application/jsonl":
+		if mappingName != "" {
+			apierror.HTTPError(w, "El parámetro mapping solo se aplica a las importaciones CSV", http.StatusBadRequest)
+			return
+		}
+	case "text/csv":
+		if mappingName == "" {
+			apierror.HTTPError(w, "Falta el parámetro mapping con la plantilla de columnas del CSV", http.StatusBadRequest)
+			return
+		}
+		// La plantilla se copia en el trabajo: editarla después no cambia una importación en curso
+		m, err := h.dbClient.GetImportMapping(r.Context(), mappingName)
+		if errors.Is(err, database.ErrImportMappingNotFound) {
+			apierror.HTTPError(w, fmt.Sprintf("Plantilla de importación no encontrada: %s", mappingName), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			apierror.Internal(w, "Error al obtener la plantilla de importación", err)
+			return
+		}
+		mapping = &m
+	default:
+		apierror.HTTPError(w, "Content-Type no soportado: use application/x-ndjson o text/csv", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	// El cuerpo se copia entero antes de responder: al terminar la petición deja de poder
+	// leerse, y así el trabajo no depende de la conexión del cliente.
+	spool, err := os.CreateTemp("", "stock-app-import-*")
+	if err != nil {
+		apierror.Internal(w, "Error al guardar la importación", err)
+		return
+	}
+	size, err := io.Copy(spool, r.Body)
+	if closeErr := spool.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(spool.Name())
+		apierror.HTTPError(w, fmt.Sprintf("Error al leer el cuerpo de la importación: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	owner, _ := auth.UserFromContext(r.Context()) // uuid.Nil con una clave de administrador
+	job, err := h.jobs.Submit(importJobKind, owner, h.importStocks(spool.Name(), size, mapping))
+	if err != nil {
+		os.Remove(spool.Name())
+		if errors.Is(err, jobs.ErrQueueFull) {
+			w.Header().Set("Retry-After", "60")
+			apierror.HTTPError(w, "Hay demasiados trabajos en curso, inténtelo más tarde", http.StatusServiceUnavailable)
+			return
+		}
+		apierror.Internal(w, "Error al encolar la importación", err)
+		return
+	}
+
+	resp := importJob{Job: job, StatusURL: jobStatusURL(job.ID)}
+	w.Header().Set("Location", resp.StatusURL)
+	writeJSON(w, r, http.StatusAccepted, resp)
+}
+
+// importStocks devuelve el trabajo que importa el cuerpo guardado en path (de size bytes)
+// con UpsertStocks en lotes de bulkBatchSize, y lo borra al terminar. Sin mapping el cuerpo
+// es NDJSON; con mapping es un CSV cuyas columnas se convierten según la plantilla.
+//
+// Las filas inválidas y los lotes que fallan no detienen la importación: se cuentan en el
+// informe y se escriben en errors.csv con su número de línea. Si el cuerpo no puede seguir
+// leyéndose (una línea demasiado larga, una columna de la plantilla que falta en la
+// cabecera), el trabajo falla con el informe de lo escrito hasta ese punto.
+func (h *StockHandlers) importStocks(path string, size int64, mapping *models.ImportMapping) jobs.Func {
+	return func(ctx context.Context, job *jobs.Handle) error {
+		defer os.Remove(path)
+		in, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("error al abrir la importación: %w", err)
+		}
+		defer in.Close()
+		out, err := job.CreateResult("errors.csv")
+		if err != nil {
+			return err
+		}
+		defer out.Close()
+
+		errorsCSV := newSpreadsheetCSV(out)
+		errorsCSV.Write([]string{"line", "ticker", "error"})
+		start := time.Now()
+		report := bulkReport{Errors: []bulkRowError{}}
+		addError := func(line int, ticker, message string) {
+			report.Failed++
+			errorsCSV.Write([]string{strconv.Itoa(line), ticker, message})
+			if len(report.Errors) == maxBulkErrors {
+				report.ErrorsTruncated = true
+				return
+			}
+			report.Errors = append(report.Errors, bulkRowError{Line: line, Ticker: ticker, Error: message})
+		}
+		publish := func() {
+			report.DurationMS = time.Since(start).Milliseconds()
+			job.SetResult(report)
+		}
+
+		counter := &countingReader{r: in}
+		batch := make([]bulkRow, 0, bulkBatchSize)
+		flush := func() {
+			if len(batch) == 0 {
+				return
+			}
+			stocks := make([]models.Stock, len(batch))
+			for i, row := range batch {
+				stocks[i] = row.stock
+			}
+			report.Batches++
+			if err := h.dbClient.UpsertStocks(ctx, stocks); err != nil {
+				// El lote se escribe en una transacción: si falla, no se escribió ninguna fila
+				log.Printf("ERROR: no se pudo escribir el lote %d de la importación (líneas %d a %d): %v",
+					report.Batches, batch[0].line, batch[len(batch)-1].line, err)
+				for _, row := range batch {
+					addError(row.line, row.stock.Ticker, bulkBatchFailed)
+				}
+			} else {
+				report.Upserted += len(batch)
+			}
+			batch = batch[:0]
+			if size > 0 {
+				job.SetProgress(float64(counter.n) / float64(size))
+			}
+			publish()
+		}
+
+		var rows importReader = newNDJSONReader(counter)
+		if mapping != nil {
+			rows = newCSVImportReader(counter, *mapping)
+		}
+		now := time.Now().UTC()
+		var readErr error
+		for {
+			row, err := rows.next()
+			if err != nil {
+				if err != io.EOF {
+					readErr = err
+				}
+				break
+			}
+			report.Received++
+			var stock models.Stock
+			if err = row.err; err == nil {
+				stock, err = parseStockInput(row.raw, models.SourceBulk, now)
+			}
+			if err != nil {
+				ticker := stock.Ticker
+				if ticker == "" {
+					ticker = row.ticker
+				}
+				addError(row.line, ticker, err.Error())
+				continue
+			}
+			batch = append(batch, bulkRow{line: row.line, stock: stock})
+			if len(batch) == bulkBatchSize {
+				flush()
+				if err := ctx.Err(); err != nil {
+					return fmt.Errorf("importación interrumpida tras la línea %d: %w", row.line, err)
+				}
+			}
+		}
+		flush()
+
+		if readErr != nil {
+			report.Aborted = readErr.Error()
+		}
+		publish()
+		errorsCSV.Flush()
+		if err := errorsCSV.Error(); err != nil {
+			return fmt.Errorf("error al escribir el CSV de errores: %w", err)
+		}
+		if err := out.Close(); err != nil {
+			return err
+		}
+		log.Printf("📥 Importación de stocks: %d filas leídas, %d escritas, %d con error", report.Received, report.Upserted, report.Failed)
+		return readErr
+	}
+}
+
+// importRow es una fila leída del cuerpo de una importación: un objeto JSON con los campos
+// del stock, o el error que impidió convertirla.
+type importRow struct {
+	line   int
+	raw    []byte
+	ticker string // El ticker de la fila si se pudo leer, para los errores
+	err    error
+}
+
+// importReader lee las filas de una importación. next devuelve io.EOF al terminar y
+// cualquier otro error si el cuerpo no puede seguir leyéndose.
+type importReader interface {
+	next() (importRow, error)
+}
+
+// ndjsonReader lee un cuerpo NDJSON, saltando las líneas vacías.
+type ndjsonReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func newNDJSONReader(r io.Reader) *ndjsonReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), maxBulkLineSize)
+	return &ndjsonReader{scanner: scanner}
+}
+
+func (n *ndjsonReader) next() (importRow, error) {
+	for n.scanner.Scan() {
+		n.line++
+		raw := bytes.TrimSpace(n.scanner.Bytes())
+		if len(raw) > 0 {
+			return importRow{line: n.line, raw: raw}, nil
+		}
+	}
+	if err := n.scanner.Err(); errors.Is(err, bufio.ErrTooLong) {
+		return importRow{}, fmt.Errorf("la línea %d supera el máximo de %d bytes", n.line+1, maxBulkLineSize)
+	} else if err != nil {
+		return importRow{}, fmt.Errorf("error al leer la importación tras la línea %d: %w", n.line, err)
+	}
+	return importRow{}, io.EOF
+}
+
+// csvImportReader lee un cuerpo CSV y convierte cada registro en un objeto JSON con los
+// campos que indica la plantilla. Las celdas vacías se omiten, como un campo ausente.
+type csvImportReader struct {
+	reader  *csv.Reader
+	mapping models.ImportMapping
+	fields  map[int]string // Campo de cada columna; se resuelve con la cabecera en la primera lectura
+}
+
+func newCSVImportReader(r io.Reader, mapping models.ImportMapping) *csvImportReader {
+	reader := csv.NewReader(r)
+	reader.Comma = mapping.Comma()
+	reader.FieldsPerRecord = -1 // Las columnas que falten al final de una fila se tratan como vacías
+	reader.TrimLeadingSpace = true
+	return &csvImportReader{reader: reader, mapping: mapping}
+}
+
+func (c *csvImportReader) next() (importRow, error) {
+	if c.fields == nil {
+		var header []string
+		if c.mapping.HasHeader {
+			var err error
+			if header, err = c.reader.Read(); err == io.EOF {
+				return importRow{}, io.EOF
+			} else if err != nil {
+				return importRow{}, fmt.Errorf("error al leer la cabecera del CSV: %w", err)
+			}
+		}
+		fields, err := c.mapping.Resolve(header)
+		if err != nil {
+			return importRow{}, fmt.Errorf("la plantilla %s no encaja con el CSV: %w", c.mapping.Name, err)
+		}
+		c.fields = fields
+	}
+
+	record, err := c.reader.Read()
+	if err == io.EOF {
+		return importRow{}, io.EOF
+	}
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		// El lector sigue en el registro siguiente, así que basta con descartar este
+		return importRow{line: parseErr.StartLine, err: fmt.Errorf("CSV inválido: %v", parseErr.Err)}, nil
+	}
+	if err != nil {
+		return importRow{}, fmt.Errorf("error al leer la importación: %w", err)
+	}
+
+	line, _ := c.reader.FieldPos(0)
+	row := importRow{line: line}
+	for index, field := range c.fields {
+		if field == "ticker" && index < len(record) {
+			row.ticker = strings.ToUpper(strings.TrimSpace(record[index]))
+		}
+	}
+	values := make(map[string]interface{}, len(c.fields))
+	for index, field := range c.fields {
+		if index >= len(record) {
+			continue
+		}
+		value := strings.TrimSpace(record[index])
+		if value == "" {
+			continue
+		}
+		if models.ImportFields[field] != models.ImportFieldNumber {
+			values[field] = value
+			continue
+		}
+		number, err := strconv.ParseFloat(strings.TrimPrefix(value, "$"), 64)
+		if err != nil {
+			row.err = fmt.Errorf("columna %d (%s): número inválido %q", index+1, field, value)
+			return row, nil
+		}
+		values[field] = number
+	}
+	row.raw, row.err = json.Marshal(values)
+	return row, nil
+}
+
+// parseStockInput decodifica y valida un stock escrito por un cliente (una fila de una
+// importación o el cuerpo de POST/PUT /stocks) y atribuye a source los campos que trae.
+// Devuelve el stock aunque falle, para que el error pueda llevar el ticker si se llegó a leer.
+func parseStockInput(raw []byte, source string, now time.Time) (models.Stock, error) {
+	var stock models.Stock
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields() // Un campo mal escrito se perdería en silencio
+	if err := dec.Decode(&stock); err != nil {
+		return stock, fmt.Errorf("JSON inválido: %v", err)
+	}
+	if dec.More() {
+		return stock, errors.New("JSON inválido: más de un objeto")
+	}
+	stock.Ticker = strings.ToUpper(strings.TrimSpace(stock.Ticker))
+	switch {
+	case stock.Ticker == "":
+		return stock, errors.New("falta el ticker")
+	case len(stock.Ticker) > maxTickerLength:
+		return stock, fmt.Errorf("ticker demasiado largo (máximo %d caracteres)", maxTickerLength)
+	case !supportedExchange(stock.Ticker):
+		return stock, errors.New("el sufijo del ticker no corresponde a ninguna bolsa soportada (ej. SAP.DE, VOD.L)")
+	case stock.CurrentPrice < 0:
+		return stock, errors.New("current_price no puede ser negativo")
+	}
+
+	// Estos campos los gestiona el servidor, no quien carga los datos
+	stock.ProviderErrors, stock.EnrichmentTier = "", ""
+	stock.RelativeStrength = models.RelativeStrength{} // Se calcula del histórico de precios
+	stock.SetExchange()
+	stock.Provenance = models.Provenance{}
+	fields := map[string]bool{
+		"current_price":         stock.CurrentPrice > 0,
+		"previous_close":        stock.PreviousClose.Valid,
+		"latest_trading_day":    stock.LatestTradingDay.Valid,
+		"pe_ratio":              stock.PERatio.Valid,
+		"dividend_yield":        stock.DividendYield.Valid,
+		"market_capitalization": stock.MarketCapitalization.Valid,
+		"alpha":                 stock.Alpha.Valid,
+		"sector":                stock.Sector != "",
+		"sentiment":             stock.Sentiment.Valid,
+		"buzz":                  stock.Buzz.Valid,
+		"esg_score":             stock.ESGScore.Valid,
+		"short_interest":        stock.ShortInterest.Valid,
+		"days_to_cover":         stock.DaysToCover.Valid,
+	}
+	for field, present := range fields {
+		if present {
+			stock.Provenance.Set(source, now, field)
+		}
+	}
+	return stock, nil
+}
+
+// countingReader cuenta los bytes leídos, para calcular el progreso de la importación.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// jobStatusURL es la URL de GET /jobs/{id}.
+func jobStatusURL(id uuid.UUID) string {
+	return "/api/v1/jobs/" + id.String()
+}
+
+// supportedExchange indica si el ticker es de EE. UU. o lleva el sufijo de una bolsa
+// soportada.
+func supportedExchange(ticker string) bool {
+	_, err := models.ExchangeForTicker(ticker)
+	return err == nil
+}
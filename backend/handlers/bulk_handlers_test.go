@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/auth"
+	"github.com/jannin2/stock-app/backend/clock"
+	"github.com/jannin2/stock-app/backend/database"
+	"github.com/jannin2/stock-app/backend/jobs"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// bulkStockDB guarda los lotes escritos y falla los que contienen el ticker FAIL.
+type bulkStockDB struct {
+	database.StockDB
+	mu       sync.Mutex
+	batches  [][]models.Stock
+	mappings map[string]models.ImportMapping
+}
+
+func (db *bulkStockDB) GetImportMapping(ctx context.Context, name string) (models.ImportMapping, error) {
+	m, ok := db.mappings[name]
+	if !ok {
+		return models.ImportMapping{}, database.ErrImportMappingNotFound
+	}
+	return m, nil
+}
+
+func (db *bulkStockDB) UpsertStocks(ctx context.Context, stocks []models.Stock) error {
+	for _, s := range stocks {
+		if s.Ticker == "FAIL" {
+			return errors.New("restricción violada")
+		}
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.batches = append(db.batches, stocks)
+	return nil
+}
+
+// importStatus es la parte de GET /jobs/{id} que comprueban los tests de importación.
+type importStatus struct {
+	StatusURL string      `json:"status_url"`
+	ResultURL string      `json:"result_url"`
+	Status    jobs.Status `json:"status"`
+	Error     string      `json:"error"`
+	Result    bulkReport  `json:"result"`
+}
+
+// importRouter monta la importación y los trabajos como en api.SetupRouter. Las
+// peticiones se hacen como administrador salvo que se indique un usuario.
+func importRouter(t *testing.T, db database.StockDB) func(method, path, contentType, body string, userID uuid.UUID) *httptest.ResponseRecorder {
+	queue := jobs.NewQueue(clock.New(), 1, 4)
+	queue.Start(context.Background())
+	t.Cleanup(func() { queue.Stop(context.Background()) })
+	stocks, jobHandlers := NewStockHandlers(db, queue), NewJobHandlers(queue)
+
+	router := chi.NewRouter()
+	router.Post("/api/v1/stocks/bulk", stocks.BulkUpsertStocks)
+	router.Get("/api/v1/jobs/{id}", jobHandlers.GetJob)
+	router.Get("/api/v1/jobs/{id}/result", jobHandlers.DownloadJobResult)
+	return func(method, path, contentType, body string, userID uuid.UUID) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		if userID == uuid.Nil {
+			req = req.WithContext(auth.WithScope(req.Context(), auth.ScopeAdmin))
+		} else {
+			req = req.WithContext(auth.WithUser(auth.WithScope(req.Context(), auth.ScopeUser), userID))
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+}
+
+// waitForImport encola body en path y consulta GET /jobs/{id} hasta que el trabajo termina.
+func waitForImport(t *testing.T, serve func(method, path, contentType, body string, userID uuid.UUID) *httptest.ResponseRecorder, path, contentType, body string) importStatus {
+	t.Helper()
+	rr := serve(http.MethodPost, path, contentType, body, uuid.Nil)
+	var status importStatus
+	if err := json.Unmarshal(rr.Body.Bytes(), &status); err != nil || rr.Code != http.StatusAccepted || rr.Header().Get("Location") != status.StatusURL {
+		t.Fatalf("❌ respuesta inesperada: %d %s", rr.Code, rr.Body)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for status.Status != jobs.StatusSucceeded && status.Status != jobs.StatusFailed && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+		json.Unmarshal(serve(http.MethodGet, status.StatusURL, "", "", uuid.Nil).Body.Bytes(), &status)
+	}
+	return status
+}
+
+func TestBulkUpsertStocks(t *testing.T) {
+	db := &bulkStockDB{}
+	serve := importRouter(t, db)
+
+	var body strings.Builder
+	for i := 0; i < bulkBatchSize+1; i++ {
+		fmt.Fprintf(&body, `{"ticker":"t%d","current_price":%d.5,"pe_ratio":12}`+"\n", i, i)
+	}
+	body.WriteString("\n")                                     // Las líneas vacías se ignoran
+	body.WriteString(`{"ticker":"","current_price":1}` + "\n") // Sin ticker
+	body.WriteString(`{"ticker":"BAD","precio":1}` + "\n")     // Campo desconocido
+	body.WriteString(`{"ticker":"FAIL"}`)                      // Su lote (con T500) falla al escribirse
+
+	status := waitForImport(t, serve, "/api/v1/stocks/bulk", "application/x-ndjson", body.String())
+	report := status.Result
+	if status.Status != jobs.StatusSucceeded {
+		t.Fatalf("❌ la importación no terminó bien: %+v", status)
+	}
+	if report.Received != bulkBatchSize+4 || report.Upserted != bulkBatchSize || report.Failed != 4 || report.Batches != 2 {
+		t.Errorf("❌ informe inesperado: %+v", report)
+	}
+	if len(report.Errors) != 4 || report.Errors[0].Line != bulkBatchSize+3 || report.Errors[1].Ticker != "BAD" ||
+		report.Errors[2].Ticker != "T500" || report.Errors[3].Line != bulkBatchSize+5 || report.Errors[3].Error != bulkBatchFailed {
+		t.Errorf("❌ errores por fila inesperados: %+v", report.Errors)
+	}
+	if len(db.batches) != 1 || len(db.batches[0]) != bulkBatchSize {
+		t.Fatalf("❌ se esperaba un lote de %d filas escrito", bulkBatchSize)
+	}
+	first := db.batches[0][0]
+	if first.Ticker != "T0" || first.Provenance["pe_ratio"].Source != models.SourceBulk {
+		t.Errorf("❌ fila normalizada inesperada: %+v", first)
+	}
+	if _, ok := first.Provenance["dividend_yield"]; ok {
+		t.Error("❌ no debería registrarse procedencia para campos no enviados")
+	}
+
+	// Los errores por fila se descargan en CSV
+	rr := serve(http.MethodGet, status.ResultURL, "", "", uuid.Nil)
+	rows, err := csv.NewReader(rr.Body).ReadAll()
+	if rr.Code != http.StatusOK || !strings.HasPrefix(rr.Header().Get("Content-Type"), "text/csv") || err != nil {
+		t.Fatalf("❌ descarga inesperada: %d %s (%v)", rr.Code, rr.Header().Get("Content-Type"), err)
+	}
+	if len(rows) != 5 || rows[0][0] != "line" || rows[4][1] != "FAIL" {
+		t.Errorf("❌ CSV de errores inesperado: %v", rows)
+	}
+
+	// Un usuario no ve los trabajos de un administrador
+	if rr := serve(http.MethodGet, status.StatusURL, "", "", uuid.New()); rr.Code != http.StatusNotFound {
+		t.Errorf("❌ se esperaba 404 para un usuario, se obtuvo %d", rr.Code)
+	}
+}
+
+func TestBulkUpsertStocks_RejectsBadInput(t *testing.T) {
+	serve := importRouter(t, &bulkStockDB{})
+	if rr := serve(http.MethodPost, "/api/v1/stocks/bulk", "application/json", `[{"ticker":"AAPL"}]`, uuid.Nil); rr.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("❌ con application/json se esperaba 415, se obtuvo %d", rr.Code)
+	}
+
+	body := `{"ticker":"AAPL"}` + "\n" + `{"ticker":"` + strings.Repeat("x", maxBulkLineSize) + `"}` + "\n"
+	status := waitForImport(t, serve, "/api/v1/stocks/bulk", "application/x-ndjson", body)
+	if status.Status != jobs.StatusFailed || status.Result.Upserted != 1 || !strings.Contains(status.Error, "línea 2") ||
+		status.Result.Aborted != status.Error {
+		t.Errorf("❌ una línea demasiado larga debería hacer fallar el trabajo tras escribir lo anterior: %+v", status)
+	}
+}
+
+func TestBulkUpsertStocks_CSVWithMapping(t *testing.T) {
+	db := &bulkStockDB{mappings: map[string]models.ImportMapping{
+		"broker-x": {Name: "broker-x", HasHeader: true, Delimiter: ";",
+			Columns: map[string]string{"Symbol": "ticker", "3": "target_to", "Last": "current_price"}},
+		"missing": {Name: "missing", HasHeader: true, Columns: map[string]string{"Ticker": "ticker"}},
+	}}
+	serve := importRouter(t, db)
+
+	body := "Symbol;Name;Target;Last\n" +
+		"aapl;Apple;$210.5;190\n" +
+		"msft;Microsoft;;410\n" + // Sin objetivo: el campo queda vacío
+		"bad;Bad;n/a;1\n" +
+		"\"x;Broken;1;1\n"
+	status := waitForImport(t, serve, "/api/v1/stocks/bulk?mapping=broker-x", "text/csv", body)
+	if status.Status != jobs.StatusSucceeded || status.Result.Upserted != 2 || status.Result.Failed != 2 {
+		t.Fatalf("❌ importación CSV inesperada: %+v", status)
+	}
+	if e := status.Result.Errors[0]; e.Line != 4 || e.Ticker != "BAD" || !strings.Contains(e.Error, "columna 3") {
+		t.Errorf("❌ error por fila inesperado: %+v", e)
+	}
+	aapl, msft := db.batches[0][0], db.batches[0][1]
+	if aapl.Ticker != "AAPL" || !aapl.TargetTo.Valid || aapl.TargetTo.Float64 != 210.5 || aapl.CurrentPrice != 190 || aapl.Company != "" {
+		t.Errorf("❌ fila convertida inesperada: %+v", aapl)
+	}
+	if msft.TargetTo.Valid || msft.Provenance["current_price"].Source != models.SourceBulk {
+		t.Errorf("❌ fila sin objetivo inesperada: %+v", msft)
+	}
+
+	// Una columna de la plantilla que no está en la cabecera hace fallar el trabajo
+	status = waitForImport(t, serve, "/api/v1/stocks/bulk?mapping=missing", "text/csv", body)
+	if status.Status != jobs.StatusFailed || !strings.Contains(status.Error, "Ticker") {
+		t.Errorf("❌ se esperaba un fallo por la cabecera: %+v", status)
+	}
+
+	for path, want := range map[string]int{
+		"/api/v1/stocks/bulk":                 http.StatusBadRequest,
+		"/api/v1/stocks/bulk?mapping=unknown": http.StatusBadRequest,
+	} {
+		if rr := serve(http.MethodPost, path, "text/csv", body, uuid.Nil); rr.Code != want {
+			t.Errorf("❌ %s: se esperaba %d, se obtuvo %d", path, want, rr.Code)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/apierror"
+	"github.com/jannin2/stock-app/backend/config"
+	"github.com/jannin2/stock-app/backend/database"
+)
+
+// clientConfig es la respuesta de GET /config: la parte no secreta de la configuración que
+// necesita el frontend, para que no tenga que repetirla.
+type clientConfig struct {
+	FeatureFlags    map[string]bool    `json:"feature_flags"`     // Solo los activados
+	DefaultPageSize int                `json:"default_page_size"` // limit de GET /stocks si no se indica
+	SortFields      []string           `json:"sort_fields"`       // Valores admitidos en sortBy
+	MaxSortKeys     int                `json:"max_sort_keys"`     // Campos como máximo en un sortBy compuesto
+	ScoreBands      []config.ScoreBand `json:"score_bands"`       // De la más alta a la más baja
+	Stream          clientStreamConfig `json:"stream"`
+	Markets         []exchangeStatus   `json:"markets"` // Como GET /market/exchanges
+}
+
+// clientStreamConfig son los límites de GET /stream que el cliente debe respetar.
+type clientStreamConfig struct {
+	MaxSubscriptions int `json:"max_subscriptions"` // 0 = sin límite
+	MessagesPerMin   int `json:"messages_per_min"`  // 0 = sin límite
+}
+
+// GetClientConfig maneja GET /config: feature flags, tamaño de página, campos de orden,
+// bandas de score y estado de los mercados. Es pública; no incluye nada que no pueda ver
+// cualquier cliente (ni claves, ni límites internos, ni flags desactivados).
+func GetClientConfig(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, http.StatusOK, newClientConfig(config.Current(), time.Now()))
+}
+
+func newClientConfig(cfg config.Config, now time.Time) clientConfig {
+	flags := map[string]bool{}
+	for name, enabled := range cfg.FeatureFlags {
+		if enabled {
+			flags[name] = true
+		}
+	}
+	bands := cfg.ScoreBands
+	if bands == nil {
+		bands = []config.ScoreBand{}
+	}
+	return clientConfig{
+		FeatureFlags:    flags,
+		DefaultPageSize: cfg.DefaultPageSize,
+		SortFields:      database.SortFields(),
+		MaxSortKeys:     database.MaxSortKeys,
+		ScoreBands:      bands,
+		Stream: clientStreamConfig{
+			MaxSubscriptions: cfg.StreamMaxSubscriptions,
+			MessagesPerMin:   cfg.StreamMessagesPerMin,
+		},
+		Markets: exchangeStatuses(now),
+	}
+}
+
+// GetConfig devuelve la configuración recargable vigente.
+func GetConfig(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, http.StatusOK, config.Current())
+}
+
+// ReloadConfig recarga la configuración desde .env y las variables de entorno, igual que SIGHUP.
+// Si la nueva configuración es inválida responde 422 y conserva la anterior.
+func ReloadConfig(w http.ResponseWriter, r *http.Request) {
+	cfg, err := config.Reload()
+	if err != nil {
+		apierror.HTTPError(w, "Configuración inválida, se conserva la anterior: "+err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, cfg)
+}
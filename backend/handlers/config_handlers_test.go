@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/config"
+)
+
+func TestGetClientConfig(t *testing.T) {
+	t.Cleanup(func() { config.Set(config.Default()) })
+	cfg := config.Default()
+	cfg.FeatureFlags = map[string]bool{"heatmap": true, "chaos": false}
+	cfg.DefaultPageSize = 25
+	config.Set(cfg)
+
+	rr := httptest.NewRecorder()
+	GetClientConfig(rr, httptest.NewRequest(http.MethodGet, "/api/v1/config", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("❌ estado %d, se esperaba 200", rr.Code)
+	}
+	body := rr.Body.String()
+	for _, secret := range []string{"user_agent", "provider_chains", "chaos", "db_pool"} {
+		if strings.Contains(body, secret) {
+			t.Errorf("❌ la respuesta no debe incluir %s: %s", secret, body)
+		}
+	}
+
+	var got clientConfig
+	if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+		t.Fatalf("❌ JSON inválido: %v", err)
+	}
+	if len(got.FeatureFlags) != 1 || !got.FeatureFlags["heatmap"] {
+		t.Errorf("❌ feature flags inesperados: %v", got.FeatureFlags)
+	}
+	if got.DefaultPageSize != 25 || got.MaxSortKeys < 1 || len(got.ScoreBands) != 2 || len(got.Markets) == 0 {
+		t.Errorf("❌ configuración inesperada: %+v", got)
+	}
+	sortable := strings.Join(got.SortFields, ",")
+	if !strings.Contains(sortable, "recommendation_score") || !strings.Contains(sortable, "target_upside") {
+		t.Errorf("❌ faltan campos de orden: %s", sortable)
+	}
+}
+
+func TestNewClientConfig_Markets(t *testing.T) {
+	// Lunes 2025-01-06 a las 15:00 UTC: Nueva York ya ha abierto
+	got := newClientConfig(config.Default(), time.Date(2025, 1, 6, 15, 0, 0, 0, time.UTC))
+	for _, market := range got.Markets {
+		if market.Code == "US" && !market.IsOpen {
+			t.Errorf("❌ US debería estar abierta: %+v", market)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jannin2/stock-app/backend/apierror"
+)
+
+// GetCorporateActions maneja GET /stocks/{ticker}/corporate-actions: los splits, fusiones y
+// cambios de ticker del ticker, del más reciente al más antiguo. Tras un cambio de ticker
+// se consultan con el nuevo e incluyen el propio cambio. Solo hay datos si el paso
+// "corporate_actions" está en ENRICHMENT_STEPS; sin eventos se devuelve una lista vacía.
+func (h *StockHandlers) GetCorporateActions(w http.ResponseWriter, r *http.Request) {
+	ticker := strings.ToUpper(chi.URLParam(r, "ticker"))
+	actions, err := h.dbClient.GetCorporateActions(r.Context(), ticker)
+	if err != nil {
+		apierror.Internal(w, "Error al obtener los eventos corporativos", err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, actions)
+}
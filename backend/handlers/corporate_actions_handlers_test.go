@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jannin2/stock-app/backend/database"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// corporateActionsStockDB devuelve el cambio de FB a META para ambos tickers.
+type corporateActionsStockDB struct {
+	database.StockDB
+	asked string
+}
+
+func (db *corporateActionsStockDB) GetCorporateActions(ctx context.Context, ticker string) ([]models.CorporateAction, error) {
+	db.asked = ticker
+	return []models.CorporateAction{{Ticker: "FB", Type: models.CorporateActionSymbolChange, NewTicker: "META",
+		EffectiveDate: time.Date(2022, 6, 9, 0, 0, 0, 0, time.UTC), Source: "finnhub"}}, nil
+}
+
+func TestGetCorporateActions(t *testing.T) {
+	db := &corporateActionsStockDB{}
+	h := NewStockHandlers(db, nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("ticker", "meta")
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/stocks/meta/corporate-actions", nil)
+	rr := httptest.NewRecorder()
+	h.GetCorporateActions(rr, req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx)))
+
+	var actions []models.CorporateAction
+	if rr.Code != http.StatusOK || json.Unmarshal(rr.Body.Bytes(), &actions) != nil {
+		t.Fatalf("❌ respuesta inesperada: %d %s", rr.Code, rr.Body.String())
+	}
+	if db.asked != "META" {
+		t.Errorf("❌ se consultó %q, se esperaba el ticker en mayúsculas META", db.asked)
+	}
+	if len(actions) != 1 || actions[0].NewTicker != "META" || actions[0].SplitFrom.Valid {
+		t.Errorf("❌ eventos inesperados: %+v", actions)
+	}
+}
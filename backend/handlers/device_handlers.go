@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/apierror"
+	"github.com/jannin2/stock-app/backend/database"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+const (
+	maxDeviceTokenLength = 4096 // Los tokens de FCM rondan los 160 caracteres y los de APNs 64
+	maxDeviceNameLength  = 100
+)
+
+// registerDeviceRequest es el cuerpo de POST /me/devices.
+type registerDeviceRequest struct {
+	Platform string `json:"platform"` // "android" (FCM) o "ios" (APNs)
+	Token    string `json:"token"`
+	Name     string `json:"name"`
+}
+
+// RegisterDevice maneja POST /me/devices: la app registra su token de push al iniciar
+// sesión. Registrar un token ya conocido lo reactiva y, si era de otro usuario, lo pasa a
+// este.
+func (h *UserHandlers) RegisterDevice(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUser(w, r)
+	if !ok {
+		return
+	}
+	var req registerDeviceRequest
+	if !decodeAuthRequest(w, r, &req) {
+		return
+	}
+	req.Token, req.Name = strings.TrimSpace(req.Token), strings.TrimSpace(req.Name)
+	if req.Platform != models.PushPlatformAndroid && req.Platform != models.PushPlatformIOS {
+		apierror.HTTPError(w, "Plataforma inválida (android o ios)", http.StatusBadRequest)
+		return
+	}
+	if req.Token == "" || len(req.Token) > maxDeviceTokenLength {
+		apierror.HTTPError(w, "Token de dispositivo inválido", http.StatusBadRequest)
+		return
+	}
+	if len(req.Name) > maxDeviceNameLength {
+		apierror.HTTPError(w, "El nombre del dispositivo es demasiado largo", http.StatusBadRequest)
+		return
+	}
+	device, err := h.users.RegisterDevice(r.Context(), userID, req.Platform, req.Token, req.Name)
+	if err != nil {
+		writeError(w, err, "Error al registrar el dispositivo")
+		return
+	}
+	writeJSON(w, r, http.StatusCreated, device)
+}
+
+// ListDevices maneja GET /me/devices: los dispositivos del usuario con el resultado de
+// la última entrega a cada uno. Los desactivados (token rechazado por FCM/APNs) se
+// incluyen con disabled_at para que la app sepa que debe registrarse de nuevo.
+func (h *UserHandlers) ListDevices(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUser(w, r)
+	if !ok {
+		return
+	}
+	devices, err := h.users.ListDevices(r.Context(), userID)
+	if err != nil {
+		writeError(w, err, "Error al obtener los dispositivos")
+		return
+	}
+	writeJSON(w, r, http.StatusOK, devices)
+}
+
+// DeleteDevice maneja DELETE /me/devices/{id}, p. ej. al cerrar sesión en el móvil.
+func (h *UserHandlers) DeleteDevice(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUser(w, r)
+	if !ok {
+		return
+	}
+	deviceID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		apierror.HTTPError(w, "ID de dispositivo inválido", http.StatusBadRequest)
+		return
+	}
+	err = h.users.DeleteDevice(r.Context(), userID, deviceID)
+	if errors.Is(err, database.ErrDeviceNotFound) {
+		apierror.HTTPError(w, "Dispositivo no encontrado", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		writeError(w, err, "Error al borrar el dispositivo")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
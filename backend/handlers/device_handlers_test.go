@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/auth"
+	"github.com/jannin2/stock-app/backend/database"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// deviceUserDB guarda en memoria los dispositivos registrados.
+type deviceUserDB struct {
+	database.UserDB
+	devices []models.Device
+}
+
+func (db *deviceUserDB) RegisterDevice(_ context.Context, userID uuid.UUID, platform, token, name string) (models.Device, error) {
+	d := models.Device{ID: uuid.New(), UserID: userID, Platform: platform, Token: token, Name: name}
+	db.devices = append(db.devices, d)
+	return d, nil
+}
+
+func (db *deviceUserDB) DeleteDevice(_ context.Context, userID, deviceID uuid.UUID) error {
+	for i, d := range db.devices {
+		if d.ID == deviceID && d.UserID == userID {
+			db.devices = append(db.devices[:i], db.devices[i+1:]...)
+			return nil
+		}
+	}
+	return database.ErrDeviceNotFound
+}
+
+func TestRegisterAndDeleteDevice(t *testing.T) {
+	db := &deviceUserDB{}
+	h := NewUserHandlers(db, nil, nil)
+	userID := uuid.New()
+	serve := func(handler http.HandlerFunc, method, id, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, "/me/devices", strings.NewReader(body))
+		ctx := auth.WithUser(req.Context(), userID)
+		if id != "" {
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", id)
+			ctx = context.WithValue(ctx, chi.RouteCtxKey, rctx)
+		}
+		rr := httptest.NewRecorder()
+		handler(rr, req.WithContext(ctx))
+		return rr
+	}
+
+	for _, body := range []string{
+		`{"platform":"windows","token":"abc"}`,
+		`{"platform":"ios","token":"  "}`,
+		`{"platform":"android","token":"abc","name":"` + strings.Repeat("x", maxDeviceNameLength+1) + `"}`,
+	} {
+		if rr := serve(h.RegisterDevice, http.MethodPost, "", body); rr.Code != http.StatusBadRequest {
+			t.Errorf("❌ %.60s: estado %d, se esperaba 400", body, rr.Code)
+		}
+	}
+
+	rr := serve(h.RegisterDevice, http.MethodPost, "", `{"platform":"ios","token":" tok-1 ","name":"iPhone"}`)
+	if rr.Code != http.StatusCreated || len(db.devices) != 1 || db.devices[0].Token != "tok-1" {
+		t.Fatalf("❌ estado %d: %s (%+v)", rr.Code, rr.Body, db.devices)
+	}
+	if strings.Contains(rr.Body.String(), "tok-1") {
+		t.Errorf("❌ la respuesta no debería incluir el token: %s", rr.Body)
+	}
+
+	if rr := serve(h.DeleteDevice, http.MethodDelete, uuid.NewString(), ""); rr.Code != http.StatusNotFound {
+		t.Errorf("❌ borrar un dispositivo inexistente: estado %d, se esperaba 404", rr.Code)
+	}
+	if rr := serve(h.DeleteDevice, http.MethodDelete, db.devices[0].ID.String(), ""); rr.Code != http.StatusNoContent || len(db.devices) != 0 {
+		t.Errorf("❌ estado %d al borrar el dispositivo: %+v", rr.Code, db.devices)
+	}
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/apierror"
+	"github.com/jannin2/stock-app/backend/database"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+const (
+	defaultEnrichmentRunsLimit = 20
+	maxEnrichmentRunsLimit     = 200
+)
+
+// ListEnrichmentRuns maneja GET /admin/enrich/runs?limit=20: el historial de ejecuciones
+// del enriquecedor, de la más reciente a la más antigua, con su duración, las acciones
+// procesadas y los errores de cada proveedor.
+func (h *StockHandlers) ListEnrichmentRuns(w http.ResponseWriter, r *http.Request) {
+	limit := defaultEnrichmentRunsLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 || n > maxEnrichmentRunsLimit {
+			apierror.HTTPError(w, fmt.Sprintf("El parámetro 'limit' debe ser un entero entre 1 y %d", maxEnrichmentRunsLimit), http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	runs, err := h.dbClient.ListEnrichmentRuns(r.Context(), limit)
+	if err != nil {
+		apierror.Internal(w, "Error al obtener el historial de ejecuciones", err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, runs)
+}
+
+// GetEnrichmentRun maneja GET /admin/enrich/runs/{id}: una ejecución del historial.
+func (h *StockHandlers) GetEnrichmentRun(w http.ResponseWriter, r *http.Request) {
+	runID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		apierror.HTTPError(w, "ID de ejecución inválido", http.StatusBadRequest)
+		return
+	}
+	run, err := h.dbClient.GetEnrichmentRun(r.Context(), runID)
+	if errors.Is(err, database.ErrEnrichmentRunNotFound) {
+		apierror.HTTPError(w, "Ejecución no encontrada", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		apierror.Internal(w, "Error al obtener la ejecución", err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, run)
+}
+
+// enrichmentRunDetailsResponse es la respuesta de GET /admin/enrich/runs/{id}/details.
+type enrichmentRunDetailsResponse struct {
+	RunID      uuid.UUID                 `json:"run_id"`
+	Tickers    int                       `json:"tickers"`    // Tickers enriquecidos en la ejecución
+	Incomplete int                       `json:"incomplete"` // De ellos, los que tienen métricas nulas o errores
+	Results    []models.EnrichmentResult `json:"results"`
+}
+
+// GetEnrichmentRunDetails maneja GET /admin/enrich/runs/{id}/details: lista por ticker los
+// campos que actualizó la ejecución y el proveedor de cada uno, las métricas que quedaron
+// nulas y los errores de los proveedores. Con ?incomplete=true solo incluye los tickers
+// con métricas nulas o errores.
+func (h *StockHandlers) GetEnrichmentRunDetails(w http.ResponseWriter, r *http.Request) {
+	runID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		apierror.HTTPError(w, "ID de ejecución inválido", http.StatusBadRequest)
+		return
+	}
+	incompleteOnly := false
+	if raw := r.URL.Query().Get("incomplete"); raw != "" {
+		if incompleteOnly, err = strconv.ParseBool(raw); err != nil {
+			apierror.HTTPError(w, "El parámetro 'incomplete' debe ser true o false", http.StatusBadRequest)
+			return
+		}
+	}
+
+	results, err := h.dbClient.GetEnrichmentResults(r.Context(), runID)
+	if err != nil {
+		apierror.Internal(w, "Error al obtener los resultados de la ejecución", err)
+		return
+	}
+	if len(results) == 0 {
+		apierror.HTTPError(w, "No hay resultados para esa ejecución", http.StatusNotFound)
+		return
+	}
+
+	resp := enrichmentRunDetailsResponse{RunID: runID, Tickers: len(results), Results: []models.EnrichmentResult{}}
+	for _, res := range results {
+		incomplete := len(res.NullFields) > 0 || res.Errors != ""
+		if incomplete {
+			resp.Incomplete++
+		}
+		if incomplete || !incompleteOnly {
+			resp.Results = append(resp.Results, res)
+		}
+	}
+	writeJSON(w, r, http.StatusOK, resp)
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/database"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// enrichmentResultsDB devuelve los resultados guardados de una sola ejecución.
+type enrichmentResultsDB struct {
+	database.StockDB
+	runID   uuid.UUID
+	results []models.EnrichmentResult
+}
+
+func (db *enrichmentResultsDB) GetEnrichmentResults(ctx context.Context, runID uuid.UUID) ([]models.EnrichmentResult, error) {
+	if runID != db.runID {
+		return []models.EnrichmentResult{}, nil
+	}
+	return db.results, nil
+}
+
+func TestGetEnrichmentRunDetails(t *testing.T) {
+	runID := uuid.New()
+	db := &enrichmentResultsDB{runID: runID, results: []models.EnrichmentResult{
+		{RunID: runID, Ticker: "AAPL", Fields: models.Provenance{"current_price": {Source: "finnhub"}}, NullFields: []string{}},
+		{RunID: runID, Ticker: "KO", NullFields: []string{"alpha"}, Errors: "alphavantage alpha: límite superado"},
+	}}
+	router := chi.NewRouter()
+	router.Get("/runs/{id}/details", (&StockHandlers{dbClient: db}).GetEnrichmentRunDetails)
+	get := func(path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		return rr
+	}
+
+	rr := get("/runs/" + runID.String() + "/details")
+	var resp enrichmentRunDetailsResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil || rr.Code != http.StatusOK {
+		t.Fatalf("❌ respuesta inesperada: %d %s", rr.Code, rr.Body)
+	}
+	if resp.Tickers != 2 || resp.Incomplete != 1 || len(resp.Results) != 2 || resp.Results[0].Fields["current_price"].Source != "finnhub" {
+		t.Errorf("❌ detalle inesperado: %s", rr.Body)
+	}
+
+	rr = get("/runs/" + runID.String() + "/details?incomplete=true")
+	resp = enrichmentRunDetailsResponse{}
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	if len(resp.Results) != 1 || resp.Results[0].Ticker != "KO" || resp.Tickers != 2 {
+		t.Errorf("❌ con incomplete=true solo debería quedar KO: %s", rr.Body)
+	}
+
+	for path, want := range map[string]int{
+		"/runs/no-es-un-uuid/details":                            http.StatusBadRequest,
+		"/runs/" + runID.String() + "/details?incomplete=quizás": http.StatusBadRequest,
+		"/runs/" + uuid.NewString() + "/details":                 http.StatusNotFound,
+	} {
+		if rr := get(path); rr.Code != want {
+			t.Errorf("❌ GET %s = %d, se esperaba %d", path, rr.Code, want)
+		}
+	}
+}
+
+// enrichmentRunsDB guarda el historial de ejecuciones en memoria, de la más reciente a la más antigua.
+type enrichmentRunsDB struct {
+	database.StockDB
+	runs      []models.EnrichmentRun
+	lastLimit int
+}
+
+func (db *enrichmentRunsDB) ListEnrichmentRuns(ctx context.Context, limit int) ([]models.EnrichmentRun, error) {
+	db.lastLimit = limit
+	return db.runs[:min(limit, len(db.runs))], nil
+}
+
+func (db *enrichmentRunsDB) GetEnrichmentRun(ctx context.Context, id uuid.UUID) (models.EnrichmentRun, error) {
+	for _, run := range db.runs {
+		if run.ID == id {
+			return run, nil
+		}
+	}
+	return models.EnrichmentRun{}, database.ErrEnrichmentRunNotFound
+}
+
+func TestEnrichmentRuns(t *testing.T) {
+	failed := models.EnrichmentRun{ID: uuid.New(), Status: models.EnrichmentRunFailed, Error: "feed caído", ProviderErrors: map[string]int{"karenai": 1}}
+	db := &enrichmentRunsDB{runs: []models.EnrichmentRun{
+		failed,
+		{ID: uuid.New(), Status: models.EnrichmentRunSucceeded, StocksProcessed: 40, ProviderErrors: map[string]int{}},
+	}}
+	h := &StockHandlers{dbClient: db}
+	router := chi.NewRouter()
+	router.Get("/runs", h.ListEnrichmentRuns)
+	router.Get("/runs/{id}", h.GetEnrichmentRun)
+	get := func(path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		return rr
+	}
+
+	rr := get("/runs")
+	var runs []models.EnrichmentRun
+	if err := json.Unmarshal(rr.Body.Bytes(), &runs); err != nil || rr.Code != http.StatusOK {
+		t.Fatalf("❌ respuesta inesperada: %d %s", rr.Code, rr.Body)
+	}
+	if len(runs) != 2 || db.lastLimit != defaultEnrichmentRunsLimit || runs[0].Status != models.EnrichmentRunFailed {
+		t.Errorf("❌ historial inesperado (limit %d): %s", db.lastLimit, rr.Body)
+	}
+
+	rr = get("/runs/" + failed.ID.String())
+	var run models.EnrichmentRun
+	if err := json.Unmarshal(rr.Body.Bytes(), &run); err != nil || rr.Code != http.StatusOK {
+		t.Fatalf("❌ respuesta inesperada: %d %s", rr.Code, rr.Body)
+	}
+	if run.Error != "feed caído" || run.ProviderErrors["karenai"] != 1 {
+		t.Errorf("❌ ejecución inesperada: %s", rr.Body)
+	}
+
+	for path, want := range map[string]int{
+		"/runs?limit=1":             http.StatusOK,
+		"/runs?limit=0":             http.StatusBadRequest,
+		"/runs?limit=muchas":        http.StatusBadRequest,
+		"/runs/no-es-un-uuid":       http.StatusBadRequest,
+		"/runs/" + uuid.NewString(): http.StatusNotFound,
+	} {
+		if rr := get(path); rr.Code != want {
+			t.Errorf("❌ GET %s = %d, se esperaba %d", path, rr.Code, want)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/jannin2/stock-app/backend/apierror"
+	"github.com/jannin2/stock-app/backend/database"
+)
+
+// errorMappings traduce los errores conocidos de la base de datos a su respuesta. Los
+// manejadores que necesitan un mensaje más concreto comprueban el error antes de llamar a
+// writeError.
+var errorMappings = []apierror.Mapping{
+	{Err: database.ErrStockNotFound, Status: http.StatusNotFound, Message: "Stock no encontrado"},
+	{Err: database.ErrUserNotFound, Status: http.StatusNotFound, Message: "Usuario no encontrado"},
+	{Err: database.ErrWatchlistNotFound, Status: http.StatusNotFound, Message: "Watchlist no encontrada"},
+	{Err: database.ErrWatchlistMemberNotFound, Status: http.StatusNotFound, Message: "El usuario no es miembro de la watchlist"},
+	{Err: database.ErrWatchlistForbidden, Status: http.StatusForbidden, Message: "Su rol en la watchlist no permite este cambio"},
+	{Err: database.ErrAlertNotFound, Status: http.StatusNotFound, Message: "Alerta no encontrada"},
+	{Err: database.ErrDeviceNotFound, Status: http.StatusNotFound, Message: "Dispositivo no encontrado"},
+	{Err: database.ErrWebhookNotFound, Status: http.StatusNotFound, Message: "Webhook no encontrado"},
+	{Err: database.ErrPortfolioNotFound, Status: http.StatusNotFound, Message: "Cartera no encontrada"},
+	{Err: database.ErrImportMappingNotFound, Status: http.StatusNotFound, Message: "Plantilla de importación no encontrada"},
+	{Err: database.ErrEnrichmentRunNotFound, Status: http.StatusNotFound, Message: "Ejecución del enriquecimiento no encontrada"},
+	{Err: database.ErrTickerExists, Status: http.StatusConflict, Message: "Ya existe un stock con ese ticker"},
+	{Err: database.ErrEmailTaken, Status: http.StatusConflict, Message: "Ya existe una cuenta con ese e-mail"},
+}
+
+// writeError responde con el error conocido que corresponde a err o, si no lo es, con un
+// 500 con message. El texto de err solo va al log.
+func writeError(w http.ResponseWriter, err error, message string) {
+	apierror.WriteError(w, err, errorMappings, message)
+}
@@ -0,0 +1,414 @@
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/apierror"
+	"github.com/jannin2/stock-app/backend/database"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+const (
+	defaultExportPageSize = 1000
+	maxExportPageSize     = 10000
+
+	// exportSpoolTTL es cuánto se conserva en disco una exportación completa para
+	// servir peticiones Range que reanudan una descarga interrumpida.
+	exportSpoolTTL = time.Hour
+)
+
+// exportHeader son las columnas del CSV exportado. Los campos exclusivos de administrador
+// no se exportan.
+var exportHeader = []string{
+	"id", "ticker", "company", "brokerage", "action", "rating_from", "rating_to",
+	"target_from", "target_to", "current_price", "pe_ratio", "dividend_yield",
+	"market_capitalization", "alpha", "latest_trading_day", "recommendation_score",
+	"sector", "exchange", "currency", "previous_close", "sentiment", "buzz", "esg_score",
+	"short_interest", "days_to_cover", "relative_strength_30d", "relative_strength_90d",
+	"created_at", "updated_at",
+}
+
+// exportRecord convierte un stock en una fila del CSV; los valores nulos quedan vacíos.
+func exportRecord(s models.Stock) []string {
+	latestTradingDay := ""
+	if s.LatestTradingDay.Valid {
+		latestTradingDay = s.LatestTradingDay.Time.Format("2006-01-02")
+	}
+	return []string{
+		s.ID.String(), s.Ticker, s.Company, s.Brokerage, s.Action, s.RatingFrom, s.RatingTo,
+		csvFloat(s.TargetFrom), csvFloat(s.TargetTo), strconv.FormatFloat(s.CurrentPrice, 'f', -1, 64),
+		csvFloat(s.PERatio), csvFloat(s.DividendYield), csvFloat(s.MarketCapitalization), csvFloat(s.Alpha),
+		latestTradingDay, csvFloat(s.RecommendationScore), s.Sector, s.Exchange, s.Currency, csvFloat(s.PreviousClose), csvFloat(s.Sentiment), csvFloat(s.Buzz), csvFloat(s.ESGScore),
+		csvFloat(s.ShortInterest), csvFloat(s.DaysToCover),
+		csvFloat(s.RelativeStrength.Days30), csvFloat(s.RelativeStrength.Days90),
+		s.CreatedAt.UTC().Format(time.RFC3339), s.UpdatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+func csvFloat(f models.NullFloat64) string {
+	if !f.Valid {
+		return ""
+	}
+	return strconv.FormatFloat(f.Float64, 'f', -1, 64)
+}
+
+// Formatos de GET /stocks/export.
+const (
+	exportFormatCSV  = "csv"
+	exportFormatXLSX = "xlsx"
+)
+
+// exportNumericColumns son las columnas de exportHeader que contienen números.
+var exportNumericColumns = map[string]bool{
+	"target_from": true, "target_to": true, "current_price": true, "pe_ratio": true,
+	"dividend_yield": true, "market_capitalization": true, "alpha": true,
+	"recommendation_score": true, "previous_close": true, "sentiment": true, "buzz": true,
+	"esg_score": true, "short_interest": true, "days_to_cover": true,
+	"relative_strength_30d": true, "relative_strength_90d": true,
+}
+
+// ExportStocks maneja GET /stocks/export?format=csv|xlsx y devuelve los stocks como archivo
+// descargable, leídos de una instantánea de la base de datos. Con search, filtros, sortBy u
+// order (los mismos parámetros que GET /stocks, incluido ?screen=) o en formato xlsx
+// exporta el listado filtrado y ordenado completo (ver exportQuery). Si no, exporta todos
+// los stocks en CSV ordenados por ticker, y hay dos formas de reanudar una descarga:
+//
+//   - Exportación completa (sin limit ni page_token): la respuesta lleva un ETag que
+//     identifica la instantánea y admite Range/If-Range, así que un cliente puede pedir
+//     solo los bytes que le faltan y recibirá exactamente los mismos datos.
+//   - Por páginas (?limit=N o ?page_token=...): cada respuesta incluye X-Next-Page-Token
+//     mientras queden stocks; el token fija la instantánea y el último ticker enviado.
+func (h *StockHandlers) ExportStocks(w http.ResponseWriter, r *http.Request) {
+	query, err := applyScreen(r.URL.Query())
+	if err != nil {
+		apierror.HTTPError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	format := strings.ToLower(query.Get("format"))
+	if format == "" {
+		format = exportFormatCSV
+	}
+	if format != exportFormatCSV && format != exportFormatXLSX {
+		apierror.HTTPError(w, fmt.Sprintf("Formato de exportación no soportado: %s (use csv o xlsx)", format), http.StatusBadRequest)
+		return
+	}
+	filters, err := parseStockFilters(query)
+	if err != nil {
+		apierror.HTTPError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	opts := database.StockQueryOptions{
+		Search:  query.Get("search"),
+		SortBy:  query.Get("sortBy"),
+		Order:   strings.ToLower(query.Get("order")),
+		Filters: filters,
+	}
+	paged := query.Get("page_token") != "" || query.Get("limit") != ""
+	if format == exportFormatCSV && opts.Search == "" && opts.SortBy == "" && opts.Order == "" && filters == (database.StockFilters{}) {
+		if paged {
+			h.exportPage(w, r)
+			return
+		}
+		h.exportFull(w, r)
+		return
+	}
+	if paged {
+		apierror.HTTPError(w, "limit y page_token solo admiten la exportación CSV sin búsqueda, filtros ni orden", http.StatusBadRequest)
+		return
+	}
+	h.exportQuery(w, r, opts, format)
+}
+
+// exportQuery escribe en format todos los stocks que devolvería GET /stocks con opts, en
+// su mismo orden. Los recorre por cursor, de defaultExportPageSize en defaultExportPageSize,
+// sobre la instantánea de enriquecimiento vigente, así que el archivo se genera a medida que
+// se envía y es coherente aunque el enricher se ejecute a la vez. No admite Range: si la
+// descarga se interrumpe hay que repetirla.
+func (h *StockHandlers) exportQuery(w http.ResponseWriter, r *http.Request, opts database.StockQueryOptions, format string) {
+	if err := database.ValidateKeysetSort(opts.SortBy, opts.Order); err != nil {
+		apierror.HTTPError(w, fmt.Sprintf("sortBy inválido: %v", err), http.StatusBadRequest)
+		return
+	}
+	opts.Limit = defaultExportPageSize
+
+	// La primera página se lee antes de enviar nada, para poder responder con un error
+	db, _ := h.snapshot(w, r)
+	stocks, next, err := db.GetStocksByCursor(r.Context(), opts, nil)
+	if err != nil {
+		apierror.Internal(w, "Error al exportar stocks", err)
+		return
+	}
+
+	filename := "stocks-" + time.Now().UTC().Format("20060102T150405Z") + "." + format
+	var out interface {
+		Write(record []string) error
+	}
+	var finish func() error
+	if format == exportFormatXLSX {
+		numeric := map[int]bool{}
+		for i, column := range exportHeader {
+			numeric[i] = exportNumericColumns[column]
+		}
+		xw := newXLSXWriter(w, numeric)
+		out, finish = xw, xw.Close
+		w.Header().Set("Content-Type", xlsxContentType)
+	} else {
+		cw := newSpreadsheetCSV(w)
+		out, finish = cw, func() error { cw.Flush(); return cw.Error() }
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	w.WriteHeader(http.StatusOK)
+
+	out.Write(exportHeader)
+	for {
+		for _, s := range stocks {
+			if err := out.Write(exportRecord(s)); err != nil {
+				return // El cliente se ha desconectado
+			}
+		}
+		if next == nil {
+			break
+		}
+		if stocks, next, err = db.GetStocksByCursor(r.Context(), opts, next); err != nil {
+			// Ya se envió el 200: se corta la conexión para que el cliente no tome el
+			// archivo incompleto por bueno
+			log.Printf("Error al exportar stocks: %v", err)
+			panic(http.ErrAbortHandler)
+		}
+	}
+	if err := finish(); err != nil {
+		log.Printf("Error al exportar stocks: %v", err)
+	}
+}
+
+// exportPage escribe una página de la exportación y el token de la siguiente.
+func (h *StockHandlers) exportPage(w http.ResponseWriter, r *http.Request) {
+	limit := defaultExportPageSize
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed <= 0 {
+			apierror.HTTPError(w, "El parámetro 'limit' debe ser un entero positivo", http.StatusBadRequest)
+			return
+		}
+		limit = min(parsed, maxExportPageSize)
+	}
+
+	var snapshot time.Time
+	var after string
+	if token := r.URL.Query().Get("page_token"); token != "" {
+		var err error
+		snapshot, after, err = decodeExportToken(token)
+		if err != nil {
+			apierror.HTTPError(w, "page_token inválido", http.StatusBadRequest)
+			return
+		}
+	} else {
+		var err error
+		if snapshot, err = h.dbClient.ExportSnapshot(r.Context()); err != nil {
+			apierror.Internal(w, "Error al exportar stocks", err)
+			return
+		}
+	}
+
+	// Se pide un stock de más para saber si existe una página siguiente.
+	var stocks []models.Stock
+	err := h.dbClient.ExportStocks(r.Context(), snapshot, after, limit+1, func(s models.Stock) error {
+		stocks = append(stocks, s)
+		return nil
+	})
+	if err != nil {
+		apierror.Internal(w, "Error al exportar stocks", err)
+		return
+	}
+
+	if len(stocks) > limit {
+		stocks = stocks[:limit]
+		w.Header().Set("X-Next-Page-Token", encodeExportToken(snapshot, stocks[len(stocks)-1].Ticker))
+	}
+	setExportHeaders(w, snapshot)
+	w.WriteHeader(http.StatusOK)
+
+	cw := newSpreadsheetCSV(w)
+	cw.Write(exportHeader)
+	for _, s := range stocks {
+		cw.Write(exportRecord(s))
+	}
+	cw.Flush()
+}
+
+// exportFull sirve la exportación completa desde un archivo temporal, lo que permite a
+// http.ServeContent atender Range e If-Range.
+func (h *StockHandlers) exportFull(w http.ResponseWriter, r *http.Request) {
+	var snapshot time.Time
+	if r.Header.Get("Range") != "" {
+		snapshot, _ = parseExportETag(r.Header.Get("If-Range"))
+	}
+
+	path, err := h.exports.ensure(r.Context(), h.dbClient, snapshot)
+	if err != nil && !snapshot.IsZero() {
+		// La instantánea pedida ya no está disponible (p. ej. fuera de la ventana de
+		// AS OF SYSTEM TIME). Con una nueva, If-Range no coincide y se envía el CSV completo.
+		log.Printf("ADVERTENCIA: no se pudo regenerar la exportación de %s: %v", snapshot.Format(time.RFC3339Nano), err)
+		snapshot = time.Time{}
+		path, err = h.exports.ensure(r.Context(), h.dbClient, snapshot)
+	}
+	if err != nil {
+		apierror.Internal(w, "Error al exportar stocks", err)
+		return
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		apierror.Internal(w, "Error al exportar stocks", err)
+		return
+	}
+	defer f.Close()
+
+	snapshot, _ = exportSnapshotFromPath(path)
+	setExportHeaders(w, snapshot)
+	w.Header().Set("ETag", exportETag(snapshot))
+	http.ServeContent(w, r, "", snapshot, f)
+}
+
+func setExportHeaders(w http.ResponseWriter, snapshot time.Time) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="stocks-%s.csv"`, snapshot.UTC().Format("20060102T150405Z")))
+}
+
+// encodeExportToken codifica la instantánea y el último ticker enviado en un token opaco.
+func encodeExportToken(snapshot time.Time, lastTicker string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d:%s", snapshot.UnixNano(), lastTicker)))
+}
+
+func decodeExportToken(token string) (time.Time, string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return time.Time{}, "", err
+	}
+	nanos, ticker, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return time.Time{}, "", fmt.Errorf("formato de token inválido")
+	}
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return time.Time{}, "", err
+	}
+	return time.Unix(0, n).UTC(), ticker, nil
+}
+
+// exportETag identifica una exportación completa por su instantánea.
+func exportETag(snapshot time.Time) string {
+	return fmt.Sprintf(`"stocks-%d"`, snapshot.UnixNano())
+}
+
+func parseExportETag(etag string) (time.Time, bool) {
+	nanos, ok := strings.CutPrefix(strings.Trim(etag, `"`), "stocks-")
+	if !ok {
+		return time.Time{}, false
+	}
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(0, n).UTC(), true
+}
+
+// exportSpool guarda en disco las exportaciones completas, una por instantánea.
+type exportSpool struct {
+	dir string
+}
+
+func newExportSpool() *exportSpool {
+	return &exportSpool{dir: filepath.Join(os.TempDir(), "stock-app-exports")}
+}
+
+func (s *exportSpool) path(snapshot time.Time) string {
+	return filepath.Join(s.dir, fmt.Sprintf("stocks-%d.csv", snapshot.UnixNano()))
+}
+
+func exportSnapshotFromPath(path string) (time.Time, bool) {
+	return parseExportETag(strings.TrimSuffix(filepath.Base(path), ".csv"))
+}
+
+// ensure devuelve la ruta de la exportación de snapshot, generándola si no está en disco.
+// Si snapshot es cero se toma una instantánea nueva.
+func (s *exportSpool) ensure(ctx context.Context, dbClient database.StockDB, snapshot time.Time) (string, error) {
+	if snapshot.IsZero() {
+		var err error
+		if snapshot, err = dbClient.ExportSnapshot(ctx); err != nil {
+			return "", err
+		}
+	}
+
+	path := s.path(snapshot)
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return "", err
+	}
+	s.cleanup()
+
+	// Se escribe en un archivo temporal y se renombra, para que otra petición nunca vea
+	// una exportación a medias.
+	tmp, err := os.CreateTemp(s.dir, "partial-*.csv")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := writeExportCSV(ctx, tmp, dbClient, snapshot); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", err
+	}
+	os.Chtimes(path, snapshot, snapshot)
+	return path, nil
+}
+
+// cleanup elimina las exportaciones cuya instantánea es más antigua que exportSpoolTTL.
+func (s *exportSpool) cleanup() {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		snapshot, ok := exportSnapshotFromPath(entry.Name())
+		if ok && time.Since(snapshot) > exportSpoolTTL {
+			os.Remove(filepath.Join(s.dir, entry.Name()))
+		}
+	}
+}
+
+func writeExportCSV(ctx context.Context, w io.Writer, dbClient database.StockDB, snapshot time.Time) error {
+	cw := newSpreadsheetCSV(w)
+	if err := cw.Write(exportHeader); err != nil {
+		return err
+	}
+	err := dbClient.ExportStocks(ctx, snapshot, "", 0, func(s models.Stock) error {
+		return cw.Write(exportRecord(s))
+	})
+	if err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
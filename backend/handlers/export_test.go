@@ -0,0 +1,310 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/database"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// exportStockDB es un StockDB en memoria que solo implementa lo necesario para exportar.
+type exportStockDB struct {
+	database.StockDB
+	snapshot time.Time
+	stocks   []models.Stock // Ordenados por ticker
+}
+
+func (db *exportStockDB) ExportSnapshot(ctx context.Context) (time.Time, error) {
+	return db.snapshot, nil
+}
+
+func (db *exportStockDB) ExportStocks(ctx context.Context, asOf time.Time, afterTicker string, limit int, fn func(models.Stock) error) error {
+	sent := 0
+	for _, s := range db.stocks {
+		if s.Ticker <= afterTicker || (limit > 0 && sent == limit) {
+			continue
+		}
+		if err := fn(s); err != nil {
+			return err
+		}
+		sent++
+	}
+	return nil
+}
+
+func (db *exportStockDB) EnrichmentSnapshot(ctx context.Context) (time.Time, error) {
+	return db.snapshot, nil
+}
+
+func (db *exportStockDB) AsOf(t time.Time) database.StockDB {
+	return db
+}
+
+// GetStocksByCursor admite la búsqueda por ticker y el orden por ticker, ascendente o
+// descendente, de página en página.
+func (db *exportStockDB) GetStocksByCursor(ctx context.Context, opts database.StockQueryOptions, after *database.StockCursor) ([]models.Stock, *database.StockCursor, error) {
+	var matched []models.Stock
+	for _, s := range db.stocks {
+		if strings.Contains(s.Ticker, strings.ToUpper(opts.Search)) {
+			matched = append(matched, s)
+		}
+	}
+	if opts.Order == "desc" {
+		for i, j := 0, len(matched)-1; i < j; i, j = i+1, j-1 {
+			matched[i], matched[j] = matched[j], matched[i]
+		}
+	}
+	start := 0
+	if after != nil {
+		for start < len(matched) && matched[start].ID != after.ID {
+			start++
+		}
+		start++
+	}
+	end := min(start+opts.Limit, len(matched))
+	page := matched[start:end]
+	if end == len(matched) {
+		return page, nil, nil
+	}
+	return page, &database.StockCursor{Sort: "ticker", Values: []interface{}{page[len(page)-1].Ticker}, ID: page[len(page)-1].ID}, nil
+}
+
+func newExportTestHandlers(t *testing.T) *StockHandlers {
+	db := &exportStockDB{snapshot: time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)}
+	for _, ticker := range []string{"AAPL", "KO", "MSFT", "PFE", "ZTS"} {
+		db.stocks = append(db.stocks, models.Stock{
+			ID: uuid.New(), Ticker: ticker, Company: ticker + ", Inc.", CurrentPrice: 100.5,
+			TargetTo: models.NewNullFloat64(120), CreatedAt: db.snapshot, UpdatedAt: db.snapshot,
+		})
+	}
+	return &StockHandlers{dbClient: db, exports: &exportSpool{dir: t.TempDir()}}
+}
+
+func TestExportStocks_ResumesWithRange(t *testing.T) {
+	h := newExportTestHandlers(t)
+
+	full := httptest.NewRecorder()
+	h.ExportStocks(full, httptest.NewRequest(http.MethodGet, "/api/v1/stocks/export", nil))
+	if full.Code != http.StatusOK {
+		t.Fatalf("❌ estado %d, se esperaba 200", full.Code)
+	}
+	etag := full.Header().Get("ETag")
+	body := full.Body.String()
+	if etag == "" || !strings.HasPrefix(body, "id,ticker,company") {
+		t.Fatalf("❌ respuesta inesperada (ETag %q): %s", etag, body)
+	}
+
+	// Un cliente que recibió solo los primeros 100 bytes pide el resto.
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/stocks/export", nil)
+	req.Header.Set("Range", "bytes=100-")
+	req.Header.Set("If-Range", etag)
+	rest := httptest.NewRecorder()
+	h.ExportStocks(rest, req)
+
+	if rest.Code != http.StatusPartialContent {
+		t.Fatalf("❌ estado %d, se esperaba 206", rest.Code)
+	}
+	if got := body[:100] + rest.Body.String(); got != body {
+		t.Errorf("❌ la descarga reanudada no coincide con la completa")
+	}
+	if cr := rest.Header().Get("Content-Range"); !strings.HasPrefix(cr, "bytes 100-") {
+		t.Errorf("❌ Content-Range inesperado: %q", cr)
+	}
+}
+
+func TestExportStocks_PageTokens(t *testing.T) {
+	h := newExportTestHandlers(t)
+
+	var tickers []string
+	url := "/api/v1/stocks/export?limit=2"
+	for pages := 0; url != ""; pages++ {
+		if pages > 5 {
+			t.Fatal("❌ demasiadas páginas, el token no avanza")
+		}
+		rr := httptest.NewRecorder()
+		h.ExportStocks(rr, httptest.NewRequest(http.MethodGet, url, nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("❌ estado %d, se esperaba 200: %s", rr.Code, rr.Body.String())
+		}
+
+		records, err := csv.NewReader(rr.Body).ReadAll()
+		if err != nil {
+			t.Fatalf("❌ CSV inválido: %v", err)
+		}
+		for _, record := range records[1:] {
+			tickers = append(tickers, record[1])
+		}
+
+		url = ""
+		if token := rr.Header().Get("X-Next-Page-Token"); token != "" {
+			url = "/api/v1/stocks/export?limit=2&page_token=" + token
+		}
+	}
+
+	if got := strings.Join(tickers, ","); got != "AAPL,KO,MSFT,PFE,ZTS" {
+		t.Errorf("❌ tickers exportados %s, se esperaba AAPL,KO,MSFT,PFE,ZTS", got)
+	}
+}
+
+func TestExportStocks_InvalidPageToken(t *testing.T) {
+	h := newExportTestHandlers(t)
+	rr := httptest.NewRecorder()
+	h.ExportStocks(rr, httptest.NewRequest(http.MethodGet, "/api/v1/stocks/export?page_token=bm9wZQ", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("❌ estado %d, se esperaba 400", rr.Code)
+	}
+}
+
+func TestExportStocks_Query(t *testing.T) {
+	h := newExportTestHandlers(t)
+	rr := httptest.NewRecorder()
+	h.ExportStocks(rr, httptest.NewRequest(http.MethodGet, "/api/v1/stocks/export?search=s&sortBy=ticker&order=desc", nil))
+	if rr.Code != http.StatusOK || rr.Header().Get("ETag") != "" {
+		t.Fatalf("❌ estado %d (ETag %q), se esperaba 200 sin ETag", rr.Code, rr.Header().Get("ETag"))
+	}
+	records, err := csv.NewReader(rr.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("❌ CSV inválido: %v", err)
+	}
+	var tickers []string
+	for _, record := range records[1:] {
+		tickers = append(tickers, record[1])
+	}
+	if got := strings.Join(tickers, ","); got != "ZTS,MSFT" {
+		t.Errorf("❌ tickers exportados %s, se esperaba ZTS,MSFT", got)
+	}
+}
+
+func TestExportStocks_XLSX(t *testing.T) {
+	h := newExportTestHandlers(t)
+	h.dbClient.(*exportStockDB).stocks[0].Company = "AT&T <Inc.>"
+	h.dbClient.(*exportStockDB).stocks[1].Company = `=HYPERLINK("http://evil.example","KO")`
+	rr := httptest.NewRecorder()
+	h.ExportStocks(rr, httptest.NewRequest(http.MethodGet, "/api/v1/stocks/export?format=xlsx", nil))
+	if rr.Code != http.StatusOK || rr.Header().Get("Content-Type") != xlsxContentType {
+		t.Fatalf("❌ estado %d con Content-Type %q", rr.Code, rr.Header().Get("Content-Type"))
+	}
+	if cd := rr.Header().Get("Content-Disposition"); !strings.HasSuffix(cd, `.xlsx"`) {
+		t.Errorf("❌ Content-Disposition inesperado: %q", cd)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(rr.Body.Bytes()), int64(rr.Body.Len()))
+	if err != nil {
+		t.Fatalf("❌ el libro no es un ZIP válido: %v", err)
+	}
+	parts := map[string]string{}
+	for _, f := range archive.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("❌ no se pudo abrir %s: %v", f.Name, err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		parts[f.Name] = string(data)
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels"} {
+		if _, ok := parts[name]; !ok {
+			t.Errorf("❌ falta la parte %s", name)
+		}
+	}
+
+	var sheet struct {
+		Rows []struct {
+			Cells []struct {
+				Ref    string `xml:"r,attr"`
+				Type   string `xml:"t,attr"`
+				Style  string `xml:"s,attr"`
+				Value  string `xml:"v"`
+				Inline string `xml:"is>t"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := xml.Unmarshal([]byte(parts["xl/worksheets/sheet1.xml"]), &sheet); err != nil {
+		t.Fatalf("❌ hoja inválida: %v", err)
+	}
+	if len(sheet.Rows) != 6 {
+		t.Fatalf("❌ %d filas, se esperaban 6 (cabecera y 5 stocks)", len(sheet.Rows))
+	}
+	cells := map[string]string{}
+	for _, c := range sheet.Rows[1].Cells {
+		cells[c.Ref] = c.Type + ":" + c.Value + c.Inline
+	}
+	// B es ticker, C company y J current_price
+	if cells["B2"] != "inlineStr:AAPL" || cells["C2"] != "inlineStr:AT&T <Inc.>" || cells["J2"] != ":100.5" {
+		t.Errorf("❌ celdas inesperadas: B2=%q C2=%q J2=%q", cells["B2"], cells["C2"], cells["J2"])
+	}
+	if _, ok := cells["K2"]; ok {
+		t.Errorf("❌ un pe_ratio nulo no debe tener celda")
+	}
+	for _, c := range sheet.Rows[2].Cells {
+		if c.Ref == "C3" && (c.Type != "inlineStr" || c.Style != "1" || c.Inline != `=HYPERLINK("http://evil.example","KO")`) {
+			t.Errorf("❌ una fórmula debe guardarse como texto con quotePrefix: %+v", c)
+		}
+	}
+	if !strings.Contains(parts["xl/styles.xml"], `quotePrefix="1"`) {
+		t.Errorf("❌ falta el estilo quotePrefix en xl/styles.xml")
+	}
+}
+
+// TestExportStocks_NeutralizesFormulas comprueba que las exportaciones CSV escriben con
+// un apóstrofo delante los campos que una hoja de cálculo evaluaría como fórmula.
+func TestExportStocks_NeutralizesFormulas(t *testing.T) {
+	h := newExportTestHandlers(t)
+	db := h.dbClient.(*exportStockDB)
+	db.stocks[0].Company = "=1+1"
+	db.stocks[1].Company = "@SUM(A1)"
+	db.stocks[2].Alpha = models.NewNullFloat64(-1.5)
+
+	for _, query := range []string{"", "?limit=10", "?search=a&sortBy=ticker"} {
+		rr := httptest.NewRecorder()
+		h.ExportStocks(rr, httptest.NewRequest(http.MethodGet, "/api/v1/stocks/export"+query, nil))
+		records, err := csv.NewReader(rr.Body).ReadAll()
+		if err != nil || len(records) < 2 {
+			t.Fatalf("❌ %s: CSV inválido (%d filas): %v", query, len(records), err)
+		}
+		// C es company y N alpha
+		byTicker := map[string][]string{}
+		for _, record := range records[1:] {
+			byTicker[record[1]] = record
+		}
+		if got := byTicker["AAPL"][2]; got != "'=1+1" {
+			t.Errorf("❌ %s: company de AAPL = %q, se esperaba '=1+1", query, got)
+		}
+		if got, ok := byTicker["KO"]; ok && got[2] != "'@SUM(A1)" {
+			t.Errorf("❌ %s: company de KO = %q, se esperaba '@SUM(A1)", query, got[2])
+		}
+		if got, ok := byTicker["MSFT"]; ok && got[13] != "-1.5" {
+			t.Errorf("❌ %s: un número negativo no debe cambiar, alpha de MSFT = %q", query, got[13])
+		}
+	}
+}
+
+func TestExportStocks_InvalidParams(t *testing.T) {
+	h := newExportTestHandlers(t)
+	for _, query := range []string{"format=pdf", "format=xlsx&limit=10", "search=a&page_token=x", "sortBy=staleness", "min_price=abc"} {
+		rr := httptest.NewRecorder()
+		h.ExportStocks(rr, httptest.NewRequest(http.MethodGet, "/api/v1/stocks/export?"+query, nil))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("❌ ?%s: estado %d, se esperaba 400", query, rr.Code)
+		}
+	}
+}
+
+func TestXLSXColumn(t *testing.T) {
+	for i, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"} {
+		if got := xlsxColumn(i); got != want {
+			t.Errorf("❌ columna %d: %s, se esperaba %s", i, got, want)
+		}
+	}
+}
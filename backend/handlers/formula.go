@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+)
+
+// looksLikeFormula indica si una hoja de cálculo interpretaría value como fórmula al abrir
+// el archivo: empieza por =, +, -, @, tabulador o retorno de carro. Los números (p. ej.
+// "-1.5") no cuentan: se abren como número y no ejecutan nada.
+func looksLikeFormula(value string) bool {
+	if value == "" {
+		return false
+	}
+	switch value[0] {
+	case '=', '+', '-', '@', '\t', '\r':
+		_, err := strconv.ParseFloat(value, 64)
+		return err != nil
+	}
+	return false
+}
+
+// spreadsheetCSV es un csv.Writer para archivos que se abrirán en una hoja de cálculo. Los
+// campos que parecen una fórmula se escriben precedidos de un apóstrofo, para que Excel,
+// LibreOffice o Google Sheets los muestren como texto en lugar de evaluarlos: un nombre de
+// empresa o de watchlist como =HYPERLINK(...) no debe poder ejecutarse en el equipo de
+// quien abre la exportación.
+type spreadsheetCSV struct {
+	*csv.Writer
+}
+
+func newSpreadsheetCSV(w io.Writer) spreadsheetCSV {
+	return spreadsheetCSV{csv.NewWriter(w)}
+}
+
+// Write escribe record con los campos que parecen fórmula neutralizados.
+func (c spreadsheetCSV) Write(record []string) error {
+	var safe []string // Copia de record, solo si hay que cambiar algún campo
+	for i, value := range record {
+		if !looksLikeFormula(value) {
+			continue
+		}
+		if safe == nil {
+			safe = append([]string(nil), record...)
+		}
+		safe[i] = "'" + value
+	}
+	if safe == nil {
+		return c.Writer.Write(record)
+	}
+	return c.Writer.Write(safe)
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/jannin2/stock-app/backend/apierror"
+	"github.com/jannin2/stock-app/backend/database"
+)
+
+// Rutas de las sondas de salud. No pasan por RequireReady.
+const (
+	LivenessPath  = "/healthz"
+	ReadinessPath = "/readyz"
+)
+
+// HealthHandlers expone las sondas de liveness y readiness del servidor.
+type HealthHandlers struct {
+	readiness *database.Readiness
+	degraded  map[string]bool // Rutas GET que RequireReady deja pasar con la base de datos caída
+}
+
+// NewHealthHandlers crea los manejadores de salud a partir del estado de la base de datos.
+// RequireReady deja pasar las peticiones GET a degradedPaths, que responden por su cuenta
+// mientras la base de datos no está disponible (ver api.ResponseCache.Fallback).
+func NewHealthHandlers(readiness *database.Readiness, degradedPaths ...string) *HealthHandlers {
+	degraded := make(map[string]bool, len(degradedPaths))
+	for _, path := range degradedPaths {
+		degraded[path] = true
+	}
+	return &HealthHandlers{readiness: readiness, degraded: degraded}
+}
+
+// Liveness responde 200 mientras el proceso esté en marcha, aunque la base de datos no lo esté.
+func (h *HealthHandlers) Liveness(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, r, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// Readiness responde 200 si la base de datos está disponible y 503 en caso contrario.
+func (h *HealthHandlers) Readiness(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	if !h.readiness.Ready() {
+		writeJSON(w, r, http.StatusServiceUnavailable, map[string]string{"status": "not ready", "database": "unavailable"})
+		return
+	}
+	writeJSON(w, r, http.StatusOK, map[string]string{"status": "ready", "database": "ok"})
+}
+
+// RequireReady rechaza con 503 las peticiones a /api mientras la base de datos no esté
+// disponible, en lugar de dejar que fallen con errores de conexión. La página de estado
+// (StatusPath) sigue respondiendo para informar de la caída, y los listados degradados
+// responden desde su última copia.
+func (h *HealthHandlers) RequireReady(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		degraded := r.Method == http.MethodGet && h.degraded[r.URL.Path]
+		if !h.readiness.Ready() && strings.HasPrefix(r.URL.Path, "/api/") && r.URL.Path != StatusPath && !degraded {
+			w.Header().Set("Retry-After", "5")
+			apierror.HTTPError(w, "Servicio no disponible: la base de datos aún no está lista", http.StatusServiceUnavailable)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequirePoolCapacity rechaza con 503 las peticiones a /api mientras el pool de conexiones
+// esté saturado, en lugar de encolarlas hasta que se libere una conexión. Como
+// RequireReady, deja pasar la página de estado.
+func RequirePoolCapacity(pool *database.PoolMonitor) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if pool.Saturated() && strings.HasPrefix(r.URL.Path, "/api/") && r.URL.Path != StatusPath {
+				w.Header().Set("Retry-After", "1")
+				apierror.HTTPError(w, "Servicio no disponible: la base de datos está saturada", http.StatusServiceUnavailable)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jannin2/stock-app/backend/database"
+)
+
+func TestHealthHandlers_ReadinessGate(t *testing.T) {
+	readiness := &database.Readiness{}
+	h := NewHealthHandlers(readiness, "/api/v1/stocks")
+	api := h.RequireReady(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name    string
+		ready   bool
+		handler http.Handler
+		path    string
+		want    int
+	}{
+		{"liveness sin base de datos", false, http.HandlerFunc(h.Liveness), LivenessPath, http.StatusOK},
+		{"readiness sin base de datos", false, http.HandlerFunc(h.Readiness), ReadinessPath, http.StatusServiceUnavailable},
+		{"api sin base de datos", false, api, "/api/v1/stocks/aggregates", http.StatusServiceUnavailable},
+		{"listado degradado sin base de datos", false, api, "/api/v1/stocks", http.StatusOK},
+		{"página de estado sin base de datos", false, api, StatusPath, http.StatusOK},
+		{"readiness con base de datos", true, http.HandlerFunc(h.Readiness), ReadinessPath, http.StatusOK},
+		{"api con base de datos", true, api, "/api/v1/stocks", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			readiness.SetReady(tt.ready)
+			rr := httptest.NewRecorder()
+			tt.handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rr.Code != tt.want {
+				t.Errorf("❌ %s: estado %d, se esperaba %d", tt.path, rr.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequirePoolCapacity(t *testing.T) {
+	pool := database.NewPoolMonitor(nil)
+	api := RequirePoolCapacity(pool)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name      string
+		saturated bool
+		path      string
+		want      int
+	}{
+		{"api con el pool libre", false, "/api/v1/stocks", http.StatusOK},
+		{"api con el pool saturado", true, "/api/v1/stocks", http.StatusServiceUnavailable},
+		{"página de estado con el pool saturado", true, StatusPath, http.StatusOK},
+		{"liveness con el pool saturado", true, LivenessPath, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool.SetSaturated(tt.saturated)
+			rr := httptest.NewRecorder()
+			api.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rr.Code != tt.want {
+				t.Errorf("❌ %s: estado %d, se esperaba %d", tt.path, rr.Code, tt.want)
+			}
+			if tt.want == http.StatusServiceUnavailable && rr.Header().Get("Retry-After") == "" {
+				t.Error("❌ Falta la cabecera Retry-After")
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jannin2/stock-app/backend/apierror"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+const (
+	defaultHistoryPoints = 200
+	maxHistoryPoints     = 1000
+)
+
+// historyResponse es la respuesta de GET /stocks/{id}/history.
+type historyResponse struct {
+	Ticker     string            `json:"ticker"`
+	Resolution string            `json:"resolution"`
+	Points     []models.PriceBar `json:"points"`
+}
+
+// candlesResponse es la respuesta de GET /stocks/{id}/ohlc.
+type candlesResponse struct {
+	Ticker     string          `json:"ticker"`
+	Resolution string          `json:"resolution"`
+	Candles    []models.Candle `json:"candles"`
+}
+
+// GetStockHistory maneja GET /stocks/{id}/history?resolution=day|week|month&points=200 y
+// devuelve los últimos points cierres del stock, uno por día, semana o mes. La agregación
+// se hace en la base de datos, así que un gráfico largo no descarga años de cierres diarios.
+func (h *StockHandlers) GetStockHistory(w http.ResponseWriter, r *http.Request) {
+	stock, resolution, points, ok := h.historyParams(w, r)
+	if !ok {
+		return
+	}
+	bars, err := h.dbClient.GetPriceBars(r.Context(), stock.Ticker, resolution, points)
+	if err != nil {
+		apierror.Internal(w, "Error al obtener el histórico de precios", err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, historyResponse{Ticker: stock.Ticker, Resolution: resolution, Points: bars})
+}
+
+// GetStockCandles maneja GET /stocks/{id}/ohlc?resolution=day|week|month&points=200 y
+// devuelve las últimas points velas OHLCV del stock para gráficos de velas, agregadas en
+// la base de datos como las de GetStockHistory.
+func (h *StockHandlers) GetStockCandles(w http.ResponseWriter, r *http.Request) {
+	stock, resolution, points, ok := h.historyParams(w, r)
+	if !ok {
+		return
+	}
+	candles, err := h.dbClient.GetCandles(r.Context(), stock.Ticker, resolution, points)
+	if err != nil {
+		apierror.Internal(w, "Error al obtener las velas del histórico de precios", err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, candlesResponse{Ticker: stock.Ticker, Resolution: resolution, Candles: candles})
+}
+
+// historyParams lee el stock de la ruta y la resolución y el número de puntos pedidos. Si
+// no son válidos, responde el error y devuelve false.
+func (h *StockHandlers) historyParams(w http.ResponseWriter, r *http.Request) (models.Stock, string, int, bool) {
+	resolution := r.URL.Query().Get("resolution")
+	if resolution == "" {
+		resolution = models.ResolutionDay
+	}
+	if !models.ValidResolution(resolution) {
+		apierror.HTTPError(w, fmt.Sprintf("Resolución no soportada: %s (use day, week o month)", resolution), http.StatusBadRequest)
+		return models.Stock{}, "", 0, false
+	}
+	points := defaultHistoryPoints
+	if raw := r.URL.Query().Get("points"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 || n > maxHistoryPoints {
+			apierror.HTTPError(w, fmt.Sprintf("El parámetro 'points' debe ser un entero entre 1 y %d", maxHistoryPoints), http.StatusBadRequest)
+			return models.Stock{}, "", 0, false
+		}
+		points = n
+	}
+
+	stock, err := h.dbClient.GetStockByID(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, err, "Error al obtener el stock")
+		return models.Stock{}, "", 0, false
+	}
+	return stock, resolution, points, true
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jannin2/stock-app/backend/database"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// historyStockDB tiene un solo stock, AAPL, y registra la consulta del histórico.
+type historyStockDB struct {
+	database.StockDB
+	resolution string
+	limit      int
+}
+
+func (db *historyStockDB) GetStockByID(ctx context.Context, id string) (models.Stock, error) {
+	if id != "s1" {
+		return models.Stock{}, fmt.Errorf("stock con ID %s: %w", id, database.ErrStockNotFound)
+	}
+	return models.Stock{Ticker: "AAPL"}, nil
+}
+
+func (db *historyStockDB) GetPriceBars(ctx context.Context, ticker, resolution string, limit int) ([]models.PriceBar, error) {
+	db.resolution, db.limit = resolution, limit
+	return []models.PriceBar{{Start: time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC), Close: 185.5}}, nil
+}
+
+func (db *historyStockDB) GetCandles(ctx context.Context, ticker, resolution string, limit int) ([]models.Candle, error) {
+	db.resolution, db.limit = resolution, limit
+	return []models.Candle{{Start: time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC), Open: 184, High: 191, Low: 183, Close: 190, Volume: 250000000}}, nil
+}
+
+func TestGetStockHistory(t *testing.T) {
+	db := &historyStockDB{}
+	h := NewStockHandlers(db, nil)
+	get := func(id, query string) *httptest.ResponseRecorder {
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", id)
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/stocks/"+id+"/history"+query, nil)
+		rr := httptest.NewRecorder()
+		h.GetStockHistory(rr, req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx)))
+		return rr
+	}
+
+	rr := get("s1", "")
+	var resp historyResponse
+	if rr.Code != http.StatusOK || json.Unmarshal(rr.Body.Bytes(), &resp) != nil || resp.Ticker != "AAPL" || len(resp.Points) != 1 {
+		t.Fatalf("❌ respuesta inesperada: %d %s", rr.Code, rr.Body.String())
+	}
+	if db.resolution != models.ResolutionDay || db.limit != defaultHistoryPoints {
+		t.Errorf("❌ consulta con %s y %d puntos, se esperaba day y %d", db.resolution, db.limit, defaultHistoryPoints)
+	}
+
+	if rr := get("s1", "?resolution=month&points=120"); rr.Code != http.StatusOK || db.resolution != models.ResolutionMonth || db.limit != 120 {
+		t.Errorf("❌ consulta con %s y %d puntos (estado %d), se esperaba month y 120", db.resolution, db.limit, rr.Code)
+	}
+
+	for _, query := range []string{"?resolution=hour", "?points=0", "?points=5000", "?points=muchos"} {
+		if rr := get("s1", query); rr.Code != http.StatusBadRequest {
+			t.Errorf("❌ estado %d con %s, se esperaba 400", rr.Code, query)
+		}
+	}
+	if rr := get("nope", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("❌ estado %d con un stock inexistente, se esperaba 404", rr.Code)
+	}
+}
+
+func TestGetStockCandles(t *testing.T) {
+	db := &historyStockDB{}
+	h := NewStockHandlers(db, nil)
+	get := func(id, query string) *httptest.ResponseRecorder {
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", id)
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/stocks/"+id+"/ohlc"+query, nil)
+		rr := httptest.NewRecorder()
+		h.GetStockCandles(rr, req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx)))
+		return rr
+	}
+
+	rr := get("s1", "?resolution=week&points=52")
+	var resp candlesResponse
+	if rr.Code != http.StatusOK || json.Unmarshal(rr.Body.Bytes(), &resp) != nil || len(resp.Candles) != 1 || resp.Candles[0].High != 191 {
+		t.Fatalf("❌ respuesta inesperada: %d %s", rr.Code, rr.Body.String())
+	}
+	if db.resolution != models.ResolutionWeek || db.limit != 52 {
+		t.Errorf("❌ consulta con %s y %d puntos, se esperaba week y 52", db.resolution, db.limit)
+	}
+	if rr := get("s1", "?resolution=year"); rr.Code != http.StatusBadRequest {
+		t.Errorf("❌ estado %d con una resolución no soportada, se esperaba 400", rr.Code)
+	}
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jannin2/stock-app/backend/apierror"
+	"github.com/jannin2/stock-app/backend/database"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// ListImportMappings maneja GET /admin/import-mappings.
+func (h *StockHandlers) ListImportMappings(w http.ResponseWriter, r *http.Request) {
+	mappings, err := h.dbClient.ListImportMappings(r.Context())
+	if err != nil {
+		apierror.Internal(w, "Error al obtener las plantillas de importación", err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, mappings)
+}
+
+// GetImportMapping maneja GET /admin/import-mappings/{name}.
+func (h *StockHandlers) GetImportMapping(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	mapping, err := h.dbClient.GetImportMapping(r.Context(), name)
+	if err != nil {
+		writeImportMappingError(w, name, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, mapping)
+}
+
+// SaveImportMapping maneja PUT /admin/import-mappings/{name}: crea o reemplaza la plantilla
+// que usan las importaciones CSV con ?mapping={name}. El cuerpo indica si el CSV trae
+// cabecera, su delimitador y el campo de cada columna, p. ej.
+// {"has_header": true, "columns": {"Symbol": "ticker", "3": "target_to"}}.
+func (h *StockHandlers) SaveImportMapping(w http.ResponseWriter, r *http.Request) {
+	var mapping models.ImportMapping
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&mapping); err != nil {
+		apierror.HTTPError(w, fmt.Sprintf("Cuerpo JSON inválido: %v", err), http.StatusBadRequest)
+		return
+	}
+	mapping.Name = chi.URLParam(r, "name") // El nombre es el de la URL, no el del cuerpo
+	if err := mapping.Validate(); err != nil {
+		apierror.HTTPError(w, fmt.Sprintf("Plantilla de importación inválida: %v", err), http.StatusBadRequest)
+		return
+	}
+	saved, err := h.dbClient.SaveImportMapping(r.Context(), mapping)
+	if err != nil {
+		apierror.Internal(w, "Error al guardar la plantilla de importación", err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, saved)
+}
+
+// DeleteImportMapping maneja DELETE /admin/import-mappings/{name}. Las importaciones ya
+// encoladas conservan su copia de la plantilla.
+func (h *StockHandlers) DeleteImportMapping(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	if err := h.dbClient.DeleteImportMapping(r.Context(), name); err != nil {
+		writeImportMappingError(w, name, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeImportMappingError(w http.ResponseWriter, name string, err error) {
+	if errors.Is(err, database.ErrImportMappingNotFound) {
+		apierror.HTTPError(w, fmt.Sprintf("Plantilla de importación no encontrada: %s", name), http.StatusNotFound)
+		return
+	}
+	apierror.Internal(w, fmt.Sprintf("Error en la plantilla de importación %s", name), err)
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jannin2/stock-app/backend/database"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// mappingStockDB guarda la última plantilla recibida.
+type mappingStockDB struct {
+	database.StockDB
+	saved *models.ImportMapping
+}
+
+func (db *mappingStockDB) SaveImportMapping(ctx context.Context, m models.ImportMapping) (models.ImportMapping, error) {
+	db.saved = &m
+	return m, nil
+}
+
+func TestSaveImportMapping(t *testing.T) {
+	db := &mappingStockDB{}
+	h := NewStockHandlers(db, nil)
+	put := func(body string) *httptest.ResponseRecorder {
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("name", "broker-x")
+		req := httptest.NewRequest(http.MethodPut, "/admin/import-mappings/broker-x", strings.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		rr := httptest.NewRecorder()
+		h.SaveImportMapping(rr, req)
+		return rr
+	}
+
+	if rr := put(`{"name":"other","has_header":true,"columns":{"Symbol":"ticker","3":"target_to"}}`); rr.Code != http.StatusOK {
+		t.Fatalf("❌ se esperaba 200, se obtuvo %d: %s", rr.Code, rr.Body)
+	}
+	if db.saved == nil || db.saved.Name != "broker-x" {
+		t.Errorf("❌ la plantilla debería guardarse con el nombre de la URL: %+v", db.saved)
+	}
+
+	for _, body := range []string{
+		`{"columns":{"1":"company"}}`,                // Sin ticker
+		`{"columns":{"Symbol":"ticker"}}`,            // Nombre de columna sin cabecera
+		`{"columns":{"1":"ticker"},"separator":";"}`, // Campo desconocido
+	} {
+		if rr := put(body); rr.Code != http.StatusBadRequest {
+			t.Errorf("❌ %s: se esperaba 400, se obtuvo %d", body, rr.Code)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/apierror"
+)
+
+const (
+	// defaultIPOWindow es el periodo que devuelve GET /ipos sin ?to=.
+	defaultIPOWindow = 30 * 24 * time.Hour
+	// maxIPOWindow limita el periodo de una consulta.
+	maxIPOWindow = 366 * 24 * time.Hour
+)
+
+// GetIPOs maneja GET /ipos?from=2025-01-01&to=2025-01-31: las salidas a bolsa con fecha
+// entre from y to (incluidas), por fecha. Sin parámetros se devuelven las de los próximos
+// 30 días. El calendario se sincroniza en cada ejecución del enricher, unos 30 días hacia
+// delante.
+func (h *StockHandlers) GetIPOs(w http.ResponseWriter, r *http.Request) {
+	from, to, err := parseIPOWindow(r, time.Now().UTC().Truncate(24*time.Hour))
+	if err != nil {
+		apierror.HTTPError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ipos, err := h.dbClient.GetIPOs(r.Context(), from, to)
+	if err != nil {
+		apierror.Internal(w, "Error al obtener el calendario de IPOs", err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, ipos)
+}
+
+// parseIPOWindow lee ?from= y ?to= (YYYY-MM-DD). from es today por defecto y to, from más
+// defaultIPOWindow.
+func parseIPOWindow(r *http.Request, today time.Time) (from, to time.Time, err error) {
+	return parseDateWindow(r, today, defaultIPOWindow, maxIPOWindow)
+}
+
+// parseDateWindow lee un periodo de ?from= y ?to= (YYYY-MM-DD, ambos incluidos). Sin ellos,
+// from es defaultFrom y to, from más defaultWindow; el periodo no puede superar maxWindow.
+func parseDateWindow(r *http.Request, defaultFrom time.Time, defaultWindow, maxWindow time.Duration) (from, to time.Time, err error) {
+	from, to = defaultFrom, time.Time{}
+	if value := r.URL.Query().Get("from"); value != "" {
+		if from, err = time.Parse("2006-01-02", value); err != nil {
+			return from, to, fmt.Errorf("el parámetro 'from' debe ser una fecha YYYY-MM-DD")
+		}
+	}
+	to = from.Add(defaultWindow)
+	if value := r.URL.Query().Get("to"); value != "" {
+		if to, err = time.Parse("2006-01-02", value); err != nil {
+			return from, to, fmt.Errorf("el parámetro 'to' debe ser una fecha YYYY-MM-DD")
+		}
+	}
+	switch {
+	case to.Before(from):
+		return from, to, fmt.Errorf("el parámetro 'to' no puede ser anterior a 'from'")
+	case to.Sub(from) > maxWindow:
+		return from, to, fmt.Errorf("el periodo entre 'from' y 'to' no puede superar %d días", int(maxWindow.Hours()/24))
+	}
+	return from, to, nil
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseIPOWindow(t *testing.T) {
+	today := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	date := func(s string) time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return d
+	}
+	tests := []struct {
+		query    string
+		from, to time.Time
+		wantErr  bool
+	}{
+		{"", today, date("2025-02-05"), false},
+		{"from=2025-03-01", date("2025-03-01"), date("2025-03-31"), false},
+		{"from=2025-01-01&to=2025-01-31", date("2025-01-01"), date("2025-01-31"), false},
+		{"from=01/01/2025", time.Time{}, time.Time{}, true},
+		{"from=2025-02-01&to=2025-01-01", time.Time{}, time.Time{}, true},
+		{"from=2025-01-01&to=2027-01-01", time.Time{}, time.Time{}, true},
+	}
+	for _, tt := range tests {
+		from, to, err := parseIPOWindow(httptest.NewRequest("GET", "/api/v1/ipos?"+tt.query, nil), today)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("❌ %q: se esperaba un error", tt.query)
+			}
+			continue
+		}
+		if err != nil || !from.Equal(tt.from) || !to.Equal(tt.to) {
+			t.Errorf("❌ %q: periodo %s - %s (%v), se esperaba %s - %s", tt.query, from, to, err, tt.from, tt.to)
+		}
+	}
+}
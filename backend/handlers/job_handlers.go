@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/apierror"
+	"github.com/jannin2/stock-app/backend/auth"
+	"github.com/jannin2/stock-app/backend/jobs"
+)
+
+// JobHandlers informa del estado de los trabajos en segundo plano (importaciones,
+// exportaciones, ...) y sirve sus resultados.
+type JobHandlers struct {
+	jobs *jobs.Queue
+}
+
+// NewJobHandlers crea los manejadores de trabajos sobre la cola indicada.
+func NewJobHandlers(queue *jobs.Queue) *JobHandlers {
+	return &JobHandlers{jobs: queue}
+}
+
+// jobStatus es el estado de un trabajo con los enlaces para consultarlo y, si produjo un
+// archivo (ej. el CSV de errores de una importación), descargarlo.
+type jobStatus struct {
+	jobs.Job
+	StatusURL string `json:"status_url"`
+	ResultURL string `json:"result_url,omitempty"`
+}
+
+func newJobStatus(job jobs.Job) jobStatus {
+	resp := jobStatus{Job: job, StatusURL: jobStatusURL(job.ID)}
+	if job.FinishedAt != nil && job.ResultPath != "" {
+		resp.ResultURL = resp.StatusURL + "/result"
+	}
+	return resp
+}
+
+// GetJob maneja GET /jobs/{id}: estado, progreso y resumen del resultado de un trabajo.
+func (h *JobHandlers) GetJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.job(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, r, http.StatusOK, newJobStatus(job))
+}
+
+// DownloadJobResult maneja GET /jobs/{id}/result: descarga el archivo que produjo un
+// trabajo terminado. Las importaciones lo producen aunque fallen, con los errores por fila
+// de lo procesado hasta el fallo. Responde 409 si el trabajo aún no ha terminado.
+func (h *JobHandlers) DownloadJobResult(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.job(w, r)
+	if !ok {
+		return
+	}
+	if job.FinishedAt == nil {
+		apierror.HTTPError(w, fmt.Sprintf("El trabajo no ha terminado (estado: %s)", job.Status), http.StatusConflict)
+		return
+	}
+	if job.ResultPath == "" {
+		apierror.HTTPError(w, "El trabajo no produjo ningún archivo", http.StatusNotFound)
+		return
+	}
+	f, err := os.Open(job.ResultPath)
+	if err != nil {
+		apierror.HTTPError(w, "El resultado ya no está disponible", http.StatusGone)
+		return
+	}
+	defer f.Close()
+
+	// El archivo se llama "<id>-<nombre>"; al cliente solo le interesa el nombre
+	name := strings.TrimPrefix(filepath.Base(job.ResultPath), job.ID.String()+"-")
+	contentType := mime.TypeByExtension(filepath.Ext(name))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-%s"`, job.Kind, name))
+	http.ServeContent(w, r, "", *job.FinishedAt, f)
+}
+
+// job busca el trabajo {id}. Un usuario solo ve los suyos; un administrador, todos. Los
+// que no puede ver se tratan como inexistentes.
+func (h *JobHandlers) job(w http.ResponseWriter, r *http.Request) (jobs.Job, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		apierror.HTTPError(w, "ID de trabajo inválido", http.StatusBadRequest)
+		return jobs.Job{}, false
+	}
+	job, err := h.jobs.Get(id)
+	if err != nil || !canAccessJob(r, job) {
+		apierror.HTTPError(w, "Trabajo no encontrado", http.StatusNotFound)
+		return jobs.Job{}, false
+	}
+	return job, true
+}
+
+func canAccessJob(r *http.Request, job jobs.Job) bool {
+	if auth.ScopeFromContext(r.Context()) >= auth.ScopeAdmin {
+		return true
+	}
+	userID, ok := auth.UserFromContext(r.Context())
+	return ok && job.Owner != uuid.Nil && userID == job.Owner
+}
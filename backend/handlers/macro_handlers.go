@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/apierror"
+	"github.com/jannin2/stock-app/backend/database"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+const (
+	// defaultMacroWindow es el periodo que devuelve GET /macro/events sin ?to=: de ayer a
+	// una semana vista.
+	defaultMacroWindow = 8 * 24 * time.Hour
+	// maxMacroWindow limita el periodo de una consulta.
+	maxMacroWindow = 366 * 24 * time.Hour
+)
+
+// GetMacroEvents maneja GET /macro/events?from=2025-01-01&to=2025-01-31&country=US&category=inflation&impact=high:
+// los eventos clave del calendario económico (IPC, decisiones del FOMC, informe de empleo...)
+// entre from y to (incluidos), por hora, para dar contexto a un día de mercado. Sin fechas
+// se devuelven los de ayer a una semana vista. El calendario se sincroniza en cada ejecución
+// del enricher; actual y surprise son null hasta que se publica el dato.
+func (h *StockHandlers) GetMacroEvents(w http.ResponseWriter, r *http.Request) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	from, to, err := parseDateWindow(r, today.AddDate(0, 0, -1), defaultMacroWindow, maxMacroWindow)
+	if err != nil {
+		apierror.HTTPError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filters, err := parseMacroFilters(r)
+	if err != nil {
+		apierror.HTTPError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	events, err := h.dbClient.GetMacroEvents(r.Context(), from, to.AddDate(0, 0, 1), filters)
+	if err != nil {
+		apierror.Internal(w, "Error al obtener el calendario económico", err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, events)
+}
+
+// parseMacroFilters lee ?country= (código ISO de dos letras), ?category= e ?impact=.
+func parseMacroFilters(r *http.Request) (database.MacroEventFilters, error) {
+	query := r.URL.Query()
+	filters := database.MacroEventFilters{
+		Country:  strings.ToUpper(strings.TrimSpace(query.Get("country"))),
+		Category: strings.ToLower(strings.TrimSpace(query.Get("category"))),
+		Impact:   strings.ToLower(strings.TrimSpace(query.Get("impact"))),
+	}
+	if filters.Country != "" && len(filters.Country) != 2 {
+		return filters, fmt.Errorf("el parámetro 'country' debe ser un código de país de dos letras (ej. US)")
+	}
+	if filters.Category != "" && !models.IsValidMacroCategory(filters.Category) {
+		return filters, fmt.Errorf("categoría no soportada: %s (use inflation, rates, jobs, growth u other)", filters.Category)
+	}
+	switch filters.Impact {
+	case "", models.MacroImpactLow, models.MacroImpactMedium, models.MacroImpactHigh:
+	default:
+		return filters, fmt.Errorf("impacto no soportado: %s (use low, medium o high)", filters.Impact)
+	}
+	return filters, nil
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/database"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// macroStockDB registra la consulta de GetMacroEvents.
+type macroStockDB struct {
+	database.StockDB
+	from, to time.Time
+	filters  database.MacroEventFilters
+}
+
+func (db *macroStockDB) GetMacroEvents(ctx context.Context, from, to time.Time, filters database.MacroEventFilters) ([]models.MacroEvent, error) {
+	db.from, db.to, db.filters = from, to, filters
+	return []models.MacroEvent{}, nil
+}
+
+func TestGetMacroEvents(t *testing.T) {
+	db := &macroStockDB{}
+	h := NewStockHandlers(db, nil)
+
+	rr := httptest.NewRecorder()
+	h.GetMacroEvents(rr, httptest.NewRequest(http.MethodGet, "/api/v1/macro/events?from=2025-01-15&to=2025-01-15&country=us&impact=HIGH", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("❌ respuesta inesperada: %d %s", rr.Code, rr.Body.String())
+	}
+	// El día de to se incluye entero.
+	if want := time.Date(2025, 1, 16, 0, 0, 0, 0, time.UTC); !db.from.Equal(want.AddDate(0, 0, -1)) || !db.to.Equal(want) {
+		t.Errorf("❌ periodo consultado %s - %s", db.from, db.to)
+	}
+	if db.filters != (database.MacroEventFilters{Country: "US", Impact: "high"}) {
+		t.Errorf("❌ filtros inesperados: %+v", db.filters)
+	}
+
+	for _, query := range []string{"country=USA", "category=weather", "impact=extreme", "from=15-01-2025"} {
+		rr := httptest.NewRecorder()
+		h.GetMacroEvents(rr, httptest.NewRequest(http.MethodGet, "/api/v1/macro/events?"+query, nil))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("❌ %s: estado %d, se esperaba 400", query, rr.Code)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/apierror"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// GetMarketHeatmap maneja GET /market/heatmap: agregados por sector (capitalización total y
+// variación diaria media ponderada) con sus stocks, listos para pintar un treemap.
+func (h *StockHandlers) GetMarketHeatmap(w http.ResponseWriter, r *http.Request) {
+	sectors, err := h.dbClient.GetMarketHeatmap(r.Context())
+	if err != nil {
+		apierror.Internal(w, "Error al obtener el heatmap del mercado", err)
+		return
+	}
+	if sectors == nil {
+		sectors = []models.HeatmapSector{}
+	}
+	writeJSON(w, r, http.StatusOK, sectors)
+}
+
+const (
+	defaultMoversLimit = 10
+	maxMoversLimit     = 50
+)
+
+// marketMoversResponse es la respuesta de GET /market/movers.
+type marketMoversResponse struct {
+	models.MarketMovers
+	Timezone string `json:"timezone"` // Zona con la que se decidió qué día es hoy
+}
+
+// GetMarketMovers maneja GET /market/movers?limit=10&tz=Europe/Madrid: los stocks que más
+// suben y más bajan hoy. "Hoy" es la fecha actual en la zona de la petición; si ese día no
+// hubo sesión (fin de semana, festivo o antes de la apertura) se usa la sesión anterior.
+func (h *StockHandlers) GetMarketMovers(w http.ResponseWriter, r *http.Request) {
+	limit := defaultMoversLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxMoversLimit {
+			apierror.HTTPError(w, fmt.Sprintf("El parámetro 'limit' debe ser un entero entre 1 y %d", maxMoversLimit), http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	movers, err := h.dbClient.GetMarketMovers(r.Context(), localDate(r.Context(), time.Now()), limit)
+	if err != nil {
+		apierror.Internal(w, "Error al obtener los movers del mercado", err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, marketMoversResponse{MarketMovers: movers, Timezone: LocationFromContext(r.Context()).String()})
+}
+
+// exchangeStatus es una bolsa de GET /market/exchanges con su estado en este momento.
+type exchangeStatus struct {
+	models.Exchange
+	IsOpen     bool   `json:"is_open"`
+	TradingDay string `json:"trading_day"` // Última sesión abierta, fecha local (YYYY-MM-DD)
+}
+
+// GetExchanges maneja GET /market/exchanges: las bolsas soportadas, con el sufijo de sus
+// tickers, su divisa, su horario y si están abiertas ahora. El calendario solo tiene en
+// cuenta los fines de semana, no los festivos.
+func GetExchanges(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, http.StatusOK, exchangeStatuses(time.Now()))
+}
+
+func exchangeStatuses(now time.Time) []exchangeStatus {
+	exchanges := models.Exchanges()
+	statuses := make([]exchangeStatus, 0, len(exchanges))
+	for _, ex := range exchanges {
+		statuses = append(statuses, exchangeStatus{Exchange: ex, IsOpen: ex.IsOpen(now), TradingDay: ex.TradingDay(now).Format("2006-01-02")})
+	}
+	return statuses
+}
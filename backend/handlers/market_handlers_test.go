@@ -0,0 +1,21 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExchangeStatuses(t *testing.T) {
+	// Lunes 2025-01-06 a las 10:00 UTC: Europa ha abierto y Nueva York todavía no.
+	statuses := exchangeStatuses(time.Date(2025, 1, 6, 10, 0, 0, 0, time.UTC))
+	byCode := map[string]exchangeStatus{}
+	for _, s := range statuses {
+		byCode[s.Code] = s
+	}
+	if us := byCode["US"]; us.IsOpen || us.TradingDay != "2025-01-03" {
+		t.Errorf("❌ US: abierta %t, sesión %s; se esperaba cerrada con la sesión del viernes 2025-01-03", us.IsOpen, us.TradingDay)
+	}
+	if xetra := byCode["XETRA"]; !xetra.IsOpen || xetra.TradingDay != "2025-01-06" || xetra.Currency != "EUR" {
+		t.Errorf("❌ XETRA: abierta %t, sesión %s, divisa %s; se esperaba abierta el 2025-01-06 en EUR", xetra.IsOpen, xetra.TradingDay, xetra.Currency)
+	}
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/jannin2/stock-app/backend/apierror"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// GetNotificationSettings maneja GET /me/notifications: horas de silencio y límites por
+// canal del usuario.
+func (h *UserHandlers) GetNotificationSettings(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUser(w, r)
+	if !ok {
+		return
+	}
+	settings, err := h.users.GetNotificationSettings(r.Context(), userID)
+	if err != nil {
+		writeError(w, err, "Error al obtener las preferencias de notificación")
+		return
+	}
+	writeJSON(w, r, http.StatusOK, settings)
+}
+
+// PutNotificationSettings maneja PUT /me/notifications. Las horas de silencio se indican
+// como "HH:MM" en la zona horaria del usuario; las notificaciones que caen dentro se
+// retienen y se envían al terminar. Un límite por canal solo se aplica si es menor que el
+// del servidor (config.NotifyMaxPerHour).
+func (h *UserHandlers) PutNotificationSettings(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUser(w, r)
+	if !ok {
+		return
+	}
+	var settings models.NotificationSettings
+	if !decodeAuthRequest(w, r, &settings) {
+		return
+	}
+	if err := settings.Validate(); err != nil {
+		apierror.HTTPError(w, "Preferencias de notificación inválidas: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := h.users.SaveNotificationSettings(r.Context(), userID, settings); err != nil {
+		writeError(w, err, "Error al guardar las preferencias de notificación")
+		return
+	}
+	writeJSON(w, r, http.StatusOK, settings)
+}
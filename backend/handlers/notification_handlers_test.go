@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/auth"
+	"github.com/jannin2/stock-app/backend/database"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// settingsUserDB guarda en memoria las preferencias de notificación.
+type settingsUserDB struct {
+	database.UserDB
+	settings map[uuid.UUID]models.NotificationSettings
+}
+
+func (db *settingsUserDB) GetNotificationSettings(_ context.Context, userID uuid.UUID) (models.NotificationSettings, error) {
+	return db.settings[userID], nil
+}
+
+func (db *settingsUserDB) SaveNotificationSettings(_ context.Context, userID uuid.UUID, settings models.NotificationSettings) error {
+	db.settings[userID] = settings
+	return nil
+}
+
+func TestPutNotificationSettings(t *testing.T) {
+	db := &settingsUserDB{settings: map[uuid.UUID]models.NotificationSettings{}}
+	h := NewUserHandlers(db, nil, nil)
+	userID := uuid.New()
+	serve := func(handler http.HandlerFunc, method, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, "/me/notifications", strings.NewReader(body))
+		req = req.WithContext(auth.WithUser(req.Context(), userID))
+		rr := httptest.NewRecorder()
+		handler(rr, req)
+		return rr
+	}
+
+	for _, body := range []string{
+		`{"quiet_hours_start":"22:00"}`,
+		`{"quiet_hours_start":"22:00","quiet_hours_end":"7am"}`,
+		`{"timezone":"Europe/Atlantis"}`,
+	} {
+		if rr := serve(h.PutNotificationSettings, http.MethodPut, body); rr.Code != http.StatusBadRequest {
+			t.Errorf("❌ %s: estado %d, se esperaba 400", body, rr.Code)
+		}
+	}
+
+	rr := serve(h.PutNotificationSettings, http.MethodPut, `{"quiet_hours_start":"22:00","quiet_hours_end":"07:00","timezone":"Europe/Madrid","max_per_hour":{"email":3}}`)
+	if rr.Code != http.StatusOK || db.settings[userID].MaxPerHour[models.NotificationChannelEmail] != 3 {
+		t.Fatalf("❌ estado %d: %s", rr.Code, rr.Body)
+	}
+	if rr := serve(h.GetNotificationSettings, http.MethodGet, ""); !strings.Contains(rr.Body.String(), `"quiet_hours_end":"07:00"`) {
+		t.Errorf("❌ GET /me/notifications inesperado: %s", rr.Body)
+	}
+}
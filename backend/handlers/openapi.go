@@ -0,0 +1,266 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/apierror"
+	"github.com/jannin2/stock-app/backend/auth"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// openAPISchema es un objeto Schema de OpenAPI; se construye como mapa para serializar
+// solo los campos usados.
+type openAPISchema map[string]interface{}
+
+// OpenAPI maneja GET /openapi.json: la especificación OpenAPI 3 de todas las rutas de
+// routes bajo /api/v1. Las rutas se leen del router, así que ninguna queda fuera; el resumen,
+// el scope y los parámetros de cada una vienen de openAPIRoutes, y los esquemas de las
+// respuestas se generan por reflexión de los tipos que devuelven los handlers. Se genera en
+// la primera petición, cuando el router ya está completo.
+func OpenAPI(routes chi.Routes) http.HandlerFunc {
+	var once sync.Once
+	var spec []byte
+	var err error
+	return func(w http.ResponseWriter, r *http.Request) {
+		once.Do(func() { spec, err = json.Marshal(buildOpenAPI(routes)) })
+		if err != nil {
+			apierror.HTTPError(w, "Error al generar la especificación OpenAPI", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(spec)
+	}
+}
+
+// swaggerUIPage carga Swagger UI desde un CDN y le indica la especificación de /openapi.json,
+// relativa a la página para que funcione detrás de cualquier prefijo.
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="es">
+<head>
+  <meta charset="utf-8">
+  <title>stock-app API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js" crossorigin></script>
+  <script>
+    window.onload = () => { window.ui = SwaggerUIBundle({ url: "openapi.json", dom_id: "#swagger-ui" }); };
+  </script>
+</body>
+</html>
+`
+
+// SwaggerUI maneja GET /docs: la documentación interactiva de la especificación de OpenAPI.
+func SwaggerUI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(swaggerUIPage))
+}
+
+// openAPIPrefix es el prefijo de las rutas documentadas.
+const openAPIPrefix = "/api/v1"
+
+var pathParamPattern = regexp.MustCompile(`\{([^}:]+)(:[^}]*)?\}`)
+
+// openAPIRouteKey normaliza un método y un patrón de chi en la clave de openAPIRoutes:
+// "GET /api/v1/stocks" para el patrón "/api/v1/stocks/".
+func openAPIRouteKey(method, pattern string) string {
+	if len(pattern) > 1 {
+		pattern = strings.TrimSuffix(pattern, "/")
+	}
+	return method + " " + pattern
+}
+
+// OpenAPIRouteKeys devuelve las claves de openAPIRoutes de las rutas de routes bajo /api/v1,
+// para comprobar que la documentación las cubre todas.
+func OpenAPIRouteKeys(routes chi.Routes) []string {
+	var keys []string
+	chi.Walk(routes, func(method, pattern string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		if strings.HasPrefix(pattern, openAPIPrefix+"/") {
+			keys = append(keys, openAPIRouteKey(method, pattern))
+		}
+		return nil
+	})
+	sort.Strings(keys)
+	return keys
+}
+
+// DocumentedRouteKeys devuelve las claves de openAPIRoutes.
+func DocumentedRouteKeys() []string {
+	keys := make([]string, 0, len(openAPIRoutes))
+	for key := range openAPIRoutes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func buildOpenAPI(routes chi.Routes) map[string]interface{} {
+	paths := map[string]map[string]interface{}{}
+	for _, key := range OpenAPIRouteKeys(routes) {
+		method, pattern, _ := strings.Cut(key, " ")
+		doc, ok := openAPIRoutes[key]
+		if !ok {
+			doc = openAPIRoute{summary: key} // Sin documentar: al menos aparece
+		}
+		path := pathParamPattern.ReplaceAllString(strings.TrimPrefix(pattern, openAPIPrefix), "{$1}")
+		if paths[path] == nil {
+			paths[path] = map[string]interface{}{}
+		}
+		paths[path][strings.ToLower(method)] = doc.operation(path)
+	}
+
+	schemas := map[string]openAPISchema{
+		"Stock":                   reflectSchema(reflect.TypeOf(models.Stock{})),
+		"StockPage":               pageSchema(reflect.TypeOf(paginatedResponse{})),
+		"StockCursorPage":         pageSchema(reflect.TypeOf(cursorPage{})),
+		"StockSchema":             reflectSchema(reflect.TypeOf(stockSchema{})),
+		"ClientConfig":            reflectSchema(reflect.TypeOf(clientConfig{})),
+		"ExchangeStatus":          reflectSchema(reflect.TypeOf(exchangeStatus{})),
+		"ScreenPreset":            reflectSchema(reflect.TypeOf(screenPreset{})),
+		"StreamPoll":              reflectSchema(reflect.TypeOf(pollResponse{})),
+		"Watchlist":               reflectSchema(reflect.TypeOf(models.Watchlist{})),
+		"WatchlistMember":         reflectSchema(reflect.TypeOf(models.WatchlistMember{})),
+		"WatchlistTickersRequest": reflectSchema(reflect.TypeOf(watchlistTickersRequest{})),
+		"WatchlistMemberRequest":  reflectSchema(reflect.TypeOf(watchlistMemberRequest{})),
+		"ErrorResponse":           reflectSchema(reflect.TypeOf(apierror.Error{})),
+		"Warning": {
+			"type":        "object",
+			"description": "Aviso de una parte obsoleta de la API usada en la petición (ver las cabeceras Deprecation y Sunset)",
+			"properties": map[string]openAPISchema{
+				"type":    {"type": "string", "enum": []string{"deprecation"}},
+				"message": {"type": "string"},
+				"sunset":  {"type": "string", "format": "date-time"},
+				"link":    {"type": "string", "format": "uri"},
+			},
+		},
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]string{
+			"title":       "stock-app API",
+			"version":     "1",
+			"description": "Recomendaciones de analistas enriquecidas con datos de mercado. Los campos marcados como solo para administradores se omiten en las demás respuestas.",
+		},
+		"servers": []map[string]string{{"url": openAPIPrefix}},
+		"paths":   paths,
+		"components": map[string]interface{}{
+			"schemas": schemas,
+			"responses": map[string]interface{}{
+				"Error": map[string]interface{}{
+					"description": "Error: code es estable (not_found, quota_exceeded, ...), message es para el usuario y details depende del error",
+					"content":     map[string]interface{}{"application/json": map[string]openAPISchema{"schema": {"$ref": "#/components/schemas/ErrorResponse"}}},
+				},
+			},
+			"securitySchemes": map[string]interface{}{
+				"user":  map[string]string{"type": "http", "scheme": "bearer", "description": "Token de sesión o clave de API del usuario"},
+				"admin": map[string]string{"type": "apiKey", "in": "header", "name": auth.AdminKeyHeader},
+			},
+		},
+	}
+}
+
+// pageSchema describe un sobre de paginación cuyo campo data contiene stocks.
+func pageSchema(t reflect.Type) openAPISchema {
+	schema := reflectSchema(t)
+	properties := schema["properties"].(map[string]openAPISchema)
+	properties["data"] = openAPISchema{"type": "array", "items": openAPISchema{"$ref": "#/components/schemas/Stock"}}
+	properties["degraded"] = openAPISchema{"type": "boolean", "description": "Servida desde la última copia porque la base de datos no está disponible"}
+	properties["warnings"] = openAPISchema{"type": "array", "items": openAPISchema{"$ref": "#/components/schemas/Warning"}}
+	return schema
+}
+
+var (
+	nullFloatType = reflect.TypeOf(models.NullFloat64{})
+	nullTimeType  = reflect.TypeOf(models.NullTime{})
+	uuidType      = reflect.TypeOf(uuid.UUID{})
+	durationType  = reflect.TypeOf(time.Duration(0))
+)
+
+// reflectSchema describe el JSON que produce encoding/json (o marshalScoped) para t: los
+// campos de las etiquetas json, los structs embebidos aplanados, y los de scope superior a
+// público marcados en su descripción.
+func reflectSchema(t reflect.Type) openAPISchema {
+	switch t {
+	case nullFloatType:
+		return openAPISchema{"type": "number", "nullable": true}
+	case nullTimeType:
+		return openAPISchema{"type": "string", "format": "date-time", "nullable": true}
+	case timeType:
+		return openAPISchema{"type": "string", "format": "date-time"}
+	case uuidType:
+		return openAPISchema{"type": "string", "format": "uuid"}
+	case durationType:
+		return openAPISchema{"type": "integer", "description": "Duración en nanosegundos"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		schema := reflectSchema(t.Elem())
+		schema["nullable"] = true
+		return schema
+	case reflect.Bool:
+		return openAPISchema{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return openAPISchema{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return openAPISchema{"type": "number"}
+	case reflect.String:
+		return openAPISchema{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return openAPISchema{"type": "string", "format": "byte"}
+		}
+		return openAPISchema{"type": "array", "items": reflectSchema(t.Elem())}
+	case reflect.Map:
+		return openAPISchema{"type": "object", "additionalProperties": reflectSchema(t.Elem())}
+	case reflect.Struct:
+		if hasCustomMarshaling(t) {
+			return openAPISchema{} // Cualquier valor: su formato lo decide el propio tipo
+		}
+		properties := map[string]openAPISchema{}
+		addStructProperties(t, properties)
+		return openAPISchema{"type": "object", "properties": properties}
+	}
+	return openAPISchema{} // interface{}: cualquier valor
+}
+
+func addStructProperties(t reflect.Type, properties map[string]openAPISchema) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" || !field.IsExported() && !field.Anonymous {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct && !hasCustomMarshaling(embedded) {
+				addStructProperties(embedded, properties)
+				continue
+			}
+		}
+		if name == "" {
+			name = field.Name
+		}
+		schema := reflectSchema(field.Type)
+		if scope := auth.ParseScope(field.Tag.Get("scope")); scope > auth.ScopePublic {
+			schema["description"] = "Solo con scope " + scope.String()
+		}
+		properties[name] = schema
+	}
+}
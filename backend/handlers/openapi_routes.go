@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/jannin2/stock-app/backend/auth"
+)
+
+// openAPIRoute documenta una ruta de la API para la especificación de OpenAPI.
+type openAPIRoute struct {
+	summary  string
+	scope    auth.Scope     // Scope mínimo que exige la ruta
+	query    []openAPIParam // Parámetros de la query; los de la ruta se leen del patrón
+	response string         // Esquema de components de la respuesta correcta; "" = sin describir
+	array    bool           // La respuesta es un array de response
+	status   string         // Código de la respuesta correcta; "" = 200, o 2XX si no hay response
+	body     string         // Esquema de components del cuerpo de la petición; "" = sin describir
+}
+
+// openAPIParam es un parámetro de la query.
+type openAPIParam struct {
+	name, description string
+	schema            openAPISchema
+}
+
+// operation devuelve el objeto Operation de la ruta, cuyo path en la especificación es path.
+func (route openAPIRoute) operation(path string) map[string]interface{} {
+	var parameters []map[string]interface{}
+	for _, match := range pathParamPattern.FindAllStringSubmatch(path, -1) {
+		parameters = append(parameters, map[string]interface{}{
+			"name": match[1], "in": "path", "required": true, "schema": openAPISchema{"type": "string"},
+		})
+	}
+	for _, p := range route.query {
+		parameters = append(parameters, map[string]interface{}{
+			"name": p.name, "in": "query", "description": p.description, "schema": p.schema,
+		})
+	}
+
+	success := map[string]interface{}{"description": "Respuesta correcta"}
+	status := route.status
+	if route.response != "" {
+		schema := openAPISchema{"$ref": "#/components/schemas/" + route.response}
+		if route.array {
+			schema = openAPISchema{"type": "array", "items": schema}
+		}
+		success["content"] = map[string]interface{}{"application/json": map[string]interface{}{"schema": schema}}
+		if status == "" {
+			status = "200"
+		}
+	}
+	if status == "" {
+		status = "2XX"
+	}
+
+	segment, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	op := map[string]interface{}{
+		"summary": route.summary,
+		"tags":    []string{segment},
+		"responses": map[string]interface{}{
+			status:    success,
+			"default": map[string]string{"$ref": "#/components/responses/Error"},
+		},
+	}
+	if parameters != nil {
+		op["parameters"] = parameters
+	}
+	if route.body != "" {
+		op["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content": map[string]interface{}{"application/json": map[string]interface{}{
+				"schema": openAPISchema{"$ref": "#/components/schemas/" + route.body},
+			}},
+		}
+	}
+	switch route.scope {
+	case auth.ScopeUser:
+		// Un administrador también puede usarlas
+		op["security"] = []map[string][]string{{"user": {}}, {"admin": {}}}
+	case auth.ScopeAdmin:
+		op["security"] = []map[string][]string{{"admin": {}}}
+	}
+	return op
+}
+
+func intParam(name, description string) openAPIParam {
+	return openAPIParam{name, description, openAPISchema{"type": "integer", "minimum": 1}}
+}
+
+func stringParam(name, description string) openAPIParam {
+	return openAPIParam{name, description, openAPISchema{"type": "string"}}
+}
+
+func enumParam(name, description string, values ...string) openAPIParam {
+	return openAPIParam{name, description, openAPISchema{"type": "string", "enum": values}}
+}
+
+// stockQueryParams son los parámetros de búsqueda, orden y filtro comunes a GET /stocks y
+// GET /stocks/export.
+func stockQueryParams() []openAPIParam {
+	return append([]openAPIParam{
+		stringParam("sortBy", "Campo o lista \"campo[:asc|desc],...\" (ver GET /stocks/schema)"),
+		enumParam("order", "Dirección de los campos de sortBy que no la indican", "asc", "desc"),
+		stringParam("screen", "Pantalla predefinida (ver GET /screens/presets)"),
+	}, stockFilterQueryParams()...)
+}
+
+// stockFilterQueryParams son la búsqueda y los filtros, generados a partir de los mismos
+// filtros que valida parseStockFilters.
+func stockFilterQueryParams() []openAPIParam {
+	params := []openAPIParam{stringParam("search", "Busca en el ticker y la compañía")}
+	for _, p := range stockFilterParams {
+		schema := openAPISchema{"type": "number", "minimum": p.min, "maximum": p.max}
+		if p.deprecated {
+			schema["deprecated"] = true
+		}
+		params = append(params, openAPIParam{p.name, p.field + " " + p.op, schema})
+	}
+	for _, p := range stockTextFilterParams {
+		params = append(params, openAPIParam{p.name, p.name + " igual, sin distinguir mayúsculas", openAPISchema{"type": "string", "maxLength": maxTextFilterLength}})
+	}
+	return params
+}
+
+var (
+	limitParam  = intParam("limit", "Elementos por página")
+	offsetParam = openAPIParam{"offset", "Elementos a omitir", openAPISchema{"type": "integer", "minimum": 0}}
+	tzParam     = stringParam("tz", "Zona horaria IANA que decide qué día es hoy (ej. Europe/Madrid)")
+)
+
+// openAPIRoutes documenta cada ruta de la API, por método y patrón sin la barra final
+// (ver openAPIRouteKey). Una prueba de api comprueba que cubre exactamente las rutas del
+// router: al añadir una ruta hay que documentarla aquí.
+var openAPIRoutes = map[string]openAPIRoute{
+	// Stocks
+	"GET /api/v1/stocks": {
+		summary:  "Lista los stocks con búsqueda, filtros, orden y paginación por offset o por cursor (?cursor=, responde StockCursorPage)",
+		query:    append([]openAPIParam{limitParam, offsetParam, stringParam("cursor", "next_cursor de la página anterior; vacío para la primera"), enumParam("view", "Campos de cada stock", "compact", "full")}, stockQueryParams()...),
+		response: "StockPage",
+	},
+	"POST /api/v1/stocks":        {summary: "Crea un stock", scope: auth.ScopeAdmin, body: "Stock", response: "Stock", status: "201"},
+	"GET /api/v1/stocks/{id}":    {summary: "Devuelve un stock por su id", query: []openAPIParam{enumParam("include", "Añade el origen de cada campo", "provenance")}, response: "Stock"},
+	"PUT /api/v1/stocks/{id}":    {summary: "Actualiza un stock", scope: auth.ScopeAdmin, body: "Stock", response: "Stock"},
+	"DELETE /api/v1/stocks/{id}": {summary: "Elimina un stock", scope: auth.ScopeAdmin},
+	"POST /api/v1/stocks/bulk":   {summary: "Crea o actualiza stocks en bloque (importación en segundo plano)", scope: auth.ScopeAdmin},
+	"GET /api/v1/stocks/export": {
+		summary: "Exporta los stocks como archivo CSV o xlsx, con la búsqueda, los filtros y el orden de GET /stocks",
+		query: append([]openAPIParam{
+			enumParam("format", "Formato del archivo", exportFormatCSV, exportFormatXLSX),
+			intParam("limit", "Exportación CSV por páginas sin filtros: stocks por página"),
+			stringParam("page_token", "X-Next-Page-Token de la página anterior"),
+		}, stockQueryParams()...),
+	},
+	"GET /api/v1/stocks/schema":                     {summary: "Describe los campos por los que se puede filtrar y ordenar", response: "StockSchema"},
+	"GET /api/v1/stocks/aggregates":                 {summary: "Agregados de los stocks que cumplen la búsqueda y los filtros", query: append(stockFilterQueryParams(), openAPIParam{"sample", "Calcula sobre una muestra", openAPISchema{"type": "boolean"}})},
+	"GET /api/v1/stocks/recommended":                {summary: "Los stocks mejor puntuados; con group_by, los mejores de cada grupo", query: []openAPIParam{intParam("limit", "Stocks, o stocks por grupo con group_by"), stringParam("group_by", "Agrupaciones separadas por comas: sector, market_cap_tier o dividend"), enumParam("view", "Campos de cada stock", "compact", "full")}},
+	"GET /api/v1/stocks/{id}/history":               {summary: "Histórico de métricas de un stock"},
+	"GET /api/v1/stocks/{id}/ohlc":                  {summary: "Velas diarias (apertura, máximo, mínimo y cierre) de un stock"},
+	"GET /api/v1/stocks/{ticker}/options-summary":   {summary: "Resumen de la cadena de opciones de un ticker"},
+	"GET /api/v1/stocks/{ticker}/corporate-actions": {summary: "Splits, fusiones y cambios de ticker de un ticker"},
+
+	// Mercado y análisis
+	"GET /api/v1/status":                {summary: "Estado de la última ejecución del enriquecimiento y de los proveedores"},
+	"GET /api/v1/config":                {summary: "Configuración no secreta para el frontend", response: "ClientConfig"},
+	"GET /api/v1/quotes":                {summary: "Cotizaciones actuales de varios tickers", query: []openAPIParam{stringParam("tickers", "Tickers separados por comas")}},
+	"GET /api/v1/stream":                {summary: "WebSocket con las actualizaciones de los tickers suscritos", query: []openAPIParam{stringParam("tickers", "Tickers separados por comas"), intParam("since_seq", "Reanuda después de esta secuencia")}, status: "101"},
+	"GET /api/v1/stream/poll":           {summary: "Las actualizaciones del stream por long-polling", query: []openAPIParam{stringParam("tickers", "Tickers separados por comas"), intParam("since_seq", "Secuencia de la última respuesta"), intParam("timeout", "Espera máxima en segundos")}, response: "StreamPoll"},
+	"GET /api/v1/market/heatmap":        {summary: "Capitalización y variación diaria por sector, con sus stocks"},
+	"GET /api/v1/market/exchanges":      {summary: "Bolsas soportadas y si están abiertas ahora", response: "ExchangeStatus", array: true},
+	"GET /api/v1/market/movers":         {summary: "Los stocks que más suben y más bajan hoy", query: []openAPIParam{limitParam, tzParam}},
+	"GET /api/v1/ipos":                  {summary: "Calendario de salidas a bolsa"},
+	"GET /api/v1/macro/events":          {summary: "Calendario económico (IPC, FOMC, empleo...)"},
+	"GET /api/v1/screens/presets":       {summary: "Pantallas predefinidas para GET /stocks?screen=", response: "ScreenPreset", array: true},
+	"GET /api/v1/analytics/correlation": {summary: "Correlación de los rendimientos diarios de varios tickers", query: []openAPIParam{tzParam}},
+	"GET /api/v1/analytics/brokerages":  {summary: "Estadísticas de las recomendaciones por casa de bolsa"},
+	"POST /api/v1/analytics/projection": {summary: "Proyecta el valor de una cartera"},
+	"POST /api/v1/scoring/what-if":      {summary: "Puntúa un stock con unos pesos hipotéticos"},
+
+	// Cuentas
+	"POST /api/v1/auth/register":            {summary: "Crea una cuenta"},
+	"POST /api/v1/auth/login":               {summary: "Inicia sesión y devuelve un token"},
+	"POST /api/v1/auth/verify-email":        {summary: "Confirma el e-mail de una cuenta"},
+	"POST /api/v1/auth/verify-email/resend": {summary: "Reenvía el e-mail de verificación"},
+	"POST /api/v1/auth/password/forgot":     {summary: "Envía un enlace para restablecer la contraseña"},
+	"POST /api/v1/auth/password/reset":      {summary: "Restablece la contraseña"},
+
+	"GET /api/v1/jobs/{id}":        {summary: "Estado de un trabajo en segundo plano", scope: auth.ScopeUser},
+	"GET /api/v1/jobs/{id}/result": {summary: "Descarga el resultado de un trabajo", scope: auth.ScopeUser},
+
+	"POST /api/v1/webhooks/incoming/karenai": {summary: "Aviso de actualización del proveedor de recomendaciones, autenticado con su firma"},
+	"POST /api/v1/webhooks":                  {summary: "Registra un webhook", scope: auth.ScopeUser},
+	"GET /api/v1/webhooks":                   {summary: "Lista los webhooks del usuario", scope: auth.ScopeUser},
+	"DELETE /api/v1/webhooks/{id}":           {summary: "Elimina un webhook", scope: auth.ScopeUser},
+	"POST /api/v1/webhooks/{id}/test":        {summary: "Envía un evento de prueba a un webhook", scope: auth.ScopeUser},
+
+	"POST /api/v1/alerts":        {summary: "Crea una alerta", scope: auth.ScopeUser},
+	"GET /api/v1/alerts":         {summary: "Lista las alertas del usuario", scope: auth.ScopeUser},
+	"GET /api/v1/alerts/{id}":    {summary: "Devuelve una alerta", scope: auth.ScopeUser},
+	"PUT /api/v1/alerts/{id}":    {summary: "Actualiza una alerta", scope: auth.ScopeUser},
+	"DELETE /api/v1/alerts/{id}": {summary: "Elimina una alerta", scope: auth.ScopeUser},
+
+	"POST /api/v1/watchlists":                         {summary: "Crea una watchlist", scope: auth.ScopeUser, response: "Watchlist", status: "201"},
+	"GET /api/v1/watchlists":                          {summary: "Lista las watchlists del usuario y las compartidas con él", scope: auth.ScopeUser, response: "Watchlist", array: true},
+	"GET /api/v1/watchlists/{id}":                     {summary: "Devuelve una watchlist con sus stocks", scope: auth.ScopeUser},
+	"PATCH /api/v1/watchlists/{id}":                   {summary: "Añade y quita tickers (propietario y editores)", scope: auth.ScopeUser, body: "WatchlistTickersRequest", response: "Watchlist"},
+	"DELETE /api/v1/watchlists/{id}":                  {summary: "Elimina una watchlist (solo el propietario)", scope: auth.ScopeUser},
+	"GET /api/v1/watchlists/{id}/members":             {summary: "Lista el propietario y los miembros de una watchlist", scope: auth.ScopeUser, response: "WatchlistMember", array: true},
+	"PUT /api/v1/watchlists/{id}/members":             {summary: "Comparte la watchlist o cambia el rol de un miembro (solo el propietario)", scope: auth.ScopeUser, body: "WatchlistMemberRequest", response: "WatchlistMember"},
+	"DELETE /api/v1/watchlists/{id}/members/{userID}": {summary: "Deja de compartir la watchlist con un miembro, o la abandona", scope: auth.ScopeUser},
+
+	"GET /api/v1/me/data":                 {summary: "Todos los datos del usuario (portabilidad)", scope: auth.ScopeUser},
+	"GET /api/v1/me/export":               {summary: "Solicita una exportación de los datos del usuario", scope: auth.ScopeUser},
+	"GET /api/v1/me/export/{id}":          {summary: "Estado de una exportación de datos", scope: auth.ScopeUser},
+	"GET /api/v1/me/export/{id}/download": {summary: "Descarga una exportación de datos", scope: auth.ScopeUser},
+	"DELETE /api/v1/me":                   {summary: "Borra la cuenta; los datos se purgan tras el periodo de retención", scope: auth.ScopeUser},
+	"GET /api/v1/me/quotas":               {summary: "Cuotas del usuario y su uso", scope: auth.ScopeUser},
+	"POST /api/v1/me/watchlists":          {summary: "Crea una watchlist", scope: auth.ScopeUser},
+	"GET /api/v1/me/notifications":        {summary: "Preferencias de notificación", scope: auth.ScopeUser},
+	"PUT /api/v1/me/notifications":        {summary: "Actualiza las preferencias de notificación", scope: auth.ScopeUser},
+	"POST /api/v1/me/devices":             {summary: "Registra un dispositivo para notificaciones push", scope: auth.ScopeUser},
+	"GET /api/v1/me/devices":              {summary: "Lista los dispositivos registrados", scope: auth.ScopeUser},
+	"DELETE /api/v1/me/devices/{id}":      {summary: "Elimina un dispositivo", scope: auth.ScopeUser},
+	"GET /api/v1/me/2fa":                  {summary: "Estado del segundo factor", scope: auth.ScopeUser},
+	"POST /api/v1/me/2fa/enroll":          {summary: "Empieza a activar el segundo factor", scope: auth.ScopeUser},
+	"POST /api/v1/me/2fa/confirm":         {summary: "Confirma la activación del segundo factor", scope: auth.ScopeUser},
+	"POST /api/v1/me/2fa/verify":          {summary: "Verifica el segundo factor y devuelve un token de sesión para acciones sensibles", scope: auth.ScopeUser},
+	"DELETE /api/v1/me/2fa":               {summary: "Desactiva el segundo factor", scope: auth.ScopeUser},
+	"POST /api/v1/me/2fa/recovery-codes":  {summary: "Genera nuevos códigos de recuperación", scope: auth.ScopeUser},
+	"POST /api/v1/me/api-key":             {summary: "Crea una clave de API", scope: auth.ScopeUser},
+	"DELETE /api/v1/me/portfolios/{id}":   {summary: "Elimina una cartera", scope: auth.ScopeUser},
+
+	// Administración
+	"GET /api/v1/admin/config":                          {summary: "Configuración recargable vigente", scope: auth.ScopeAdmin},
+	"POST /api/v1/admin/config/reload":                  {summary: "Recarga la configuración", scope: auth.ScopeAdmin},
+	"PUT /api/v1/admin/stocks/{ticker}/enrichment-tier": {summary: "Fija el tier de enriquecimiento de un stock", scope: auth.ScopeAdmin},
+	"GET /api/v1/admin/stocks/{ticker}/payloads":        {summary: "Últimas respuestas crudas de los proveedores para un ticker", scope: auth.ScopeAdmin},
+	"GET /api/v1/admin/providers/stats":                 {summary: "Latencias y errores de los proveedores", scope: auth.ScopeAdmin},
+	"GET /api/v1/admin/enrich/runs":                     {summary: "Ejecuciones del enriquecimiento", scope: auth.ScopeAdmin},
+	"GET /api/v1/admin/enrich/runs/{id}":                {summary: "Una ejecución del enriquecimiento", scope: auth.ScopeAdmin},
+	"GET /api/v1/admin/enrich/runs/{id}/details":        {summary: "Resultado por ticker de una ejecución", scope: auth.ScopeAdmin},
+	"GET /api/v1/admin/scoring/rules":                   {summary: "Reglas de puntuación", scope: auth.ScopeAdmin},
+	"PUT /api/v1/admin/scoring/rules":                   {summary: "Guarda las reglas de puntuación", scope: auth.ScopeAdmin},
+	"GET /api/v1/admin/import-mappings":                 {summary: "Mapeos de importación", scope: auth.ScopeAdmin},
+	"GET /api/v1/admin/import-mappings/{name}":          {summary: "Un mapeo de importación", scope: auth.ScopeAdmin},
+	"PUT /api/v1/admin/import-mappings/{name}":          {summary: "Guarda un mapeo de importación", scope: auth.ScopeAdmin},
+	"DELETE /api/v1/admin/import-mappings/{name}":       {summary: "Elimina un mapeo de importación", scope: auth.ScopeAdmin},
+	"GET /api/v1/admin/audit":                           {summary: "Registro de auditoría", scope: auth.ScopeAdmin},
+
+	// Documentación
+	"GET /api/v1/openapi.json": {summary: "Esta especificación"},
+	"GET /api/v1/docs":         {summary: "Documentación interactiva (Swagger UI)"},
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestOpenAPI(t *testing.T) {
+	root := chi.NewRouter()
+	root.Route("/api/v1", func(r chi.Router) {
+		r.Get("/stocks/{id}", GetStockSchema)
+		r.Get("/admin/config", GetStockSchema)
+		r.Get("/openapi.json", OpenAPI(root))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/openapi.json", nil)
+	rec := httptest.NewRecorder()
+	root.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("❌ Código %d: %s", rec.Code, rec.Body)
+	}
+
+	var spec struct {
+		OpenAPI string `json:"openapi"`
+		Paths   map[string]map[string]struct {
+			Parameters []struct {
+				Name string `json:"name"`
+				In   string `json:"in"`
+			} `json:"parameters"`
+			Responses map[string]json.RawMessage `json:"responses"`
+			Security  []map[string][]string      `json:"security"`
+		} `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]struct {
+					Type        string `json:"type"`
+					Nullable    bool   `json:"nullable"`
+					Description string `json:"description"`
+				} `json:"properties"`
+			} `json:"schemas"`
+			Responses map[string]json.RawMessage `json:"responses"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &spec); err != nil {
+		t.Fatalf("❌ JSON inválido: %v", err)
+	}
+	if spec.OpenAPI != "3.0.3" {
+		t.Errorf("❌ openapi = %q", spec.OpenAPI)
+	}
+
+	byID := spec.Paths["/stocks/{id}"]["get"]
+	if len(byID.Parameters) == 0 || byID.Parameters[0].Name != "id" || byID.Parameters[0].In != "path" {
+		t.Errorf("❌ Falta el parámetro de ruta id: %+v", byID.Parameters)
+	}
+	if _, ok := byID.Responses["default"]; !ok {
+		t.Error("❌ Falta la respuesta de error por defecto")
+	}
+	if _, ok := spec.Paths["/admin/config"]["get"]; !ok || len(spec.Paths["/admin/config"]["get"].Security) != 1 {
+		t.Errorf("❌ La ruta de administración debería exigir el esquema admin: %+v", spec.Paths["/admin/config"])
+	}
+
+	stock := spec.Components.Schemas["Stock"].Properties
+	if stock["ticker"].Type != "string" {
+		t.Errorf("❌ Stock.ticker = %+v", stock["ticker"])
+	}
+	if p := stock["provider_errors"]; p.Description == "" {
+		t.Errorf("❌ Stock.provider_errors debería indicar que es solo de administrador: %+v", p)
+	}
+	if resp, ok := spec.Components.Responses["Error"]; !ok || !strings.Contains(string(resp), "#/components/schemas/ErrorResponse") {
+		t.Errorf("❌ La respuesta Error de components debería usar el esquema ErrorResponse: %s", resp)
+	}
+}
@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jannin2/stock-app/backend/apierror"
+	"github.com/jannin2/stock-app/backend/database"
+)
+
+// GetOptionsSummary maneja GET /stocks/{ticker}/options-summary: devuelve el último resumen
+// de la cadena de opciones del ticker (ratio put/call y volatilidad implícita). Solo existe
+// si el paso "options" está en ENRICHMENT_STEPS.
+func (h *StockHandlers) GetOptionsSummary(w http.ResponseWriter, r *http.Request) {
+	ticker := strings.ToUpper(chi.URLParam(r, "ticker"))
+	summary, err := h.dbClient.GetOptionsSummary(r.Context(), ticker)
+	if errors.Is(err, database.ErrOptionsSummaryNotFound) {
+		apierror.HTTPError(w, fmt.Sprintf("No hay resumen de opciones para %s", ticker), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		apierror.Internal(w, "Error al obtener el resumen de opciones", err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, summary)
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jannin2/stock-app/backend/database"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// optionsStockDB devuelve un resumen de opciones solo para AAPL.
+type optionsStockDB struct {
+	database.StockDB
+}
+
+func (optionsStockDB) GetOptionsSummary(ctx context.Context, ticker string) (models.OptionsSummary, error) {
+	if ticker != "AAPL" {
+		return models.OptionsSummary{}, database.ErrOptionsSummaryNotFound
+	}
+	return models.OptionsSummary{Ticker: "AAPL", Source: "finnhub", PutCallRatio: models.NewNullFloat64(0.75)}, nil
+}
+
+func TestGetOptionsSummary(t *testing.T) {
+	h := NewStockHandlers(optionsStockDB{}, nil)
+	get := func(ticker string) *httptest.ResponseRecorder {
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("ticker", ticker)
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/stocks/"+ticker+"/options-summary", nil)
+		rr := httptest.NewRecorder()
+		h.GetOptionsSummary(rr, req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx)))
+		return rr
+	}
+
+	rr := get("aapl")
+	var summary models.OptionsSummary
+	if rr.Code != http.StatusOK || json.Unmarshal(rr.Body.Bytes(), &summary) != nil || summary.PutCallRatio.Float64 != 0.75 {
+		t.Errorf("❌ respuesta inesperada: %d %s", rr.Code, rr.Body.String())
+	}
+	if rr := get("MSFT"); rr.Code != http.StatusNotFound {
+		t.Errorf("❌ estado %d sin resumen, se esperaba 404", rr.Code)
+	}
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jannin2/stock-app/backend/apierror"
+	"github.com/jannin2/stock-app/backend/config"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// providerPayloadView es una respuesta guardada tal como se muestra a los admins: el cuerpo
+// va como JSON si lo es y como texto si no (p. ej. errores HTML o respuestas truncadas).
+type providerPayloadView struct {
+	Provider   string      `json:"provider"`
+	Endpoint   string      `json:"endpoint"`
+	StatusCode int         `json:"status_code"`
+	FetchedAt  time.Time   `json:"fetched_at"`
+	Truncated  bool        `json:"truncated,omitempty"`
+	Body       interface{} `json:"body"`
+}
+
+// providerPayloadsResponse es la respuesta de GET /admin/stocks/{ticker}/payloads.
+type providerPayloadsResponse struct {
+	Ticker    string                `json:"ticker"`
+	Retention string                `json:"retention"` // Cuánto se conservan, ej. "72h0m0s"
+	Payloads  []providerPayloadView `json:"payloads"`
+}
+
+// GetProviderPayloads maneja GET /admin/stocks/{ticker}/payloads: devuelve la última
+// respuesta cruda de cada endpoint de Finnhub y Alpha Vantage para el ticker, para
+// rastrear discrepancias de datos sin volver a llamar a los proveedores.
+func (h *StockHandlers) GetProviderPayloads(w http.ResponseWriter, r *http.Request) {
+	ticker := strings.ToUpper(chi.URLParam(r, "ticker"))
+	payloads, err := h.dbClient.GetProviderPayloads(r.Context(), ticker)
+	if err != nil {
+		apierror.Internal(w, "Error al obtener las respuestas de los proveedores", err)
+		return
+	}
+
+	resp := providerPayloadsResponse{Ticker: ticker, Retention: config.Current().ProviderPayloadRetention.String(),
+		Payloads: make([]providerPayloadView, 0, len(payloads))}
+	for _, p := range payloads {
+		resp.Payloads = append(resp.Payloads, providerPayloadView{Provider: p.Provider, Endpoint: p.Endpoint,
+			StatusCode: p.StatusCode, FetchedAt: p.FetchedAt, Truncated: p.Truncated, Body: payloadBody(p)})
+	}
+	writeJSON(w, r, http.StatusOK, resp)
+}
+
+func payloadBody(p models.ProviderPayload) interface{} {
+	if !p.Truncated && json.Valid(p.Body) {
+		return json.RawMessage(p.Body)
+	}
+	return string(p.Body)
+}
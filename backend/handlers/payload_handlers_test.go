@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jannin2/stock-app/backend/database"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// payloadStockDB devuelve respuestas guardadas fijas.
+type payloadStockDB struct {
+	database.StockDB
+	payloads map[string][]models.ProviderPayload
+}
+
+func (db *payloadStockDB) GetProviderPayloads(ctx context.Context, ticker string) ([]models.ProviderPayload, error) {
+	return db.payloads[ticker], nil
+}
+
+func TestGetProviderPayloads(t *testing.T) {
+	fetchedAt := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	db := &payloadStockDB{payloads: map[string][]models.ProviderPayload{"AAPL": {
+		{Ticker: "AAPL", Provider: "alphavantage", Endpoint: "GLOBAL_QUOTE", StatusCode: 200,
+			Body: []byte(`{"Note":"API call frequency exceeded"}`), FetchedAt: fetchedAt},
+		{Ticker: "AAPL", Provider: "finnhub", Endpoint: "quote", StatusCode: 502,
+			Body: []byte("<html>Bad Gateway</html>"), FetchedAt: fetchedAt},
+	}}}
+	router := chi.NewRouter()
+	router.Get("/api/v1/admin/stocks/{ticker}/payloads", (&StockHandlers{dbClient: db}).GetProviderPayloads)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/admin/stocks/aapl/payloads", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("❌ estado %d, se esperaba 200: %s", rr.Code, rr.Body.String())
+	}
+	var resp struct {
+		Ticker   string `json:"ticker"`
+		Payloads []struct {
+			Endpoint string          `json:"endpoint"`
+			Body     json.RawMessage `json:"body"`
+		} `json:"payloads"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("❌ respuesta inválida: %v", err)
+	}
+	if resp.Ticker != "AAPL" || len(resp.Payloads) != 2 {
+		t.Fatalf("❌ respuesta inesperada: %s", rr.Body.String())
+	}
+	if got := string(resp.Payloads[0].Body); got != `{"Note":"API call frequency exceeded"}` {
+		t.Errorf("❌ el cuerpo JSON debería mostrarse tal cual, se obtuvo %s", got)
+	}
+	var text string
+	if err := json.Unmarshal(resp.Payloads[1].Body, &text); err != nil || text != "<html>Bad Gateway</html>" {
+		t.Errorf("❌ el cuerpo que no es JSON debería mostrarse como texto, se obtuvo %s", resp.Payloads[1].Body)
+	}
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/analytics"
+	"github.com/jannin2/stock-app/backend/apierror"
+)
+
+const (
+	defaultProjectionHorizon  = 30
+	maxProjectionHorizon      = 252 // Un año bursátil
+	defaultProjectionPaths    = 5000
+	maxProjectionPaths        = 20000
+	maxProjectionSamples      = 2_000_000 // Límite de paths × días para acotar la memoria
+	defaultProjectionLookback = 365
+	maxProjectionLookback     = 1825
+
+	// projectionTimeout limita la duración de la simulación; al vencer se responde con los
+	// paths completados y truncated=true.
+	projectionTimeout = 2 * time.Second
+)
+
+// projectionRequest es el cuerpo de POST /analytics/projection. Se indica un ticker o una
+// cartera con pesos relativos.
+type projectionRequest struct {
+	Ticker       string              `json:"ticker"`
+	Portfolio    []analytics.Holding `json:"portfolio"`
+	HorizonDays  int                 `json:"horizon_days"`
+	Paths        int                 `json:"paths"`
+	LookbackDays int                 `json:"lookback_days"` // Días de histórico para estimar drift y volatilidad
+	Seed         *int64              `json:"seed"`          // Opcional, para resultados reproducibles
+}
+
+// RunProjection maneja POST /analytics/projection: una simulación Monte Carlo del valor de un
+// ticker (partiendo de su último cierre) o de una cartera (partiendo de 1) con bandas de percentiles.
+func (h *StockHandlers) RunProjection(w http.ResponseWriter, r *http.Request) {
+	var req projectionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.HTTPError(w, fmt.Sprintf("Cuerpo JSON inválido: %v", err), http.StatusBadRequest)
+		return
+	}
+	holdings, err := req.validate()
+	if err != nil {
+		apierror.HTTPError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tickers := make([]string, len(holdings))
+	for i, holding := range holdings {
+		tickers[i] = holding.Ticker
+	}
+	since := localDate(r.Context(), time.Now()).AddDate(0, 0, -req.LookbackDays)
+	history, err := h.dbClient.GetPriceHistory(r.Context(), tickers, since)
+	if err != nil {
+		apierror.Internal(w, "Error al obtener el histórico de precios", err)
+		return
+	}
+
+	drift, vol, observations, err := analytics.EstimateDrift(holdings, history)
+	if errors.Is(err, analytics.ErrInsufficientHistory) {
+		apierror.HTTPError(w, "No hay suficiente histórico de precios para estimar la proyección", http.StatusUnprocessableEntity)
+		return
+	}
+	if err != nil {
+		apierror.HTTPError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	params := analytics.ProjectionParams{
+		Holdings:    holdings,
+		HorizonDays: req.HorizonDays,
+		Paths:       req.Paths,
+		Seed:        time.Now().UnixNano(),
+		StartValue:  1,
+	}
+	if req.Seed != nil {
+		params.Seed = *req.Seed
+	}
+	if len(holdings) == 1 {
+		points := history[holdings[0].Ticker]
+		params.StartValue = points[len(points)-1].Close
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), projectionTimeout)
+	defer cancel()
+	projection, err := analytics.Project(ctx, params, drift, vol)
+	if err != nil {
+		apierror.HTTPError(w, "La simulación excedió el tiempo máximo", http.StatusServiceUnavailable)
+		return
+	}
+	projection.Observations = observations
+
+	writeJSON(w, r, http.StatusOK, projection)
+}
+
+// validate aplica los valores por defecto y devuelve la cartera a simular.
+func (req *projectionRequest) validate() ([]analytics.Holding, error) {
+	holdings := req.Portfolio
+	switch {
+	case req.Ticker != "" && len(holdings) > 0:
+		return nil, errors.New("Indique 'ticker' o 'portfolio', no ambos")
+	case req.Ticker != "":
+		holdings = []analytics.Holding{{Ticker: req.Ticker, Weight: 1}}
+	case len(holdings) == 0:
+		return nil, errors.New("Se requiere 'ticker' o 'portfolio'")
+	}
+	if len(holdings) > maxCorrelationTickers {
+		return nil, fmt.Errorf("La cartera admite como máximo %d tickers", maxCorrelationTickers)
+	}
+
+	seen := map[string]bool{}
+	for i := range holdings {
+		holdings[i].Ticker = strings.ToUpper(strings.TrimSpace(holdings[i].Ticker))
+		if holdings[i].Ticker == "" || holdings[i].Weight <= 0 || seen[holdings[i].Ticker] {
+			return nil, errors.New("Cada posición de la cartera requiere un ticker único y un peso positivo")
+		}
+		seen[holdings[i].Ticker] = true
+	}
+
+	if req.HorizonDays == 0 {
+		req.HorizonDays = defaultProjectionHorizon
+	}
+	if req.Paths == 0 {
+		req.Paths = defaultProjectionPaths
+	}
+	if req.LookbackDays == 0 {
+		req.LookbackDays = defaultProjectionLookback
+	}
+	if req.HorizonDays < 1 || req.HorizonDays > maxProjectionHorizon {
+		return nil, fmt.Errorf("'horizon_days' debe estar entre 1 y %d", maxProjectionHorizon)
+	}
+	if req.Paths < 100 || req.Paths > maxProjectionPaths {
+		return nil, fmt.Errorf("'paths' debe estar entre 100 y %d", maxProjectionPaths)
+	}
+	if req.Paths*req.HorizonDays > maxProjectionSamples {
+		return nil, fmt.Errorf("'paths' × 'horizon_days' no puede superar %d", maxProjectionSamples)
+	}
+	if req.LookbackDays < 30 || req.LookbackDays > maxProjectionLookback {
+		return nil, fmt.Errorf("'lookback_days' debe estar entre 30 y %d", maxProjectionLookback)
+	}
+	return holdings, nil
+}
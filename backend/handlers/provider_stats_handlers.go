@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/apierror"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+const (
+	defaultProviderStatsDays = 30
+	maxProviderStatsDays     = 365
+)
+
+// providerStatsSummary resume un proveedor en el periodo consultado: los totales salen de
+// combinar los histogramas diarios, no de promediar los percentiles de cada día.
+type providerStatsSummary struct {
+	Provider        string                    `json:"provider"`
+	Calls           int64                     `json:"calls"`
+	Errors          int64                     `json:"errors"`
+	ErrorRate       float64                   `json:"error_rate"`
+	ErrorCategories models.ErrorCounts        `json:"error_categories"`
+	P50Ms           int64                     `json:"p50_ms"`
+	P95Ms           int64                     `json:"p95_ms"`
+	P99Ms           int64                     `json:"p99_ms"`
+	MaxMs           int64                     `json:"max_ms"`
+	Daily           []models.ProviderDayStats `json:"daily"`
+}
+
+type providerStatsResponse struct {
+	Since     time.Time              `json:"since"`
+	Days      int                    `json:"days"`
+	Providers []providerStatsSummary `json:"providers"`
+}
+
+// GetProviderStats maneja GET /admin/providers/stats?days=30: latencias (p50/p95/p99) y
+// errores por categoría de cada proveedor, por día y en total, para comparar proveedores.
+func (h *StockHandlers) GetProviderStats(w http.ResponseWriter, r *http.Request) {
+	days := defaultProviderStatsDays
+	if daysStr := r.URL.Query().Get("days"); daysStr != "" {
+		parsed, err := strconv.Atoi(daysStr)
+		if err != nil || parsed < 1 || parsed > maxProviderStatsDays {
+			apierror.HTTPError(w, fmt.Sprintf("Parámetro 'days' inválido: debe ser un entero entre 1 y %d", maxProviderStatsDays), http.StatusBadRequest)
+			return
+		}
+		days = parsed
+	}
+
+	// El día actual cuenta como uno de los days.
+	since := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -(days - 1))
+	stats, err := h.dbClient.GetProviderStats(r.Context(), since)
+	if err != nil {
+		apierror.Internal(w, "Error al obtener las estadísticas de proveedores", err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, providerStatsResponse{Since: since, Days: days, Providers: summarizeProviderStats(stats)})
+}
+
+// summarizeProviderStats agrupa las filas diarias (ordenadas por proveedor) por proveedor.
+func summarizeProviderStats(stats []models.ProviderDayStats) []providerStatsSummary {
+	summaries := []providerStatsSummary{}
+	for _, day := range stats {
+		if len(summaries) == 0 || summaries[len(summaries)-1].Provider != day.Provider {
+			summaries = append(summaries, providerStatsSummary{Provider: day.Provider})
+		}
+		summary := &summaries[len(summaries)-1]
+		summary.Daily = append(summary.Daily, day)
+	}
+
+	for i := range summaries {
+		total := models.ProviderDayStats{Provider: summaries[i].Provider}
+		for _, day := range summaries[i].Daily {
+			total.Merge(day)
+		}
+		summaries[i].Calls = total.Calls
+		summaries[i].Errors = total.Errors
+		summaries[i].ErrorRate = total.ErrorRate()
+		summaries[i].ErrorCategories = total.ErrorCategories
+		summaries[i].P50Ms, summaries[i].P95Ms, summaries[i].P99Ms = total.P50Ms, total.P95Ms, total.P99Ms
+		summaries[i].MaxMs = total.MaxMs
+	}
+	return summaries
+}
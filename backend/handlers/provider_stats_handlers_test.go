@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/database"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// providerStatsDB devuelve siempre las mismas filas y guarda el since consultado.
+type providerStatsDB struct {
+	database.StockDB
+	stats []models.ProviderDayStats
+	since time.Time
+}
+
+func (db *providerStatsDB) GetProviderStats(ctx context.Context, since time.Time) ([]models.ProviderDayStats, error) {
+	db.since = since
+	return db.stats, nil
+}
+
+func TestGetProviderStats(t *testing.T) {
+	day := func(d int, latency time.Duration, calls, failed int) models.ProviderDayStats {
+		s := models.ProviderDayStats{Provider: "finnhub", Day: time.Date(2025, 1, d, 0, 0, 0, 0, time.UTC)}
+		for i := 0; i < calls; i++ {
+			category := ""
+			if i < failed {
+				category = models.ErrorCategoryHTTP5xx
+			}
+			s.Observe(latency, category)
+		}
+		s.UpdatePercentiles()
+		return s
+	}
+	alpha := day(6, 900*time.Millisecond, 4, 4)
+	alpha.Provider = "alphavantage"
+	db := &providerStatsDB{stats: []models.ProviderDayStats{alpha, day(5, 20*time.Millisecond, 6, 0), day(6, 400*time.Millisecond, 4, 1)}}
+	h := &StockHandlers{dbClient: db}
+
+	rr := httptest.NewRecorder()
+	h.GetProviderStats(rr, httptest.NewRequest(http.MethodGet, "/api/v1/admin/providers/stats?days=7", nil))
+	var resp providerStatsResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil || rr.Code != http.StatusOK {
+		t.Fatalf("❌ respuesta inesperada: %d %s", rr.Code, rr.Body)
+	}
+	if want := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -6); !db.since.Equal(want) || resp.Days != 7 {
+		t.Errorf("❌ since = %s, se esperaba %s", db.since, want)
+	}
+	if len(resp.Providers) != 2 || resp.Providers[0].Provider != "alphavantage" || resp.Providers[1].Provider != "finnhub" {
+		t.Fatalf("❌ proveedores inesperados: %s", rr.Body)
+	}
+	finnhub := resp.Providers[1]
+	if finnhub.Calls != 10 || finnhub.Errors != 1 || finnhub.ErrorRate != 0.1 || len(finnhub.Daily) != 2 {
+		t.Errorf("❌ totales de finnhub inesperados: %+v", finnhub)
+	}
+	// Los percentiles totales salen del histograma combinado de los dos días
+	if finnhub.P50Ms != 25 || finnhub.P95Ms != 400 {
+		t.Errorf("❌ percentiles de finnhub inesperados: p50=%d p95=%d", finnhub.P50Ms, finnhub.P95Ms)
+	}
+
+	for _, days := range []string{"0", "366", "abc"} {
+		rr := httptest.NewRecorder()
+		h.GetProviderStats(rr, httptest.NewRequest(http.MethodGet, "/api/v1/admin/providers/stats?days="+days, nil))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("❌ days=%s: estado %d, se esperaba 400", days, rr.Code)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/apierror"
+	"github.com/jannin2/stock-app/backend/config"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+const (
+	maxWatchlistNameLength = 100
+	maxWatchlistTickers    = 100
+)
+
+// watchlistRequest es el cuerpo de POST /me/watchlists.
+type watchlistRequest struct {
+	Name    string   `json:"name"`
+	Tickers []string `json:"tickers"`
+}
+
+// GetQuotas maneja GET /me/quotas: el límite y el uso de cada cuota del usuario.
+func (h *UserHandlers) GetQuotas(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUser(w, r)
+	if !ok {
+		return
+	}
+	quotas := config.Current().UserQuotas
+	resources := make([]string, 0, len(quotas))
+	for resource := range quotas {
+		resources = append(resources, resource)
+	}
+	sort.Strings(resources)
+
+	usage := make([]models.QuotaUsage, 0, len(resources))
+	for _, resource := range resources {
+		used, err := h.users.CountUserResources(r.Context(), userID, resource)
+		if err != nil {
+			writeError(w, err, "Error al consultar las cuotas")
+			return
+		}
+		usage = append(usage, models.QuotaUsage{Resource: resource, Limit: quotas[resource], Used: used})
+	}
+	writeJSON(w, r, http.StatusOK, usage)
+}
+
+// CreateWatchlist maneja POST /me/watchlists, sujeto a la cuota de watchlists.
+func (h *UserHandlers) CreateWatchlist(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUser(w, r)
+	if !ok {
+		return
+	}
+	var req watchlistRequest
+	if !decodeAuthRequest(w, r, &req) {
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" || utf8.RuneCountInString(name) > maxWatchlistNameLength {
+		apierror.HTTPError(w, fmt.Sprintf("El nombre de la watchlist es obligatorio (máximo %d caracteres)", maxWatchlistNameLength), http.StatusBadRequest)
+		return
+	}
+	tickers, err := normalizeTickers(req.Tickers)
+	if err != nil {
+		apierror.HTTPError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !h.enforceQuota(w, r, userID, models.QuotaWatchlists) {
+		return
+	}
+	watchlist, err := h.users.CreateWatchlist(r.Context(), userID, name, tickers)
+	if err != nil {
+		writeError(w, err, "Error al crear la watchlist")
+		return
+	}
+	writeJSON(w, r, http.StatusCreated, watchlist)
+}
+
+// enforceQuota responde 422 si el usuario ya tiene tantos recursos de tipo resource como
+// permite config.UserQuotas. Es una cuota blanda: dos peticiones simultáneas pueden
+// superarla en uno, lo que basta para evitar abusos.
+func (h *UserHandlers) enforceQuota(w http.ResponseWriter, r *http.Request, userID uuid.UUID, resource string) bool {
+	limit := config.Current().UserQuotas[resource]
+	if limit == 0 {
+		return true
+	}
+	used, err := h.users.CountUserResources(r.Context(), userID, resource)
+	if err != nil {
+		writeError(w, err, "Error al comprobar la cuota")
+		return false
+	}
+	if used >= limit {
+		// details lleva el uso de la cuota, con la misma forma que GET /me/quotas
+		message := fmt.Sprintf("Se ha alcanzado el límite de %d %s por usuario; borre alguno antes de crear otro", limit, resource)
+		apierror.Write(w, apierror.New(http.StatusUnprocessableEntity, message).
+			WithCode(apierror.CodeQuotaExceeded).
+			WithDetails(models.QuotaUsage{Resource: resource, Limit: limit, Used: used}))
+		return false
+	}
+	return true
+}
+
+// normalizeTickers pasa los tickers a mayúsculas y elimina duplicados, conservando el orden.
+func normalizeTickers(raw []string) ([]string, error) {
+	if len(raw) > maxWatchlistTickers {
+		return nil, fmt.Errorf("Una watchlist admite como máximo %d tickers", maxWatchlistTickers)
+	}
+	tickers := make([]string, 0, len(raw))
+	seen := map[string]bool{}
+	for _, ticker := range raw {
+		ticker = strings.ToUpper(strings.TrimSpace(ticker))
+		if ticker == "" || len(ticker) > 10 {
+			return nil, fmt.Errorf("Ticker inválido: %q", ticker)
+		}
+		if !seen[ticker] {
+			seen[ticker] = true
+			tickers = append(tickers, ticker)
+		}
+	}
+	return tickers, nil
+}
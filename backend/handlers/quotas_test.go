@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/apierror"
+	"github.com/jannin2/stock-app/backend/auth"
+	"github.com/jannin2/stock-app/backend/config"
+	"github.com/jannin2/stock-app/backend/database"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// watchlistUserDB guarda en memoria las watchlists creadas.
+type watchlistUserDB struct {
+	database.UserDB
+	watchlists []models.Watchlist
+}
+
+func (db *watchlistUserDB) CountUserResources(_ context.Context, userID uuid.UUID, resource string) (int, error) {
+	if resource != models.QuotaWatchlists {
+		return 0, nil
+	}
+	return len(db.watchlists), nil
+}
+
+func (db *watchlistUserDB) CreateWatchlist(_ context.Context, userID uuid.UUID, name string, tickers []string) (models.Watchlist, error) {
+	w := models.Watchlist{ID: uuid.New(), UserID: userID, Name: name, Tickers: tickers}
+	db.watchlists = append(db.watchlists, w)
+	return w, nil
+}
+
+func TestCreateWatchlist_EnforcesQuota(t *testing.T) {
+	cfg := config.Default()
+	cfg.UserQuotas = map[string]int{models.QuotaWatchlists: 2, models.QuotaAlerts: 0}
+	config.Set(cfg)
+	t.Cleanup(func() { config.Set(config.Default()) })
+
+	db := &watchlistUserDB{}
+	h := NewUserHandlers(db, nil, nil)
+	userID := uuid.New()
+	serve := func(handler http.HandlerFunc, method, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, "/me/watchlists", strings.NewReader(body))
+		req = req.WithContext(auth.WithUser(req.Context(), userID))
+		rr := httptest.NewRecorder()
+		handler(rr, req)
+		return rr
+	}
+
+	rr := serve(h.CreateWatchlist, http.MethodPost, `{"name":" Tech ","tickers":["aapl","MSFT","AAPL"]}`)
+	if rr.Code != http.StatusCreated || len(db.watchlists) != 1 {
+		t.Fatalf("❌ estado %d: %s", rr.Code, rr.Body)
+	}
+	if got := db.watchlists[0]; got.Name != "Tech" || strings.Join(got.Tickers, ",") != "AAPL,MSFT" {
+		t.Errorf("❌ watchlist mal normalizada: %+v", got)
+	}
+	if rr := serve(h.CreateWatchlist, http.MethodPost, `{"name":""}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("❌ nombre vacío: estado %d, se esperaba 400", rr.Code)
+	}
+
+	serve(h.CreateWatchlist, http.MethodPost, `{"name":"Energía"}`)
+	rr = serve(h.CreateWatchlist, http.MethodPost, `{"name":"Una más"}`)
+	var exceeded struct {
+		Code    string            `json:"code"`
+		Message string            `json:"message"`
+		Details models.QuotaUsage `json:"details"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &exceeded); err != nil || rr.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("❌ cuota superada: estado %d: %s", rr.Code, rr.Body)
+	}
+	if exceeded.Code != apierror.CodeQuotaExceeded || exceeded.Message == "" ||
+		exceeded.Details != (models.QuotaUsage{Resource: models.QuotaWatchlists, Limit: 2, Used: 2}) {
+		t.Errorf("❌ respuesta 422 inesperada: %+v", exceeded)
+	}
+	if len(db.watchlists) != 2 {
+		t.Errorf("❌ no debería haberse creado la tercera watchlist")
+	}
+
+	rr = serve(h.GetQuotas, http.MethodGet, "")
+	var usage []models.QuotaUsage
+	if err := json.Unmarshal(rr.Body.Bytes(), &usage); err != nil || len(usage) != 2 {
+		t.Fatalf("❌ GET /me/quotas: estado %d: %s", rr.Code, rr.Body)
+	}
+	if usage[0] != (models.QuotaUsage{Resource: models.QuotaAlerts, Limit: 0, Used: 0}) ||
+		usage[1] != (models.QuotaUsage{Resource: models.QuotaWatchlists, Limit: 2, Used: 2}) {
+		t.Errorf("❌ uso de cuotas inesperado: %+v", usage)
+	}
+}
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/jannin2/stock-app/backend/apierror"
+	"github.com/jannin2/stock-app/backend/quotes"
+)
+
+// maxQuoteTickers es el número máximo de tickers por petición a /quotes.
+const maxQuoteTickers = 50
+
+// QuoteHandlers sirve cotizaciones ligeras desde la caché en memoria, sin consultar la tabla stocks.
+type QuoteHandlers struct {
+	cache *quotes.Cache
+}
+
+// NewQuoteHandlers crea los manejadores de cotizaciones a partir de la caché que mantiene el enricher.
+func NewQuoteHandlers(cache *quotes.Cache) *QuoteHandlers {
+	return &QuoteHandlers{cache: cache}
+}
+
+// GetQuotes maneja GET /quotes?tickers=AAPL,MSFT y devuelve {ticker, price, change, as_of}
+// de cada ticker en el orden solicitado. Los tickers sin cotización en caché se omiten.
+func (h *QuoteHandlers) GetQuotes(w http.ResponseWriter, r *http.Request) {
+	tickers := parseTickerList(r.URL.Query().Get("tickers"))
+	if len(tickers) == 0 || len(tickers) > maxQuoteTickers {
+		apierror.HTTPError(w, fmt.Sprintf("El parámetro 'tickers' debe contener entre 1 y %d tickers separados por comas", maxQuoteTickers), http.StatusBadRequest)
+		return
+	}
+
+	found, _ := h.cache.Get(tickers)
+	writeJSON(w, r, http.StatusOK, found)
+}
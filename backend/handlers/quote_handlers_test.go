@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jannin2/stock-app/backend/models"
+	"github.com/jannin2/stock-app/backend/quotes"
+)
+
+func TestGetQuotes(t *testing.T) {
+	cache := quotes.NewCache()
+	cache.PutStocks([]models.Stock{
+		{Ticker: "AAPL", CurrentPrice: 190, PreviousClose: models.NewNullFloat64(200)},
+		{Ticker: "MSFT", CurrentPrice: 410},
+	})
+	h := NewQuoteHandlers(cache)
+
+	rr := httptest.NewRecorder()
+	h.GetQuotes(rr, httptest.NewRequest(http.MethodGet, "/api/v1/quotes?tickers=msft,NOPE,aapl", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("❌ estado %d, se esperaba 200: %s", rr.Code, rr.Body.String())
+	}
+	var got []map[string]interface{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+		t.Fatalf("❌ JSON inválido: %v", err)
+	}
+	if len(got) != 2 || got[0]["ticker"] != "MSFT" || got[1]["ticker"] != "AAPL" {
+		t.Fatalf("❌ se esperaban MSFT y AAPL en orden, se obtuvo %v", got)
+	}
+	if got[1]["change"] != -5.0 || got[0]["change"] != nil {
+		t.Errorf("❌ cambios inesperados: %v", got)
+	}
+	if len(got[0]) != 4 {
+		t.Errorf("❌ se esperaban solo ticker, price, change y as_of, se obtuvo %v", got[0])
+	}
+
+	for _, query := range []string{"", "?tickers=", "?tickers=" + manyTickers(maxQuoteTickers+1)} {
+		rr := httptest.NewRecorder()
+		h.GetQuotes(rr, httptest.NewRequest(http.MethodGet, "/api/v1/quotes"+query, nil))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("❌ %q: estado %d, se esperaba 400", query, rr.Code)
+		}
+	}
+}
+
+// manyTickers devuelve n tickers distintos separados por comas.
+func manyTickers(n int) string {
+	s := ""
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			s += ","
+		}
+		s += string(rune('A'+i/26)) + string(rune('A'+i%26))
+	}
+	return s
+}
@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/apierror"
+	"github.com/jannin2/stock-app/backend/auth"
+)
+
+// writeJSON serializa v como JSON ocultando los campos cuyo scope (etiqueta `scope:"..."`)
+// sea mayor que el scope de la petición. Las fechas se escriben en UTC, sea cual sea la
+// zona con la que las devolvió la base de datos.
+func writeJSON(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	body, err := marshalScoped(v, auth.ScopeFromContext(r.Context()))
+	if err != nil {
+		apierror.HTTPError(w, "Error al serializar la respuesta", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(body)
+	w.Write([]byte("\n"))
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// hasCustomMarshaling indica si t (o *t) define su propia serialización JSON o de texto.
+func hasCustomMarshaling(t reflect.Type) bool {
+	pt := reflect.PointerTo(t)
+	return t.Implements(jsonMarshalerType) || pt.Implements(jsonMarshalerType) ||
+		t.Implements(textMarshalerType) || pt.Implements(textMarshalerType)
+}
+
+// marshalScoped funciona como json.Marshal, pero omite los campos de structs etiquetados
+// con un scope superior a scope. Los tipos con serialización propia (json.Marshaler o
+// encoding.TextMarshaler) se serializan tal cual.
+func marshalScoped(v interface{}, scope auth.Scope) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := encodeScoped(&buf, reflect.ValueOf(v), scope); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func encodeScoped(buf *bytes.Buffer, v reflect.Value, scope auth.Scope) error {
+	if !v.IsValid() {
+		buf.WriteString("null")
+		return nil
+	}
+	switch {
+	case v.Type() == timeType:
+		return encodeDefault(buf, reflect.ValueOf(v.Interface().(time.Time).UTC()))
+	case v.Kind() == reflect.Pointer && v.Type().Elem() == timeType && !v.IsNil():
+		return encodeScoped(buf, v.Elem(), scope)
+	case v.Kind() != reflect.Interface && hasCustomMarshaling(v.Type()):
+		return encodeDefault(buf, v)
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			buf.WriteString("null")
+			return nil
+		}
+		return encodeScoped(buf, v.Elem(), scope)
+	case reflect.Struct:
+		return encodeStruct(buf, v, scope)
+	case reflect.Slice:
+		if v.IsNil() {
+			buf.WriteString("null")
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return encodeDefault(buf, v) // []byte se codifica en base64
+		}
+		fallthrough
+	case reflect.Array:
+		buf.WriteByte('[')
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := encodeScoped(buf, v.Index(i), scope); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+		return nil
+	case reflect.Map:
+		if v.IsNil() {
+			buf.WriteString("null")
+			return nil
+		}
+		return encodeMap(buf, v, scope)
+	default:
+		return encodeDefault(buf, v)
+	}
+}
+
+func encodeDefault(buf *bytes.Buffer, v reflect.Value) error {
+	b, err := json.Marshal(v.Interface())
+	if err != nil {
+		return err
+	}
+	buf.Write(b)
+	return nil
+}
+
+// encodeMap delega en json.Marshal el orden y formato de las claves, y codifica
+// los valores con encodeScoped.
+func encodeMap(buf *bytes.Buffer, v reflect.Value, scope auth.Scope) error {
+	values := make(map[string]json.RawMessage, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		key, err := json.Marshal(iter.Key().Interface())
+		if err != nil {
+			return err
+		}
+		var elem bytes.Buffer
+		if err := encodeScoped(&elem, iter.Value(), scope); err != nil {
+			return err
+		}
+		values[strings.Trim(string(key), `"`)] = elem.Bytes()
+	}
+	return encodeDefault(buf, reflect.ValueOf(values))
+}
+
+// encodeStruct codifica los campos exportados respetando las etiquetas `json` (nombre,
+// "-" y omitempty) y omitiendo los que requieren un scope mayor que el de la petición.
+func encodeStruct(buf *bytes.Buffer, v reflect.Value, scope auth.Scope) error {
+	buf.WriteByte('{')
+	first := true
+	if err := encodeStructFields(buf, v, scope, &first); err != nil {
+		return err
+	}
+	buf.WriteByte('}')
+	return nil
+}
+
+func encodeStructFields(buf *bytes.Buffer, v reflect.Value, scope auth.Scope, first *bool) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if tag, ok := field.Tag.Lookup("scope"); ok && auth.ParseScope(tag) > scope {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" && opts == "" {
+			continue
+		}
+		fv := v.Field(i)
+
+		// Los structs embebidos sin nombre JSON se aplanan, igual que en encoding/json.
+		if field.Anonymous && name == "" && fv.Kind() == reflect.Struct && !hasCustomMarshaling(fv.Type()) {
+			if err := encodeStructFields(buf, fv, scope, first); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if strings.Contains(opts, "omitempty") && isEmptyValue(fv) {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		if !*first {
+			buf.WriteByte(',')
+		}
+		*first = false
+		key, _ := json.Marshal(name)
+		buf.Write(key)
+		buf.WriteByte(':')
+		if err := encodeScoped(buf, fv, scope); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isEmptyValue reproduce la definición de "vacío" que usa encoding/json para omitempty.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.Interface, reflect.Pointer:
+		return v.IsZero()
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/auth"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+func TestMarshalScoped_HidesAdminFields(t *testing.T) {
+	stock := models.Stock{
+		ID:             uuid.New(),
+		Ticker:         "AAPL",
+		PERatio:        models.NewNullFloat64(28.5),
+		ProviderErrors: "alphavantage: rate limit",
+	}
+
+	public, err := marshalScoped([]models.Stock{stock}, auth.ScopePublic)
+	if err != nil {
+		t.Fatalf("❌ error inesperado al serializar: %v", err)
+	}
+	var publicRows []map[string]interface{}
+	if err := json.Unmarshal(public, &publicRows); err != nil {
+		t.Fatalf("❌ JSON inválido: %v (%s)", err, public)
+	}
+	if _, ok := publicRows[0]["provider_errors"]; ok {
+		t.Errorf("❌ provider_errors no debería exponerse en el scope público: %s", public)
+	}
+	if publicRows[0]["id"] != stock.ID.String() || publicRows[0]["pe_ratio"] != 28.5 {
+		t.Errorf("❌ campos públicos serializados incorrectamente: %s", public)
+	}
+
+	admin, err := marshalScoped([]models.Stock{stock}, auth.ScopeAdmin)
+	if err != nil {
+		t.Fatalf("❌ error inesperado al serializar: %v", err)
+	}
+	var adminRows []map[string]interface{}
+	if err := json.Unmarshal(admin, &adminRows); err != nil {
+		t.Fatalf("❌ JSON inválido: %v (%s)", err, admin)
+	}
+	if adminRows[0]["provider_errors"] != stock.ProviderErrors {
+		t.Errorf("❌ provider_errors debería exponerse en el scope admin: %s", admin)
+	}
+}
+
+func TestMarshalScoped_MatchesEncodingJSON(t *testing.T) {
+	// Sin campos con scope, la salida debe coincidir con la de encoding/json.
+	buckets := map[string][]models.StockBucket{
+		"sector": {{Key: "Technology", Stocks: []models.Stock{{Ticker: "MSFT"}}}},
+	}
+	expected, _ := json.Marshal(buckets)
+	actual, err := marshalScoped(buckets, auth.ScopeAdmin)
+	if err != nil {
+		t.Fatalf("❌ error inesperado al serializar: %v", err)
+	}
+	if string(actual) != string(expected) {
+		t.Errorf("❌ salida distinta a encoding/json:\n got: %s\nwant: %s", actual, expected)
+	}
+}
+
+func TestMarshalScoped_TimesInUTC(t *testing.T) {
+	madrid, err := time.LoadLocation("Europe/Madrid")
+	if err != nil {
+		t.Skipf("⚠️ sin base de datos de zonas horarias: %v", err)
+	}
+	at := time.Date(2025, 1, 6, 10, 30, 0, 0, madrid)
+	body, err := marshalScoped(struct {
+		At      time.Time       `json:"at"`
+		Ptr     *time.Time      `json:"ptr"`
+		Trading models.NullTime `json:"trading"`
+	}{at, &at, models.NullTime{NullTime: sql.NullTime{Time: at, Valid: true}}}, auth.ScopePublic)
+	if err != nil {
+		t.Fatalf("❌ error inesperado al serializar: %v", err)
+	}
+	want := `{"at":"2025-01-06T09:30:00Z","ptr":"2025-01-06T09:30:00Z","trading":"2025-01-06T09:30:00Z"}`
+	if string(body) != want {
+		t.Errorf("❌ las fechas deberían serializarse en UTC:\n got: %s\nwant: %s", body, want)
+	}
+}
@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/jannin2/stock-app/backend/apierror"
+	"github.com/jannin2/stock-app/backend/models"
+	"github.com/jannin2/stock-app/backend/scoring"
+)
+
+// whatIfRequest es el cuerpo de POST /scoring/what-if. Si se indica id, los campos de
+// overrides se aplican sobre el stock guardado; si no, overrides es el stock completo.
+// weights o rules permiten probar pesos o reglas distintos de los que usa el enricher.
+type whatIfRequest struct {
+	ID        string               `json:"id"`
+	Overrides json.RawMessage      `json:"overrides"`
+	Weights   *scoring.Weights     `json:"weights"`
+	Rules     []models.ScoringRule `json:"rules"`
+}
+
+// whatIfResponse devuelve el stock hipotético con su score recalculado y el desglose por regla.
+type whatIfResponse struct {
+	Stock        models.Stock         `json:"stock"`
+	Score        float64              `json:"score"`
+	CurrentScore models.NullFloat64   `json:"current_score"` // Score guardado del stock base, si se indicó id
+	Breakdown    []scoring.Component  `json:"breakdown"`
+	Weights      *scoring.Weights     `json:"weights,omitempty"` // Si se puntuó con las reglas integradas
+	Rules        []models.ScoringRule `json:"rules,omitempty"`   // Si se puntuó con reglas de expresiones
+}
+
+// ScoreWhatIf maneja POST /scoring/what-if: recalcula el score de un stock con campos
+// modificados (ej. un precio objetivo hipotético) sin guardar nada.
+func (h *StockHandlers) ScoreWhatIf(w http.ResponseWriter, r *http.Request) {
+	var req whatIfRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.HTTPError(w, fmt.Sprintf("Cuerpo JSON inválido: %v", err), http.StatusBadRequest)
+		return
+	}
+	if req.ID == "" && len(bytes.TrimSpace(req.Overrides)) == 0 {
+		apierror.HTTPError(w, "Se requiere 'id' u 'overrides'", http.StatusBadRequest)
+		return
+	}
+
+	var stock models.Stock
+	var current models.NullFloat64
+	if req.ID != "" {
+		base, err := h.dbClient.GetStockByID(r.Context(), req.ID)
+		if err != nil {
+			writeError(w, err, "Error al obtener el stock")
+			return
+		}
+		stock, current = base, base.RecommendationScore
+	}
+
+	// Decodificar sobre el stock base solo reemplaza los campos presentes en overrides.
+	if len(bytes.TrimSpace(req.Overrides)) > 0 {
+		if err := json.Unmarshal(req.Overrides, &stock); err != nil {
+			apierror.HTTPError(w, fmt.Sprintf("'overrides' inválido: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	scorer := scoring.Current()
+	switch {
+	case req.Weights != nil && len(req.Rules) > 0:
+		apierror.HTTPError(w, "Indique 'weights' o 'rules', no ambos", http.StatusBadRequest)
+		return
+	case req.Weights != nil:
+		scorer = *req.Weights
+	case len(req.Rules) > 0:
+		rules, err := scoring.CompileRules(req.Rules)
+		if err != nil {
+			apierror.HTTPError(w, fmt.Sprintf("'rules' inválido: %v", err), http.StatusBadRequest)
+			return
+		}
+		scorer = rules
+	}
+
+	breakdown := scorer.Explain(stock)
+	stock.RecommendationScore = models.NewNullFloat64(breakdown.Score)
+
+	resp := whatIfResponse{
+		Stock:        stock,
+		Score:        breakdown.Score,
+		CurrentScore: current,
+		Breakdown:    breakdown.Components,
+	}
+	switch s := scorer.(type) {
+	case scoring.Weights:
+		resp.Weights = &s
+	case *scoring.RuleSet:
+		resp.Rules = s.Rules()
+	}
+	writeJSON(w, r, http.StatusOK, resp)
+}
+
+// scoringRulesRequest es el cuerpo de PUT /admin/scoring/rules. Una lista vacía vuelve a
+// las reglas integradas.
+type scoringRulesRequest struct {
+	Rules []models.ScoringRule `json:"rules"`
+}
+
+// scoringRulesResponse describe las reglas de scoring vigentes y lo que se puede usar al
+// escribirlas.
+type scoringRulesResponse struct {
+	models.ScoringRules
+	BuiltIn   *scoring.Weights  `json:"built_in,omitempty"` // Pesos de las reglas integradas, si no hay reglas guardadas
+	Variables map[string]string `json:"variables"`
+	Functions map[string]string `json:"functions"`
+}
+
+func newScoringRulesResponse(rules models.ScoringRules) scoringRulesResponse {
+	resp := scoringRulesResponse{ScoringRules: rules, Variables: scoring.Variables, Functions: scoring.Functions}
+	if len(rules.Rules) == 0 {
+		resp.BuiltIn = &scoring.DefaultWeights
+	}
+	return resp
+}
+
+// GetScoringRules maneja GET /admin/scoring/rules.
+func (h *StockHandlers) GetScoringRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := h.dbClient.GetScoringRules(r.Context())
+	if err != nil {
+		apierror.Internal(w, "Error al obtener las reglas de scoring", err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, newScoringRulesResponse(rules))
+}
+
+// SaveScoringRules maneja PUT /admin/scoring/rules: valida las reglas, las guarda y las
+// activa en esta instancia. Las demás las cargan al empezar su siguiente enriquecimiento;
+// los scores ya guardados no cambian hasta entonces.
+func (h *StockHandlers) SaveScoringRules(w http.ResponseWriter, r *http.Request) {
+	var req scoringRulesRequest
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		apierror.HTTPError(w, fmt.Sprintf("Cuerpo JSON inválido: %v", err), http.StatusBadRequest)
+		return
+	}
+	var compiled *scoring.RuleSet
+	if len(req.Rules) > 0 {
+		var err error
+		if compiled, err = scoring.CompileRules(req.Rules); err != nil {
+			apierror.HTTPError(w, fmt.Sprintf("Reglas inválidas: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	saved, err := h.dbClient.SaveScoringRules(r.Context(), req.Rules)
+	if err != nil {
+		apierror.Internal(w, "Error al guardar las reglas de scoring", err)
+		return
+	}
+	scoring.SetRules(compiled)
+	writeJSON(w, r, http.StatusOK, newScoringRulesResponse(saved))
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/database"
+	"github.com/jannin2/stock-app/backend/models"
+	"github.com/jannin2/stock-app/backend/scoring"
+)
+
+// singleStockDB es un StockDB en memoria con un único stock.
+type singleStockDB struct {
+	database.StockDB
+	stock models.Stock
+}
+
+func (db *singleStockDB) GetStockByID(ctx context.Context, id string) (models.Stock, error) {
+	if id != db.stock.ID.String() {
+		return models.Stock{}, database.ErrStockNotFound
+	}
+	return db.stock, nil
+}
+
+func TestScoreWhatIf(t *testing.T) {
+	stock := models.Stock{
+		ID: uuid.New(), Ticker: "AAPL", Action: "Buy", CurrentPrice: 100,
+		TargetTo: models.NewNullFloat64(105), RecommendationScore: models.NewNullFloat64(5),
+	}
+	h := &StockHandlers{dbClient: &singleStockDB{stock: stock}}
+
+	body := `{"id":"` + stock.ID.String() + `","overrides":{"target_to":130}}`
+	rr := httptest.NewRecorder()
+	h.ScoreWhatIf(rr, httptest.NewRequest(http.MethodPost, "/api/v1/scoring/what-if", strings.NewReader(body)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("❌ estado %d, se esperaba 200: %s", rr.Code, rr.Body.String())
+	}
+
+	var resp struct {
+		Stock        models.Stock       `json:"stock"`
+		Score        float64            `json:"score"`
+		CurrentScore models.NullFloat64 `json:"current_score"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("❌ JSON inválido: %v", err)
+	}
+	if resp.Score != 8 || resp.CurrentScore.Float64 != 5 {
+		t.Errorf("❌ score %v (actual %v), se esperaba 8 (actual 5)", resp.Score, resp.CurrentScore.Float64)
+	}
+	if resp.Stock.Ticker != "AAPL" || resp.Stock.TargetTo.Float64 != 130 {
+		t.Errorf("❌ los overrides no se aplicaron sobre el stock base: %+v", resp.Stock)
+	}
+
+	rr = httptest.NewRecorder()
+	h.ScoreWhatIf(rr, httptest.NewRequest(http.MethodPost, "/api/v1/scoring/what-if", strings.NewReader(`{"id":"`+uuid.NewString()+`"}`)))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("❌ estado %d para un id inexistente, se esperaba 404", rr.Code)
+	}
+}
+
+func TestScoreWhatIf_WithRules(t *testing.T) {
+	h := &StockHandlers{dbClient: &singleStockDB{}}
+	body := `{"overrides":{"ticker":"AAPL","current_price":100,"target_to":125},
+		"rules":[{"name":"scaled_upside","when":"upside > 0","points":"min(upside * 20, 4)"}]}`
+	rr := httptest.NewRecorder()
+	h.ScoreWhatIf(rr, httptest.NewRequest(http.MethodPost, "/api/v1/scoring/what-if", strings.NewReader(body)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("❌ estado %d, se esperaba 200: %s", rr.Code, rr.Body.String())
+	}
+	var resp struct {
+		Score   float64              `json:"score"`
+		Rules   []models.ScoringRule `json:"rules"`
+		Weights json.RawMessage      `json:"weights"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("❌ JSON inválido: %v", err)
+	}
+	if resp.Score != 4 || len(resp.Rules) != 1 || resp.Weights != nil {
+		t.Errorf("❌ se esperaba un score de 4 con las reglas enviadas: %s", rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	h.ScoreWhatIf(rr, httptest.NewRequest(http.MethodPost, "/api/v1/scoring/what-if",
+		strings.NewReader(`{"overrides":{"ticker":"AAPL"},"rules":[{"name":"x","points":"price * 2"}]}`)))
+	if rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), "price") {
+		t.Errorf("❌ estado %d para una regla inválida, se esperaba 400: %s", rr.Code, rr.Body.String())
+	}
+}
+
+// rulesStockDB guarda las reglas de scoring en memoria.
+type rulesStockDB struct {
+	database.StockDB
+	rules []models.ScoringRule
+}
+
+func (db *rulesStockDB) GetScoringRules(ctx context.Context) (models.ScoringRules, error) {
+	return models.ScoringRules{Rules: append([]models.ScoringRule{}, db.rules...)}, nil
+}
+
+func (db *rulesStockDB) SaveScoringRules(ctx context.Context, rules []models.ScoringRule) (models.ScoringRules, error) {
+	db.rules = rules
+	return db.GetScoringRules(context.Background())
+}
+
+func TestSaveScoringRules(t *testing.T) {
+	t.Cleanup(func() { scoring.SetRules(nil) })
+	db := &rulesStockDB{}
+	h := &StockHandlers{dbClient: db}
+
+	rr := httptest.NewRecorder()
+	h.SaveScoringRules(rr, httptest.NewRequest(http.MethodPut, "/api/v1/admin/scoring/rules",
+		strings.NewReader(`{"rules":[{"name":"bad","when":"upside >","points":"1"}]}`)))
+	if rr.Code != http.StatusBadRequest || db.rules != nil {
+		t.Fatalf("❌ estado %d para reglas inválidas, se esperaba 400 sin guardar nada", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	h.SaveScoringRules(rr, httptest.NewRequest(http.MethodPut, "/api/v1/admin/scoring/rules",
+		strings.NewReader(`{"rules":[{"name":"flat","points":"1.5"}]}`)))
+	if rr.Code != http.StatusOK || len(db.rules) != 1 {
+		t.Fatalf("❌ estado %d, se esperaba 200 con la regla guardada: %s", rr.Code, rr.Body.String())
+	}
+	if got := scoring.Current().Explain(models.Stock{}).Score; got != 1.5 {
+		t.Errorf("❌ las reglas guardadas deberían activarse al momento, score %v", got)
+	}
+
+	// Una lista vacía vuelve a las reglas integradas.
+	rr = httptest.NewRecorder()
+	h.SaveScoringRules(rr, httptest.NewRequest(http.MethodPut, "/api/v1/admin/scoring/rules", strings.NewReader(`{"rules":[]}`)))
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"built_in"`) {
+		t.Errorf("❌ se esperaban las reglas integradas: %d %s", rr.Code, rr.Body.String())
+	}
+	if _, ok := scoring.Current().(scoring.Weights); !ok {
+		t.Errorf("❌ se esperaban las reglas integradas activas, se obtuvo %T", scoring.Current())
+	}
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/jannin2/stock-app/backend/apierror"
+	"github.com/jannin2/stock-app/backend/database"
+)
+
+// stockScreen es un listado predefinido de GET /stocks?screen=nombre: parámetros de filtro
+// y orden que se aplican como valores por defecto. Los parámetros que el cliente envía
+// explícitamente prevalecen sobre los de la pantalla.
+type stockScreen struct {
+	name        string
+	title       string
+	description string
+	params      url.Values
+}
+
+// stockScreens son las pantallas disponibles, en el orden en que las lista
+// GET /screens/presets.
+var stockScreens = []stockScreen{
+	{
+		name:        "dividend_aristocrats",
+		title:       "Aristócratas del dividendo",
+		description: "Grandes compañías con una rentabilidad por dividendo de al menos el 2,5 %.",
+		params: url.Values{
+			"min_dividend_yield": {"2.5"},
+			"min_market_cap":     {"10000"}, // 10.000 millones de USD
+			"sortBy":             {"dividend_yield"},
+			"order":              {"desc"},
+		},
+	},
+	{
+		name:        "growth_momentum",
+		title:       "Crecimiento con impulso",
+		description: "Compañías que superan a su sector en al menos 10 puntos en los últimos 90 días.",
+		params: url.Values{
+			"min_relative_strength": {"10"},
+			"min_market_cap":        {"2000"},
+			"sortBy":                {"relative_strength"},
+			"order":                 {"desc"},
+		},
+	},
+	{
+		name:        "deep_value",
+		title:       "Valor profundo",
+		description: "Compañías rentables con un PER de 12 o menos.",
+		params: url.Values{
+			"max_pe":         {"12"},
+			"min_market_cap": {"2000"},
+			"sortBy":         {"pe_ratio"},
+			"order":          {"asc"},
+		},
+	},
+	{
+		name:        "heavily_shorted",
+		title:       "Muy vendidos en corto",
+		description: "Posiciones cortas que necesitan más de 5 días de volumen medio para cubrirse.",
+		params: url.Values{
+			"min_days_to_cover": {"5"},
+			"sortBy":            {"days_to_cover"},
+			"order":             {"desc"},
+		},
+	},
+}
+
+// findScreen busca la pantalla llamada name.
+func findScreen(name string) (stockScreen, bool) {
+	for _, s := range stockScreens {
+		if s.name == name {
+			return s, true
+		}
+	}
+	return stockScreen{}, false
+}
+
+// applyScreen devuelve query con los parámetros de la pantalla indicada en screen
+// añadidos donde falten. Sin screen devuelve query sin cambios.
+func applyScreen(query url.Values) (url.Values, error) {
+	name := query.Get("screen")
+	if name == "" {
+		return query, nil
+	}
+	screen, ok := findScreen(name)
+	if !ok {
+		names := make([]string, 0, len(stockScreens))
+		for _, s := range stockScreens {
+			names = append(names, s.name)
+		}
+		return nil, fmt.Errorf("pantalla desconocida: %s (use %s)", name, strings.Join(names, ", "))
+	}
+	merged := url.Values{}
+	for key, values := range screen.params {
+		merged[key] = values
+	}
+	for key, values := range query {
+		merged[key] = values
+	}
+	return merged, nil
+}
+
+// screenPreset es una pantalla tal como la devuelve GET /screens/presets: la query que
+// reproduce el listado y su interpretación ya validada (orden y filtros por valor).
+type screenPreset struct {
+	Name        string             `json:"name"`
+	Title       string             `json:"title"`
+	Description string             `json:"description"`
+	Query       string             `json:"query"` // Parámetros de GET /stocks equivalentes a ?screen=name
+	SortBy      string             `json:"sort_by"`
+	Order       string             `json:"order"`
+	Filters     map[string]float64 `json:"filters"`
+}
+
+// preset valida la pantalla con las mismas reglas que GET /stocks y la describe.
+func (s stockScreen) preset() (screenPreset, error) {
+	if _, err := parseStockFilters(s.params); err != nil {
+		return screenPreset{}, fmt.Errorf("pantalla %s: %w", s.name, err)
+	}
+	sortBy, order := s.params.Get("sortBy"), s.params.Get("order")
+	if !database.IsSortField(sortBy) {
+		return screenPreset{}, fmt.Errorf("pantalla %s: campo de orden no soportado: %s", s.name, sortBy)
+	}
+	if order != "asc" && order != "desc" {
+		return screenPreset{}, fmt.Errorf("pantalla %s: orden no soportado: %s", s.name, order)
+	}
+
+	filters := map[string]float64{}
+	for _, p := range stockFilterParams {
+		if raw := s.params.Get(p.name); raw != "" {
+			filters[p.name], _ = strconv.ParseFloat(raw, 64) // Ya validado por parseStockFilters
+		}
+	}
+	return screenPreset{Name: s.name, Title: s.title, Description: s.description, Query: s.params.Encode(),
+		SortBy: sortBy, Order: order, Filters: filters}, nil
+}
+
+// GetScreenPresets lista las pantallas predefinidas para que el frontend las ofrezca como
+// accesos directos a GET /stocks?screen=nombre.
+func (h *StockHandlers) GetScreenPresets(w http.ResponseWriter, r *http.Request) {
+	presets := make([]screenPreset, 0, len(stockScreens))
+	for _, s := range stockScreens {
+		preset, err := s.preset()
+		if err != nil {
+			apierror.Internal(w, "Pantalla predefinida no válida", err)
+			return
+		}
+		presets = append(presets, preset)
+	}
+	writeJSON(w, r, http.StatusOK, presets)
+}
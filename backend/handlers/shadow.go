@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/config"
+	"github.com/jannin2/stock-app/backend/database"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// maxShadowInFlight limita las consultas sombra simultáneas para que el tráfico duplicado
+// no compita con las peticiones reales por las conexiones a la base de datos. Si se
+// alcanza el límite, la muestra se descarta.
+const maxShadowInFlight = 4
+
+// Reemplazables en los tests.
+var (
+	shadowRand  = rand.Float64
+	shadowLogf  = log.Printf
+	shadowSlots = make(chan struct{}, maxShadowInFlight)
+)
+
+// stocksPage es el resultado de una de las implementaciones de la lista de stocks.
+type stocksPage struct {
+	stocks      []models.Stock
+	total       int
+	approximate bool // total es una estimación y no se compara
+	latency     time.Duration
+}
+
+// shadowStocksPage repite en segundo plano, para una muestra de las peticiones, la consulta
+// de GET /stocks con GetStocksPage (v2) y registra las diferencias de resultado y latencia
+// con la implementación vigente (v1), leyendo de db (la misma instantánea que v1). Se activa
+// con el feature flag "shadow" y la fracción de config.ShadowSampleRate; la respuesta al
+// cliente siempre sale de v1.
+func (h *StockHandlers) shadowStocksPage(db database.StockDB, opts database.StockQueryOptions, v1 stocksPage) {
+	cfg := config.Current()
+	if !cfg.Enabled(config.FlagShadow) || cfg.ShadowSampleRate <= 0 || shadowRand() >= cfg.ShadowSampleRate {
+		return
+	}
+
+	select {
+	case shadowSlots <- struct{}{}:
+	default:
+		return
+	}
+	go func() {
+		defer func() { <-shadowSlots }()
+		h.compareStocksPage(db, opts, v1)
+	}()
+}
+
+// compareStocksPage ejecuta v2 y registra si coincide con v1.
+func (h *StockHandlers) compareStocksPage(db database.StockDB, opts database.StockQueryOptions, v1 stocksPage) {
+	// Se ejecuta después de responder, cuando el contexto de la petición ya está cancelado.
+	start := time.Now()
+	stocks, total, err := db.GetStocksPage(context.Background(), opts)
+	v2 := stocksPage{stocks: stocks, total: total, latency: time.Since(start)}
+	if err != nil {
+		shadowLogf("SHADOW GetAllStocks: v2 falló (%+v) tras %s: %v", opts, v2.latency, err)
+		return
+	}
+
+	if diffs := diffStocksPages(v1, v2); len(diffs) > 0 {
+		shadowLogf("SHADOW GetAllStocks: DIFERENCIAS (%+v) v1=%s v2=%s: %s", opts, v1.latency, v2.latency, strings.Join(diffs, "; "))
+		return
+	}
+	shadowLogf("SHADOW GetAllStocks: coincide (%+v) v1=%s v2=%s", opts, v1.latency, v2.latency)
+}
+
+// diffStocksPages describe las diferencias entre dos páginas: total (si v1 no es una
+// estimación), número de filas y, posición a posición, el stock devuelto y su contenido.
+// Devuelve nil si son iguales.
+func diffStocksPages(v1, v2 stocksPage) []string {
+	var diffs []string
+	if !v1.approximate && v1.total != v2.total {
+		diffs = append(diffs, fmt.Sprintf("total %d != %d", v1.total, v2.total))
+	}
+	if len(v1.stocks) != len(v2.stocks) {
+		diffs = append(diffs, fmt.Sprintf("filas %d != %d", len(v1.stocks), len(v2.stocks)))
+	}
+	for i := 0; i < len(v1.stocks) && i < len(v2.stocks); i++ {
+		a, b := v1.stocks[i], v2.stocks[i]
+		switch {
+		case a.ID != b.ID:
+			diffs = append(diffs, fmt.Sprintf("posición %d: %s != %s", i, a.Ticker, b.Ticker))
+		case !reflect.DeepEqual(a, b):
+			diffs = append(diffs, fmt.Sprintf("posición %d: contenido distinto de %s", i, a.Ticker))
+		}
+	}
+	return diffs
+}
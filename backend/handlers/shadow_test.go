@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/config"
+	"github.com/jannin2/stock-app/backend/database"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// pageStockDB devuelve una página fija desde GetStocksPage y avisa de cada llamada.
+type pageStockDB struct {
+	database.StockDB
+	stocks []models.Stock
+	total  int
+	calls  chan database.StockQueryOptions
+}
+
+func (f *pageStockDB) GetStocksPage(ctx context.Context, opts database.StockQueryOptions) ([]models.Stock, int, error) {
+	f.calls <- opts
+	return f.stocks, f.total, nil
+}
+
+func TestShadowStocksPage(t *testing.T) {
+	aapl := models.Stock{ID: uuid.New(), Ticker: "AAPL", CurrentPrice: 190}
+	ko := models.Stock{ID: uuid.New(), Ticker: "KO", CurrentPrice: 62.5}
+	db := &pageStockDB{stocks: []models.Stock{aapl, ko}, total: 2, calls: make(chan database.StockQueryOptions, 1)}
+	h := &StockHandlers{dbClient: db}
+
+	origLogf, origRand := shadowLogf, shadowRand
+	logs := make(chan string, 1)
+	shadowLogf = func(format string, args ...interface{}) { logs <- fmt.Sprintf(format, args...) }
+	draw := 0.5
+	shadowRand = func() float64 { return draw }
+	t.Cleanup(func() {
+		config.Set(config.Default())
+		shadowLogf, shadowRand = origLogf, origRand
+	})
+
+	cfg := config.Default()
+	cfg.ShadowSampleRate = 0.6
+	config.Set(cfg)
+	opts := database.StockQueryOptions{Limit: 2}
+	v1 := stocksPage{stocks: []models.Stock{aapl, ko}, total: 2}
+
+	// Sin el feature flag no hay tráfico sombra
+	h.shadowStocksPage(db, opts, v1)
+	select {
+	case <-db.calls:
+		t.Fatalf("❌ v2 no debería ejecutarse sin el flag shadow")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	cfg.FeatureFlags = map[string]bool{config.FlagShadow: true}
+	config.Set(cfg)
+	h.shadowStocksPage(db, opts, v1)
+	if got := <-db.calls; got != opts {
+		t.Errorf("❌ opciones de v2 inesperadas: %+v", got)
+	}
+	if msg := <-logs; !strings.Contains(msg, "coincide") {
+		t.Errorf("❌ se esperaba que v1 y v2 coincidieran: %s", msg)
+	}
+
+	// Fuera de la muestra no se ejecuta
+	draw = 0.7
+	h.shadowStocksPage(db, opts, v1)
+	select {
+	case <-db.calls:
+		t.Fatalf("❌ v2 no debería ejecutarse fuera de la muestra")
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestDiffStocksPages(t *testing.T) {
+	aapl := models.Stock{ID: uuid.New(), Ticker: "AAPL", CurrentPrice: 190}
+	ko := models.Stock{ID: uuid.New(), Ticker: "KO", CurrentPrice: 62.5}
+	staleKO := ko
+	staleKO.CurrentPrice = 61
+
+	if diffs := diffStocksPages(stocksPage{stocks: []models.Stock{aapl, ko}, total: 2}, stocksPage{stocks: []models.Stock{aapl, ko}, total: 2}); diffs != nil {
+		t.Errorf("❌ no se esperaban diferencias: %v", diffs)
+	}
+
+	diffs := diffStocksPages(
+		stocksPage{stocks: []models.Stock{aapl, ko}, total: 5},
+		stocksPage{stocks: []models.Stock{ko, staleKO, aapl}, total: 3},
+	)
+	want := []string{"total 5 != 3", "filas 2 != 3", "posición 0: AAPL != KO", "posición 1: contenido distinto de KO"}
+	if strings.Join(diffs, "|") != strings.Join(want, "|") {
+		t.Errorf("❌ diferencias inesperadas:\n%v\nse esperaba:\n%v", diffs, want)
+	}
+	approximate := stocksPage{stocks: []models.Stock{aapl, ko}, total: 120000, approximate: true}
+	if diffs := diffStocksPages(approximate, stocksPage{stocks: []models.Stock{aapl, ko}, total: 119873}); diffs != nil {
+		t.Errorf("❌ un total estimado no debería compararse: %v", diffs)
+	}
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/config"
+	"github.com/jannin2/stock-app/backend/database"
+	"github.com/jannin2/stock-app/backend/jobs"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// StatusPath es la ruta de la página de estado. Responde aunque la base de datos no esté
+// disponible (RequireReady no la bloquea), porque es justo cuando más se consulta.
+const StatusPath = "/api/v1/status"
+
+// Estados de un subsistema en GET /status, de mejor a peor.
+const (
+	statusOperational = "operational"
+	statusUnknown     = "unknown" // Aún no hay datos (ej. el enricher no ha terminado ninguna ejecución)
+	statusDegraded    = "degraded"
+	statusDown        = "down"
+)
+
+var statusRank = map[string]int{statusOperational: 0, statusUnknown: 0, statusDegraded: 1, statusDown: 2}
+
+// enrichmentTracker informa de la última ejecución correcta del enricher.
+type enrichmentTracker interface {
+	LastSuccess() time.Time
+}
+
+// StatusHandlers resume el estado de los subsistemas para la página de estado pública.
+type StatusHandlers struct {
+	readiness *database.Readiness
+	enricher  enrichmentTracker
+	providers func() []models.ProviderHealth // Normalmente providers.HealthReport
+	jobs      *jobs.Queue
+	now       func() time.Time
+}
+
+// NewStatusHandlers crea los manejadores de estado a partir de las fuentes de cada subsistema.
+func NewStatusHandlers(readiness *database.Readiness, enricher enrichmentTracker, providers func() []models.ProviderHealth, queue *jobs.Queue) *StatusHandlers {
+	return &StatusHandlers{readiness: readiness, enricher: enricher, providers: providers, jobs: queue, now: time.Now}
+}
+
+type componentStatus struct {
+	Status string `json:"status"`
+}
+
+type enrichmentStatus struct {
+	Status      string     `json:"status"`
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+	AgeSeconds  *int64     `json:"age_seconds,omitempty"`
+}
+
+type providerStatus struct {
+	Status string `json:"status"`
+	models.ProviderHealth
+}
+
+type queueStatus struct {
+	Status   string `json:"status"`
+	Depth    int    `json:"depth"`
+	Capacity int    `json:"capacity"`
+}
+
+// statusResponse es la respuesta de GET /status. status es el peor estado de los subsistemas.
+type statusResponse struct {
+	Status     string           `json:"status"`
+	CheckedAt  time.Time        `json:"checked_at"`
+	Database   componentStatus  `json:"database"`
+	Enrichment enrichmentStatus `json:"enrichment"`
+	Providers  []providerStatus `json:"providers"`
+	JobQueue   queueStatus      `json:"job_queue"`
+}
+
+// GetStatus maneja GET /status. Siempre responde 200: el estado va en el cuerpo para que
+// el widget de la página de estado pueda mostrarlo aunque algo esté caído.
+func (h *StatusHandlers) GetStatus(w http.ResponseWriter, r *http.Request) {
+	now := h.now()
+	resp := statusResponse{CheckedAt: now.UTC()}
+
+	resp.Database.Status = statusOperational
+	if !h.readiness.Ready() {
+		resp.Database.Status = statusDown
+	}
+
+	resp.Enrichment = h.enrichmentStatus(now)
+
+	resp.Providers = []providerStatus{}
+	for _, health := range h.providers() {
+		status := statusOperational
+		switch {
+		case health.Failing():
+			status = statusDegraded
+		case health.LastSuccess == nil:
+			status = statusUnknown
+		}
+		resp.Providers = append(resp.Providers, providerStatus{Status: status, ProviderHealth: health})
+	}
+
+	resp.JobQueue = queueStatus{Status: statusOperational, Depth: h.jobs.Depth(), Capacity: h.jobs.Capacity()}
+	if resp.JobQueue.Depth >= resp.JobQueue.Capacity {
+		resp.JobQueue.Status = statusDegraded
+	}
+
+	resp.Status = worstStatus(resp)
+	writeJSON(w, r, http.StatusOK, resp)
+}
+
+// enrichmentStatus considera degradado el enriquecimiento si la última ejecución correcta
+// es más antigua que dos intervalos del enricher.
+func (h *StatusHandlers) enrichmentStatus(now time.Time) enrichmentStatus {
+	last := h.enricher.LastSuccess()
+	if last.IsZero() {
+		return enrichmentStatus{Status: statusUnknown}
+	}
+	age := now.Sub(last)
+	seconds := int64(age / time.Second)
+	status := statusOperational
+	if age > 2*config.Current().EnrichmentInterval {
+		status = statusDegraded
+	}
+	lastUTC := last.UTC()
+	return enrichmentStatus{Status: status, LastSuccess: &lastUTC, AgeSeconds: &seconds}
+}
+
+func worstStatus(resp statusResponse) string {
+	statuses := []string{resp.Database.Status, resp.Enrichment.Status, resp.JobQueue.Status}
+	for _, p := range resp.Providers {
+		statuses = append(statuses, p.Status)
+	}
+	worst := statusOperational
+	for _, s := range statuses {
+		if statusRank[s] > statusRank[worst] {
+			worst = s
+		}
+	}
+	return worst
+}
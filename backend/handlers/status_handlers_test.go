@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/clock"
+	"github.com/jannin2/stock-app/backend/config"
+	"github.com/jannin2/stock-app/backend/database"
+	"github.com/jannin2/stock-app/backend/jobs"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// fixedEnricher devuelve siempre la misma última ejecución correcta.
+type fixedEnricher time.Time
+
+func (e fixedEnricher) LastSuccess() time.Time { return time.Time(e) }
+
+func TestGetStatus(t *testing.T) {
+	config.Set(config.Default()) // Enricher cada hora: degradado a partir de 2h sin éxito
+	t.Cleanup(func() { config.Set(config.Default()) })
+
+	now := time.Date(2025, 1, 6, 12, 0, 0, 0, time.UTC)
+	before, after := now.Add(-10*time.Minute), now.Add(-5*time.Minute)
+	health := []models.ProviderHealth{
+		{Provider: "alphavantage", LastSuccess: &before, LastError: &after, LastErrorMessage: "https://www.alphavantage.co/query?apikey=secreta"},
+		{Provider: "finnhub", LastSuccess: &after},
+	}
+	readiness := &database.Readiness{}
+	readiness.SetReady(true)
+	h := NewStatusHandlers(readiness, fixedEnricher(now.Add(-30*time.Minute)), func() []models.ProviderHealth { return health }, jobs.NewQueue(clock.NewMock(), 1, 10))
+	h.now = func() time.Time { return now }
+
+	get := func() (statusResponse, string) {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		h.GetStatus(rr, httptest.NewRequest(http.MethodGet, StatusPath, nil))
+		var resp statusResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil || rr.Code != http.StatusOK {
+			t.Fatalf("❌ respuesta inesperada: %d %s", rr.Code, rr.Body)
+		}
+		return resp, rr.Body.String()
+	}
+
+	resp, body := get()
+	if resp.Status != statusDegraded || resp.Database.Status != statusOperational || resp.Enrichment.Status != statusOperational {
+		t.Errorf("❌ estados inesperados: %s", body)
+	}
+	if len(resp.Providers) != 2 || resp.Providers[0].Status != statusDegraded || resp.Providers[1].Status != statusOperational {
+		t.Errorf("❌ estados de proveedores inesperados: %+v", resp.Providers)
+	}
+	if strings.Contains(body, "apikey") {
+		t.Errorf("❌ el mensaje de error del proveedor no debería ser público: %s", body)
+	}
+	if resp.Enrichment.AgeSeconds == nil || *resp.Enrichment.AgeSeconds != 1800 {
+		t.Errorf("❌ antigüedad del enriquecimiento inesperada: %s", body)
+	}
+
+	// Sin base de datos y con el enriquecimiento atrasado
+	readiness.SetReady(false)
+	h.enricher = fixedEnricher(now.Add(-3 * time.Hour))
+	health[0].LastSuccess = &now
+	resp, body = get()
+	if resp.Status != statusDown || resp.Database.Status != statusDown || resp.Enrichment.Status != statusDegraded {
+		t.Errorf("❌ estados inesperados con la base de datos caída: %s", body)
+	}
+
+	h.enricher = fixedEnricher(time.Time{})
+	if resp, _ := get(); resp.Enrichment.Status != statusUnknown {
+		t.Errorf("❌ sin ejecuciones el enriquecimiento debería ser unknown: %+v", resp.Enrichment)
+	}
+}
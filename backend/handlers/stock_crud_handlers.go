@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/apierror"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// Los stocks los crea y actualiza el enricher a partir del feed de Karenai. Estas rutas
+// permiten a un administrador corregir uno a mano: un stock creado o borrado aquí vuelve a
+// quedar en manos del enricher si el feed lo recomienda en la siguiente ejecución.
+
+// CreateStock maneja POST /stocks: crea un stock con los campos del cuerpo (los mismos que
+// devuelve GET /stocks/{id}). Responde 201 con el stock guardado y su URL en Location, o
+// 409 si el ticker ya existe.
+func (h *StockHandlers) CreateStock(w http.ResponseWriter, r *http.Request) {
+	stock, ok := decodeStockInput(w, r)
+	if !ok {
+		return
+	}
+
+	created, err := h.dbClient.CreateStock(r.Context(), stock)
+	if err != nil {
+		writeError(w, err, "Error al crear el stock")
+		return
+	}
+	w.Header().Set("Location", "/api/v1/stocks/"+created.ID.String())
+	writeJSON(w, r, http.StatusCreated, created)
+}
+
+// UpdateStock maneja PUT /stocks/{id}: reemplaza los campos del stock por los del cuerpo.
+// Los campos que no se envían quedan vacíos, como en una importación.
+func (h *StockHandlers) UpdateStock(w http.ResponseWriter, r *http.Request) {
+	id, ok := stockIDParam(w, r)
+	if !ok {
+		return
+	}
+	stock, ok := decodeStockInput(w, r)
+	if !ok {
+		return
+	}
+
+	updated, err := h.dbClient.UpdateStock(r.Context(), id, stock)
+	if err != nil {
+		writeError(w, err, "Error al actualizar el stock")
+		return
+	}
+	writeJSON(w, r, http.StatusOK, updated)
+}
+
+// DeleteStock maneja DELETE /stocks/{id}. Responde 204, o 404 si el stock no existe.
+func (h *StockHandlers) DeleteStock(w http.ResponseWriter, r *http.Request) {
+	id, ok := stockIDParam(w, r)
+	if !ok {
+		return
+	}
+	if err := h.dbClient.DeleteStock(r.Context(), id); err != nil {
+		writeError(w, err, "Error al borrar el stock")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// stockIDParam lee el {id} de la ruta, que debe ser un UUID, o responde 400.
+func stockIDParam(w http.ResponseWriter, r *http.Request) (string, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		apierror.HTTPError(w, "ID de stock inválido: debe ser un UUID", http.StatusBadRequest)
+		return "", false
+	}
+	return id.String(), true
+}
+
+// decodeStockInput lee y valida el stock del cuerpo con las mismas reglas que una fila de
+// POST /stocks/bulk, o responde 400.
+func decodeStockInput(w http.ResponseWriter, r *http.Request) (models.Stock, bool) {
+	raw, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBulkLineSize))
+	if err != nil {
+		apierror.HTTPError(w, fmt.Sprintf("No se pudo leer el cuerpo: %v", err), http.StatusBadRequest)
+		return models.Stock{}, false
+	}
+	stock, err := parseStockInput(raw, models.SourceManual, time.Now().UTC())
+	if err != nil {
+		apierror.HTTPError(w, fmt.Sprintf("Stock inválido: %v", err), http.StatusBadRequest)
+		return models.Stock{}, false
+	}
+	return stock, true
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/database"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// crudStockDB es un StockDB en memoria indexado por ID.
+type crudStockDB struct {
+	database.StockDB
+	stocks map[string]models.Stock
+}
+
+func (db *crudStockDB) CreateStock(ctx context.Context, s models.Stock) (models.Stock, error) {
+	for _, existing := range db.stocks {
+		if existing.Ticker == s.Ticker {
+			return models.Stock{}, fmt.Errorf("no se puede crear %s: %w", s.Ticker, database.ErrTickerExists)
+		}
+	}
+	s.ID = uuid.New()
+	db.stocks[s.ID.String()] = s
+	return s, nil
+}
+
+func (db *crudStockDB) UpdateStock(ctx context.Context, id string, s models.Stock) (models.Stock, error) {
+	if _, ok := db.stocks[id]; !ok {
+		return models.Stock{}, database.ErrStockNotFound
+	}
+	s.ID = uuid.MustParse(id)
+	db.stocks[id] = s
+	return s, nil
+}
+
+func (db *crudStockDB) DeleteStock(ctx context.Context, id string) error {
+	if _, ok := db.stocks[id]; !ok {
+		return database.ErrStockNotFound
+	}
+	delete(db.stocks, id)
+	return nil
+}
+
+func TestStockCRUD(t *testing.T) {
+	db := &crudStockDB{stocks: map[string]models.Stock{}}
+	h := NewStockHandlers(db, nil)
+	r := chi.NewRouter()
+	r.Post("/api/v1/stocks", h.CreateStock)
+	r.Put("/api/v1/stocks/{id}", h.UpdateStock)
+	r.Delete("/api/v1/stocks/{id}", h.DeleteStock)
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rr
+	}
+
+	rr := serve(http.MethodPost, "/api/v1/stocks", `{"ticker":" sap.de ","company":"SAP","current_price":210.5}`)
+	var created models.Stock
+	if rr.Code != http.StatusCreated || json.Unmarshal(rr.Body.Bytes(), &created) != nil {
+		t.Fatalf("❌ crear: estado %d, %s", rr.Code, rr.Body)
+	}
+	if created.Ticker != "SAP.DE" || created.Currency != "EUR" ||
+		db.stocks[created.ID.String()].Provenance["current_price"].Source != models.SourceManual {
+		t.Errorf("❌ stock creado inesperado: %+v", created)
+	}
+	if got, want := rr.Header().Get("Location"), "/api/v1/stocks/"+created.ID.String(); got != want {
+		t.Errorf("❌ Location = %q, se esperaba %q", got, want)
+	}
+
+	if rr := serve(http.MethodPost, "/api/v1/stocks", `{"ticker":"SAP.DE"}`); rr.Code != http.StatusConflict {
+		t.Errorf("❌ ticker repetido: estado %d, se esperaba 409", rr.Code)
+	}
+	if rr := serve(http.MethodPost, "/api/v1/stocks", `{"ticker":"AAPL","price":1}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("❌ campo desconocido: estado %d, se esperaba 400", rr.Code)
+	}
+
+	path := "/api/v1/stocks/" + created.ID.String()
+	if rr := serve(http.MethodPut, path, `{"ticker":"SAP.DE","company":"SAP SE","current_price":212}`); rr.Code != http.StatusOK {
+		t.Errorf("❌ actualizar: estado %d, %s", rr.Code, rr.Body)
+	}
+	if got := db.stocks[created.ID.String()]; got.Company != "SAP SE" || got.CurrentPrice != 212 {
+		t.Errorf("❌ stock actualizado inesperado: %+v", got)
+	}
+	if rr := serve(http.MethodPut, "/api/v1/stocks/no-es-un-uuid", `{"ticker":"SAP.DE"}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("❌ ID inválido: estado %d, se esperaba 400", rr.Code)
+	}
+
+	if rr := serve(http.MethodDelete, path, ""); rr.Code != http.StatusNoContent || len(db.stocks) != 0 {
+		t.Errorf("❌ borrar: estado %d, quedan %d stocks", rr.Code, len(db.stocks))
+	}
+	if rr := serve(http.MethodDelete, path, ""); rr.Code != http.StatusNotFound {
+		t.Errorf("❌ borrar dos veces: estado %d, se esperaba 404", rr.Code)
+	}
+}
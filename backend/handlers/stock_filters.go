@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/jannin2/stock-app/backend/database"
+)
+
+// maxTextFilterLength es la longitud máxima de los filtros de texto (action, brokerage).
+const maxTextFilterLength = 100
+
+// Operadores de los filtros, tal como los describe GET /stocks/schema.
+const (
+	filterOpGTE = "gte" // El campo es mayor o igual que el valor
+	filterOpLTE = "lte" // El campo es menor o igual que el valor
+	filterOpEq  = "eq"  // El campo es igual al valor, sin distinguir mayúsculas
+)
+
+// stockFilterParams son los parámetros de GET /stocks que filtran por el valor de un campo
+// numérico: el campo, el operador y el rango admitido.
+var stockFilterParams = []struct {
+	name, field, op string
+	min, max        float64
+	deprecated      bool // Nombre anterior de otro parámetro, que se sigue aceptando
+	set             func(f *database.StockFilters, v float64)
+}{
+	{"min_esg", "esg_score", filterOpGTE, 0, 100, false, func(f *database.StockFilters, v float64) { f.MinESG = &v }},
+	{"min_short_interest", "short_interest", filterOpGTE, 0, 1e12, false, func(f *database.StockFilters, v float64) { f.MinShortInterest = &v }},
+	{"min_days_to_cover", "days_to_cover", filterOpGTE, 0, 1000, false, func(f *database.StockFilters, v float64) { f.MinDaysToCover = &v }},
+	{"min_dividend_yield", "dividend_yield", filterOpGTE, 0, 100, false, func(f *database.StockFilters, v float64) { f.MinDividendYield = &v }},
+	{"min_market_cap", "market_capitalization", filterOpGTE, 0, 1e8, false, func(f *database.StockFilters, v float64) { f.MinMarketCap = &v }},
+	{"min_pe", "pe_ratio", filterOpGTE, 0, 1e4, false, func(f *database.StockFilters, v float64) { f.MinPERatio = &v }},
+	{"max_pe", "pe_ratio", filterOpLTE, 0, 1e4, false, func(f *database.StockFilters, v float64) { f.MaxPERatio = &v }},
+	{"max_pe_ratio", "pe_ratio", filterOpLTE, 0, 1e4, true, func(f *database.StockFilters, v float64) { f.MaxPERatio = &v }}, // Nombre anterior de max_pe
+	{"min_relative_strength", "relative_strength_90d", filterOpGTE, -1000, 1000, false, func(f *database.StockFilters, v float64) { f.MinRelativeStrength = &v }},
+	{"min_price", "current_price", filterOpGTE, 0, 1e7, false, func(f *database.StockFilters, v float64) { f.MinPrice = &v }},
+	{"max_price", "current_price", filterOpLTE, 0, 1e7, false, func(f *database.StockFilters, v float64) { f.MaxPrice = &v }},
+}
+
+// stockTextFilterParams son los parámetros de GET /stocks que filtran por el valor exacto
+// (sin distinguir mayúsculas) de un campo de texto; el nombre del parámetro es el del campo.
+var stockTextFilterParams = []struct {
+	name  string
+	value func(f *database.StockFilters) *string
+}{
+	{"action", func(f *database.StockFilters) *string { return &f.Action }},
+	{"brokerage", func(f *database.StockFilters) *string { return &f.Brokerage }},
+}
+
+// parseStockFilters lee los filtros de la query: los numéricos de stockFilterParams y los
+// de texto de stockTextFilterParams. Un parámetro ausente no filtra.
+func parseStockFilters(query url.Values) (database.StockFilters, error) {
+	var filters database.StockFilters
+	for _, p := range stockFilterParams {
+		raw := query.Get(p.name)
+		if raw == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil || math.IsNaN(v) || v < p.min || v > p.max {
+			return filters, fmt.Errorf("%s debe ser un número entre %g y %g", p.name, p.min, p.max)
+		}
+		p.set(&filters, v)
+	}
+	if filters.MinPrice != nil && filters.MaxPrice != nil && *filters.MinPrice > *filters.MaxPrice {
+		return filters, fmt.Errorf("min_price no puede ser mayor que max_price")
+	}
+	if filters.MinPERatio != nil && filters.MaxPERatio != nil && *filters.MinPERatio > *filters.MaxPERatio {
+		return filters, fmt.Errorf("min_pe no puede ser mayor que max_pe")
+	}
+
+	for _, p := range stockTextFilterParams {
+		value := strings.TrimSpace(query.Get(p.name))
+		if len(value) > maxTextFilterLength {
+			return filters, fmt.Errorf("%s admite como máximo %d caracteres", p.name, maxTextFilterLength)
+		}
+		*p.value(&filters) = value
+	}
+	return filters, nil
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/jannin2/stock-app/backend/database"
+)
+
+func TestParseStockFilters(t *testing.T) {
+	filters, err := parseStockFilters(url.Values{"min_esg": {"62.5"}})
+	if err != nil || filters.MinESG == nil || *filters.MinESG != 62.5 {
+		t.Errorf("❌ filtros inesperados: %+v (%v)", filters, err)
+	}
+
+	if filters, err := parseStockFilters(url.Values{}); err != nil || filters.MinESG != nil {
+		t.Errorf("❌ sin parámetros no debería filtrar: %+v (%v)", filters, err)
+	}
+
+	for _, raw := range []string{"abc", "-1", "101", "NaN"} {
+		if _, err := parseStockFilters(url.Values{"min_esg": {raw}}); err == nil {
+			t.Errorf("❌ se esperaba un error para min_esg=%s", raw)
+		}
+	}
+
+	filters, err = parseStockFilters(url.Values{"min_price": {"10"}, "max_price": {"50"}, "min_pe": {"5"}, "max_pe": {"20"},
+		"action": {" upgraded by "}, "brokerage": {"Goldman Sachs"}})
+	if err != nil || *filters.MinPrice != 10 || *filters.MaxPrice != 50 || *filters.MinPERatio != 5 || *filters.MaxPERatio != 20 ||
+		filters.Action != "upgraded by" || filters.Brokerage != "Goldman Sachs" {
+		t.Errorf("❌ filtros inesperados: %+v (%v)", filters, err)
+	}
+	// max_pe_ratio sigue aceptándose como nombre anterior de max_pe
+	if filters, err := parseStockFilters(url.Values{"max_pe_ratio": {"12"}}); err != nil || *filters.MaxPERatio != 12 {
+		t.Errorf("❌ max_pe_ratio: %+v (%v)", filters, err)
+	}
+
+	for _, query := range []url.Values{
+		{"min_price": {"50"}, "max_price": {"10"}},
+		{"min_pe": {"30"}, "max_pe": {"20"}},
+		{"max_price": {"-1"}},
+		{"brokerage": {strings.Repeat("x", maxTextFilterLength+1)}},
+	} {
+		if _, err := parseStockFilters(query); err == nil {
+			t.Errorf("❌ se esperaba un error para %v", query)
+		}
+	}
+}
+
+func TestApplyScreen(t *testing.T) {
+	query, err := applyScreen(url.Values{"screen": {"heavily_shorted"}, "min_days_to_cover": {"10"}, "limit": {"5"}})
+	if err != nil {
+		t.Fatalf("❌ error inesperado: %v", err)
+	}
+	// Lo enviado por el cliente prevalece sobre la pantalla
+	if query.Get("min_days_to_cover") != "10" || query.Get("sortBy") != "days_to_cover" || query.Get("limit") != "5" {
+		t.Errorf("❌ parámetros inesperados: %v", query)
+	}
+	filters, err := parseStockFilters(query)
+	if err != nil || filters.MinDaysToCover == nil || *filters.MinDaysToCover != 10 {
+		t.Errorf("❌ filtros inesperados: %+v (%v)", filters, err)
+	}
+
+	if _, err := applyScreen(url.Values{"screen": {"meme"}}); err == nil {
+		t.Error("❌ se esperaba un error para una pantalla desconocida")
+	}
+}
+
+func TestGetScreenPresets(t *testing.T) {
+	rr := httptest.NewRecorder()
+	NewStockHandlers(nil, nil).GetScreenPresets(rr, httptest.NewRequest(http.MethodGet, "/api/v1/screens/presets", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("❌ código %d, se esperaba 200: %s", rr.Code, rr.Body.String())
+	}
+	var presets []screenPreset
+	if err := json.Unmarshal(rr.Body.Bytes(), &presets); err != nil || len(presets) != len(stockScreens) {
+		t.Fatalf("❌ respuesta inesperada: %s (%v)", rr.Body.String(), err)
+	}
+	deep := presets[2]
+	if deep.Name != "deep_value" || deep.SortBy != "pe_ratio" || deep.Order != "asc" || deep.Filters["max_pe"] != 12 {
+		t.Errorf("❌ pantalla deep_value inesperada: %+v", deep)
+	}
+
+	// La query de cada pantalla produce los mismos filtros que ?screen=
+	for _, p := range presets {
+		query, _ := url.ParseQuery(p.Query)
+		direct, err := parseStockFilters(query)
+		screened, _ := applyScreen(url.Values{"screen": {p.Name}})
+		viaScreen, _ := parseStockFilters(screened)
+		if err != nil || !filtersEqual(direct, viaScreen) {
+			t.Errorf("❌ la query de %s no equivale a la pantalla: %s (%v)", p.Name, p.Query, err)
+		}
+	}
+}
+
+// filtersEqual compara los filtros por valor, no por puntero.
+func filtersEqual(a, b database.StockFilters) bool {
+	ja, _ := json.Marshal(a)
+	jb, _ := json.Marshal(b)
+	return string(ja) == string(jb)
+}
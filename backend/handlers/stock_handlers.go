@@ -1,38 +1,105 @@
 package handlers
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/jannin2/stock-app/backend/apierror"
+	"github.com/jannin2/stock-app/backend/config"
 	"github.com/jannin2/stock-app/backend/database"
+	"github.com/jannin2/stock-app/backend/jobs"
+	"github.com/jannin2/stock-app/backend/models"
 )
 
 // StockHandlers contiene la interfaz de la base de datos.
 type StockHandlers struct {
 	dbClient database.StockDB
+	jobs     *jobs.Queue  // Importaciones en segundo plano
+	exports  *exportSpool // Exportaciones completas en disco para reanudar descargas con Range
 }
 
 // NewStockHandlers crea una nueva instancia de StockHandlers.
-// Recibe la interfaz StockDB como dependencia.
-func NewStockHandlers(dbClient database.StockDB) *StockHandlers {
-	return &StockHandlers{dbClient: dbClient}
+// Recibe la interfaz StockDB y la cola de trabajos como dependencias.
+func NewStockHandlers(dbClient database.StockDB, queue *jobs.Queue) *StockHandlers {
+	return &StockHandlers{dbClient: dbClient, jobs: queue, exports: newExportSpool()}
 }
 
-// GetStocks maneja la obtención de una lista de stocks con paginación, búsqueda y ordenamiento.
+// totalCountApproximateHeader marca que X-Total-Count es una estimación: el listado sin
+// búsqueda ni filtros de una tabla grande no cuenta las filas (ver
+// database.EstimateStockCount).
+const totalCountApproximateHeader = "X-Total-Count-Approximate"
+
+// LegacyPaginationHeader es la cabecera con la que un cliente anterior al sobre de
+// paginación ("X-Legacy-Pagination: true") recibe el listado como un array JSON, con el
+// total en X-Total-Count y totalCountApproximateHeader.
+const LegacyPaginationHeader = "X-Legacy-Pagination"
+
+// paginatedResponse es el sobre de los listados paginados: la página en Data y lo necesario
+// para pedir la siguiente sin leer cabeceras. Si se sirve desde la última copia porque la
+// base de datos no está disponible lleva además "degraded": true (api.ResponseCache.Fallback).
+type paginatedResponse struct {
+	Data             interface{} `json:"data"`
+	Total            int         `json:"total"`
+	TotalApproximate bool        `json:"total_approximate,omitempty"` // Total estimado, ver database.EstimateStockCount
+	Limit            int         `json:"limit"`
+	Offset           int         `json:"offset"`
+	NextOffset       *int        `json:"next_offset"` // null en la última página
+	AsOf             *time.Time  `json:"as_of"`       // Instantánea leída, como asOfHeader; null si no se pudo fijar
+}
+
+// newPaginatedResponse construye el sobre de una página de count elementos.
+func newPaginatedResponse(data interface{}, count, total int, approximate bool, limit, offset int) paginatedResponse {
+	page := paginatedResponse{Data: data, Total: total, TotalApproximate: approximate, Limit: limit, Offset: offset}
+	// Con un total aproximado no se sabe dónde acaba: hay más mientras la página esté llena.
+	if next := offset + count; count > 0 && (next < total || approximate && count == limit) {
+		page.NextOffset = &next
+	}
+	return page
+}
+
+// GetStocks maneja la obtención de una lista de stocks con paginación, búsqueda, filtros
+// (rangos numéricos, acción y casa de bolsa, ver parseStockFilters) y ordenamiento por uno
+// o varios campos (sortBy=recommendation_score:desc,ticker:asc), opcionalmente partiendo de
+// una pantalla predefinida (?screen=). La página se devuelve en un paginatedResponse, o
+// como array con LegacyPaginationHeader; con ?cursor= se pagina por cursor en lugar de con
+// offset (ver getStocksByCursor).
 func (h *StockHandlers) GetStocks(w http.ResponseWriter, r *http.Request) {
-	limitStr := r.URL.Query().Get("limit")
-	offsetStr := r.URL.Query().Get("offset")
-	searchQuery := r.URL.Query().Get("search")
-	sortBy := r.URL.Query().Get("sortBy")
-	order := r.URL.Query().Get("order")
+	query, err := applyScreen(r.URL.Query())
+	if err != nil {
+		apierror.HTTPError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	limitStr := query.Get("limit")
+	offsetStr := query.Get("offset")
+	searchQuery := query.Get("search")
+	sortBy := query.Get("sortBy")
+	order := query.Get("order")
+	view := query.Get("view")
+	if !isValidView(view) {
+		apierror.HTTPError(w, fmt.Sprintf("Valor de view no soportado: %s (use compact o full)", view), http.StatusBadRequest)
+		return
+	}
+	filters, err := parseStockFilters(query)
+	if err != nil {
+		apierror.HTTPError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if sortBy != "" {
+		if err := database.ValidateSort(sortBy); err != nil {
+			apierror.HTTPError(w, fmt.Sprintf("sortBy inválido: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
 
 	limit, err := strconv.Atoi(limitStr)
 	if err != nil || limit <= 0 {
-		limit = 10 // Límite por defecto
+		limit = config.Current().DefaultPageSize
 	}
 	offset, err := strconv.Atoi(offsetStr)
 	if err != nil || offset < 0 {
@@ -45,43 +112,192 @@ func (h *StockHandlers) GetStocks(w http.ResponseWriter, r *http.Request) {
 		Order:  strings.ToLower(order),
 		Limit:  limit,
 		Offset: offset,
+
+		Filters: filters,
+	}
+	if query.Has("cursor") {
+		if offsetStr != "" {
+			apierror.HTTPError(w, "cursor y offset no se pueden combinar", http.StatusBadRequest)
+			return
+		}
+		h.getStocksByCursor(w, r, opts, query.Get("cursor"), view)
+		return
 	}
 
-	// Llama a los métodos de la interfaz StockDB a través de h.dbClient
-	stocks, err := h.dbClient.GetAllStocks(opts)
+	// La página y el total se leen de la misma instantánea
+	db, asOf := h.snapshot(w, r)
+	start := time.Now()
+	stocks, err := db.GetAllStocks(r.Context(), opts)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Error al obtener stocks: %v", err), http.StatusInternalServerError)
+		apierror.Internal(w, "Error al obtener stocks", err)
 		return
 	}
 
-	totalCount, err := h.dbClient.GetStockCount(searchQuery)
+	totalCount, approximate, err := db.EstimateStockCount(r.Context(), searchQuery, filters)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Error al obtener el conteo de stocks: %v", err), http.StatusInternalServerError)
+		apierror.Internal(w, "Error al obtener el conteo de stocks", err)
 		return
 	}
+	h.shadowStocksPage(db, opts, stocksPage{stocks: stocks, total: totalCount, approximate: approximate, latency: time.Since(start)})
+
+	setDataAsOf(w, stocks)
+	if r.Header.Get(LegacyPaginationHeader) == "true" {
+		w.Header().Set("X-Total-Count", strconv.Itoa(totalCount))
+		if approximate {
+			w.Header().Set(totalCountApproximateHeader, "true")
+		}
+		writeJSON(w, r, http.StatusOK, shapeStocks(view, stocks))
+		return
+	}
+	page := newPaginatedResponse(shapeStocks(view, stocks), len(stocks), totalCount, approximate, limit, offset)
+	page.AsOf = asOf
+	writeJSON(w, r, http.StatusOK, page)
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("X-Total-Count", strconv.Itoa(totalCount))
-	json.NewEncoder(w).Encode(stocks)
+// cursorPage es el sobre de los listados paginados por cursor: la página y el token con el
+// que pedir la siguiente.
+type cursorPage struct {
+	Data             interface{} `json:"data"`
+	Total            int         `json:"total"`
+	TotalApproximate bool        `json:"total_approximate,omitempty"`
+	Limit            int         `json:"limit"`
+	NextCursor       *string     `json:"next_cursor"` // null en la última página
+	AsOf             *time.Time  `json:"as_of"`       // Como en paginatedResponse
+}
+
+// getStocksByCursor sirve GET /stocks?cursor=: con el cursor vacío, la primera página; con
+// el next_cursor de una respuesta anterior, la siguiente. El cursor solo vale para el mismo
+// orden; la búsqueda y los filtros deben ser también los mismos para recorrer un listado
+// coherente. Siempre responde con cursorPage, también con LegacyPaginationHeader.
+func (h *StockHandlers) getStocksByCursor(w http.ResponseWriter, r *http.Request, opts database.StockQueryOptions, token, view string) {
+	if err := database.ValidateKeysetSort(opts.SortBy, opts.Order); err != nil {
+		apierror.HTTPError(w, fmt.Sprintf("sortBy inválido: %v", err), http.StatusBadRequest)
+		return
+	}
+	var after *database.StockCursor
+	if token != "" {
+		cursor, err := database.DecodeStockCursor(token)
+		if err != nil {
+			apierror.HTTPError(w, "Cursor inválido", http.StatusBadRequest)
+			return
+		}
+		after = &cursor
+	}
+
+	db, asOf := h.snapshot(w, r)
+	stocks, next, err := db.GetStocksByCursor(r.Context(), opts, after)
+	if errors.Is(err, database.ErrCursorMismatch) {
+		apierror.HTTPError(w, "El cursor no corresponde a este orden (sortBy y order deben ser los de la primera página)", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		apierror.Internal(w, "Error al obtener stocks", err)
+		return
+	}
+	totalCount, approximate, err := db.EstimateStockCount(r.Context(), opts.Search, opts.Filters)
+	if err != nil {
+		apierror.Internal(w, "Error al obtener el conteo de stocks", err)
+		return
+	}
+
+	page := cursorPage{Data: shapeStocks(view, stocks), Total: totalCount, TotalApproximate: approximate, Limit: opts.Limit, AsOf: asOf}
+	if next != nil {
+		encoded := next.Encode()
+		page.NextCursor = &encoded
+	}
+	setDataAsOf(w, stocks)
+	writeJSON(w, r, http.StatusOK, page)
 }
 
 // GetStockByID maneja la obtención de un stock por su ID.
 func (h *StockHandlers) GetStockByID(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
-		http.Error(w, "Se requiere el ID del stock", http.StatusBadRequest)
+		apierror.HTTPError(w, "Se requiere el ID del stock", http.StatusBadRequest)
 		return
 	}
+	includeProvenance := false
+	if include := r.URL.Query().Get("include"); include != "" {
+		for _, part := range strings.Split(include, ",") {
+			switch strings.TrimSpace(part) {
+			case includeProvenanceParam:
+				includeProvenance = true
+			default:
+				apierror.HTTPError(w, fmt.Sprintf("Valor de include no soportado: %s (use provenance)", part), http.StatusBadRequest)
+				return
+			}
+		}
+	}
 
 	// Llama al método de la interfaz StockDB a través de h.dbClient
-	stock, err := h.dbClient.GetStockByID(id)
+	stock, err := h.dbClient.GetStockByID(r.Context(), id)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Stock no encontrado: %v", err), http.StatusNotFound)
+		writeError(w, err, "Error al obtener el stock")
+		return
+	}
+
+	setDataAsOf(w, []models.Stock{stock})
+	if includeProvenance {
+		provenance := stock.Provenance
+		if provenance == nil {
+			provenance = models.Provenance{} // Stocks aún no enriquecidos: objeto vacío en lugar de null
+		}
+		writeJSON(w, r, http.StatusOK, stockWithProvenance{Stock: stock, Provenance: provenance})
 		return
 	}
+	writeJSON(w, r, http.StatusOK, stock)
+}
+
+// Las respuestas de stocks llevan dos marcas de tiempo distintas:
+//
+//   - dataAsOfHeader (X-Data-As-Of) es el updated_at más reciente de los stocks de la
+//     respuesta: cuándo se actualizaron por última vez los datos, para mostrar su antigüedad.
+//   - asOfHeader (X-As-Of) y el campo as_of de los listados son la instantánea de la que se
+//     leyó el listado (ver snapshot): todas sus filas son las de ese instante. Sin ninguna
+//     ejecución del enricher en curso es la hora de la petición, así que no sirve para
+//     saber si los datos son recientes.
+const dataAsOfHeader = "X-Data-As-Of"
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(stock)
+// setDataAsOf fija dataAsOfHeader con el updated_at más reciente de stocks (en RFC 3339).
+// Si no hay stocks no se envía la cabecera.
+func setDataAsOf(w http.ResponseWriter, stocks []models.Stock) {
+	var latest time.Time
+	for _, s := range stocks {
+		if s.UpdatedAt.After(latest) {
+			latest = s.UpdatedAt
+		}
+	}
+	if !latest.IsZero() {
+		w.Header().Set(dataAsOfHeader, latest.UTC().Format(time.RFC3339))
+	}
+}
+
+// asOfHeader es la instantánea de la que se leyó un listado (ver snapshot), en RFC 3339.
+const asOfHeader = "X-As-Of"
+
+// snapshot devuelve la base de datos fijada en la instantánea de enriquecimiento vigente,
+// para que un listado nunca mezcle filas de dos ejecuciones del enricher aunque se resuelva
+// con varias consultas, y esa instantánea, que además publica en asOfHeader. Si no se puede
+// obtener se lee el estado actual y el instante es nil: es preferible a no responder.
+func (h *StockHandlers) snapshot(w http.ResponseWriter, r *http.Request) (database.StockDB, *time.Time) {
+	asOf, err := h.dbClient.EnrichmentSnapshot(r.Context())
+	if err != nil {
+		log.Printf("Advertencia: se lee el estado actual de los stocks: %v", err)
+		return h.dbClient, nil
+	}
+	asOf = asOf.UTC()
+	w.Header().Set(asOfHeader, asOf.Format(time.RFC3339Nano))
+	return h.dbClient.AsOf(asOf), &asOf
+}
+
+// includeProvenanceParam es el valor de ?include= que añade el origen de cada campo.
+const includeProvenanceParam = "provenance"
+
+// stockWithProvenance es la respuesta de GetStockByID con ?include=provenance: el stock
+// más el proveedor y la hora de obtención de cada campo enriquecido.
+type stockWithProvenance struct {
+	models.Stock
+	Provenance models.Provenance `json:"provenance"`
 }
 
 // GetRecommendedStocks maneja la obtención de stocks recomendados.
@@ -91,14 +307,84 @@ func (h *StockHandlers) GetRecommendedStocks(w http.ResponseWriter, r *http.Requ
 	if err != nil || limit <= 0 {
 		limit = 5 // Límite por defecto para stocks recomendados
 	}
+	view := r.URL.Query().Get("view")
+	if !isValidView(view) {
+		apierror.HTTPError(w, fmt.Sprintf("Valor de view no soportado: %s (use compact o full)", view), http.StatusBadRequest)
+		return
+	}
 
-	// Llama al método de la interfaz StockDB a través de h.dbClient
-	stocks, err := h.dbClient.GetRecommendedStocks(limit)
+	// Con group_by se devuelven buckets (ej. group_by=sector,market_cap_tier,dividend) en una sola respuesta.
+	// En ese caso limit es el número máximo de stocks por bucket.
+	if groupByParam := r.URL.Query().Get("group_by"); groupByParam != "" {
+		h.getRecommendedBuckets(w, r, groupByParam, limit)
+		return
+	}
+
+	db, _ := h.snapshot(w, r)
+	stocks, err := db.GetRecommendedStocks(r.Context(), limit)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Error al obtener stocks recomendados: %v", err), http.StatusInternalServerError)
+		apierror.Internal(w, "Error al obtener stocks recomendados", err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(stocks)
+	setDataAsOf(w, stocks)
+	writeJSON(w, r, http.StatusOK, shapeStocks(view, stocks))
+}
+
+// getRecommendedBuckets responde con un objeto que contiene, por cada agrupación solicitada,
+// la lista de buckets con sus stocks recomendados.
+func (h *StockHandlers) getRecommendedBuckets(w http.ResponseWriter, r *http.Request, groupByParam string, perBucket int) {
+	result := make(map[string][]models.StockBucket)
+	var stocks []models.Stock // Todos los stocks de la respuesta, para X-Data-As-Of
+	db, _ := h.snapshot(w, r) // Todas las agrupaciones salen de la misma instantánea
+	for _, groupBy := range strings.Split(groupByParam, ",") {
+		groupBy = strings.TrimSpace(groupBy)
+		if groupBy == "" {
+			continue
+		}
+		if !database.IsValidRecommendedGrouping(groupBy) {
+			apierror.HTTPError(w, fmt.Sprintf("Valor de group_by no soportado: %s", groupBy), http.StatusBadRequest)
+			return
+		}
+
+		buckets, err := db.GetRecommendedBuckets(r.Context(), groupBy, perBucket)
+		if err != nil {
+			apierror.Internal(w, "Error al obtener buckets de stocks recomendados", err)
+			return
+		}
+		if buckets == nil {
+			buckets = []models.StockBucket{} // Serializar como [] en lugar de null
+		}
+		result[groupBy] = buckets
+		for _, b := range buckets {
+			stocks = append(stocks, b.Stocks...)
+		}
+	}
+
+	setDataAsOf(w, stocks)
+	writeJSON(w, r, http.StatusOK, result)
+}
+
+// Vistas soportadas por el parámetro ?view= de los endpoints de listas.
+const (
+	viewFull    = "full"
+	viewCompact = "compact"
+)
+
+// isValidView indica si el valor de ?view= es soportado. Un valor vacío equivale a viewFull.
+func isValidView(view string) bool {
+	return view == "" || view == viewFull || view == viewCompact
+}
+
+// shapeStocks devuelve la representación de la lista de stocks según la vista solicitada.
+// La vista compacta reduce cada fila a ticker, compañía, precio, cambio diario y score.
+func shapeStocks(view string, stocks []models.Stock) interface{} {
+	if view != viewCompact {
+		return stocks
+	}
+	compact := make([]models.CompactStock, 0, len(stocks))
+	for _, s := range stocks {
+		compact = append(compact, s.Compact())
+	}
+	return compact
 }
@@ -0,0 +1,302 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/database"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+func TestGetStockByID_IncludeProvenance(t *testing.T) {
+	fetchedAt := time.Date(2025, 1, 6, 14, 30, 0, 0, time.UTC)
+	stock := models.Stock{
+		ID: uuid.New(), Ticker: "KO", CurrentPrice: 62.5, PERatio: models.NewNullFloat64(24.1), UpdatedAt: fetchedAt.Add(time.Minute),
+		Provenance: models.Provenance{
+			"pe_ratio":      {Source: "alphavantage", FetchedAt: fetchedAt},
+			"current_price": {Source: "finnhub", FetchedAt: fetchedAt},
+		},
+	}
+	router := chi.NewRouter()
+	router.Get("/api/v1/stocks/{id}", (&StockHandlers{dbClient: &singleStockDB{stock: stock}}).GetStockByID)
+
+	get := func(query string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/stocks/"+stock.ID.String()+query, nil))
+		return rr
+	}
+
+	// Sin include la respuesta no cambia
+	rr := get("")
+	var plain map[string]json.RawMessage
+	if err := json.Unmarshal(rr.Body.Bytes(), &plain); err != nil {
+		t.Fatalf("❌ JSON inválido: %v", err)
+	}
+	if _, ok := plain["provenance"]; ok {
+		t.Errorf("❌ provenance no debería incluirse sin ?include=provenance")
+	}
+	if got := rr.Header().Get(dataAsOfHeader); got != "2025-01-06T14:31:00Z" {
+		t.Errorf("❌ %s = %q, se esperaba 2025-01-06T14:31:00Z", dataAsOfHeader, got)
+	}
+
+	rr = get("?include=provenance")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("❌ estado %d, se esperaba 200: %s", rr.Code, rr.Body.String())
+	}
+	var resp struct {
+		Ticker     string            `json:"ticker"`
+		Provenance models.Provenance `json:"provenance"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("❌ JSON inválido: %v", err)
+	}
+	if resp.Ticker != "KO" {
+		t.Errorf("❌ ticker = %q, se esperaba KO", resp.Ticker)
+	}
+	pe := resp.Provenance["pe_ratio"]
+	if pe.Source != "alphavantage" || !pe.FetchedAt.Equal(fetchedAt) {
+		t.Errorf("❌ origen de pe_ratio inesperado: %+v", pe)
+	}
+
+	if rr := get("?include=history"); rr.Code != http.StatusBadRequest {
+		t.Errorf("❌ estado %d para include desconocido, se esperaba 400", rr.Code)
+	}
+}
+
+// snapshotStockDB registra la instantánea con la que se lee cada consulta del listado.
+type snapshotStockDB struct {
+	database.StockDB
+	snapshot    time.Time
+	asOf        time.Time // De la vista; cero en la base de datos original
+	reads       *[]time.Time
+	approximate bool // Si el total es una estimación
+}
+
+func (db *snapshotStockDB) EnrichmentSnapshot(ctx context.Context) (time.Time, error) {
+	return db.snapshot, nil
+}
+
+func (db *snapshotStockDB) AsOf(t time.Time) database.StockDB {
+	view := *db
+	view.asOf = t
+	return &view
+}
+
+func (db *snapshotStockDB) GetAllStocks(context.Context, database.StockQueryOptions) ([]models.Stock, error) {
+	*db.reads = append(*db.reads, db.asOf)
+	return []models.Stock{{Ticker: "AAPL"}}, nil
+}
+
+func (db *snapshotStockDB) GetStocksByCursor(context.Context, database.StockQueryOptions, *database.StockCursor) ([]models.Stock, *database.StockCursor, error) {
+	*db.reads = append(*db.reads, db.asOf)
+	return []models.Stock{{Ticker: "AAPL"}}, nil, nil
+}
+
+func (db *snapshotStockDB) EstimateStockCount(context.Context, string, database.StockFilters) (int, bool, error) {
+	*db.reads = append(*db.reads, db.asOf)
+	return 1, db.approximate, nil
+}
+
+func TestGetStocks_ReadsOneSnapshot(t *testing.T) {
+	snapshot := time.Date(2025, 1, 6, 9, 0, 0, 500, time.UTC)
+	var reads []time.Time
+	h := NewStockHandlers(&snapshotStockDB{snapshot: snapshot, reads: &reads}, nil)
+
+	rr := httptest.NewRecorder()
+	h.GetStocks(rr, httptest.NewRequest(http.MethodGet, "/api/v1/stocks?limit=1", nil))
+	if rr.Code != http.StatusOK || rr.Header().Get(asOfHeader) != "2025-01-06T09:00:00.0000005Z" {
+		t.Fatalf("❌ respuesta inesperada: %d, %s=%q", rr.Code, asOfHeader, rr.Header().Get(asOfHeader))
+	}
+	if len(reads) != 2 || !reads[0].Equal(snapshot) || !reads[1].Equal(snapshot) {
+		t.Errorf("❌ la página y el total deberían leerse en la instantánea: %v", reads)
+	}
+	var page struct {
+		AsOf *time.Time `json:"as_of"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &page); err != nil || page.AsOf == nil || !page.AsOf.Equal(snapshot) {
+		t.Errorf("❌ as_of = %v (%v), se esperaba %s", page.AsOf, err, snapshot)
+	}
+
+	// El sobre de la paginación por cursor lleva la misma instantánea
+	rr = httptest.NewRecorder()
+	h.GetStocks(rr, httptest.NewRequest(http.MethodGet, "/api/v1/stocks?limit=1&cursor=", nil))
+	page.AsOf = nil
+	if err := json.Unmarshal(rr.Body.Bytes(), &page); err != nil || page.AsOf == nil || !page.AsOf.Equal(snapshot) {
+		t.Errorf("❌ as_of con cursor = %v (%v): %s", page.AsOf, err, rr.Body)
+	}
+}
+
+func TestGetStocks_FlagsApproximateTotal(t *testing.T) {
+	for _, approximate := range []bool{false, true} {
+		var reads []time.Time
+		h := NewStockHandlers(&snapshotStockDB{reads: &reads, approximate: approximate}, nil)
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/stocks", nil)
+		req.Header.Set(LegacyPaginationHeader, "true")
+		h.GetStocks(rr, req)
+		if rr.Code != http.StatusOK || rr.Header().Get("X-Total-Count") != "1" {
+			t.Fatalf("❌ respuesta inesperada: %d, X-Total-Count=%q", rr.Code, rr.Header().Get("X-Total-Count"))
+		}
+		if got := rr.Header().Get(totalCountApproximateHeader) == "true"; got != approximate {
+			t.Errorf("❌ %s presente=%v, se esperaba %v", totalCountApproximateHeader, got, approximate)
+		}
+	}
+}
+
+func TestGetStocks_Envelope(t *testing.T) {
+	var reads []time.Time
+	h := NewStockHandlers(&snapshotStockDB{reads: &reads}, nil)
+	rr := httptest.NewRecorder()
+	h.GetStocks(rr, httptest.NewRequest(http.MethodGet, "/api/v1/stocks?limit=1", nil))
+	if rr.Code != http.StatusOK || rr.Header().Get("X-Total-Count") != "" {
+		t.Fatalf("❌ respuesta inesperada: %d, X-Total-Count=%q", rr.Code, rr.Header().Get("X-Total-Count"))
+	}
+	var page struct {
+		Data       []models.Stock `json:"data"`
+		Total      int            `json:"total"`
+		Limit      int            `json:"limit"`
+		Offset     int            `json:"offset"`
+		NextOffset *int           `json:"next_offset"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &page); err != nil {
+		t.Fatalf("❌ el sobre no es JSON válido: %v", err)
+	}
+	if len(page.Data) != 1 || page.Total != 1 || page.Limit != 1 || page.Offset != 0 || page.NextOffset != nil {
+		t.Errorf("❌ sobre inesperado: %+v", page)
+	}
+}
+
+func TestGetStocks_InvalidSort(t *testing.T) {
+	var reads []time.Time
+	h := NewStockHandlers(&snapshotStockDB{reads: &reads}, nil)
+	for _, sortBy := range []string{"bogus", "recommendation_score:desc,bogus", "ticker:sideways"} {
+		rr := httptest.NewRecorder()
+		h.GetStocks(rr, httptest.NewRequest(http.MethodGet, "/api/v1/stocks?sortBy="+url.QueryEscape(sortBy), nil))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("❌ sortBy=%s: estado %d, se esperaba 400", sortBy, rr.Code)
+		}
+	}
+	if len(reads) != 0 {
+		t.Errorf("❌ un orden inválido no debe consultar la base de datos: %v", reads)
+	}
+
+	rr := httptest.NewRecorder()
+	h.GetStocks(rr, httptest.NewRequest(http.MethodGet, "/api/v1/stocks?sortBy=recommendation_score:desc,ticker:asc", nil))
+	if rr.Code != http.StatusOK {
+		t.Errorf("❌ orden compuesto: estado %d, se esperaba 200: %s", rr.Code, rr.Body.String())
+	}
+}
+
+// cursorStockDB pagina por cursor una lista fija de stocks ordenada por ticker.
+type cursorStockDB struct {
+	snapshotStockDB
+	stocks []models.Stock
+}
+
+func (db *cursorStockDB) AsOf(time.Time) database.StockDB {
+	return db
+}
+
+func (db *cursorStockDB) GetStocksByCursor(_ context.Context, opts database.StockQueryOptions, after *database.StockCursor) ([]models.Stock, *database.StockCursor, error) {
+	if after != nil && opts.SortBy != "" && after.Sort != opts.SortBy+":asc" {
+		return nil, nil, database.ErrCursorMismatch
+	}
+	start := 0
+	for after != nil && start < len(db.stocks) && db.stocks[start].Ticker <= after.Values[0].(string) {
+		start++
+	}
+	end := min(start+opts.Limit, len(db.stocks))
+	var next *database.StockCursor
+	if end < len(db.stocks) {
+		last := db.stocks[end-1]
+		next = &database.StockCursor{Sort: "ticker:asc", Values: []interface{}{last.Ticker}, ID: last.ID}
+	}
+	return db.stocks[start:end], next, nil
+}
+
+func TestGetStocks_Cursor(t *testing.T) {
+	var reads []time.Time
+	db := &cursorStockDB{snapshotStockDB: snapshotStockDB{reads: &reads}}
+	for _, ticker := range []string{"AAPL", "KO", "MSFT"} {
+		db.stocks = append(db.stocks, models.Stock{ID: uuid.New(), Ticker: ticker})
+	}
+	h := NewStockHandlers(db, nil)
+
+	get := func(query string) (*httptest.ResponseRecorder, cursorPage) {
+		rr := httptest.NewRecorder()
+		h.GetStocks(rr, httptest.NewRequest(http.MethodGet, "/api/v1/stocks"+query, nil))
+		var page cursorPage
+		json.Unmarshal(rr.Body.Bytes(), &page)
+		return rr, page
+	}
+
+	var tickers []string
+	query := "?limit=2&cursor="
+	for pages := 0; pages < 3; pages++ {
+		rr, page := get(query)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("❌ estado %d, se esperaba 200: %s", rr.Code, rr.Body.String())
+		}
+		for _, s := range page.Data.([]interface{}) {
+			tickers = append(tickers, s.(map[string]interface{})["ticker"].(string))
+		}
+		if page.NextCursor == nil {
+			break
+		}
+		query = "?limit=2&cursor=" + url.QueryEscape(*page.NextCursor)
+	}
+	if strings.Join(tickers, ",") != "AAPL,KO,MSFT" {
+		t.Errorf("❌ se esperaban AAPL, KO y MSFT en dos páginas, se obtuvo %v", tickers)
+	}
+
+	first, _ := get("?limit=2&cursor=")
+	var raw map[string]json.RawMessage
+	json.Unmarshal(first.Body.Bytes(), &raw)
+	next := "?limit=2&cursor=" + url.QueryEscape(strings.Trim(string(raw["next_cursor"]), `"`))
+	for _, query := range []string{
+		"?cursor=basura",
+		"?cursor=&offset=10",
+		"?cursor=&sortBy=staleness",
+		next + "&sortBy=company",
+	} {
+		if rr, _ := get(query); rr.Code != http.StatusBadRequest {
+			t.Errorf("❌ %s: estado %d, se esperaba 400", query, rr.Code)
+		}
+	}
+}
+
+func TestNewPaginatedResponse_NextOffset(t *testing.T) {
+	tests := []struct {
+		name          string
+		count, total  int
+		approximate   bool
+		limit, offset int
+		wantNext      int // -1 = sin página siguiente
+	}{
+		{"primera página", 10, 25, false, 10, 0, 10},
+		{"última página", 5, 25, false, 10, 20, -1},
+		{"más allá del final", 0, 25, false, 10, 30, -1},
+		{"total aproximado con la página llena", 10, 20, true, 10, 10, 20},
+		{"total aproximado con la página incompleta", 3, 20, true, 10, 20, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := newPaginatedResponse(nil, tt.count, tt.total, tt.approximate, tt.limit, tt.offset)
+			got := -1
+			if page.NextOffset != nil {
+				got = *page.NextOffset
+			}
+			if got != tt.wantNext {
+				t.Errorf("❌ next_offset = %d, se esperaba %d", got, tt.wantNext)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/jannin2/stock-app/backend/database"
+)
+
+// stockSchema es la respuesta de GET /stocks/schema: qué parámetros de GET /stocks (y de
+// /stocks/export) filtran y ordenan, para que un cliente pueda construir los filtros sin
+// conocerlos de antemano.
+type stockSchema struct {
+	Search stockSchemaSearch  `json:"search"`
+	Sort   stockSchemaSort    `json:"sort"`
+	Fields []stockSchemaField `json:"fields"` // En orden alfabético
+}
+
+type stockSchemaSearch struct {
+	Param  string   `json:"param"`
+	Fields []string `json:"fields"` // Campos en los que se busca el término, sin distinguir mayúsculas
+}
+
+type stockSchemaSort struct {
+	Param      string   `json:"param"`       // "campo[:asc|desc],..."
+	OrderParam string   `json:"order_param"` // Dirección de los campos que no la indican
+	Directions []string `json:"directions"`
+	MaxKeys    int      `json:"max_keys"`
+	Default    string   `json:"default"`
+}
+
+// stockSchemaField es un campo por el que se puede ordenar, filtrar o ambas cosas.
+type stockSchemaField struct {
+	Name     string              `json:"name"`
+	Type     string              `json:"type"` // number, string o duration
+	Sortable bool                `json:"sortable"`
+	Cursor   bool                `json:"cursor"` // Si admite la paginación por cursor
+	Filters  []stockSchemaFilter `json:"filters"`
+}
+
+// stockSchemaFilter es un parámetro que filtra por el campo: ?param=valor se cumple si el
+// campo cumple operator con el valor. Los límites son los que admite el parámetro.
+type stockSchemaFilter struct {
+	Param      string   `json:"param"`
+	Operator   string   `json:"operator"` // gte, lte o eq (sin distinguir mayúsculas)
+	Min        *float64 `json:"min,omitempty"`
+	Max        *float64 `json:"max,omitempty"`
+	MaxLength  int      `json:"max_length,omitempty"`
+	Deprecated bool     `json:"deprecated,omitempty"`
+}
+
+// GetStockSchema maneja GET /stocks/schema. Se genera a partir de las mismas listas con las
+// que se validan sortBy (database.SortFields) y los filtros (stockFilterParams y
+// stockTextFilterParams), así que siempre describe lo que la API acepta.
+func GetStockSchema(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, http.StatusOK, newStockSchema())
+}
+
+func newStockSchema() stockSchema {
+	fields := map[string]*stockSchemaField{}
+	field := func(name, fieldType string) *stockSchemaField {
+		f, ok := fields[name]
+		if !ok {
+			f = &stockSchemaField{Name: name, Type: fieldType, Filters: []stockSchemaFilter{}}
+			fields[name] = f
+		}
+		return f
+	}
+
+	for _, name := range database.SortFields() {
+		f := field(name, database.SortFieldType(name))
+		f.Sortable = true
+		f.Cursor = database.ValidateKeysetSort(name, "") == nil
+	}
+	for _, p := range stockFilterParams {
+		min, max := p.min, p.max
+		f := field(p.field, database.FieldTypeNumber)
+		f.Filters = append(f.Filters, stockSchemaFilter{Param: p.name, Operator: p.op, Min: &min, Max: &max, Deprecated: p.deprecated})
+	}
+	for _, p := range stockTextFilterParams {
+		f := field(p.name, database.FieldTypeString)
+		f.Filters = append(f.Filters, stockSchemaFilter{Param: p.name, Operator: filterOpEq, MaxLength: maxTextFilterLength})
+	}
+
+	schema := stockSchema{
+		Search: stockSchemaSearch{Param: "search", Fields: []string{"ticker", "company"}},
+		Sort: stockSchemaSort{
+			Param:      "sortBy",
+			OrderParam: "order",
+			Directions: []string{"asc", "desc"},
+			MaxKeys:    database.MaxSortKeys,
+			Default:    "ticker",
+		},
+		Fields: make([]stockSchemaField, 0, len(fields)),
+	}
+	for _, f := range fields {
+		schema.Fields = append(schema.Fields, *f)
+	}
+	sort.Slice(schema.Fields, func(i, j int) bool { return schema.Fields[i].Name < schema.Fields[j].Name })
+	return schema
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/jannin2/stock-app/backend/database"
+)
+
+func TestGetStockSchema(t *testing.T) {
+	rr := httptest.NewRecorder()
+	GetStockSchema(rr, httptest.NewRequest(http.MethodGet, "/api/v1/stocks/schema", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("❌ estado %d, se esperaba 200", rr.Code)
+	}
+	var schema stockSchema
+	if err := json.Unmarshal(rr.Body.Bytes(), &schema); err != nil {
+		t.Fatalf("❌ JSON inválido: %v", err)
+	}
+	if schema.Sort.MaxKeys != database.MaxSortKeys || schema.Search.Param != "search" {
+		t.Errorf("❌ búsqueda u orden inesperados: %+v %+v", schema.Search, schema.Sort)
+	}
+
+	byName := map[string]stockSchemaField{}
+	for _, f := range schema.Fields {
+		byName[f.Name] = f
+	}
+	if f := byName["current_price"]; f.Type != database.FieldTypeNumber || !f.Sortable || !f.Cursor || len(f.Filters) != 2 {
+		t.Errorf("❌ current_price inesperado: %+v", f)
+	}
+	if f := byName["brokerage"]; f.Type != database.FieldTypeString || f.Sortable || len(f.Filters) != 1 || f.Filters[0].Operator != filterOpEq {
+		t.Errorf("❌ brokerage inesperado: %+v", f)
+	}
+	if f := byName["staleness"]; f.Type != database.FieldTypeDuration || f.Cursor {
+		t.Errorf("❌ staleness no admite cursor: %+v", f)
+	}
+
+	// Todo lo que describe el esquema lo acepta GET /stocks
+	for _, f := range schema.Fields {
+		if f.Sortable {
+			if err := database.ValidateSort(f.Name); err != nil {
+				t.Errorf("❌ %s figura como ordenable: %v", f.Name, err)
+			}
+		}
+		for _, filter := range f.Filters {
+			value := "a"
+			if filter.Max != nil {
+				value = "1"
+			}
+			if _, err := parseStockFilters(url.Values{filter.Param: {value}}); err != nil {
+				t.Errorf("❌ %s figura como filtro: %v", filter.Param, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,429 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/apierror"
+	"github.com/jannin2/stock-app/backend/auth"
+	"github.com/jannin2/stock-app/backend/clock"
+	"github.com/jannin2/stock-app/backend/config"
+	"github.com/jannin2/stock-app/backend/database"
+	"github.com/jannin2/stock-app/backend/stream"
+)
+
+// streamPingInterval es cada cuánto se envía un ping a los clientes del stream, para
+// detectar conexiones muertas y evitar que los proxies las cierren por inactividad.
+const streamPingInterval = 30 * time.Second
+
+// streamReadTimeout es cuánto se espera sin recibir nada del cliente (ni siquiera el pong)
+// antes de dar la conexión por muerta. Cubre dos pings perdidos.
+const streamReadTimeout = 75 * time.Second
+
+// streamMaxWatchlists es cuántas watchlists puede seguir cada conexión.
+const streamMaxWatchlists = 20
+
+// Acciones que acepta el stream en los mensajes del cliente.
+const (
+	streamSubscribe   = "subscribe"
+	streamUnsubscribe = "unsubscribe"
+	streamFollow      = "follow"
+	streamUnfollow    = "unfollow"
+)
+
+// streamRequest es un mensaje del cliente: {"action": "subscribe", "tickers": ["AAPL"]} o
+// {"action": "follow", "watchlist": "<id>"}.
+type streamRequest struct {
+	Action    string   `json:"action"`
+	Tickers   []string `json:"tickers"`
+	Watchlist string   `json:"watchlist"`
+}
+
+// streamReply es la respuesta a un mensaje del cliente: los tickers que sigue tras aplicarlo
+// o, si no se pudo aplicar, el error (y los tickers que sigue, que no cambian).
+type streamReply struct {
+	Type       string   `json:"type"` // "subscriptions" o "error"
+	Tickers    []string `json:"tickers"`
+	Watchlists []string `json:"watchlists,omitempty"` // Watchlists seguidas, si hay alguna
+	Error      string   `json:"error,omitempty"`
+
+	// Solo en la respuesta al conectar: la última secuencia publicada, desde la que reanudar
+	// con ?since_seq= si no llega ninguna actualización antes de desconectarse, y si las
+	// perdidas desde since_seq ya no están disponibles y hay que volver a leer el estado.
+	Seq   uint64 `json:"seq,omitempty"`
+	Reset bool   `json:"reset,omitempty"`
+}
+
+// StreamHandlers sirve las actualizaciones en tiempo real de los tickers y de las
+// watchlists compartidas por WebSocket.
+type StreamHandlers struct {
+	hub        *stream.Hub
+	watchlists database.UserDB // Para comprobar que quien sigue una watchlist es miembro
+	clock      clock.Clock
+}
+
+// NewStreamHandlers crea los manejadores del stream sobre hub, en el que publican el
+// enricher y los manejadores de watchlists.
+func NewStreamHandlers(hub *stream.Hub, watchlists database.UserDB, clk clock.Clock) *StreamHandlers {
+	return &StreamHandlers{hub: hub, watchlists: watchlists, clock: clk}
+}
+
+// Stream maneja GET /stream: abre un WebSocket por el que el cliente se suscribe y se da de
+// baja de tickers con mensajes {"action": "subscribe"|"unsubscribe", "tickers": [...]} y
+// recibe solo las actualizaciones de los que sigue. ?tickers=AAPL,MSFT suscribe al conectar.
+// Cada actualización lleva su número de secuencia (seq); un cliente que se reconecta con
+// ?since_seq=<la última recibida> recibe antes que nada las que se perdió de esos tickers, o
+// "reset": true si ya no están disponibles y debe volver a leer el estado. Cada conexión
+// sigue como máximo STREAM_MAX_SUBSCRIPTIONS tickers y envía como máximo
+// STREAM_MESSAGES_PER_MIN mensajes por minuto; los que se pasan reciben un error y no se
+// aplican. Un usuario autenticado puede además seguir los cambios de las watchlists de las
+// que es miembro con {"action": "follow"|"unfollow", "watchlist": "<id>"}; esos no se
+// reenvían al reconectar, así que hay que volver a leer la watchlist.
+func (h *StreamHandlers) Stream(w http.ResponseWriter, r *http.Request) {
+	cfg := config.Current()
+	var initial []string
+	if raw := r.URL.Query().Get("tickers"); raw != "" {
+		tickers, err := validateStreamTickers(strings.Split(raw, ","), cfg.StreamMaxSubscriptions)
+		if err != nil {
+			apierror.HTTPError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		initial = tickers
+	}
+	var since *uint64
+	if raw := r.URL.Query().Get("since_seq"); raw != "" {
+		seq, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			apierror.HTTPError(w, "since_seq debe ser un número entero no negativo", http.StatusBadRequest)
+			return
+		}
+		since = &seq
+	}
+
+	conn, err := stream.Upgrade(w, r)
+	if err != nil {
+		return
+	}
+	sub := h.hub.Connect()
+	defer sub.Close()
+	conn.SetReadTimeout(streamReadTimeout)
+
+	// Sin since_seq no hay nada que reanudar: se parte de la última secuencia publicada.
+	from := h.hub.Seq()
+	if since != nil {
+		from = *since
+	}
+	replay, err := sub.Resume(initial, cfg.StreamMaxSubscriptions, from)
+	if err != nil && !errors.Is(err, stream.ErrReplayGap) {
+		closeStream(conn, err)
+		return
+	}
+	reply := streamReply{Type: "subscriptions", Tickers: replay.Tickers, Seq: replay.Seq, Reset: err != nil}
+	if err := writeStreamMessage(conn, reply); err != nil {
+		conn.Close(stream.CloseNormal, "")
+		return
+	}
+	for _, u := range replay.Missed {
+		if err := writeStreamMessage(conn, u); err != nil {
+			conn.Close(stream.CloseNormal, "")
+			return
+		}
+	}
+
+	readerDone := make(chan struct{})
+	go func() {
+		defer close(readerDone)
+		userID, _ := auth.UserFromContext(r.Context())
+		h.readRequests(r.Context(), conn, sub, cfg, userID)
+	}()
+	defer func() { <-readerDone }()
+
+	ping := h.clock.NewTicker(streamPingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case u := <-sub.Updates():
+			if err := writeStreamMessage(conn, u); err != nil {
+				conn.Close(stream.CloseNormal, "")
+				return
+			}
+		case <-ping.C:
+			if err := conn.Ping(); err != nil {
+				conn.Close(stream.CloseNormal, "")
+				return
+			}
+		case <-sub.Done():
+			closeStream(conn, sub.Err())
+			return
+		case <-readerDone:
+			conn.Close(stream.CloseNormal, "")
+			return
+		}
+	}
+}
+
+// readRequests aplica los mensajes del cliente hasta que la conexión se cierra. userID es
+// el usuario autenticado, o uuid.Nil.
+func (h *StreamHandlers) readRequests(ctx context.Context, conn *stream.Conn, sub *stream.Subscription, cfg config.Config, userID uuid.UUID) {
+	limiter := &streamLimiter{clock: h.clock, limit: cfg.StreamMessagesPerMin}
+	for {
+		message, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		reply := h.applyRequest(ctx, message, sub, limiter, cfg.StreamMaxSubscriptions, userID)
+		if err := writeStreamMessage(conn, reply); err != nil {
+			return
+		}
+	}
+}
+
+// applyRequest aplica un mensaje del cliente a sub y devuelve la respuesta que se le envía.
+func (h *StreamHandlers) applyRequest(ctx context.Context, message []byte, sub *stream.Subscription, limiter *streamLimiter, max int, userID uuid.UUID) streamReply {
+	if !limiter.Allow() {
+		return streamError(sub.Tickers(), fmt.Sprintf("Límite de mensajes excedido: máximo %d por minuto", limiter.limit))
+	}
+
+	var req streamRequest
+	if err := json.Unmarshal(message, &req); err != nil {
+		return streamError(sub.Tickers(), "Mensaje inválido: se esperaba JSON con action y tickers")
+	}
+	switch req.Action {
+	case streamSubscribe, streamUnsubscribe:
+	case streamFollow, streamUnfollow:
+		return h.applyFollow(ctx, req, sub, userID)
+	default:
+		return streamError(sub.Tickers(), fmt.Sprintf("Acción desconocida %q (subscribe, unsubscribe, follow o unfollow)", req.Action))
+	}
+	tickers, err := validateStreamTickers(req.Tickers, max)
+	if err != nil {
+		return streamError(sub.Tickers(), err.Error())
+	}
+	if len(tickers) == 0 {
+		return streamError(sub.Tickers(), "El mensaje debe incluir al menos un ticker")
+	}
+
+	if req.Action == streamSubscribe {
+		subscribed, err := sub.Add(tickers, max)
+		if errors.Is(err, stream.ErrTooManySubscriptions) {
+			return streamError(subscribed, fmt.Sprintf("Se pueden seguir como máximo %d tickers por conexión", max))
+		}
+		if err != nil {
+			return streamError(subscribed, err.Error())
+		}
+		return streamReply{Type: "subscriptions", Tickers: subscribed, Watchlists: sub.Watchlists()}
+	}
+	return streamReply{Type: "subscriptions", Tickers: sub.Remove(tickers), Watchlists: sub.Watchlists()}
+}
+
+// applyFollow aplica un mensaje follow o unfollow. Solo se sigue una watchlist de la que
+// userID es propietario o miembro; el hub deja de enviarla cuando deja de serlo.
+func (h *StreamHandlers) applyFollow(ctx context.Context, req streamRequest, sub *stream.Subscription, userID uuid.UUID) streamReply {
+	fail := func(message string) streamReply {
+		reply := streamError(sub.Tickers(), message)
+		reply.Watchlists = sub.Watchlists()
+		return reply
+	}
+	watchlistID, err := uuid.Parse(req.Watchlist)
+	if err != nil {
+		return fail("ID de watchlist inválido")
+	}
+	if req.Action == streamUnfollow {
+		return streamReply{Type: "subscriptions", Tickers: sub.Tickers(), Watchlists: sub.Unfollow(watchlistID.String())}
+	}
+
+	if userID == uuid.Nil {
+		return fail("Seguir una watchlist requiere la clave de API de un usuario")
+	}
+	if _, err := h.watchlists.GetWatchlist(ctx, userID, watchlistID); errors.Is(err, database.ErrWatchlistNotFound) {
+		return fail("Watchlist no encontrada")
+	} else if err != nil {
+		return fail("Error al comprobar la watchlist")
+	}
+	followed, err := sub.Follow(watchlistID.String(), userID.String(), streamMaxWatchlists)
+	if errors.Is(err, stream.ErrTooManySubscriptions) {
+		return fail(fmt.Sprintf("Se pueden seguir como máximo %d watchlists por conexión", streamMaxWatchlists))
+	}
+	if err != nil {
+		return fail(err.Error())
+	}
+	return streamReply{Type: "subscriptions", Tickers: sub.Tickers(), Watchlists: followed}
+}
+
+// validateStreamTickers pasa los tickers a mayúsculas y quita los repetidos. Rechaza los
+// vacíos o de más de 10 caracteres y las listas con más de max tickers.
+func validateStreamTickers(raw []string, max int) ([]string, error) {
+	tickers := make([]string, 0, len(raw))
+	seen := map[string]bool{}
+	for _, ticker := range raw {
+		ticker = strings.ToUpper(strings.TrimSpace(ticker))
+		if ticker == "" || len(ticker) > 10 {
+			return nil, fmt.Errorf("Ticker inválido: %q", ticker)
+		}
+		if !seen[ticker] {
+			seen[ticker] = true
+			tickers = append(tickers, ticker)
+		}
+	}
+	if len(tickers) > max {
+		return nil, fmt.Errorf("Se pueden seguir como máximo %d tickers por conexión", max)
+	}
+	return tickers, nil
+}
+
+// streamError construye una respuesta de error con los tickers que sigue el cliente.
+func streamError(tickers []string, message string) streamReply {
+	if tickers == nil {
+		tickers = []string{}
+	}
+	return streamReply{Type: "error", Tickers: tickers, Error: message}
+}
+
+// writeStreamMessage envía v como mensaje de texto JSON.
+func writeStreamMessage(conn *stream.Conn, v interface{}) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("error al serializar el mensaje del stream: %w", err)
+	}
+	return conn.WriteText(body)
+}
+
+// closeStream cierra la conexión con el código que corresponde al fin de la suscripción.
+func closeStream(conn *stream.Conn, err error) {
+	switch {
+	case errors.Is(err, stream.ErrHubClosed):
+		conn.Close(stream.CloseGoingAway, "el servidor se está apagando")
+	case errors.Is(err, stream.ErrSlowConsumer):
+		log.Printf("⚠️ Stream: cliente desconectado por no leer a tiempo")
+		conn.Close(stream.ClosePolicyViolation, "el cliente no lee las actualizaciones a tiempo")
+	default:
+		conn.Close(stream.CloseNormal, "")
+	}
+}
+
+// streamLimiter limita los mensajes de una conexión a limit por minuto natural (0 = sin
+// límite), con ventanas fijas como el limitador de la API.
+type streamLimiter struct {
+	clock  clock.Clock
+	limit  int
+	window time.Time
+	count  int
+}
+
+// Allow cuenta un mensaje e indica si entra en el límite.
+func (l *streamLimiter) Allow() bool {
+	if l.limit <= 0 {
+		return true
+	}
+	window := l.clock.Now().Truncate(time.Minute)
+	if !window.Equal(l.window) {
+		l.window = window
+		l.count = 0
+	}
+	l.count++
+	return l.count <= l.limit
+}
+
+// defaultPollTimeout y maxPollTimeout son la espera por defecto y la máxima de GET
+// /stream/poll. Por debajo de los 60 s con los que suelen cortar los proxies.
+const (
+	defaultPollTimeout = 25 * time.Second
+	maxPollTimeout     = 55 * time.Second
+)
+
+// pollResponse es la respuesta de GET /stream/poll.
+type pollResponse struct {
+	Seq     uint64          `json:"seq"`             // Secuencia desde la que pedir la siguiente (since_seq)
+	Reset   bool            `json:"reset,omitempty"` // Se perdieron actualizaciones: hay que volver a leer el estado
+	Updates []stream.Update `json:"updates"`         // Con el mismo formato que en el WebSocket
+}
+
+// Poll maneja GET /stream/poll?tickers=AAPL,MSFT&since_seq=N&timeout=25, la alternativa
+// por long-polling a Stream para clientes tras proxies que bloquean WebSocket. Comparte el
+// hub y sus secuencias: devuelve en cuanto hay actualizaciones de los tickers posteriores a
+// since_seq, o vacía al cabo de timeout segundos (25 por defecto, como máximo 55), con la
+// secuencia que pasar como since_seq en la siguiente petición. Sin since_seq espera a la
+// primera actualización que se publique.
+func (h *StreamHandlers) Poll(w http.ResponseWriter, r *http.Request) {
+	cfg := config.Current()
+	query := r.URL.Query()
+	tickers, err := validateStreamTickers(parseTickerList(query.Get("tickers")), cfg.StreamMaxSubscriptions)
+	if err != nil {
+		apierror.HTTPError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(tickers) == 0 {
+		apierror.HTTPError(w, "El parámetro 'tickers' debe incluir al menos un ticker", http.StatusBadRequest)
+		return
+	}
+	since := h.hub.Seq()
+	if raw := query.Get("since_seq"); raw != "" {
+		if since, err = strconv.ParseUint(raw, 10, 64); err != nil {
+			apierror.HTTPError(w, "since_seq debe ser un número entero no negativo", http.StatusBadRequest)
+			return
+		}
+	}
+	timeout := defaultPollTimeout
+	if raw := query.Get("timeout"); raw != "" {
+		seconds, err := strconv.Atoi(raw)
+		if err != nil || seconds < 1 || time.Duration(seconds)*time.Second > maxPollTimeout {
+			apierror.HTTPError(w, fmt.Sprintf("timeout debe ser un número de segundos entre 1 y %d", int(maxPollTimeout.Seconds())), http.StatusBadRequest)
+			return
+		}
+		timeout = time.Duration(seconds) * time.Second
+	}
+
+	sub := h.hub.Connect()
+	defer sub.Close()
+	replay, err := sub.Resume(tickers, cfg.StreamMaxSubscriptions, since)
+	resp := pollResponse{Seq: replay.Seq, Updates: replay.Missed}
+	switch {
+	case errors.Is(err, stream.ErrReplayGap):
+		resp.Reset = true
+	case errors.Is(err, stream.ErrHubClosed):
+		apierror.HTTPError(w, "El servidor se está apagando", http.StatusServiceUnavailable)
+		return
+	case err != nil:
+		apierror.Internal(w, "Error al suscribirse", err)
+		return
+	}
+
+	if !resp.Reset && len(resp.Updates) == 0 {
+		// Un ticker que se detiene al salir, a diferencia de After, no deja el temporizador
+		// registrado en el reloj hasta que vence si llega antes una actualización.
+		expire := h.clock.NewTicker(timeout)
+		defer expire.Stop()
+		select {
+		case u := <-sub.Updates():
+			resp.Updates = append(resp.Updates, u)
+			// Las que ya estén en cola van en la misma respuesta
+			for len(sub.Updates()) > 0 {
+				resp.Updates = append(resp.Updates, <-sub.Updates())
+			}
+		case <-sub.Done():
+			if errors.Is(sub.Err(), stream.ErrHubClosed) {
+				apierror.HTTPError(w, "El servidor se está apagando", http.StatusServiceUnavailable)
+				return
+			}
+			resp.Reset = true
+		case <-expire.C:
+		case <-r.Context().Done():
+			return
+		}
+	}
+	if n := len(resp.Updates); n > 0 && resp.Updates[n-1].Seq > resp.Seq {
+		resp.Seq = resp.Updates[n-1].Seq
+	}
+	if resp.Updates == nil {
+		resp.Updates = []stream.Update{}
+	}
+	writeJSON(w, r, http.StatusOK, resp)
+}
@@ -0,0 +1,367 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/clock"
+	"github.com/jannin2/stock-app/backend/config"
+	"github.com/jannin2/stock-app/backend/models"
+	"github.com/jannin2/stock-app/backend/stream"
+)
+
+// streamClient es un cliente WebSocket mínimo para probar /stream.
+type streamClient struct {
+	t    *testing.T
+	conn net.Conn
+	br   *bufio.Reader
+}
+
+// dialStream arranca el stream de h y se conecta con query.
+func dialStream(t *testing.T, h *StreamHandlers, query string) *streamClient {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(h.Stream))
+	t.Cleanup(server.Close)
+	conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatalf("❌ no se pudo conectar: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	conn.Write([]byte("GET /api/v1/stream" + query + " HTTP/1.1\r\nHost: test\r\nUpgrade: websocket\r\n" +
+		"Connection: Upgrade\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n"))
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil || resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("❌ handshake fallido: %v %v", resp, err)
+	}
+	return &streamClient{t: t, conn: conn, br: br}
+}
+
+// send envía v como mensaje de texto enmascarado.
+func (c *streamClient) send(v interface{}) {
+	c.t.Helper()
+	payload, _ := json.Marshal(v)
+	frame := []byte{0x81, 0x80 | byte(len(payload)), 0, 0, 0, 0} // Máscara nula
+	c.conn.Write(append(frame, payload...))
+}
+
+// read lee la siguiente trama; devuelve su tipo y contenido.
+func (c *streamClient) read() (byte, []byte) {
+	c.t.Helper()
+	var header [2]byte
+	if _, err := io.ReadFull(c.br, header[:]); err != nil {
+		c.t.Fatalf("❌ error al leer del stream: %v", err)
+	}
+	length := int(header[1])
+	if length == 126 {
+		var ext [2]byte
+		io.ReadFull(c.br, ext[:])
+		length = int(binary.BigEndian.Uint16(ext[:]))
+	}
+	payload := make([]byte, length)
+	io.ReadFull(c.br, payload)
+	return header[0] & 0x0F, payload
+}
+
+// reply lee la respuesta a un mensaje, saltando los ping.
+func (c *streamClient) reply() streamReply {
+	c.t.Helper()
+	opcode, payload := c.read()
+	for opcode == 0x9 {
+		opcode, payload = c.read()
+	}
+	var reply streamReply
+	if err := json.Unmarshal(payload, &reply); err != nil {
+		c.t.Fatalf("❌ respuesta inválida %q: %v", payload, err)
+	}
+	return reply
+}
+
+func setStreamConfig(t *testing.T, maxSubscriptions, messagesPerMin int) {
+	cfg := config.Default()
+	cfg.StreamMaxSubscriptions = maxSubscriptions
+	cfg.StreamMessagesPerMin = messagesPerMin
+	config.Set(cfg)
+	t.Cleanup(func() { config.Set(config.Default()) })
+}
+
+func TestStream_Subscriptions(t *testing.T) {
+	setStreamConfig(t, 3, 0)
+	hub := stream.NewHub()
+	client := dialStream(t, NewStreamHandlers(hub, nil, clock.NewMock()), "?tickers=aapl")
+
+	if got := client.reply(); got.Type != "subscriptions" || !reflect.DeepEqual(got.Tickers, []string{"AAPL"}) {
+		t.Fatalf("❌ al conectar se esperaba la suscripción a AAPL, se obtuvo %+v", got)
+	}
+	client.send(streamRequest{Action: "subscribe", Tickers: []string{"msft", "nvda"}})
+	if got := client.reply(); !reflect.DeepEqual(got.Tickers, []string{"AAPL", "MSFT", "NVDA"}) {
+		t.Fatalf("❌ subscribe: %+v", got)
+	}
+
+	// Solo llegan las actualizaciones de los tickers suscritos
+	hub.Publish(stream.Update{Type: stream.UpdateQuote, Ticker: "TSLA", Data: 250.0})
+	hub.Publish(stream.Update{Type: stream.UpdateQuote, Ticker: "MSFT", Data: 410.0})
+	_, payload := client.read()
+	var update stream.Update
+	json.Unmarshal(payload, &update)
+	if update.Type != stream.UpdateQuote || update.Ticker != "MSFT" || update.Data != 410.0 {
+		t.Errorf("❌ se esperaba la cotización de MSFT, se obtuvo %s", payload)
+	}
+
+	client.send(streamRequest{Action: "subscribe", Tickers: []string{"TSLA"}})
+	if got := client.reply(); got.Type != "error" || !strings.Contains(got.Error, "máximo 3") || len(got.Tickers) != 3 {
+		t.Errorf("❌ se esperaba el error del máximo de suscripciones, se obtuvo %+v", got)
+	}
+	client.send(streamRequest{Action: "unsubscribe", Tickers: []string{"AAPL", "NVDA"}})
+	if got := client.reply(); got.Type != "subscriptions" || !reflect.DeepEqual(got.Tickers, []string{"MSFT"}) {
+		t.Errorf("❌ unsubscribe: %+v", got)
+	}
+
+	for _, req := range []streamRequest{
+		{Action: "watch", Tickers: []string{"AAPL"}},
+		{Action: "subscribe"},
+		{Action: "subscribe", Tickers: []string{"DEMASIADOLARGO"}},
+	} {
+		client.send(req)
+		if got := client.reply(); got.Type != "error" || !reflect.DeepEqual(got.Tickers, []string{"MSFT"}) {
+			t.Errorf("❌ %+v: se esperaba un error sin cambiar las suscripciones, se obtuvo %+v", req, got)
+		}
+	}
+}
+
+func TestStream_RateLimit(t *testing.T) {
+	setStreamConfig(t, 10, 2)
+	clk := clock.NewMock()
+	client := dialStream(t, NewStreamHandlers(stream.NewHub(), nil, clk), "")
+	client.reply()
+
+	subscribe := streamRequest{Action: "subscribe", Tickers: []string{"AAPL"}}
+	for i := 0; i < 2; i++ {
+		client.send(subscribe)
+		if got := client.reply(); got.Type != "subscriptions" {
+			t.Fatalf("❌ mensaje %d: %+v", i+1, got)
+		}
+	}
+	client.send(streamRequest{Action: "subscribe", Tickers: []string{"MSFT"}})
+	if got := client.reply(); got.Type != "error" || !strings.Contains(got.Error, "Límite de mensajes") ||
+		!reflect.DeepEqual(got.Tickers, []string{"AAPL"}) {
+		t.Errorf("❌ se esperaba el error del límite de mensajes, se obtuvo %+v", got)
+	}
+
+	clk.Add(time.Minute)
+	client.send(streamRequest{Action: "subscribe", Tickers: []string{"MSFT"}})
+	if got := client.reply(); got.Type != "subscriptions" || len(got.Tickers) != 2 {
+		t.Errorf("❌ el límite debe reiniciarse cada minuto, se obtuvo %+v", got)
+	}
+}
+
+func TestStream_HubClose(t *testing.T) {
+	setStreamConfig(t, 10, 0)
+	hub := stream.NewHub()
+	client := dialStream(t, NewStreamHandlers(hub, nil, clock.NewMock()), "")
+	client.reply()
+
+	hub.Close()
+	opcode, payload := client.read()
+	if opcode != 0x8 || len(payload) < 2 || binary.BigEndian.Uint16(payload) != stream.CloseGoingAway {
+		t.Errorf("❌ se esperaba un cierre 1001, se obtuvo la trama %x %q", opcode, payload)
+	}
+}
+
+func TestStream_InvalidInitialTickers(t *testing.T) {
+	setStreamConfig(t, 2, 0)
+	h := NewStreamHandlers(stream.NewHub(), nil, clock.NewMock())
+	for _, query := range []string{"?tickers=AAPL,DEMASIADOLARGO", "?tickers=AAPL,MSFT,NVDA"} {
+		rr := httptest.NewRecorder()
+		h.Stream(rr, httptest.NewRequest(http.MethodGet, "/api/v1/stream"+query, nil))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("❌ %s: estado %d, se esperaba 400", query, rr.Code)
+		}
+	}
+}
+
+func TestStream_Resume(t *testing.T) {
+	setStreamConfig(t, 10, 0)
+	hub := stream.NewHub()
+	h := NewStreamHandlers(hub, nil, clock.NewMock())
+	first := dialStream(t, h, "?tickers=AAPL")
+	connected := first.reply()
+	if connected.Seq != hub.Seq() || connected.Reset {
+		t.Fatalf("❌ al conectar se esperaba la secuencia %d, se obtuvo %+v", hub.Seq(), connected)
+	}
+	first.conn.Close()
+
+	// Mientras el cliente está desconectado
+	hub.Publish(stream.Update{Type: stream.UpdateQuote, Ticker: "AAPL", Data: 190.0})
+	hub.Publish(stream.Update{Type: stream.UpdateQuote, Ticker: "MSFT", Data: 410.0})
+	hub.Publish(stream.Update{Type: stream.UpdateQuote, Ticker: "AAPL", Data: 191.0})
+
+	client := dialStream(t, h, "?tickers=AAPL&since_seq="+strconv.FormatUint(connected.Seq, 10))
+	if got := client.reply(); got.Reset || got.Seq != connected.Seq+3 {
+		t.Fatalf("❌ respuesta inesperada al reanudar: %+v", got)
+	}
+	for _, want := range []uint64{connected.Seq + 1, connected.Seq + 3} {
+		_, payload := client.read()
+		var update stream.Update
+		if json.Unmarshal(payload, &update); update.Seq != want || update.Ticker != "AAPL" {
+			t.Errorf("❌ se esperaba la actualización %d de AAPL, se obtuvo %s", want, payload)
+		}
+	}
+
+	// Una secuencia que el hub no conoce (p. ej. de antes de reiniciar) obliga a releer
+	stale := dialStream(t, h, "?tickers=AAPL&since_seq=1")
+	if got := stale.reply(); !got.Reset || !reflect.DeepEqual(got.Tickers, []string{"AAPL"}) {
+		t.Errorf("❌ se esperaba reset con AAPL suscrito, se obtuvo %+v", got)
+	}
+
+	rr := httptest.NewRecorder()
+	h.Stream(rr, httptest.NewRequest(http.MethodGet, "/api/v1/stream?since_seq=-1", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("❌ since_seq=-1: estado %d, se esperaba 400", rr.Code)
+	}
+}
+
+// poll llama a Poll con query y devuelve la respuesta.
+func poll(t *testing.T, h *StreamHandlers, query string) (int, pollResponse) {
+	t.Helper()
+	rr := httptest.NewRecorder()
+	h.Poll(rr, httptest.NewRequest(http.MethodGet, "/api/v1/stream/poll"+query, nil))
+	var resp pollResponse
+	if rr.Code == http.StatusOK {
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("❌ JSON inválido: %v", err)
+		}
+	}
+	return rr.Code, resp
+}
+
+func TestPoll(t *testing.T) {
+	setStreamConfig(t, 10, 0)
+	hub := stream.NewHub()
+	clk := clock.NewMock()
+	h := NewStreamHandlers(hub, nil, clk)
+	start := hub.Seq()
+	hub.Publish(stream.Update{Type: stream.UpdateQuote, Ticker: "AAPL", Data: 190.0})
+	hub.Publish(stream.Update{Type: stream.UpdateQuote, Ticker: "MSFT", Data: 410.0})
+
+	// Las actualizaciones posteriores a since_seq se devuelven sin esperar
+	code, resp := poll(t, h, "?tickers=aapl&since_seq="+strconv.FormatUint(start, 10))
+	if code != http.StatusOK || len(resp.Updates) != 1 || resp.Updates[0].Ticker != "AAPL" || resp.Seq != start+2 {
+		t.Fatalf("❌ respuesta inesperada (%d): %+v", code, resp)
+	}
+
+	// Sin pendientes espera a la siguiente publicación
+	done := make(chan pollResponse)
+	go func() {
+		_, resp := poll(t, h, "?tickers=AAPL,MSFT&since_seq="+strconv.FormatUint(resp.Seq, 10))
+		done <- resp
+	}()
+	clk.BlockUntil(1)
+	hub.Publish(stream.Update{Type: stream.UpdateQuote, Ticker: "TSLA"})
+	hub.Publish(stream.Update{Type: stream.UpdateQuote, Ticker: "MSFT", Data: 411.0})
+	if resp := <-done; len(resp.Updates) != 1 || resp.Updates[0].Seq != start+4 || resp.Seq != start+4 {
+		t.Errorf("❌ se esperaba la actualización %d de MSFT, se obtuvo %+v", start+4, resp)
+	}
+
+	// Sin publicaciones devuelve vacía al cabo del timeout
+	go func() {
+		_, resp := poll(t, h, "?tickers=AAPL&timeout=10")
+		done <- resp
+	}()
+	clk.BlockUntil(1)
+	clk.Add(10 * time.Second)
+	if resp := <-done; len(resp.Updates) != 0 || resp.Updates == nil || resp.Seq != start+4 || resp.Reset {
+		t.Errorf("❌ se esperaba una respuesta vacía hasta %d, se obtuvo %+v", start+4, resp)
+	}
+
+	if _, resp := poll(t, h, "?tickers=AAPL&since_seq=1"); !resp.Reset || resp.Seq != start+4 {
+		t.Errorf("❌ una secuencia desconocida debe pedir releer el estado, se obtuvo %+v", resp)
+	}
+}
+
+func TestPoll_InvalidParams(t *testing.T) {
+	setStreamConfig(t, 2, 0)
+	h := NewStreamHandlers(stream.NewHub(), nil, clock.NewMock())
+	for _, query := range []string{"", "?tickers=,", "?tickers=A,B,C", "?tickers=AAPL&since_seq=abc", "?tickers=AAPL&timeout=56", "?tickers=AAPL&timeout=0"} {
+		if code, _ := poll(t, h, query); code != http.StatusBadRequest {
+			t.Errorf("❌ %q: estado %d, se esperaba 400", query, code)
+		}
+	}
+}
+
+func TestPoll_HubClosed(t *testing.T) {
+	setStreamConfig(t, 10, 0)
+	hub := stream.NewHub()
+	clk := clock.NewMock()
+	h := NewStreamHandlers(hub, nil, clk)
+
+	done := make(chan int)
+	go func() {
+		code, _ := poll(t, h, "?tickers=AAPL")
+		done <- code
+	}()
+	clk.BlockUntil(1)
+	hub.Close()
+	if code := <-done; code != http.StatusServiceUnavailable {
+		t.Errorf("❌ al apagar: estado %d, se esperaba 503", code)
+	}
+	if code, _ := poll(t, h, "?tickers=AAPL"); code != http.StatusServiceUnavailable {
+		t.Errorf("❌ con el hub cerrado: estado %d, se esperaba 503", code)
+	}
+}
+
+func TestStream_FollowWatchlist(t *testing.T) {
+	memberID := uuid.New()
+	watchlistID := uuid.New()
+	db := &sharedWatchlistDB{
+		watchlist: models.Watchlist{ID: watchlistID},
+		roles:     map[uuid.UUID]string{memberID: models.WatchlistViewer},
+	}
+	h := NewStreamHandlers(stream.NewHub(), db, clock.NewMock())
+	sub := h.hub.Connect()
+	defer sub.Close()
+	limiter := &streamLimiter{clock: h.clock}
+	apply := func(req streamRequest, userID uuid.UUID) streamReply {
+		message, _ := json.Marshal(req)
+		return h.applyRequest(context.Background(), message, sub, limiter, 10, userID)
+	}
+
+	for _, tt := range []struct {
+		req    streamRequest
+		userID uuid.UUID
+		want   string
+	}{
+		{streamRequest{Action: "follow", Watchlist: watchlistID.String()}, uuid.Nil, "requiere la clave de API"},
+		{streamRequest{Action: "follow", Watchlist: watchlistID.String()}, uuid.New(), "no encontrada"},
+		{streamRequest{Action: "follow", Watchlist: "equipo"}, memberID, "inválido"},
+	} {
+		if got := apply(tt.req, tt.userID); got.Type != "error" || !strings.Contains(got.Error, tt.want) || len(got.Watchlists) != 0 {
+			t.Errorf("❌ %+v: se esperaba el error %q, se obtuvo %+v", tt.req, tt.want, got)
+		}
+	}
+
+	got := apply(streamRequest{Action: "follow", Watchlist: watchlistID.String()}, memberID)
+	if got.Type != "subscriptions" || !reflect.DeepEqual(got.Watchlists, []string{watchlistID.String()}) {
+		t.Fatalf("❌ follow: %+v", got)
+	}
+	h.hub.Publish(stream.Update{Type: stream.UpdateWatchlist, Watchlist: watchlistID.String()})
+	if len(sub.Updates()) != 1 {
+		t.Errorf("❌ se esperaba la actualización de la watchlist seguida")
+	}
+	if got := apply(streamRequest{Action: "unfollow", Watchlist: watchlistID.String()}, memberID); got.Type != "subscriptions" || len(got.Watchlists) != 0 {
+		t.Errorf("❌ unfollow: %+v", got)
+	}
+}
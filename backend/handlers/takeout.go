@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/apierror"
+	"github.com/jannin2/stock-app/backend/auth"
+	"github.com/jannin2/stock-app/backend/jobs"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// takeoutJobKind identifica en la cola los trabajos de exportación de cuenta.
+const takeoutJobKind = "takeout"
+
+// takeoutJob es el estado de una exportación de cuenta, con los enlaces para consultarla y,
+// cuando termina, descargarla.
+type takeoutJob struct {
+	jobs.Job
+	StatusURL   string `json:"status_url"`
+	DownloadURL string `json:"download_url,omitempty"`
+}
+
+func newTakeoutJob(job jobs.Job) takeoutJob {
+	resp := takeoutJob{Job: job, StatusURL: "/api/v1/me/export/" + job.ID.String()}
+	if job.Status == jobs.StatusSucceeded {
+		resp.DownloadURL = resp.StatusURL + "/download"
+	}
+	return resp
+}
+
+// RequestExport maneja GET /me/export: encola la generación de un ZIP con todos los datos
+// del usuario y responde 202 con el trabajo. Si ya hay una exportación en curso, devuelve
+// esa en lugar de encolar otra.
+func (h *UserHandlers) RequestExport(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	job, active := h.jobs.Active(takeoutJobKind, userID)
+	if !active {
+		var err error
+		job, err = h.jobs.Submit(takeoutJobKind, userID, h.buildTakeout(userID))
+		if errors.Is(err, jobs.ErrQueueFull) {
+			w.Header().Set("Retry-After", "60")
+			apierror.HTTPError(w, "Hay demasiadas exportaciones en curso, inténtelo más tarde", http.StatusServiceUnavailable)
+			return
+		}
+		if err != nil {
+			apierror.Internal(w, "Error al encolar la exportación", err)
+			return
+		}
+	}
+
+	resp := newTakeoutJob(job)
+	w.Header().Set("Location", resp.StatusURL)
+	writeJSON(w, r, http.StatusAccepted, resp)
+}
+
+// GetExport maneja GET /me/export/{id}: estado de una exportación del usuario.
+func (h *UserHandlers) GetExport(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.takeoutJob(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, r, http.StatusOK, newTakeoutJob(job))
+}
+
+// DownloadExport maneja GET /me/export/{id}/download: descarga el ZIP de una exportación
+// terminada. Responde 409 si todavía no está lista.
+func (h *UserHandlers) DownloadExport(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.takeoutJob(w, r)
+	if !ok {
+		return
+	}
+	if job.Status != jobs.StatusSucceeded {
+		apierror.HTTPError(w, fmt.Sprintf("La exportación no está lista (estado: %s)", job.Status), http.StatusConflict)
+		return
+	}
+
+	f, err := os.Open(job.ResultPath)
+	if err != nil {
+		apierror.HTTPError(w, "La exportación ya no está disponible", http.StatusGone)
+		return
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="stock-app-export-%s.zip"`, job.FinishedAt.Format("2006-01-02")))
+	http.ServeContent(w, r, "", *job.FinishedAt, f)
+}
+
+// takeoutJob busca la exportación {id} del usuario autenticado. Las de otros usuarios se
+// tratan como inexistentes.
+func (h *UserHandlers) takeoutJob(w http.ResponseWriter, r *http.Request) (jobs.Job, bool) {
+	userID, ok := requireUser(w, r)
+	if !ok {
+		return jobs.Job{}, false
+	}
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		apierror.HTTPError(w, "ID de exportación inválido", http.StatusBadRequest)
+		return jobs.Job{}, false
+	}
+	job, err := h.jobs.Get(id)
+	if err != nil || job.Kind != takeoutJobKind || job.Owner != userID {
+		apierror.HTTPError(w, "Exportación no encontrada", http.StatusNotFound)
+		return jobs.Job{}, false
+	}
+	return job, true
+}
+
+// buildTakeout devuelve el trabajo que genera el ZIP de la cuenta de userID.
+func (h *UserHandlers) buildTakeout(userID uuid.UUID) jobs.Func {
+	return func(ctx context.Context, job *jobs.Handle) error {
+		data, err := h.users.GetUserData(ctx, userID)
+		if err != nil {
+			return err
+		}
+		data.ExportedAt = time.Now().UTC()
+		job.SetProgress(0.5)
+
+		f, err := job.CreateResult("export.zip")
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if err := writeTakeout(f, data); err != nil {
+			return err
+		}
+		return f.Close()
+	}
+}
+
+// takeoutFiles son los archivos del ZIP de exportación. account.json contiene todos los
+// datos; los CSV los mismos datos tabulados para abrirlos en una hoja de cálculo.
+var takeoutFiles = []struct {
+	name  string
+	write func(io.Writer, models.UserData) error
+}{
+	{"account.json", writeTakeoutJSON},
+	{"watchlists.csv", writeWatchlistsCSV},
+	{"notes.csv", writeNotesCSV},
+	{"portfolios.csv", writePortfoliosCSV},
+}
+
+// writeTakeout escribe el ZIP de exportación de data en w.
+func writeTakeout(w io.Writer, data models.UserData) error {
+	zw := zip.NewWriter(w)
+	for _, file := range takeoutFiles {
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: file.name, Method: zip.Deflate, Modified: data.ExportedAt})
+		if err != nil {
+			return fmt.Errorf("error al crear %s: %w", file.name, err)
+		}
+		if err := file.write(fw, data); err != nil {
+			return fmt.Errorf("error al escribir %s: %w", file.name, err)
+		}
+	}
+	return zw.Close()
+}
+
+// writeTakeoutJSON escribe los datos como writeJSON (fechas en UTC), pero indentados.
+func writeTakeoutJSON(w io.Writer, data models.UserData) error {
+	body, err := marshalScoped(data, auth.ScopeUser)
+	if err != nil {
+		return err
+	}
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, body, "", "  "); err != nil {
+		return err
+	}
+	indented.WriteByte('\n')
+	_, err = indented.WriteTo(w)
+	return err
+}
+
+func writeWatchlistsCSV(w io.Writer, data models.UserData) error {
+	cw := newSpreadsheetCSV(w)
+	cw.Write([]string{"id", "name", "tickers", "created_at", "updated_at"})
+	for _, wl := range data.Watchlists {
+		cw.Write([]string{wl.ID.String(), wl.Name, strings.Join(wl.Tickers, " "), csvTime(wl.CreatedAt), csvTime(wl.UpdatedAt)})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeNotesCSV(w io.Writer, data models.UserData) error {
+	cw := newSpreadsheetCSV(w)
+	cw.Write([]string{"id", "ticker", "body", "created_at", "updated_at"})
+	for _, n := range data.Notes {
+		cw.Write([]string{n.ID.String(), n.Ticker, n.Body, csvTime(n.CreatedAt), csvTime(n.UpdatedAt)})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writePortfoliosCSV escribe una fila por posición.
+func writePortfoliosCSV(w io.Writer, data models.UserData) error {
+	cw := newSpreadsheetCSV(w)
+	cw.Write([]string{"portfolio_id", "portfolio", "ticker", "shares", "cost_basis", "updated_at"})
+	for _, p := range data.Portfolios {
+		for _, pos := range p.Positions {
+			cw.Write([]string{
+				p.ID.String(), p.Name, pos.Ticker, strconv.FormatFloat(pos.Shares, 'f', -1, 64),
+				csvFloat(pos.CostBasis), csvTime(p.UpdatedAt),
+			})
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func csvTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/auth"
+	"github.com/jannin2/stock-app/backend/clock"
+	"github.com/jannin2/stock-app/backend/jobs"
+	"github.com/jannin2/stock-app/backend/mail"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+func TestTakeout(t *testing.T) {
+	db := &fakeUserDB{user: models.User{ID: uuid.New(), Email: "ana@example.com"}}
+	queue := jobs.NewQueue(clock.New(), 1, 4)
+	queue.Start(context.Background())
+	t.Cleanup(func() { queue.Stop(context.Background()) })
+	h := NewUserHandlers(db, queue, mail.LogMailer{})
+
+	router := chi.NewRouter()
+	router.Get("/api/v1/me/export", h.RequestExport)
+	router.Get("/api/v1/me/export/{id}", h.GetExport)
+	router.Get("/api/v1/me/export/{id}/download", h.DownloadExport)
+	get := func(path string, userID uuid.UUID) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req = req.WithContext(auth.WithUser(req.Context(), userID))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := get("/api/v1/me/export", db.user.ID)
+	var job takeoutJob
+	if err := json.Unmarshal(rr.Body.Bytes(), &job); err != nil || rr.Code != http.StatusAccepted || rr.Header().Get("Location") != job.StatusURL {
+		t.Fatalf("❌ respuesta inesperada: %d %s", rr.Code, rr.Body)
+	}
+
+	// Se consulta el estado hasta que aparezca el enlace de descarga
+	deadline := time.Now().Add(2 * time.Second)
+	for job.DownloadURL == "" && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+		json.Unmarshal(get(job.StatusURL, db.user.ID).Body.Bytes(), &job)
+	}
+	if job.Status != jobs.StatusSucceeded {
+		t.Fatalf("❌ la exportación no terminó: %+v", job)
+	}
+
+	// Otro usuario no puede ver ni descargar la exportación
+	if rr := get(job.DownloadURL, uuid.New()); rr.Code != http.StatusNotFound {
+		t.Errorf("❌ se esperaba 404 para otro usuario, se obtuvo %d", rr.Code)
+	}
+
+	rr = get(job.DownloadURL, db.user.ID)
+	if rr.Code != http.StatusOK || rr.Header().Get("Content-Type") != "application/zip" {
+		t.Fatalf("❌ descarga inesperada: %d %s", rr.Code, rr.Header().Get("Content-Type"))
+	}
+	zr, err := zip.NewReader(bytes.NewReader(rr.Body.Bytes()), int64(rr.Body.Len()))
+	if err != nil {
+		t.Fatalf("❌ ZIP inválido: %v", err)
+	}
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, _ := f.Open()
+		body, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(body)
+	}
+	if !strings.Contains(files["account.json"], "ana@example.com") {
+		t.Errorf("❌ account.json inesperado: %s", files["account.json"])
+	}
+	if !strings.Contains(files["watchlists.csv"], "Dividendos,KO") {
+		t.Errorf("❌ watchlists.csv inesperado: %s", files["watchlists.csv"])
+	}
+	if _, ok := files["portfolios.csv"]; !ok {
+		t.Errorf("❌ falta portfolios.csv: %v", files)
+	}
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jannin2/stock-app/backend/apierror"
+	"github.com/jannin2/stock-app/backend/database"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// enrichmentTierRequest es el cuerpo de PUT /admin/stocks/{ticker}/enrichment-tier.
+// Un tier vacío o "auto" vuelve a la asignación automática por capitalización.
+type enrichmentTierRequest struct {
+	Tier string `json:"tier"`
+}
+
+// SetEnrichmentTier maneja PUT /admin/stocks/{ticker}/enrichment-tier: fija cada cuánto
+// refresca el enricher un stock (hot, standard, archived o auto).
+func (h *StockHandlers) SetEnrichmentTier(w http.ResponseWriter, r *http.Request) {
+	ticker := strings.ToUpper(chi.URLParam(r, "ticker"))
+
+	var req enrichmentTierRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.HTTPError(w, fmt.Sprintf("Cuerpo JSON inválido: %v", err), http.StatusBadRequest)
+		return
+	}
+	tier := strings.ToLower(strings.TrimSpace(req.Tier))
+	if tier == "auto" {
+		tier = ""
+	}
+	if tier != "" && !models.IsValidEnrichmentTier(tier) {
+		apierror.HTTPError(w, fmt.Sprintf("Tier no soportado: %s (use hot, standard, archived o auto)", req.Tier), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.dbClient.SetEnrichmentTier(r.Context(), ticker, tier); err != nil {
+		if errors.Is(err, database.ErrStockNotFound) {
+			apierror.HTTPError(w, fmt.Sprintf("Stock no encontrado: %s", ticker), http.StatusNotFound)
+			return
+		}
+		apierror.Internal(w, "Error al asignar el tier", err)
+		return
+	}
+
+	if tier == "" {
+		tier = "auto"
+	}
+	writeJSON(w, r, http.StatusOK, map[string]string{"ticker": ticker, "enrichment_tier": tier})
+}
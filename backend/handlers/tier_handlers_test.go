@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jannin2/stock-app/backend/database"
+)
+
+// tierStockDB guarda los tiers asignados en memoria.
+type tierStockDB struct {
+	database.StockDB
+	tiers map[string]string
+}
+
+func (db *tierStockDB) SetEnrichmentTier(ctx context.Context, ticker, tier string) error {
+	if _, ok := db.tiers[ticker]; !ok {
+		return fmt.Errorf("%s: %w", ticker, database.ErrStockNotFound)
+	}
+	db.tiers[ticker] = tier
+	return nil
+}
+
+func TestSetEnrichmentTier(t *testing.T) {
+	db := &tierStockDB{tiers: map[string]string{"AAPL": "", "XYZ": "hot"}}
+	router := chi.NewRouter()
+	router.Put("/api/v1/admin/stocks/{ticker}/enrichment-tier", (&StockHandlers{dbClient: db}).SetEnrichmentTier)
+
+	tests := []struct {
+		ticker, body string
+		wantCode     int
+		wantTier     string
+	}{
+		{"xyz", `{"tier":"Archived"}`, http.StatusOK, "archived"},
+		{"AAPL", `{"tier":"auto"}`, http.StatusOK, ""},
+		{"AAPL", `{"tier":"cold"}`, http.StatusBadRequest, ""},
+		{"NOPE", `{"tier":"hot"}`, http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodPut, "/api/v1/admin/stocks/"+tt.ticker+"/enrichment-tier", strings.NewReader(tt.body)))
+		if rr.Code != tt.wantCode {
+			t.Errorf("❌ %s %s: estado %d, se esperaba %d: %s", tt.ticker, tt.body, rr.Code, tt.wantCode, rr.Body.String())
+			continue
+		}
+		if got := db.tiers[strings.ToUpper(tt.ticker)]; tt.wantCode == http.StatusOK && got != tt.wantTier {
+			t.Errorf("❌ %s: tier guardado %q, se esperaba %q", tt.ticker, got, tt.wantTier)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/apierror"
+	"github.com/jannin2/stock-app/backend/auth"
+)
+
+// Las fechas de las respuestas se serializan siempre en UTC (RFC 3339, ver writeJSON). La
+// zona horaria de la petición solo decide qué es "hoy" en los campos derivados del día,
+// como los movers del día o el inicio de una ventana de análisis.
+
+type locationKey struct{}
+
+// WithLocation devuelve una copia de ctx con la zona horaria de la petición.
+func WithLocation(ctx context.Context, loc *time.Location) context.Context {
+	return context.WithValue(ctx, locationKey{}, loc)
+}
+
+// LocationFromContext devuelve la zona horaria de la petición, o UTC si no se indicó.
+func LocationFromContext(ctx context.Context) *time.Location {
+	if loc, ok := ctx.Value(locationKey{}).(*time.Location); ok {
+		return loc
+	}
+	return time.UTC
+}
+
+// Timezone es el middleware que resuelve la zona horaria de la petición: el parámetro ?tz=
+// (un nombre IANA como Europe/Madrid) o, si no viene, la zona guardada en las preferencias
+// del usuario autenticado. Un tz desconocido se rechaza con 400.
+func (h *UserHandlers) Timezone(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimSpace(r.URL.Query().Get("tz"))
+		if name == "" {
+			// La respuesta depende entonces del usuario: una caché compartida no debe
+			// servírsela a otro
+			w.Header().Add("Vary", "Authorization")
+			name = h.preferredTimezone(r)
+		}
+		if name == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		loc, err := time.LoadLocation(name)
+		if err != nil {
+			apierror.HTTPError(w, fmt.Sprintf("Zona horaria desconocida: %s (use un nombre IANA, p. ej. Europe/Madrid)", name), http.StatusBadRequest)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(WithLocation(r.Context(), loc)))
+	})
+}
+
+// preferredTimezone devuelve la zona de las preferencias del usuario, o "" si la petición
+// es anónima o no se pudo leer: un fallo aquí no debe impedir responder en UTC.
+func (h *UserHandlers) preferredTimezone(r *http.Request) string {
+	userID, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		return ""
+	}
+	settings, err := h.users.GetNotificationSettings(r.Context(), userID)
+	if err != nil {
+		log.Printf("Advertencia: no se pudo leer la zona horaria del usuario %s: %v", userID, err)
+		return ""
+	}
+	return settings.Timezone
+}
+
+// localDate devuelve la fecha de calendario de t en la zona de la petición, como la
+// medianoche UTC de ese día: así se compara con las columnas DATE (trading_day, ...).
+func localDate(ctx context.Context, t time.Time) time.Time {
+	y, m, d := t.In(LocationFromContext(ctx)).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
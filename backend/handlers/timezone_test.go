@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/auth"
+	"github.com/jannin2/stock-app/backend/database"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// timezoneUserDB devuelve la misma zona horaria en las preferencias de cualquier usuario.
+type timezoneUserDB struct {
+	database.UserDB
+	timezone string
+}
+
+func (db *timezoneUserDB) GetNotificationSettings(context.Context, uuid.UUID) (models.NotificationSettings, error) {
+	return models.NotificationSettings{Timezone: db.timezone}, nil
+}
+
+// moversStockDB guarda el día con el que se pidieron los movers.
+type moversStockDB struct {
+	database.StockDB
+	day time.Time
+}
+
+func (db *moversStockDB) GetMarketMovers(ctx context.Context, day time.Time, limit int) (models.MarketMovers, error) {
+	db.day = day
+	return models.MarketMovers{Gainers: []models.Mover{}, Losers: []models.Mover{}}, nil
+}
+
+func TestTimezone(t *testing.T) {
+	if _, err := time.LoadLocation("Pacific/Kiritimati"); err != nil {
+		t.Skipf("⚠️ sin base de datos de zonas horarias: %v", err)
+	}
+	users := &UserHandlers{users: &timezoneUserDB{timezone: "Pacific/Kiritimati"}}
+	stocks := &moversStockDB{}
+	handler := users.Timezone(http.HandlerFunc(NewStockHandlers(stocks, nil).GetMarketMovers))
+	serve := func(path string, userID uuid.UUID) (*httptest.ResponseRecorder, marketMoversResponse) {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if userID != uuid.Nil {
+			req = req.WithContext(auth.WithUser(req.Context(), userID))
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		var resp marketMoversResponse
+		json.Unmarshal(rr.Body.Bytes(), &resp)
+		return rr, resp
+	}
+
+	// Kiritimati (UTC+14) y Pago Pago (UTC-11) están siempre en días distintos
+	_, resp := serve("/market/movers?tz=Pacific/Pago_Pago", uuid.New())
+	pagoPago := stocks.day
+	if resp.Timezone != "Pacific/Pago_Pago" {
+		t.Errorf("❌ el parámetro tz debería tener prioridad, se obtuvo %q", resp.Timezone)
+	}
+	_, resp = serve("/market/movers", uuid.New())
+	if resp.Timezone != "Pacific/Kiritimati" || !stocks.day.After(pagoPago) {
+		t.Errorf("❌ sin tz debería usarse la preferencia del usuario: %q, %v", resp.Timezone, stocks.day)
+	}
+	if stocks.day.Location() != time.UTC || stocks.day.Hour() != 0 {
+		t.Errorf("❌ el día debería ser una medianoche UTC: %v", stocks.day)
+	}
+	if _, resp = serve("/market/movers", uuid.Nil); resp.Timezone != "UTC" {
+		t.Errorf("❌ una petición anónima sin tz debería usar UTC, se obtuvo %q", resp.Timezone)
+	}
+	if rr, _ := serve("/market/movers?tz=Mars/Olympus", uuid.Nil); rr.Code != http.StatusBadRequest {
+		t.Errorf("❌ con una zona desconocida se esperaba 400, se obtuvo %d", rr.Code)
+	}
+}
@@ -0,0 +1,333 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/apierror"
+	"github.com/jannin2/stock-app/backend/auth"
+	"github.com/jannin2/stock-app/backend/database"
+)
+
+// freshSecondFactorWindow es cuánto vale una verificación del segundo factor para las
+// acciones sensibles (crear una clave de API, borrar una cartera, ...).
+const freshSecondFactorWindow = 5 * time.Minute
+
+// totpIssuer es el nombre con el que aparece la cuenta en la app de autenticación.
+const totpIssuer = "Stock App"
+
+type secondFactorRequest struct {
+	Code string `json:"code"` // Código TOTP o de recuperación
+}
+
+// twoFactorEnrollment es la respuesta de POST /me/2fa/enroll.
+type twoFactorEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"` // Para el código QR
+}
+
+// recoveryCodesResponse muestra los códigos de recuperación; es la única vez que se ven.
+type recoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// verifiedSessionResponse es la respuesta de POST /me/2fa/verify: el token de sesión que
+// sustituye al actual y autoriza las acciones sensibles.
+type verifiedSessionResponse struct {
+	Token          string    `json:"token"`
+	TokenExpiresAt time.Time `json:"token_expires_at"`
+}
+
+type apiKeyResponse struct {
+	APIKey string `json:"api_key"`
+}
+
+// GetTwoFactor maneja GET /me/2fa: si el 2FA está activado y cuántos códigos de
+// recuperación quedan.
+func (h *UserHandlers) GetTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUser(w, r)
+	if !ok {
+		return
+	}
+	tf, err := h.users.GetTwoFactor(r.Context(), userID)
+	if err != nil {
+		writeError(w, err, "Error al obtener el estado del 2FA")
+		return
+	}
+	writeJSON(w, r, http.StatusOK, tf)
+}
+
+// EnrollTwoFactor maneja POST /me/2fa/enroll: genera un secreto TOTP nuevo. El 2FA no se
+// exige hasta confirmarlo con un código de la app (POST /me/2fa/confirm).
+func (h *UserHandlers) EnrollTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUser(w, r)
+	if !ok {
+		return
+	}
+	creds, err := h.users.GetCredentials(r.Context(), userID)
+	if err != nil {
+		writeError(w, err, "Error al activar el 2FA")
+		return
+	}
+
+	secret, err := auth.NewTOTPSecret()
+	if err == nil {
+		err = h.users.SetPendingTOTPSecret(r.Context(), userID, secret)
+	}
+	if errors.Is(err, database.ErrTwoFactorEnabled) {
+		apierror.HTTPError(w, "La autenticación en dos pasos ya está activada", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		writeError(w, err, "Error al activar el 2FA")
+		return
+	}
+	writeJSON(w, r, http.StatusOK, twoFactorEnrollment{Secret: secret, URI: auth.TOTPURI(totpIssuer, creds.Email, secret)})
+}
+
+// ConfirmTwoFactor maneja POST /me/2fa/confirm: con un código válido del secreto pendiente
+// activa el 2FA y devuelve los códigos de recuperación.
+func (h *UserHandlers) ConfirmTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUser(w, r)
+	if !ok {
+		return
+	}
+	var req secondFactorRequest
+	if !decodeAuthRequest(w, r, &req) {
+		return
+	}
+	tf, err := h.users.GetTwoFactor(r.Context(), userID)
+	if err != nil {
+		writeError(w, err, "Error al activar el 2FA")
+		return
+	}
+	if tf.Enabled() {
+		apierror.HTTPError(w, "La autenticación en dos pasos ya está activada", http.StatusConflict)
+		return
+	}
+	if tf.Secret == "" {
+		apierror.HTTPError(w, "Primero inicia el alta con POST /me/2fa/enroll", http.StatusConflict)
+		return
+	}
+	step, valid := auth.ValidateTOTP(tf.Secret, req.Code, h.now())
+	if !valid {
+		apierror.HTTPError(w, "Código incorrecto", http.StatusUnauthorized)
+		return
+	}
+	if err := h.users.RecordSecondFactor(r.Context(), userID, step); err != nil {
+		writeSecondFactorError(w, err)
+		return
+	}
+	h.issueRecoveryCodes(w, r, userID)
+}
+
+// VerifySecondFactor maneja POST /me/2fa/verify: comprueba un código TOTP o de
+// recuperación y devuelve un token de sesión nuevo que autoriza las acciones sensibles
+// durante freshSecondFactorWindow. Solo esa sesión queda autorizada: las demás sesiones y
+// la clave de API del usuario siguen sin poder hacerlas.
+func (h *UserHandlers) VerifySecondFactor(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUser(w, r)
+	if !ok {
+		return
+	}
+	session, ok := auth.SessionFromContext(r.Context())
+	if !ok || session.Subject != userID {
+		apierror.HTTPError(w, "La verificación del segundo factor requiere un token de sesión (POST /api/v1/auth/login)", http.StatusForbidden)
+		return
+	}
+	var req secondFactorRequest
+	if !decodeAuthRequest(w, r, &req) {
+		return
+	}
+	tf, err := h.users.GetTwoFactor(r.Context(), userID)
+	if err != nil {
+		writeError(w, err, "Error al verificar el segundo factor")
+		return
+	}
+	if !tf.Enabled() {
+		apierror.HTTPError(w, "La autenticación en dos pasos no está activada", http.StatusConflict)
+		return
+	}
+	if err := h.checkSecondFactor(r.Context(), userID, tf.Secret, req.Code); err != nil {
+		writeSecondFactorError(w, err)
+		return
+	}
+	token, expiresAt := auth.SignVerifiedAccessToken(session, h.now())
+	writeJSON(w, r, http.StatusOK, verifiedSessionResponse{Token: token, TokenExpiresAt: expiresAt})
+}
+
+// DisableTwoFactor maneja DELETE /me/2fa. Exige un código en el cuerpo aunque haya una
+// verificación reciente.
+func (h *UserHandlers) DisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUser(w, r)
+	if !ok {
+		return
+	}
+	var req secondFactorRequest
+	if !decodeAuthRequest(w, r, &req) {
+		return
+	}
+	tf, err := h.users.GetTwoFactor(r.Context(), userID)
+	if err != nil {
+		writeError(w, err, "Error al desactivar el 2FA")
+		return
+	}
+	if tf.Enabled() {
+		if err := h.checkSecondFactor(r.Context(), userID, tf.Secret, req.Code); err != nil {
+			writeSecondFactorError(w, err)
+			return
+		}
+	}
+	if err := h.users.DisableTwoFactor(r.Context(), userID); err != nil {
+		writeError(w, err, "Error al desactivar el 2FA")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RegenerateRecoveryCodes maneja POST /me/2fa/recovery-codes (requiere 2FA reciente):
+// invalida los códigos anteriores y devuelve unos nuevos.
+func (h *UserHandlers) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.requireFreshSecondFactor(w, r)
+	if !ok {
+		return
+	}
+	tf, err := h.users.GetTwoFactor(r.Context(), userID)
+	if err != nil {
+		writeError(w, err, "Error al regenerar los códigos de recuperación")
+		return
+	}
+	if !tf.Enabled() {
+		apierror.HTTPError(w, "La autenticación en dos pasos no está activada", http.StatusConflict)
+		return
+	}
+	h.issueRecoveryCodes(w, r, userID)
+}
+
+// CreateAPIKey maneja POST /me/api-key (requiere 2FA reciente): emite una clave de API
+// nueva que sustituye a la actual.
+func (h *UserHandlers) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.requireFreshSecondFactor(w, r)
+	if !ok {
+		return
+	}
+	key, err := auth.NewAPIKey()
+	if err == nil {
+		err = h.users.SetAPIKeyHash(r.Context(), userID, auth.HashAPIKey(key))
+	}
+	if err != nil {
+		writeError(w, err, "Error al crear la clave de API")
+		return
+	}
+	writeJSON(w, r, http.StatusCreated, apiKeyResponse{APIKey: key})
+}
+
+// DeletePortfolio maneja DELETE /me/portfolios/{id} (requiere 2FA reciente).
+func (h *UserHandlers) DeletePortfolio(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.requireFreshSecondFactor(w, r)
+	if !ok {
+		return
+	}
+	portfolioID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		apierror.HTTPError(w, "ID de cartera inválido", http.StatusBadRequest)
+		return
+	}
+	err = h.users.DeletePortfolio(r.Context(), userID, portfolioID)
+	if errors.Is(err, database.ErrPortfolioNotFound) {
+		apierror.HTTPError(w, "Cartera no encontrada", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		writeError(w, err, "Error al borrar la cartera")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// requireFreshSecondFactor devuelve el usuario autenticado si no tiene 2FA o si el token de
+// sesión de la petición lo verificó hace menos de freshSecondFactorWindow; si no, responde
+// 403. Con 2FA, una clave de API nunca basta.
+func (h *UserHandlers) requireFreshSecondFactor(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	userID, ok := requireUser(w, r)
+	if !ok {
+		return uuid.Nil, false
+	}
+	tf, err := h.users.GetTwoFactor(r.Context(), userID)
+	if err != nil {
+		writeError(w, err, "Error al comprobar el segundo factor")
+		return uuid.Nil, false
+	}
+	if tf.Enabled() && !h.sessionVerifiedSecondFactor(r.Context(), userID) {
+		apierror.HTTPError(w, "Esta acción requiere verificar el segundo factor (POST /api/v1/me/2fa/verify)", http.StatusForbidden)
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+// sessionVerifiedSecondFactor indica si la petición llega con un token de sesión de userID
+// que verificó el segundo factor hace menos de freshSecondFactorWindow.
+func (h *UserHandlers) sessionVerifiedSecondFactor(ctx context.Context, userID uuid.UUID) bool {
+	session, ok := auth.SessionFromContext(ctx)
+	if !ok || session.Subject != userID || session.SecondFactorAt == 0 {
+		return false
+	}
+	return h.now().Sub(time.Unix(session.SecondFactorAt, 0)) <= freshSecondFactorWindow
+}
+
+// errInvalidSecondFactor indica que el código no es un TOTP ni un código de recuperación válido.
+var errInvalidSecondFactor = errors.New("código incorrecto")
+
+// checkSecondFactor acepta un código TOTP de 6 dígitos o un código de recuperación y
+// registra la verificación.
+func (h *UserHandlers) checkSecondFactor(ctx context.Context, userID uuid.UUID, secret, code string) error {
+	code = strings.TrimSpace(code)
+	if step, ok := auth.ValidateTOTP(secret, code, h.now()); ok {
+		return h.users.RecordSecondFactor(ctx, userID, step)
+	}
+	if code == "" {
+		return errInvalidSecondFactor
+	}
+	used, err := h.users.UseRecoveryCode(ctx, userID, auth.HashRecoveryCode(code))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return errInvalidSecondFactor
+	}
+	return nil
+}
+
+func (h *UserHandlers) issueRecoveryCodes(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	codes, err := auth.NewRecoveryCodes()
+	if err != nil {
+		apierror.Internal(w, "Error al generar los códigos de recuperación", err)
+		return
+	}
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = auth.HashRecoveryCode(code)
+	}
+	if err := h.users.EnableTwoFactor(r.Context(), userID, hashes); err != nil {
+		writeError(w, err, "Error al guardar los códigos de recuperación")
+		return
+	}
+	writeJSON(w, r, http.StatusOK, recoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// writeSecondFactorError responde 401 a los códigos incorrectos o reutilizados.
+func writeSecondFactorError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errInvalidSecondFactor):
+		apierror.HTTPError(w, "Código incorrecto", http.StatusUnauthorized)
+	case errors.Is(err, database.ErrSecondFactorReused):
+		apierror.HTTPError(w, "Código ya utilizado; espera al siguiente", http.StatusUnauthorized)
+	default:
+		writeError(w, err, "Error al verificar el segundo factor")
+	}
+}
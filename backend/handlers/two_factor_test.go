@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/auth"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+func TestTwoFactor_GatesSensitiveActions(t *testing.T) {
+	db := newAccountUserDB()
+	now := time.Date(2025, 1, 6, 12, 0, 0, 0, time.UTC)
+	db.now = func() time.Time { return now }
+	h := NewUserHandlers(db, nil, nil)
+	h.now = db.now
+
+	passwordHash, _ := auth.HashPassword("una contraseña larga")
+	verifiedAt := now
+	user := &models.UserCredentials{User: models.User{ID: uuid.New(), Email: "ana@example.com", EmailVerifiedAt: &verifiedAt}, PasswordHash: passwordHash}
+	db.accounts[user.Email] = user
+	portfolioID := uuid.New()
+	db.portfolios[portfolioID] = user.ID
+
+	router := chi.NewRouter()
+	router.Post("/2fa/enroll", h.EnrollTwoFactor)
+	router.Post("/2fa/confirm", h.ConfirmTwoFactor)
+	router.Post("/2fa/verify", h.VerifySecondFactor)
+	router.Post("/api-key", h.CreateAPIKey)
+	router.Delete("/portfolios/{id}", h.DeletePortfolio)
+	router.Post("/login", h.Login)
+	// session es el token de sesión con el que llegan las peticiones; nil = clave de API.
+	fingerprint := auth.Fingerprint(passwordHash)
+	session := &auth.AccessClaims{Subject: user.ID, Fingerprint: fingerprint}
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		ctx := auth.WithUser(req.Context(), user.ID)
+		if session != nil {
+			ctx = auth.WithSession(ctx, *session)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req.WithContext(ctx))
+		return rr
+	}
+	// verify verifica el segundo factor y pasa a usar el token de sesión que devuelve.
+	verify := func(body string) *httptest.ResponseRecorder {
+		t.Helper()
+		rr := serve(http.MethodPost, "/2fa/verify", body)
+		if rr.Code != http.StatusOK {
+			return rr
+		}
+		var resp verifiedSessionResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("❌ respuesta de la verificación: %v", err)
+		}
+		claims, err := auth.VerifyAccessToken(resp.Token, now)
+		if err != nil || claims.Subject != user.ID || claims.SecondFactorAt != now.Unix() {
+			t.Fatalf("❌ token de sesión verificado inesperado: %+v (%v)", claims, err)
+		}
+		session = &claims
+		return rr
+	}
+	codeAt := func(at time.Time) string {
+		code, _ := auth.TOTPCode(db.twoFactor[user.ID].Secret, at)
+		return `{"code":"` + code + `"}`
+	}
+
+	// Sin 2FA las acciones sensibles no piden nada más
+	if rr := serve(http.MethodPost, "/api-key", ""); rr.Code != http.StatusCreated {
+		t.Fatalf("❌ sin 2FA: estado %d: %s", rr.Code, rr.Body)
+	}
+
+	rr := serve(http.MethodPost, "/2fa/enroll", "")
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "otpauth://totp/") {
+		t.Fatalf("❌ alta del 2FA: estado %d: %s", rr.Code, rr.Body)
+	}
+	if rr := serve(http.MethodPost, "/2fa/confirm", `{"code":"000000"}`); rr.Code != http.StatusUnauthorized {
+		t.Errorf("❌ confirmación con código incorrecto: estado %d", rr.Code)
+	}
+	rr = serve(http.MethodPost, "/2fa/confirm", codeAt(now))
+	var codes recoveryCodesResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &codes); err != nil || rr.Code != http.StatusOK || len(codes.RecoveryCodes) != auth.RecoveryCodeCount {
+		t.Fatalf("❌ confirmación del 2FA: estado %d: %s", rr.Code, rr.Body)
+	}
+
+	// Las acciones sensibles exigen que la sesión verifique el segundo factor
+	now = now.Add(freshSecondFactorWindow + time.Minute)
+	if rr := serve(http.MethodDelete, "/portfolios/"+portfolioID.String(), ""); rr.Code != http.StatusForbidden {
+		t.Errorf("❌ borrar cartera sin 2FA reciente: estado %d, se esperaba 403", rr.Code)
+	}
+	if rr := verify(codeAt(now)); rr.Code != http.StatusOK {
+		t.Fatalf("❌ verificación: estado %d: %s", rr.Code, rr.Body)
+	}
+	if rr := verify(codeAt(now)); rr.Code != http.StatusUnauthorized {
+		t.Errorf("❌ reutilizar un código TOTP: estado %d, se esperaba 401", rr.Code)
+	}
+
+	// La verificación vale solo para la sesión que la hizo: ni otra sesión ni la clave de API
+	// del usuario pueden aprovecharla
+	verified := session
+	session = &auth.AccessClaims{Subject: user.ID, Fingerprint: fingerprint}
+	if rr := serve(http.MethodPost, "/api-key", ""); rr.Code != http.StatusForbidden {
+		t.Errorf("❌ crear clave de API desde otra sesión: estado %d, se esperaba 403", rr.Code)
+	}
+	session = nil
+	if rr := serve(http.MethodDelete, "/portfolios/"+portfolioID.String(), ""); rr.Code != http.StatusForbidden {
+		t.Errorf("❌ borrar cartera con la clave de API: estado %d, se esperaba 403", rr.Code)
+	}
+	if rr := serve(http.MethodPost, "/2fa/verify", codeAt(now.Add(time.Minute))); rr.Code != http.StatusForbidden {
+		t.Errorf("❌ verificar con la clave de API: estado %d, se esperaba 403", rr.Code)
+	}
+	session = verified
+	if rr := serve(http.MethodDelete, "/portfolios/"+portfolioID.String(), ""); rr.Code != http.StatusNoContent {
+		t.Errorf("❌ borrar cartera con 2FA reciente: estado %d: %s", rr.Code, rr.Body)
+	}
+
+	// Un código de recuperación sirve una sola vez
+	now = now.Add(freshSecondFactorWindow + time.Minute)
+	if rr := serve(http.MethodPost, "/api-key", ""); rr.Code != http.StatusForbidden {
+		t.Errorf("❌ crear clave de API pasada la ventana: estado %d, se esperaba 403", rr.Code)
+	}
+	recovery := `{"code":"` + strings.ToUpper(codes.RecoveryCodes[0]) + `"}`
+	if rr := verify(recovery); rr.Code != http.StatusOK {
+		t.Errorf("❌ código de recuperación: estado %d: %s", rr.Code, rr.Body)
+	}
+	if rr := verify(recovery); rr.Code != http.StatusUnauthorized {
+		t.Errorf("❌ reutilizar un código de recuperación: estado %d, se esperaba 401", rr.Code)
+	}
+	if rr := serve(http.MethodPost, "/api-key", ""); rr.Code != http.StatusCreated {
+		t.Errorf("❌ crear clave de API tras el código de recuperación: estado %d", rr.Code)
+	}
+
+	// El login pide el segundo factor
+	login := `{"email":"ana@example.com","password":"una contraseña larga"`
+	if rr := serve(http.MethodPost, "/login", login+"}"); rr.Code != http.StatusUnauthorized || !strings.Contains(rr.Body.String(), `"code":"two_factor_required"`) {
+		t.Errorf("❌ login sin código: estado %d: %s", rr.Code, rr.Body)
+	}
+	now = now.Add(time.Minute)
+	code, _ := auth.TOTPCode(db.twoFactor[user.ID].Secret, now)
+	if rr := serve(http.MethodPost, "/login", login+`,"code":"`+code+`"}`); rr.Code != http.StatusOK {
+		t.Errorf("❌ login con código: estado %d: %s", rr.Code, rr.Body)
+	}
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/apierror"
+	"github.com/jannin2/stock-app/backend/auth"
+	"github.com/jannin2/stock-app/backend/config"
+	"github.com/jannin2/stock-app/backend/database"
+	"github.com/jannin2/stock-app/backend/jobs"
+	"github.com/jannin2/stock-app/backend/mail"
+)
+
+// UserHandlers gestiona las cuentas (registro, login, verificación de e-mail y reseteo de
+// contraseña) y la cuenta del usuario autenticado y sus datos.
+type UserHandlers struct {
+	users   database.UserDB
+	jobs    *jobs.Queue            // Genera en segundo plano las exportaciones de cuenta
+	deliver func(msg mail.Message) // Entrega los e-mails de verificación y reseteo
+	now     func() time.Time
+}
+
+// NewUserHandlers crea los manejadores de cuenta sobre la base de datos de usuarios, la
+// cola de trabajos y el servicio de e-mail.
+func NewUserHandlers(users database.UserDB, queue *jobs.Queue, mailer mail.Mailer) *UserHandlers {
+	return &UserHandlers{users: users, jobs: queue, deliver: deliverAsync(mailer), now: time.Now}
+}
+
+// accountDeletion es la respuesta de DELETE /me.
+type accountDeletion struct {
+	DeletedAt  time.Time `json:"deleted_at"`
+	PurgeAfter time.Time `json:"purge_after"` // A partir de aquí el job de purga elimina los datos
+}
+
+// ExportMyData maneja GET /me/data: descarga en JSON la cuenta y todos los datos del
+// usuario (watchlists, notas, carteras), para conservarlos antes de borrar la cuenta.
+func (h *UserHandlers) ExportMyData(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	data, err := h.users.GetUserData(r.Context(), userID)
+	if err != nil {
+		writeError(w, err, "Error al obtener los datos del usuario")
+		return
+	}
+	data.ExportedAt = time.Now().UTC()
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="stock-app-datos-%s.json"`, data.ExportedAt.Format("2006-01-02")))
+	writeJSON(w, r, http.StatusOK, data)
+}
+
+// DeleteMe maneja DELETE /me: marca la cuenta y sus datos como borrados. La clave de API
+// deja de funcionar de inmediato y los datos se eliminan definitivamente pasado
+// config.UserDataRetention.
+func (h *UserHandlers) DeleteMe(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	deletedAt, err := h.users.SoftDeleteUser(r.Context(), userID)
+	if err != nil {
+		writeError(w, err, "Error al borrar la cuenta")
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, accountDeletion{
+		DeletedAt:  deletedAt.UTC(),
+		PurgeAfter: deletedAt.Add(config.Current().UserDataRetention).UTC(),
+	})
+}
+
+// requireUser devuelve el usuario autenticado o responde 401 si la petición no tiene uno
+// (por ejemplo, una petición solo con la clave de administrador).
+func requireUser(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	userID, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		apierror.HTTPError(w, "Se requiere la clave de API de un usuario", http.StatusUnauthorized)
+	}
+	return userID, ok
+}
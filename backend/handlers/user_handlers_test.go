@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/auth"
+	"github.com/jannin2/stock-app/backend/config"
+	"github.com/jannin2/stock-app/backend/database"
+	"github.com/jannin2/stock-app/backend/mail"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// fakeUserDB guarda un único usuario y simula su borrado lógico.
+type fakeUserDB struct {
+	database.UserDB
+	user      models.User
+	deletedAt time.Time
+}
+
+func (f *fakeUserDB) GetUserData(_ context.Context, userID uuid.UUID) (models.UserData, error) {
+	if userID != f.user.ID || !f.deletedAt.IsZero() {
+		return models.UserData{}, database.ErrUserNotFound
+	}
+	return models.UserData{User: f.user, Watchlists: []models.Watchlist{{Name: "Dividendos", Tickers: []string{"KO"}}}}, nil
+}
+
+func (f *fakeUserDB) SoftDeleteUser(_ context.Context, userID uuid.UUID) (time.Time, error) {
+	if userID != f.user.ID || !f.deletedAt.IsZero() {
+		return time.Time{}, database.ErrUserNotFound
+	}
+	f.deletedAt = time.Date(2025, 1, 6, 12, 0, 0, 0, time.UTC)
+	return f.deletedAt, nil
+}
+
+func TestUserHandlers_ExportThenDelete(t *testing.T) {
+	db := &fakeUserDB{user: models.User{ID: uuid.New(), Email: "ana@example.com"}}
+	h := NewUserHandlers(db, nil, mail.LogMailer{})
+	t.Cleanup(func() { config.Set(config.Default()) })
+	config.Set(config.Default())
+
+	serve := func(handler http.HandlerFunc, method string, userID *uuid.UUID) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/v1/me", nil)
+		if userID != nil {
+			req = req.WithContext(auth.WithUser(req.Context(), *userID))
+		}
+		rr := httptest.NewRecorder()
+		handler(rr, req)
+		return rr
+	}
+
+	if rr := serve(h.ExportMyData, http.MethodGet, nil); rr.Code != http.StatusUnauthorized {
+		t.Errorf("❌ sin usuario se esperaba 401, se obtuvo %d", rr.Code)
+	}
+
+	rr := serve(h.ExportMyData, http.MethodGet, &db.user.ID)
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Header().Get("Content-Disposition"), "attachment") {
+		t.Fatalf("❌ exportación inesperada: %d %q", rr.Code, rr.Header().Get("Content-Disposition"))
+	}
+	var data models.UserData
+	if err := json.Unmarshal(rr.Body.Bytes(), &data); err != nil || data.User.Email != "ana@example.com" || len(data.Watchlists) != 1 || data.ExportedAt.IsZero() {
+		t.Errorf("❌ datos exportados inesperados: %s (%v)", rr.Body, err)
+	}
+
+	rr = serve(h.DeleteMe, http.MethodDelete, &db.user.ID)
+	var deletion accountDeletion
+	if err := json.Unmarshal(rr.Body.Bytes(), &deletion); err != nil || rr.Code != http.StatusOK {
+		t.Fatalf("❌ borrado inesperado: %d %s", rr.Code, rr.Body)
+	}
+	if want := db.deletedAt.Add(30 * 24 * time.Hour); !deletion.PurgeAfter.Equal(want) {
+		t.Errorf("❌ purge_after %s, se esperaba %s", deletion.PurgeAfter, want)
+	}
+
+	// Tras el borrado, los datos ya no se pueden exportar ni volver a borrar
+	if rr := serve(h.ExportMyData, http.MethodGet, &db.user.ID); rr.Code != http.StatusNotFound {
+		t.Errorf("❌ exportar una cuenta borrada debería devolver 404, se obtuvo %d", rr.Code)
+	}
+	if rr := serve(h.DeleteMe, http.MethodDelete, &db.user.ID); rr.Code != http.StatusNotFound {
+		t.Errorf("❌ borrar dos veces debería devolver 404, se obtuvo %d", rr.Code)
+	}
+}
@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/apierror"
+	"github.com/jannin2/stock-app/backend/database"
+	"github.com/jannin2/stock-app/backend/models"
+	"github.com/jannin2/stock-app/backend/stream"
+)
+
+// WatchlistHandlers gestiona las watchlists de los usuarios bajo /watchlists. Se crean con
+// UserHandlers.CreateWatchlist, que aplica la cuota; aquí se listan, se consultan con los
+// datos de sus stocks, se cambian sus tickers, se comparten y se borran. Cada cambio se
+// publica en el hub para los miembros que siguen la watchlist por /stream.
+type WatchlistHandlers struct {
+	users  database.UserDB
+	stocks database.StockDB
+	hub    *stream.Hub
+}
+
+// NewWatchlistHandlers crea los manejadores de watchlists, que publican los cambios en hub.
+func NewWatchlistHandlers(users database.UserDB, stocks database.StockDB, hub *stream.Hub) *WatchlistHandlers {
+	return &WatchlistHandlers{users: users, stocks: stocks, hub: hub}
+}
+
+// Acciones de los eventos de watchlist publicados en el stream.
+const (
+	watchlistTickersChanged = "tickers_changed"
+	watchlistMemberSet      = "member_set"
+	watchlistMemberRemoved  = "member_removed"
+	watchlistDeleted        = "deleted"
+)
+
+// watchlistEvent son los datos de una actualización de tipo stream.UpdateWatchlist.
+type watchlistEvent struct {
+	Action  string                  `json:"action"`
+	By      uuid.UUID               `json:"by"`                // Usuario que hizo el cambio
+	Tickers []string                `json:"tickers,omitempty"` // Todos los tickers tras el cambio
+	Member  *models.WatchlistMember `json:"member,omitempty"`
+}
+
+// publish envía event a quienes siguen la watchlist.
+func (h *WatchlistHandlers) publish(watchlistID uuid.UUID, event watchlistEvent) {
+	h.hub.Publish(stream.Update{Type: stream.UpdateWatchlist, Watchlist: watchlistID.String(), Data: event})
+}
+
+// ListWatchlists maneja GET /watchlists: las watchlists del usuario y las compartidas con
+// él, con su rol en cada una, sin los datos de los stocks.
+func (h *WatchlistHandlers) ListWatchlists(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUser(w, r)
+	if !ok {
+		return
+	}
+	watchlists, err := h.users.ListWatchlists(r.Context(), userID)
+	if err != nil {
+		writeError(w, err, "Error al obtener las watchlists")
+		return
+	}
+	writeJSON(w, r, http.StatusOK, watchlists)
+}
+
+// GetWatchlist maneja GET /watchlists/{id}: la watchlist con los datos enriquecidos de sus
+// stocks. Los tickers que todavía no se siguen se devuelven en missing.
+func (h *WatchlistHandlers) GetWatchlist(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUser(w, r)
+	if !ok {
+		return
+	}
+	watchlistID, ok := watchlistIDParam(w, r)
+	if !ok {
+		return
+	}
+	watchlist, err := h.users.GetWatchlist(r.Context(), userID, watchlistID)
+	if err != nil {
+		writeError(w, err, "Error al obtener la watchlist")
+		return
+	}
+	stocks, err := h.stocks.GetStocksByTickers(r.Context(), watchlist.Tickers)
+	if err != nil {
+		apierror.Internal(w, "Error al obtener los stocks de la watchlist", err)
+		return
+	}
+
+	tracked := make(map[string]bool, len(stocks))
+	for _, s := range stocks {
+		tracked[s.Ticker] = true
+	}
+	missing := []string{}
+	for _, ticker := range watchlist.Tickers {
+		if !tracked[ticker] {
+			missing = append(missing, ticker)
+		}
+	}
+	setDataAsOf(w, stocks)
+	writeJSON(w, r, http.StatusOK, models.WatchlistStocks{Watchlist: watchlist, Stocks: stocks, Missing: missing})
+}
+
+// DeleteWatchlist maneja DELETE /watchlists/{id}. Solo puede borrarla su propietario; los
+// miembros que la seguían reciben el evento deleted y dejan de seguirla.
+func (h *WatchlistHandlers) DeleteWatchlist(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUser(w, r)
+	if !ok {
+		return
+	}
+	watchlistID, ok := watchlistIDParam(w, r)
+	if !ok {
+		return
+	}
+	if err := h.users.DeleteWatchlist(r.Context(), userID, watchlistID); err != nil {
+		writeError(w, err, "Error al borrar la watchlist")
+		return
+	}
+	h.publish(watchlistID, watchlistEvent{Action: watchlistDeleted, By: userID})
+	h.hub.Unfollow(watchlistID.String(), "")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// watchlistTickersRequest es el cuerpo de PATCH /watchlists/{id}.
+type watchlistTickersRequest struct {
+	Add    []string `json:"add"`
+	Remove []string `json:"remove"`
+}
+
+// UpdateWatchlist maneja PATCH /watchlists/{id}: {"add": [...], "remove": [...]} añade y
+// quita tickers. Se envían los cambios y no la lista completa para que dos miembros que
+// editan a la vez no se pisen. Pueden hacerlo el propietario y los editores.
+func (h *WatchlistHandlers) UpdateWatchlist(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUser(w, r)
+	if !ok {
+		return
+	}
+	watchlistID, ok := watchlistIDParam(w, r)
+	if !ok {
+		return
+	}
+	var req watchlistTickersRequest
+	if !decodeAuthRequest(w, r, &req) {
+		return
+	}
+	add, err := normalizeTickers(req.Add)
+	if err == nil {
+		req.Remove, err = normalizeTickers(req.Remove)
+	}
+	if err != nil {
+		apierror.HTTPError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(add) == 0 && len(req.Remove) == 0 {
+		apierror.HTTPError(w, "Indique los tickers que añadir (add) o quitar (remove)", http.StatusBadRequest)
+		return
+	}
+
+	watchlist, err := h.users.UpdateWatchlistTickers(r.Context(), userID, watchlistID, add, req.Remove, maxWatchlistTickers)
+	if errors.Is(err, database.ErrWatchlistFull) {
+		apierror.HTTPError(w, fmt.Sprintf("Una watchlist admite como máximo %d tickers", maxWatchlistTickers), http.StatusUnprocessableEntity)
+		return
+	}
+	if err != nil {
+		writeError(w, err, "Error al actualizar la watchlist")
+		return
+	}
+	h.publish(watchlistID, watchlistEvent{Action: watchlistTickersChanged, By: userID, Tickers: watchlist.Tickers})
+	writeJSON(w, r, http.StatusOK, watchlist)
+}
+
+// ListWatchlistMembers maneja GET /watchlists/{id}/members: el propietario y los miembros
+// con su rol. Cualquier miembro puede verlos.
+func (h *WatchlistHandlers) ListWatchlistMembers(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUser(w, r)
+	if !ok {
+		return
+	}
+	watchlistID, ok := watchlistIDParam(w, r)
+	if !ok {
+		return
+	}
+	members, err := h.users.ListWatchlistMembers(r.Context(), userID, watchlistID)
+	if err != nil {
+		writeError(w, err, "Error al obtener los miembros de la watchlist")
+		return
+	}
+	writeJSON(w, r, http.StatusOK, members)
+}
+
+// watchlistMemberRequest es el cuerpo de PUT /watchlists/{id}/members.
+type watchlistMemberRequest struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+// SetWatchlistMember maneja PUT /watchlists/{id}/members: {"email": ..., "role": "editor" |
+// "viewer"} comparte la watchlist con ese usuario o le cambia el rol. Solo puede hacerlo el
+// propietario.
+func (h *WatchlistHandlers) SetWatchlistMember(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUser(w, r)
+	if !ok {
+		return
+	}
+	watchlistID, ok := watchlistIDParam(w, r)
+	if !ok {
+		return
+	}
+	var req watchlistMemberRequest
+	if !decodeAuthRequest(w, r, &req) {
+		return
+	}
+	if req.Role != models.WatchlistEditor && req.Role != models.WatchlistViewer {
+		apierror.HTTPError(w, fmt.Sprintf("Rol inválido %q (editor o viewer)", req.Role), http.StatusBadRequest)
+		return
+	}
+	email, ok := normalizeEmail(w, req.Email)
+	if !ok {
+		return
+	}
+	// El e-mail se resuelve solo para el propietario: a cualquier otro, la respuesta no debe
+	// revelar si hay una cuenta con ese e-mail.
+	watchlist, err := h.users.GetWatchlist(r.Context(), userID, watchlistID)
+	if err == nil && watchlist.Role != models.WatchlistOwner {
+		err = database.ErrWatchlistForbidden
+	}
+	if err != nil {
+		writeError(w, err, "Error al compartir la watchlist")
+		return
+	}
+	member, err := h.users.GetCredentialsByEmail(r.Context(), email)
+	if errors.Is(err, database.ErrUserNotFound) {
+		apierror.HTTPError(w, "No hay ningún usuario con ese e-mail", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		writeError(w, err, "Error al buscar el usuario")
+		return
+	}
+	if member.ID == userID {
+		apierror.HTTPError(w, "El propietario no puede ser miembro de su propia watchlist", http.StatusBadRequest)
+		return
+	}
+
+	added, err := h.users.SetWatchlistMember(r.Context(), userID, watchlistID, member.ID, req.Role)
+	if err != nil {
+		writeError(w, err, "Error al compartir la watchlist")
+		return
+	}
+	added.Email = member.Email
+	h.publish(watchlistID, watchlistEvent{Action: watchlistMemberSet, By: userID, Member: &added})
+	writeJSON(w, r, http.StatusOK, added)
+}
+
+// RemoveWatchlistMember maneja DELETE /watchlists/{id}/members/{userID}: el propietario deja
+// de compartir la watchlist con un miembro, o un miembro la abandona indicando su propio id.
+// El miembro recibe el evento member_removed y deja de seguirla.
+func (h *WatchlistHandlers) RemoveWatchlistMember(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUser(w, r)
+	if !ok {
+		return
+	}
+	watchlistID, ok := watchlistIDParam(w, r)
+	if !ok {
+		return
+	}
+	memberID, err := uuid.Parse(chi.URLParam(r, "userID"))
+	if err != nil {
+		apierror.HTTPError(w, "ID de usuario inválido", http.StatusBadRequest)
+		return
+	}
+	if err := h.users.RemoveWatchlistMember(r.Context(), userID, watchlistID, memberID); err != nil {
+		writeError(w, err, "Error al quitar el miembro de la watchlist")
+		return
+	}
+	h.publish(watchlistID, watchlistEvent{Action: watchlistMemberRemoved, By: userID, Member: &models.WatchlistMember{UserID: memberID}})
+	h.hub.Unfollow(watchlistID.String(), memberID.String())
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// watchlistIDParam lee el {id} de la ruta y responde 400 si no es un UUID.
+func watchlistIDParam(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		apierror.HTTPError(w, "ID de watchlist inválido", http.StatusBadRequest)
+		return uuid.Nil, false
+	}
+	return id, true
+}
@@ -0,0 +1,294 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/auth"
+	"github.com/jannin2/stock-app/backend/database"
+	"github.com/jannin2/stock-app/backend/models"
+	"github.com/jannin2/stock-app/backend/stream"
+)
+
+// tickerStockDB devuelve los stocks seguidos entre los tickers pedidos.
+type tickerStockDB struct {
+	database.StockDB
+	stocks map[string]models.Stock
+}
+
+func (db tickerStockDB) GetStocksByTickers(ctx context.Context, tickers []string) ([]models.Stock, error) {
+	stocks := []models.Stock{}
+	for _, ticker := range tickers {
+		if s, ok := db.stocks[ticker]; ok {
+			stocks = append(stocks, s)
+		}
+	}
+	return stocks, nil
+}
+
+func (db *watchlistUserDB) ListWatchlists(_ context.Context, userID uuid.UUID) ([]models.Watchlist, error) {
+	var own []models.Watchlist
+	for _, w := range db.watchlists {
+		if w.UserID == userID {
+			own = append(own, w)
+		}
+	}
+	return own, nil
+}
+
+func (db *watchlistUserDB) GetWatchlist(_ context.Context, userID, watchlistID uuid.UUID) (models.Watchlist, error) {
+	for _, w := range db.watchlists {
+		if w.ID == watchlistID && w.UserID == userID {
+			return w, nil
+		}
+	}
+	return models.Watchlist{}, database.ErrWatchlistNotFound
+}
+
+func (db *watchlistUserDB) DeleteWatchlist(_ context.Context, userID, watchlistID uuid.UUID) error {
+	for i, w := range db.watchlists {
+		if w.ID == watchlistID && w.UserID == userID {
+			db.watchlists = append(db.watchlists[:i], db.watchlists[i+1:]...)
+			return nil
+		}
+	}
+	return database.ErrWatchlistNotFound
+}
+
+func TestWatchlistHandlers(t *testing.T) {
+	userID, otherID := uuid.New(), uuid.New()
+	tech := models.Watchlist{ID: uuid.New(), UserID: userID, Name: "Tech", Tickers: []string{"MSFT", "NEWCO", "AAPL"}}
+	other := models.Watchlist{ID: uuid.New(), UserID: otherID, Name: "Ajena", Tickers: []string{"AAPL"}}
+	db := &watchlistUserDB{watchlists: []models.Watchlist{tech, other}}
+	h := NewWatchlistHandlers(db, tickerStockDB{stocks: map[string]models.Stock{
+		"AAPL": {Ticker: "AAPL", CurrentPrice: 190},
+		"MSFT": {Ticker: "MSFT", CurrentPrice: 410},
+	}}, stream.NewHub())
+	serve := func(handler http.HandlerFunc, method, id string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, "/watchlists", nil)
+		ctx := auth.WithUser(req.Context(), userID)
+		if id != "" {
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", id)
+			ctx = context.WithValue(ctx, chi.RouteCtxKey, rctx)
+		}
+		rr := httptest.NewRecorder()
+		handler(rr, req.WithContext(ctx))
+		return rr
+	}
+
+	rr := serve(h.ListWatchlists, http.MethodGet, "")
+	var listed []models.Watchlist
+	if err := json.Unmarshal(rr.Body.Bytes(), &listed); err != nil || len(listed) != 1 || listed[0].ID != tech.ID {
+		t.Fatalf("❌ GET /watchlists debería devolver solo las del usuario: estado %d: %s", rr.Code, rr.Body)
+	}
+
+	rr = serve(h.GetWatchlist, http.MethodGet, tech.ID.String())
+	var got models.WatchlistStocks
+	if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil || rr.Code != http.StatusOK {
+		t.Fatalf("❌ GET /watchlists/{id}: estado %d: %s", rr.Code, rr.Body)
+	}
+	if got.Name != "Tech" || len(got.Stocks) != 2 || strings.Join(got.Missing, ",") != "NEWCO" {
+		t.Errorf("❌ watchlist con stocks inesperada: %+v", got)
+	}
+
+	for _, id := range []string{other.ID.String(), uuid.NewString()} {
+		if rr := serve(h.GetWatchlist, http.MethodGet, id); rr.Code != http.StatusNotFound {
+			t.Errorf("❌ watchlist %s ajena o inexistente: estado %d, se esperaba 404", id, rr.Code)
+		}
+	}
+	if rr := serve(h.GetWatchlist, http.MethodGet, "tech"); rr.Code != http.StatusBadRequest {
+		t.Errorf("❌ ID inválido: estado %d, se esperaba 400", rr.Code)
+	}
+
+	if rr := serve(h.DeleteWatchlist, http.MethodDelete, other.ID.String()); rr.Code != http.StatusNotFound {
+		t.Errorf("❌ borrar una watchlist ajena: estado %d, se esperaba 404", rr.Code)
+	}
+	if rr := serve(h.DeleteWatchlist, http.MethodDelete, tech.ID.String()); rr.Code != http.StatusNoContent {
+		t.Errorf("❌ borrar la watchlist: estado %d, se esperaba 204", rr.Code)
+	}
+	if len(db.watchlists) != 1 || db.watchlists[0].ID != other.ID {
+		t.Errorf("❌ watchlists tras el borrado: %+v", db.watchlists)
+	}
+}
+
+// sharedWatchlistDB es una watchlist compartida: roles tiene el rol de cada usuario con
+// acceso, incluido el propietario.
+type sharedWatchlistDB struct {
+	database.UserDB
+	watchlist models.Watchlist
+	roles     map[uuid.UUID]string
+	emails    map[string]uuid.UUID
+}
+
+func (db *sharedWatchlistDB) GetWatchlist(_ context.Context, userID, watchlistID uuid.UUID) (models.Watchlist, error) {
+	role, ok := db.roles[userID]
+	if !ok || watchlistID != db.watchlist.ID {
+		return models.Watchlist{}, database.ErrWatchlistNotFound
+	}
+	w := db.watchlist
+	w.Role = role
+	return w, nil
+}
+
+func (db *sharedWatchlistDB) UpdateWatchlistTickers(_ context.Context, userID, watchlistID uuid.UUID, add, remove []string, maxTickers int) (models.Watchlist, error) {
+	w, err := db.GetWatchlist(context.Background(), userID, watchlistID)
+	if err != nil {
+		return w, err
+	}
+	if w.Role == models.WatchlistViewer {
+		return models.Watchlist{}, database.ErrWatchlistForbidden
+	}
+	if len(w.Tickers)+len(add) > maxTickers {
+		return models.Watchlist{}, database.ErrWatchlistFull
+	}
+	db.watchlist.Tickers = append(append([]string{}, w.Tickers...), add...)
+	return db.GetWatchlist(context.Background(), userID, watchlistID)
+}
+
+func (db *sharedWatchlistDB) GetCredentialsByEmail(_ context.Context, email string) (models.UserCredentials, error) {
+	id, ok := db.emails[email]
+	if !ok {
+		return models.UserCredentials{}, database.ErrUserNotFound
+	}
+	return models.UserCredentials{User: models.User{ID: id, Email: email}}, nil
+}
+
+func (db *sharedWatchlistDB) SetWatchlistMember(_ context.Context, ownerID, watchlistID, memberID uuid.UUID, role string) (models.WatchlistMember, error) {
+	if db.roles[ownerID] != models.WatchlistOwner {
+		return models.WatchlistMember{}, database.ErrWatchlistForbidden
+	}
+	db.roles[memberID] = role
+	return models.WatchlistMember{UserID: memberID, Role: role}, nil
+}
+
+func (db *sharedWatchlistDB) RemoveWatchlistMember(_ context.Context, userID, watchlistID, memberID uuid.UUID) error {
+	if db.roles[userID] != models.WatchlistOwner && userID != memberID {
+		return database.ErrWatchlistForbidden
+	}
+	delete(db.roles, memberID)
+	return nil
+}
+
+func TestWatchlistHandlers_Sharing(t *testing.T) {
+	ownerID, editorID, viewerID := uuid.New(), uuid.New(), uuid.New()
+	watchlist := models.Watchlist{ID: uuid.New(), UserID: ownerID, Name: "Equipo", Tickers: []string{"AAPL"}}
+	db := &sharedWatchlistDB{
+		watchlist: watchlist,
+		roles:     map[uuid.UUID]string{ownerID: models.WatchlistOwner},
+		emails:    map[string]uuid.UUID{"editor@example.com": editorID, "viewer@example.com": viewerID},
+	}
+	hub := stream.NewHub()
+	h := NewWatchlistHandlers(db, nil, hub)
+
+	serve := func(handler http.HandlerFunc, userID uuid.UUID, body string, params ...string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/watchlists", strings.NewReader(body))
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", watchlist.ID.String())
+		for i := 0; i+1 < len(params); i += 2 {
+			rctx.URLParams.Add(params[i], params[i+1])
+		}
+		ctx := context.WithValue(auth.WithUser(req.Context(), userID), chi.RouteCtxKey, rctx)
+		rr := httptest.NewRecorder()
+		handler(rr, req.WithContext(ctx))
+		return rr
+	}
+
+	// El propietario comparte la watchlist; un miembro no puede
+	if rr := serve(h.SetWatchlistMember, ownerID, `{"email": "Editor@example.com", "role": "editor"}`); rr.Code != http.StatusOK {
+		t.Fatalf("❌ compartir como editor: estado %d: %s", rr.Code, rr.Body)
+	}
+	serve(h.SetWatchlistMember, ownerID, `{"email": "viewer@example.com", "role": "viewer"}`)
+	if rr := serve(h.SetWatchlistMember, editorID, `{"email": "viewer@example.com", "role": "editor"}`); rr.Code != http.StatusForbidden {
+		t.Errorf("❌ un editor cambió un rol: estado %d, se esperaba 403", rr.Code)
+	}
+	// A quien no es el propietario no se le revela si el e-mail tiene cuenta
+	for _, email := range []string{"viewer@example.com", "nadie@example.com"} {
+		body := `{"email": "` + email + `", "role": "viewer"}`
+		if rr := serve(h.SetWatchlistMember, editorID, body); rr.Code != http.StatusForbidden {
+			t.Errorf("❌ un editor compartiendo con %s: estado %d, se esperaba 403", email, rr.Code)
+		}
+		if rr := serve(h.SetWatchlistMember, uuid.New(), body); rr.Code != http.StatusNotFound {
+			t.Errorf("❌ un desconocido compartiendo con %s: estado %d, se esperaba 404", email, rr.Code)
+		}
+	}
+	for body, want := range map[string]int{
+		`{"email": "viewer@example.com", "role": "owner"}`: http.StatusBadRequest,
+		`{"email": "nadie@example.com", "role": "viewer"}`: http.StatusNotFound,
+		`{"email": "no es un e-mail", "role": "viewer"}`:   http.StatusBadRequest,
+		`{"email": "editor@example.com", "role": "editor"`: http.StatusBadRequest,
+	} {
+		if rr := serve(h.SetWatchlistMember, ownerID, body); rr.Code != want {
+			t.Errorf("❌ %s: estado %d, se esperaba %d", body, rr.Code, want)
+		}
+	}
+
+	// Los miembros que siguen la watchlist reciben los cambios de tickers
+	sub := hub.Connect()
+	defer sub.Close()
+	sub.Follow(watchlist.ID.String(), viewerID.String(), 0)
+
+	rr := serve(h.UpdateWatchlist, editorID, `{"add": ["msft"]}`)
+	var updated models.Watchlist
+	if err := json.Unmarshal(rr.Body.Bytes(), &updated); err != nil || rr.Code != http.StatusOK || strings.Join(updated.Tickers, ",") != "AAPL,MSFT" {
+		t.Fatalf("❌ PATCH como editor: estado %d: %s", rr.Code, rr.Body)
+	}
+	select {
+	case u := <-sub.Updates():
+		event, ok := u.Data.(watchlistEvent)
+		if u.Type != stream.UpdateWatchlist || !ok || event.Action != watchlistTickersChanged || event.By != editorID || len(event.Tickers) != 2 {
+			t.Errorf("❌ actualización inesperada: %+v", u)
+		}
+	default:
+		t.Error("❌ el miembro no recibió el cambio de tickers")
+	}
+
+	for _, tt := range []struct {
+		user uuid.UUID
+		body string
+		want int
+	}{
+		{viewerID, `{"add": ["NVDA"]}`, http.StatusForbidden},
+		{uuid.New(), `{"add": ["NVDA"]}`, http.StatusNotFound},
+		{editorID, `{}`, http.StatusBadRequest},
+		{editorID, `{"remove": [""]}`, http.StatusBadRequest},
+	} {
+		if rr := serve(h.UpdateWatchlist, tt.user, tt.body); rr.Code != tt.want {
+			t.Errorf("❌ PATCH %s: estado %d, se esperaba %d", tt.body, rr.Code, tt.want)
+		}
+	}
+	many := make([]string, maxWatchlistTickers)
+	for i := range many {
+		many[i] = fmt.Sprintf("T%d", i)
+	}
+	body, _ := json.Marshal(watchlistTickersRequest{Add: many})
+	if rr := serve(h.UpdateWatchlist, editorID, string(body)); rr.Code != http.StatusUnprocessableEntity {
+		t.Errorf("❌ PATCH por encima del máximo: estado %d, se esperaba 422", rr.Code)
+	}
+
+	// Al quitar a un miembro recibe el aviso y deja de seguir la watchlist
+	if rr := serve(h.RemoveWatchlistMember, ownerID, "", "userID", viewerID.String()); rr.Code != http.StatusNoContent {
+		t.Fatalf("❌ quitar un miembro: estado %d: %s", rr.Code, rr.Body)
+	}
+	if u := <-sub.Updates(); u.Data.(watchlistEvent).Action != watchlistMemberRemoved {
+		t.Errorf("❌ se esperaba member_removed, se obtuvo %+v", u)
+	}
+	if got := sub.Watchlists(); len(got) != 0 {
+		t.Errorf("❌ el miembro quitado sigue la watchlist: %v", got)
+	}
+	if rr := serve(h.RemoveWatchlistMember, editorID, "", "userID", ownerID.String()); rr.Code != http.StatusForbidden {
+		t.Errorf("❌ un editor quitó al propietario: estado %d, se esperaba 403", rr.Code)
+	}
+	if rr := serve(h.RemoveWatchlistMember, editorID, "", "userID", "yo"); rr.Code != http.StatusBadRequest {
+		t.Errorf("❌ ID de usuario inválido: estado %d, se esperaba 400", rr.Code)
+	}
+}
@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/apierror"
+	"github.com/jannin2/stock-app/backend/clock"
+	"github.com/jannin2/stock-app/backend/database"
+	"github.com/jannin2/stock-app/backend/webhook"
+)
+
+// WebhookEventPing es el evento que envía POST /webhooks/{id}/test.
+const WebhookEventPing = "ping"
+
+// WebhookHandlers gestiona los webhooks de los usuarios y recibe los de los proveedores.
+type WebhookHandlers struct {
+	users   database.UserDB
+	sender  *webhook.Sender
+	karenai *webhook.Verifier // nil si no hay secreto configurado: se responde 404
+	refresh func()            // Pide una ejecución del enricher
+}
+
+// NewWebhookHandlers crea los manejadores de webhooks. refresh se llama cuando Karenai
+// avisa de que hay recomendaciones nuevas; karenaiSecrets son los secretos con los que
+// firma (el actual y, durante una rotación, el anterior).
+func NewWebhookHandlers(users database.UserDB, refresh func(), karenaiSecrets ...string) *WebhookHandlers {
+	h := &WebhookHandlers{users: users, sender: webhook.NewSender(clock.New()), refresh: refresh}
+	if len(karenaiSecrets) > 0 {
+		h.karenai = webhook.NewVerifier("karenai", users, clock.New(), karenaiSecrets...)
+	}
+	return h
+}
+
+// createWebhookRequest es el cuerpo de POST /webhooks.
+type createWebhookRequest struct {
+	URL string `json:"url"`
+}
+
+// webhookTestResult es la respuesta de POST /webhooks/{id}/test.
+type webhookTestResult struct {
+	webhook.Delivery
+	OK bool `json:"ok"`
+}
+
+// CreateWebhook maneja POST /webhooks. La respuesta incluye el secreto de firma, que no
+// vuelve a mostrarse: el receptor lo necesita para verificar X-Webhook-Signature.
+func (h *WebhookHandlers) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUser(w, r)
+	if !ok {
+		return
+	}
+	var req createWebhookRequest
+	if !decodeAuthRequest(w, r, &req) {
+		return
+	}
+	req.URL = strings.TrimSpace(req.URL)
+	if err := webhook.ValidateURL(req.URL); err != nil {
+		apierror.HTTPError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	secret, err := webhook.NewSecret()
+	if err != nil {
+		apierror.Internal(w, "Error al generar el secreto del webhook", err)
+		return
+	}
+	created, err := h.users.CreateWebhook(r.Context(), userID, req.URL, secret)
+	if err != nil {
+		writeError(w, err, "Error al crear el webhook")
+		return
+	}
+	writeJSON(w, r, http.StatusCreated, created)
+}
+
+// ListWebhooks maneja GET /webhooks.
+func (h *WebhookHandlers) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUser(w, r)
+	if !ok {
+		return
+	}
+	webhooks, err := h.users.ListWebhooks(r.Context(), userID)
+	if err != nil {
+		writeError(w, err, "Error al obtener los webhooks")
+		return
+	}
+	writeJSON(w, r, http.StatusOK, webhooks)
+}
+
+// DeleteWebhook maneja DELETE /webhooks/{id}.
+func (h *WebhookHandlers) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	userID, webhookID, ok := requireWebhookID(w, r)
+	if !ok {
+		return
+	}
+	err := h.users.DeleteWebhook(r.Context(), userID, webhookID)
+	if errors.Is(err, database.ErrWebhookNotFound) {
+		apierror.HTTPError(w, "Webhook no encontrado", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		writeError(w, err, "Error al borrar el webhook")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// TestWebhook maneja POST /webhooks/{id}/test: envía un evento "ping" firmado para que el
+// usuario compruebe que su receptor verifica la firma y responde 2xx. Responde 200 con el
+// resultado de la entrega aunque el receptor falle; ese resultado es lo que se prueba.
+func (h *WebhookHandlers) TestWebhook(w http.ResponseWriter, r *http.Request) {
+	userID, webhookID, ok := requireWebhookID(w, r)
+	if !ok {
+		return
+	}
+	hook, err := h.users.GetWebhook(r.Context(), userID, webhookID)
+	if errors.Is(err, database.ErrWebhookNotFound) {
+		apierror.HTTPError(w, "Webhook no encontrado", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		writeError(w, err, "Error al obtener el webhook")
+		return
+	}
+	delivery := h.sender.Send(r.Context(), hook.URL, hook.Secret, WebhookEventPing, map[string]string{
+		"webhook_id": hook.ID.String(),
+		"message":    "Entrega de prueba: si puedes verificar la firma, el webhook está bien configurado.",
+	})
+	writeJSON(w, r, http.StatusOK, webhookTestResult{Delivery: delivery, OK: delivery.OK()})
+}
+
+// KarenaiPush maneja POST /webhooks/incoming/karenai: Karenai avisa de que hay
+// recomendaciones nuevas y se adelanta la siguiente ejecución del enricher. Solo se
+// aceptan avisos firmados con el secreto compartido, dentro de la tolerancia del
+// timestamp y que no se hayan recibido antes.
+func (h *WebhookHandlers) KarenaiPush(w http.ResponseWriter, r *http.Request) {
+	if h.karenai == nil {
+		apierror.NotFound(w, r)
+		return
+	}
+	_, err := h.karenai.VerifyRequest(r)
+	switch {
+	case errors.Is(err, webhook.ErrBodyTooLarge):
+		apierror.HTTPError(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	case errors.Is(err, webhook.ErrReplayed):
+		apierror.HTTPError(w, err.Error(), http.StatusConflict)
+		return
+	case errors.Is(err, webhook.ErrMissingSignature), errors.Is(err, webhook.ErrInvalidSignature),
+		errors.Is(err, webhook.ErrTimestampOutOfRange):
+		log.Printf("Advertencia: webhook de Karenai rechazado desde %s: %v", r.RemoteAddr, err)
+		apierror.HTTPError(w, err.Error(), http.StatusUnauthorized)
+		return
+	case err != nil:
+		apierror.Internal(w, "Error al verificar el webhook", err)
+		return
+	}
+	h.refresh()
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// requireWebhookID devuelve el usuario autenticado y el {id} de la ruta, o responde con
+// el error correspondiente.
+func requireWebhookID(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	userID, ok := requireUser(w, r)
+	if !ok {
+		return uuid.Nil, uuid.Nil, false
+	}
+	webhookID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		apierror.HTTPError(w, "ID de webhook inválido", http.StatusBadRequest)
+		return uuid.Nil, uuid.Nil, false
+	}
+	return userID, webhookID, true
+}
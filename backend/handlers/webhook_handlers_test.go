@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/auth"
+	"github.com/jannin2/stock-app/backend/clock"
+	"github.com/jannin2/stock-app/backend/database"
+	"github.com/jannin2/stock-app/backend/models"
+	"github.com/jannin2/stock-app/backend/webhook"
+)
+
+// webhookUserDB guarda en memoria los webhooks y los nonces.
+type webhookUserDB struct {
+	database.UserDB
+	webhooks map[uuid.UUID]models.Webhook
+	nonces   map[string]bool
+}
+
+func (db *webhookUserDB) GetWebhook(_ context.Context, userID, webhookID uuid.UUID) (models.Webhook, error) {
+	if w, ok := db.webhooks[webhookID]; ok && w.UserID == userID {
+		return w, nil
+	}
+	return models.Webhook{}, database.ErrWebhookNotFound
+}
+
+func (db *webhookUserDB) RememberWebhookNonce(_ context.Context, nonce string, expiresAt time.Time) (bool, error) {
+	if db.nonces[nonce] {
+		return false, nil
+	}
+	db.nonces[nonce] = true
+	return true, nil
+}
+
+func TestKarenaiPush(t *testing.T) {
+	db := &webhookUserDB{nonces: map[string]bool{}}
+	refreshes := 0
+	h := NewWebhookHandlers(db, func() { refreshes++ }, "whsec_karenai")
+	push := func(secret, nonce string) int {
+		t.Helper()
+		body := []byte(`{"event":"recommendations.updated"}`)
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/incoming/karenai", strings.NewReader(string(body)))
+		req.Header.Set(webhook.SignatureHeader, webhook.Sign(secret, time.Now(), nonce, body))
+		req.Header.Set(webhook.DeliveryHeader, nonce)
+		rr := httptest.NewRecorder()
+		h.KarenaiPush(rr, req)
+		return rr.Code
+	}
+
+	if code := push("whsec_karenai", "n-1"); code != http.StatusAccepted || refreshes != 1 {
+		t.Fatalf("❌ estado %d y %d ejecuciones pedidas; se esperaba 202 y 1", code, refreshes)
+	}
+	if code := push("whsec_karenai", "n-1"); code != http.StatusConflict {
+		t.Errorf("❌ una entrega repetida debería responder 409, respondió %d", code)
+	}
+	if code := push("whsec_falso", "n-2"); code != http.StatusUnauthorized {
+		t.Errorf("❌ una firma inválida debería responder 401, respondió %d", code)
+	}
+	if refreshes != 1 {
+		t.Errorf("❌ solo el aviso válido debería pedir una ejecución, hubo %d", refreshes)
+	}
+
+	// Sin secreto configurado el endpoint no existe
+	rr := httptest.NewRecorder()
+	NewWebhookHandlers(db, func() {}).KarenaiPush(rr, httptest.NewRequest(http.MethodPost, "/webhooks/incoming/karenai", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("❌ sin secreto se esperaba 404, se obtuvo %d", rr.Code)
+	}
+}
+
+func TestTestWebhook(t *testing.T) {
+	var signature string
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signature = r.Header.Get(webhook.SignatureHeader)
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	defer srv.Close()
+
+	userID, webhookID := uuid.New(), uuid.New()
+	db := &webhookUserDB{webhooks: map[uuid.UUID]models.Webhook{
+		webhookID: {ID: webhookID, UserID: userID, URL: srv.URL, Secret: "whsec_abc"},
+	}}
+	h := NewWebhookHandlers(db, func() {})
+	h.sender = webhook.NewSenderWithClient(srv.Client(), clock.New())
+	serve := func(id uuid.UUID) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/"+id.String()+"/test", nil)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", id.String())
+		req = req.WithContext(context.WithValue(auth.WithUser(req.Context(), userID), chi.RouteCtxKey, rctx))
+		rr := httptest.NewRecorder()
+		h.TestWebhook(rr, req)
+		return rr
+	}
+
+	rr := serve(webhookID)
+	var result struct {
+		OK         bool `json:"ok"`
+		StatusCode int  `json:"status_code"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &result)
+	if rr.Code != http.StatusOK || result.OK || result.StatusCode != http.StatusTeapot || signature == "" {
+		t.Errorf("❌ resultado inesperado (%d): %s; firma %q", rr.Code, rr.Body, signature)
+	}
+	if rr := serve(uuid.New()); rr.Code != http.StatusNotFound {
+		t.Errorf("❌ un webhook ajeno o inexistente debería responder 404, respondió %d", rr.Code)
+	}
+}
@@ -0,0 +1,285 @@
+// Package jobs ejecuta en segundo plano los trabajos que no caben en una petición HTTP
+// (exportaciones de cuenta, importaciones, ...). Las peticiones encolan el trabajo,
+// responden 202 con su ID y el cliente consulta el progreso y descarga el resultado después.
+//
+// La cola vive en memoria: los trabajos pendientes se pierden si el proceso se reinicia,
+// y el cliente debe volver a solicitarlos.
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/clock"
+)
+
+// ResultTTL es cuánto se conservan un trabajo terminado y su archivo de resultado.
+const ResultTTL = 24 * time.Hour
+
+// Status es el estado de un trabajo.
+type Status string
+
+const (
+	StatusQueued    Status = "queued"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+)
+
+var (
+	// ErrQueueFull indica que no caben más trabajos pendientes; el cliente debe reintentar.
+	ErrQueueFull = errors.New("la cola de trabajos está llena")
+	// ErrNotFound indica que el trabajo no existe o ya caducó.
+	ErrNotFound = errors.New("trabajo no encontrado")
+)
+
+// Job es el estado de un trabajo tal y como se informa al cliente.
+type Job struct {
+	ID         uuid.UUID   `json:"id"`
+	Kind       string      `json:"kind"`
+	Owner      uuid.UUID   `json:"-"` // Usuario que lo solicitó (uuid.Nil si fue un administrador)
+	Status     Status      `json:"status"`
+	Progress   float64     `json:"progress"` // Fracción completada, de 0 a 1
+	Error      string      `json:"error,omitempty"`
+	Result     interface{} `json:"result,omitempty"` // Resumen del resultado (ej. el informe de una importación)
+	CreatedAt  time.Time   `json:"created_at"`
+	StartedAt  *time.Time  `json:"started_at,omitempty"`
+	FinishedAt *time.Time  `json:"finished_at,omitempty"`
+	ResultPath string      `json:"-"` // Archivo con el resultado, si el trabajo produce uno
+}
+
+// Func ejecuta un trabajo. ctx se cancela al apagar el servidor. El resultado se escribe
+// con Handle.CreateResult y su resumen con Handle.SetResult.
+type Func func(ctx context.Context, h *Handle) error
+
+// Handle permite a un trabajo en ejecución informar de su progreso y crear su resultado.
+type Handle struct {
+	q  *Queue
+	id uuid.UUID
+}
+
+// SetProgress actualiza la fracción completada (de 0 a 1).
+func (h *Handle) SetProgress(progress float64) {
+	h.q.update(h.id, func(j *Job) { j.Progress = min(max(progress, 0), 1) })
+}
+
+// SetResult guarda el resumen del resultado que se devuelve con el estado del trabajo. Se
+// puede llamar aunque el trabajo termine con error, p. ej. con lo procesado hasta el fallo.
+func (h *Handle) SetResult(result interface{}) {
+	h.q.update(h.id, func(j *Job) { j.Result = result })
+}
+
+// CreateResult crea el archivo de resultado del trabajo. name solo se usa como sufijo
+// del nombre del archivo (ej. "export.zip").
+func (h *Handle) CreateResult(name string) (*os.File, error) {
+	dir, err := h.q.resultDir()
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, h.id.String()+"-"+filepath.Base(name))
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("error al crear el resultado del trabajo: %w", err)
+	}
+	h.q.update(h.id, func(j *Job) { j.ResultPath = path })
+	return f, nil
+}
+
+type task struct {
+	id uuid.UUID
+	fn Func
+}
+
+// Queue es una cola de trabajos con un número fijo de workers.
+type Queue struct {
+	clock   clock.Clock
+	workers int
+	pending chan task
+
+	mu   sync.Mutex
+	jobs map[uuid.UUID]*Job
+	dir  string // Directorio de resultados, creado al necesitarlo
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewQueue crea una cola con workers trabajos simultáneos y hasta capacity pendientes.
+func NewQueue(c clock.Clock, workers, capacity int) *Queue {
+	return &Queue{
+		clock:   c,
+		workers: workers,
+		pending: make(chan task, capacity),
+		jobs:    map[uuid.UUID]*Job{},
+	}
+}
+
+// Start arranca los workers. Los trabajos encolados antes de Start esperan a que arranque.
+func (q *Queue) Start(ctx context.Context) error {
+	ctx, q.cancel = context.WithCancel(ctx)
+	for i := 0; i < q.workers; i++ {
+		q.wg.Add(1)
+		go q.work(ctx)
+	}
+	return nil
+}
+
+// Stop cancela los trabajos en curso y espera a que los workers terminen, o a que venza ctx.
+func (q *Queue) Stop(ctx context.Context) error {
+	if q.cancel != nil {
+		q.cancel()
+	}
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Submit encola un trabajo de tipo kind solicitado por owner y devuelve su estado inicial.
+func (q *Queue) Submit(kind string, owner uuid.UUID, fn Func) (Job, error) {
+	q.prune()
+
+	// El llamador recibe una copia: el worker modifica la registrada en q.jobs.
+	job := Job{ID: uuid.New(), Kind: kind, Owner: owner, Status: StatusQueued, CreatedAt: q.clock.Now().UTC()}
+	registered := job
+	q.mu.Lock()
+	q.jobs[job.ID] = &registered
+	q.mu.Unlock()
+
+	select {
+	case q.pending <- task{id: job.ID, fn: fn}:
+		return job, nil
+	default:
+		q.mu.Lock()
+		delete(q.jobs, job.ID)
+		q.mu.Unlock()
+		return Job{}, ErrQueueFull
+	}
+}
+
+// Get devuelve el estado de un trabajo.
+func (q *Queue) Get(id uuid.UUID) (Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job, ok := q.jobs[id]
+	if !ok {
+		return Job{}, ErrNotFound
+	}
+	return *job, nil
+}
+
+// Active devuelve el trabajo de tipo kind de owner que aún no ha terminado, si lo hay.
+// Sirve para no encolar dos veces el mismo trabajo cuando el cliente repite la petición.
+func (q *Queue) Active(kind string, owner uuid.UUID) (Job, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, job := range q.jobs {
+		if job.Kind == kind && job.Owner == owner && (job.Status == StatusQueued || job.Status == StatusRunning) {
+			return *job, true
+		}
+	}
+	return Job{}, false
+}
+
+// Depth devuelve cuántos trabajos hay pendientes o en ejecución.
+func (q *Queue) Depth() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	depth := 0
+	for _, job := range q.jobs {
+		if job.Status == StatusQueued || job.Status == StatusRunning {
+			depth++
+		}
+	}
+	return depth
+}
+
+// Capacity devuelve cuántos trabajos pendientes admite la cola.
+func (q *Queue) Capacity() int {
+	return cap(q.pending)
+}
+
+func (q *Queue) work(ctx context.Context) {
+	defer q.wg.Done()
+	for {
+		select {
+		case t := <-q.pending:
+			q.run(ctx, t)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (q *Queue) run(ctx context.Context, t task) {
+	started := q.clock.Now().UTC()
+	q.update(t.id, func(j *Job) {
+		j.Status = StatusRunning
+		j.StartedAt = &started
+	})
+
+	err := t.fn(ctx, &Handle{q: q, id: t.id})
+
+	finished := q.clock.Now().UTC()
+	q.update(t.id, func(j *Job) {
+		j.FinishedAt = &finished
+		if err != nil {
+			j.Status = StatusFailed
+			j.Error = err.Error()
+			log.Printf("ERROR: el trabajo %s (%s) falló: %v", j.ID, j.Kind, err)
+			return
+		}
+		j.Status = StatusSucceeded
+		j.Progress = 1
+	})
+}
+
+func (q *Queue) update(id uuid.UUID, fn func(*Job)) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if job, ok := q.jobs[id]; ok {
+		fn(job)
+	}
+}
+
+func (q *Queue) resultDir() (string, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.dir == "" {
+		dir, err := os.MkdirTemp("", "stock-app-jobs-")
+		if err != nil {
+			return "", fmt.Errorf("error al crear el directorio de resultados: %w", err)
+		}
+		q.dir = dir
+	}
+	return q.dir, nil
+}
+
+// prune olvida los trabajos terminados hace más de ResultTTL y borra sus resultados.
+func (q *Queue) prune() {
+	cutoff := q.clock.Now().Add(-ResultTTL)
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for id, job := range q.jobs {
+		if job.FinishedAt != nil && job.FinishedAt.Before(cutoff) {
+			if job.ResultPath != "" {
+				os.Remove(job.ResultPath)
+			}
+			delete(q.jobs, id)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/clock"
+)
+
+// waitFor espera a que el trabajo termine.
+func waitFor(t *testing.T, q *Queue, id uuid.UUID) Job {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := q.Get(id)
+		if err != nil {
+			t.Fatalf("❌ error inesperado: %v", err)
+		}
+		if job.Status == StatusSucceeded || job.Status == StatusFailed {
+			return job
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("❌ el trabajo %s no terminó a tiempo", id)
+	return Job{}
+}
+
+func TestQueue_RunsJobsAndKeepsResults(t *testing.T) {
+	mock := clock.NewMock()
+	q := NewQueue(mock, 1, 4)
+	q.Start(context.Background())
+	t.Cleanup(func() { q.Stop(context.Background()) })
+
+	owner := uuid.New()
+	release := make(chan struct{})
+	job, err := q.Submit("export", owner, func(ctx context.Context, h *Handle) error {
+		<-release
+		h.SetProgress(0.5)
+		f, err := h.CreateResult("export.txt")
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = f.WriteString("hola")
+		return err
+	})
+	if err != nil || job.Status != StatusQueued {
+		t.Fatalf("❌ Submit = %+v, %v", job, err)
+	}
+	if active, ok := q.Active("export", owner); !ok || active.ID != job.ID || q.Depth() != 1 {
+		t.Errorf("❌ el trabajo debería estar activo (profundidad %d)", q.Depth())
+	}
+
+	close(release)
+	done := waitFor(t, q, job.ID)
+	if done.Status != StatusSucceeded || done.Progress != 1 || done.FinishedAt == nil {
+		t.Fatalf("❌ estado final inesperado: %+v", done)
+	}
+	if body, err := os.ReadFile(done.ResultPath); err != nil || string(body) != "hola" {
+		t.Errorf("❌ resultado inesperado: %q (%v)", body, err)
+	}
+	if _, ok := q.Active("export", owner); ok || q.Depth() != 0 {
+		t.Errorf("❌ un trabajo terminado no debería contar como activo")
+	}
+
+	// Un trabajo fallido conserva el resumen publicado antes del fallo
+	failed, _ := q.Submit("export", owner, func(ctx context.Context, h *Handle) error {
+		h.SetResult(map[string]int{"rows": 3})
+		return errors.New("sin datos")
+	})
+	if got := waitFor(t, q, failed.ID); got.Status != StatusFailed || got.Error != "sin datos" || got.Result.(map[string]int)["rows"] != 3 {
+		t.Errorf("❌ se esperaba un trabajo fallido con su resumen: %+v", got)
+	}
+
+	// Pasado ResultTTL, el siguiente Submit olvida los trabajos terminados y borra sus archivos
+	mock.Add(ResultTTL + time.Minute)
+	q.Submit("export", owner, func(ctx context.Context, h *Handle) error { return nil })
+	if _, err := q.Get(job.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("❌ el trabajo caducado debería haberse olvidado, se obtuvo %v", err)
+	}
+	if _, err := os.Stat(done.ResultPath); !os.IsNotExist(err) {
+		t.Errorf("❌ el resultado caducado debería haberse borrado: %v", err)
+	}
+}
+
+func TestQueue_Full(t *testing.T) {
+	q := NewQueue(clock.NewMock(), 1, 1) // Sin Start: nada sale de la cola
+	noop := func(ctx context.Context, h *Handle) error { return nil }
+
+	if _, err := q.Submit("import", uuid.New(), noop); err != nil {
+		t.Fatalf("❌ error inesperado: %v", err)
+	}
+	if _, err := q.Submit("import", uuid.New(), noop); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("❌ se esperaba ErrQueueFull, se obtuvo %v", err)
+	}
+	if q.Depth() != 1 {
+		t.Errorf("❌ profundidad %d, se esperaba 1", q.Depth())
+	}
+}
@@ -0,0 +1,146 @@
+// Package lifecycle coordina el arranque y el apagado ordenado de los subsistemas del
+// servidor (servidor HTTP, enricher, base de datos, ...).
+//
+// Los hooks se arrancan en el orden en que se registran y se detienen en orden inverso,
+// de modo que un subsistema se detiene antes que aquellos de los que depende.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// DefaultStopTimeout es el tiempo máximo que se espera a un hook de parada sin Timeout propio.
+const DefaultStopTimeout = 10 * time.Second
+
+// Hook describe un subsistema con funciones opcionales de arranque y parada.
+type Hook struct {
+	Name string
+
+	// Start arranca el subsistema. Debe volver en cuanto esté en marcha (el trabajo de
+	// larga duración va en goroutines). ctx se cancela cuando empieza el apagado.
+	Start func(ctx context.Context) error
+
+	// Stop detiene el subsistema. ctx vence tras Timeout.
+	Stop func(ctx context.Context) error
+
+	// Timeout limita la duración de Stop (DefaultStopTimeout si es cero).
+	Timeout time.Duration
+}
+
+// Manager registra hooks y gestiona su arranque y apagado.
+type Manager struct {
+	mu      sync.Mutex
+	hooks   []Hook
+	started int // Cantidad de hooks arrancados, en orden de registro
+	cancel  context.CancelFunc
+}
+
+// New crea un Manager sin hooks.
+func New() *Manager {
+	return &Manager{}
+}
+
+// Register añade un hook. Debe llamarse antes de Start.
+func (m *Manager) Register(h Hook) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.hooks = append(m.hooks, h)
+}
+
+// Start arranca los hooks en orden de registro. Si uno falla, detiene los ya arrancados
+// en orden inverso y devuelve el error.
+func (m *Manager) Start(ctx context.Context) error {
+	m.mu.Lock()
+	runCtx, cancel := context.WithCancel(ctx)
+	m.cancel = cancel
+	hooks := m.hooks
+	m.mu.Unlock()
+
+	for i, h := range hooks {
+		if h.Start != nil {
+			if err := h.Start(runCtx); err != nil {
+				startErr := fmt.Errorf("error al arrancar %s: %w", h.Name, err)
+				if stopErr := m.Stop(context.Background()); stopErr != nil {
+					return errors.Join(startErr, stopErr)
+				}
+				return startErr
+			}
+			log.Printf("▶️ %s arrancado", h.Name)
+		}
+		m.mu.Lock()
+		m.started = i + 1
+		m.mu.Unlock()
+	}
+	return nil
+}
+
+// Stop detiene los hooks arrancados en orden inverso, cada uno limitado por su Timeout.
+// Un hook que falla o excede su tiempo no impide detener los siguientes; los errores se
+// devuelven combinados.
+func (m *Manager) Stop(ctx context.Context) error {
+	m.mu.Lock()
+	if m.cancel != nil {
+		m.cancel()
+	}
+	hooks := m.hooks[:m.started]
+	m.started = 0
+	m.mu.Unlock()
+
+	var errs []error
+	for i := len(hooks) - 1; i >= 0; i-- {
+		h := hooks[i]
+		if h.Stop == nil {
+			continue
+		}
+		if err := stopHook(ctx, h); err != nil {
+			log.Printf("❌ Error al detener %s: %v", h.Name, err)
+			errs = append(errs, fmt.Errorf("error al detener %s: %w", h.Name, err))
+			continue
+		}
+		log.Printf("⏹️ %s detenido", h.Name)
+	}
+	return errors.Join(errs...)
+}
+
+// stopHook ejecuta h.Stop y deja de esperarlo si excede su timeout.
+func stopHook(ctx context.Context, h Hook) error {
+	timeout := h.Timeout
+	if timeout <= 0 {
+		timeout = DefaultStopTimeout
+	}
+	stopCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- h.Stop(stopCtx) }()
+
+	select {
+	case err := <-done:
+		return err
+	case <-stopCtx.Done():
+		return fmt.Errorf("tiempo de parada excedido (%s): %w", timeout, stopCtx.Err())
+	}
+}
+
+// Run arranca los hooks, espera a SIGINT/SIGTERM o a que se cancele ctx, y después
+// los detiene en orden inverso.
+func (m *Manager) Run(ctx context.Context) error {
+	if err := m.Start(ctx); err != nil {
+		return err
+	}
+
+	signalCtx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-signalCtx.Done()
+
+	log.Println("Apagando el servidor...")
+	return m.Stop(context.Background())
+}
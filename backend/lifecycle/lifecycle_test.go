@@ -0,0 +1,96 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// recorder registra el orden en que se llaman los hooks.
+type recorder struct {
+	calls []string
+}
+
+func (r *recorder) hook(name string) Hook {
+	return Hook{
+		Name: name,
+		Start: func(ctx context.Context) error {
+			r.calls = append(r.calls, "start "+name)
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			r.calls = append(r.calls, "stop "+name)
+			return nil
+		},
+	}
+}
+
+func TestManager_StartsInOrderAndStopsInReverse(t *testing.T) {
+	rec := &recorder{}
+	m := New()
+	m.Register(rec.hook("db"))
+	m.Register(rec.hook("enricher"))
+	m.Register(rec.hook("http"))
+
+	if err := m.Start(context.Background()); err != nil {
+		t.Fatalf("❌ error inesperado al arrancar: %v", err)
+	}
+	if err := m.Stop(context.Background()); err != nil {
+		t.Fatalf("❌ error inesperado al detener: %v", err)
+	}
+
+	want := []string{"start db", "start enricher", "start http", "stop http", "stop enricher", "stop db"}
+	if !reflect.DeepEqual(rec.calls, want) {
+		t.Errorf("❌ orden %v, se esperaba %v", rec.calls, want)
+	}
+}
+
+func TestManager_StartFailureStopsStartedHooks(t *testing.T) {
+	rec := &recorder{}
+	m := New()
+	m.Register(rec.hook("db"))
+	m.Register(Hook{Name: "http", Start: func(ctx context.Context) error { return errors.New("puerto en uso") }})
+	m.Register(rec.hook("nunca"))
+
+	err := m.Start(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "http") {
+		t.Fatalf("❌ se esperaba un error de arranque de http, se obtuvo %v", err)
+	}
+
+	want := []string{"start db", "stop db"}
+	if !reflect.DeepEqual(rec.calls, want) {
+		t.Errorf("❌ orden %v, se esperaba %v", rec.calls, want)
+	}
+}
+
+func TestManager_StopTimeoutDoesNotBlockOtherHooks(t *testing.T) {
+	rec := &recorder{}
+	m := New()
+	m.Register(rec.hook("db"))
+	m.Register(Hook{
+		Name:    "lento",
+		Stop:    func(ctx context.Context) error { time.Sleep(time.Second); return nil },
+		Timeout: 10 * time.Millisecond,
+	})
+
+	var startCtx context.Context
+	m.Register(Hook{Name: "ctx", Start: func(ctx context.Context) error { startCtx = ctx; return nil }})
+
+	if err := m.Start(context.Background()); err != nil {
+		t.Fatalf("❌ error inesperado al arrancar: %v", err)
+	}
+
+	err := m.Stop(context.Background())
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("❌ se esperaba un timeout de parada, se obtuvo %v", err)
+	}
+	if startCtx.Err() == nil {
+		t.Error("❌ el contexto de arranque debería cancelarse al detener")
+	}
+	if got := rec.calls[len(rec.calls)-1]; got != "stop db" {
+		t.Errorf("❌ db debería detenerse aunque otro hook exceda su tiempo, última llamada: %s", got)
+	}
+}
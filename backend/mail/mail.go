@@ -0,0 +1,166 @@
+// Package mail renderiza y envía los e-mails transaccionales de la app (verificación de
+// e-mail, reseteo de contraseña, alertas). Las plantillas se renderizan en el servidor con
+// html/template, así que los datos del usuario se escapan siempre.
+package mail
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html/template"
+	"log"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+// Plantillas disponibles (archivos de templates/ sin la extensión).
+const (
+	TemplateVerifyEmail   = "verify_email"
+	TemplateResetPassword = "reset_password"
+	TemplateAlert         = "alert"
+	TemplateAlertDigest   = "alert_digest"
+)
+
+// metricLabels son los nombres de las métricas de alerta en los e-mails.
+var metricLabels = map[string]string{
+	models.AlertMetricPrice:               "precio",
+	models.AlertMetricChangePct:           "variación diaria (%)",
+	models.AlertMetricPERatio:             "PER",
+	models.AlertMetricDividendYield:       "rentabilidad por dividendo",
+	models.AlertMetricRecommendationScore: "puntuación de recomendación",
+}
+
+// MetricLabel devuelve el nombre legible de una métrica de alerta.
+func MetricLabel(metric string) string {
+	if label, ok := metricLabels[metric]; ok {
+		return label
+	}
+	return metric
+}
+
+// OperatorLabel devuelve el texto de un operador de alerta ("por encima de", ...).
+func OperatorLabel(operator string) string {
+	if operator == models.AlertBelow {
+		return "por debajo de"
+	}
+	return "por encima de"
+}
+
+var templates = template.Must(template.New("").Funcs(template.FuncMap{
+	"metric":   MetricLabel,
+	"operator": OperatorLabel,
+}).ParseFS(templateFS, "templates/*.html"))
+
+// LinkData son los datos de las plantillas que envían un enlace con token.
+type LinkData struct {
+	Email     string
+	Link      string
+	ExpiresAt time.Time
+}
+
+// AlertData son los datos de las plantillas de alertas: una alerta suelta o un resumen.
+type AlertData struct {
+	Events []models.AlertEvent
+	Link   string // Página de alertas del frontend
+}
+
+// Message es un e-mail listo para enviar.
+type Message struct {
+	To      string
+	Subject string
+	HTML    string
+}
+
+// Mailer envía e-mails.
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// Render construye el mensaje de la plantilla name para to. Cada plantilla define los
+// bloques "<name>_subject" y "<name>_body".
+func Render(name, to string, data interface{}) (Message, error) {
+	var subject, body bytes.Buffer
+	if err := templates.ExecuteTemplate(&subject, name+"_subject", data); err != nil {
+		return Message{}, fmt.Errorf("error al renderizar el asunto de %s: %w", name, err)
+	}
+	if err := templates.ExecuteTemplate(&body, name+"_body", data); err != nil {
+		return Message{}, fmt.Errorf("error al renderizar el e-mail %s: %w", name, err)
+	}
+	return Message{To: to, Subject: strings.TrimSpace(subject.String()), HTML: body.String()}, nil
+}
+
+// BaseURL es la URL pública del frontend con la que se construyen los enlaces de los
+// e-mails (APP_BASE_URL, por defecto el servidor de desarrollo de Vite).
+func BaseURL() string {
+	if base := os.Getenv("APP_BASE_URL"); base != "" {
+		return strings.TrimRight(base, "/")
+	}
+	return "http://localhost:5173"
+}
+
+// FromEnv devuelve un SMTPMailer si SMTP_ADDR está configurada y, si no, un LogMailer.
+func FromEnv() Mailer {
+	addr := os.Getenv("SMTP_ADDR")
+	if addr == "" {
+		log.Println("Advertencia: SMTP_ADDR no está configurada; los e-mails se escribirán en el log en lugar de enviarse.")
+		return LogMailer{}
+	}
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "no-reply@stock-app.local"
+	}
+	return &SMTPMailer{Addr: addr, From: from, Username: os.Getenv("SMTP_USERNAME"), Password: os.Getenv("SMTP_PASSWORD")}
+}
+
+// LogMailer escribe los e-mails en el log. Solo para desarrollo: los enlaces con tokens
+// quedan en el log.
+type LogMailer struct{}
+
+func (LogMailer) Send(msg Message) error {
+	log.Printf("E-mail (no enviado, sin SMTP) para %s - %s:\n%s", msg.To, msg.Subject, msg.HTML)
+	return nil
+}
+
+// SMTPMailer envía los e-mails por SMTP, con autenticación PLAIN si hay credenciales.
+type SMTPMailer struct {
+	Addr     string // host:puerto
+	From     string
+	Username string
+	Password string
+}
+
+func (m *SMTPMailer) Send(msg Message) error {
+	var auth smtp.Auth
+	if m.Username != "" {
+		host, _, err := net.SplitHostPort(m.Addr)
+		if err != nil {
+			return fmt.Errorf("SMTP_ADDR inválida %q: %w", m.Addr, err)
+		}
+		auth = smtp.PlainAuth("", m.Username, m.Password, host)
+	}
+	if err := smtp.SendMail(m.Addr, auth, m.From, []string{msg.To}, buildMIME(m.From, msg)); err != nil {
+		return fmt.Errorf("error al enviar el e-mail a %s: %w", msg.To, err)
+	}
+	return nil
+}
+
+func buildMIME(from string, msg Message) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/html; charset=UTF-8\r\n\r\n")
+	b.WriteString(msg.HTML)
+	return b.Bytes()
+}
@@ -0,0 +1,58 @@
+package mail
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+func TestRender_EscapesUserData(t *testing.T) {
+	data := LinkData{
+		Email:     `<script>alert(1)</script>@example.com`,
+		Link:      "https://stocks.example.com/reset-password?token=abc.def",
+		ExpiresAt: time.Date(2025, 1, 6, 13, 0, 0, 0, time.UTC),
+	}
+	msg, err := Render(TemplateResetPassword, "ana@example.com", data)
+	if err != nil {
+		t.Fatalf("❌ error inesperado al renderizar: %v", err)
+	}
+	if msg.To != "ana@example.com" || msg.Subject != "Restablece tu contraseña de Stock App" {
+		t.Errorf("❌ cabeceras inesperadas: %+v", msg)
+	}
+	if strings.Contains(msg.HTML, "<script>") || !strings.Contains(msg.HTML, "&lt;script&gt;") {
+		t.Errorf("❌ el e-mail del usuario debería escaparse: %s", msg.HTML)
+	}
+	if !strings.Contains(msg.HTML, `href="https://stocks.example.com/reset-password?token=abc.def"`) || !strings.Contains(msg.HTML, "06/01/2025 13:00 UTC") {
+		t.Errorf("❌ falta el enlace o la caducidad: %s", msg.HTML)
+	}
+}
+
+func TestRender_AlertDigest(t *testing.T) {
+	at := time.Date(2025, 1, 6, 15, 30, 0, 0, time.UTC)
+	data := AlertData{
+		Link: "https://stocks.example.com/alerts",
+		Events: []models.AlertEvent{
+			{Ticker: "AAPL", Metric: models.AlertMetricPrice, Operator: models.AlertAbove, Threshold: 200, Value: 201.5, TriggeredAt: at},
+			{Ticker: "MSFT", Metric: models.AlertMetricChangePct, Operator: models.AlertBelow, Threshold: -5, Value: -6.25, TriggeredAt: at},
+		},
+	}
+	msg, err := Render(TemplateAlertDigest, "ana@example.com", data)
+	if err != nil {
+		t.Fatalf("❌ error inesperado al renderizar: %v", err)
+	}
+	if msg.Subject != "Resumen: 2 alertas cumplidas" {
+		t.Errorf("❌ asunto inesperado: %q", msg.Subject)
+	}
+	for _, want := range []string{"precio por encima de 200.00", "variación diaria (%) por debajo de -5.00", "-6.25", "06/01 15:30 UTC"} {
+		if !strings.Contains(msg.HTML, want) {
+			t.Errorf("❌ falta %q en el resumen: %s", want, msg.HTML)
+		}
+	}
+
+	msg, err = Render(TemplateAlert, "ana@example.com", AlertData{Events: data.Events[:1], Link: data.Link})
+	if err != nil || msg.Subject != "Alerta: AAPL por encima de 200.00" {
+		t.Errorf("❌ alerta suelta inesperada: %q (%v)", msg.Subject, err)
+	}
+}
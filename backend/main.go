@@ -1,19 +1,50 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"log"
+	"net"
 	"net/http"
 	"os"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/chi/v5/middleware"
-	"github.com/go-chi/cors"   // Import the cors package
 	"github.com/joho/godotenv" // Import godotenv
 
+	"github.com/jannin2/stock-app/backend/alerts"
 	"github.com/jannin2/stock-app/backend/api"
+	"github.com/jannin2/stock-app/backend/auth"
+	"github.com/jannin2/stock-app/backend/clock"
+	"github.com/jannin2/stock-app/backend/config"
 	enricher "github.com/jannin2/stock-app/backend/cron"
 	"github.com/jannin2/stock-app/backend/database"
 	"github.com/jannin2/stock-app/backend/handlers"
+	"github.com/jannin2/stock-app/backend/jobs"
+	"github.com/jannin2/stock-app/backend/lifecycle"
+	"github.com/jannin2/stock-app/backend/mail"
+	"github.com/jannin2/stock-app/backend/notify"
+	"github.com/jannin2/stock-app/backend/providers"
+	"github.com/jannin2/stock-app/backend/quotes"
+	"github.com/jannin2/stock-app/backend/retention"
+	"github.com/jannin2/stock-app/backend/stream"
+	"github.com/jannin2/stock-app/backend/webhook"
+)
+
+// dbHealthCheckInterval es la frecuencia con la que se comprueba la conexión a la base de datos.
+const dbHealthCheckInterval = 10 * time.Second
+
+// dbPoolCheckInterval es la frecuencia con la que se mide la espera por conexiones del pool.
+const dbPoolCheckInterval = 2 * time.Second
+
+// Tamaño de la cola de trabajos en segundo plano (exportaciones de cuenta, importaciones
+// de stocks, ...).
+const (
+	jobWorkers       = 2
+	jobQueueCapacity = 100
 )
 
 func main() {
@@ -24,52 +55,253 @@ func main() {
 		log.Println("Advertencia: No se pudo cargar el archivo .env. Asegúrate de que las variables de entorno estén configuradas o se usarán los valores por defecto.")
 	}
 
-	// 1. Conectar a la base de datos
-	// `err` is already declared by godotenv.Load(), so use `=`
-	dbConn, err := database.ConnectDB()
+	// `backend check` comprueba la instalación y termina sin arrancar el servidor
+	if len(os.Args) > 1 && os.Args[1] == "check" {
+		os.Exit(runCheck(os.Stdout))
+	}
+	// `backend migrate up|down|status` gestiona las migraciones del esquema sin arrancar el servidor
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(os.Args[2:], os.Stdout))
+	}
+
+	// Configuración recargable en caliente con SIGHUP o POST /api/v1/admin/config/reload
+	cfg, err := config.FromEnv()
 	if err != nil {
-		log.Fatalf("❌ Error al conectar a la base de datos: %v", err)
+		log.Fatalf("❌ Configuración inválida: %v", err)
+	}
+	if err := enricher.ValidateSteps(cfg.EnrichmentSteps); err != nil {
+		log.Fatalf("❌ ENRICHMENT_STEPS inválido: %v", err)
 	}
-	defer database.CloseDB(dbConn)
+	if err := providers.ValidateChains(cfg.ProviderChains); err != nil {
+		log.Fatalf("❌ PROVIDER_CHAIN_* inválido: %v", err)
+	}
+	config.Set(cfg)
 
-	// 2. Inicializar el esquema de la base de datos (crear tablas si no existen)
-	if err = database.InitSchema(dbConn); err != nil {
-		log.Fatalf("❌ Error al inicializar el esquema de la base de datos: %v", err)
+	// 1. Abrir el pool de conexiones. No se espera a la base de datos: el servidor HTTP
+	// arranca de inmediato y /readyz responde 503 hasta que la base de datos esté lista.
+	dbConn, err := database.OpenDB(os.Getenv("DATABASE_URL"), cfg.DBPool)
+	if err != nil {
+		log.Fatalf("❌ Error al conectar a la base de datos: %v", err)
 	}
 
-	// 3. Crear una instancia del cliente de base de datos que implementa StockDB
+	// 2. Crear una instancia del cliente de base de datos que implementa StockDB, y la de
+	// usuarios, que también autentica las claves de API de usuario y guarda la auditoría
 	dbClient := database.NewStockDB(dbConn)
+	userDB := database.NewUserDB(dbConn)
+	auth.SetUserStore(userDB)
+	auth.SetAuditLog(userDB)
 
-	// 4. Inicializar los manejadores de HTTP con la instancia de dbClient
-	stockHandlers := handlers.NewStockHandlers(dbClient)
-
-	// 5. Inicializar el job de cron con la instancia de dbClient
-	enricherJob := enricher.NewEnricher(dbClient)
-	go enricherJob.StartFetching() // Inicia el job de cron en una goroutine
+	// 3. Inicializar los manejadores de HTTP con la instancia de dbClient
+	jobQueue := jobs.NewQueue(clock.New(), jobWorkers, jobQueueCapacity)
+	stockHandlers := handlers.NewStockHandlers(dbClient, jobQueue)
+	quoteCache := quotes.NewCache()
+	quoteHandlers := handlers.NewQuoteHandlers(quoteCache)
+	hub := stream.NewHub()
+	streamHandlers := handlers.NewStreamHandlers(hub, userDB, clock.New())
+	mailer := mail.FromEnv()
+	userHandlers := handlers.NewUserHandlers(userDB, jobQueue, mailer)
+	readiness := &database.Readiness{}
+	healthHandlers := handlers.NewHealthHandlers(readiness, api.DegradedPaths...)
+	poolMonitor := database.NewPoolMonitor(dbConn)
 
-	// 6. Configurar el router HTTP
+	// 4. Router HTTP; las rutas se montan en SetupServer cuando existen todos los manejadores
 	router := chi.NewRouter()
-	router.Use(middleware.Logger)
-	router.Use(middleware.Recoverer)
-
-	// --- Add CORS middleware here. This should be placed BEFORE any specific routes ---
-	router.Use(cors.Handler(cors.Options{
-		AllowedOrigins:   []string{"http://localhost:5173"}, // Allow your frontend origin
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
-		AllowCredentials: true,
-		MaxAge:           300,
-	}))
-
-	// Rutas de la API (asumiendo que SetupRouter las define)
-	api.SetupRouter(router, stockHandlers)
-
-	// Iniciar el servidor HTTP
+
+	// 5. Inicializar el job de cron con la instancia de dbClient. Tras cada ejecución se
+	// precalientan en segundo plano las respuestas de las consultas más frecuentes y se
+	// evalúan las alertas de los usuarios con los datos nuevos.
+	responseCache := api.NewResponseCache()
+	responseCache.SetReadiness(readiness.Ready)
+	// Última respuesta cruda de los proveedores por ticker, visible en /admin/stocks/{ticker}/payloads
+	api.RecordPayloads(dbClient)
+	channels := []notify.Channel{
+		notify.EmailChannel{Mailer: mailer},
+		notify.NewWebhookChannel(userDB, webhook.NewSender(clock.New())),
+	}
+	// NOTIFY_LOG=true escribe también las alertas en el log, útil en desarrollo
+	if os.Getenv("NOTIFY_LOG") == "true" {
+		channels = append(channels, notify.LogChannel{})
+	}
+	pushSenders, err := notify.PushSendersFromEnv()
+	if err != nil {
+		log.Fatalf("❌ Error en la configuración de las notificaciones push: %v", err)
+	}
+	if len(pushSenders) > 0 {
+		channels = append(channels, notify.NewPushChannel(userDB, clock.New(), pushSenders))
+	}
+	dispatcher := notify.NewDispatcher(userDB, clock.New(), channels...)
+	alertEvaluator := alerts.NewEvaluator(userDB, clock.New(), dispatcher)
+	enricherJob := enricher.NewEnricher(dbClient,
+		enricher.WithInterval(config.Current().EnrichmentInterval),
+		enricher.WithSchedule(config.Current().EnrichSchedule()),
+		enricher.WithQuoteCache(quoteCache),
+		enricher.WithStream(hub),
+		enricher.WithAfterRun(func() {
+			go responseCache.Warm(router, api.WarmPaths...)
+			alertEvaluator.AfterEnrichment()
+		}),
+	)
+	config.OnReload(func(c config.Config) {
+		enricherJob.SetInterval(c.EnrichmentInterval)
+		enricherJob.SetSchedule(c.EnrichSchedule())
+		database.ConfigurePool(dbConn, c.DBPool)
+		// La recarga ya se aplicó; un paso desconocido hará fallar las siguientes ejecuciones.
+		if err := enricher.ValidateSteps(c.EnrichmentSteps); err != nil {
+			log.Printf("ERROR: ENRICHMENT_STEPS inválido tras recargar la configuración: %v", err)
+		}
+		if err := providers.ValidateChains(c.ProviderChains); err != nil {
+			log.Printf("ERROR: PROVIDER_CHAIN_* inválido tras recargar la configuración: %v", err)
+		}
+	})
+	purger := retention.NewPurger(userDB, dbClient, clock.New())
+
+	// Rutas de la API; la página de estado resume base de datos, enricher, proveedores y cola
+	statusHandlers := handlers.NewStatusHandlers(readiness, enricherJob, providers.HealthReport, jobQueue)
+	// Karenai puede avisar por webhook de recomendaciones nuevas; KARENAI_WEBHOOK_SECRET
+	// admite varios secretos separados por comas para rotarlos sin cortar los avisos.
+	var karenaiSecrets []string
+	if secrets := os.Getenv("KARENAI_WEBHOOK_SECRET"); secrets != "" {
+		karenaiSecrets = strings.Split(secrets, ",")
+	}
+	webhookHandlers := handlers.NewWebhookHandlers(userDB, enricherJob.Trigger, karenaiSecrets...)
+	api.SetupServer(router, api.Server{
+		Stocks:        stockHandlers,
+		Quotes:        quoteHandlers,
+		Streams:       streamHandlers,
+		Users:         userHandlers,
+		Status:        statusHandlers,
+		Webhooks:      webhookHandlers,
+		Watchlists:    handlers.NewWatchlistHandlers(userDB, dbClient, hub),
+		Jobs:          handlers.NewJobHandlers(jobQueue),
+		Health:        healthHandlers,
+		Pool:          poolMonitor,
+		ResponseCache: responseCache,
+	})
+
+	// 6. Servidor HTTP
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8081"
 	}
-	log.Printf("🚀 Servidor escuchando en http://localhost:%s", port)
-	log.Fatal(http.ListenAndServe(":"+port, router))
+	server := &http.Server{Addr: ":" + port, Handler: router}
+	// Shutdown no espera a las conexiones secuestradas por el stream: el hub las cierra.
+	server.RegisterOnShutdown(hub.Close)
+
+	// 7. Registrar los subsistemas. Arrancan en este orden y se detienen en el inverso:
+	// primero deja de aceptar peticiones el servidor HTTP, después terminan la cola de
+	// trabajos, el enricher y las tareas en segundo plano y por último se cierra la base
+	// de datos.
+	var background sync.WaitGroup // bootstrapDatabase y las tareas que arranca
+	lc := lifecycle.New()
+	lc.Register(lifecycle.Hook{
+		Name: "configuración",
+		Start: func(ctx context.Context) error {
+			go config.WatchSIGHUP(ctx)
+			return nil
+		},
+	})
+	lc.Register(lifecycle.Hook{
+		Name: "base de datos",
+		Start: func(ctx context.Context) error {
+			go poolMonitor.Monitor(ctx, dbPoolCheckInterval)
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			database.CloseDB(dbConn)
+			return nil
+		},
+	})
+	lc.Register(lifecycle.Hook{
+		Name: "tareas en segundo plano",
+		// Se cancelan con el contexto de Start; se espera a que terminen la vuelta en curso
+		// y la evaluación de alertas para no cerrar la base de datos a mitad de una escritura.
+		Stop: func(ctx context.Context) error {
+			background.Wait()
+			return alertEvaluator.Stop(ctx)
+		},
+		Timeout: 15 * time.Second,
+	})
+	lc.Register(lifecycle.Hook{
+		Name: "enricher",
+		Start: func(ctx context.Context) error {
+			background.Add(1)
+			go func() {
+				defer background.Done()
+				bootstrapDatabase(ctx, dbConn, dbClient, readiness, quoteCache, enricherJob, purger, dispatcher, &background)
+			}()
+			return nil
+		},
+		Stop:    enricherJob.Stop,
+		Timeout: 30 * time.Second,
+	})
+	lc.Register(lifecycle.Hook{
+		Name:    "cola de trabajos",
+		Start:   jobQueue.Start,
+		Stop:    jobQueue.Stop,
+		Timeout: 30 * time.Second,
+	})
+	lc.Register(lifecycle.Hook{
+		Name: "servidor HTTP",
+		Start: func(ctx context.Context) error {
+			ln, err := net.Listen("tcp", server.Addr)
+			if err != nil {
+				return err
+			}
+			log.Printf("🚀 Servidor escuchando en http://localhost:%s", port)
+			go func() {
+				if err := server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					log.Fatalf("❌ Error del servidor HTTP: %v", err)
+				}
+			}()
+			return nil
+		},
+		Stop:    server.Shutdown,
+		Timeout: 15 * time.Second,
+	})
+
+	if err := lc.Run(context.Background()); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+}
+
+// bootstrapDatabase espera a la base de datos, aplica las migraciones pendientes, carga la caché de
+// cotizaciones, arranca el enricher, la purga de cuentas borradas y el envío de
+// notificaciones (contándolas en background) y vigila la conexión para reflejar caídas y
+// reconexiones en /readyz, hasta que se cancela ctx.
+func bootstrapDatabase(ctx context.Context, dbConn *sql.DB, dbClient database.StockDB, readiness *database.Readiness, quoteCache *quotes.Cache, enricherJob *enricher.Enricher, purger *retention.Purger, dispatcher *notify.Dispatcher, background *sync.WaitGroup) {
+	if err := database.WaitForDB(ctx, dbConn, database.DefaultRetryConfig); err != nil {
+		if ctx.Err() != nil {
+			return // Apagado antes de que la base de datos estuviera disponible
+		}
+		log.Fatalf("❌ Error al conectar a la base de datos: %v", err)
+	}
+	log.Println("Conexión a la base de datos establecida correctamente.")
+
+	if _, err := database.MigrateUp(ctx, dbConn); err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+		log.Fatalf("❌ Error al migrar el esquema de la base de datos: %v", err)
+	}
+	readiness.SetReady(true)
+
+	// Las cotizaciones de /quotes se sirven desde memoria: se cargan las ya guardadas para no
+	// esperar a la próxima ejecución del enricher.
+	if err := quoteCache.Load(ctx, dbClient); err != nil {
+		log.Printf("Advertencia: no se pudo cargar la caché de cotizaciones: %v", err)
+	} else {
+		log.Printf("Caché de cotizaciones cargada con %d tickers.", quoteCache.Len())
+	}
+
+	go enricherJob.StartFetching()
+	background.Add(2)
+	go func() {
+		defer background.Done()
+		purger.Run(ctx)
+	}()
+	go func() {
+		defer background.Done()
+		dispatcher.Run(ctx)
+	}()
+	readiness.Monitor(ctx, dbConn, dbHealthCheckInterval)
 }
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/jannin2/stock-app/backend/config"
+	"github.com/jannin2/stock-app/backend/database"
+)
+
+const migrateUsage = "uso: backend migrate up | down [n] | status"
+
+// runMigrate implementa `backend migrate`: up aplica las migraciones pendientes (lo mismo
+// que hace el servidor al arrancar), down revierte las n últimas aplicadas (1 por defecto) y
+// status lista todas con su estado. Escribe el resultado en out y devuelve el código de
+// salida.
+func runMigrate(args []string, out io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprintln(out, migrateUsage)
+		return 2
+	}
+	steps := 1
+	switch {
+	case args[0] == "down" && len(args) == 2:
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n <= 0 {
+			fmt.Fprintf(out, "❌ número de migraciones inválido: %q\n", args[1])
+			return 2
+		}
+		steps = n
+	case (args[0] == "up" || args[0] == "down" || args[0] == "status") && len(args) == 1:
+	default:
+		fmt.Fprintln(out, migrateUsage)
+		return 2
+	}
+
+	connStr := os.Getenv("DATABASE_URL")
+	if connStr == "" {
+		fmt.Fprintln(out, "❌ DATABASE_URL no está configurada")
+		return 1
+	}
+	cfg, err := config.FromEnv()
+	if err != nil {
+		fmt.Fprintf(out, "❌ configuración inválida: %v\n", err)
+		return 1
+	}
+	db, err := database.OpenDB(connStr, cfg.DBPool)
+	if err != nil {
+		fmt.Fprintf(out, "❌ %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	switch args[0] {
+	case "up":
+		applied, err := database.MigrateUp(ctx, db)
+		printMigrations(out, "aplicada", applied)
+		if err != nil {
+			fmt.Fprintf(out, "❌ %v\n", err)
+			return 1
+		}
+		if len(applied) == 0 {
+			fmt.Fprintln(out, "El esquema ya está al día")
+		}
+	case "down":
+		reverted, err := database.MigrateDown(ctx, db, steps)
+		printMigrations(out, "revertida", reverted)
+		if err != nil {
+			fmt.Fprintf(out, "❌ %v\n", err)
+			return 1
+		}
+		if len(reverted) == 0 {
+			fmt.Fprintln(out, "No hay migraciones aplicadas")
+		}
+	case "status":
+		statuses, err := database.MigrationStatuses(ctx, db)
+		if err != nil {
+			fmt.Fprintf(out, "❌ %v\n", err)
+			return 1
+		}
+		for _, s := range statuses {
+			state := "pendiente"
+			if s.AppliedAt != nil {
+				state = "aplicada el " + s.AppliedAt.UTC().Format("2006-01-02 15:04:05") + " UTC"
+			}
+			fmt.Fprintf(out, "%04d_%-30s %s\n", s.Version, s.Name, state)
+		}
+	}
+	return 0
+}
+
+// printMigrations escribe una línea por migración con la acción realizada.
+func printMigrations(out io.Writer, action string, migrations []database.Migration) {
+	for _, m := range migrations {
+		fmt.Fprintf(out, "✅ %04d_%s %s\n", m.Version, m.Name, action)
+	}
+}
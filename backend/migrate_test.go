@@ -0,0 +1,24 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRunMigrate_InvalidArgs(t *testing.T) {
+	for _, args := range [][]string{nil, {"sideways"}, {"up", "2"}, {"down", "0"}, {"down", "x"}, {"status", "-v"}} {
+		var out bytes.Buffer
+		if code := runMigrate(args, &out); code != 2 {
+			t.Errorf("❌ %q: código de salida %d, se esperaba 2 (%s)", args, code, out.String())
+		}
+	}
+}
+
+func TestRunMigrate_MissingDatabaseURL(t *testing.T) {
+	t.Setenv("DATABASE_URL", "")
+	var out bytes.Buffer
+	if code := runMigrate([]string{"status"}, &out); code != 1 || !strings.Contains(out.String(), "DATABASE_URL") {
+		t.Errorf("❌ código %d, salida %q: se esperaba un error por DATABASE_URL", code, out.String())
+	}
+}
@@ -0,0 +1,25 @@
+package models
+
+// StockAggregates summarizes by sector the stocks that match a filter, for charts.
+type StockAggregates struct {
+	Total int `json:"total"`
+	// Sampled tells that the figures are estimated from a sample of SampleSize stocks
+	// instead of computed over every match: counts and market caps are scaled up, and
+	// averages are those of the sample.
+	Sampled    bool              `json:"sampled"`
+	SampleSize int               `json:"sample_size,omitempty"`
+	Sectors    []SectorAggregate `json:"sectors"`
+}
+
+// SectorAggregate holds the figures of one sector. Averages leave out the stocks without
+// the field, and are null when none has it.
+type SectorAggregate struct {
+	Sector     string `json:"sector"`
+	StockCount int    `json:"stock_count"`
+	// MarketCap is the sum of the market capitalizations (millions of USD).
+	MarketCap              float64     `json:"market_cap"`
+	AvgChangePercent       NullFloat64 `json:"avg_change_percent"`
+	AvgPERatio             NullFloat64 `json:"avg_pe_ratio"`
+	AvgDividendYield       NullFloat64 `json:"avg_dividend_yield"`
+	AvgRecommendationScore NullFloat64 `json:"avg_recommendation_score"`
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Stock metrics an alert rule can watch.
+const (
+	AlertMetricPrice               = "price"
+	AlertMetricChangePct           = "change_pct" // Change from the previous close, in percent
+	AlertMetricPERatio             = "pe_ratio"
+	AlertMetricDividendYield       = "dividend_yield"
+	AlertMetricRecommendationScore = "recommendation_score"
+)
+
+// AlertMetrics are the metrics an alert rule can watch, in the order the API lists them.
+var AlertMetrics = []string{AlertMetricPrice, AlertMetricChangePct, AlertMetricPERatio, AlertMetricDividendYield, AlertMetricRecommendationScore}
+
+// Alert rule operators.
+const (
+	AlertAbove     = "above"
+	AlertBelow     = "below"
+	AlertChangesBy = "changes_by" // Moves by at least the threshold, either way, from the baseline
+)
+
+// Alert is a user's rule such as "AAPL price above 200". It fires once when the condition
+// becomes true and re-arms when it becomes false again, so a price hovering above the
+// threshold does not notify on every enrichment run. A changes_by rule compares against
+// its baseline, the value when it was created or last fired, so it fires again after each
+// further move of the threshold size.
+type Alert struct {
+	ID          uuid.UUID  `json:"id"`
+	UserID      uuid.UUID  `json:"-"`
+	Ticker      string     `json:"ticker"`
+	Metric      string     `json:"metric"`
+	Operator    string     `json:"operator"`
+	Threshold   float64    `json:"threshold"`
+	Baseline    *float64   `json:"baseline,omitempty"`     // changes_by only; nil until the metric has a value
+	TriggeredAt *time.Time `json:"triggered_at,omitempty"` // Set while the condition holds
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// Validate checks the metric, operator and threshold of the rule.
+func (a Alert) Validate() error {
+	known := false
+	for _, m := range AlertMetrics {
+		known = known || a.Metric == m
+	}
+	if !known {
+		return fmt.Errorf("unknown metric %q", a.Metric)
+	}
+	if a.Operator != AlertAbove && a.Operator != AlertBelow && a.Operator != AlertChangesBy {
+		return fmt.Errorf("unknown operator %q (use %s, %s or %s)", a.Operator, AlertAbove, AlertBelow, AlertChangesBy)
+	}
+	if math.IsNaN(a.Threshold) || math.IsInf(a.Threshold, 0) {
+		return fmt.Errorf("invalid threshold %v", a.Threshold)
+	}
+	if a.Operator == AlertChangesBy && a.Threshold <= 0 {
+		return fmt.Errorf("the threshold of a %s rule must be positive", AlertChangesBy)
+	}
+	return nil
+}
+
+// AlertEvent records an alert firing, with the rule as it was and the value that crossed
+// the threshold.
+type AlertEvent struct {
+	ID          uuid.UUID `json:"id"`
+	AlertID     uuid.UUID `json:"alert_id"`
+	UserID      uuid.UUID `json:"-"`
+	Ticker      string    `json:"ticker"`
+	Metric      string    `json:"metric"`
+	Operator    string    `json:"operator"`
+	Threshold   float64   `json:"threshold"`
+	Value       float64   `json:"value"`
+	TriggeredAt time.Time `json:"triggered_at"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// Corporate action types.
+const (
+	CorporateActionSplit        = "split"
+	CorporateActionMerger       = "merger"        // The company was absorbed; NewTicker is the acquirer when listed
+	CorporateActionSymbolChange = "symbol_change" // The company now trades as NewTicker
+)
+
+// CorporateAction is an event that changes a stock's shares or symbol. A ticker has at
+// most one action of each type per effective date.
+type CorporateAction struct {
+	Ticker        string      `json:"ticker"` // Ticker the action was reported for, before any symbol change
+	Type          string      `json:"type"`
+	EffectiveDate time.Time   `json:"effective_date"`
+	SplitFrom     NullFloat64 `json:"split_from"` // For splits, e.g. 1 in a 4-for-1 split; null otherwise
+	SplitTo       NullFloat64 `json:"split_to"`   // For splits, e.g. 4 in a 4-for-1 split; null otherwise
+	NewTicker     string      `json:"new_ticker,omitempty"`
+	Source        string      `json:"source"`
+	RecordedAt    time.Time   `json:"recorded_at"`
+}
+
+// Renames reports whether the action moves the stock to another ticker once effective.
+func (a CorporateAction) Renames() bool {
+	return a.NewTicker != "" && a.NewTicker != a.Ticker &&
+		(a.Type == CorporateActionSymbolChange || a.Type == CorporateActionMerger)
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Push notification platforms.
+const (
+	PushPlatformAndroid = "android" // Firebase Cloud Messaging
+	PushPlatformIOS     = "ios"     // Apple Push Notification service
+)
+
+// Push delivery outcomes.
+const (
+	PushStatusDelivered    = "delivered"
+	PushStatusFailed       = "failed"
+	PushStatusInvalidToken = "invalid_token" // The provider rejected the token; the device is disabled
+)
+
+// Device is a phone registered to receive push notifications.
+type Device struct {
+	ID           uuid.UUID     `json:"id"`
+	UserID       uuid.UUID     `json:"-"`
+	Platform     string        `json:"platform"`
+	Token        string        `json:"-"`
+	Name         string        `json:"name,omitempty"`
+	CreatedAt    time.Time     `json:"created_at"`
+	DisabledAt   *time.Time    `json:"disabled_at,omitempty"`
+	LastDelivery *PushDelivery `json:"last_delivery,omitempty"`
+}
+
+// PushDelivery records one attempt to deliver a notification to a device.
+type PushDelivery struct {
+	DeviceID  uuid.UUID `json:"-"`
+	Status    string    `json:"status"`
+	MessageID string    `json:"message_id,omitempty"` // Provider message ID, for support requests
+	Error     string    `json:"error,omitempty"`
+	Events    int       `json:"events"` // Alerts included (more than one for a digest)
+	SentAt    time.Time `json:"sent_at"`
+}
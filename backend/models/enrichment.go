@@ -0,0 +1,110 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EnrichmentCursor records how far the current enrichment run has progressed, so a run
+// interrupted by a restart can resume after the last persisted ticker.
+type EnrichmentCursor struct {
+	RunID      uuid.UUID `json:"run_id"`
+	LastTicker string    `json:"last_ticker"` // Last ticker saved to the database (tickers are processed in order)
+	StartedAt  time.Time `json:"started_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	Completed  bool      `json:"completed"`
+}
+
+// Enrichment run statuses.
+const (
+	EnrichmentRunRunning   = "running"
+	EnrichmentRunSucceeded = "succeeded"
+	EnrichmentRunFailed    = "failed"
+	EnrichmentRunAbandoned = "abandoned" // Interrupted and not resumed before the next run started
+)
+
+// EnrichmentRun is the history record of an enrichment run. A run resumed after a restart
+// keeps its ID and accumulates its counts.
+type EnrichmentRun struct {
+	ID              uuid.UUID      `json:"id"`
+	Status          string         `json:"status"` // One of the EnrichmentRun* constants
+	StartedAt       time.Time      `json:"started_at"`
+	FinishedAt      *time.Time     `json:"finished_at,omitempty"`
+	StocksProcessed int            `json:"stocks_processed"`
+	ProviderErrors  map[string]int `json:"provider_errors"` // Failed provider calls by provider
+	Error           string         `json:"error,omitempty"` // Why the run failed
+}
+
+// ProviderHealth is the outcome of the most recent calls to a data provider.
+type ProviderHealth struct {
+	Provider    string     `json:"provider"`
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+	LastError   *time.Time `json:"last_error,omitempty"`
+	// LastErrorMessage may contain request URLs with API keys, so only admins see it.
+	LastErrorMessage string `json:"last_error_message,omitempty" scope:"admin"`
+}
+
+// Failing reports whether the provider's most recent call failed.
+func (h ProviderHealth) Failing() bool {
+	return h.LastError != nil && (h.LastSuccess == nil || h.LastError.After(*h.LastSuccess))
+}
+
+// EnrichmentResult is the outcome of one ticker in an enrichment run: the fields it
+// updated and the provider of each, the metrics left null and the provider errors that
+// explain them.
+type EnrichmentResult struct {
+	RunID      uuid.UUID  `json:"run_id"`
+	Ticker     string     `json:"ticker"`
+	Fields     Provenance `json:"fields"`      // Updated fields and the provider that supplied each
+	NullFields []string   `json:"null_fields"` // Enriched metrics no provider could supply
+	Errors     string     `json:"errors,omitempty"`
+	EnrichedAt time.Time  `json:"enriched_at"`
+}
+
+// NewEnrichmentResult summarizes an enriched stock as its result in run runID.
+func NewEnrichmentResult(runID uuid.UUID, s Stock) EnrichmentResult {
+	fields := Provenance{}
+	for field, src := range s.Provenance {
+		fields[field] = src
+	}
+	return EnrichmentResult{
+		RunID:      runID,
+		Ticker:     s.Ticker,
+		Fields:     fields,
+		NullFields: s.NullMetrics(),
+		Errors:     s.ProviderErrors,
+		EnrichedAt: s.UpdatedAt,
+	}
+}
+
+// NullMetrics returns the JSON names of the enriched metrics the stock has no value for,
+// in the order they appear in Stock.
+func (s Stock) NullMetrics() []string {
+	metrics := []struct {
+		name  string
+		valid bool
+	}{
+		{"current_price", s.CurrentPrice != 0},
+		{"pe_ratio", s.PERatio.Valid},
+		{"dividend_yield", s.DividendYield.Valid},
+		{"market_capitalization", s.MarketCapitalization.Valid},
+		{"alpha", s.Alpha.Valid},
+		{"latest_trading_day", s.LatestTradingDay.Valid},
+		{"recommendation_score", s.RecommendationScore.Valid},
+		{"sector", s.Sector != ""},
+		{"previous_close", s.PreviousClose.Valid},
+		{"sentiment", s.Sentiment.Valid},
+		{"buzz", s.Buzz.Valid},
+		{"esg_score", s.ESGScore.Valid},
+		{"short_interest", s.ShortInterest.Valid},
+		{"days_to_cover", s.DaysToCover.Valid},
+	}
+	null := []string{}
+	for _, m := range metrics {
+		if !m.valid {
+			null = append(null, m.name)
+		}
+	}
+	return null
+}
@@ -0,0 +1,153 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	_ "time/tzdata" // The exchange calendars must work in images without a zoneinfo database
+)
+
+// Exchange is a stock exchange a ticker can be listed on. Non-US listings use the
+// suffix convention of Yahoo and Finnhub ("SAP.DE", "VOD.L"); a ticker without a suffix
+// is a US listing.
+type Exchange struct {
+	Code     string `json:"code"`
+	Suffix   string `json:"suffix"` // Empty for US listings
+	Name     string `json:"name"`
+	Country  string `json:"country"`  // ISO 3166-1 alpha-2
+	Currency string `json:"currency"` // ISO 4217, or GBX for London, which quotes in pence
+	Timezone string `json:"timezone"`
+	Open     string `json:"open"`  // Regular session, local time (HH:MM)
+	Close    string `json:"close"` // Regular session, local time (HH:MM)
+
+	location *time.Location
+	open     time.Duration // Wall clock time of day
+	close    time.Duration
+}
+
+// ExchangeUS is the exchange of tickers without a suffix. NYSE and Nasdaq share hours
+// and currency, so they are not told apart.
+const ExchangeUS = "US"
+
+var exchanges = []Exchange{
+	newExchange(ExchangeUS, "", "NYSE / Nasdaq", "US", "USD", "America/New_York", "09:30", "16:00"),
+	newExchange("TSX", "TO", "Toronto Stock Exchange", "CA", "CAD", "America/Toronto", "09:30", "16:00"),
+	newExchange("LSE", "L", "London Stock Exchange", "GB", "GBX", "Europe/London", "08:00", "16:30"),
+	newExchange("XETRA", "DE", "Xetra", "DE", "EUR", "Europe/Berlin", "09:00", "17:30"),
+	newExchange("EPA", "PA", "Euronext Paris", "FR", "EUR", "Europe/Paris", "09:00", "17:30"),
+	newExchange("AMS", "AS", "Euronext Amsterdam", "NL", "EUR", "Europe/Amsterdam", "09:00", "17:30"),
+	newExchange("EBR", "BR", "Euronext Brussels", "BE", "EUR", "Europe/Brussels", "09:00", "17:30"),
+	newExchange("ELI", "LS", "Euronext Lisbon", "PT", "EUR", "Europe/Lisbon", "08:00", "16:30"),
+	newExchange("ISE", "IR", "Euronext Dublin", "IE", "EUR", "Europe/Dublin", "08:00", "16:30"),
+	newExchange("BIT", "MI", "Borsa Italiana", "IT", "EUR", "Europe/Rome", "09:00", "17:30"),
+	newExchange("BME", "MC", "Bolsa de Madrid", "ES", "EUR", "Europe/Madrid", "09:00", "17:30"),
+	newExchange("SIX", "SW", "SIX Swiss Exchange", "CH", "CHF", "Europe/Zurich", "09:00", "17:30"),
+	newExchange("VIE", "VI", "Wiener Börse", "AT", "EUR", "Europe/Vienna", "09:00", "17:30"),
+	newExchange("STO", "ST", "Nasdaq Stockholm", "SE", "SEK", "Europe/Stockholm", "09:00", "17:30"),
+	newExchange("CPH", "CO", "Nasdaq Copenhagen", "DK", "DKK", "Europe/Copenhagen", "09:00", "17:00"),
+	newExchange("HEL", "HE", "Nasdaq Helsinki", "FI", "EUR", "Europe/Helsinki", "10:00", "18:30"),
+	newExchange("OSL", "OL", "Oslo Børs", "NO", "NOK", "Europe/Oslo", "09:00", "16:20"),
+}
+
+func newExchange(code, suffix, name, country, currency, timezone, open, close string) Exchange {
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		panic(fmt.Sprintf("exchange %s: %v", code, err))
+	}
+	return Exchange{Code: code, Suffix: suffix, Name: name, Country: country, Currency: currency,
+		Timezone: timezone, Open: open, Close: close,
+		location: loc, open: clockTime(open), close: clockTime(close)}
+}
+
+// clockTime parses an HH:MM time of day.
+func clockTime(hhmm string) time.Duration {
+	t, err := time.Parse("15:04", hhmm)
+	if err != nil {
+		panic(fmt.Sprintf("invalid session time %q", hhmm))
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+}
+
+// Exchanges returns the supported exchanges, US first.
+func Exchanges() []Exchange {
+	return append([]Exchange(nil), exchanges...)
+}
+
+// ExchangeForTicker returns the exchange a ticker is listed on. Other than London's ".L",
+// a suffix of a single letter is a share class ("BRK.B"), so such tickers are US listings.
+// It fails for a suffix that is not a supported exchange.
+func ExchangeForTicker(ticker string) (Exchange, error) {
+	suffix := ""
+	if i := strings.LastIndex(ticker, "."); i >= 0 {
+		suffix = strings.ToUpper(ticker[i+1:])
+	}
+	for _, ex := range exchanges {
+		if ex.Suffix == suffix {
+			return ex, nil
+		}
+	}
+	if len(suffix) == 1 {
+		return exchanges[0], nil
+	}
+	return Exchange{}, fmt.Errorf("unsupported exchange suffix .%s in ticker %s", suffix, ticker)
+}
+
+// BaseSymbol returns the ticker without the exchange suffix, e.g. "SAP" for "SAP.DE".
+func (ex Exchange) BaseSymbol(ticker string) string {
+	if ex.Suffix == "" {
+		return ticker
+	}
+	return ticker[:len(ticker)-len(ex.Suffix)-1]
+}
+
+// Location returns the exchange's time zone.
+func (ex Exchange) Location() *time.Location {
+	if ex.location == nil {
+		return time.UTC
+	}
+	return ex.location
+}
+
+// IsOpen reports whether t falls in a regular session: a weekday between the opening and
+// closing time, local time. Holidays are not part of the calendar.
+func (ex Exchange) IsOpen(t time.Time) bool {
+	local := t.In(ex.Location())
+	if !isWeekday(local) {
+		return false
+	}
+	now := timeOfDay(local)
+	return now >= ex.open && now < ex.close
+}
+
+// TradingDay returns the date, as UTC midnight, of the latest session that had opened by t:
+// today's local date once the market opens on a weekday, the previous weekday otherwise.
+func (ex Exchange) TradingDay(t time.Time) time.Time {
+	local := t.In(ex.Location())
+	day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+	if !isWeekday(local) || timeOfDay(local) < ex.open {
+		day = day.AddDate(0, 0, -1)
+	}
+	for !isWeekday(day) {
+		day = day.AddDate(0, 0, -1)
+	}
+	return day
+}
+
+// timeOfDay is the wall clock time of t, which on DST changes differs from the time
+// elapsed since midnight.
+func timeOfDay(t time.Time) time.Duration {
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+}
+
+func isWeekday(t time.Time) bool {
+	return t.Weekday() != time.Saturday && t.Weekday() != time.Sunday
+}
+
+// SetExchange fills the stock's Exchange and Currency from its ticker. They stay empty
+// when the ticker's suffix is not a supported exchange.
+func (s *Stock) SetExchange() {
+	s.Exchange, s.Currency = "", ""
+	if ex, err := ExchangeForTicker(s.Ticker); err == nil {
+		s.Exchange, s.Currency = ex.Code, ex.Currency
+	}
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExchangeForTicker(t *testing.T) {
+	cases := map[string]string{
+		"AAPL":    "US",
+		"BRK.B":   "US", // Share class, not an exchange
+		"SAP.DE":  "XETRA",
+		"VOD.L":   "LSE",
+		"ITX.MC":  "BME",
+		"shop.to": "TSX",
+	}
+	for ticker, want := range cases {
+		ex, err := ExchangeForTicker(ticker)
+		if err != nil || ex.Code != want {
+			t.Errorf("ExchangeForTicker(%s) = %q, %v; want %s", ticker, ex.Code, err, want)
+		}
+	}
+	if _, err := ExchangeForTicker("ABC.XX"); err == nil {
+		t.Error("expected an error for an unsupported suffix")
+	}
+}
+
+func TestExchange_Calendar(t *testing.T) {
+	xetra, _ := ExchangeForTicker("SAP.DE")
+	// Monday 2025-01-06: Xetra opens at 08:00 UTC (09:00 in Berlin).
+	monday := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		at       time.Time
+		open     bool
+		tradeDay time.Time
+	}{
+		{monday.Add(7 * time.Hour), false, monday.AddDate(0, 0, -3)}, // Before the open: Friday's session
+		{monday.Add(8 * time.Hour), true, monday},
+		{monday.Add(17 * time.Hour), false, monday},                                     // After the close
+		{monday.AddDate(0, 0, -1).Add(12 * time.Hour), false, monday.AddDate(0, 0, -3)}, // Sunday
+	}
+	for _, tt := range tests {
+		if got := xetra.IsOpen(tt.at); got != tt.open {
+			t.Errorf("IsOpen(%s) = %t, want %t", tt.at, got, tt.open)
+		}
+		if got := xetra.TradingDay(tt.at); !got.Equal(tt.tradeDay) {
+			t.Errorf("TradingDay(%s) = %s, want %s", tt.at, got, tt.tradeDay)
+		}
+	}
+
+	// After Europe moves to summer time the session still opens at 09:00 local time.
+	dst := time.Date(2025, 3, 31, 7, 0, 0, 0, time.UTC) // Monday, 09:00 in Berlin
+	if !xetra.IsOpen(dst) {
+		t.Errorf("Xetra should be open at %s", dst)
+	}
+}
+
+func TestStock_SetExchange(t *testing.T) {
+	s := Stock{Ticker: "NESN.SW"}
+	s.SetExchange()
+	if s.Exchange != "SIX" || s.Currency != "CHF" {
+		t.Errorf("got %s/%s, want SIX/CHF", s.Exchange, s.Currency)
+	}
+	s = Stock{Ticker: "ABC.XX", Exchange: "US", Currency: "USD"}
+	s.SetExchange()
+	if s.Exchange != "" || s.Currency != "" {
+		t.Errorf("got %s/%s for an unsupported suffix, want empty", s.Exchange, s.Currency)
+	}
+}
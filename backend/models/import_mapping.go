@@ -0,0 +1,130 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Kinds of importable stock fields, which decide how a CSV cell is converted.
+const (
+	ImportFieldText   = "text"
+	ImportFieldNumber = "number"
+	ImportFieldDate   = "date" // RFC 3339 or 2006-01-02, like NullTime
+)
+
+// ImportFields lists the stock fields a CSV import can map columns to, by JSON name.
+var ImportFields = map[string]string{
+	"ticker":                ImportFieldText,
+	"company":               ImportFieldText,
+	"brokerage":             ImportFieldText,
+	"action":                ImportFieldText,
+	"rating_from":           ImportFieldText,
+	"rating_to":             ImportFieldText,
+	"sector":                ImportFieldText,
+	"target_from":           ImportFieldNumber,
+	"target_to":             ImportFieldNumber,
+	"current_price":         ImportFieldNumber,
+	"previous_close":        ImportFieldNumber,
+	"pe_ratio":              ImportFieldNumber,
+	"dividend_yield":        ImportFieldNumber,
+	"market_capitalization": ImportFieldNumber,
+	"alpha":                 ImportFieldNumber,
+	"recommendation_score":  ImportFieldNumber,
+	"latest_trading_day":    ImportFieldDate,
+}
+
+var importMappingName = regexp.MustCompile(`^[A-Za-z0-9._-]{1,100}$`)
+
+// ImportMapping is a reusable template describing how the columns of a recurring CSV map
+// to stock fields, e.g. "column 3 is target_to".
+type ImportMapping struct {
+	Name      string `json:"name"`
+	HasHeader bool   `json:"has_header"`          // Whether the first row holds column names
+	Delimiter string `json:"delimiter,omitempty"` // A single character; "," when empty
+	// Columns maps each column to a field. A column is a 1-based index ("3") or, when
+	// HasHeader is set, a header name matched case-insensitively.
+	Columns   map[string]string `json:"columns"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// Validate checks the name, delimiter and column mapping. The ticker must be mapped and
+// no field may be mapped twice.
+func (m ImportMapping) Validate() error {
+	if !importMappingName.MatchString(m.Name) {
+		return errors.New("name must be 1-100 letters, digits, dots, dashes or underscores")
+	}
+	if m.Delimiter != "" {
+		r, size := utf8.DecodeRuneInString(m.Delimiter)
+		if size != len(m.Delimiter) || r == '"' || r == '\r' || r == '\n' || r == utf8.RuneError {
+			return fmt.Errorf("invalid delimiter %q", m.Delimiter)
+		}
+	}
+	mapped := map[string]string{}
+	for column, field := range m.Columns {
+		if _, ok := ImportFields[field]; !ok {
+			return fmt.Errorf("unknown field %q for column %q", field, column)
+		}
+		if other, dup := mapped[field]; dup {
+			return fmt.Errorf("field %s is mapped to both column %q and column %q", field, other, column)
+		}
+		mapped[field] = column
+		if index, err := strconv.Atoi(column); err == nil {
+			if index < 1 {
+				return fmt.Errorf("column index %d must be 1 or greater", index)
+			}
+		} else if !m.HasHeader || strings.TrimSpace(column) == "" {
+			return fmt.Errorf("column %q is not an index and the mapping has no header", column)
+		}
+	}
+	if _, ok := mapped["ticker"]; !ok {
+		return errors.New("the ticker field must be mapped")
+	}
+	return nil
+}
+
+// Comma returns the delimiter as a rune for encoding/csv.
+func (m ImportMapping) Comma() rune {
+	if m.Delimiter == "" {
+		return ','
+	}
+	r, _ := utf8.DecodeRuneInString(m.Delimiter)
+	return r
+}
+
+// Resolve returns the field of each mapped column by 0-based index, looking up header
+// names in header (the first row, nil when the mapping has no header).
+func (m ImportMapping) Resolve(header []string) (map[int]string, error) {
+	byName := map[string]int{}
+	for i, name := range header {
+		if i == 0 {
+			name = strings.TrimPrefix(name, "\uFEFF") // Spreadsheets often prepend a BOM
+		}
+		byName[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	fields := map[int]string{}
+	var missing []string
+	for column, field := range m.Columns {
+		if index, err := strconv.Atoi(column); err == nil {
+			fields[index-1] = field
+			continue
+		}
+		index, ok := byName[strings.ToLower(strings.TrimSpace(column))]
+		if !ok {
+			missing = append(missing, column)
+			continue
+		}
+		fields[index] = field
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, fmt.Errorf("columns not found in the header: %s", strings.Join(missing, ", "))
+	}
+	return fields, nil
+}
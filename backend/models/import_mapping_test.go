@@ -0,0 +1,43 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestImportMapping_Validate(t *testing.T) {
+	valid := ImportMapping{Name: "broker-x", HasHeader: true, Delimiter: ";",
+		Columns: map[string]string{"Symbol": "ticker", "3": "target_to"}}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("Expected a valid mapping, got %v", err)
+	}
+
+	cases := map[string]ImportMapping{
+		"bad name":                   {Name: "broker x", Columns: map[string]string{"1": "ticker"}},
+		"no ticker":                  {Name: "m", Columns: map[string]string{"1": "company"}},
+		"unknown field":              {Name: "m", Columns: map[string]string{"1": "ticker", "2": "price"}},
+		"duplicate field":            {Name: "m", Columns: map[string]string{"1": "ticker", "2": "ticker"}},
+		"zero index":                 {Name: "m", Columns: map[string]string{"0": "ticker"}},
+		"header name without header": {Name: "m", Columns: map[string]string{"Symbol": "ticker"}},
+		"long delimiter":             {Name: "m", Delimiter: ";;", Columns: map[string]string{"1": "ticker"}},
+		"quote delimiter":            {Name: "m", Delimiter: `"`, Columns: map[string]string{"1": "ticker"}},
+	}
+	for name, m := range cases {
+		if err := m.Validate(); err == nil {
+			t.Errorf("%s: expected a validation error", name)
+		}
+	}
+}
+
+func TestImportMapping_Resolve(t *testing.T) {
+	m := ImportMapping{Name: "m", HasHeader: true, Columns: map[string]string{"symbol": "ticker", "4": "pe_ratio"}}
+	fields, err := m.Resolve([]string{"\uFEFFSymbol", "Name", "Target"})
+	if err != nil || fields[0] != "ticker" || fields[3] != "pe_ratio" {
+		t.Errorf("Unexpected resolution %v (%v)", fields, err)
+	}
+
+	m.Columns["Target High"] = "target_to"
+	if _, err := m.Resolve([]string{"Symbol"}); err == nil || !strings.Contains(err.Error(), "Target High") {
+		t.Errorf("Expected a missing column error, got %v", err)
+	}
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// IPO statuses, as reported by Finnhub.
+const (
+	IPOStatusFiled     = "filed"
+	IPOStatusExpected  = "expected"
+	IPOStatusPriced    = "priced" // Listed: the shares started trading on Date
+	IPOStatusWithdrawn = "withdrawn"
+)
+
+// IPO is an entry of the IPO calendar. A company has a single entry, updated as its
+// offering moves from filed to priced.
+type IPO struct {
+	Symbol    string      `json:"symbol"` // Empty until the exchange assigns it
+	Company   string      `json:"company"`
+	Exchange  string      `json:"exchange"`
+	Date      time.Time   `json:"date"` // Expected or actual listing date
+	PriceLow  NullFloat64 `json:"price_low"`
+	PriceHigh NullFloat64 `json:"price_high"` // Equal to PriceLow once priced
+	Shares    NullFloat64 `json:"shares"`
+	Status    string      `json:"status"`
+	Source    string      `json:"source"`
+	AddedAt   NullTime    `json:"added_at"` // When the ticker was added to the tracked stocks; null if it was not
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
+// Listed reports whether the IPO has a symbol that was trading by asOf.
+func (i IPO) Listed(asOf time.Time) bool {
+	return i.Status == IPOStatusPriced && i.Symbol != "" && !i.Date.After(asOf)
+}
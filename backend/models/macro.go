@@ -0,0 +1,89 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Macro event categories, used to pick out the releases that move the whole market.
+const (
+	MacroCategoryInflation = "inflation" // CPI, PPI, PCE
+	MacroCategoryRates     = "rates"     // Central bank rate decisions, e.g. the FOMC
+	MacroCategoryJobs      = "jobs"      // Nonfarm payrolls, unemployment, jobless claims
+	MacroCategoryGrowth    = "growth"    // GDP, retail sales, PMIs
+	MacroCategoryOther     = "other"
+)
+
+// Macro event impacts, as reported by the economic calendar.
+const (
+	MacroImpactLow    = "low"
+	MacroImpactMedium = "medium"
+	MacroImpactHigh   = "high"
+)
+
+// macroCategoryKeywords classify an event by its name. They are checked in order, so rate
+// decisions are not taken for inflation because of "Interest Rate" or similar.
+var macroCategoryKeywords = []struct {
+	category string
+	keywords []string
+}{
+	{MacroCategoryRates, []string{"fomc", "interest rate decision", "fed interest rate", "rate decision", "monetary policy"}},
+	{MacroCategoryJobs, []string{"nonfarm", "non-farm", "payrolls", "unemployment", "jobless", "employment change", "jolts"}},
+	{MacroCategoryInflation, []string{"cpi", "consumer price", "ppi", "producer price", "pce", "inflation"}},
+	{MacroCategoryGrowth, []string{"gdp", "retail sales", "pmi", "industrial production", "durable goods"}},
+}
+
+// MacroCategory classifies an economic calendar event by its name, e.g. "CPI MoM" is
+// MacroCategoryInflation. Events that match no category are MacroCategoryOther.
+func MacroCategory(event string) string {
+	name := strings.ToLower(event)
+	for _, c := range macroCategoryKeywords {
+		for _, keyword := range c.keywords {
+			if strings.Contains(name, keyword) {
+				return c.category
+			}
+		}
+	}
+	return MacroCategoryOther
+}
+
+// IsValidMacroCategory reports whether category is one of the MacroCategory* constants.
+func IsValidMacroCategory(category string) bool {
+	switch category {
+	case MacroCategoryInflation, MacroCategoryRates, MacroCategoryJobs, MacroCategoryGrowth, MacroCategoryOther:
+		return true
+	}
+	return false
+}
+
+// MacroEvent is a release of the economic calendar, such as a CPI print or an FOMC rate
+// decision. A country has a single event of each name at a given time; Actual is filled
+// in once the figure is released.
+type MacroEvent struct {
+	Country   string      `json:"country"` // ISO 3166 alpha-2, e.g. "US"
+	Event     string      `json:"event"`
+	Category  string      `json:"category"`
+	Impact    string      `json:"impact"`
+	Time      time.Time   `json:"time"` // Scheduled release time
+	Actual    NullFloat64 `json:"actual"`
+	Estimate  NullFloat64 `json:"estimate"`
+	Previous  NullFloat64 `json:"previous"`
+	Surprise  NullFloat64 `json:"surprise"` // Actual minus Estimate, once both are known
+	Unit      string      `json:"unit,omitempty"`
+	Source    string      `json:"source"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
+// SetSurprise derives Surprise from Actual and Estimate.
+func (e *MacroEvent) SetSurprise() {
+	e.Surprise = NullFloat64{}
+	if e.Actual.Valid && e.Estimate.Valid {
+		e.Surprise = NewNullFloat64(e.Actual.Float64 - e.Estimate.Float64)
+	}
+}
+
+// Key reports whether the event is worth keeping: a high or medium impact release, or one
+// of the categorized market movers.
+func (e MacroEvent) Key() bool {
+	return e.Impact == MacroImpactHigh || e.Impact == MacroImpactMedium || e.Category != MacroCategoryOther
+}
@@ -0,0 +1,34 @@
+package models
+
+import "testing"
+
+func TestMacroCategory(t *testing.T) {
+	cases := map[string]string{
+		"CPI MoM":                    MacroCategoryInflation,
+		"Core PCE Price Index YoY":   MacroCategoryInflation,
+		"Fed Interest Rate Decision": MacroCategoryRates,
+		"FOMC Minutes":               MacroCategoryRates,
+		"Nonfarm Payrolls":           MacroCategoryJobs,
+		"Initial Jobless Claims":     MacroCategoryJobs,
+		"GDP Growth Rate QoQ":        MacroCategoryGrowth,
+		"Baker Hughes Oil Rig Count": MacroCategoryOther,
+	}
+	for event, want := range cases {
+		if got := MacroCategory(event); got != want {
+			t.Errorf("MacroCategory(%q) = %s; want %s", event, got, want)
+		}
+	}
+}
+
+func TestMacroEvent_SetSurprise(t *testing.T) {
+	e := MacroEvent{Actual: NewNullFloat64(256), Estimate: NewNullFloat64(160)}
+	e.SetSurprise()
+	if !e.Surprise.Valid || e.Surprise.Float64 != 96 {
+		t.Errorf("Expected a surprise of 96, got %+v", e.Surprise)
+	}
+	e.Actual = NullFloat64{}
+	e.SetSurprise()
+	if e.Surprise.Valid {
+		t.Errorf("Expected no surprise before the release, got %+v", e.Surprise)
+	}
+}
@@ -0,0 +1,51 @@
+package models
+
+// HeatmapTile is a single stock inside a heatmap sector. In a treemap the tile size is
+// MarketCap and its color is ChangePercent.
+type HeatmapTile struct {
+	Ticker        string      `json:"ticker"`
+	Company       string      `json:"company"`
+	MarketCap     NullFloat64 `json:"market_cap"`
+	ChangePercent NullFloat64 `json:"change_percent"`
+}
+
+// HeatmapSector aggregates the stocks of a sector for the market heatmap.
+type HeatmapSector struct {
+	Sector     string `json:"sector"`
+	StockCount int    `json:"stock_count"`
+	// MarketCap is the sum of the sector's market capitalizations (millions of USD).
+	MarketCap float64 `json:"market_cap"`
+	// ChangePercent is the market-cap-weighted average daily change. Stocks without a
+	// market cap or a daily change are left out of the average.
+	ChangePercent NullFloat64   `json:"change_percent"`
+	Stocks        []HeatmapTile `json:"stocks"`
+}
+
+// Mover is a stock with one of the largest daily changes of a trading day.
+type Mover struct {
+	Ticker        string      `json:"ticker"`
+	Company       string      `json:"company"`
+	CurrentPrice  float64     `json:"current_price"`
+	PreviousClose NullFloat64 `json:"previous_close"`
+	ChangePercent float64     `json:"change_percent"`
+}
+
+// MarketMovers are the top gainers and losers of a trading day.
+type MarketMovers struct {
+	TradingDay NullTime `json:"trading_day"` // Null when no stock has traded yet
+	Gainers    []Mover  `json:"gainers"`
+	Losers     []Mover  `json:"losers"`
+}
+
+// BrokerageStats summarizes the ratings of a brokerage across the stocks it covers.
+type BrokerageStats struct {
+	Brokerage    string `json:"brokerage"`
+	Ratings      int    `json:"ratings"`
+	Upgrades     int    `json:"upgrades"`
+	Downgrades   int    `json:"downgrades"`
+	TargetRaises int    `json:"target_raises"`
+	TargetCuts   int    `json:"target_cuts"`
+	// AvgTargetUpside is the average upside from the current price to the brokerage's
+	// price targets, in percent. Null when none of its ratings has a target.
+	AvgTargetUpside NullFloat64 `json:"avg_target_upside"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// MentionCount is the number of social media mentions of a ticker on one day, as stored in
+// the mention history.
+type MentionCount struct {
+	Ticker   string    `json:"ticker"`
+	Day      time.Time `json:"day"`
+	Source   string    `json:"source"`
+	Mentions int       `json:"mentions"`
+}
+
+// MentionCountFromStock builds the history entry for the stock's Buzz, dated with the UTC
+// day it was fetched on. It returns false when the stock has no buzz.
+func MentionCountFromStock(s Stock) (MentionCount, bool) {
+	src, ok := s.Provenance["buzz"]
+	if !s.Buzz.Valid || !ok {
+		return MentionCount{}, false
+	}
+	day := src.FetchedAt.UTC()
+	return MentionCount{
+		Ticker:   s.Ticker,
+		Day:      time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC),
+		Source:   src.Source,
+		Mentions: int(s.Buzz.Float64),
+	}, true
+}
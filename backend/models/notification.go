@@ -0,0 +1,99 @@
+package models
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Notification channels.
+const (
+	NotificationChannelEmail   = "email"
+	NotificationChannelPush    = "push"
+	NotificationChannelWebhook = "webhook" // The user's webhooks, see Webhook
+	NotificationChannelLog     = "log"     // The server log, for development
+)
+
+// quietHoursLayout is the format of the quiet hours bounds.
+const quietHoursLayout = "15:04"
+
+// NotificationSettings are a user's delivery preferences for alert notifications.
+type NotificationSettings struct {
+	QuietHoursStart string         `json:"quiet_hours_start,omitempty"` // "22:00", in Timezone
+	QuietHoursEnd   string         `json:"quiet_hours_end,omitempty"`   // "07:00"; before the start for overnight quiet hours
+	Timezone        string         `json:"timezone,omitempty"`          // IANA name; UTC when empty
+	MaxPerHour      map[string]int `json:"max_per_hour,omitempty"`      // Per-channel limit, never above the server-wide one
+}
+
+// Validate checks the quiet hours, time zone and limits.
+func (s NotificationSettings) Validate() error {
+	if (s.QuietHoursStart == "") != (s.QuietHoursEnd == "") {
+		return errors.New("quiet_hours_start and quiet_hours_end must be set together")
+	}
+	for _, bound := range []string{s.QuietHoursStart, s.QuietHoursEnd} {
+		if _, err := time.Parse(quietHoursLayout, bound); bound != "" && err != nil {
+			return fmt.Errorf("invalid quiet hours time %q (use HH:MM)", bound)
+		}
+	}
+	if _, err := time.LoadLocation(s.Timezone); err != nil {
+		return fmt.Errorf("unknown time zone %q", s.Timezone)
+	}
+	for channel, limit := range s.MaxPerHour {
+		if limit < 0 {
+			return fmt.Errorf("invalid max_per_hour for %s: %d", channel, limit)
+		}
+	}
+	return nil
+}
+
+// InQuietHours reports whether t falls within the user's quiet hours.
+func (s NotificationSettings) InQuietHours(t time.Time) bool {
+	start, errStart := time.Parse(quietHoursLayout, s.QuietHoursStart)
+	end, errEnd := time.Parse(quietHoursLayout, s.QuietHoursEnd)
+	if errStart != nil || errEnd != nil || start.Equal(end) {
+		return false
+	}
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	local := t.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+	from, to := start.Hour()*60+start.Minute(), end.Hour()*60+end.Minute()
+	if from < to {
+		return minute >= from && minute < to
+	}
+	return minute >= from || minute < to // Overnight, e.g. 22:00-07:00
+}
+
+// Limit returns how many notifications per hour the user accepts on channel, given the
+// server-wide limit (0 = unlimited). A user limit only applies when it is stricter.
+func (s NotificationSettings) Limit(channel string, serverLimit int) int {
+	if user := s.MaxPerHour[channel]; user > 0 && (serverLimit == 0 || user < serverLimit) {
+		return user
+	}
+	return serverLimit
+}
+
+// Value implements driver.Valuer, storing the settings as a JSON object.
+func (s NotificationSettings) Value() (driver.Value, error) {
+	return jsonValue(s, struct{}{})
+}
+
+// Scan implements sql.Scanner for JSONB columns.
+func (s *NotificationSettings) Scan(src interface{}) error {
+	return scanJSON(src, s, "NotificationSettings")
+}
+
+// PendingNotification is an alert event waiting in the outbox to be sent on a channel.
+type PendingNotification struct {
+	ID       uuid.UUID
+	UserID   uuid.UUID
+	Email    string
+	Channel  string
+	Settings NotificationSettings
+	Event    AlertEvent
+}
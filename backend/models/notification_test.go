@@ -0,0 +1,65 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNotificationSettings_InQuietHours(t *testing.T) {
+	overnight := NotificationSettings{QuietHoursStart: "22:00", QuietHoursEnd: "07:00", Timezone: "Europe/Madrid"}
+	cases := []struct {
+		at   time.Time
+		want bool
+	}{
+		{time.Date(2025, 1, 6, 20, 59, 0, 0, time.UTC), false}, // 21:59 in Madrid
+		{time.Date(2025, 1, 6, 21, 0, 0, 0, time.UTC), true},   // 22:00
+		{time.Date(2025, 1, 7, 3, 0, 0, 0, time.UTC), true},    // 04:00
+		{time.Date(2025, 1, 7, 6, 0, 0, 0, time.UTC), false},   // 07:00, quiet hours are over
+	}
+	for _, c := range cases {
+		if got := overnight.InQuietHours(c.at); got != c.want {
+			t.Errorf("InQuietHours(%s) = %v, want %v", c.at, got, c.want)
+		}
+	}
+
+	daytime := NotificationSettings{QuietHoursStart: "09:00", QuietHoursEnd: "17:30"}
+	if !daytime.InQuietHours(time.Date(2025, 1, 6, 17, 29, 0, 0, time.UTC)) || daytime.InQuietHours(time.Date(2025, 1, 6, 17, 30, 0, 0, time.UTC)) {
+		t.Errorf("daytime quiet hours should end at 17:30 UTC")
+	}
+	if (NotificationSettings{}).InQuietHours(time.Now()) {
+		t.Errorf("no quiet hours configured should never be quiet")
+	}
+}
+
+func TestNotificationSettings_Validate(t *testing.T) {
+	valid := NotificationSettings{QuietHoursStart: "22:00", QuietHoursEnd: "07:00", Timezone: "America/New_York", MaxPerHour: map[string]int{NotificationChannelEmail: 3}}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	for _, s := range []NotificationSettings{
+		{QuietHoursStart: "22:00"},
+		{QuietHoursStart: "25:00", QuietHoursEnd: "07:00"},
+		{Timezone: "Mars/Olympus_Mons"},
+		{MaxPerHour: map[string]int{NotificationChannelEmail: -1}},
+	} {
+		if err := s.Validate(); err == nil {
+			t.Errorf("expected an error for %+v", s)
+		}
+	}
+}
+
+func TestNotificationSettings_Limit(t *testing.T) {
+	s := NotificationSettings{MaxPerHour: map[string]int{NotificationChannelEmail: 3}}
+	if got := s.Limit(NotificationChannelEmail, 10); got != 3 {
+		t.Errorf("a stricter user limit should apply, got %d", got)
+	}
+	if got := s.Limit(NotificationChannelEmail, 2); got != 2 {
+		t.Errorf("the server limit should cap the user limit, got %d", got)
+	}
+	if got := s.Limit(NotificationChannelEmail, 0); got != 3 {
+		t.Errorf("with no server limit the user limit should apply, got %d", got)
+	}
+	if got := (NotificationSettings{}).Limit(NotificationChannelEmail, 10); got != 10 {
+		t.Errorf("without a user limit the server limit should apply, got %d", got)
+	}
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// OptionsSummary summarizes the options chain of a ticker, for users evaluating hedges.
+// Volumes and open interest add up every expiration; the implied volatility is the one of
+// the nearest expiration.
+type OptionsSummary struct {
+	Ticker                   string      `json:"ticker"`
+	Source                   string      `json:"source"`
+	NearestExpiration        time.Time   `json:"nearest_expiration"`
+	Expirations              int         `json:"expirations"`
+	ImpliedVolatility        NullFloat64 `json:"implied_volatility"` // In percent
+	PutVolume                float64     `json:"put_volume"`
+	CallVolume               float64     `json:"call_volume"`
+	PutCallRatio             NullFloat64 `json:"put_call_ratio"` // Put over call volume; null without call volume
+	PutOpenInterest          float64     `json:"put_open_interest"`
+	CallOpenInterest         float64     `json:"call_open_interest"`
+	PutCallOpenInterestRatio NullFloat64 `json:"put_call_open_interest_ratio"`
+	FetchedAt                time.Time   `json:"fetched_at"`
+}
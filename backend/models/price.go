@@ -0,0 +1,79 @@
+package models
+
+import "time"
+
+// PricePoint is a stock's closing price on a trading day, as stored in the price history,
+// with the rest of the day's candle when the quote provider reported it.
+type PricePoint struct {
+	Ticker     string    `json:"ticker"`
+	TradingDay time.Time `json:"trading_day"`
+	Close      float64   `json:"close"`
+	DayCandle
+}
+
+// DayCandle is the open, high, low and volume of a trading day. Zero fields are unknown.
+type DayCandle struct {
+	Open   float64 `json:"open,omitempty"`
+	High   float64 `json:"high,omitempty"`
+	Low    float64 `json:"low,omitempty"`
+	Volume int64   `json:"volume,omitempty"`
+}
+
+// PricePointFromStock builds the history point for the stock's current price. The trading
+// day is LatestTradingDay when known, otherwise the date of fallback in the time zone of the
+// stock's exchange (UTC if unsupported). The candle is the stock's DayCandle, if any. It
+// returns false when the stock has no price.
+func PricePointFromStock(s Stock, fallback time.Time) (PricePoint, bool) {
+	if s.CurrentPrice <= 0 {
+		return PricePoint{}, false
+	}
+	day := fallback.UTC()
+	if ex, err := ExchangeForTicker(s.Ticker); err == nil {
+		day = fallback.In(ex.Location())
+	}
+	if s.LatestTradingDay.Valid {
+		day = s.LatestTradingDay.Time.UTC()
+	}
+	point := PricePoint{
+		Ticker:     s.Ticker,
+		TradingDay: time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC),
+		Close:      s.CurrentPrice,
+	}
+	if s.DayCandle != nil {
+		point.DayCandle = *s.DayCandle
+	}
+	return point, true
+}
+
+// Resolutions of a downsampled price history: each bar covers a day, an ISO week (starting
+// on Monday) or a calendar month.
+const (
+	ResolutionDay   = "day"
+	ResolutionWeek  = "week"
+	ResolutionMonth = "month"
+)
+
+// ValidResolution reports whether r is one of the supported resolutions.
+func ValidResolution(r string) bool {
+	return r == ResolutionDay || r == ResolutionWeek || r == ResolutionMonth
+}
+
+// PriceBar is one point of a downsampled price history: the last close of the period that
+// starts on Start.
+type PriceBar struct {
+	Start time.Time `json:"start"`
+	Close float64   `json:"close"`
+}
+
+// Candle is the OHLCV bar of the period that starts on Start, for candlestick charts:
+// the first open, highest high, lowest low and last close of its trading days, and their
+// total volume. Days stored without a candle count with their close as open, high and
+// low; Volume is 0 when no day of the period has one.
+type Candle struct {
+	Start  time.Time `json:"start"`
+	Open   float64   `json:"open"`
+	High   float64   `json:"high"`
+	Low    float64   `json:"low"`
+	Close  float64   `json:"close"`
+	Volume int64     `json:"volume"`
+}
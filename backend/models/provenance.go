@@ -0,0 +1,69 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// SourceBulk is the provenance source of fields written through the bulk write API
+// (POST /stocks/bulk) instead of fetched from a provider.
+const SourceBulk = "bulk"
+
+// SourceManual is the provenance source of fields written one stock at a time through
+// POST /stocks and PUT /stocks/{id}.
+const SourceManual = "manual"
+
+// FieldSource records where an enriched field's value came from and when it was fetched.
+type FieldSource struct {
+	Source    string    `json:"source"` // Provider name, e.g. "finnhub"
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+// Provenance maps enriched field names (their JSON names, e.g. "pe_ratio") to the
+// provider that supplied them in the last enrichment. Fields that no provider could
+// supply are absent.
+type Provenance map[string]FieldSource
+
+// Set records source as the supplier of each of the given fields, fetched at fetchedAt.
+func (p Provenance) Set(source string, fetchedAt time.Time, fields ...string) {
+	for _, field := range fields {
+		p[field] = FieldSource{Source: source, FetchedAt: fetchedAt.UTC()}
+	}
+}
+
+// Value implements driver.Valuer, storing the provenance as JSON. An empty provenance
+// is stored as NULL.
+func (p Provenance) Value() (driver.Value, error) {
+	if len(p) == 0 {
+		return nil, nil
+	}
+	b, err := json.Marshal(map[string]FieldSource(p))
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan implements sql.Scanner for JSONB columns.
+func (p *Provenance) Scan(src interface{}) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		*p = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into Provenance", src)
+	}
+	m := map[string]FieldSource{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return fmt.Errorf("invalid provenance JSON: %w", err)
+	}
+	*p = m
+	return nil
+}
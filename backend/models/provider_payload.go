@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// ProviderPayload is the latest raw response of one provider endpoint for a ticker. It is
+// kept for a short time so data discrepancies can be traced without calling the provider
+// again.
+type ProviderPayload struct {
+	Ticker     string    `json:"ticker"`
+	Provider   string    `json:"provider"` // "finnhub" or "alphavantage"
+	Endpoint   string    `json:"endpoint"` // Finnhub path (e.g. "stock/metric") or Alpha Vantage function
+	StatusCode int       `json:"status_code"`
+	Body       []byte    `json:"-"`                   // Uncompressed response body
+	Truncated  bool      `json:"truncated,omitempty"` // Body was cut at the capture limit
+	FetchedAt  time.Time `json:"fetched_at"`
+}
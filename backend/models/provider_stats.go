@@ -0,0 +1,187 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// LatencyBucketsMs are the upper bounds, in milliseconds, of the provider latency
+// histogram buckets. A final overflow bucket counts the calls slower than the last bound.
+var LatencyBucketsMs = []int64{10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+
+// Provider call error categories.
+const (
+	ErrorCategoryTimeout     = "timeout"
+	ErrorCategoryRateLimited = "rate_limited"
+	ErrorCategoryHTTP4xx     = "http_4xx"
+	ErrorCategoryHTTP5xx     = "http_5xx"
+	ErrorCategoryNetwork     = "network"
+	ErrorCategoryDecode      = "decode"
+	ErrorCategoryConfig      = "config" // Missing API key
+	ErrorCategoryOther       = "other"
+)
+
+// LatencyHistogram counts calls per latency bucket: one entry per LatencyBucketsMs bound
+// plus the overflow bucket.
+type LatencyHistogram []int64
+
+// NewLatencyHistogram returns an empty histogram with every bucket.
+func NewLatencyHistogram() LatencyHistogram {
+	return make(LatencyHistogram, len(LatencyBucketsMs)+1)
+}
+
+// Observe counts one call that took latency.
+func (h LatencyHistogram) Observe(latency time.Duration) {
+	ms := latency.Milliseconds()
+	for i, bound := range LatencyBucketsMs {
+		if ms <= bound {
+			h[i]++
+			return
+		}
+	}
+	h[len(LatencyBucketsMs)]++
+}
+
+// Value implements driver.Valuer, storing the histogram as a JSON array.
+func (h LatencyHistogram) Value() (driver.Value, error) {
+	return jsonValue([]int64(h), []int64{})
+}
+
+// Scan implements sql.Scanner for JSONB columns.
+func (h *LatencyHistogram) Scan(src interface{}) error {
+	return scanJSON(src, (*[]int64)(h), "LatencyHistogram")
+}
+
+// ErrorCounts counts failed calls per error category.
+type ErrorCounts map[string]int64
+
+// Value implements driver.Valuer, storing the counts as a JSON object.
+func (c ErrorCounts) Value() (driver.Value, error) {
+	return jsonValue(map[string]int64(c), map[string]int64{})
+}
+
+// Scan implements sql.Scanner for JSONB columns.
+func (c *ErrorCounts) Scan(src interface{}) error {
+	return scanJSON(src, (*map[string]int64)(c), "ErrorCounts")
+}
+
+// ProviderDayStats aggregates the calls made to a provider during one UTC day.
+type ProviderDayStats struct {
+	Provider         string           `json:"provider"`
+	Day              time.Time        `json:"day"`
+	Calls            int64            `json:"calls"`
+	Errors           int64            `json:"errors"`
+	ErrorCategories  ErrorCounts      `json:"error_categories"`
+	LatencyHistogram LatencyHistogram `json:"latency_histogram"`
+	MaxMs            int64            `json:"max_ms"`
+	P50Ms            int64            `json:"p50_ms"`
+	P95Ms            int64            `json:"p95_ms"`
+	P99Ms            int64            `json:"p99_ms"`
+}
+
+// Observe counts one call. category is empty for successful calls.
+func (s *ProviderDayStats) Observe(latency time.Duration, category string) {
+	if s.LatencyHistogram == nil {
+		s.LatencyHistogram = NewLatencyHistogram()
+	}
+	s.Calls++
+	s.LatencyHistogram.Observe(latency)
+	s.MaxMs = max(s.MaxMs, latency.Milliseconds())
+	if category != "" {
+		if s.ErrorCategories == nil {
+			s.ErrorCategories = ErrorCounts{}
+		}
+		s.Errors++
+		s.ErrorCategories[category]++
+	}
+}
+
+// Merge adds the calls counted in other and recomputes the percentiles.
+func (s *ProviderDayStats) Merge(other ProviderDayStats) {
+	if s.LatencyHistogram == nil {
+		s.LatencyHistogram = NewLatencyHistogram()
+	}
+	if s.ErrorCategories == nil {
+		s.ErrorCategories = ErrorCounts{}
+	}
+	s.Calls += other.Calls
+	s.Errors += other.Errors
+	for i := range other.LatencyHistogram {
+		if i < len(s.LatencyHistogram) {
+			s.LatencyHistogram[i] += other.LatencyHistogram[i]
+		}
+	}
+	for category, n := range other.ErrorCategories {
+		s.ErrorCategories[category] += n
+	}
+	s.MaxMs = max(s.MaxMs, other.MaxMs)
+	s.UpdatePercentiles()
+}
+
+// UpdatePercentiles recomputes P50Ms, P95Ms and P99Ms from the histogram.
+func (s *ProviderDayStats) UpdatePercentiles() {
+	s.P50Ms = s.Percentile(0.50)
+	s.P95Ms = s.Percentile(0.95)
+	s.P99Ms = s.Percentile(0.99)
+}
+
+// Percentile approximates the q-th latency percentile (0 < q <= 1) by the upper bound of
+// the bucket it falls in, capped at the slowest call seen. It returns 0 without calls.
+func (s *ProviderDayStats) Percentile(q float64) int64 {
+	var total int64
+	for _, n := range s.LatencyHistogram {
+		total += n
+	}
+	if total == 0 {
+		return 0
+	}
+	rank := int64(q*float64(total) + 0.5)
+	rank = min(max(rank, 1), total)
+	var seen int64
+	for i, n := range s.LatencyHistogram {
+		seen += n
+		if seen >= rank {
+			if i < len(LatencyBucketsMs) {
+				return min(LatencyBucketsMs[i], s.MaxMs)
+			}
+			return s.MaxMs
+		}
+	}
+	return s.MaxMs
+}
+
+// ErrorRate returns the fraction of calls that failed.
+func (s *ProviderDayStats) ErrorRate() float64 {
+	if s.Calls == 0 {
+		return 0
+	}
+	return float64(s.Errors) / float64(s.Calls)
+}
+
+func jsonValue(v, empty interface{}) (driver.Value, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if string(b) == "null" {
+		b, _ = json.Marshal(empty)
+	}
+	return string(b), nil
+}
+
+func scanJSON(src interface{}, dst interface{}, typeName string) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into %s", src, typeName)
+	}
+	return json.Unmarshal(data, dst)
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProviderDayStats_MergeAndPercentiles(t *testing.T) {
+	var a, b ProviderDayStats
+	for i := 0; i < 90; i++ {
+		a.Observe(40*time.Millisecond, "")
+	}
+	for i := 0; i < 8; i++ {
+		b.Observe(700*time.Millisecond, ErrorCategoryTimeout)
+	}
+	b.Observe(30*time.Second, ErrorCategoryHTTP5xx)
+	b.Observe(12*time.Second, ErrorCategoryHTTP5xx)
+
+	var total ProviderDayStats
+	total.Merge(a)
+	total.Merge(b)
+
+	if total.Calls != 100 || total.Errors != 10 || total.ErrorRate() != 0.1 {
+		t.Errorf("unexpected totals: calls=%d errors=%d rate=%v", total.Calls, total.Errors, total.ErrorRate())
+	}
+	if total.ErrorCategories[ErrorCategoryTimeout] != 8 || total.ErrorCategories[ErrorCategoryHTTP5xx] != 2 {
+		t.Errorf("unexpected error categories: %v", total.ErrorCategories)
+	}
+	// p50 falls in the 50ms bucket, p95 in the 1s bucket and p99 in the overflow bucket,
+	// which reports the slowest call.
+	if total.P50Ms != 50 || total.P95Ms != 1000 || total.P99Ms != 30000 {
+		t.Errorf("unexpected percentiles: p50=%d p95=%d p99=%d", total.P50Ms, total.P95Ms, total.P99Ms)
+	}
+
+	var empty ProviderDayStats
+	if empty.Percentile(0.5) != 0 {
+		t.Errorf("percentile without calls should be 0")
+	}
+}
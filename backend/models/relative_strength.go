@@ -0,0 +1,67 @@
+package models
+
+import "strings"
+
+// RelativeStrength is a stock's return minus the return of its sector ETF over the same
+// period, in percentage points: 5 means the stock gained 5 points more than its sector.
+// A period is null until the price history covers it.
+type RelativeStrength struct {
+	Days30 NullFloat64 `json:"30d"`
+	Days90 NullFloat64 `json:"90d"`
+}
+
+// Relative strength periods, in calendar days.
+const (
+	RelativeStrengthShort = 30
+	RelativeStrengthLong  = 90
+)
+
+// sectorETFs maps the industries reported by Finnhub to the SPDR sector ETF that
+// benchmarks them.
+var sectorETFs = map[string]string{
+	"technology":                       "XLK",
+	"semiconductors":                   "XLK",
+	"electrical equipment":             "XLK",
+	"communications":                   "XLC",
+	"media":                            "XLC",
+	"telecommunication":                "XLC",
+	"health care":                      "XLV",
+	"healthcare":                       "XLV",
+	"pharmaceuticals":                  "XLV",
+	"biotechnology":                    "XLV",
+	"life sciences tools & services":   "XLV",
+	"banking":                          "XLF",
+	"financial services":               "XLF",
+	"insurance":                        "XLF",
+	"energy":                           "XLE",
+	"utilities":                        "XLU",
+	"real estate":                      "XLRE",
+	"chemicals":                        "XLB",
+	"metals & mining":                  "XLB",
+	"packaging":                        "XLB",
+	"aerospace & defense":              "XLI",
+	"airlines":                         "XLI",
+	"logistics & transportation":       "XLI",
+	"machinery":                        "XLI",
+	"industrial conglomerates":         "XLI",
+	"building":                         "XLI",
+	"construction":                     "XLI",
+	"road & rail":                      "XLI",
+	"beverages":                        "XLP",
+	"food products":                    "XLP",
+	"tobacco":                          "XLP",
+	"consumer products":                "XLP",
+	"retail":                           "XLY",
+	"automobiles":                      "XLY",
+	"auto components":                  "XLY",
+	"hotels, restaurants & leisure":    "XLY",
+	"textiles, apparel & luxury goods": "XLY",
+	"leisure products":                 "XLY",
+	"distributors":                     "XLY",
+}
+
+// SectorETF returns the ticker of the sector ETF that benchmarks sector, or "" if the
+// sector has none.
+func SectorETF(sector string) string {
+	return sectorETFs[strings.ToLower(strings.TrimSpace(sector))]
+}
@@ -0,0 +1,16 @@
+package models
+
+// ScoringRule is a scoring rule written in the scoring expression language. When the
+// condition holds, the stock gets the points the Points expression evaluates to.
+type ScoringRule struct {
+	Name   string `json:"name"`
+	When   string `json:"when,omitempty"` // Condition, e.g. `action in ["Buy", "Strong Buy"]`; empty means always
+	Points string `json:"points"`         // Number expression, e.g. "3" or "min(upside * 20, 4)"
+}
+
+// ScoringRules is the rule set stored in the database. An empty set means the built-in
+// weighted rules are used.
+type ScoringRules struct {
+	Rules     []ScoringRule `json:"rules"`
+	UpdatedAt NullTime      `json:"updated_at"`
+}
@@ -58,7 +58,7 @@ func (nt NullTime) MarshalJSON() ([]byte, error) {
 	if !nt.Valid {
 		return []byte("null"), nil // Return JSON 'null' for invalid time
 	}
-	return json.Marshal(nt.Time.Format(time.RFC3339))
+	return json.Marshal(nt.Time.UTC().Format(time.RFC3339))
 }
 
 // UnmarshalJSON implements the json.Unmarshaler interface.
@@ -101,22 +101,143 @@ func NewNullTime(t time.Time) NullTime {
 
 // Stock represents a stock entry with detailed financial metrics.
 type Stock struct {
-	ID                   uuid.UUID   `json:"id"`
-	Ticker               string      `json:"ticker"`
-	Company              string      `json:"company"`
-	Brokerage            string      `json:"brokerage"`
-	Action               string      `json:"action"`      // E.g., Buy, Sell, Hold
-	RatingFrom           string      `json:"rating_from"` // Previous rating
-	RatingTo             string      `json:"rating_to"`   // New rating
-	TargetFrom           NullFloat64 `json:"target_from"` // Previous target price
-	TargetTo             NullFloat64 `json:"target_to"`   // New target price
-	CurrentPrice         float64     `json:"current_price"`
-	PERatio              NullFloat64 `json:"pe_ratio"`
-	DividendYield        NullFloat64 `json:"dividend_yield"`
-	MarketCapitalization NullFloat64 `json:"market_capitalization"`
-	Alpha                NullFloat64 `json:"alpha"`              // Alpha value
-	LatestTradingDay     NullTime    `json:"latest_trading_day"` // Date of the latest trading data
-	RecommendationScore  NullFloat64 `json:"recommendation_score"`
-	CreatedAt            time.Time   `json:"created_at"`
-	UpdatedAt            time.Time   `json:"updated_at"`
+	ID                   uuid.UUID         `json:"id"`
+	Ticker               string            `json:"ticker"`
+	Company              string            `json:"company"`
+	Brokerage            string            `json:"brokerage"`
+	Action               string            `json:"action"`      // E.g., Buy, Sell, Hold
+	RatingFrom           string            `json:"rating_from"` // Previous rating
+	RatingTo             string            `json:"rating_to"`   // New rating
+	TargetFrom           NullFloat64       `json:"target_from"` // Previous target price
+	TargetTo             NullFloat64       `json:"target_to"`   // New target price
+	CurrentPrice         float64           `json:"current_price"`
+	PERatio              NullFloat64       `json:"pe_ratio"`
+	DividendYield        NullFloat64       `json:"dividend_yield"`
+	MarketCapitalization NullFloat64       `json:"market_capitalization"`
+	Alpha                NullFloat64       `json:"alpha"`              // Alpha value
+	LatestTradingDay     NullTime          `json:"latest_trading_day"` // Date of the latest trading data
+	RecommendationScore  NullFloat64       `json:"recommendation_score"`
+	Sector               string            `json:"sector"`                                  // Industry classification reported by Finnhub
+	PreviousClose        NullFloat64       `json:"previous_close"`                          // Previous session close, used for daily change
+	Exchange             string            `json:"exchange"`                                // Derived from the ticker suffix; see ExchangeForTicker
+	Currency             string            `json:"currency"`                                // Currency of the prices and targets, that of the exchange
+	Sentiment            NullFloat64       `json:"sentiment"`                               // Recent news sentiment, from -1 (negative) to 1 (positive)
+	Buzz                 NullFloat64       `json:"buzz"`                                    // Social media mentions on the day of the last enrichment
+	ESGScore             NullFloat64       `json:"esg_score"`                               // Total ESG score, from 0 to 100
+	ShortInterest        NullFloat64       `json:"short_interest"`                          // Shares sold short in the latest report
+	DaysToCover          NullFloat64       `json:"days_to_cover"`                           // Short interest over the average daily volume
+	RelativeStrength     RelativeStrength  `json:"relative_strength"`                       // Return over that of the sector ETF, from the price history
+	ProviderErrors       string            `json:"provider_errors,omitempty" scope:"admin"` // Raw provider errors from the last enrichment
+	Provenance           Provenance        `json:"-"`                                       // Provider that supplied each enriched field
+	Options              *OptionsSummary   `json:"-"`                                       // Set by the options step and saved to its own table; not read back with the stock
+	CorporateActions     []CorporateAction `json:"-"`                                       // Set by the corporate_actions step and saved to its own table; not read back with the stock
+	DayCandle            *DayCandle        `json:"-"`                                       // Set by the quotes step and saved to the price history; not read back with the stock
+	EnrichmentTier       string            `json:"enrichment_tier,omitempty" scope:"admin"` // Tier assigned by an admin; empty means automatic
+	CreatedAt            time.Time         `json:"created_at"`
+	UpdatedAt            time.Time         `json:"updated_at"`
+}
+
+// Market capitalization tiers. Thresholds are expressed in millions of USD,
+// the same unit Finnhub uses for MarketCapitalization.
+const (
+	MarketCapTierMega    = "mega"
+	MarketCapTierLarge   = "large"
+	MarketCapTierMid     = "mid"
+	MarketCapTierSmall   = "small"
+	MarketCapTierMicro   = "micro"
+	MarketCapTierUnknown = "unknown"
+)
+
+// MarketCapTier classifies a market capitalization (in millions of USD) into a tier.
+func MarketCapTier(marketCap NullFloat64) string {
+	if !marketCap.Valid {
+		return MarketCapTierUnknown
+	}
+	switch mc := marketCap.Float64; {
+	case mc >= 200000:
+		return MarketCapTierMega
+	case mc >= 10000:
+		return MarketCapTierLarge
+	case mc >= 2000:
+		return MarketCapTierMid
+	case mc >= 300:
+		return MarketCapTierSmall
+	default:
+		return MarketCapTierMicro
+	}
+}
+
+// StockBucket groups stocks that share the same key within a grouping (e.g. a sector).
+type StockBucket struct {
+	Key    string  `json:"key"`
+	Stocks []Stock `json:"stocks"`
+}
+
+// ChangePercent returns the daily price change in percent relative to the previous close.
+// It is null when the previous close is unknown.
+func (s Stock) ChangePercent() NullFloat64 {
+	if !s.PreviousClose.Valid || s.PreviousClose.Float64 == 0 || s.CurrentPrice == 0 {
+		return NullFloat64{}
+	}
+	return NewNullFloat64((s.CurrentPrice - s.PreviousClose.Float64) / s.PreviousClose.Float64 * 100)
+}
+
+// CompactStock is the trimmed representation of a stock used by list views on mobile clients.
+type CompactStock struct {
+	ID                  uuid.UUID   `json:"id"`
+	Ticker              string      `json:"ticker"`
+	Company             string      `json:"company"`
+	CurrentPrice        float64     `json:"current_price"`
+	ChangePercent       NullFloat64 `json:"change_percent"`
+	RecommendationScore NullFloat64 `json:"recommendation_score"`
+}
+
+// Compact returns the CompactStock view of the stock.
+func (s Stock) Compact() CompactStock {
+	return CompactStock{
+		ID:                  s.ID,
+		Ticker:              s.Ticker,
+		Company:             s.Company,
+		CurrentPrice:        s.CurrentPrice,
+		ChangePercent:       s.ChangePercent(),
+		RecommendationScore: s.RecommendationScore,
+	}
+}
+
+// Enrichment tiers decide how often a stock is refreshed from the providers. The interval
+// of each tier is configurable (config.EnrichmentTiers).
+const (
+	EnrichmentTierHot      = "hot"      // Mega caps: every hour by default
+	EnrichmentTierStandard = "standard" // Every day by default
+	EnrichmentTierArchived = "archived" // Stocks nobody follows anymore: every week by default
+)
+
+// IsValidEnrichmentTier reports whether tier is one of the known enrichment tiers.
+func IsValidEnrichmentTier(tier string) bool {
+	switch tier {
+	case EnrichmentTierHot, EnrichmentTierStandard, EnrichmentTierArchived:
+		return true
+	}
+	return false
+}
+
+// EnrichmentSchedule is the state the scheduler needs to decide whether a stock is due.
+type EnrichmentSchedule struct {
+	Ticker string
+	// Tier is the tier assigned by an admin; empty means automatic (see EffectiveTier).
+	Tier                 string
+	MarketCapitalization NullFloat64
+	LastEnrichedAt       time.Time
+}
+
+// EffectiveTier returns the assigned tier or, if none, the automatic one: mega caps are
+// hot and every other stock is standard.
+func (s EnrichmentSchedule) EffectiveTier() string {
+	if s.Tier != "" {
+		return s.Tier
+	}
+	if MarketCapTier(s.MarketCapitalization) == MarketCapTierMega {
+		return EnrichmentTierHot
+	}
+	return EnrichmentTierStandard
 }
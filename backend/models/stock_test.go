@@ -77,3 +77,49 @@ func TestNullFloat64_UnmarshalJSON(t *testing.T) {
 		})
 	}
 }
+
+func TestMarketCapTier(t *testing.T) {
+	tests := []struct {
+		name      string
+		marketCap NullFloat64
+		expected  string
+	}{
+		{name: "Null market cap", marketCap: NullFloat64{}, expected: MarketCapTierUnknown},
+		{name: "Mega cap", marketCap: NewNullFloat64(3.2e6), expected: MarketCapTierMega},
+		{name: "Large cap boundary", marketCap: NewNullFloat64(10000), expected: MarketCapTierLarge},
+		{name: "Mid cap", marketCap: NewNullFloat64(5000), expected: MarketCapTierMid},
+		{name: "Small cap", marketCap: NewNullFloat64(300), expected: MarketCapTierSmall},
+		{name: "Micro cap", marketCap: NewNullFloat64(120.5), expected: MarketCapTierMicro},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MarketCapTier(tt.marketCap); got != tt.expected {
+				t.Errorf("MarketCapTier() = %s, expected %s", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestStock_Compact(t *testing.T) {
+	s := Stock{
+		Ticker:              "AAPL",
+		Company:             "Apple",
+		CurrentPrice:        110.0,
+		PreviousClose:       NewNullFloat64(100.0),
+		RecommendationScore: NewNullFloat64(8.0),
+	}
+
+	c := s.Compact()
+	if c.Ticker != "AAPL" || c.CurrentPrice != 110.0 {
+		t.Errorf("Unexpected compact stock: %+v", c)
+	}
+	if !c.ChangePercent.Valid || c.ChangePercent.Float64 != 10.0 {
+		t.Errorf("Expected ChangePercent 10.0, got %f (Valid: %t)", c.ChangePercent.Float64, c.ChangePercent.Valid)
+	}
+
+	s.PreviousClose = NullFloat64{}
+	if s.ChangePercent().Valid {
+		t.Errorf("Expected null ChangePercent when previous close is unknown")
+	}
+}
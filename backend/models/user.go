@@ -0,0 +1,186 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// User is an account of the app. Users log in with e-mail and password to get a session
+// token, and can create a personal API key with POST /me/api-key (after a fresh second-factor
+// check if 2FA is on). Only the hashes of the password and the key are stored, so they never
+// appear in the model.
+type User struct {
+	ID              uuid.UUID  `json:"id"`
+	Email           string     `json:"email"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"` // Nil until the user follows the verification link
+	CreatedAt       time.Time  `json:"created_at"`
+	DeletedAt       *time.Time `json:"deleted_at,omitempty"` // Set while the account waits to be purged
+}
+
+// UserCredentials is an active user with its password hash, for the login and the e-mail
+// verification and password reset flows. It is never serialized.
+type UserCredentials struct {
+	User
+	PasswordHash string
+}
+
+// TwoFactor is the TOTP two-factor state of a user. Secret is set from enrollment, but
+// two-factor authentication is only enforced once EnabledAt is set (after the user proves
+// the authenticator app works).
+type TwoFactor struct {
+	Secret            string     `json:"-"`
+	EnabledAt         *time.Time `json:"enabled_at,omitempty"`
+	LastStep          int64      `json:"-"`                     // Last TOTP time step accepted, to reject replays
+	VerifiedAt        *time.Time `json:"verified_at,omitempty"` // Last successful second-factor check
+	RecoveryCodesLeft int        `json:"recovery_codes_left"`
+}
+
+// Enabled reports whether the user must pass a second-factor check.
+func (t TwoFactor) Enabled() bool {
+	return t.EnabledAt != nil
+}
+
+// Resources with a per-user quota (config UserQuotas).
+const (
+	QuotaWatchlists = "watchlists"
+	QuotaAlerts     = "alerts"
+)
+
+// QuotaUsage is how much of a per-user quota is in use. A Limit of 0 means unlimited.
+type QuotaUsage struct {
+	Resource string `json:"resource"`
+	Limit    int    `json:"limit"`
+	Used     int    `json:"used"`
+}
+
+// Watchlist is a named list of tickers a user follows. UserID is its owner, who can share
+// it with other users (see WatchlistMember).
+type Watchlist struct {
+	ID        uuid.UUID `json:"id"`
+	UserID    uuid.UUID `json:"-"`
+	Name      string    `json:"name"`
+	Tickers   []string  `json:"tickers"`
+	Role      string    `json:"role,omitempty"` // Role of the requesting user in the watchlist
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Roles in a shared watchlist. The owner and the editors can change its tickers; only the
+// owner can share it, change roles or delete it.
+const (
+	WatchlistOwner  = "owner"
+	WatchlistEditor = "editor"
+	WatchlistViewer = "viewer"
+)
+
+// WatchlistMember is a user with access to a watchlist, including its owner.
+type WatchlistMember struct {
+	UserID  uuid.UUID `json:"user_id"`
+	Email   string    `json:"email"`
+	Role    string    `json:"role"`
+	AddedAt time.Time `json:"added_at"`
+}
+
+// WatchlistStocks is a watchlist with the current data of its stocks. Missing lists the
+// tickers of the watchlist that are not tracked (yet), in the watchlist order.
+type WatchlistStocks struct {
+	Watchlist
+	Stocks  []Stock  `json:"stocks"`
+	Missing []string `json:"missing"`
+}
+
+// Note is a free-text note a user attached to a ticker.
+type Note struct {
+	ID        uuid.UUID `json:"id"`
+	UserID    uuid.UUID `json:"-"`
+	Ticker    string    `json:"ticker"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Position is a holding in a portfolio.
+type Position struct {
+	Ticker    string      `json:"ticker"`
+	Shares    float64     `json:"shares"`
+	CostBasis NullFloat64 `json:"cost_basis"` // Average price paid per share, if known
+}
+
+// Positions is stored as a JSONB column.
+type Positions []Position
+
+// Value implements driver.Valuer, storing the positions as a JSON array.
+func (p Positions) Value() (driver.Value, error) {
+	if p == nil {
+		p = Positions{}
+	}
+	b, err := json.Marshal([]Position(p))
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan implements sql.Scanner for JSONB columns.
+func (p *Positions) Scan(src interface{}) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		*p = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into Positions", src)
+	}
+	var positions []Position
+	if err := json.Unmarshal(data, &positions); err != nil {
+		return fmt.Errorf("invalid positions JSON: %w", err)
+	}
+	*p = positions
+	return nil
+}
+
+// Portfolio is a named set of positions held by a user.
+type Portfolio struct {
+	ID        uuid.UUID `json:"id"`
+	UserID    uuid.UUID `json:"-"`
+	Name      string    `json:"name"`
+	Positions Positions `json:"positions"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// UserData is everything stored about a user, as returned by the data export.
+type UserData struct {
+	User       User        `json:"user"`
+	Watchlists []Watchlist `json:"watchlists"`
+	Notes      []Note      `json:"notes"`
+	Portfolios []Portfolio `json:"portfolios"`
+	ExportedAt time.Time   `json:"exported_at"`
+}
+
+// Audit log actions.
+const (
+	AuditImpersonation       = "impersonation"        // An admin made a request as a user
+	AuditImpersonationDenied = "impersonation.denied" // An admin tried a write while impersonating
+)
+
+// AuditEntry is an entry of the audit log of admin actions on user accounts.
+type AuditEntry struct {
+	ID           uuid.UUID  `json:"id"`
+	OccurredAt   time.Time  `json:"occurred_at"`
+	Actor        string     `json:"actor"` // Who performed the action (the X-Admin-Actor header for admins)
+	Action       string     `json:"action"`
+	TargetUserID *uuid.UUID `json:"target_user_id,omitempty"`
+	Method       string     `json:"method"`
+	Path         string     `json:"path"`
+	Reason       string     `json:"reason"`
+	RemoteAddr   string     `json:"remote_addr"`
+}
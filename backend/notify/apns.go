@@ -0,0 +1,151 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/config"
+)
+
+const (
+	apnsProductionURL = "https://api.push.apple.com"
+	apnsSandboxURL    = "https://api.sandbox.push.apple.com"
+
+	// apnsTokenLifetime es cada cuánto se renueva el token de proveedor. Apple rechaza los
+	// de más de una hora y también los que se renuevan más de una vez cada 20 minutos.
+	apnsTokenLifetime = 50 * time.Minute
+)
+
+// APNsSender envía notificaciones a iOS con la API HTTP/2 de Apple Push Notification
+// service, autenticándose con un token de proveedor firmado con la clave .p8 del equipo.
+type APNsSender struct {
+	keyID   string
+	teamID  string
+	topic   string // Bundle ID de la app
+	key     *ecdsa.PrivateKey
+	baseURL string
+	client  *http.Client // net/http negocia HTTP/2 por TLS, que APNs exige
+
+	mu       sync.Mutex
+	token    string
+	issuedAt time.Time
+}
+
+// NewAPNsSender crea un APNsSender con la clave .p8 (PEM) y sus identificadores. Con
+// production a false envía al entorno sandbox, el de las builds de desarrollo.
+func NewAPNsSender(p8 []byte, keyID, teamID, topic string, production bool) (*APNsSender, error) {
+	if keyID == "" || teamID == "" || topic == "" {
+		return nil, errors.New("APNs necesita el ID de la clave, el ID del equipo y el bundle ID")
+	}
+	key, err := parsePKCS8Key(p8)
+	if err != nil {
+		return nil, fmt.Errorf("clave de APNs: %w", err)
+	}
+	ecKey, ok := key.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("la clave de APNs no es ECDSA")
+	}
+	baseURL := apnsSandboxURL
+	if production {
+		baseURL = apnsProductionURL
+	}
+	return &APNsSender{keyID: keyID, teamID: teamID, topic: topic, key: ecKey, baseURL: baseURL,
+		client: &http.Client{Timeout: pushTimeout}}, nil
+}
+
+// APNsSenderFromEnv crea el APNsSender con APNS_KEY_FILE, APNS_KEY_ID, APNS_TEAM_ID,
+// APNS_TOPIC y APNS_PRODUCTION. Devuelve nil si APNS_KEY_FILE no está configurada.
+func APNsSenderFromEnv() (*APNsSender, error) {
+	path := os.Getenv("APNS_KEY_FILE")
+	if path == "" {
+		return nil, nil
+	}
+	p8, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error al leer APNS_KEY_FILE: %w", err)
+	}
+	return NewAPNsSender(p8, os.Getenv("APNS_KEY_ID"), os.Getenv("APNS_TEAM_ID"), os.Getenv("APNS_TOPIC"),
+		os.Getenv("APNS_PRODUCTION") == "true")
+}
+
+func (s *APNsSender) Send(ctx context.Context, token string, msg PushMessage) (string, error) {
+	providerToken, err := s.providerToken()
+	if err != nil {
+		return "", err
+	}
+	payload := map[string]interface{}{
+		"aps": map[string]interface{}{
+			"alert": map[string]string{"title": msg.Title, "body": msg.Body},
+			"sound": "default",
+		},
+	}
+	for k, v := range msg.Data {
+		payload[k] = v
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.baseURL+"/3/device/"+url.PathEscape(token), bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "bearer "+providerToken)
+	req.Header.Set("User-Agent", config.Current().UserAgent)
+	req.Header.Set("apns-topic", s.topic)
+	req.Header.Set("apns-push-type", "alert")
+	req.Header.Set("apns-priority", "10")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error al enviar a APNs: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return resp.Header.Get("apns-id"), nil
+	}
+
+	var apnsErr struct {
+		Reason string `json:"reason"`
+	}
+	_ = json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&apnsErr)
+	switch {
+	case resp.StatusCode == http.StatusGone, apnsErr.Reason == "BadDeviceToken", apnsErr.Reason == "Unregistered":
+		return "", fmt.Errorf("APNs %s: %w", apnsErr.Reason, ErrInvalidToken)
+	case apnsErr.Reason == "ExpiredProviderToken":
+		s.mu.Lock()
+		s.token = "" // Se firmará otro en el siguiente envío
+		s.mu.Unlock()
+	}
+	return "", fmt.Errorf("APNs respondió %d: %s", resp.StatusCode, apnsErr.Reason)
+}
+
+// providerToken devuelve el JWT de proveedor vigente, firmando uno nuevo cada
+// apnsTokenLifetime.
+func (s *APNsSender) providerToken() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token != "" && time.Since(s.issuedAt) < apnsTokenLifetime {
+		return s.token, nil
+	}
+	now := time.Now()
+	token, err := signJWT(
+		map[string]interface{}{"alg": "ES256", "kid": s.keyID},
+		map[string]interface{}{"iss": s.teamID, "iat": now.Unix()},
+		signES256(s.key))
+	if err != nil {
+		return "", err
+	}
+	s.token, s.issuedAt = token, now
+	return token, nil
+}
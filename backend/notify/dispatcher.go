@@ -0,0 +1,182 @@
+// Package notify envía a los usuarios las alertas disparadas por los canales configurados
+// (e-mail, push a móviles). Las notificaciones pasan por una bandeja de salida en la base de datos,
+// así que las que no pueden enviarse todavía (horas de silencio, límite por hora superado,
+// fallo del canal) esperan a la siguiente vuelta del dispatcher en lugar de perderse.
+package notify
+
+import (
+	"context"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/clock"
+	"github.com/jannin2/stock-app/backend/config"
+	"github.com/jannin2/stock-app/backend/database"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// FlushInterval es cada cuánto se reintenta enviar la bandeja de salida, por ejemplo al
+// terminar las horas de silencio de un usuario.
+const FlushInterval = time.Minute
+
+// throttleWindow es el periodo sobre el que se aplica el límite de envíos por canal.
+const throttleWindow = time.Hour
+
+// Notification es un envío a un usuario por un canal: una alerta suelta o, si no caben
+// todas en el límite por hora, un resumen con todas las pendientes.
+type Notification struct {
+	UserID uuid.UUID
+	Email  string
+	Events []models.AlertEvent
+	Digest bool
+}
+
+// Channel envía notificaciones por un medio concreto.
+type Channel interface {
+	Name() string
+	Send(ctx context.Context, n Notification) error
+}
+
+// Dispatcher encola las alertas disparadas y las envía respetando las horas de silencio y
+// el límite por hora de cada usuario y canal.
+type Dispatcher struct {
+	users    database.UserDB
+	clock    clock.Clock
+	channels map[string]Channel
+
+	mu sync.Mutex // Serializa las vueltas de Flush
+}
+
+// NewDispatcher crea un Dispatcher que envía por channels.
+func NewDispatcher(users database.UserDB, c clock.Clock, channels ...Channel) *Dispatcher {
+	d := &Dispatcher{users: users, clock: c, channels: map[string]Channel{}}
+	for _, ch := range channels {
+		d.channels[ch.Name()] = ch
+	}
+	return d
+}
+
+// Enqueue añade las alertas disparadas a la bandeja de salida de cada canal y las envía
+// en cuanto se pueda.
+func (d *Dispatcher) Enqueue(ctx context.Context, events []models.AlertEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	ids := make([]uuid.UUID, len(events))
+	for i, e := range events {
+		ids[i] = e.ID
+	}
+	names := make([]string, 0, len(d.channels))
+	for name := range d.channels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if _, err := d.users.EnqueueNotifications(ctx, ids, names); err != nil {
+		return err
+	}
+	_, err := d.Flush(ctx)
+	return err
+}
+
+// Flush envía las notificaciones pendientes que se pueden enviar ya y devuelve cuántos
+// envíos hizo. Por cada usuario y canal:
+//   - en horas de silencio no se envía nada;
+//   - si las pendientes caben en lo que queda del límite por hora, se envían una a una;
+//   - si no, se envían todas en un único resumen, siempre que quede al menos un envío.
+func (d *Dispatcher) Flush(ctx context.Context) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	pending, err := d.users.PendingNotifications(ctx)
+	if err != nil || len(pending) == 0 {
+		return 0, err
+	}
+	now := d.clock.Now()
+	sent, err := d.users.SentNotificationBatches(ctx, now.Add(-throttleWindow))
+	if err != nil {
+		return 0, err
+	}
+	limits := config.Current().NotifyMaxPerHour
+
+	deliveries := 0
+	for _, group := range groupPending(pending) {
+		first := group[0]
+		channel, ok := d.channels[first.Channel]
+		if !ok || first.Settings.InQuietHours(now) {
+			continue
+		}
+		budget := -1 // Sin límite
+		if limit := first.Settings.Limit(first.Channel, limits[first.Channel]); limit > 0 {
+			budget = limit - sent[first.UserID][first.Channel]
+			if budget <= 0 {
+				continue // Se enviarán en un resumen cuando quede hueco
+			}
+		}
+
+		if budget < 0 || len(group) <= budget {
+			for _, p := range group {
+				if d.send(ctx, channel, now, []models.PendingNotification{p}, false) {
+					deliveries++
+				}
+			}
+			continue
+		}
+		if d.send(ctx, channel, now, group, true) {
+			deliveries++
+		}
+	}
+	return deliveries, nil
+}
+
+// Run ejecuta Flush cada FlushInterval hasta que se cancela ctx.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := d.clock.NewTicker(FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := d.Flush(ctx); err != nil {
+				log.Printf("ERROR: no se pudieron enviar las notificaciones pendientes: %v", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// send envía pending como un solo envío y lo marca como enviado. Un fallo solo se
+// registra: las notificaciones siguen pendientes y se reintentan en la siguiente vuelta.
+func (d *Dispatcher) send(ctx context.Context, channel Channel, now time.Time, pending []models.PendingNotification, digest bool) bool {
+	n := Notification{UserID: pending[0].UserID, Email: pending[0].Email, Digest: digest}
+	ids := make([]uuid.UUID, len(pending))
+	for i, p := range pending {
+		n.Events = append(n.Events, p.Event)
+		ids[i] = p.ID
+	}
+	if err := channel.Send(ctx, n); err != nil {
+		log.Printf("ERROR: no se pudo enviar la notificación por %s al usuario %s: %v", channel.Name(), n.UserID, err)
+		return false
+	}
+	if err := d.users.MarkNotificationsSent(ctx, ids, uuid.New(), now.UTC()); err != nil {
+		log.Printf("ERROR: notificación enviada por %s al usuario %s pero no marcada: %v", channel.Name(), n.UserID, err)
+	}
+	return true
+}
+
+// groupPending agrupa las notificaciones pendientes por usuario y canal. Llegan ordenadas
+// por usuario y canal, así que basta con cortar en cada cambio.
+func groupPending(pending []models.PendingNotification) [][]models.PendingNotification {
+	var groups [][]models.PendingNotification
+	start := 0
+	for i := 1; i <= len(pending); i++ {
+		if i == len(pending) || pending[i].UserID != pending[start].UserID || pending[i].Channel != pending[start].Channel {
+			groups = append(groups, pending[start:i])
+			start = i
+		}
+	}
+	return groups
+}
@@ -0,0 +1,169 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/clock"
+	"github.com/jannin2/stock-app/backend/config"
+	"github.com/jannin2/stock-app/backend/database"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// outboxUserDB simula la bandeja de salida en memoria.
+type outboxUserDB struct {
+	database.UserDB
+	settings map[uuid.UUID]models.NotificationSettings
+	outbox   []*outboxEntry
+}
+
+type outboxEntry struct {
+	models.PendingNotification
+	sentAt  *time.Time
+	batchID uuid.UUID
+}
+
+func (db *outboxUserDB) EnqueueNotifications(_ context.Context, eventIDs []uuid.UUID, channels []string) (int64, error) {
+	return 0, errors.New("no usado")
+}
+
+func (db *outboxUserDB) PendingNotifications(context.Context) ([]models.PendingNotification, error) {
+	var pending []models.PendingNotification
+	for _, e := range db.outbox {
+		if e.sentAt == nil {
+			p := e.PendingNotification
+			p.Settings = db.settings[p.UserID]
+			pending = append(pending, p)
+		}
+	}
+	return pending, nil
+}
+
+func (db *outboxUserDB) SentNotificationBatches(_ context.Context, since time.Time) (map[uuid.UUID]map[string]int, error) {
+	batches := map[uuid.UUID]map[string]map[uuid.UUID]bool{}
+	for _, e := range db.outbox {
+		if e.sentAt == nil || e.sentAt.Before(since) {
+			continue
+		}
+		if batches[e.UserID] == nil {
+			batches[e.UserID] = map[string]map[uuid.UUID]bool{}
+		}
+		if batches[e.UserID][e.Channel] == nil {
+			batches[e.UserID][e.Channel] = map[uuid.UUID]bool{}
+		}
+		batches[e.UserID][e.Channel][e.batchID] = true
+	}
+	sent := map[uuid.UUID]map[string]int{}
+	for userID, channels := range batches {
+		sent[userID] = map[string]int{}
+		for channel, ids := range channels {
+			sent[userID][channel] = len(ids)
+		}
+	}
+	return sent, nil
+}
+
+func (db *outboxUserDB) MarkNotificationsSent(_ context.Context, ids []uuid.UUID, batchID uuid.UUID, sentAt time.Time) error {
+	for _, id := range ids {
+		for _, e := range db.outbox {
+			if e.ID == id {
+				e.sentAt, e.batchID = &sentAt, batchID
+			}
+		}
+	}
+	return nil
+}
+
+func (db *outboxUserDB) add(userID uuid.UUID, n int) {
+	for i := 0; i < n; i++ {
+		db.outbox = append(db.outbox, &outboxEntry{PendingNotification: models.PendingNotification{
+			ID: uuid.New(), UserID: userID, Email: "ana@example.com", Channel: models.NotificationChannelEmail,
+			Event: models.AlertEvent{ID: uuid.New(), UserID: userID, Ticker: "AAPL"},
+		}})
+	}
+}
+
+// recordingChannel guarda los envíos y puede fallar.
+type recordingChannel struct {
+	sent []Notification
+	err  error
+}
+
+func (c *recordingChannel) Name() string { return models.NotificationChannelEmail }
+
+func (c *recordingChannel) Send(_ context.Context, n Notification) error {
+	if c.err != nil {
+		return c.err
+	}
+	c.sent = append(c.sent, n)
+	return nil
+}
+
+func TestFlush_ThrottlesAndDigests(t *testing.T) {
+	cfg := config.Default()
+	cfg.NotifyMaxPerHour = map[string]int{models.NotificationChannelEmail: 3}
+	config.Set(cfg)
+	t.Cleanup(func() { config.Set(config.Default()) })
+
+	c := clock.NewMock() // 2025-01-06 09:00 UTC
+	userID := uuid.New()
+	db := &outboxUserDB{settings: map[uuid.UUID]models.NotificationSettings{}}
+	channel := &recordingChannel{}
+	d := NewDispatcher(db, c, channel)
+
+	// Caben en el límite: se envían una a una
+	db.add(userID, 2)
+	if n, err := d.Flush(context.Background()); n != 2 || err != nil || len(channel.sent) != 2 || channel.sent[0].Digest {
+		t.Fatalf("❌ Flush = %d, %v; envíos %+v", n, err, channel.sent)
+	}
+
+	// Queda un envío en la hora y hay cinco pendientes: un único resumen
+	db.add(userID, 5)
+	if n, _ := d.Flush(context.Background()); n != 1 || len(channel.sent) != 3 || !channel.sent[2].Digest || len(channel.sent[2].Events) != 5 {
+		t.Fatalf("❌ se esperaba un resumen con 5 alertas: %d envíos, %+v", n, channel.sent)
+	}
+
+	// Límite agotado: esperan hasta que haya hueco en la última hora
+	db.add(userID, 2)
+	if n, _ := d.Flush(context.Background()); n != 0 {
+		t.Errorf("❌ con el límite agotado no debería enviarse nada, hubo %d envíos", n)
+	}
+	c.Add(time.Hour + time.Minute)
+	if n, _ := d.Flush(context.Background()); n != 2 {
+		t.Errorf("❌ pasada la hora deberían enviarse las 2 pendientes, hubo %d envíos", n)
+	}
+
+	// Un fallo del canal deja la notificación pendiente
+	channel.err = errors.New("SMTP caído")
+	db.add(userID, 1)
+	if n, _ := d.Flush(context.Background()); n != 0 {
+		t.Errorf("❌ un envío fallido no debería contarse")
+	}
+	channel.err = nil
+	if n, _ := d.Flush(context.Background()); n != 1 {
+		t.Errorf("❌ la notificación fallida debería reintentarse, hubo %d envíos", n)
+	}
+}
+
+func TestFlush_QuietHours(t *testing.T) {
+	config.Set(config.Default())
+	c := clock.NewMock() // 09:00 UTC
+	userID := uuid.New()
+	db := &outboxUserDB{settings: map[uuid.UUID]models.NotificationSettings{
+		userID: {QuietHoursStart: "08:00", QuietHoursEnd: "09:30"},
+	}}
+	channel := &recordingChannel{}
+	d := NewDispatcher(db, c, channel)
+
+	db.add(userID, 1)
+	if n, _ := d.Flush(context.Background()); n != 0 {
+		t.Errorf("❌ en horas de silencio no debería enviarse nada")
+	}
+	c.Add(30 * time.Minute)
+	if n, _ := d.Flush(context.Background()); n != 1 || len(channel.sent) != 1 {
+		t.Errorf("❌ al terminar las horas de silencio debería enviarse la pendiente, hubo %d envíos", n)
+	}
+}
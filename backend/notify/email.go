@@ -0,0 +1,27 @@
+package notify
+
+import (
+	"context"
+
+	"github.com/jannin2/stock-app/backend/mail"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// EmailChannel envía las notificaciones por e-mail.
+type EmailChannel struct {
+	Mailer mail.Mailer
+}
+
+func (EmailChannel) Name() string { return models.NotificationChannelEmail }
+
+func (c EmailChannel) Send(_ context.Context, n Notification) error {
+	template := mail.TemplateAlert
+	if n.Digest {
+		template = mail.TemplateAlertDigest
+	}
+	msg, err := mail.Render(template, n.Email, mail.AlertData{Events: n.Events, Link: mail.BaseURL() + "/alerts"})
+	if err != nil {
+		return err
+	}
+	return c.Mailer.Send(msg)
+}
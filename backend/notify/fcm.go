@@ -0,0 +1,199 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/config"
+)
+
+const (
+	fcmBaseURL = "https://fcm.googleapis.com"
+	fcmScope   = "https://www.googleapis.com/auth/firebase.messaging"
+)
+
+// FCMSender envía notificaciones a Android con la API HTTP v1 de Firebase Cloud Messaging.
+// Se autentica con una cuenta de servicio: firma un JWT con su clave y lo cambia por un
+// token de acceso OAuth2, que reutiliza hasta poco antes de que caduque.
+type FCMSender struct {
+	projectID   string
+	clientEmail string
+	key         *rsa.PrivateKey
+	tokenURI    string
+	baseURL     string // Sustituible en los tests
+	client      *http.Client
+
+	mu          sync.Mutex
+	accessToken string
+	expiresAt   time.Time
+}
+
+// serviceAccount son los campos que se usan del JSON de una cuenta de servicio de Google.
+type serviceAccount struct {
+	ProjectID   string `json:"project_id"`
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// NewFCMSender crea un FCMSender a partir del JSON de una cuenta de servicio.
+func NewFCMSender(serviceAccountJSON []byte) (*FCMSender, error) {
+	var sa serviceAccount
+	if err := json.Unmarshal(serviceAccountJSON, &sa); err != nil {
+		return nil, fmt.Errorf("cuenta de servicio de FCM inválida: %w", err)
+	}
+	if sa.ProjectID == "" || sa.ClientEmail == "" || sa.TokenURI == "" {
+		return nil, errors.New("a la cuenta de servicio de FCM le faltan project_id, client_email o token_uri")
+	}
+	key, err := parsePKCS8Key([]byte(sa.PrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("cuenta de servicio de FCM: %w", err)
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("la clave de la cuenta de servicio de FCM no es RSA")
+	}
+	return &FCMSender{
+		projectID: sa.ProjectID, clientEmail: sa.ClientEmail, key: rsaKey, tokenURI: sa.TokenURI,
+		baseURL: fcmBaseURL, client: &http.Client{Timeout: pushTimeout},
+	}, nil
+}
+
+// FCMSenderFromEnv crea el FCMSender con la cuenta de servicio del fichero
+// FCM_SERVICE_ACCOUNT_FILE. Devuelve nil si no está configurada.
+func FCMSenderFromEnv() (*FCMSender, error) {
+	path := os.Getenv("FCM_SERVICE_ACCOUNT_FILE")
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error al leer FCM_SERVICE_ACCOUNT_FILE: %w", err)
+	}
+	return NewFCMSender(data)
+}
+
+// fcmError es el cuerpo de error de la API de FCM.
+type fcmError struct {
+	Error struct {
+		Status  string `json:"status"`
+		Message string `json:"message"`
+		Details []struct {
+			ErrorCode string `json:"errorCode"`
+		} `json:"details"`
+	} `json:"error"`
+}
+
+func (s *FCMSender) Send(ctx context.Context, token string, msg PushMessage) (string, error) {
+	accessToken, err := s.token(ctx)
+	if err != nil {
+		return "", err
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"message": map[string]interface{}{
+			"token":        token,
+			"notification": map[string]string{"title": msg.Title, "body": msg.Body},
+			"data":         msg.Data,
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		fmt.Sprintf("%s/v1/projects/%s/messages:send", s.baseURL, url.PathEscape(s.projectID)), bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", config.Current().UserAgent)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error al enviar a FCM: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		var sent struct {
+			Name string `json:"name"` // projects/<id>/messages/<message_id>
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&sent); err != nil {
+			return "", fmt.Errorf("respuesta de FCM inválida: %w", err)
+		}
+		return sent.Name[strings.LastIndex(sent.Name, "/")+1:], nil
+	}
+
+	var fe fcmError
+	_ = json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&fe)
+	if resp.StatusCode == http.StatusUnauthorized {
+		s.mu.Lock()
+		s.accessToken = "" // Se pedirá otro en el siguiente envío
+		s.mu.Unlock()
+	}
+	for _, d := range fe.Error.Details {
+		if d.ErrorCode == "UNREGISTERED" {
+			return "", fmt.Errorf("FCM: %w", ErrInvalidToken)
+		}
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return "", fmt.Errorf("FCM: %w", ErrInvalidToken)
+	}
+	return "", fmt.Errorf("FCM respondió %d %s: %s", resp.StatusCode, fe.Error.Status, fe.Error.Message)
+}
+
+// token devuelve un token de acceso válido, pidiendo uno nuevo si falta o está a punto de
+// caducar.
+func (s *FCMSender) token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.accessToken != "" && time.Now().Before(s.expiresAt.Add(-time.Minute)) {
+		return s.accessToken, nil
+	}
+
+	now := time.Now()
+	assertion, err := signJWT(
+		map[string]interface{}{"alg": "RS256", "typ": "JWT"},
+		map[string]interface{}{
+			"iss": s.clientEmail, "scope": fcmScope, "aud": s.tokenURI,
+			"iat": now.Unix(), "exp": now.Add(time.Hour).Unix(),
+		},
+		signRS256(s.key))
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"}, "assertion": {assertion}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.tokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", config.Current().UserAgent)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error al pedir el token de acceso de FCM: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("error al pedir el token de acceso de FCM: respuesta %d", resp.StatusCode)
+	}
+	var tok struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tok); err != nil || tok.AccessToken == "" {
+		return "", fmt.Errorf("token de acceso de FCM inválido: %v", err)
+	}
+	s.accessToken, s.expiresAt = tok.AccessToken, now.Add(time.Duration(tok.ExpiresIn)*time.Second)
+	return s.accessToken, nil
+}
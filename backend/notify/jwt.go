@@ -0,0 +1,70 @@
+package notify
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+)
+
+// signJWT firma un JWT compacto con la función sign, que recibe el resumen SHA-256 de la
+// cabecera y los claims codificados.
+func signJWT(header, claims map[string]interface{}, sign func(digest []byte) ([]byte, error)) (string, error) {
+	var parts [2]string
+	for i, v := range []interface{}{header, claims} {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		parts[i] = base64.RawURLEncoding.EncodeToString(b)
+	}
+	input := parts[0] + "." + parts[1]
+	digest := sha256.Sum256([]byte(input))
+	sig, err := sign(digest[:])
+	if err != nil {
+		return "", fmt.Errorf("error al firmar el JWT: %w", err)
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// signRS256 firma con RSASSA-PKCS1-v1_5 (cuentas de servicio de Google).
+func signRS256(key *rsa.PrivateKey) func([]byte) ([]byte, error) {
+	return func(digest []byte) ([]byte, error) {
+		return rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest)
+	}
+}
+
+// signES256 firma con ECDSA P-256 (claves .p8 de APNs). JWS exige r||s de 32 bytes cada
+// uno, no la codificación ASN.1 de ecdsa.SignASN1.
+func signES256(key *ecdsa.PrivateKey) func([]byte) ([]byte, error) {
+	return func(digest []byte) ([]byte, error) {
+		r, s, err := ecdsa.Sign(rand.Reader, key, digest)
+		if err != nil {
+			return nil, err
+		}
+		sig := make([]byte, 64)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+		return sig, nil
+	}
+}
+
+// parsePKCS8Key lee una clave privada PKCS#8 en PEM.
+func parsePKCS8Key(pemData []byte) (interface{}, error) {
+	block, _ := pem.Decode(pemData)
+	if block == nil {
+		return nil, errors.New("la clave privada no está en formato PEM")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("clave privada PKCS#8 inválida: %w", err)
+	}
+	return key, nil
+}
@@ -0,0 +1,27 @@
+package notify
+
+import (
+	"context"
+
+	"log"
+
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// LogChannel escribe las notificaciones en el log del servidor. Sirve en desarrollo para
+// ver las alertas disparadas sin configurar e-mail, push ni webhooks.
+type LogChannel struct{}
+
+func (LogChannel) Name() string { return models.NotificationChannelLog }
+
+func (LogChannel) Send(_ context.Context, n Notification) error {
+	kind := "alerta"
+	if n.Digest {
+		kind = "resumen de alertas"
+	}
+	log.Printf("🔔 Notificación (%s) para el usuario %s:", kind, n.UserID)
+	for _, e := range n.Events {
+		log.Printf("   %s %s %s %g: valor %g (%s)", e.Ticker, e.Metric, e.Operator, e.Threshold, e.Value, e.TriggeredAt.Format("2006-01-02 15:04:05Z07:00"))
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/clock"
+	"github.com/jannin2/stock-app/backend/database"
+	"github.com/jannin2/stock-app/backend/mail"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// pushTimeout es el tiempo máximo de cada envío a FCM o APNs.
+const pushTimeout = 10 * time.Second
+
+// ErrInvalidToken indica que el proveedor rechaza el token del dispositivo (app
+// desinstalada, token caducado, ...). El dispositivo se desactiva.
+var ErrInvalidToken = errors.New("token de dispositivo inválido")
+
+// PushMessage es el contenido de una notificación push.
+type PushMessage struct {
+	Title string
+	Body  string
+	Data  map[string]string // Datos para la app, p. ej. el ticker que abrir
+}
+
+// PushSender envía notificaciones push a los dispositivos de una plataforma y devuelve el
+// ID del mensaje asignado por el proveedor.
+type PushSender interface {
+	Send(ctx context.Context, token string, msg PushMessage) (string, error)
+}
+
+// PushChannel envía las notificaciones a los móviles registrados de cada usuario y deja
+// constancia de la entrega a cada dispositivo.
+type PushChannel struct {
+	users   database.UserDB
+	clock   clock.Clock
+	senders map[string]PushSender // Por plataforma
+}
+
+// NewPushChannel crea un PushChannel con un sender por plataforma. Los dispositivos de
+// plataformas sin sender configurado se ignoran.
+func NewPushChannel(users database.UserDB, c clock.Clock, senders map[string]PushSender) *PushChannel {
+	return &PushChannel{users: users, clock: c, senders: senders}
+}
+
+func (*PushChannel) Name() string { return models.NotificationChannelPush }
+
+// Send envía n a todos los dispositivos activos del usuario. Solo devuelve error (y la
+// notificación se reintenta) si no llegó a ninguno y algún fallo fue transitorio: si ya
+// llegó a un dispositivo, reintentar la duplicaría en ese.
+func (c *PushChannel) Send(ctx context.Context, n Notification) error {
+	devices, err := c.users.ListDevices(ctx, n.UserID)
+	if err != nil {
+		return err
+	}
+	msg := pushMessage(n)
+
+	delivered, failed := 0, 0
+	var lastErr error
+	for _, d := range devices {
+		sender, ok := c.senders[d.Platform]
+		if d.DisabledAt != nil || !ok {
+			continue
+		}
+		sendCtx, cancel := context.WithTimeout(ctx, pushTimeout)
+		messageID, err := sender.Send(sendCtx, d.Token, msg)
+		cancel()
+
+		delivery := models.PushDelivery{DeviceID: d.ID, Status: models.PushStatusDelivered, MessageID: messageID,
+			Events: len(n.Events), SentAt: c.clock.Now().UTC()}
+		switch {
+		case errors.Is(err, ErrInvalidToken):
+			delivery.Status, delivery.Error = models.PushStatusInvalidToken, err.Error()
+		case err != nil:
+			delivery.Status, delivery.Error = models.PushStatusFailed, err.Error()
+			failed++
+			lastErr = err
+		default:
+			delivered++
+		}
+		if err := c.users.RecordPushDelivery(ctx, delivery); err != nil {
+			log.Printf("ERROR: no se pudo registrar la entrega push al dispositivo %s: %v", d.ID, err)
+		}
+	}
+	if delivered == 0 && failed > 0 {
+		return fmt.Errorf("no se pudo entregar a ninguno de los %d dispositivos: %w", failed, lastErr)
+	}
+	return nil
+}
+
+// pushMessage resume n en un título y un texto cortos, que es lo que cabe en la pantalla
+// de bloqueo.
+func pushMessage(n Notification) PushMessage {
+	if len(n.Events) == 1 && !n.Digest {
+		e := n.Events[0]
+		return PushMessage{
+			Title: fmt.Sprintf("Alerta de %s", e.Ticker),
+			Body:  fmt.Sprintf("%s %s %.2f (actual: %.2f)", mail.MetricLabel(e.Metric), mail.OperatorLabel(e.Operator), e.Threshold, e.Value),
+			Data:  map[string]string{"ticker": e.Ticker, "alert_id": e.AlertID.String()},
+		}
+	}
+	var tickers []string
+	seen := map[string]bool{}
+	for _, e := range n.Events {
+		if !seen[e.Ticker] {
+			seen[e.Ticker] = true
+			tickers = append(tickers, e.Ticker)
+		}
+	}
+	body := strings.Join(tickers, ", ")
+	if len(tickers) > 5 {
+		body = fmt.Sprintf("%s y %d más", strings.Join(tickers[:5], ", "), len(tickers)-5)
+	}
+	return PushMessage{
+		Title: fmt.Sprintf("%d alertas cumplidas", len(n.Events)),
+		Body:  body,
+		Data:  map[string]string{"digest": "true"},
+	}
+}
+
+// PushSendersFromEnv crea los senders de las plataformas configuradas (FCM_* para
+// Android, APNS_* para iOS). Si no hay ninguna, el mapa está vacío y no se envían push.
+func PushSendersFromEnv() (map[string]PushSender, error) {
+	senders := map[string]PushSender{}
+	fcm, err := FCMSenderFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if fcm != nil {
+		senders[models.PushPlatformAndroid] = fcm
+	}
+	apns, err := APNsSenderFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if apns != nil {
+		senders[models.PushPlatformIOS] = apns
+	}
+	return senders, nil
+}
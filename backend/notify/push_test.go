@@ -0,0 +1,203 @@
+package notify
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/clock"
+	"github.com/jannin2/stock-app/backend/database"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// deviceUserDB devuelve dispositivos fijos y guarda las entregas registradas.
+type deviceUserDB struct {
+	database.UserDB
+	devices    []models.Device
+	deliveries []models.PushDelivery
+}
+
+func (db *deviceUserDB) ListDevices(_ context.Context, userID uuid.UUID) ([]models.Device, error) {
+	return db.devices, nil
+}
+
+func (db *deviceUserDB) RecordPushDelivery(_ context.Context, delivery models.PushDelivery) error {
+	db.deliveries = append(db.deliveries, delivery)
+	return nil
+}
+
+// fakeSender responde según el token: "invalid" y "down" fallan.
+type fakeSender struct {
+	sent []PushMessage
+}
+
+func (s *fakeSender) Send(ctx context.Context, token string, msg PushMessage) (string, error) {
+	switch token {
+	case "invalid":
+		return "", ErrInvalidToken
+	case "down":
+		return "", errors.New("503")
+	}
+	s.sent = append(s.sent, msg)
+	return "msg-" + token, nil
+}
+
+func TestPushChannel_TracksEachDevice(t *testing.T) {
+	disabled := time.Now()
+	db := &deviceUserDB{devices: []models.Device{
+		{ID: uuid.New(), Platform: models.PushPlatformIOS, Token: "ok"},
+		{ID: uuid.New(), Platform: models.PushPlatformAndroid, Token: "invalid"},
+		{ID: uuid.New(), Platform: models.PushPlatformAndroid, Token: "old", DisabledAt: &disabled},
+	}}
+	sender := &fakeSender{}
+	ch := NewPushChannel(db, clock.NewMock(), map[string]PushSender{
+		models.PushPlatformIOS: sender, models.PushPlatformAndroid: sender,
+	})
+
+	n := Notification{Events: []models.AlertEvent{{Ticker: "AAPL", Metric: models.AlertMetricPrice, Operator: models.AlertAbove, Threshold: 200, Value: 201.5}}}
+	if err := ch.Send(context.Background(), n); err != nil {
+		t.Fatalf("❌ error inesperado: %v", err)
+	}
+	if len(sender.sent) != 1 || sender.sent[0].Title != "Alerta de AAPL" || !strings.Contains(sender.sent[0].Body, "201.50") {
+		t.Errorf("❌ mensajes inesperados: %+v", sender.sent)
+	}
+	if len(db.deliveries) != 2 || db.deliveries[0].Status != models.PushStatusDelivered || db.deliveries[0].MessageID != "msg-ok" ||
+		db.deliveries[1].Status != models.PushStatusInvalidToken {
+		t.Errorf("❌ entregas inesperadas (el dispositivo desactivado no debería intentarse): %+v", db.deliveries)
+	}
+
+	// Sin ninguna entrega y con un fallo transitorio se devuelve error para reintentar
+	db.devices = []models.Device{{ID: uuid.New(), Platform: models.PushPlatformIOS, Token: "down"}}
+	if err := ch.Send(context.Background(), n); err == nil {
+		t.Error("❌ se esperaba un error si no se pudo entregar a ningún dispositivo")
+	}
+}
+
+func TestPushMessage_Digest(t *testing.T) {
+	var events []models.AlertEvent
+	for _, ticker := range []string{"AAPL", "MSFT", "AAPL", "NVDA", "TSLA", "AMZN", "META", "GOOG"} {
+		events = append(events, models.AlertEvent{Ticker: ticker})
+	}
+	msg := pushMessage(Notification{Events: events, Digest: true})
+	if msg.Title != "8 alertas cumplidas" || msg.Body != "AAPL, MSFT, NVDA, TSLA, AMZN y 2 más" {
+		t.Errorf("❌ resumen inesperado: %+v", msg)
+	}
+}
+
+func TestFCMSender(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+	tokenRequests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			tokenRequests++
+			if r.FormValue("grant_type") != "urn:ietf:params:oauth:grant-type:jwt-bearer" || r.FormValue("assertion") == "" {
+				http.Error(w, "bad request", http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`{"access_token":"ya29.test","expires_in":3600}`))
+		case "/v1/projects/stock-app/messages:send":
+			var body struct {
+				Message struct {
+					Token string `json:"token"`
+				} `json:"message"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			if r.Header.Get("Authorization") != "Bearer ya29.test" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if body.Message.Token == "gone" {
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{"error":{"status":"NOT_FOUND","details":[{"errorCode":"UNREGISTERED"}]}}`))
+				return
+			}
+			w.Write([]byte(`{"name":"projects/stock-app/messages/0:123"}`))
+		}
+	}))
+	defer srv.Close()
+
+	account, _ := json.Marshal(serviceAccount{ProjectID: "stock-app", ClientEmail: "push@stock-app.iam.gserviceaccount.com",
+		PrivateKey: string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), TokenURI: srv.URL + "/token"})
+	sender, err := NewFCMSender(account)
+	if err != nil {
+		t.Fatalf("❌ error inesperado: %v", err)
+	}
+	sender.baseURL = srv.URL
+
+	for i := 0; i < 2; i++ {
+		if id, err := sender.Send(context.Background(), "tok", PushMessage{Title: "t"}); err != nil || id != "0:123" {
+			t.Fatalf("❌ Send = %q, %v", id, err)
+		}
+	}
+	if tokenRequests != 1 {
+		t.Errorf("❌ el token de acceso debería reutilizarse, se pidió %d veces", tokenRequests)
+	}
+	if _, err := sender.Send(context.Background(), "gone", PushMessage{}); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("❌ se esperaba ErrInvalidToken, se obtuvo %v", err)
+	}
+}
+
+func TestAPNsSender(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		jwt := strings.TrimPrefix(r.Header.Get("Authorization"), "bearer ")
+		parts := strings.Split(jwt, ".")
+		sig, _ := base64.RawURLEncoding.DecodeString(parts[len(parts)-1])
+		digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		if len(parts) != 3 || len(sig) != 64 ||
+			!ecdsa.Verify(&key.PublicKey, digest[:], new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])) {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"reason":"InvalidProviderToken"}`))
+			return
+		}
+		if r.Header.Get("apns-topic") != "com.example.stocks" || r.Header.Get("apns-push-type") != "alert" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"reason":"MissingTopic"}`))
+			return
+		}
+		if r.URL.Path == "/3/device/gone" {
+			w.WriteHeader(http.StatusGone)
+			w.Write([]byte(`{"reason":"Unregistered"}`))
+			return
+		}
+		w.Header().Set("apns-id", "apns-123")
+	}))
+	defer srv.Close()
+
+	sender, err := NewAPNsSender(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), "KEY123", "TEAM456", "com.example.stocks", false)
+	if err != nil {
+		t.Fatalf("❌ error inesperado: %v", err)
+	}
+	sender.baseURL = srv.URL
+
+	if id, err := sender.Send(context.Background(), "tok", PushMessage{Title: "t", Data: map[string]string{"ticker": "AAPL"}}); err != nil || id != "apns-123" {
+		t.Fatalf("❌ Send = %q, %v", id, err)
+	}
+	if _, err := sender.Send(context.Background(), "gone", PushMessage{}); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("❌ se esperaba ErrInvalidToken, se obtuvo %v", err)
+	}
+}
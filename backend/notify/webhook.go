@@ -0,0 +1,77 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jannin2/stock-app/backend/database"
+	"github.com/jannin2/stock-app/backend/models"
+	"github.com/jannin2/stock-app/backend/webhook"
+)
+
+// Tipos de los eventos de alerta que reciben los webhooks de los usuarios.
+const (
+	WebhookEventAlert       = "alert.triggered"
+	WebhookEventAlertDigest = "alert.digest"
+)
+
+// WebhookChannel envía las notificaciones a los webhooks registrados de cada usuario
+// (POST /webhooks), firmadas con el secreto de cada uno.
+type WebhookChannel struct {
+	users  database.UserDB
+	sender *webhook.Sender
+}
+
+// NewWebhookChannel crea un WebhookChannel que entrega con sender.
+func NewWebhookChannel(users database.UserDB, sender *webhook.Sender) *WebhookChannel {
+	return &WebhookChannel{users: users, sender: sender}
+}
+
+// webhookAlertData es el campo data de los eventos de alerta. Los IDs de los eventos
+// permiten al receptor descartar los que ya recibió en un reintento.
+type webhookAlertData struct {
+	Events []models.AlertEvent `json:"events"`
+}
+
+func (*WebhookChannel) Name() string { return models.NotificationChannelWebhook }
+
+// Send entrega n a todos los webhooks del usuario. Como PushChannel, solo devuelve error
+// (y la notificación se reintenta) si no llegó a ninguno.
+func (c *WebhookChannel) Send(ctx context.Context, n Notification) error {
+	hooks, err := c.users.ListWebhooks(ctx, n.UserID)
+	if err != nil {
+		return err
+	}
+	eventType := WebhookEventAlert
+	if n.Digest {
+		eventType = WebhookEventAlertDigest
+	}
+
+	delivered := 0
+	var lastErr error
+	for _, h := range hooks {
+		hook, err := c.users.GetWebhook(ctx, n.UserID, h.ID) // Con el secreto, que ListWebhooks no devuelve
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		delivery := c.sender.Send(ctx, hook.URL, hook.Secret, eventType, webhookAlertData{Events: n.Events})
+		if delivery.OK() {
+			delivered++
+			continue
+		}
+		lastErr = fmt.Errorf("webhook %s: %s", hook.ID, deliveryError(delivery))
+	}
+	if delivered == 0 && lastErr != nil {
+		return lastErr
+	}
+	return nil
+}
+
+// deliveryError describe una entrega fallida.
+func deliveryError(d webhook.Delivery) string {
+	if d.Error != "" {
+		return d.Error
+	}
+	return fmt.Sprintf("respuesta %d", d.StatusCode)
+}
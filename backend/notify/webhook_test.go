@@ -0,0 +1,73 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/clock"
+	"github.com/jannin2/stock-app/backend/database"
+	"github.com/jannin2/stock-app/backend/models"
+	"github.com/jannin2/stock-app/backend/webhook"
+)
+
+// webhookUserDB devuelve webhooks fijos con sus secretos.
+type webhookUserDB struct {
+	database.UserDB
+	hooks []models.Webhook
+}
+
+func (db *webhookUserDB) ListWebhooks(_ context.Context, userID uuid.UUID) ([]models.Webhook, error) {
+	return db.hooks, nil
+}
+
+func (db *webhookUserDB) GetWebhook(_ context.Context, userID, webhookID uuid.UUID) (models.Webhook, error) {
+	for _, h := range db.hooks {
+		if h.ID == webhookID {
+			return h, nil
+		}
+	}
+	return models.Webhook{}, database.ErrWebhookNotFound
+}
+
+func TestWebhookChannel_RetriesOnlyIfNoneDelivered(t *testing.T) {
+	var received []webhook.Event
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/down" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var event webhook.Event
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("❌ cuerpo del webhook inválido: %v", err)
+		}
+		received = append(received, event)
+	}))
+	defer srv.Close()
+
+	up := models.Webhook{ID: uuid.New(), URL: srv.URL + "/up", Secret: "whsec_up"}
+	down := models.Webhook{ID: uuid.New(), URL: srv.URL + "/down", Secret: "whsec_down"}
+	db := &webhookUserDB{hooks: []models.Webhook{down, up}}
+	channel := NewWebhookChannel(db, webhook.NewSenderWithClient(srv.Client(), clock.New()))
+	n := Notification{UserID: uuid.New(), Events: []models.AlertEvent{{ID: uuid.New(), Ticker: "AAPL", Metric: "price", Operator: "above", Threshold: 200, Value: 201}}}
+
+	if err := channel.Send(context.Background(), n); err != nil {
+		t.Fatalf("❌ llegó a un webhook, no debería reintentarse: %v", err)
+	}
+	if len(received) != 1 || received[0].Type != WebhookEventAlert {
+		t.Fatalf("❌ entregas inesperadas: %+v", received)
+	}
+
+	db.hooks = []models.Webhook{down}
+	n.Digest = true
+	if err := channel.Send(context.Background(), n); err == nil {
+		t.Errorf("❌ sin ninguna entrega se esperaba un error para reintentar")
+	}
+	db.hooks = nil
+	if err := channel.Send(context.Background(), n); err != nil {
+		t.Errorf("❌ un usuario sin webhooks no es un fallo: %v", err)
+	}
+}
@@ -0,0 +1,67 @@
+package providers
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/api"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+var (
+	healthMu sync.Mutex
+	health   = map[string]*models.ProviderHealth{}
+	now      = time.Now // Replaced in tests
+)
+
+// RecordCall records the outcome and latency of a call to the named provider, for the
+// health report and the daily stats. api.ErrNoData counts as a success: the provider
+// answered, it just has nothing for that ticker.
+func RecordCall(name string, latency time.Duration, err error) {
+	recordStats(name, latency, err)
+
+	healthMu.Lock()
+	defer healthMu.Unlock()
+
+	h, ok := health[name]
+	if !ok {
+		h = &models.ProviderHealth{Provider: name}
+		health[name] = h
+	}
+	at := now().UTC()
+	if err == nil || errors.Is(err, api.ErrNoData) {
+		h.LastSuccess = &at
+		return
+	}
+	h.LastError = &at
+	h.LastErrorMessage = err.Error()
+}
+
+// HealthReport returns the health of Karenai, every registered provider and any other
+// provider called since startup, sorted by name. Providers not called yet have no
+// timestamps.
+func HealthReport() []models.ProviderHealth {
+	healthMu.Lock()
+	defer healthMu.Unlock()
+
+	names := map[string]bool{Karenai: true}
+	for name := range registry {
+		names[name] = true
+	}
+	for name := range health {
+		names[name] = true
+	}
+
+	report := make([]models.ProviderHealth, 0, len(names))
+	for name := range names {
+		if h, ok := health[name]; ok {
+			report = append(report, *h)
+		} else {
+			report = append(report, models.ProviderHealth{Provider: name})
+		}
+	}
+	sort.Slice(report, func(i, j int) bool { return report[i].Provider < report[j].Provider })
+	return report
+}
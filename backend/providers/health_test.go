@@ -0,0 +1,47 @@
+package providers
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/api"
+	"github.com/jannin2/stock-app/backend/config"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+func TestHealthReport(t *testing.T) {
+	prevHealth, prevNow := health, now
+	health = map[string]*models.ProviderHealth{}
+	clock := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	now = func() time.Time { return clock }
+	t.Cleanup(func() { health, now = prevHealth, prevNow })
+
+	down := &fakeProvider{name: "down", err: errors.New("503 Service Unavailable")}
+	empty := &fakeProvider{name: "empty", err: api.ErrNoData}
+	withProviders(t, map[string][]string{config.DataTypeQuote: {"down", "empty"}}, down, empty, &fakeProvider{name: "idle"})
+
+	FetchQuote("AAPL")
+	clock = clock.Add(time.Minute)
+	RecordCall(Karenai, 0, nil)
+
+	byName := map[string]models.ProviderHealth{}
+	for _, h := range HealthReport() {
+		byName[h.Provider] = h
+	}
+	if len(byName) != 4 {
+		t.Fatalf("expected karenai, down, empty and idle, got %v", byName)
+	}
+	if h := byName["down"]; !h.Failing() || h.LastErrorMessage != "503 Service Unavailable" {
+		t.Errorf("down should be failing: %+v", h)
+	}
+	if h := byName["empty"]; h.Failing() || h.LastSuccess == nil {
+		t.Errorf("a provider without data should count as healthy: %+v", h)
+	}
+	if h := byName["idle"]; h.LastSuccess != nil || h.LastError != nil {
+		t.Errorf("idle was never called: %+v", h)
+	}
+	if h := byName[Karenai]; h.LastSuccess == nil || !h.LastSuccess.Equal(clock) {
+		t.Errorf("unexpected karenai health: %+v", h)
+	}
+}
@@ -0,0 +1,880 @@
+// Package providers puts the external market data APIs behind small per-data-type
+// interfaces and resolves each data type through a configurable priority chain
+// (config.ProviderChains), falling back to the next provider when one has no data.
+//
+// A new data source (Polygon, Tiingo, Yahoo...) implements the interfaces of the data it
+// offers, usually MarketDataProvider, and is added with Register at startup; listing its
+// name in the chains is then enough for the enricher to use it.
+package providers
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/api"
+	"github.com/jannin2/stock-app/backend/config"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// Provider names, as used in the configured chains and in stock provenance.
+const (
+	Finnhub      = "finnhub"
+	AlphaVantage = "alphavantage"
+	Karenai      = "karenai" // Source of the analyst recommendations; not part of any chain
+)
+
+// Quote is a price snapshot for a ticker.
+type Quote struct {
+	Price            float64
+	PreviousClose    float64 // 0 when unknown
+	LatestTradingDay time.Time
+
+	// The open, high, low and volume of LatestTradingDay so far; 0 when unknown (Finnhub
+	// has no volume).
+	Open, High, Low float64
+	Volume          int64
+}
+
+// Fundamentals are valuation metrics for a ticker. Units follow Finnhub: dividend yield
+// in percent and market capitalization in millions of USD.
+type Fundamentals struct {
+	PERatio              float64
+	DividendYield        float64
+	MarketCapitalization float64
+}
+
+// Mentions is the number of social media posts that mentioned a ticker on one day.
+type Mentions struct {
+	Day   time.Time // UTC midnight
+	Count int
+}
+
+// ESG are environmental, social and governance ratings, from 0 to 100.
+type ESG struct {
+	Total         float64
+	Environmental float64
+	Social        float64
+	Governance    float64
+}
+
+// ShortInterest is the latest short interest report of a ticker.
+type ShortInterest struct {
+	Shares         float64   // Shares sold short
+	DaysToCover    float64   // Shares over the average daily volume; 0 when the volume is unknown
+	SettlementDate time.Time // Date the report refers to
+}
+
+// OptionsExpiration is the activity of the options of a ticker that expire on one date.
+type OptionsExpiration struct {
+	Date              time.Time
+	ImpliedVolatility float64 // In percent; 0 when unknown
+	PutVolume         float64
+	CallVolume        float64
+	PutOpenInterest   float64
+	CallOpenInterest  float64
+}
+
+// CorporateAction is a split, merger or symbol change of a ticker. Type is one of the
+// models.CorporateAction* constants.
+type CorporateAction struct {
+	Type      string
+	Date      time.Time // Effective date
+	SplitFrom float64   // Splits only
+	SplitTo   float64   // Splits only
+	NewTicker string    // Symbol changes, and mergers into a listed company
+}
+
+// IPO is an entry of the IPO calendar. Status is one of the models.IPOStatus* constants.
+type IPO struct {
+	Symbol    string // Empty until assigned
+	Company   string
+	Exchange  string
+	Date      time.Time
+	PriceLow  float64 // 0 when unknown
+	PriceHigh float64 // 0 when unknown
+	Shares    float64 // 0 when unknown
+	Status    string
+}
+
+// MacroEvent is a release of the economic calendar. Impact is one of the
+// models.MacroImpact* constants.
+type MacroEvent struct {
+	Country  string
+	Event    string
+	Impact   string
+	Time     time.Time
+	Actual   models.NullFloat64 // Null until released
+	Estimate models.NullFloat64
+	Previous models.NullFloat64
+	Unit     string
+}
+
+// Provider is implemented by every data source.
+type Provider interface {
+	Name() string
+}
+
+// QuoteProvider supplies quotes.
+type QuoteProvider interface {
+	Provider
+	Quote(ticker string) (Quote, error)
+}
+
+// QuoteBatchSize is the most tickers FetchQuotes asks a BatchQuoteProvider for in one call.
+const QuoteBatchSize = 50
+
+// BatchQuoteProvider is a QuoteProvider with a multi-symbol quote endpoint. Quotes is
+// called with at most QuoteBatchSize symbols and returns the quotes it found by symbol;
+// symbols missing from the result have no data. A provider whose plan lacks the endpoint
+// returns an error wrapping api.ErrUnauthorized, and its symbols are then quoted one by one.
+type BatchQuoteProvider interface {
+	QuoteProvider
+	Quotes(symbols []string) (map[string]Quote, error)
+}
+
+// FundamentalsProvider supplies fundamentals.
+type FundamentalsProvider interface {
+	Provider
+	Fundamentals(ticker string) (Fundamentals, error)
+}
+
+// AlphaProvider supplies a stock's alpha: its return in excess of what its market risk
+// explains.
+type AlphaProvider interface {
+	Provider
+	Alpha(ticker string) (float64, error)
+}
+
+// MarketDataProvider is a source of the core market data of the enrichment: quotes,
+// fundamentals and alpha.
+type MarketDataProvider interface {
+	QuoteProvider
+	FundamentalsProvider
+	AlphaProvider
+}
+
+// MentionsProvider supplies social media mention counts. Mentions returns the count for
+// the UTC day containing day; a ticker nobody talked about has a count of 0, not an error.
+type MentionsProvider interface {
+	Provider
+	Mentions(ticker string, day time.Time) (Mentions, error)
+}
+
+// ESGProvider supplies ESG ratings.
+type ESGProvider interface {
+	Provider
+	ESG(ticker string) (ESG, error)
+}
+
+// ShortInterestProvider supplies short interest. ShortInterest returns the latest report
+// settled on or before asOf.
+type ShortInterestProvider interface {
+	Provider
+	ShortInterest(ticker string, asOf time.Time) (ShortInterest, error)
+}
+
+// OptionsProvider supplies the options chain, summarized by expiration date.
+type OptionsProvider interface {
+	Provider
+	Options(ticker string) ([]OptionsExpiration, error)
+}
+
+// SymbolMapper is implemented by providers whose symbols for non-US listings differ from
+// the app's tickers ("SAP.DE"). Symbol returns the provider's symbol for a ticker of ex, or
+// false when the provider does not cover the exchange. Providers without it are called
+// with the ticker as is.
+type SymbolMapper interface {
+	Symbol(ticker string, ex models.Exchange) (string, bool)
+}
+
+// Symbol returns the symbol the named provider uses for ticker, and false when it does
+// not cover the ticker's exchange. It is for callers that use a provider directly instead
+// of through a chain.
+func Symbol(provider, ticker string) (string, bool) {
+	p, ok := registry[provider]
+	if !ok {
+		return ticker, true
+	}
+	return symbolFor(p, ticker)
+}
+
+func symbolFor(p Provider, ticker string) (string, bool) {
+	m, ok := p.(SymbolMapper)
+	if !ok {
+		return ticker, true
+	}
+	ex, err := models.ExchangeForTicker(ticker)
+	if err != nil {
+		return "", false
+	}
+	return m.Symbol(ticker, ex)
+}
+
+// CorporateActionsProvider supplies corporate actions effective between from and to,
+// both dates included.
+type CorporateActionsProvider interface {
+	Provider
+	CorporateActions(ticker string, from, to time.Time) ([]CorporateAction, error)
+}
+
+// IPOProvider supplies the market-wide IPO calendar for dates between from and to, both
+// included.
+type IPOProvider interface {
+	Provider
+	IPOs(from, to time.Time) ([]IPO, error)
+}
+
+// MacroProvider supplies the economic calendar for dates between from and to, both
+// included.
+type MacroProvider interface {
+	Provider
+	MacroEvents(from, to time.Time) ([]MacroEvent, error)
+}
+
+// registry holds the available providers by name.
+var registry = map[string]Provider{
+	Finnhub:      finnhubProvider{},
+	AlphaVantage: alphaVantageProvider{},
+}
+
+// Register adds or replaces a provider. It is called at startup, before ValidateChains,
+// or with a fake in tests.
+func Register(p Provider) {
+	registry[p.Name()] = p
+}
+
+// implements reports whether p implements the data type interface I.
+func implements[I any](p Provider) bool {
+	_, ok := p.(I)
+	return ok
+}
+
+// dataTypeSupport tells whether a provider supplies each data type of the chains.
+var dataTypeSupport = map[string]func(Provider) bool{
+	config.DataTypeQuote:            implements[QuoteProvider],
+	config.DataTypeFundamentals:     implements[FundamentalsProvider],
+	config.DataTypeAlpha:            implements[AlphaProvider],
+	config.DataTypeMentions:         implements[MentionsProvider],
+	config.DataTypeESG:              implements[ESGProvider],
+	config.DataTypeShortInterest:    implements[ShortInterestProvider],
+	config.DataTypeOptions:          implements[OptionsProvider],
+	config.DataTypeCorporateActions: implements[CorporateActionsProvider],
+	config.DataTypeIPOs:             implements[IPOProvider],
+	config.DataTypeMacro:            implements[MacroProvider],
+}
+
+// ValidateChains checks that every provider named in chains is registered and supplies
+// the data type of its chain. fetch skips such names, so without this check a typo in a
+// PROVIDER_CHAIN_* variable would silently leave a data type without a source.
+func ValidateChains(chains map[string][]string) error {
+	dataTypes := make([]string, 0, len(chains))
+	for dataType := range chains {
+		dataTypes = append(dataTypes, dataType)
+	}
+	sort.Strings(dataTypes)
+
+	for _, dataType := range dataTypes {
+		supports, known := dataTypeSupport[dataType]
+		if !known {
+			return fmt.Errorf("unknown data type %q", dataType)
+		}
+		for _, name := range chains[dataType] {
+			p, ok := registry[name]
+			if !ok {
+				return fmt.Errorf("%s chain: unknown provider %q (registered: %s)", dataType, name, strings.Join(registeredNames(), ", "))
+			}
+			if !supports(p) {
+				return fmt.Errorf("%s chain: provider %q does not supply %s data", dataType, name, dataType)
+			}
+		}
+	}
+	return nil
+}
+
+// registeredNames returns the names of the registered providers, sorted.
+func registeredNames() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Failure is a provider that was tried for a data type and returned an error.
+type Failure struct {
+	Provider string
+	Err      error
+}
+
+func (f Failure) Error() string {
+	return f.Provider + ": " + f.Err.Error()
+}
+
+// ChainError is returned when every provider in a chain failed.
+type ChainError struct {
+	DataType string
+	Failures []Failure // In chain order
+}
+
+func (e *ChainError) Error() string {
+	parts := make([]string, 0, len(e.Failures))
+	for _, f := range e.Failures {
+		parts = append(parts, f.Error())
+	}
+	return fmt.Sprintf("no provider returned %s data (%s)", e.DataType, strings.Join(parts, "; "))
+}
+
+// FetchQuote walks the configured quote chain and returns the first quote obtained and
+// the name of the provider that supplied it. Providers that failed before it are returned
+// in failures; when all of them fail err is a *ChainError.
+func FetchQuote(ticker string) (quote Quote, source string, failures []Failure, err error) {
+	return fetch(config.DataTypeQuote, ticker, func(p Provider, symbol string) (Quote, bool, error) {
+		qp, ok := p.(QuoteProvider)
+		if !ok {
+			return Quote{}, false, nil
+		}
+		q, err := qp.Quote(symbol)
+		return q, true, err
+	})
+}
+
+// QuoteResult is the outcome of the quote chain for one ticker of FetchQuotes, with the
+// values FetchQuote would have returned for it.
+type QuoteResult struct {
+	Quote    Quote
+	Source   string
+	Failures []Failure
+	Err      error
+}
+
+// HasBatchQuotes reports whether a provider of the configured quote chain implements
+// BatchQuoteProvider, that is, whether FetchQuotes saves calls over FetchQuote.
+func HasBatchQuotes() bool {
+	for _, name := range config.Current().ProviderChains[config.DataTypeQuote] {
+		if implements[BatchQuoteProvider](registry[name]) {
+			return true
+		}
+	}
+	return false
+}
+
+// FetchQuotes walks the configured quote chain for many tickers at once, returning the
+// result of each ticker. Every provider is asked only for the tickers the ones before it
+// could not quote; a BatchQuoteProvider gets them in chunks of QuoteBatchSize, so N
+// tickers cost N/QuoteBatchSize calls instead of N, and the others are called per ticker.
+func FetchQuotes(tickers []string) map[string]QuoteResult {
+	results := make(map[string]QuoteResult, len(tickers))
+	pending := make([]string, 0, len(tickers))
+	for _, ticker := range tickers {
+		if _, dup := results[ticker]; !dup {
+			results[ticker] = QuoteResult{}
+			pending = append(pending, ticker)
+		}
+	}
+
+	for _, name := range config.Current().ProviderChains[config.DataTypeQuote] {
+		qp, ok := registry[name].(QuoteProvider)
+		if !ok || len(pending) == 0 {
+			continue
+		}
+		// Tickers on exchanges the provider does not cover wait for the next one.
+		var covered, symbols, next []string
+		for _, ticker := range pending {
+			if symbol, ok := symbolFor(qp, ticker); ok {
+				covered, symbols = append(covered, ticker), append(symbols, symbol)
+			} else {
+				next = append(next, ticker)
+			}
+		}
+
+		quotes, errs := quoteAll(qp, symbols)
+		for i, ticker := range covered {
+			res := results[ticker]
+			if errs[i] == nil {
+				res.Quote, res.Source = quotes[i], name
+			} else {
+				res.Failures = append(res.Failures, Failure{Provider: name, Err: errs[i]})
+				next = append(next, ticker)
+			}
+			results[ticker] = res
+		}
+		pending = next
+	}
+
+	for _, ticker := range pending {
+		res := results[ticker]
+		if len(res.Failures) == 0 {
+			res.Err = fmt.Errorf("no provider configured for %s data", config.DataTypeQuote)
+		} else {
+			res.Err = &ChainError{DataType: config.DataTypeQuote, Failures: res.Failures}
+		}
+		results[ticker] = res
+	}
+	return results
+}
+
+// quoteAll asks qp for the quote of each symbol, in chunks of QuoteBatchSize when it is a
+// BatchQuoteProvider. It returns the quote and the error of each symbol, in order.
+func quoteAll(qp QuoteProvider, symbols []string) ([]Quote, []error) {
+	quotes := make([]Quote, len(symbols))
+	errs := make([]error, len(symbols))
+	quoteEach := func(from, to int) {
+		for i := from; i < to; i++ {
+			start := now()
+			quotes[i], errs[i] = qp.Quote(symbols[i])
+			RecordCall(qp.Name(), now().Sub(start), errs[i])
+		}
+	}
+
+	bp, ok := qp.(BatchQuoteProvider)
+	if !ok {
+		quoteEach(0, len(symbols))
+		return quotes, errs
+	}
+	for from := 0; from < len(symbols); from += QuoteBatchSize {
+		to := min(from+QuoteBatchSize, len(symbols))
+		start := now()
+		batch, err := bp.Quotes(symbols[from:to])
+		RecordCall(qp.Name(), now().Sub(start), err)
+		if errors.Is(err, api.ErrUnauthorized) {
+			quoteEach(from, to)
+			continue
+		}
+		for i := from; i < to; i++ {
+			q, found := batch[symbols[i]]
+			switch {
+			case err != nil:
+				errs[i] = err
+			case !found:
+				errs[i] = fmt.Errorf("%s returned no quote for %s: %w", qp.Name(), symbols[i], api.ErrNoData)
+			default:
+				quotes[i] = q
+			}
+		}
+	}
+	return quotes, errs
+}
+
+// FetchFundamentals walks the configured fundamentals chain, like FetchQuote.
+func FetchFundamentals(ticker string) (fundamentals Fundamentals, source string, failures []Failure, err error) {
+	return fetch(config.DataTypeFundamentals, ticker, func(p Provider, symbol string) (Fundamentals, bool, error) {
+		fp, ok := p.(FundamentalsProvider)
+		if !ok {
+			return Fundamentals{}, false, nil
+		}
+		f, err := fp.Fundamentals(symbol)
+		return f, true, err
+	})
+}
+
+// FetchAlpha walks the configured alpha chain, like FetchQuote.
+func FetchAlpha(ticker string) (alpha float64, source string, failures []Failure, err error) {
+	return fetch(config.DataTypeAlpha, ticker, func(p Provider, symbol string) (float64, bool, error) {
+		ap, ok := p.(AlphaProvider)
+		if !ok {
+			return 0, false, nil
+		}
+		a, err := ap.Alpha(symbol)
+		return a, true, err
+	})
+}
+
+// FetchMentions walks the configured mentions chain, like FetchQuote.
+func FetchMentions(ticker string, day time.Time) (mentions Mentions, source string, failures []Failure, err error) {
+	return fetch(config.DataTypeMentions, ticker, func(p Provider, symbol string) (Mentions, bool, error) {
+		mp, ok := p.(MentionsProvider)
+		if !ok {
+			return Mentions{}, false, nil
+		}
+		m, err := mp.Mentions(symbol, day)
+		return m, true, err
+	})
+}
+
+// FetchESG walks the configured ESG chain, like FetchQuote.
+func FetchESG(ticker string) (esg ESG, source string, failures []Failure, err error) {
+	return fetch(config.DataTypeESG, ticker, func(p Provider, symbol string) (ESG, bool, error) {
+		ep, ok := p.(ESGProvider)
+		if !ok {
+			return ESG{}, false, nil
+		}
+		e, err := ep.ESG(symbol)
+		return e, true, err
+	})
+}
+
+// FetchShortInterest walks the configured short interest chain, like FetchQuote.
+func FetchShortInterest(ticker string, asOf time.Time) (short ShortInterest, source string, failures []Failure, err error) {
+	return fetch(config.DataTypeShortInterest, ticker, func(p Provider, symbol string) (ShortInterest, bool, error) {
+		sp, ok := p.(ShortInterestProvider)
+		if !ok {
+			return ShortInterest{}, false, nil
+		}
+		s, err := sp.ShortInterest(symbol, asOf)
+		return s, true, err
+	})
+}
+
+// FetchOptions walks the configured options chain, like FetchQuote.
+func FetchOptions(ticker string) (expirations []OptionsExpiration, source string, failures []Failure, err error) {
+	return fetch(config.DataTypeOptions, ticker, func(p Provider, symbol string) ([]OptionsExpiration, bool, error) {
+		op, ok := p.(OptionsProvider)
+		if !ok {
+			return nil, false, nil
+		}
+		e, err := op.Options(symbol)
+		return e, true, err
+	})
+}
+
+// FetchCorporateActions walks the configured corporate actions chain, like FetchQuote.
+func FetchCorporateActions(ticker string, from, to time.Time) (actions []CorporateAction, source string, failures []Failure, err error) {
+	return fetch(config.DataTypeCorporateActions, ticker, func(p Provider, symbol string) ([]CorporateAction, bool, error) {
+		cp, ok := p.(CorporateActionsProvider)
+		if !ok {
+			return nil, false, nil
+		}
+		a, err := cp.CorporateActions(symbol, from, to)
+		return a, true, err
+	})
+}
+
+// FetchIPOs walks the configured IPO chain, like FetchQuote. The calendar is not per
+// ticker, so every provider is asked regardless of the exchanges it covers.
+func FetchIPOs(from, to time.Time) (ipos []IPO, source string, failures []Failure, err error) {
+	return fetch(config.DataTypeIPOs, "", func(p Provider, _ string) ([]IPO, bool, error) {
+		ip, ok := p.(IPOProvider)
+		if !ok {
+			return nil, false, nil
+		}
+		i, err := ip.IPOs(from, to)
+		return i, true, err
+	})
+}
+
+// FetchMacroEvents walks the configured macro chain, like FetchIPOs.
+func FetchMacroEvents(from, to time.Time) (events []MacroEvent, source string, failures []Failure, err error) {
+	return fetch(config.DataTypeMacro, "", func(p Provider, _ string) ([]MacroEvent, bool, error) {
+		mp, ok := p.(MacroProvider)
+		if !ok {
+			return nil, false, nil
+		}
+		e, err := mp.MacroEvents(from, to)
+		return e, true, err
+	})
+}
+
+// fetch tries each provider of the data type's chain in order, passing call the ticker in
+// the provider's format. call reports false when a provider does not support the data type,
+// in which case it is skipped, as are names missing from the registry and providers that do
+// not cover the ticker's exchange. Market-wide data types pass an empty ticker.
+func fetch[T any](dataType, ticker string, call func(p Provider, symbol string) (T, bool, error)) (T, string, []Failure, error) {
+	var zero T
+	var failures []Failure
+
+	for _, name := range config.Current().ProviderChains[dataType] {
+		p, ok := registry[name]
+		if !ok {
+			continue
+		}
+		symbol, ok := ticker, true
+		if ticker != "" {
+			symbol, ok = symbolFor(p, ticker)
+		}
+		if !ok {
+			continue
+		}
+		start := now()
+		value, supported, err := call(p, symbol)
+		if !supported {
+			continue
+		}
+		RecordCall(name, now().Sub(start), err)
+		if err == nil {
+			return value, name, failures, nil
+		}
+		failures = append(failures, Failure{Provider: name, Err: err})
+	}
+
+	if len(failures) == 0 {
+		return zero, "", nil, fmt.Errorf("no provider configured for %s data", dataType)
+	}
+	return zero, "", failures, &ChainError{DataType: dataType, Failures: failures}
+}
+
+type finnhubProvider struct{}
+
+func (finnhubProvider) Name() string { return Finnhub }
+
+func (finnhubProvider) Quote(ticker string) (Quote, error) {
+	data, err := api.GetFinnhubQuote(ticker)
+	if err != nil {
+		return Quote{}, err
+	}
+	return Quote{
+		Price: data.CurrentPrice, PreviousClose: data.PreviousClose, LatestTradingDay: data.LatestTradingDay,
+		Open: data.Open, High: data.High, Low: data.Low,
+	}, nil
+}
+
+func (finnhubProvider) Fundamentals(ticker string) (Fundamentals, error) {
+	data, err := api.GetFinnhubMetrics(ticker)
+	if err != nil {
+		return Fundamentals{}, err
+	}
+	return Fundamentals{PERatio: data.PE_Ratio, DividendYield: data.DividendYield, MarketCapitalization: data.MarketCapitalization}, nil
+}
+
+// Mentions adds up the hourly Reddit and Twitter mentions of the day.
+func (finnhubProvider) Mentions(ticker string, day time.Time) (Mentions, error) {
+	day = day.UTC().Truncate(24 * time.Hour)
+	data, err := api.GetFinnhubSocialMentions(ticker, day, day)
+	if err != nil {
+		return Mentions{}, err
+	}
+	m := Mentions{Day: day}
+	for _, hour := range append(data.Reddit, data.Twitter...) {
+		m.Count += hour.Mention
+	}
+	return m, nil
+}
+
+func (finnhubProvider) ESG(ticker string) (ESG, error) {
+	data, err := api.GetFinnhubESG(ticker)
+	if err != nil {
+		return ESG{}, err
+	}
+	return ESG{Total: data.TotalESGScore, Environmental: data.EnvironmentScore, Social: data.SocialScore, Governance: data.GovernanceScore}, nil
+}
+
+// shortInterestLookback covers at least two reports: FINRA publishes them twice a month.
+const shortInterestLookback = 45 * 24 * time.Hour
+
+// ShortInterest takes the latest report of the lookback window and the 10-day average
+// volume from the metrics endpoint for days to cover.
+func (finnhubProvider) ShortInterest(ticker string, asOf time.Time) (ShortInterest, error) {
+	data, err := api.GetFinnhubShortInterest(ticker, asOf.Add(-shortInterestLookback), asOf)
+	if err != nil {
+		return ShortInterest{}, err
+	}
+	var latest ShortInterest
+	for _, report := range data.Data {
+		date, err := time.Parse("2006-01-02", report.Date)
+		if err != nil || date.Before(latest.SettlementDate) {
+			continue
+		}
+		latest = ShortInterest{Shares: report.ShortInterest, SettlementDate: date}
+	}
+	if latest.SettlementDate.IsZero() {
+		return ShortInterest{}, fmt.Errorf("Finnhub returned no short interest for %s: %w", ticker, api.ErrNoData)
+	}
+
+	// Without the volume the report is still useful; days to cover stays unknown.
+	if metrics, err := api.GetFinnhubMetrics(ticker); err == nil && metrics.AverageVolume > 0 {
+		latest.DaysToCover = latest.Shares / (metrics.AverageVolume * 1e6)
+	}
+	return latest, nil
+}
+
+func (finnhubProvider) Options(ticker string) ([]OptionsExpiration, error) {
+	data, err := api.GetFinnhubOptionChain(ticker)
+	if err != nil {
+		return nil, err
+	}
+	expirations := make([]OptionsExpiration, 0, len(data.Data))
+	for _, e := range data.Data {
+		date, err := time.Parse("2006-01-02", e.ExpirationDate)
+		if err != nil {
+			continue
+		}
+		expirations = append(expirations, OptionsExpiration{Date: date, ImpliedVolatility: e.ImpliedVolatility,
+			PutVolume: e.PutVolume, CallVolume: e.CallVolume, PutOpenInterest: e.PutOpenInterest, CallOpenInterest: e.CallOpenInterest})
+	}
+	if len(expirations) == 0 {
+		return nil, fmt.Errorf("Finnhub returned no options for %s: %w", ticker, api.ErrNoData)
+	}
+	return expirations, nil
+}
+
+// CorporateActions combines the ticker's splits with the symbol changes that renamed it.
+// Finnhub does not report mergers. A ticker without actions has an empty list, not an error.
+func (finnhubProvider) CorporateActions(ticker string, from, to time.Time) ([]CorporateAction, error) {
+	splits, err := api.GetFinnhubSplits(ticker, from, to)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := finnhubSymbolChanges.get(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	actions := []CorporateAction{}
+	for _, s := range splits {
+		date, err := time.Parse("2006-01-02", s.Date)
+		if err != nil || s.FromFactor <= 0 || s.ToFactor <= 0 {
+			continue
+		}
+		actions = append(actions, CorporateAction{Type: models.CorporateActionSplit, Date: date, SplitFrom: s.FromFactor, SplitTo: s.ToFactor})
+	}
+	for _, c := range changes.Data {
+		date, err := time.Parse("2006-01-02", c.AtDate)
+		if err != nil || !strings.EqualFold(c.OldSymbol, ticker) || c.NewSymbol == "" {
+			continue
+		}
+		actions = append(actions, CorporateAction{Type: models.CorporateActionSymbolChange, Date: date, NewTicker: strings.ToUpper(c.NewSymbol)})
+	}
+	return actions, nil
+}
+
+// symbolChangeCache keeps the last market-wide symbol change list. The list is the same for
+// every ticker, so one enrichment run downloads it once instead of once per stock.
+type symbolChangeCache struct {
+	mu       sync.Mutex
+	from, to time.Time
+	changes  api.FinnhubSymbolChangeResponse
+}
+
+var finnhubSymbolChanges = &symbolChangeCache{}
+
+func (c *symbolChangeCache) get(from, to time.Time) (api.FinnhubSymbolChangeResponse, error) {
+	from, to = from.UTC().Truncate(24*time.Hour), to.UTC().Truncate(24*time.Hour)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.from.Equal(from) && c.to.Equal(to) {
+		return c.changes, nil
+	}
+	changes, err := api.GetFinnhubSymbolChanges(from, to)
+	if err != nil {
+		return api.FinnhubSymbolChangeResponse{}, err
+	}
+	c.from, c.to, c.changes = from, to, changes
+	return changes, nil
+}
+
+// IPOs maps Finnhub's calendar. Entries without a company name are dropped.
+func (finnhubProvider) IPOs(from, to time.Time) ([]IPO, error) {
+	data, err := api.GetFinnhubIPOCalendar(from, to)
+	if err != nil {
+		return nil, err
+	}
+	ipos := make([]IPO, 0, len(data.IPOCalendar))
+	for _, e := range data.IPOCalendar {
+		date, err := time.Parse("2006-01-02", e.Date)
+		if err != nil || e.Name == "" {
+			continue
+		}
+		low, high := parsePriceRange(e.Price)
+		ipos = append(ipos, IPO{Symbol: strings.ToUpper(e.Symbol), Company: e.Name, Exchange: e.Exchange, Date: date,
+			PriceLow: low, PriceHigh: high, Shares: e.NumberOfShares, Status: strings.ToLower(e.Status)})
+	}
+	return ipos, nil
+}
+
+// MacroEvents maps Finnhub's economic calendar. Entries without a name or a valid time
+// are dropped.
+func (finnhubProvider) MacroEvents(from, to time.Time) ([]MacroEvent, error) {
+	data, err := api.GetFinnhubEconomicCalendar(from, to)
+	if err != nil {
+		return nil, err
+	}
+	events := make([]MacroEvent, 0, len(data.EconomicCalendar))
+	for _, e := range data.EconomicCalendar {
+		at, err := time.Parse("2006-01-02 15:04:05", e.Time)
+		if err != nil || e.Event == "" {
+			continue
+		}
+		events = append(events, MacroEvent{Country: strings.ToUpper(e.Country), Event: e.Event,
+			Impact: strings.ToLower(e.Impact), Time: at, Actual: e.Actual, Estimate: e.Estimate, Previous: e.Prev, Unit: e.Unit})
+	}
+	return events, nil
+}
+
+// parsePriceRange reads "14.00-16.00" or "15.00". Unparsable prices are 0.
+func parsePriceRange(price string) (low, high float64) {
+	lowStr, highStr, isRange := strings.Cut(price, "-")
+	low, _ = strconv.ParseFloat(strings.TrimSpace(lowStr), 64)
+	high = low
+	if isRange {
+		high, _ = strconv.ParseFloat(strings.TrimSpace(highStr), 64)
+	}
+	return low, high
+}
+
+type alphaVantageProvider struct{}
+
+func (alphaVantageProvider) Name() string { return AlphaVantage }
+
+// alphaVantageSuffixes are the Alpha Vantage suffixes of the non-US exchanges it covers.
+var alphaVantageSuffixes = map[string]string{
+	"TSX":   "TRT",
+	"LSE":   "LON",
+	"XETRA": "DEX",
+}
+
+// Symbol maps "SAP.DE" to Alpha Vantage's "SAP.DEX".
+func (alphaVantageProvider) Symbol(ticker string, ex models.Exchange) (string, bool) {
+	if ex.Code == models.ExchangeUS {
+		return ticker, true
+	}
+	suffix, ok := alphaVantageSuffixes[ex.Code]
+	if !ok {
+		return "", false
+	}
+	return ex.BaseSymbol(ticker) + "." + suffix, true
+}
+
+func (alphaVantageProvider) Quote(ticker string) (Quote, error) {
+	data, err := api.GetAlphaAndLatestTradingDayFromAlphaVantage(ticker)
+	if err != nil {
+		return Quote{}, err
+	}
+	if data.Price == 0 {
+		return Quote{}, fmt.Errorf("Alpha Vantage returned no quote for %s: %w", ticker, api.ErrNoData)
+	}
+	return alphaVantageQuote(data), nil
+}
+
+// Quotes uses the REALTIME_BULK_QUOTES function, which needs a premium key.
+func (alphaVantageProvider) Quotes(symbols []string) (map[string]Quote, error) {
+	data, err := api.GetAlphaVantageBulkQuotes(symbols)
+	if err != nil {
+		return nil, err
+	}
+	quotes := make(map[string]Quote, len(data))
+	for _, symbol := range symbols {
+		if q, ok := data[strings.ToUpper(symbol)]; ok {
+			quotes[symbol] = alphaVantageQuote(q)
+		}
+	}
+	return quotes, nil
+}
+
+func alphaVantageQuote(data api.AlphaVantageData) Quote {
+	return Quote{
+		Price: data.Price, PreviousClose: data.PreviousClose, LatestTradingDay: data.LatestTradingDay,
+		Open: data.Open, High: data.High, Low: data.Low, Volume: data.Volume,
+	}
+}
+
+// Alpha reads the alpha that comes with the Alpha Vantage quote.
+func (alphaVantageProvider) Alpha(ticker string) (float64, error) {
+	data, err := api.GetAlphaAndLatestTradingDayFromAlphaVantage(ticker)
+	if err != nil {
+		return 0, err
+	}
+	return data.Alpha, nil
+}
+
+func (alphaVantageProvider) Fundamentals(ticker string) (Fundamentals, error) {
+	data, err := api.GetAlphaVantageOverview(ticker)
+	if err != nil {
+		return Fundamentals{}, err
+	}
+	return Fundamentals{PERatio: data.PERatio, DividendYield: data.DividendYield, MarketCapitalization: data.MarketCapitalization}, nil
+}
@@ -0,0 +1,301 @@
+package providers
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jannin2/stock-app/backend/api"
+	"github.com/jannin2/stock-app/backend/config"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// fakeProvider supplies fixed data, or err when set.
+type fakeProvider struct {
+	name         string
+	quote        Quote
+	fundamentals Fundamentals
+	alpha        float64
+	err          error
+	calls        int
+}
+
+func (f *fakeProvider) Name() string { return f.name }
+
+func (f *fakeProvider) Quote(string) (Quote, error) {
+	f.calls++
+	return f.quote, f.err
+}
+
+func (f *fakeProvider) Fundamentals(string) (Fundamentals, error) {
+	f.calls++
+	return f.fundamentals, f.err
+}
+
+func (f *fakeProvider) Alpha(string) (float64, error) {
+	f.calls++
+	return f.alpha, f.err
+}
+
+// withProviders registers the given providers and chains for the duration of the test.
+func withProviders(t *testing.T, chains map[string][]string, ps ...Provider) {
+	t.Helper()
+	prevRegistry := registry
+	prevConfig := config.Current()
+	registry = map[string]Provider{}
+	for _, p := range ps {
+		Register(p)
+	}
+	cfg := config.Default()
+	cfg.ProviderChains = chains
+	config.Set(cfg)
+	t.Cleanup(func() {
+		registry = prevRegistry
+		config.Set(prevConfig)
+	})
+}
+
+func TestFetchQuote_FallsBackWhenFirstHasNoData(t *testing.T) {
+	first := &fakeProvider{name: "first", err: api.ErrNoData}
+	second := &fakeProvider{name: "second", quote: Quote{Price: 101.5}}
+	withProviders(t, map[string][]string{config.DataTypeQuote: {"first", "second"}}, first, second)
+
+	quote, source, failures, err := FetchQuote("AAPL")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if source != "second" || quote.Price != 101.5 {
+		t.Errorf("got %.2f from %q, want 101.50 from second", quote.Price, source)
+	}
+	if len(failures) != 1 || failures[0].Provider != "first" || !errors.Is(failures[0].Err, api.ErrNoData) {
+		t.Errorf("unexpected failures: %v", failures)
+	}
+}
+
+func TestFetchFundamentals_FollowsConfiguredOrder(t *testing.T) {
+	a := &fakeProvider{name: "a", fundamentals: Fundamentals{PERatio: 10}}
+	b := &fakeProvider{name: "b", fundamentals: Fundamentals{PERatio: 20}}
+	withProviders(t, map[string][]string{config.DataTypeFundamentals: {"b", "a"}}, a, b)
+
+	fundamentals, source, failures, err := FetchFundamentals("KO")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if source != "b" || fundamentals.PERatio != 20 || len(failures) != 0 {
+		t.Errorf("got PE %.0f from %q (failures %v), want 20 from b", fundamentals.PERatio, source, failures)
+	}
+	if a.calls != 0 {
+		t.Errorf("provider a was called %d times, want 0", a.calls)
+	}
+}
+
+func TestFetchAlpha_SkipsUncoveredExchanges(t *testing.T) {
+	us := &fakeProvider{name: "us", alpha: 0.012}
+	withProviders(t, map[string][]string{config.DataTypeAlpha: {"us"}}, &listedOnly{fakeProvider: us})
+
+	alpha, source, _, err := FetchAlpha("AAPL")
+	if err != nil || source != "us" || alpha != 0.012 {
+		t.Errorf("got %v from %q (%v), want 0.012 from us", alpha, source, err)
+	}
+	if _, _, failures, err := FetchAlpha("SAP.DE"); err == nil || len(failures) != 0 || us.calls != 1 {
+		t.Errorf("got failures %v, err %v after %d calls; want no provider for SAP.DE", failures, err, us.calls)
+	}
+}
+
+// listedOnly is a full provider that only covers US listings.
+type listedOnly struct{ *fakeProvider }
+
+func (listedOnly) Symbol(ticker string, ex models.Exchange) (string, bool) {
+	return ticker, ex.Code == models.ExchangeUS
+}
+
+func TestValidateChains(t *testing.T) {
+	withProviders(t, nil, &fakeProvider{name: "polygon"}, &quoteOnlyProvider{name: "quotes"})
+	var _ MarketDataProvider = &fakeProvider{}
+	var _ MarketDataProvider = alphaVantageProvider{}
+	var _ BatchQuoteProvider = alphaVantageProvider{}
+
+	valid := map[string][]string{
+		config.DataTypeQuote: {"quotes", "polygon"},
+		config.DataTypeAlpha: {"polygon"},
+	}
+	if err := ValidateChains(valid); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	for name, chains := range map[string]map[string][]string{
+		"unregistered": {config.DataTypeQuote: {"polygon", "tiingo"}},
+		"unsupported":  {config.DataTypeFundamentals: {"quotes"}},
+		"data type":    {"dividends": {"polygon"}},
+	} {
+		if err := ValidateChains(chains); err == nil {
+			t.Errorf("%s: want an error for %v", name, chains)
+		}
+	}
+}
+
+func TestValidateChains_Defaults(t *testing.T) {
+	if err := ValidateChains(config.Default().ProviderChains); err != nil {
+		t.Errorf("the default chains are invalid: %v", err)
+	}
+}
+
+func TestFetchQuote_AllProvidersFail(t *testing.T) {
+	boom := errors.New("boom")
+	a := &fakeProvider{name: "a", err: boom}
+	b := &fakeProvider{name: "b", err: api.ErrNoData}
+	withProviders(t, map[string][]string{config.DataTypeQuote: {"a", "unknown", "b"}}, a, b)
+
+	_, source, failures, err := FetchQuote("XYZ")
+	var chainErr *ChainError
+	if !errors.As(err, &chainErr) {
+		t.Fatalf("got error %v, want *ChainError", err)
+	}
+	if source != "" || len(failures) != 2 || failures[0].Provider != "a" || failures[1].Provider != "b" {
+		t.Errorf("unexpected result: source %q, failures %v", source, failures)
+	}
+}
+
+func TestFetchFundamentals_SkipsProvidersWithoutSupport(t *testing.T) {
+	q := &quoteOnlyProvider{name: "quotes"}
+	f := &fakeProvider{name: "full", fundamentals: Fundamentals{DividendYield: 3}}
+	withProviders(t, map[string][]string{config.DataTypeFundamentals: {"quotes", "full"}}, q, f)
+
+	_, source, failures, err := FetchFundamentals("PFE")
+	if err != nil || source != "full" || len(failures) != 0 {
+		t.Errorf("got source %q, failures %v, err %v; want full with no failures", source, failures, err)
+	}
+}
+
+// quoteOnlyProvider implements QuoteProvider only.
+type quoteOnlyProvider struct{ name string }
+
+func (q *quoteOnlyProvider) Name() string { return q.name }
+
+func (q *quoteOnlyProvider) Quote(string) (Quote, error) { return Quote{Price: 1}, nil }
+
+// listedProvider covers only the exchanges in suffixes, and records the symbols it is
+// asked for.
+type listedProvider struct {
+	name     string
+	suffixes map[string]string // Exchange code to provider suffix
+	symbols  []string
+}
+
+func (l *listedProvider) Name() string { return l.name }
+
+func (l *listedProvider) Symbol(ticker string, ex models.Exchange) (string, bool) {
+	suffix, ok := l.suffixes[ex.Code]
+	if !ok {
+		return "", false
+	}
+	return ex.BaseSymbol(ticker) + "." + suffix, true
+}
+
+func (l *listedProvider) Quote(symbol string) (Quote, error) {
+	l.symbols = append(l.symbols, symbol)
+	return Quote{Price: 1}, nil
+}
+
+func TestFetchQuote_RoutesByExchange(t *testing.T) {
+	us := &listedProvider{name: "us", suffixes: map[string]string{models.ExchangeUS: "US"}}
+	eu := &listedProvider{name: "eu", suffixes: map[string]string{"XETRA": "XETR"}}
+	withProviders(t, map[string][]string{config.DataTypeQuote: {"us", "eu"}}, us, eu)
+
+	_, source, failures, err := FetchQuote("SAP.DE")
+	if err != nil || source != "eu" || len(failures) != 0 {
+		t.Fatalf("got source %q, failures %v, err %v; want eu with no failures", source, failures, err)
+	}
+	if len(us.symbols) != 0 {
+		t.Errorf("us provider was asked for %v, want no calls", us.symbols)
+	}
+	if len(eu.symbols) != 1 || eu.symbols[0] != "SAP.XETR" {
+		t.Errorf("eu provider was asked for %v, want [SAP.XETR]", eu.symbols)
+	}
+
+	if _, _, _, err := FetchQuote("NESN.SW"); err == nil {
+		t.Error("expected an error for an exchange no provider covers")
+	}
+}
+
+func TestAlphaVantageSymbol(t *testing.T) {
+	cases := map[string]string{"AAPL": "AAPL", "BRK.B": "BRK.B", "SAP.DE": "SAP.DEX", "VOD.L": "VOD.LON", "SHOP.TO": "SHOP.TRT", "MC.PA": ""}
+	for ticker, want := range cases {
+		got, ok := Symbol(AlphaVantage, ticker)
+		if ok != (want != "") || got != want {
+			t.Errorf("Symbol(alphavantage, %s) = %q, %t; want %q", ticker, got, ok, want)
+		}
+	}
+}
+
+// batchProvider quotes every symbol but those in missing, in batches, and records the
+// size of each batch. With err set, every batch call fails with it.
+type batchProvider struct {
+	quoteOnlyProvider
+	missing map[string]bool
+	err     error
+	batches []int
+	singles int
+}
+
+func (b *batchProvider) Quote(string) (Quote, error) {
+	b.singles++
+	return Quote{Price: 2}, nil
+}
+
+func (b *batchProvider) Quotes(symbols []string) (map[string]Quote, error) {
+	b.batches = append(b.batches, len(symbols))
+	if b.err != nil {
+		return nil, b.err
+	}
+	quotes := map[string]Quote{}
+	for _, s := range symbols {
+		if !b.missing[s] {
+			quotes[s] = Quote{Price: 2}
+		}
+	}
+	return quotes, nil
+}
+
+func TestFetchQuotes_ChunksBatchesAndFallsBack(t *testing.T) {
+	bulk := &batchProvider{quoteOnlyProvider: quoteOnlyProvider{name: "bulk"}, missing: map[string]bool{"T7": true}}
+	single := &fakeProvider{name: "single", quote: Quote{Price: 3}}
+	withProviders(t, map[string][]string{config.DataTypeQuote: {"bulk", "single"}}, bulk, single)
+	if !HasBatchQuotes() {
+		t.Fatal("HasBatchQuotes() = false with a batch provider in the chain")
+	}
+
+	tickers := make([]string, 120)
+	for i := range tickers {
+		tickers[i] = fmt.Sprintf("T%d", i)
+	}
+	results := FetchQuotes(tickers)
+
+	if len(bulk.batches) != 3 || bulk.batches[0] != QuoteBatchSize || bulk.batches[2] != 20 || bulk.singles != 0 {
+		t.Errorf("got batches %v and %d single calls, want 50, 50 and 20", bulk.batches, bulk.singles)
+	}
+	if len(results) != 120 || results["T0"].Source != "bulk" || results["T0"].Quote.Price != 2 {
+		t.Errorf("unexpected result for T0: %+v", results["T0"])
+	}
+	// A symbol missing from the batch falls back to the next provider, like a single miss.
+	if r := results["T7"]; r.Source != "single" || len(r.Failures) != 1 || !errors.Is(r.Failures[0].Err, api.ErrNoData) || single.calls != 1 {
+		t.Errorf("got %+v after %d single calls, want T7 from single after a bulk miss", r, single.calls)
+	}
+}
+
+func TestFetchQuotes_BatchUnauthorized(t *testing.T) {
+	bulk := &batchProvider{quoteOnlyProvider: quoteOnlyProvider{name: "bulk"}, err: fmt.Errorf("premium (%w)", api.ErrUnauthorized)}
+	withProviders(t, map[string][]string{config.DataTypeQuote: {"bulk"}}, bulk)
+
+	// Without the batch endpoint in its plan, the provider is asked one symbol at a time.
+	results := FetchQuotes([]string{"AAPL", "KO", "AAPL"})
+	if len(bulk.batches) != 1 || bulk.singles != 2 || len(results) != 2 || results["KO"].Source != "bulk" {
+		t.Errorf("got batches %v, %d single calls and %+v", bulk.batches, bulk.singles, results)
+	}
+
+	bulk.err, bulk.batches, bulk.singles = api.ErrRateLimited, nil, 0
+	var chainErr *ChainError
+	if r := FetchQuotes([]string{"AAPL"})["AAPL"]; !errors.As(r.Err, &chainErr) || !errors.Is(r.Failures[0].Err, api.ErrRateLimited) || bulk.singles != 0 {
+		t.Errorf("got %+v after %d single calls, want the batch error for AAPL", r, bulk.singles)
+	}
+}
@@ -0,0 +1,102 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/api"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// StatsStore persists the daily provider stats. database.StockDB implements it.
+type StatsStore interface {
+	MergeProviderStats(ctx context.Context, stats []models.ProviderDayStats) error
+}
+
+type statsKey struct {
+	provider string
+	day      time.Time
+}
+
+var (
+	statsMu sync.Mutex
+	pending = map[statsKey]*models.ProviderDayStats{} // Calls not flushed to the store yet
+)
+
+func recordStats(name string, latency time.Duration, err error) {
+	day := now().UTC().Truncate(24 * time.Hour)
+	statsMu.Lock()
+	defer statsMu.Unlock()
+
+	key := statsKey{provider: name, day: day}
+	s, ok := pending[key]
+	if !ok {
+		s = &models.ProviderDayStats{Provider: name, Day: day}
+		pending[key] = s
+	}
+	s.Observe(latency, ErrorCategory(err))
+}
+
+// FlushStats merges the calls recorded since the last flush into the store. If the store
+// fails they are kept for the next flush.
+func FlushStats(ctx context.Context, store StatsStore) error {
+	statsMu.Lock()
+	flushed := pending
+	pending = map[statsKey]*models.ProviderDayStats{}
+	statsMu.Unlock()
+
+	if len(flushed) == 0 {
+		return nil
+	}
+	stats := make([]models.ProviderDayStats, 0, len(flushed))
+	for _, s := range flushed {
+		stats = append(stats, *s)
+	}
+	if err := store.MergeProviderStats(ctx, stats); err != nil {
+		statsMu.Lock()
+		for key, s := range flushed {
+			if newer, ok := pending[key]; ok {
+				s.Merge(*newer)
+			}
+			pending[key] = s
+		}
+		statsMu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// ErrorCategory classifies a provider call error into one of the models.ErrorCategory*
+// values. It returns "" for successful calls, including api.ErrNoData.
+func ErrorCategory(err error) string {
+	var statusErr *api.StatusError
+	var netErr net.Error
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+
+	switch {
+	case err == nil, errors.Is(err, api.ErrNoData):
+		return ""
+	case errors.Is(err, api.ErrRateLimited):
+		return models.ErrorCategoryRateLimited
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return models.ErrorCategoryTimeout
+	case errors.As(err, &statusErr):
+		if statusErr.StatusCode >= 500 {
+			return models.ErrorCategoryHTTP5xx
+		}
+		return models.ErrorCategoryHTTP4xx
+	case errors.As(err, &syntaxErr), errors.As(err, &typeErr):
+		return models.ErrorCategoryDecode
+	case errors.Is(err, api.ErrMissingAPIKey):
+		return models.ErrorCategoryConfig
+	case errors.As(err, &netErr):
+		return models.ErrorCategoryNetwork
+	default:
+		return models.ErrorCategoryOther
+	}
+}
@@ -0,0 +1,89 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/api"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+func TestErrorCategory(t *testing.T) {
+	var syntaxErr *json.SyntaxError
+	decodeErr := json.Unmarshal([]byte("{"), &struct{}{})
+	if !errors.As(decodeErr, &syntaxErr) {
+		t.Fatalf("expected a syntax error, got %v", decodeErr)
+	}
+
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{nil, ""},
+		{fmt.Errorf("wrapped: %w", api.ErrNoData), ""},
+		{fmt.Errorf("note/warning (%w)", api.ErrRateLimited), models.ErrorCategoryRateLimited},
+		{&api.StatusError{StatusCode: 429}, models.ErrorCategoryRateLimited},
+		{&api.StatusError{StatusCode: 503}, models.ErrorCategoryHTTP5xx},
+		{fmt.Errorf("wrapped: %w", &api.StatusError{StatusCode: 404}), models.ErrorCategoryHTTP4xx},
+		{&url.Error{Op: "Get", URL: "https://finnhub.io", Err: context.DeadlineExceeded}, models.ErrorCategoryTimeout},
+		{&url.Error{Op: "Get", URL: "https://finnhub.io", Err: errors.New("connection refused")}, models.ErrorCategoryNetwork},
+		{fmt.Errorf("decoding: %w", decodeErr), models.ErrorCategoryDecode},
+		{fmt.Errorf("FINNHUB_API_KEY %w", api.ErrMissingAPIKey), models.ErrorCategoryConfig},
+		{errors.New("something else"), models.ErrorCategoryOther},
+	}
+	for _, tt := range tests {
+		if got := ErrorCategory(tt.err); got != tt.want {
+			t.Errorf("ErrorCategory(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+// statsStore keeps the merged stats, or fails with err when set.
+type statsStore struct {
+	stats []models.ProviderDayStats
+	err   error
+}
+
+func (s *statsStore) MergeProviderStats(ctx context.Context, stats []models.ProviderDayStats) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.stats = append(s.stats, stats...)
+	return nil
+}
+
+func TestFlushStats_KeepsStatsWhenStoreFails(t *testing.T) {
+	prevPending, prevNow := pending, now
+	pending = map[statsKey]*models.ProviderDayStats{}
+	clock := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	now = func() time.Time { return clock }
+	t.Cleanup(func() { pending, now = prevPending, prevNow })
+
+	RecordCall(Finnhub, 120*time.Millisecond, nil)
+	store := &statsStore{err: errors.New("db down")}
+	if err := FlushStats(context.Background(), store); err == nil {
+		t.Fatal("expected the store error")
+	}
+
+	RecordCall(Finnhub, 2*time.Second, &api.StatusError{StatusCode: 502})
+	store.err = nil
+	if err := FlushStats(context.Background(), store); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(store.stats) != 1 {
+		t.Fatalf("expected one provider-day, got %+v", store.stats)
+	}
+	s := store.stats[0]
+	if s.Provider != Finnhub || !s.Day.Equal(time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)) || s.Calls != 2 || s.ErrorCategories[models.ErrorCategoryHTTP5xx] != 1 {
+		t.Errorf("unexpected stats after retry: %+v", s)
+	}
+
+	if err := FlushStats(context.Background(), store); err != nil || len(store.stats) != 1 {
+		t.Errorf("nothing new should be flushed: %v %+v", err, store.stats)
+	}
+}
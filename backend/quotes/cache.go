@@ -0,0 +1,110 @@
+// Package quotes keeps the latest price of every stock in memory so lightweight clients
+// (watchlist widgets polling every few seconds) can be served without querying the
+// stocks table.
+package quotes
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/database"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// Quote is the minimal price snapshot served by GET /api/v1/quotes.
+type Quote struct {
+	Ticker string             `json:"ticker"`
+	Price  float64            `json:"price"`
+	Change models.NullFloat64 `json:"change"` // Daily change in percent; null without a previous close
+	AsOf   time.Time          `json:"as_of"`  // Time of the quote, or of the last enrichment if the provider gave none
+}
+
+// FromStock builds the quote of a stock. It returns false when the stock has no price.
+func FromStock(s models.Stock) (Quote, bool) {
+	if s.CurrentPrice <= 0 {
+		return Quote{}, false
+	}
+	asOf := s.UpdatedAt
+	if s.LatestTradingDay.Valid {
+		asOf = s.LatestTradingDay.Time
+	}
+	return Quote{
+		Ticker: s.Ticker,
+		Price:  s.CurrentPrice,
+		Change: s.ChangePercent(),
+		AsOf:   asOf.UTC(),
+	}, true
+}
+
+// Cache holds the latest quote per ticker. It is safe for concurrent use.
+type Cache struct {
+	mu     sync.RWMutex
+	quotes map[string]Quote
+}
+
+// NewCache creates an empty cache.
+func NewCache() *Cache {
+	return &Cache{quotes: make(map[string]Quote)}
+}
+
+// PutStocks stores the quotes of the given stocks, replacing older ones. Stocks without
+// a price are skipped, so a failed enrichment never erases a good quote.
+func (c *Cache) PutStocks(stocks []models.Stock) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, s := range stocks {
+		if q, ok := FromStock(s); ok {
+			c.quotes[strings.ToUpper(s.Ticker)] = q
+		}
+	}
+}
+
+// Get returns the cached quotes of tickers, in the same order. Tickers without a
+// cached quote are returned in missing.
+func (c *Cache) Get(tickers []string) (found []Quote, missing []string) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	found = make([]Quote, 0, len(tickers))
+	for _, ticker := range tickers {
+		if q, ok := c.quotes[strings.ToUpper(ticker)]; ok {
+			found = append(found, q)
+		} else {
+			missing = append(missing, ticker)
+		}
+	}
+	return found, missing
+}
+
+// Len returns the number of cached quotes.
+func (c *Cache) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.quotes)
+}
+
+// Load fills the cache from a consistent snapshot of the stocks table. It is meant to
+// run once at startup, so quotes are available before the next enrichment run. Loading
+// stops when ctx is canceled.
+func (c *Cache) Load(ctx context.Context, db database.StockDB) error {
+	asOf, err := db.ExportSnapshot(ctx)
+	if err != nil {
+		return fmt.Errorf("could not take a snapshot to load quotes: %w", err)
+	}
+	batch := make([]models.Stock, 0, 100)
+	err = db.ExportStocks(ctx, asOf, "", 0, func(s models.Stock) error {
+		batch = append(batch, s)
+		if len(batch) == cap(batch) {
+			c.PutStocks(batch)
+			batch = batch[:0]
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("could not load quotes: %w", err)
+	}
+	c.PutStocks(batch)
+	return nil
+}
@@ -0,0 +1,71 @@
+package quotes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/database"
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+func TestCache_PutStocksAndGet(t *testing.T) {
+	day := time.Date(2025, 1, 6, 21, 0, 0, 0, time.UTC)
+	c := NewCache()
+	c.PutStocks([]models.Stock{
+		{Ticker: "AAPL", CurrentPrice: 110, PreviousClose: models.NewNullFloat64(100), LatestTradingDay: models.NewNullTime(day)},
+		{Ticker: "KO", CurrentPrice: 62, UpdatedAt: day.Add(time.Hour)},
+		{Ticker: "XYZ"}, // No price: not cached
+	})
+
+	found, missing := c.Get([]string{"ko", "XYZ", "AAPL", "MSFT"})
+	if len(found) != 2 || found[0].Ticker != "KO" || found[1].Ticker != "AAPL" {
+		t.Fatalf("Expected KO and AAPL in request order, got %+v", found)
+	}
+	if found[0].Change.Valid || !found[0].AsOf.Equal(day.Add(time.Hour)) {
+		t.Errorf("Expected KO without change and as_of from updated_at, got %+v", found[0])
+	}
+	if found[1].Change.Float64 != 10 || !found[1].AsOf.Equal(day) {
+		t.Errorf("Expected AAPL change 10%% as of the trading day, got %+v", found[1])
+	}
+	if len(missing) != 2 || missing[0] != "XYZ" || missing[1] != "MSFT" {
+		t.Errorf("Expected XYZ and MSFT missing, got %v", missing)
+	}
+
+	// A later run without a price keeps the previous quote.
+	c.PutStocks([]models.Stock{{Ticker: "AAPL"}})
+	if found, _ := c.Get([]string{"AAPL"}); len(found) != 1 || found[0].Price != 110 {
+		t.Errorf("Expected the AAPL quote to survive a failed enrichment, got %+v", found)
+	}
+}
+
+// exportDB is a StockDB that exports a fixed list of stocks.
+type exportDB struct {
+	database.StockDB
+	stocks []models.Stock
+}
+
+func (db *exportDB) ExportSnapshot(ctx context.Context) (time.Time, error) { return time.Now(), nil }
+
+func (db *exportDB) ExportStocks(ctx context.Context, asOf time.Time, afterTicker string, limit int, fn func(models.Stock) error) error {
+	for _, s := range db.stocks {
+		if err := fn(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestCache_Load(t *testing.T) {
+	stocks := make([]models.Stock, 250)
+	for i := range stocks {
+		stocks[i] = models.Stock{Ticker: string(rune('A'+i/26%26)) + string(rune('A'+i%26)) + "X", CurrentPrice: float64(i + 1)}
+	}
+	c := NewCache()
+	if err := c.Load(context.Background(), &exportDB{stocks: stocks}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if c.Len() != len(stocks) {
+		t.Errorf("Expected %d cached quotes, got %d", len(stocks), c.Len())
+	}
+}
@@ -0,0 +1,79 @@
+// Package retention elimina definitivamente los datos que ya no deben conservarse, como
+// las cuentas borradas con DELETE /me una vez vencido su periodo de retención, los
+// nonces caducados de los webhooks recibidos, las respuestas crudas de los proveedores o
+// los resultados por ticker de las ejecuciones del enriquecimiento.
+package retention
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/clock"
+	"github.com/jannin2/stock-app/backend/config"
+	"github.com/jannin2/stock-app/backend/database"
+)
+
+// PurgeInterval es cada cuánto se buscan cuentas borradas listas para purgar. La retención
+// se mide en días, así que no hace falta más precisión.
+const PurgeInterval = 6 * time.Hour
+
+// EnrichmentResultRetention es cuánto se conservan los resultados por ticker de cada
+// ejecución del enriquecimiento. Sirven para investigar las últimas ejecuciones, no como
+// histórico.
+const EnrichmentResultRetention = 14 * 24 * time.Hour
+
+// Purger purga periódicamente las cuentas borradas hace más de config.UserDataRetention.
+type Purger struct {
+	users  database.UserDB
+	stocks database.StockDB
+	clock  clock.Clock
+}
+
+// NewPurger crea un Purger sobre las bases de datos de usuarios y de stocks.
+func NewPurger(users database.UserDB, stocks database.StockDB, c clock.Clock) *Purger {
+	return &Purger{users: users, stocks: stocks, clock: c}
+}
+
+// RunOnce purga las cuentas cuyo periodo de retención ya venció y devuelve cuántas eran.
+func (p *Purger) RunOnce(ctx context.Context) (int64, error) {
+	cutoff := p.clock.Now().Add(-config.Current().UserDataRetention)
+	purged, err := p.users.PurgeDeletedUsers(ctx, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	if purged > 0 {
+		log.Printf("🗑️ Purgadas %d cuentas borradas antes del %s", purged, cutoff.Format(time.RFC3339))
+	}
+	// Los nonces solo sirven para detectar repeticiones dentro de la tolerancia del
+	// timestamp; un fallo aquí no afecta a la purga de cuentas.
+	if _, err := p.users.PurgeWebhookNonces(ctx, p.clock.Now()); err != nil {
+		log.Printf("ERROR: no se pudieron purgar los nonces de webhooks: %v", err)
+	}
+	// Con la captura desactivada (retención 0) se borran todas las que quedaran.
+	if _, err := p.stocks.PurgeProviderPayloads(ctx, p.clock.Now().Add(-config.Current().ProviderPayloadRetention)); err != nil {
+		log.Printf("ERROR: no se pudieron purgar las respuestas de los proveedores: %v", err)
+	}
+	if _, err := p.stocks.PurgeEnrichmentResults(ctx, p.clock.Now().Add(-EnrichmentResultRetention)); err != nil {
+		log.Printf("ERROR: no se pudieron purgar los resultados del enriquecimiento: %v", err)
+	}
+	return purged, nil
+}
+
+// Run ejecuta RunOnce al arrancar y después cada PurgeInterval, hasta que se cancela ctx.
+// Un fallo solo se registra: la purga se reintenta en la siguiente vuelta.
+func (p *Purger) Run(ctx context.Context) {
+	ticker := p.clock.NewTicker(PurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := p.RunOnce(ctx); err != nil {
+			log.Printf("ERROR: no se pudieron purgar las cuentas borradas: %v", err)
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package retention
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/clock"
+	"github.com/jannin2/stock-app/backend/config"
+	"github.com/jannin2/stock-app/backend/database"
+)
+
+// fakeUserDB registra el límite con el que se purga.
+type fakeUserDB struct {
+	database.UserDB
+	cutoffs      []time.Time
+	nonceCutoffs []time.Time
+}
+
+func (f *fakeUserDB) PurgeWebhookNonces(_ context.Context, before time.Time) (int64, error) {
+	f.nonceCutoffs = append(f.nonceCutoffs, before)
+	return 0, nil
+}
+
+func (f *fakeUserDB) PurgeDeletedUsers(_ context.Context, deletedBefore time.Time) (int64, error) {
+	f.cutoffs = append(f.cutoffs, deletedBefore)
+	return 2, nil
+}
+
+// fakeStockDB registra los límites con los que se purgan las respuestas de los proveedores
+// y los resultados del enriquecimiento.
+type fakeStockDB struct {
+	database.StockDB
+	payloadCutoffs []time.Time
+	resultCutoffs  []time.Time
+}
+
+func (f *fakeStockDB) PurgeEnrichmentResults(ctx context.Context, before time.Time) (int64, error) {
+	f.resultCutoffs = append(f.resultCutoffs, before)
+	return 0, nil
+}
+
+func (f *fakeStockDB) PurgeProviderPayloads(ctx context.Context, before time.Time) (int64, error) {
+	f.payloadCutoffs = append(f.payloadCutoffs, before)
+	return 0, nil
+}
+
+func TestPurger_RunOnce(t *testing.T) {
+	cfg := config.Default()
+	cfg.UserDataRetention = 30 * 24 * time.Hour
+	cfg.ProviderPayloadRetention = 72 * time.Hour
+	config.Set(cfg)
+	t.Cleanup(func() { config.Set(config.Default()) })
+
+	mock := clock.NewMock()
+	db, stocks := &fakeUserDB{}, &fakeStockDB{}
+	purged, err := NewPurger(db, stocks, mock).RunOnce(context.Background())
+	if err != nil || purged != 2 {
+		t.Fatalf("❌ RunOnce = %d, %v; se esperaban 2 cuentas purgadas", purged, err)
+	}
+	if want := mock.Now().AddDate(0, 0, -30); len(db.cutoffs) != 1 || !db.cutoffs[0].Equal(want) {
+		t.Errorf("❌ límite de purga %v, se esperaba %s", db.cutoffs, want)
+	}
+	if len(db.nonceCutoffs) != 1 || !db.nonceCutoffs[0].Equal(mock.Now()) {
+		t.Errorf("❌ los nonces de webhooks deberían purgarse hasta ahora, se obtuvo %v", db.nonceCutoffs)
+	}
+	if want := mock.Now().Add(-72 * time.Hour); len(stocks.payloadCutoffs) != 1 || !stocks.payloadCutoffs[0].Equal(want) {
+		t.Errorf("❌ límite de purga de respuestas %v, se esperaba %s", stocks.payloadCutoffs, want)
+	}
+	if want := mock.Now().Add(-EnrichmentResultRetention); len(stocks.resultCutoffs) != 1 || !stocks.resultCutoffs[0].Equal(want) {
+		t.Errorf("❌ límite de purga de resultados %v, se esperaba %s", stocks.resultCutoffs, want)
+	}
+}
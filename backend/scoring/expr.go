@@ -0,0 +1,689 @@
+package scoring
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// The expression language used by scoring rules. It is deliberately small: literals
+// (numbers, "strings", true, false, null), the stock variables in Variables, arithmetic
+// (+ - * /), comparisons (== != < <= > >=), boolean logic (&& || !), membership
+// (x in [a, b]) and the functions in Functions. There are no loops, assignments or access
+// to anything outside the stock, so a rule can be evaluated safely on every enrichment.
+//
+// null propagates through arithmetic and functions, ordered comparisons with null are
+// false, and null is false when used as a condition, so rules over missing data simply
+// do not apply.
+
+const (
+	maxExprLength = 500 // Characters per expression
+	maxExprDepth  = 32  // Nesting depth, so a pathological expression cannot exhaust the stack
+)
+
+// Variables are the stock fields available to expressions, with their description.
+var Variables = map[string]string{
+	"ticker":         "ticker symbol (string)",
+	"company":        "company name (string)",
+	"brokerage":      "brokerage that issued the rating (string)",
+	"action":         `rating action, e.g. "Buy" or "target raised by" (string)`,
+	"rating_from":    "previous rating (string)",
+	"rating_to":      "new rating (string)",
+	"sector":         "Finnhub industry (string)",
+	"exchange":       `exchange code from the ticker suffix, e.g. "US" or "XETRA" (string)`,
+	"currency":       `currency of the prices, e.g. "USD" or "EUR" (string)`,
+	"current_price":  "latest price; null if unknown",
+	"previous_close": "previous session close; null if unknown",
+	"target_from":    "previous target price; null if unknown",
+	"target_to":      "new target price; null if unknown",
+	"upside":         "target_to over current_price minus 1, e.g. 0.1 = 10%; null if unknown",
+	"daily_change":   "current_price over previous_close minus 1; null if unknown",
+	"pe_ratio":       "price/earnings ratio; null if unknown",
+	"dividend_yield": "dividend yield; null if unknown",
+	"market_cap":     "market capitalization in millions of the listing currency; null if unknown",
+	"alpha":          "Alpha Vantage alpha; null if unknown",
+	"sentiment":      "rolling news sentiment from -1 to 1; null without the sentiment step or recent news",
+	"buzz":           "social media mentions today; null without the buzz step",
+	"esg_score":      "total ESG score from 0 to 100; null without the esg step",
+	"short_interest": "shares sold short in the latest report; null without the short_interest step",
+	"days_to_cover":  "short interest over the average daily volume; null if unknown",
+}
+
+// Functions are the functions available to expressions, with their description.
+var Functions = map[string]string{
+	"min":      "min(a, b, ...) smallest number",
+	"max":      "max(a, b, ...) largest number",
+	"abs":      "abs(x) absolute value",
+	"lower":    "lower(s) lowercase string",
+	"upper":    "upper(s) uppercase string",
+	"contains": "contains(s, sub) whether s contains sub, ignoring case",
+	"coalesce": "coalesce(a, b, ...) first argument that is not null",
+}
+
+// functionArity is the minimum and maximum number of arguments of each function
+// (-1 = unbounded).
+var functionArity = map[string][2]int{
+	"min": {1, -1}, "max": {1, -1}, "abs": {1, 1}, "lower": {1, 1}, "upper": {1, 1},
+	"contains": {2, 2}, "coalesce": {1, -1},
+}
+
+// Expr is a compiled expression.
+type Expr struct {
+	src  string
+	root node
+}
+
+// Compile parses src and checks that it only uses known variables and functions.
+func Compile(src string) (*Expr, error) {
+	if len(src) > maxExprLength {
+		return nil, fmt.Errorf("expression longer than %d characters", maxExprLength)
+	}
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr(0)
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %q at position %d", t.text, t.pos)
+	}
+	return &Expr{src: src, root: root}, nil
+}
+
+func (e *Expr) String() string { return e.src }
+
+// Eval evaluates the expression with vars. The result is nil, a float64, a string or a bool.
+func (e *Expr) Eval(vars map[string]interface{}) (interface{}, error) {
+	return e.root.eval(vars)
+}
+
+// --- Tokenizer ---
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokString
+	tokIdent
+	tokOp // Operators and punctuation
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	num  float64
+	pos  int
+}
+
+func tokenize(src string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c >= '0' && c <= '9' || c == '.' && i+1 < len(src) && src[i+1] >= '0' && src[i+1] <= '9':
+			start := i
+			for i < len(src) && (src[i] >= '0' && src[i] <= '9' || src[i] == '.') {
+				i++
+			}
+			n, err := strconv.ParseFloat(src[start:i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q at position %d", src[start:i], start)
+			}
+			tokens = append(tokens, token{kind: tokNumber, text: src[start:i], num: n, pos: start})
+		case c == '"' || c == '\'':
+			start := i
+			i++
+			var sb strings.Builder
+			for i < len(src) && src[i] != c {
+				if src[i] == '\\' && i+1 < len(src) {
+					i++
+				}
+				sb.WriteByte(src[i])
+				i++
+			}
+			if i >= len(src) {
+				return nil, fmt.Errorf("unterminated string at position %d", start)
+			}
+			i++
+			tokens = append(tokens, token{kind: tokString, text: sb.String(), pos: start})
+		case c == '_' || unicode.IsLetter(rune(c)):
+			start := i
+			for i < len(src) && (src[i] == '_' || unicode.IsLetter(rune(src[i])) || src[i] >= '0' && src[i] <= '9') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: src[start:i], pos: start})
+		default:
+			op := ""
+			for _, candidate := range []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "+", "-", "*", "/", "!", "(", ")", "[", "]", ","} {
+				if strings.HasPrefix(src[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected character %q at position %d", c, i)
+			}
+			tokens = append(tokens, token{kind: tokOp, text: op, pos: i})
+			i += len(op)
+		}
+	}
+	return append(tokens, token{kind: tokEOF, text: "end of expression", pos: len(src)}), nil
+}
+
+// --- Parser ---
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token { return p.tokens[p.pos] }
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) accept(op string) bool {
+	if t := p.peek(); t.kind == tokOp && t.text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(op string) error {
+	if !p.accept(op) {
+		t := p.peek()
+		return fmt.Errorf("expected %q at position %d, got %q", op, t.pos, t.text)
+	}
+	return nil
+}
+
+func (p *parser) parseOr(depth int) (node, error) {
+	if depth > maxExprDepth {
+		return nil, errors.New("expression nested too deeply")
+	}
+	left, err := p.parseAnd(depth)
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		right, err := p.parseAnd(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = logicalNode{op: "||", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd(depth int) (node, error) {
+	left, err := p.parseComparison(depth)
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		right, err := p.parseComparison(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = logicalNode{op: "&&", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseComparison(depth int) (node, error) {
+	left, err := p.parseAdditive(depth)
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind == tokIdent && t.text == "in" {
+		p.next()
+		if err := p.expect("["); err != nil {
+			return nil, err
+		}
+		var list []node
+		for !p.accept("]") {
+			if len(list) > 0 {
+				if err := p.expect(","); err != nil {
+					return nil, err
+				}
+			}
+			item, err := p.parseOr(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, item)
+		}
+		return inNode{value: left, list: list}, nil
+	}
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if p.accept(op) {
+			right, err := p.parseAdditive(depth)
+			if err != nil {
+				return nil, err
+			}
+			return compareNode{op: op, left: left, right: right}, nil
+		}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAdditive(depth int) (node, error) {
+	left, err := p.parseMultiplicative(depth)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := ""
+		switch {
+		case p.accept("+"):
+			op = "+"
+		case p.accept("-"):
+			op = "-"
+		default:
+			return left, nil
+		}
+		right, err := p.parseMultiplicative(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = arithNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) parseMultiplicative(depth int) (node, error) {
+	left, err := p.parseUnary(depth)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := ""
+		switch {
+		case p.accept("*"):
+			op = "*"
+		case p.accept("/"):
+			op = "/"
+		default:
+			return left, nil
+		}
+		right, err := p.parseUnary(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = arithNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) parseUnary(depth int) (node, error) {
+	if depth > maxExprDepth {
+		return nil, errors.New("expression nested too deeply")
+	}
+	switch {
+	case p.accept("!"):
+		operand, err := p.parseUnary(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand: operand}, nil
+	case p.accept("-"):
+		operand, err := p.parseUnary(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return arithNode{op: "-", left: literalNode{value: 0.0}, right: operand}, nil
+	}
+	return p.parsePrimary(depth)
+}
+
+func (p *parser) parsePrimary(depth int) (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokNumber:
+		return literalNode{value: t.num}, nil
+	case tokString:
+		return literalNode{value: t.text}, nil
+	case tokIdent:
+		switch t.text {
+		case "true":
+			return literalNode{value: true}, nil
+		case "false":
+			return literalNode{value: false}, nil
+		case "null":
+			return literalNode{value: nil}, nil
+		}
+		if p.accept("(") {
+			return p.parseCall(t, depth)
+		}
+		if _, ok := Variables[t.text]; !ok {
+			return nil, fmt.Errorf("unknown variable %q at position %d", t.text, t.pos)
+		}
+		return variableNode{name: t.text}, nil
+	case tokOp:
+		if t.text == "(" {
+			inner, err := p.parseOr(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			return inner, p.expect(")")
+		}
+	}
+	return nil, fmt.Errorf("unexpected %q at position %d", t.text, t.pos)
+}
+
+func (p *parser) parseCall(name token, depth int) (node, error) {
+	arity, ok := functionArity[name.text]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %q at position %d", name.text, name.pos)
+	}
+	var args []node
+	for !p.accept(")") {
+		if len(args) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		arg, err := p.parseOr(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	if len(args) < arity[0] || arity[1] >= 0 && len(args) > arity[1] {
+		return nil, fmt.Errorf("wrong number of arguments to %s: %d", name.text, len(args))
+	}
+	return callNode{name: name.text, args: args}, nil
+}
+
+// --- Evaluation ---
+
+type node interface {
+	eval(vars map[string]interface{}) (interface{}, error)
+}
+
+type literalNode struct{ value interface{} }
+
+func (n literalNode) eval(map[string]interface{}) (interface{}, error) { return n.value, nil }
+
+type variableNode struct{ name string }
+
+func (n variableNode) eval(vars map[string]interface{}) (interface{}, error) {
+	return vars[n.name], nil
+}
+
+type notNode struct{ operand node }
+
+func (n notNode) eval(vars map[string]interface{}) (interface{}, error) {
+	v, err := n.operand.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	b, err := truthy(v)
+	return !b, err
+}
+
+type logicalNode struct {
+	op          string
+	left, right node
+}
+
+func (n logicalNode) eval(vars map[string]interface{}) (interface{}, error) {
+	lv, err := n.left.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	left, err := truthy(lv)
+	if err != nil {
+		return nil, err
+	}
+	if n.op == "&&" && !left || n.op == "||" && left {
+		return left, nil
+	}
+	rv, err := n.right.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	return truthy(rv)
+}
+
+type arithNode struct {
+	op          string
+	left, right node
+}
+
+func (n arithNode) eval(vars map[string]interface{}) (interface{}, error) {
+	lv, err := n.left.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	rv, err := n.right.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	if lv == nil || rv == nil {
+		return nil, nil
+	}
+	if ls, ok := lv.(string); ok && n.op == "+" {
+		if rs, ok := rv.(string); ok {
+			return ls + rs, nil
+		}
+	}
+	l, lok := lv.(float64)
+	r, rok := rv.(float64)
+	if !lok || !rok {
+		return nil, fmt.Errorf("cannot apply %s to %s and %s", n.op, typeName(lv), typeName(rv))
+	}
+	switch n.op {
+	case "+":
+		return l + r, nil
+	case "-":
+		return l - r, nil
+	case "*":
+		return l * r, nil
+	default:
+		if r == 0 {
+			return nil, nil // Like any other unknown value, a division by zero does not score
+		}
+		return l / r, nil
+	}
+}
+
+type compareNode struct {
+	op          string
+	left, right node
+}
+
+func (n compareNode) eval(vars map[string]interface{}) (interface{}, error) {
+	lv, err := n.left.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	rv, err := n.right.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "==":
+		return equal(lv, rv), nil
+	case "!=":
+		return !equal(lv, rv), nil
+	}
+	if lv == nil || rv == nil {
+		return false, nil
+	}
+	var cmp int
+	switch l := lv.(type) {
+	case float64:
+		r, ok := rv.(float64)
+		if !ok {
+			return nil, fmt.Errorf("cannot compare %s with %s", typeName(lv), typeName(rv))
+		}
+		cmp = compareFloats(l, r)
+	case string:
+		r, ok := rv.(string)
+		if !ok {
+			return nil, fmt.Errorf("cannot compare %s with %s", typeName(lv), typeName(rv))
+		}
+		cmp = strings.Compare(l, r)
+	default:
+		return nil, fmt.Errorf("cannot compare %s with %s", typeName(lv), typeName(rv))
+	}
+	switch n.op {
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	default:
+		return cmp >= 0, nil
+	}
+}
+
+type inNode struct {
+	value node
+	list  []node
+}
+
+func (n inNode) eval(vars map[string]interface{}) (interface{}, error) {
+	v, err := n.value.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range n.list {
+		iv, err := item.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		if equal(v, iv) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+type callNode struct {
+	name string
+	args []node
+}
+
+func (n callNode) eval(vars map[string]interface{}) (interface{}, error) {
+	args := make([]interface{}, len(n.args))
+	for i, arg := range n.args {
+		v, err := arg.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	if n.name == "coalesce" {
+		for _, a := range args {
+			if a != nil {
+				return a, nil
+			}
+		}
+		return nil, nil
+	}
+	for _, a := range args {
+		if a == nil {
+			return nil, nil
+		}
+	}
+	switch n.name {
+	case "min", "max":
+		best, ok := args[0].(float64)
+		if !ok {
+			return nil, fmt.Errorf("%s expects numbers, got %s", n.name, typeName(args[0]))
+		}
+		for _, a := range args[1:] {
+			f, ok := a.(float64)
+			if !ok {
+				return nil, fmt.Errorf("%s expects numbers, got %s", n.name, typeName(a))
+			}
+			if n.name == "min" {
+				best = math.Min(best, f)
+			} else {
+				best = math.Max(best, f)
+			}
+		}
+		return best, nil
+	case "abs":
+		f, ok := args[0].(float64)
+		if !ok {
+			return nil, fmt.Errorf("abs expects a number, got %s", typeName(args[0]))
+		}
+		return math.Abs(f), nil
+	case "lower", "upper":
+		s, ok := args[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("%s expects a string, got %s", n.name, typeName(args[0]))
+		}
+		if n.name == "lower" {
+			return strings.ToLower(s), nil
+		}
+		return strings.ToUpper(s), nil
+	default: // contains
+		s, ok1 := args[0].(string)
+		sub, ok2 := args[1].(string)
+		if !ok1 || !ok2 {
+			return nil, fmt.Errorf("contains expects strings, got %s and %s", typeName(args[0]), typeName(args[1]))
+		}
+		return strings.Contains(strings.ToLower(s), strings.ToLower(sub)), nil
+	}
+}
+
+// truthy converts a condition value to a bool: null is false and anything that is not a
+// bool is an error, so a typo such as "when": "upside" fails loudly instead of matching.
+func truthy(v interface{}) (bool, error) {
+	switch b := v.(type) {
+	case nil:
+		return false, nil
+	case bool:
+		return b, nil
+	default:
+		return false, fmt.Errorf("expected a condition, got %s", typeName(v))
+	}
+}
+
+func equal(a, b interface{}) bool {
+	return a == b
+}
+
+func compareFloats(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
@@ -0,0 +1,66 @@
+package scoring
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExprEval(t *testing.T) {
+	vars := map[string]interface{}{"action": "Buy", "upside": 0.25, "current_price": 100.0, "pe_ratio": nil}
+	tests := []struct {
+		src  string
+		want interface{}
+	}{
+		{`action in ["Buy", "Strong Buy"]`, true},
+		{`upside > 0.1 && !(action == "Sell")`, true},
+		{`min(upside * 20, 4)`, 4.0},
+		{`current_price * (1 + upside) - 25`, 100.0},
+		{`-upside`, -0.25},
+		{`pe_ratio < 15`, false},             // Ordered comparisons with null are false
+		{`pe_ratio * 2`, nil},                // null propagates through arithmetic
+		{`pe_ratio == null`, true},           // ... but can be tested explicitly
+		{`coalesce(pe_ratio, 20) / 2`, 10.0}, // ... or replaced
+		{`contains(action, "bu") || false`, true},
+		{`current_price / 0`, nil},
+	}
+	for _, tt := range tests {
+		e, err := Compile(tt.src)
+		if err != nil {
+			t.Errorf("Compile(%q) failed: %v", tt.src, err)
+			continue
+		}
+		got, err := e.Eval(vars)
+		if err != nil || got != tt.want {
+			t.Errorf("%q = %v (%v), want %v", tt.src, got, err, tt.want)
+		}
+	}
+}
+
+func TestCompile_Errors(t *testing.T) {
+	tests := map[string]string{
+		`upside >`:                      "unexpected",
+		`price > 10`:                    `unknown variable "price"`,
+		`sqrt(upside)`:                  `unknown function "sqrt"`,
+		`abs(1, 2)`:                     "wrong number of arguments",
+		`"unterminated`:                 "unterminated string",
+		`upside $ 2`:                    "unexpected character",
+		`(upside`:                       `expected ")"`,
+		strings.Repeat("(", 40):         "nested too deeply",
+		strings.Repeat("1+", 300) + "1": "longer than",
+	}
+	for src, want := range tests {
+		if _, err := Compile(src); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Compile(%.20q) error = %v, want it to mention %q", src, err, want)
+		}
+	}
+}
+
+func TestExprEval_TypeErrors(t *testing.T) {
+	e, err := Compile(`action > 3`)
+	if err != nil {
+		t.Fatalf("Unexpected compile error: %v", err)
+	}
+	if _, err := e.Eval(map[string]interface{}{"action": "Buy"}); err == nil {
+		t.Error("Expected an error comparing a string with a number")
+	}
+}
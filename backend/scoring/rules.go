@@ -0,0 +1,189 @@
+package scoring
+
+import (
+	"fmt"
+	"regexp"
+	"sync/atomic"
+
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// maxRules bounds the size of a rule set; each rule runs for every enriched stock.
+const maxRules = 50
+
+var ruleNamePattern = regexp.MustCompile(`^[a-z0-9_]{1,50}$`)
+
+// Scorer computes a score together with the rules that produced it.
+type Scorer interface {
+	Explain(stock models.Stock) Breakdown
+}
+
+// Explain scores the stock with the built-in weighted rules.
+func (w Weights) Explain(stock models.Stock) Breakdown {
+	return Explain(stock, w)
+}
+
+// RuleSet is a compiled list of expression rules.
+type RuleSet struct {
+	rules    []models.ScoringRule
+	compiled []compiledRule
+}
+
+type compiledRule struct {
+	name   string
+	when   *Expr // nil means always
+	points *Expr
+}
+
+// CompileRules validates and compiles rules. Errors name the offending rule.
+func CompileRules(rules []models.ScoringRule) (*RuleSet, error) {
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("a rule set needs at least one rule")
+	}
+	if len(rules) > maxRules {
+		return nil, fmt.Errorf("too many rules: %d (maximum %d)", len(rules), maxRules)
+	}
+	rs := &RuleSet{rules: append([]models.ScoringRule(nil), rules...)}
+	seen := map[string]bool{}
+	for i, r := range rules {
+		if !ruleNamePattern.MatchString(r.Name) {
+			return nil, fmt.Errorf("rule %d: name must be 1-50 lowercase letters, digits or underscores", i+1)
+		}
+		if seen[r.Name] {
+			return nil, fmt.Errorf("rule %s: duplicate name", r.Name)
+		}
+		seen[r.Name] = true
+
+		c := compiledRule{name: r.Name}
+		if r.When != "" {
+			when, err := Compile(r.When)
+			if err != nil {
+				return nil, fmt.Errorf("rule %s: when: %w", r.Name, err)
+			}
+			c.when = when
+		}
+		points, err := Compile(r.Points)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: points: %w", r.Name, err)
+		}
+		c.points = points
+		rs.compiled = append(rs.compiled, c)
+	}
+	return rs, nil
+}
+
+// Rules returns the rules the set was compiled from.
+func (rs *RuleSet) Rules() []models.ScoringRule {
+	return append([]models.ScoringRule(nil), rs.rules...)
+}
+
+// Explain scores the stock with every rule. A rule that fails at evaluation time (e.g. it
+// compares a number with a string) is reported as not applied instead of failing the score.
+func (rs *RuleSet) Explain(stock models.Stock) Breakdown {
+	vars := stockVars(stock)
+	var b Breakdown
+	for _, r := range rs.compiled {
+		b.add(r.explain(vars))
+	}
+	return b
+}
+
+func (r compiledRule) explain(vars map[string]interface{}) Component {
+	c := Component{Rule: r.name}
+	if r.when != nil {
+		v, err := r.when.Eval(vars)
+		var ok bool
+		if err == nil {
+			ok, err = truthy(v)
+		}
+		if err != nil {
+			c.Reason = fmt.Sprintf("error evaluating %q: %v", r.when, err)
+			return c
+		}
+		if !ok {
+			c.Reason = fmt.Sprintf("%s is false", r.when)
+			return c
+		}
+	}
+	v, err := r.points.Eval(vars)
+	if err != nil {
+		c.Reason = fmt.Sprintf("error evaluating %q: %v", r.points, err)
+		return c
+	}
+	switch points := v.(type) {
+	case float64:
+		c.Applied, c.Points = true, points
+		c.Reason = fmt.Sprintf("%s = %.2f", r.points, points)
+		if r.when != nil {
+			c.Reason = fmt.Sprintf("%s; %s", r.when, c.Reason)
+		}
+	case nil:
+		c.Reason = fmt.Sprintf("%s is null", r.points)
+	default:
+		c.Reason = fmt.Sprintf("points must be a number, %s is %s", r.points, typeName(v))
+	}
+	return c
+}
+
+// stockVars exposes the stock fields listed in Variables. Unknown values are nil.
+func stockVars(s models.Stock) map[string]interface{} {
+	nullable := func(f models.NullFloat64) interface{} {
+		if !f.Valid {
+			return nil
+		}
+		return f.Float64
+	}
+	vars := map[string]interface{}{
+		"ticker":         s.Ticker,
+		"company":        s.Company,
+		"brokerage":      s.Brokerage,
+		"action":         s.Action,
+		"rating_from":    s.RatingFrom,
+		"rating_to":      s.RatingTo,
+		"sector":         s.Sector,
+		"exchange":       s.Exchange,
+		"currency":       s.Currency,
+		"current_price":  nil,
+		"previous_close": nullable(s.PreviousClose),
+		"target_from":    nullable(s.TargetFrom),
+		"target_to":      nullable(s.TargetTo),
+		"upside":         nil,
+		"daily_change":   nil,
+		"pe_ratio":       nullable(s.PERatio),
+		"dividend_yield": nullable(s.DividendYield),
+		"market_cap":     nullable(s.MarketCapitalization),
+		"alpha":          nullable(s.Alpha),
+		"sentiment":      nullable(s.Sentiment),
+		"buzz":           nullable(s.Buzz),
+		"esg_score":      nullable(s.ESGScore),
+		"short_interest": nullable(s.ShortInterest),
+		"days_to_cover":  nullable(s.DaysToCover),
+	}
+	// Like the built-in rules, a non-positive price means the quote is unknown.
+	if s.CurrentPrice > 0 {
+		vars["current_price"] = s.CurrentPrice
+		if s.TargetTo.Valid {
+			vars["upside"] = s.TargetTo.Float64/s.CurrentPrice - 1
+		}
+		if s.PreviousClose.Valid && s.PreviousClose.Float64 > 0 {
+			vars["daily_change"] = s.CurrentPrice/s.PreviousClose.Float64 - 1
+		}
+	}
+	return vars
+}
+
+var activeRules atomic.Pointer[RuleSet]
+
+// SetRules makes rs the rule set used by Current. nil goes back to DefaultWeights.
+func SetRules(rs *RuleSet) {
+	activeRules.Store(rs)
+}
+
+// Current returns the scorer used by the enricher: the rule set from SetRules, or the
+// built-in rules with DefaultWeights if none is set.
+func Current() Scorer {
+	if rs := activeRules.Load(); rs != nil {
+		return rs
+	}
+	return DefaultWeights
+}
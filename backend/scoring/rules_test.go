@@ -0,0 +1,79 @@
+package scoring
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// defaultRules are the built-in rules with DefaultWeights written as expressions.
+var defaultRules = []models.ScoringRule{
+	{Name: "buy_action", When: `action in ["Buy", "Strong Buy"]`, Points: "5"},
+	{Name: "target_upside", When: "upside > 0.1", Points: "3"},
+}
+
+func TestRuleSet_MatchesBuiltInRules(t *testing.T) {
+	rs, err := CompileRules(defaultRules)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	stocks := []models.Stock{
+		{Action: "Buy", CurrentPrice: 100, TargetTo: models.NewNullFloat64(115)},
+		{Action: "Strong Buy", CurrentPrice: 100, TargetTo: models.NewNullFloat64(105)},
+		{Action: "Sell", TargetTo: models.NewNullFloat64(10)},
+	}
+	for _, s := range stocks {
+		if got, want := rs.Explain(s).Score, Score(s, DefaultWeights); got != want {
+			t.Errorf("Rule set scored %+v as %v, built-in rules as %v", s, got, want)
+		}
+	}
+}
+
+func TestRuleSet_Explain(t *testing.T) {
+	rs, err := CompileRules([]models.ScoringRule{
+		{Name: "scaled_upside", When: "upside > 0", Points: "min(upside * 20, 4)"},
+		{Name: "cheap", When: "pe_ratio < 15", Points: "2"},
+		{Name: "broken", Points: `action + 1`},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	b := rs.Explain(models.Stock{Action: "Buy", CurrentPrice: 100, TargetTo: models.NewNullFloat64(125)})
+	if b.Score != 4 || len(b.Components) != 3 {
+		t.Fatalf("Expected a score of 4 (capped) from scaled_upside only, got %+v", b)
+	}
+	if !b.Components[0].Applied || b.Components[1].Applied || b.Components[1].Points != 0 {
+		t.Errorf("Expected only scaled_upside to apply, got %+v", b.Components)
+	}
+	if b.Components[2].Applied || !strings.Contains(b.Components[2].Reason, "error evaluating") {
+		t.Errorf("Expected the broken rule to be reported, not applied, got %+v", b.Components[2])
+	}
+}
+
+func TestCompileRules_Invalid(t *testing.T) {
+	tests := map[string][]models.ScoringRule{
+		"at least one rule": nil,
+		"name must be":      {{Name: "Buy Action", Points: "1"}},
+		"duplicate name":    {{Name: "a", Points: "1"}, {Name: "a", Points: "2"}},
+		"rule a: when":      {{Name: "a", When: "upside >", Points: "1"}},
+		"rule a: points":    {{Name: "a", Points: ""}},
+	}
+	for want, rules := range tests {
+		if _, err := CompileRules(rules); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("CompileRules(%+v) error = %v, want it to mention %q", rules, err, want)
+		}
+	}
+}
+
+func TestCurrent(t *testing.T) {
+	t.Cleanup(func() { SetRules(nil) })
+	if _, ok := Current().(Weights); !ok {
+		t.Fatalf("Expected the built-in weights by default, got %T", Current())
+	}
+	rs, _ := CompileRules([]models.ScoringRule{{Name: "flat", Points: "1"}})
+	SetRules(rs)
+	if got := Current().Explain(models.Stock{}).Score; got != 1 {
+		t.Errorf("Expected the active rule set to score 1, got %v", got)
+	}
+}
@@ -0,0 +1,109 @@
+// Package scoring computes the recommendation score of a stock and explains how each rule
+// contributed to it.
+package scoring
+
+import (
+	"fmt"
+
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+// Weights are the points awarded by each scoring rule.
+type Weights struct {
+	BuyAction       float64 `json:"buy_action"`       // Action is "Buy" or "Strong Buy"
+	TargetUpside    float64 `json:"target_upside"`    // TargetTo exceeds the price by more than UpsideThreshold
+	UpsideThreshold float64 `json:"upside_threshold"` // Fraction above the current price, e.g. 0.1 = 10%
+	Sentiment       float64 `json:"sentiment"`        // Points per unit of news sentiment; 0 leaves the rule out
+	ESG             float64 `json:"esg"`              // Points for a perfect ESG score of 100; 0 leaves the rule out
+}
+
+// DefaultWeights are the weights used by the enricher.
+var DefaultWeights = Weights{
+	BuyAction:       5.0,
+	TargetUpside:    3.0,
+	UpsideThreshold: 0.10,
+}
+
+// Component is the contribution of one rule to a score.
+type Component struct {
+	Rule    string  `json:"rule"`
+	Applied bool    `json:"applied"`
+	Points  float64 `json:"points"`
+	Reason  string  `json:"reason"`
+}
+
+// Breakdown is a score together with the rules that produced it.
+type Breakdown struct {
+	Score      float64     `json:"score"`
+	Components []Component `json:"components"`
+}
+
+// Explain scores the stock with w and reports every rule, applied or not.
+func Explain(stock models.Stock, w Weights) Breakdown {
+	var b Breakdown
+
+	// Rule 1: Based on the action
+	buy := stock.Action == "Buy" || stock.Action == "Strong Buy"
+	b.add(Component{
+		Rule:    "buy_action",
+		Applied: buy,
+		Points:  w.BuyAction,
+		Reason:  fmt.Sprintf("action is %q", stock.Action),
+	})
+
+	// Rule 2: Based on target price vs. current price.
+	// CurrentPrice must be positive to avoid division by zero or nonsensical logic.
+	upside := Component{Rule: "target_upside", Points: w.TargetUpside}
+	switch {
+	case stock.CurrentPrice <= 0:
+		upside.Reason = "current price unknown"
+	case !stock.TargetTo.Valid:
+		upside.Reason = "target price unknown"
+	default:
+		pct := (stock.TargetTo.Float64 - stock.CurrentPrice) / stock.CurrentPrice * 100
+		upside.Applied = stock.TargetTo.Float64 > stock.CurrentPrice*(1+w.UpsideThreshold)
+		upside.Reason = fmt.Sprintf("target is %.2f%% above the current price (threshold %.2f%%)", pct, w.UpsideThreshold*100)
+	}
+	b.add(upside)
+
+	// Rule 3: News sentiment, only when it has a weight. Unlike the other rules its points
+	// scale with the value, so negative news lowers the score.
+	if w.Sentiment != 0 {
+		news := Component{Rule: "news_sentiment", Reason: "sentiment unknown"}
+		if stock.Sentiment.Valid {
+			news.Applied = true
+			news.Points = w.Sentiment * stock.Sentiment.Float64
+			news.Reason = fmt.Sprintf("news sentiment is %.3f", stock.Sentiment.Float64)
+		}
+		b.add(news)
+	}
+
+	// Rule 4: ESG, only when it has a weight. The points are proportional to the score.
+	if w.ESG != 0 {
+		esg := Component{Rule: "esg", Reason: "ESG score unknown"}
+		if stock.ESGScore.Valid {
+			esg.Applied = true
+			esg.Points = w.ESG * stock.ESGScore.Float64 / 100
+			esg.Reason = fmt.Sprintf("ESG score is %.2f of 100", stock.ESGScore.Float64)
+		}
+		b.add(esg)
+	}
+
+	// Alpha contribution removed as per discussion.
+	// If you ever integrate a real Alpha, add it here as another rule.
+
+	return b
+}
+
+// Score returns only the score computed by Explain.
+func Score(stock models.Stock, w Weights) float64 {
+	return Explain(stock, w).Score
+}
+
+func (b *Breakdown) add(c Component) {
+	if !c.Applied {
+		c.Points = 0
+	}
+	b.Score += c.Points
+	b.Components = append(b.Components, c)
+}
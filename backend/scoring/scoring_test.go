@@ -0,0 +1,50 @@
+package scoring
+
+import (
+	"testing"
+
+	"github.com/jannin2/stock-app/backend/models"
+)
+
+func TestExplain(t *testing.T) {
+	stock := models.Stock{Action: "Buy", CurrentPrice: 100, TargetTo: models.NewNullFloat64(115)}
+
+	b := Explain(stock, DefaultWeights)
+	if b.Score != 8 || len(b.Components) != 2 || !b.Components[0].Applied || !b.Components[1].Applied {
+		t.Errorf("Expected both rules to apply for a score of 8, got %+v", b)
+	}
+
+	// A stricter threshold turns off the upside rule and its points.
+	strict := DefaultWeights
+	strict.UpsideThreshold = 0.2
+	b = Explain(stock, strict)
+	if b.Score != 5 || b.Components[1].Applied || b.Components[1].Points != 0 {
+		t.Errorf("Expected only the action rule with a 20%% threshold, got %+v", b)
+	}
+
+	// The sentiment rule only shows up with a weight, and scales with the sentiment.
+	weighted := DefaultWeights
+	weighted.Sentiment = 2
+	stock.Sentiment = models.NewNullFloat64(-0.5)
+	b = Explain(stock, weighted)
+	if b.Score != 7 || len(b.Components) != 3 || b.Components[2].Points != -1 {
+		t.Errorf("Expected negative news to take 1 point off, got %+v", b)
+	}
+	stock.Sentiment = models.NullFloat64{}
+	if b = Explain(stock, weighted); b.Score != 8 || b.Components[2].Applied {
+		t.Errorf("Expected an unknown sentiment not to count, got %+v", b)
+	}
+
+	// So does the ESG rule, in proportion to a perfect score.
+	weighted = DefaultWeights
+	weighted.ESG = 2
+	stock.ESGScore = models.NewNullFloat64(75)
+	if b = Explain(stock, weighted); b.Score != 9.5 || len(b.Components) != 3 || b.Components[2].Rule != "esg" {
+		t.Errorf("Expected an ESG score of 75 to add 1.5 points, got %+v", b)
+	}
+
+	b = Explain(models.Stock{Action: "Sell", TargetTo: models.NewNullFloat64(10)}, DefaultWeights)
+	if b.Score != 0 || b.Components[1].Reason != "current price unknown" {
+		t.Errorf("Expected a zero score without a current price, got %+v", b)
+	}
+}
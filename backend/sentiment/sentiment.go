@@ -0,0 +1,108 @@
+// Package sentiment scores news headlines with a small finance lexicon and combines the
+// scores of recent headlines into a single rolling value per ticker.
+package sentiment
+
+import (
+	"math"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	// Window is how far back headlines count towards the rolling value.
+	Window = 7 * 24 * time.Hour
+	// HalfLife is the age at which a headline weighs half as much as a fresh one.
+	HalfLife = 48 * time.Hour
+)
+
+// Headline is a news headline about a ticker.
+type Headline struct {
+	Text        string
+	PublishedAt time.Time
+}
+
+// positive and negative are stems matched against the start of each word, so "upgrade"
+// also matches "upgrades" and "upgraded".
+var positive = []string{
+	"beat", "boost", "bullish", "gain", "growth", "jump", "outperform", "profit", "rally",
+	"record", "rebound", "rise", "rising", "soar", "strong", "surge", "upbeat", "upgrade",
+	"win", "expand", "approval", "approve", "buy",
+}
+
+var negative = []string{
+	"bearish", "crash", "cut", "decline", "default", "downgrade", "drop", "fall", "fell",
+	"fraud", "investigation", "lawsuit", "layoff", "loss", "miss", "plunge", "probe",
+	"recall", "sell", "slump", "tumble", "underperform", "warn", "weak", "bankrupt",
+}
+
+// negations flip the polarity of the next word ("not strong", "no growth").
+var negations = map[string]bool{"not": true, "no": true, "never": true, "without": true}
+
+// ScoreHeadline returns the sentiment of a headline, from -1 (all negative words) to 1 (all
+// positive words). Headlines without lexicon words score 0.
+func ScoreHeadline(text string) float64 {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+	var pos, neg int
+	negate := false
+	for _, w := range words {
+		if negations[w] {
+			negate = true
+			continue
+		}
+		polarity := polarityOf(w)
+		if negate {
+			polarity = -polarity
+			negate = false
+		}
+		switch {
+		case polarity > 0:
+			pos++
+		case polarity < 0:
+			neg++
+		}
+	}
+	if pos+neg == 0 {
+		return 0
+	}
+	return float64(pos-neg) / float64(pos+neg)
+}
+
+func polarityOf(word string) int {
+	for _, stem := range positive {
+		if strings.HasPrefix(word, stem) {
+			return 1
+		}
+	}
+	for _, stem := range negative {
+		if strings.HasPrefix(word, stem) {
+			return -1
+		}
+	}
+	return 0
+}
+
+// Rolling combines the headlines published within Window before now into one value in
+// [-1, 1], rounded to three decimals. Each headline weighs 0.5^(age/HalfLife), so the
+// value follows the news as it ages out. ok is false when no headline is in the window.
+func Rolling(headlines []Headline, now time.Time) (value float64, ok bool) {
+	var sum, weights float64
+	for _, h := range headlines {
+		age := now.Sub(h.PublishedAt)
+		if age < 0 {
+			age = 0
+		}
+		if age > Window {
+			continue
+		}
+		w := math.Pow(0.5, float64(age)/float64(HalfLife))
+		sum += w * ScoreHeadline(h.Text)
+		weights += w
+	}
+	if weights == 0 {
+		return 0, false
+	}
+	return math.Round(sum/weights*1000) / 1000, true
+}
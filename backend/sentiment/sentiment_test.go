@@ -0,0 +1,48 @@
+package sentiment
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScoreHeadline(t *testing.T) {
+	tests := []struct {
+		text string
+		want float64
+	}{
+		{"Apple shares surge after record quarter", 1},
+		{"Analysts downgrade Pfizer as sales slump", -1},
+		{"Microsoft beats estimates but warns on cloud growth", 1.0 / 3},
+		{"Coca-Cola growth is not strong enough", 0},
+		{"Tesla to hold annual meeting in Austin", 0},
+		{"", 0},
+	}
+	for _, tt := range tests {
+		if got := ScoreHeadline(tt.text); got != tt.want {
+			t.Errorf("ScoreHeadline(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestRolling(t *testing.T) {
+	now := time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC)
+
+	if _, ok := Rolling(nil, now); ok {
+		t.Error("Rolling with no headlines should not be ok")
+	}
+	old := []Headline{{Text: "Shares surge", PublishedAt: now.Add(-Window - time.Hour)}}
+	if _, ok := Rolling(old, now); ok {
+		t.Error("headlines older than Window should be ignored")
+	}
+
+	// A fresh negative headline outweighs a positive one that is one half-life old:
+	// (1*-1 + 0.5*1) / 1.5 = -0.333.
+	headlines := []Headline{
+		{Text: "Shares plunge on fraud probe", PublishedAt: now},
+		{Text: "Shares surge", PublishedAt: now.Add(-HalfLife)},
+	}
+	got, ok := Rolling(headlines, now)
+	if !ok || got != -0.333 {
+		t.Errorf("Rolling = %v, %t, want -0.333, true", got, ok)
+	}
+}
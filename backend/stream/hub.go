@@ -0,0 +1,405 @@
+// Package stream reparte en tiempo real las actualizaciones de los tickers (cotizaciones
+// nuevas del enricher, ...) y de las watchlists compartidas entre los clientes conectados,
+// cada uno suscrito solo a los tickers y watchlists que le interesan.
+package stream
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// subscriptionBuffer es cuántas actualizaciones pendientes de enviar admite cada
+// suscripción. Un cliente que se queda más atrás se desconecta (ver ErrSlowConsumer) para
+// no frenar a los demás ni acumular memoria.
+const subscriptionBuffer = 256
+
+// replayBuffer es cuántas actualizaciones recientes guarda el hub para reenviarlas a los
+// clientes que se reconectan (ver Subscription.Resume).
+const replayBuffer = 1024
+
+// Tipos de las actualizaciones.
+const (
+	UpdateQuote     = "quote"     // La cotización nueva de un ticker
+	UpdateWatchlist = "watchlist" // Un cambio en los tickers o los miembros de una watchlist
+)
+
+var (
+	// ErrTooManySubscriptions indica que la suscripción superaría el máximo de tickers.
+	ErrTooManySubscriptions = errors.New("demasiadas suscripciones")
+	// ErrSlowConsumer indica que el cliente no leía las actualizaciones al ritmo en que llegan.
+	ErrSlowConsumer = errors.New("el cliente no lee las actualizaciones a tiempo")
+	// ErrHubClosed indica que el hub se cerró, normalmente porque el servidor se apaga.
+	ErrHubClosed = errors.New("el servidor se está apagando")
+	// ErrClosed indica que la suscripción la cerró su propio cliente.
+	ErrClosed = errors.New("suscripción cerrada")
+	// ErrReplayGap indica que parte de las actualizaciones pedidas a Resume ya no están en el
+	// buffer (o son de antes de reiniciar el servidor): el cliente debe volver a leer el estado.
+	ErrReplayGap = errors.New("las actualizaciones desde ese número de secuencia ya no están disponibles")
+)
+
+// Update es una actualización de un ticker o, si Watchlist no está vacío, de una watchlist,
+// tal como se envía a los clientes.
+type Update struct {
+	Seq       uint64      `json:"seq"` // Número de secuencia, asignado por Publish
+	Type      string      `json:"type"`
+	Ticker    string      `json:"ticker,omitempty"`
+	Watchlist string      `json:"watchlist,omitempty"`
+	Data      interface{} `json:"data"`
+}
+
+// Replay es el resultado de Subscription.Resume.
+type Replay struct {
+	Tickers []string // Todos los tickers seguidos, en orden
+	Missed  []Update // Actualizaciones perdidas de esos tickers, en orden de publicación
+	Seq     uint64   // Última secuencia publicada; las siguientes llegan por Updates
+}
+
+// Hub guarda qué suscripciones siguen cada ticker y les entrega las actualizaciones. Es
+// seguro para uso concurrente.
+type Hub struct {
+	mu            sync.Mutex
+	subscriptions map[*Subscription]struct{}
+	byTicker      map[string]map[*Subscription]struct{}
+	byWatchlist   map[string]map[*Subscription]struct{}
+	closed        bool
+
+	// Las secuencias empiezan en la hora de creación del hub en microsegundos, de modo que
+	// siguen creciendo tras reiniciar el servidor y Resume reconoce las de antes del reinicio.
+	first   uint64            // Secuencia de la que parte el hub; la primera publicada es first+1
+	seq     uint64            // Última secuencia publicada
+	history []Update          // Últimas replayBuffer actualizaciones, en anillo
+	next    int               // Posición de history que se sobrescribe cuando está lleno
+	evicted map[string]uint64 // Secuencia de la última actualización de cada ticker que salió de history
+}
+
+// NewHub crea un hub sin suscripciones.
+func NewHub() *Hub {
+	first := uint64(time.Now().UnixMicro())
+	return &Hub{
+		subscriptions: make(map[*Subscription]struct{}),
+		byTicker:      make(map[string]map[*Subscription]struct{}),
+		byWatchlist:   make(map[string]map[*Subscription]struct{}),
+		first:         first,
+		seq:           first,
+		history:       make([]Update, 0, replayBuffer),
+		evicted:       make(map[string]uint64),
+	}
+}
+
+// Connect crea una suscripción sin tickers. Hay que cerrarla con Close cuando el cliente se
+// desconecta. Si el hub ya está cerrado, la suscripción nace terminada.
+func (h *Hub) Connect() *Subscription {
+	s := &Subscription{
+		hub:        h,
+		updates:    make(chan Update, subscriptionBuffer),
+		done:       make(chan struct{}),
+		tickers:    make(map[string]struct{}),
+		watchlists: make(map[string]string),
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		s.err = ErrHubClosed
+		close(s.done)
+		return s
+	}
+	h.subscriptions[s] = struct{}{}
+	return s
+}
+
+// Publish numera u con la siguiente secuencia, la guarda para Resume y la entrega a las
+// suscripciones de su ticker (o de su watchlist) sin esperar a ninguna: la que tiene el
+// buffer lleno se termina con ErrSlowConsumer. Las actualizaciones de watchlists no se
+// guardan: quien se reconecta vuelve a leer la watchlist.
+func (h *Hub) Publish(u Update) {
+	u.Ticker = strings.ToUpper(u.Ticker)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.seq++
+	u.Seq = h.seq
+	if u.Watchlist != "" {
+		h.deliver(h.byWatchlist[u.Watchlist], u)
+		return
+	}
+	if len(h.history) < replayBuffer {
+		h.history = append(h.history, u)
+	} else {
+		old := h.history[h.next]
+		h.evicted[old.Ticker] = old.Seq
+		h.history[h.next] = u
+		h.next = (h.next + 1) % replayBuffer
+	}
+	h.deliver(h.byTicker[u.Ticker], u)
+}
+
+// deliver entrega u a subs. Se llama con h.mu bloqueado.
+func (h *Hub) deliver(subs map[*Subscription]struct{}, u Update) {
+	for s := range subs {
+		select {
+		case s.updates <- u:
+		default:
+			h.remove(s, ErrSlowConsumer)
+		}
+	}
+}
+
+// Unfollow deja de entregar las actualizaciones de la watchlist a las suscripciones de
+// userID, o a todas si userID está vacío: a quien deja de ser miembro y a todos cuando se
+// borra. Conviene publicar antes el cambio para que también les llegue.
+func (h *Hub) Unfollow(watchlistID, userID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for s := range h.byWatchlist[watchlistID] {
+		if userID == "" || s.watchlists[watchlistID] == userID {
+			delete(s.watchlists, watchlistID)
+			h.unindexWatchlist(watchlistID, s)
+		}
+	}
+}
+
+// Close termina todas las suscripciones con ErrHubClosed y rechaza las nuevas. Está pensado
+// para http.Server.RegisterOnShutdown: el apagado del servidor no espera a las conexiones
+// secuestradas para WebSocket.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for s := range h.subscriptions {
+		h.remove(s, ErrHubClosed)
+	}
+}
+
+// Seq devuelve la secuencia de la última actualización publicada.
+func (h *Hub) Seq() uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.seq
+}
+
+// Len devuelve cuántas suscripciones hay abiertas.
+func (h *Hub) Len() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subscriptions)
+}
+
+// remove quita s del hub y la termina con err. Se llama con h.mu bloqueado.
+func (h *Hub) remove(s *Subscription, err error) {
+	if _, ok := h.subscriptions[s]; !ok {
+		return
+	}
+	delete(h.subscriptions, s)
+	for ticker := range s.tickers {
+		h.unindex(ticker, s)
+	}
+	for watchlistID := range s.watchlists {
+		h.unindexWatchlist(watchlistID, s)
+	}
+	s.err = err
+	close(s.done)
+}
+
+// unindex quita s de los suscriptores de ticker. Se llama con h.mu bloqueado.
+func (h *Hub) unindex(ticker string, s *Subscription) {
+	delete(h.byTicker[ticker], s)
+	if len(h.byTicker[ticker]) == 0 {
+		delete(h.byTicker, ticker)
+	}
+}
+
+// unindexWatchlist quita s de los suscriptores de la watchlist. Se llama con h.mu bloqueado.
+func (h *Hub) unindexWatchlist(watchlistID string, s *Subscription) {
+	delete(h.byWatchlist[watchlistID], s)
+	if len(h.byWatchlist[watchlistID]) == 0 {
+		delete(h.byWatchlist, watchlistID)
+	}
+}
+
+// Subscription son los tickers y las watchlists que sigue un cliente y las actualizaciones
+// pendientes de enviarle.
+type Subscription struct {
+	hub     *Hub
+	updates chan Update
+	done    chan struct{}
+
+	// Protegidos por hub.mu
+	tickers    map[string]struct{}
+	watchlists map[string]string // Usuario que sigue cada watchlist, para Hub.Unfollow
+	err        error             // Motivo del fin de la suscripción; nil mientras sigue abierta
+}
+
+// Add suscribe a tickers (en mayúsculas) y devuelve todos los tickers seguidos, en orden.
+// Si el total superaría max (0 = sin límite) no añade ninguno y devuelve
+// ErrTooManySubscriptions.
+func (s *Subscription) Add(tickers []string, max int) ([]string, error) {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	return s.add(tickers, max)
+}
+
+// Resume suscribe a tickers como Add y devuelve además las actualizaciones de los tickers
+// seguidos con secuencia mayor que since, para que un cliente que se reconecta reciba lo que
+// se perdió. Las siguientes llegan por Updates, sin huecos ni repeticiones. Si alguna de las
+// perdidas ya no está guardada, o since no es de este hub, los tickers se suscriben igual
+// pero no devuelve ninguna y el error es ErrReplayGap.
+func (s *Subscription) Resume(tickers []string, max int, since uint64) (Replay, error) {
+	h := s.hub
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	subscribed, err := s.add(tickers, max)
+	replay := Replay{Tickers: subscribed, Seq: h.seq}
+	if err != nil {
+		return replay, err
+	}
+	if since < h.first || since > h.seq {
+		return replay, ErrReplayGap
+	}
+	for ticker := range s.tickers {
+		if h.evicted[ticker] > since {
+			return replay, ErrReplayGap
+		}
+	}
+	for i := range h.history {
+		u := h.history[(h.next+i)%len(h.history)]
+		if _, ok := s.tickers[u.Ticker]; ok && u.Seq > since {
+			replay.Missed = append(replay.Missed, u)
+		}
+	}
+	return replay, nil
+}
+
+// add implementa Add. Se llama con hub.mu bloqueado.
+func (s *Subscription) add(tickers []string, max int) ([]string, error) {
+	h := s.hub
+	if s.err != nil {
+		return nil, s.err
+	}
+	added := 0
+	for _, ticker := range tickers {
+		if _, ok := s.tickers[ticker]; !ok {
+			added++
+		}
+	}
+	if max > 0 && len(s.tickers)+added > max {
+		return s.sortedTickers(), ErrTooManySubscriptions
+	}
+	for _, ticker := range tickers {
+		s.tickers[ticker] = struct{}{}
+		if h.byTicker[ticker] == nil {
+			h.byTicker[ticker] = make(map[*Subscription]struct{})
+		}
+		h.byTicker[ticker][s] = struct{}{}
+	}
+	return s.sortedTickers(), nil
+}
+
+// Remove da de baja tickers y devuelve los que siguen suscritos, en orden.
+func (s *Subscription) Remove(tickers []string) []string {
+	h := s.hub
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, ticker := range tickers {
+		if _, ok := s.tickers[ticker]; ok {
+			delete(s.tickers, ticker)
+			h.unindex(ticker, s)
+		}
+	}
+	return s.sortedTickers()
+}
+
+// Follow suscribe a las actualizaciones de una watchlist en nombre de userID, que debe ser
+// miembro (lo comprueba quien llama), y devuelve todas las watchlists seguidas, en orden. Si
+// el total superaría max (0 = sin límite) no la añade y devuelve ErrTooManySubscriptions.
+func (s *Subscription) Follow(watchlistID, userID string, max int) ([]string, error) {
+	h := s.hub
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if s.err != nil {
+		return nil, s.err
+	}
+	if _, ok := s.watchlists[watchlistID]; !ok {
+		if max > 0 && len(s.watchlists) >= max {
+			return s.sortedWatchlists(), ErrTooManySubscriptions
+		}
+		if h.byWatchlist[watchlistID] == nil {
+			h.byWatchlist[watchlistID] = make(map[*Subscription]struct{})
+		}
+		h.byWatchlist[watchlistID][s] = struct{}{}
+	}
+	s.watchlists[watchlistID] = userID
+	return s.sortedWatchlists(), nil
+}
+
+// Unfollow da de baja una watchlist y devuelve las que sigue, en orden.
+func (s *Subscription) Unfollow(watchlistID string) []string {
+	h := s.hub
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := s.watchlists[watchlistID]; ok {
+		delete(s.watchlists, watchlistID)
+		h.unindexWatchlist(watchlistID, s)
+	}
+	return s.sortedWatchlists()
+}
+
+// Watchlists devuelve las watchlists seguidas, en orden.
+func (s *Subscription) Watchlists() []string {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	return s.sortedWatchlists()
+}
+
+// sortedWatchlists se llama con hub.mu bloqueado.
+func (s *Subscription) sortedWatchlists() []string {
+	watchlists := make([]string, 0, len(s.watchlists))
+	for id := range s.watchlists {
+		watchlists = append(watchlists, id)
+	}
+	sort.Strings(watchlists)
+	return watchlists
+}
+
+// Tickers devuelve los tickers seguidos, en orden.
+func (s *Subscription) Tickers() []string {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	return s.sortedTickers()
+}
+
+// sortedTickers se llama con hub.mu bloqueado.
+func (s *Subscription) sortedTickers() []string {
+	tickers := make([]string, 0, len(s.tickers))
+	for ticker := range s.tickers {
+		tickers = append(tickers, ticker)
+	}
+	sort.Strings(tickers)
+	return tickers
+}
+
+// Updates devuelve el canal de las actualizaciones de los tickers seguidos.
+func (s *Subscription) Updates() <-chan Update {
+	return s.updates
+}
+
+// Done se cierra cuando la suscripción termina; Err indica el motivo.
+func (s *Subscription) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err devuelve por qué terminó la suscripción (ErrSlowConsumer, ErrHubClosed o ErrClosed), o
+// nil si sigue abierta.
+func (s *Subscription) Err() error {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	return s.err
+}
+
+// Close termina la suscripción. Se puede llamar más de una vez.
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.remove(s, ErrClosed)
+}
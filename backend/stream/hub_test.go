@@ -0,0 +1,239 @@
+package stream
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestSubscription_AddRemove(t *testing.T) {
+	hub := NewHub()
+	sub := hub.Connect()
+	defer sub.Close()
+
+	got, err := sub.Add([]string{"MSFT", "AAPL"}, 3)
+	if err != nil || !reflect.DeepEqual(got, []string{"AAPL", "MSFT"}) {
+		t.Fatalf("❌ Add: %v, %v; se esperaba [AAPL MSFT]", got, err)
+	}
+	// Repetir un ticker no cuenta para el máximo
+	if got, err := sub.Add([]string{"AAPL", "NVDA"}, 3); err != nil || len(got) != 3 {
+		t.Fatalf("❌ Add con un ticker repetido: %v, %v", got, err)
+	}
+	got, err = sub.Add([]string{"TSLA", "AMZN"}, 4)
+	if !errors.Is(err, ErrTooManySubscriptions) {
+		t.Fatalf("❌ se esperaba ErrTooManySubscriptions, se obtuvo %v", err)
+	}
+	if !reflect.DeepEqual(got, []string{"AAPL", "MSFT", "NVDA"}) {
+		t.Errorf("❌ una suscripción rechazada no debe añadir ningún ticker, se obtuvo %v", got)
+	}
+
+	if got := sub.Remove([]string{"MSFT", "NOPE"}); !reflect.DeepEqual(got, []string{"AAPL", "NVDA"}) {
+		t.Errorf("❌ Remove: %v, se esperaba [AAPL NVDA]", got)
+	}
+	if got := sub.Tickers(); !reflect.DeepEqual(got, []string{"AAPL", "NVDA"}) {
+		t.Errorf("❌ Tickers: %v", got)
+	}
+}
+
+func TestHub_PublishRoutesByTicker(t *testing.T) {
+	hub := NewHub()
+	apple := hub.Connect()
+	defer apple.Close()
+	both := hub.Connect()
+	defer both.Close()
+	apple.Add([]string{"AAPL"}, 0)
+	both.Add([]string{"AAPL", "MSFT"}, 0)
+
+	hub.Publish(Update{Type: UpdateQuote, Ticker: "msft", Data: 410.0})
+	hub.Publish(Update{Type: UpdateQuote, Ticker: "AAPL", Data: 190.0})
+	hub.Publish(Update{Type: UpdateQuote, Ticker: "TSLA", Data: 250.0})
+
+	if got := drain(apple); !reflect.DeepEqual(got, []string{"AAPL"}) {
+		t.Errorf("❌ la suscripción a AAPL recibió %v", got)
+	}
+	if got := drain(both); !reflect.DeepEqual(got, []string{"MSFT", "AAPL"}) {
+		t.Errorf("❌ la suscripción a AAPL y MSFT recibió %v", got)
+	}
+
+	both.Remove([]string{"AAPL"})
+	hub.Publish(Update{Type: UpdateQuote, Ticker: "AAPL"})
+	if got := drain(both); len(got) != 0 {
+		t.Errorf("❌ tras darse de baja de AAPL se recibió %v", got)
+	}
+}
+
+func TestHub_SlowConsumer(t *testing.T) {
+	hub := NewHub()
+	slow := hub.Connect()
+	slow.Add([]string{"AAPL"}, 0)
+	fast := hub.Connect()
+	defer fast.Close()
+	fast.Add([]string{"MSFT"}, 0)
+
+	for i := 0; i <= subscriptionBuffer; i++ {
+		hub.Publish(Update{Type: UpdateQuote, Ticker: "AAPL"})
+	}
+	select {
+	case <-slow.Done():
+	default:
+		t.Fatal("❌ la suscripción con el buffer lleno debió terminar")
+	}
+	if !errors.Is(slow.Err(), ErrSlowConsumer) {
+		t.Errorf("❌ se esperaba ErrSlowConsumer, se obtuvo %v", slow.Err())
+	}
+	if fast.Err() != nil || hub.Len() != 1 {
+		t.Errorf("❌ las demás suscripciones no deben verse afectadas (err %v, %d abiertas)", fast.Err(), hub.Len())
+	}
+	if _, err := slow.Add([]string{"MSFT"}, 0); !errors.Is(err, ErrSlowConsumer) {
+		t.Errorf("❌ Add en una suscripción terminada: se esperaba ErrSlowConsumer, se obtuvo %v", err)
+	}
+}
+
+func TestHub_Close(t *testing.T) {
+	hub := NewHub()
+	sub := hub.Connect()
+	sub.Add([]string{"AAPL"}, 0)
+
+	hub.Close()
+	<-sub.Done()
+	if !errors.Is(sub.Err(), ErrHubClosed) {
+		t.Errorf("❌ se esperaba ErrHubClosed, se obtuvo %v", sub.Err())
+	}
+	late := hub.Connect()
+	<-late.Done()
+	if !errors.Is(late.Err(), ErrHubClosed) {
+		t.Errorf("❌ una suscripción tras Close debe nacer terminada, se obtuvo %v", late.Err())
+	}
+	// Cerrar una suscripción ya terminada no cambia el motivo
+	sub.Close()
+	if !errors.Is(sub.Err(), ErrHubClosed) {
+		t.Errorf("❌ Close cambió el motivo a %v", sub.Err())
+	}
+}
+
+// drain devuelve los tickers de las actualizaciones pendientes de s.
+func drain(s *Subscription) []string {
+	var tickers []string
+	for {
+		select {
+		case u := <-s.Updates():
+			tickers = append(tickers, u.Ticker)
+		default:
+			return tickers
+		}
+	}
+}
+
+func TestSubscription_Resume(t *testing.T) {
+	hub := NewHub()
+	start := hub.Seq()
+	for _, ticker := range []string{"AAPL", "MSFT", "AAPL", "TSLA"} {
+		hub.Publish(Update{Type: UpdateQuote, Ticker: ticker})
+	}
+	if hub.Seq() != start+4 {
+		t.Fatalf("❌ se esperaba la secuencia %d, se obtuvo %d", start+4, hub.Seq())
+	}
+
+	sub := hub.Connect()
+	defer sub.Close()
+	replay, err := sub.Resume([]string{"AAPL", "TSLA"}, 0, start+1)
+	if err != nil {
+		t.Fatalf("❌ error inesperado: %v", err)
+	}
+	var seqs []uint64
+	for _, u := range replay.Missed {
+		seqs = append(seqs, u.Seq)
+	}
+	if !reflect.DeepEqual(seqs, []uint64{start + 3, start + 4}) || replay.Seq != start+4 {
+		t.Errorf("❌ se esperaban las secuencias %d y %d hasta %d, se obtuvo %v hasta %d", start+3, start+4, start+4, seqs, replay.Seq)
+	}
+	// Las siguientes llegan por Updates
+	hub.Publish(Update{Type: UpdateQuote, Ticker: "AAPL"})
+	if u := <-sub.Updates(); u.Seq != start+5 {
+		t.Errorf("❌ se esperaba la secuencia %d, se obtuvo %d", start+5, u.Seq)
+	}
+
+	for name, since := range map[string]uint64{"anterior al hub": start - 1, "futura": start + 100} {
+		other := hub.Connect()
+		replay, err := other.Resume([]string{"AAPL"}, 0, since)
+		if !errors.Is(err, ErrReplayGap) || len(replay.Missed) != 0 || !reflect.DeepEqual(replay.Tickers, []string{"AAPL"}) {
+			t.Errorf("❌ since %s: se esperaba ErrReplayGap con AAPL suscrito, se obtuvo %+v, %v", name, replay, err)
+		}
+		other.Close()
+	}
+}
+
+func TestSubscription_ResumeAfterEviction(t *testing.T) {
+	hub := NewHub()
+	start := hub.Seq()
+	hub.Publish(Update{Type: UpdateQuote, Ticker: "AAPL"})
+	for i := 0; i < replayBuffer; i++ {
+		hub.Publish(Update{Type: UpdateQuote, Ticker: "MSFT"})
+	}
+
+	// La actualización de AAPL salió del buffer: reanudar desde antes de ella deja un hueco
+	sub := hub.Connect()
+	defer sub.Close()
+	if _, err := sub.Resume([]string{"AAPL"}, 0, start); !errors.Is(err, ErrReplayGap) {
+		t.Errorf("❌ se esperaba ErrReplayGap, se obtuvo %v", err)
+	}
+	// Pero no afecta a quien la había recibido, aunque el resto del buffer sea de otro ticker
+	other := hub.Connect()
+	defer other.Close()
+	if replay, err := other.Resume([]string{"AAPL"}, 0, start+1); err != nil || len(replay.Missed) != 0 {
+		t.Errorf("❌ no faltaba ninguna actualización de AAPL: %+v, %v", replay, err)
+	}
+	if replay, err := other.Resume([]string{"MSFT"}, 0, hub.Seq()-2); err != nil || len(replay.Missed) != 2 {
+		t.Errorf("❌ se esperaban las 2 últimas de MSFT: %d, %v", len(replay.Missed), err)
+	}
+}
+
+func TestHub_Watchlists(t *testing.T) {
+	hub := NewHub()
+	alice := hub.Connect()
+	defer alice.Close()
+	bob := hub.Connect()
+	defer bob.Close()
+
+	if got, err := alice.Follow("wl-1", "alice", 2); err != nil || !reflect.DeepEqual(got, []string{"wl-1"}) {
+		t.Fatalf("❌ Follow: %v, %v", got, err)
+	}
+	alice.Follow("wl-2", "alice", 2)
+	if _, err := alice.Follow("wl-3", "alice", 2); !errors.Is(err, ErrTooManySubscriptions) {
+		t.Errorf("❌ se esperaba ErrTooManySubscriptions, se obtuvo %v", err)
+	}
+	bob.Follow("wl-1", "bob", 0)
+
+	hub.Publish(Update{Type: UpdateWatchlist, Watchlist: "wl-1", Data: "tickers_added"})
+	for name, sub := range map[string]*Subscription{"alice": alice, "bob": bob} {
+		select {
+		case u := <-sub.Updates():
+			if u.Watchlist != "wl-1" || u.Seq == 0 {
+				t.Errorf("❌ %s recibió %+v", name, u)
+			}
+		default:
+			t.Errorf("❌ %s no recibió la actualización de la watchlist", name)
+		}
+	}
+
+	// Quien deja de ser miembro deja de recibirlas; los demás siguen
+	hub.Unfollow("wl-1", "bob")
+	if got := bob.Watchlists(); len(got) != 0 {
+		t.Errorf("❌ bob sigue las watchlists %v", got)
+	}
+	hub.Publish(Update{Type: UpdateWatchlist, Watchlist: "wl-1"})
+	if len(alice.Updates()) != 1 || len(bob.Updates()) != 0 {
+		t.Errorf("❌ pendientes: alice %d (se esperaba 1), bob %d (se esperaba 0)", len(alice.Updates()), len(bob.Updates()))
+	}
+
+	// Las de watchlists no se guardan para Resume
+	replay, err := alice.Resume([]string{"AAPL"}, 0, hub.Seq()-2)
+	if err != nil || len(replay.Missed) != 0 {
+		t.Errorf("❌ Resume devolvió %+v, %v; las watchlists no se reenvían", replay, err)
+	}
+
+	hub.Unfollow("wl-2", "")
+	if got := alice.Unfollow("wl-1"); len(got) != 0 {
+		t.Errorf("❌ Unfollow: %v", got)
+	}
+}
@@ -0,0 +1,306 @@
+package stream
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/apierror"
+)
+
+// websocketGUID es la constante con la que se calcula Sec-WebSocket-Accept (RFC 6455, 1.3).
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// MaxMessageSize es el tamaño máximo de un mensaje del cliente. Los del stream son órdenes
+// de suscripción de unos pocos tickers; uno mayor cierra la conexión con CloseMessageTooBig.
+const MaxMessageSize = 16 << 10
+
+// writeTimeout es el plazo de cada escritura, para no bloquear al que escribe si el cliente
+// dejó de leer sin cerrar la conexión.
+const writeTimeout = 10 * time.Second
+
+// Códigos de cierre (RFC 6455, 7.4.1).
+const (
+	CloseNormal          = 1000
+	CloseGoingAway       = 1001
+	CloseProtocolError   = 1002
+	CloseUnsupportedData = 1003
+	ClosePolicyViolation = 1008
+	CloseMessageTooBig   = 1009
+)
+
+// Tipos de trama (RFC 6455, 5.2).
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xA
+)
+
+// CloseError es el cierre de la conexión, enviado por el cliente o por nosotros al detectar
+// un error de protocolo.
+type CloseError struct {
+	Code   int
+	Reason string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("conexión WebSocket cerrada (%d %s)", e.Code, e.Reason)
+}
+
+// Conn es una conexión WebSocket del lado del servidor con lo que necesita el stream:
+// mensajes de texto, ping/pong y cierre. No negocia extensiones ni subprotocolos. Admite un
+// lector y cualquier número de escritores concurrentes.
+type Conn struct {
+	conn        net.Conn
+	br          *bufio.Reader
+	readTimeout time.Duration // Ver SetReadTimeout
+
+	writeMu   sync.Mutex
+	closeSent bool // Protegido por writeMu
+}
+
+// Upgrade completa el handshake de WebSocket de r y devuelve la conexión. Si la petición no
+// es un handshake válido responde con el error HTTP correspondiente y devuelve error.
+func Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	if r.Method != http.MethodGet || !headerHasToken(r.Header, "Connection", "upgrade") ||
+		!headerHasToken(r.Header, "Upgrade", "websocket") {
+		apierror.HTTPError(w, "Se esperaba una petición GET de WebSocket (Upgrade: websocket)", http.StatusBadRequest)
+		return nil, errors.New("la petición no es un handshake de WebSocket")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		apierror.HTTPError(w, "Versión de WebSocket no soportada", http.StatusUpgradeRequired)
+		return nil, errors.New("versión de WebSocket no soportada")
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		apierror.HTTPError(w, "Sec-WebSocket-Key inválida", http.StatusBadRequest)
+		return nil, errors.New("Sec-WebSocket-Key inválida")
+	}
+
+	netConn, brw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		apierror.HTTPError(w, "El servidor no admite WebSocket en esta ruta", http.StatusInternalServerError)
+		return nil, fmt.Errorf("no se pudo secuestrar la conexión: %w", err)
+	}
+	// El servidor HTTP pudo fijar plazos para la petición; la conexión ya no es suya.
+	_ = netConn.SetDeadline(time.Time{})
+
+	accept := sha1.Sum([]byte(key + websocketGUID))
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(accept[:]) + "\r\n\r\n"
+	if _, err := brw.WriteString(response); err == nil {
+		err = brw.Flush()
+	}
+	if err != nil {
+		netConn.Close()
+		return nil, fmt.Errorf("error al completar el handshake de WebSocket: %w", err)
+	}
+	return &Conn{conn: netConn, br: brw.Reader}, nil
+}
+
+// headerHasToken indica si la cabecera name contiene token en su lista separada por comas.
+func headerHasToken(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, part := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ReadMessage devuelve el siguiente mensaje de texto del cliente. Responde a los ping por
+// su cuenta; un cierre del cliente se contesta y se devuelve como *CloseError, igual que los
+// errores de protocolo, tras los que la conexión queda cerrada.
+func (c *Conn) ReadMessage() ([]byte, error) {
+	var message []byte
+	var started bool
+	for {
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil {
+			var closeErr *CloseError
+			if errors.As(err, &closeErr) {
+				c.Close(closeErr.Code, closeErr.Reason)
+			}
+			return nil, err
+		}
+
+		switch opcode {
+		case opPing:
+			if err := c.writeFrame(opPong, payload); err != nil {
+				return nil, err
+			}
+			continue
+		case opPong:
+			continue
+		case opClose:
+			code := CloseNormal
+			if len(payload) >= 2 {
+				code = int(binary.BigEndian.Uint16(payload))
+			}
+			c.Close(code, "")
+			return nil, &CloseError{Code: code, Reason: string(payload[min(len(payload), 2):])}
+		case opText, opBinary:
+			if started {
+				return nil, c.fail(CloseProtocolError, "mensaje nuevo antes de terminar el anterior")
+			}
+			if opcode == opBinary {
+				return nil, c.fail(CloseUnsupportedData, "solo se admiten mensajes de texto")
+			}
+			started = true
+		case opContinuation:
+			if !started {
+				return nil, c.fail(CloseProtocolError, "continuación sin mensaje")
+			}
+		default:
+			return nil, c.fail(CloseProtocolError, "tipo de trama desconocido")
+		}
+
+		if len(message)+len(payload) > MaxMessageSize {
+			return nil, c.fail(CloseMessageTooBig, "mensaje demasiado grande")
+		}
+		message = append(message, payload...)
+		if fin {
+			return message, nil
+		}
+	}
+}
+
+// readFrame lee una trama del cliente y la desenmascara.
+func (c *Conn) readFrame() (fin bool, opcode byte, payload []byte, err error) {
+	if c.readTimeout > 0 {
+		_ = c.conn.SetReadDeadline(time.Now().Add(c.readTimeout))
+	}
+	var header [2]byte
+	if _, err := io.ReadFull(c.br, header[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin = header[0]&0x80 != 0
+	opcode = header[0] & 0x0F
+	if header[0]&0x70 != 0 {
+		return false, 0, nil, &CloseError{CloseProtocolError, "bits reservados sin extensión"}
+	}
+	if header[1]&0x80 == 0 {
+		return false, 0, nil, &CloseError{CloseProtocolError, "las tramas del cliente deben ir enmascaradas"}
+	}
+
+	length := uint64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	if opcode >= opClose && (!fin || length > 125) {
+		return false, 0, nil, &CloseError{CloseProtocolError, "trama de control inválida"}
+	}
+	if length > MaxMessageSize {
+		return false, 0, nil, &CloseError{CloseMessageTooBig, "mensaje demasiado grande"}
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(c.br, mask[:]); err != nil {
+		return false, 0, nil, err
+	}
+	payload = make([]byte, length)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		return false, 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return fin, opcode, payload, nil
+}
+
+// fail cierra la conexión con code y devuelve el error correspondiente.
+func (c *Conn) fail(code int, reason string) error {
+	c.Close(code, reason)
+	return &CloseError{Code: code, Reason: reason}
+}
+
+// WriteText envía un mensaje de texto.
+func (c *Conn) WriteText(data []byte) error {
+	return c.writeFrame(opText, data)
+}
+
+// Ping envía un ping; el cliente debe responder con un pong, que ReadMessage descarta pero
+// que cuenta como actividad para SetReadTimeout.
+func (c *Conn) Ping() error {
+	return c.writeFrame(opPing, nil)
+}
+
+// SetReadTimeout hace que ReadMessage falle si el cliente pasa d sin enviar ninguna trama,
+// incluidos los pong. 0 espera indefinidamente. Hay que llamarlo antes de empezar a leer.
+func (c *Conn) SetReadTimeout(d time.Duration) {
+	c.readTimeout = d
+}
+
+// Close envía el cierre con code y reason (si no se envió ya) y cierra la conexión. Se
+// puede llamar más de una vez y desde cualquier goroutine.
+func (c *Conn) Close(code int, reason string) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if !c.closeSent {
+		c.closeSent = true
+		payload := make([]byte, 2, 2+len(reason))
+		binary.BigEndian.PutUint16(payload, uint16(code))
+		payload = append(payload, reason[:min(len(reason), 123)]...)
+		_ = c.writeFrameLocked(opClose, payload)
+	}
+	return c.conn.Close()
+}
+
+// writeFrame envía una trama completa, sin enmascarar como corresponde al servidor.
+func (c *Conn) writeFrame(opcode byte, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closeSent {
+		return net.ErrClosed
+	}
+	return c.writeFrameLocked(opcode, payload)
+}
+
+// writeFrameLocked se llama con writeMu bloqueado.
+func (c *Conn) writeFrameLocked(opcode byte, payload []byte) error {
+	frame := make([]byte, 0, 10+len(payload))
+	frame = append(frame, 0x80|opcode)
+	switch n := len(payload); {
+	case n <= 125:
+		frame = append(frame, byte(n))
+	case n <= 0xFFFF:
+		frame = append(frame, 126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(n))
+	default:
+		frame = append(frame, 127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+	frame = append(frame, payload...)
+
+	_ = c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	_, err := c.conn.Write(frame)
+	return err
+}
@@ -0,0 +1,279 @@
+package stream
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testClient es un cliente WebSocket mínimo para las pruebas: envía tramas enmascaradas y
+// lee las del servidor.
+type testClient struct {
+	conn net.Conn
+	br   *bufio.Reader
+}
+
+// dial abre un WebSocket contra server con la clave de ejemplo de la RFC 6455.
+func dial(t *testing.T, server *httptest.Server) *testClient {
+	t.Helper()
+	conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatalf("❌ no se pudo conectar: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	req := "GET / HTTP/1.1\r\nHost: test\r\nUpgrade: websocket\r\nConnection: keep-alive, Upgrade\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n"
+	if _, err := conn.Write([]byte(req)); err != nil {
+		t.Fatalf("❌ error al enviar el handshake: %v", err)
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatalf("❌ respuesta al handshake inválida: %v", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("❌ estado %d, se esperaba 101", resp.StatusCode)
+	}
+	// Valor de ejemplo de la RFC 6455, 1.3
+	if got := resp.Header.Get("Sec-WebSocket-Accept"); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("❌ Sec-WebSocket-Accept %q", got)
+	}
+	return &testClient{conn: conn, br: br}
+}
+
+// send envía una trama enmascarada.
+func (c *testClient) send(t *testing.T, fin bool, opcode byte, payload []byte) {
+	t.Helper()
+	first := opcode
+	if fin {
+		first |= 0x80
+	}
+	frame := []byte{first}
+	switch n := len(payload); {
+	case n <= 125:
+		frame = append(frame, 0x80|byte(n))
+	case n <= 0xFFFF:
+		frame = append(frame, 0x80|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(n))
+	default:
+		frame = append(frame, 0x80|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+	mask := []byte{1, 2, 3, 4}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	if _, err := c.conn.Write(frame); err != nil {
+		t.Fatalf("❌ error al enviar la trama: %v", err)
+	}
+}
+
+// read lee una trama del servidor, que no debe venir enmascarada.
+func (c *testClient) read(t *testing.T) (opcode byte, payload []byte) {
+	t.Helper()
+	var header [2]byte
+	if _, err := io.ReadFull(c.br, header[:]); err != nil {
+		t.Fatalf("❌ error al leer la trama: %v", err)
+	}
+	if header[1]&0x80 != 0 {
+		t.Fatal("❌ el servidor no debe enmascarar sus tramas")
+	}
+	length := int(header[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		io.ReadFull(c.br, ext[:])
+		length = int(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		io.ReadFull(c.br, ext[:])
+		length = int(binary.BigEndian.Uint64(ext[:]))
+	}
+	payload = make([]byte, length)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		t.Fatalf("❌ error al leer la trama: %v", err)
+	}
+	return header[0] & 0x0F, payload
+}
+
+// expectClose lee una trama de cierre y comprueba su código.
+func (c *testClient) expectClose(t *testing.T, code int) {
+	t.Helper()
+	opcode, payload := c.read(t)
+	if opcode != opClose || len(payload) < 2 {
+		t.Fatalf("❌ se esperaba un cierre, se obtuvo la trama %x %q", opcode, payload)
+	}
+	if got := int(binary.BigEndian.Uint16(payload)); got != code {
+		t.Errorf("❌ código de cierre %d, se esperaba %d", got, code)
+	}
+}
+
+// serve arranca un servidor que acepta un WebSocket y pasa la conexión a handle.
+func serve(t *testing.T, handle func(*Conn)) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		handle(conn)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestUpgrade_InvalidHandshake(t *testing.T) {
+	valid := func() *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Connection", "Upgrade")
+		r.Header.Set("Upgrade", "websocket")
+		r.Header.Set("Sec-WebSocket-Version", "13")
+		r.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+		return r
+	}
+	cases := map[string]struct {
+		modify func(*http.Request)
+		status int
+	}{
+		"sin Upgrade":    {func(r *http.Request) { r.Header.Del("Upgrade") }, http.StatusBadRequest},
+		"POST":           {func(r *http.Request) { r.Method = http.MethodPost }, http.StatusBadRequest},
+		"versión 8":      {func(r *http.Request) { r.Header.Set("Sec-WebSocket-Version", "8") }, http.StatusUpgradeRequired},
+		"clave inválida": {func(r *http.Request) { r.Header.Set("Sec-WebSocket-Key", "abc") }, http.StatusBadRequest},
+	}
+	for name, tc := range cases {
+		r := valid()
+		tc.modify(r)
+		rr := httptest.NewRecorder()
+		if _, err := Upgrade(rr, r); err == nil {
+			t.Errorf("❌ %s: se esperaba error", name)
+		}
+		if rr.Code != tc.status {
+			t.Errorf("❌ %s: estado %d, se esperaba %d", name, rr.Code, tc.status)
+		}
+	}
+}
+
+func TestConn_Echo(t *testing.T) {
+	server := serve(t, func(conn *Conn) {
+		for {
+			message, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			conn.WriteText(message)
+		}
+	})
+	client := dial(t, server)
+
+	client.send(t, true, opText, []byte("hola"))
+	if opcode, payload := client.read(t); opcode != opText || string(payload) != "hola" {
+		t.Errorf("❌ se esperaba el eco de \"hola\", se obtuvo %x %q", opcode, payload)
+	}
+
+	// Un mensaje fragmentado con un ping en medio: el ping se contesta antes del mensaje
+	client.send(t, false, opText, []byte("ho"))
+	client.send(t, true, opPing, []byte("p"))
+	client.send(t, true, opContinuation, []byte("la"))
+	if opcode, payload := client.read(t); opcode != opPong || string(payload) != "p" {
+		t.Errorf("❌ se esperaba el pong, se obtuvo %x %q", opcode, payload)
+	}
+	if opcode, payload := client.read(t); opcode != opText || string(payload) != "hola" {
+		t.Errorf("❌ se esperaba el mensaje reensamblado, se obtuvo %x %q", opcode, payload)
+	}
+
+	// Un mensaje de más de 125 bytes usa la longitud extendida
+	long := strings.Repeat("x", 300)
+	client.send(t, true, opText, []byte(long))
+	if _, payload := client.read(t); string(payload) != long {
+		t.Errorf("❌ el mensaje largo llegó con %d bytes", len(payload))
+	}
+
+	client.send(t, true, opClose, []byte{0x03, 0xE8})
+	client.expectClose(t, CloseNormal)
+}
+
+func TestConn_ProtocolErrors(t *testing.T) {
+	cases := map[string]struct {
+		send func(*testing.T, *testClient)
+		code int
+	}{
+		"binario": {func(t *testing.T, c *testClient) { c.send(t, true, opBinary, []byte{1}) }, CloseUnsupportedData},
+		"demasiado grande": {func(t *testing.T, c *testClient) {
+			c.send(t, true, opText, make([]byte, MaxMessageSize+1))
+		}, CloseMessageTooBig},
+		"fragmentos demasiado grandes": {func(t *testing.T, c *testClient) {
+			c.send(t, false, opText, make([]byte, MaxMessageSize))
+			c.send(t, true, opContinuation, []byte{1})
+		}, CloseMessageTooBig},
+		"continuación sin mensaje": {func(t *testing.T, c *testClient) {
+			c.send(t, true, opContinuation, []byte("x"))
+		}, CloseProtocolError},
+		"sin máscara": {func(t *testing.T, c *testClient) {
+			c.conn.Write([]byte{0x81, 0x01, 'x'})
+		}, CloseProtocolError},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			errs := make(chan error, 1)
+			server := serve(t, func(conn *Conn) {
+				_, err := conn.ReadMessage()
+				errs <- err
+			})
+			client := dial(t, server)
+			tc.send(t, client)
+			client.expectClose(t, tc.code)
+
+			var closeErr *CloseError
+			if err := <-errs; !errors.As(err, &closeErr) || closeErr.Code != tc.code {
+				t.Errorf("❌ se esperaba CloseError %d, se obtuvo %v", tc.code, err)
+			}
+		})
+	}
+}
+
+func TestConn_ReadTimeout(t *testing.T) {
+	errs := make(chan error, 1)
+	server := serve(t, func(conn *Conn) {
+		conn.SetReadTimeout(50 * time.Millisecond)
+		_, err := conn.ReadMessage()
+		errs <- err
+	})
+	dial(t, server)
+
+	select {
+	case err := <-errs:
+		var netErr net.Error
+		if !errors.As(err, &netErr) || !netErr.Timeout() {
+			t.Errorf("❌ se esperaba un timeout, se obtuvo %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("❌ ReadMessage no respetó el timeout")
+	}
+}
+
+func TestConn_CloseIsIdempotent(t *testing.T) {
+	done := make(chan error, 1)
+	server := serve(t, func(conn *Conn) {
+		conn.Close(CloseGoingAway, "adiós")
+		conn.Close(CloseNormal, "")
+		done <- conn.WriteText([]byte("tarde"))
+	})
+	client := dial(t, server)
+
+	client.expectClose(t, CloseGoingAway)
+	if err := <-done; !errors.Is(err, net.ErrClosed) {
+		t.Errorf("❌ escribir tras Close: se esperaba net.ErrClosed, se obtuvo %v", err)
+	}
+	if _, err := client.br.ReadByte(); err != io.EOF {
+		t.Errorf("❌ tras el cierre no debe llegar nada más (err %v)", err)
+	}
+}
@@ -0,0 +1,141 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jannin2/stock-app/backend/clock"
+	"github.com/jannin2/stock-app/backend/config"
+)
+
+// sendTimeout es el tiempo máximo que se espera la respuesta del receptor.
+const sendTimeout = 10 * time.Second
+
+// ErrPrivateAddress indica que la URL del webhook apunta a una dirección interna.
+var ErrPrivateAddress = errors.New("la URL del webhook apunta a una dirección privada")
+
+// Event es el cuerpo de un webhook saliente.
+type Event struct {
+	ID        string      `json:"id"` // Igual que DeliveryHeader
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// Delivery es el resultado de entregar un webhook.
+type Delivery struct {
+	ID         string `json:"id"`
+	StatusCode int    `json:"status_code,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+// OK indica si el receptor aceptó la entrega (respuesta 2xx).
+func (d Delivery) OK() bool {
+	return d.StatusCode >= 200 && d.StatusCode < 300
+}
+
+// Sender envía webhooks firmados a URLs de los usuarios.
+type Sender struct {
+	client *http.Client
+	clock  clock.Clock
+}
+
+// NewSender crea un Sender que no sigue redirecciones ni se conecta a direcciones
+// privadas, de loopback o link-local: las URLs las elige el usuario y no deben servir para
+// llegar a servicios internos.
+func NewSender(c clock.Clock) *Sender {
+	dialer := &net.Dialer{Timeout: 5 * time.Second, Control: refusePrivate}
+	return NewSenderWithClient(&http.Client{
+		Timeout:   sendTimeout,
+		Transport: &http.Transport{DialContext: dialer.DialContext, TLSHandshakeTimeout: 5 * time.Second},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}, c)
+}
+
+// NewSenderWithClient crea un Sender con un cliente HTTP propio (p. ej. el de un
+// httptest.Server en los tests).
+func NewSenderWithClient(client *http.Client, c clock.Clock) *Sender {
+	return &Sender{client: client, clock: c}
+}
+
+// Send envía un evento eventType con data a target, firmado con secret. Los errores de red
+// y las respuestas que no son 2xx se devuelven en la Delivery, no como error: para el
+// emisor son un resultado más de la entrega.
+func (s *Sender) Send(ctx context.Context, target, secret, eventType string, data interface{}) Delivery {
+	now := s.clock.Now().UTC()
+	event := Event{ID: uuid.NewString(), Type: eventType, CreatedAt: now, Data: data}
+	delivery := Delivery{ID: event.ID}
+	body, err := json.Marshal(event)
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", config.Current().UserAgent)
+	req.Header.Set(EventHeader, eventType)
+	req.Header.Set(DeliveryHeader, event.ID)
+	req.Header.Set(SignatureHeader, Sign(secret, now, event.ID, body))
+
+	start := time.Now()
+	resp, err := s.client.Do(req)
+	delivery.DurationMS = time.Since(start).Milliseconds()
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	delivery.StatusCode = resp.StatusCode
+	if !delivery.OK() {
+		delivery.Error = fmt.Sprintf("el receptor respondió %s", resp.Status)
+	}
+	return delivery
+}
+
+// ValidateURL comprueba que target sea una URL https absoluta sin credenciales.
+func ValidateURL(target string) error {
+	u, err := url.Parse(target)
+	if err != nil || u.Scheme != "https" || u.Hostname() == "" || u.User != nil {
+		return errors.New("la URL del webhook debe ser https://host/... sin credenciales")
+	}
+	if ip := net.ParseIP(u.Hostname()); ip != nil && isPrivate(ip) {
+		return ErrPrivateAddress
+	}
+	return nil
+}
+
+// refusePrivate se ejecuta justo antes de conectar, ya resuelto el nombre, así que también
+// cubre los dominios que resuelven a direcciones internas.
+func refusePrivate(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || isPrivate(ip) {
+		return ErrPrivateAddress
+	}
+	return nil
+}
+
+func isPrivate(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsUnspecified() || ip.IsMulticast()
+}
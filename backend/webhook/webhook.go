@@ -0,0 +1,175 @@
+// Package webhook firma y verifica webhooks con HMAC-SHA256, tanto los que la app envía a
+// las URLs de los usuarios como los que recibe de los proveedores (Karenai). Cada entrega
+// lleva firmados un timestamp y un nonce: el receptor rechaza las que se salen de la
+// tolerancia y las que ya vio, así que una petición capturada no puede reenviarse.
+//
+// La firma va en la cabecera X-Webhook-Signature como "t=<unix>,v1=<hex>", donde v1 es el
+// HMAC de "<t>.<nonce>.<cuerpo>" y el nonce es la cabecera X-Webhook-Delivery. Durante una
+// rotación del secreto puede haber varias v1; basta con que una sea válida.
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jannin2/stock-app/backend/clock"
+)
+
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	DeliveryHeader  = "X-Webhook-Delivery" // Nonce único de cada entrega
+	EventHeader     = "X-Webhook-Event"
+
+	// DefaultTolerance es la diferencia máxima entre el timestamp firmado y el reloj del
+	// receptor. Cubre desfases de reloj y reintentos rápidos sin dejar una ventana amplia.
+	DefaultTolerance = 5 * time.Minute
+
+	// MaxBodySize es el tamaño máximo del cuerpo de un webhook recibido.
+	MaxBodySize = 1 << 20
+
+	secretPrefix = "whsec_"
+)
+
+var (
+	ErrMissingSignature    = errors.New("faltan la firma o el ID de entrega del webhook")
+	ErrInvalidSignature    = errors.New("firma del webhook inválida")
+	ErrTimestampOutOfRange = errors.New("timestamp del webhook fuera de la tolerancia")
+	ErrReplayed            = errors.New("entrega de webhook repetida")
+	ErrBodyTooLarge        = errors.New("cuerpo del webhook demasiado grande")
+)
+
+// NewSecret genera un secreto de firma aleatorio.
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("error al generar el secreto del webhook: %w", err)
+	}
+	return secretPrefix + hex.EncodeToString(b), nil
+}
+
+// Sign devuelve el valor de SignatureHeader para una entrega con nonce enviada en t.
+func Sign(secret string, t time.Time, nonce string, body []byte) string {
+	return fmt.Sprintf("t=%d,v1=%s", t.Unix(), hex.EncodeToString(mac(secret, t.Unix(), nonce, body)))
+}
+
+func mac(secret string, ts int64, nonce string, body []byte) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(h, "%d.%s.", ts, nonce)
+	h.Write(body)
+	return h.Sum(nil)
+}
+
+// NonceStore recuerda los nonces ya recibidos hasta que caducan.
+type NonceStore interface {
+	// RememberWebhookNonce guarda nonce y devuelve false si ya estaba guardado.
+	RememberWebhookNonce(ctx context.Context, nonce string, expiresAt time.Time) (bool, error)
+}
+
+// Verifier comprueba los webhooks recibidos de una fuente.
+type Verifier struct {
+	source    string // Prefijo de los nonces, para que dos fuentes no choquen
+	secrets   []string
+	tolerance time.Duration
+	nonces    NonceStore
+	clock     clock.Clock
+}
+
+// NewVerifier crea un Verifier para los webhooks de source firmados con alguno de secrets
+// (el actual y, durante una rotación, el anterior).
+func NewVerifier(source string, nonces NonceStore, c clock.Clock, secrets ...string) *Verifier {
+	return &Verifier{source: source, secrets: secrets, tolerance: DefaultTolerance, nonces: nonces, clock: c}
+}
+
+// Verify comprueba la firma, el timestamp y que el nonce no se haya usado ya. El nonce
+// solo se guarda si la firma es válida, para que peticiones falsas no llenen el almacén.
+func (v *Verifier) Verify(ctx context.Context, signature, nonce string, body []byte) error {
+	if signature == "" || nonce == "" {
+		return ErrMissingSignature
+	}
+	ts, sigs, err := parseSignature(signature)
+	if err != nil {
+		return err
+	}
+	signedAt := time.Unix(ts, 0)
+	if d := v.clock.Now().Sub(signedAt); d > v.tolerance || d < -v.tolerance {
+		return ErrTimestampOutOfRange
+	}
+	if !v.validMAC(ts, nonce, body, sigs) {
+		return ErrInvalidSignature
+	}
+	// Pasada la tolerancia el timestamp ya no es válido, así que no hace falta recordar
+	// el nonce más tiempo.
+	fresh, err := v.nonces.RememberWebhookNonce(ctx, v.source+":"+nonce, signedAt.Add(v.tolerance))
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return ErrReplayed
+	}
+	return nil
+}
+
+// VerifyRequest lee el cuerpo de r (hasta MaxBodySize) y lo verifica con sus cabeceras.
+func (v *Verifier) VerifyRequest(r *http.Request) ([]byte, error) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, MaxBodySize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > MaxBodySize {
+		return nil, ErrBodyTooLarge
+	}
+	return body, v.Verify(r.Context(), r.Header.Get(SignatureHeader), r.Header.Get(DeliveryHeader), body)
+}
+
+func (v *Verifier) validMAC(ts int64, nonce string, body []byte, sigs [][]byte) bool {
+	for _, secret := range v.secrets {
+		expected := mac(secret, ts, nonce, body)
+		for _, sig := range sigs {
+			if hmac.Equal(sig, expected) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parseSignature separa el timestamp y las firmas v1 de SignatureHeader. Los esquemas
+// desconocidos se ignoran para poder añadir otros en el futuro.
+func parseSignature(header string) (int64, [][]byte, error) {
+	var ts int64
+	var sigs [][]byte
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return 0, nil, ErrInvalidSignature
+		}
+		switch key {
+		case "t":
+			parsed, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return 0, nil, ErrInvalidSignature
+			}
+			ts = parsed
+		case "v1":
+			sig, err := hex.DecodeString(value)
+			if err != nil {
+				return 0, nil, ErrInvalidSignature
+			}
+			sigs = append(sigs, sig)
+		}
+	}
+	if ts == 0 || len(sigs) == 0 {
+		return 0, nil, ErrInvalidSignature
+	}
+	return ts, sigs, nil
+}
@@ -62,7 +62,14 @@ describe('Stock Store', () => {
       if (url.includes('/stocks')) {
         return Promise.resolve({
           ok: true,
-          json: () => Promise.resolve(mockStocksData), // Usar los mismos datos mock para simplicidad
+          // GET /stocks devuelve el sobre de paginación
+          json: () => Promise.resolve({
+            data: mockStocksData,
+            total: mockStocksData.length,
+            limit: 50,
+            offset: 0,
+            next_offset: null,
+          }),
         });
       }
       return Promise.reject(new Error('URL de API no mockeada'));
@@ -106,6 +113,8 @@ describe('Stock Store', () => {
     expect(store.loading).toBe(false);
     expect(store.error).toBeNull();
     expect(store.stocks).toEqual(mockStocksData);
+    expect(store.total).toBe(mockStocksData.length);
+    expect(store.nextOffset).toBeNull();
   });
 
   // --- Pruebas de Funciones de Formato ---
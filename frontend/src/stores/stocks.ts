@@ -2,8 +2,20 @@
 import { defineStore } from 'pinia';
 import type { Stock } from '../types/stock';
 
+// Paginated mirrors the envelope returned by GET /stocks.
+interface Paginated<T> {
+    data: T[];
+    total: number;
+    total_approximate?: boolean;
+    limit: number;
+    offset: number;
+    next_offset: number | null;
+}
+
 interface StockState {
     stocks: Stock[];
+    total: number;
+    nextOffset: number | null;
     recommendedStocks: Stock[];
     selectedStock: Stock | null;
     loading: boolean;
@@ -13,6 +25,8 @@ interface StockState {
 export const useStockStore = defineStore('stock', {
     state: (): StockState => ({
         stocks: [],
+        total: 0,
+        nextOffset: null,
         recommendedStocks: [],
         selectedStock: null,
         loading: false,
@@ -27,8 +41,10 @@ export const useStockStore = defineStore('stock', {
                 if (!response.ok) {
                     throw new Error(`HTTP error! status: ${response.status}`);
                 }
-                const data: Stock[] = await response.json();
-                this.stocks = data;
+                const page: Paginated<Stock> = await response.json();
+                this.stocks = page.data;
+                this.total = page.total;
+                this.nextOffset = page.next_offset;
             } catch (e: any) {
                 this.error = e.message;
                 console.error('Error fetching stocks:', e);